@@ -3,6 +3,7 @@ package backends
 import (
 	"context"
 	"errors"
+	"time"
 
 	as "github.com/aerospike/aerospike-client-go"
 	as_types "github.com/aerospike/aerospike-client-go/types"
@@ -18,7 +19,7 @@ const binValue = "value"
 // Wrapper for the Aerospike client
 type AerospikeDB interface {
 	NewUuidKey(namespace string, key string) (*as.Key, error)
-	Get(key *as.Key) (*as.Record, error)
+	Get(policy *as.BasePolicy, key *as.Key) (*as.Record, error)
 	Put(policy *as.WritePolicy, key *as.Key, binMap as.BinMap) error
 }
 
@@ -26,8 +27,8 @@ type AerospikeDBClient struct {
 	client *as.Client
 }
 
-func (db AerospikeDBClient) Get(key *as.Key) (*as.Record, error) {
-	return db.client.Get(nil, key, binValue)
+func (db AerospikeDBClient) Get(policy *as.BasePolicy, key *as.Key) (*as.Record, error) {
+	return db.client.Get(policy, key, binValue)
 }
 
 func (db AerospikeDBClient) Put(policy *as.WritePolicy, key *as.Key, binMap as.BinMap) error {
@@ -63,6 +64,18 @@ func NewAerospikeBackend(cfg config.Aerospike, metrics *metrics.Metrics) *Aerosp
 		hosts = append(hosts, as.NewHost(host, cfg.Port))
 	}
 
+	if cfg.TLS.Enabled {
+		tlsConfig, err := clientTLSConfig(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.CAFile)
+		if err != nil {
+			log.Fatalf("Error connecting to Aerospike over TLS: %v", err)
+			panic("AerospikeBackend failure. This shouldn't happen.")
+		}
+		clientPolicy.TlsConfig = tlsConfig
+		for _, host := range hosts {
+			host.TLSName = cfg.TLS.Name
+		}
+	}
+
 	client, err := as.NewClientWithPolicyAndHost(clientPolicy, hosts...)
 	if err != nil {
 		log.Fatalf("%v", formatAerospikeError(err).Error())
@@ -78,11 +91,15 @@ func NewAerospikeBackend(cfg config.Aerospike, metrics *metrics.Metrics) *Aerosp
 }
 
 func (a *AerospikeBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	asKey, err := a.client.NewUuidKey(a.cfg.Namespace, key)
 	if err != nil {
 		return "", formatAerospikeError(err)
 	}
-	rec, err := a.client.Get(asKey)
+	rec, err := a.client.Get(newReadPolicy(a.cfg), asKey)
 	if err != nil {
 		return "", formatAerospikeError(err)
 	}
@@ -104,18 +121,24 @@ func (a *AerospikeBackend) Get(ctx context.Context, key string) (string, error)
 	return str, nil
 }
 
+// DefaultTTLSeconds is the configured TTL of values put without one. When zero, Aerospike applies the
+// default TTL of the namespace instead.
+func (a *AerospikeBackend) DefaultTTLSeconds() int {
+	return a.cfg.DefaultTTL
+}
+
 func (a *AerospikeBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	asKey, err := a.client.NewUuidKey(a.cfg.Namespace, key)
 	if err != nil {
 		return formatAerospikeError(err)
 	}
 
-	if ttlSeconds == 0 {
-		ttlSeconds = a.cfg.DefaultTTL
-	}
-
 	bins := as.BinMap{binValue: value}
-	policy := &as.WritePolicy{Expiration: uint32(ttlSeconds)}
+	policy := newWritePolicy(a.cfg, ttlSeconds)
 
 	if err := a.client.Put(policy, asKey, bins); err != nil {
 		return formatAerospikeError(err)
@@ -124,11 +147,48 @@ func (a *AerospikeBackend) Put(ctx context.Context, key string, value string, tt
 	return nil
 }
 
+// newReadPolicy returns nil, for the client to use its default policy, unless a timeout is configured
+func newReadPolicy(cfg config.Aerospike) *as.BasePolicy {
+	if cfg.SocketTimeoutMillis == 0 && cfg.TotalTimeoutMillis == 0 {
+		return nil
+	}
+	policy := as.NewPolicy()
+	applyTimeouts(cfg, policy)
+	return policy
+}
+
+// newWritePolicy leaves the timeouts without a limit and commits to every replica, unless configured
+// otherwise
+func newWritePolicy(cfg config.Aerospike, ttlSeconds int) *as.WritePolicy {
+	policy := &as.WritePolicy{Expiration: uint32(ttlSeconds)}
+	if cfg.CommitLevel == config.AerospikeCommitMaster {
+		policy.CommitLevel = as.COMMIT_MASTER
+	}
+	applyTimeouts(cfg, &policy.BasePolicy)
+	return policy
+}
+
+func applyTimeouts(cfg config.Aerospike, policy *as.BasePolicy) {
+	if cfg.SocketTimeoutMillis > 0 {
+		policy.SocketTimeout = time.Duration(cfg.SocketTimeoutMillis) * time.Millisecond
+	}
+	if cfg.TotalTimeoutMillis > 0 {
+		policy.TotalTimeout = time.Duration(cfg.TotalTimeoutMillis) * time.Millisecond
+	}
+}
+
 func formatAerospikeError(err error) error {
 	if err != nil {
 		if aerr, ok := err.(as_types.AerospikeError); ok {
-			if aerr.ResultCode() == as_types.KEY_NOT_FOUND_ERROR {
+			switch aerr.ResultCode() {
+			case as_types.KEY_NOT_FOUND_ERROR:
 				return utils.KeyNotFoundError{}
+			case as_types.TIMEOUT:
+				return utils.NewBackendError(utils.Timeout, errors.New(err.Error()))
+			case as_types.RECORD_TOO_BIG:
+				return utils.NewBackendError(utils.ValueTooLarge, errors.New(err.Error()))
+			case as_types.KEY_EXISTS_ERROR, as_types.GENERATION_ERROR:
+				return utils.NewBackendError(utils.Conflict, errors.New(err.Error()))
 			}
 		}
 		return errors.New(err.Error())
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	as "github.com/aerospike/aerospike-client-go"
 	as_types "github.com/aerospike/aerospike-client-go/types"
@@ -33,7 +34,7 @@ func (c *errorProneAerospikeClient) NewUuidKey(namespace string, key string) (*a
 	return nil, nil
 }
 
-func (c *errorProneAerospikeClient) Get(key *as.Key) (*as.Record, error) {
+func (c *errorProneAerospikeClient) Get(policy *as.BasePolicy, key *as.Key) (*as.Record, error) {
 	if c.errorThrowingFunction == "TEST_GET_ERROR" {
 		return nil, as_types.NewAerospikeError(as_types.KEY_NOT_FOUND_ERROR)
 	} else if c.errorThrowingFunction == "TEST_NO_BUCKET_ERROR" {
@@ -66,7 +67,7 @@ func NewGoodAerospikeClient() *goodAerospikeClient {
 	}
 }
 
-func (c *goodAerospikeClient) Get(aeKey *as.Key) (*as.Record, error) {
+func (c *goodAerospikeClient) Get(policy *as.BasePolicy, aeKey *as.Key) (*as.Record, error) {
 	if aeKey != nil && aeKey.Value() != nil {
 
 		key := aeKey.Value().String()
@@ -358,3 +359,74 @@ func TestClientPut(t *testing.T) {
 		}
 	}
 }
+
+// Mock Aerospike client that remembers the policies of the last operations
+type policyRecordingAerospikeClient struct {
+	*goodAerospikeClient
+	readPolicy  *as.BasePolicy
+	writePolicy *as.WritePolicy
+}
+
+func (c *policyRecordingAerospikeClient) Get(policy *as.BasePolicy, aeKey *as.Key) (*as.Record, error) {
+	c.readPolicy = policy
+	return c.goodAerospikeClient.Get(policy, aeKey)
+}
+
+func (c *policyRecordingAerospikeClient) Put(policy *as.WritePolicy, aeKey *as.Key, binMap as.BinMap) error {
+	c.writePolicy = policy
+	return c.goodAerospikeClient.Put(policy, aeKey, binMap)
+}
+
+func TestAerospikePolicies(t *testing.T) {
+	testCases := []struct {
+		desc                  string
+		inCfg                 config.Aerospike
+		expectDefaultRead     bool
+		expectedSocketTimeout time.Duration
+		expectedTotalTimeout  time.Duration
+		expectedCommitLevel   as.CommitLevel
+	}{
+		{
+			desc:                "Defaults keep the client's read policy, and writes without limits to every replica",
+			inCfg:               config.Aerospike{CommitLevel: config.AerospikeCommitAll},
+			expectDefaultRead:   true,
+			expectedCommitLevel: as.COMMIT_ALL,
+		},
+		{
+			desc:                  "Configured timeouts and commit level",
+			inCfg:                 config.Aerospike{CommitLevel: config.AerospikeCommitMaster, SocketTimeoutMillis: 100, TotalTimeoutMillis: 250},
+			expectedSocketTimeout: 100 * time.Millisecond,
+			expectedTotalTimeout:  250 * time.Millisecond,
+			expectedCommitLevel:   as.COMMIT_MASTER,
+		},
+		{
+			desc:                 "Total timeout alone",
+			inCfg:                config.Aerospike{TotalTimeoutMillis: 250},
+			expectedTotalTimeout: 250 * time.Millisecond,
+			expectedCommitLevel:  as.COMMIT_ALL,
+		},
+	}
+
+	for _, tc := range testCases {
+		client := &policyRecordingAerospikeClient{goodAerospikeClient: NewGoodAerospikeClient()}
+		backend := &AerospikeBackend{cfg: tc.inCfg, client: client, metrics: metricstest.CreateMockMetrics()}
+
+		assert.NoError(t, backend.Put(context.Background(), "key", "value", 60), tc.desc)
+		backend.Get(context.Background(), "key")
+
+		if assert.NotNil(t, client.writePolicy, tc.desc) {
+			assert.Equal(t, uint32(60), client.writePolicy.Expiration, tc.desc)
+			assert.Equal(t, tc.expectedCommitLevel, client.writePolicy.CommitLevel, tc.desc)
+			assert.Equal(t, tc.expectedSocketTimeout, client.writePolicy.SocketTimeout, tc.desc)
+			assert.Equal(t, tc.expectedTotalTimeout, client.writePolicy.TotalTimeout, tc.desc)
+		}
+		if tc.expectDefaultRead {
+			assert.Nil(t, client.readPolicy, tc.desc)
+		} else if assert.NotNil(t, client.readPolicy, tc.desc) {
+			assert.Equal(t, tc.expectedTotalTimeout, client.readPolicy.TotalTimeout, tc.desc)
+			if tc.expectedSocketTimeout > 0 {
+				assert.Equal(t, tc.expectedSocketTimeout, client.readPolicy.SocketTimeout, tc.desc)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// azureBlobExpiresAtMetadata is the metadata every blob put with a TTL carries its expiration in, as a
+// unix time in seconds. Metadata names must be valid C# identifiers, and come back in the canonical
+// form of HTTP headers.
+const azureBlobExpiresAtMetadata = "expires_at"
+
+// AzureBlobClient is an interface that helps us communicate with Azure Blob Storage. The client of the
+// Azure SDK implements it.
+type AzureBlobClient interface {
+	DownloadStream(ctx context.Context, containerName string, blobName string, o *azblob.DownloadStreamOptions) (azblob.DownloadStreamResponse, error)
+	UploadBuffer(ctx context.Context, containerName string, blobName string, buffer []byte, o *azblob.UploadBufferOptions) (azblob.UploadBufferResponse, error)
+}
+
+// AzureBlobBackend stores every value as a block blob of a container. Lifecycle rules only expire blobs
+// in whole days, so the expiration of every value is stored along with it and values past it are
+// reported missing until a lifecycle rule deletes them.
+type AzureBlobBackend struct {
+	cfg    config.AzureBlob
+	client AzureBlobClient
+}
+
+// NewAzureBlobBackend creates the Azure Blob Storage client and makes sure the configured container can
+// be reached
+func NewAzureBlobBackend(cfg config.AzureBlob) *AzureBlobBackend {
+	client, err := newAzureBlobClient(cfg)
+	if err != nil {
+		log.Fatalf("Error creating Azure Blob backend: %v", err)
+		panic("AzureBlobBackend failure. This shouldn't happen.")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.ServiceClient().NewContainerClient(cfg.Container).GetProperties(ctx, nil); err != nil {
+		log.Fatalf("Error creating Azure Blob backend: container %s can't be reached: %v", cfg.Container, err)
+		panic("AzureBlobBackend failure. This shouldn't happen.")
+	}
+	log.Infof("Connected to Azure Blob container %s of account %s", cfg.Container, cfg.Account)
+
+	return &AzureBlobBackend{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// newAzureBlobClient authenticates with the account key if there's one, or with the managed identity
+// of the host otherwise
+func newAzureBlobClient(cfg config.AzureBlob) (*azblob.Client, error) {
+	serviceURL := cfg.Endpoint
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.Account)
+	}
+
+	if cfg.AccountKey != "" {
+		cred, err := azblob.NewSharedKeyCredential(cfg.Account, cfg.AccountKey)
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	}
+
+	var opts *azidentity.ManagedIdentityCredentialOptions
+	if cfg.ManagedIdentityClientID != "" {
+		opts = &azidentity.ManagedIdentityCredentialOptions{ID: azidentity.ClientID(cfg.ManagedIdentityClientID)}
+	}
+	cred, err := azidentity.NewManagedIdentityCredential(opts)
+	if err != nil {
+		return nil, err
+	}
+	return azblob.NewClient(serviceURL, cred, nil)
+}
+
+func (b *AzureBlobBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	out, err := b.client.DownloadStream(ctx, b.cfg.Container, b.blobName(key), nil)
+	if err != nil {
+		return "", classifyAzureBlobError(err)
+	}
+	defer out.Body.Close()
+
+	if azureBlobExpired(out.Metadata, time.Now()) {
+		return "", utils.KeyNotFoundError{}
+	}
+
+	value, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// Put stores the value along with its expiration, overwriting any blob of the same name
+func (b *AzureBlobBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	opts := &azblob.UploadBufferOptions{}
+	if ttlSeconds > 0 {
+		expiresAt := strconv.FormatInt(time.Now().Add(time.Duration(ttlSeconds)*time.Second).Unix(), 10)
+		opts.Metadata = map[string]*string{azureBlobExpiresAtMetadata: &expiresAt}
+	}
+
+	if _, err := b.client.UploadBuffer(ctx, b.cfg.Container, b.blobName(key), []byte(value), opts); err != nil {
+		return classifyAzureBlobError(err)
+	}
+	return nil
+}
+
+// blobName returns the name of the blob that stores the value of key
+func (b *AzureBlobBackend) blobName(key string) string {
+	if b.cfg.Prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(b.cfg.Prefix, "/") + "/" + key
+}
+
+// azureBlobExpired tells whether the blob with the given metadata is past its expiration. Blobs stored
+// without a TTL never expire.
+func azureBlobExpired(metadata map[string]*string, now time.Time) bool {
+	for name, value := range metadata {
+		if !strings.EqualFold(name, azureBlobExpiresAtMetadata) || value == nil {
+			continue
+		}
+		expiresAt, err := strconv.ParseInt(*value, 10, 64)
+		return err == nil && !now.Before(time.Unix(expiresAt, 0))
+	}
+	return false
+}
+
+// classifyAzureBlobError maps the errors of the Azure SDK to their error code. A missing container is
+// returned as is, since it makes every request fail rather than a single key.
+func classifyAzureBlobError(err error) error {
+	switch {
+	case bloberror.HasCode(err, bloberror.BlobNotFound):
+		return utils.KeyNotFoundError{}
+	case bloberror.HasCode(err, bloberror.RequestBodyTooLarge):
+		return utils.NewBackendError(utils.ValueTooLarge, err)
+	case bloberror.HasCode(err, bloberror.OperationTimedOut):
+		return utils.NewBackendError(utils.Timeout, err)
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAzureBlob struct {
+	value    string
+	metadata map[string]*string
+}
+
+type fakeAzureBlobPut struct {
+	container string
+	name      string
+	metadata  map[string]*string
+}
+
+// Mock Azure Blob client that keeps its blobs in memory
+type fakeAzureBlobClient struct {
+	blobs  map[string]fakeAzureBlob
+	puts   []fakeAzureBlobPut
+	getErr error
+	putErr error
+}
+
+func newFakeAzureBlobClient() *fakeAzureBlobClient {
+	return &fakeAzureBlobClient{
+		blobs: map[string]fakeAzureBlob{"values/defaultKey": {value: "Default value"}},
+	}
+}
+
+func (c *fakeAzureBlobClient) DownloadStream(ctx context.Context, containerName string, blobName string, o *azblob.DownloadStreamOptions) (azblob.DownloadStreamResponse, error) {
+	if c.getErr != nil {
+		return azblob.DownloadStreamResponse{}, c.getErr
+	}
+	stored, found := c.blobs[blobName]
+	if !found {
+		return azblob.DownloadStreamResponse{}, &azcore.ResponseError{ErrorCode: string(bloberror.BlobNotFound), StatusCode: 404}
+	}
+	return azblob.DownloadStreamResponse{
+		DownloadResponse: blob.DownloadResponse{
+			Body:     ioutil.NopCloser(strings.NewReader(stored.value)),
+			Metadata: stored.metadata,
+		},
+	}, nil
+}
+
+func (c *fakeAzureBlobClient) UploadBuffer(ctx context.Context, containerName string, blobName string, buffer []byte, o *azblob.UploadBufferOptions) (azblob.UploadBufferResponse, error) {
+	if c.putErr != nil {
+		return azblob.UploadBufferResponse{}, c.putErr
+	}
+	c.blobs[blobName] = fakeAzureBlob{value: string(buffer), metadata: o.Metadata}
+	c.puts = append(c.puts, fakeAzureBlobPut{container: containerName, name: blobName, metadata: o.Metadata})
+	return azblob.UploadBufferResponse{}, nil
+}
+
+func TestAzureBlobGet(t *testing.T) {
+	expiredAt := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	notExpiredAt := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	timeoutErr := &azcore.ResponseError{ErrorCode: string(bloberror.OperationTimedOut), StatusCode: 500}
+
+	testCases := []struct {
+		desc          string
+		inKey         string
+		inBlobs       map[string]fakeAzureBlob
+		getErr        error
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:          "AzureBlobBackend.Get() key found",
+			inKey:         "defaultKey",
+			expectedValue: "Default value",
+		},
+		{
+			desc:        "AzureBlobBackend.Get() BlobNotFound",
+			inKey:       "unknownKey",
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:  "AzureBlobBackend.Get() value past its expiration",
+			inKey: "expiredKey",
+			inBlobs: map[string]fakeAzureBlob{
+				"values/expiredKey": {value: "Expired value", metadata: map[string]*string{"Expires_at": &expiredAt}},
+			},
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:  "AzureBlobBackend.Get() value within its expiration",
+			inKey: "freshKey",
+			inBlobs: map[string]fakeAzureBlob{
+				"values/freshKey": {value: "Fresh value", metadata: map[string]*string{"Expires_at": &notExpiredAt}},
+			},
+			expectedValue: "Fresh value",
+		},
+		{
+			desc:        "AzureBlobBackend.Get() operation timed out",
+			inKey:       "defaultKey",
+			getErr:      timeoutErr,
+			expectedErr: utils.NewBackendError(utils.Timeout, timeoutErr),
+		},
+		{
+			desc:        "AzureBlobBackend.Get() other Azure error",
+			inKey:       "defaultKey",
+			getErr:      errors.New("connection refused"),
+			expectedErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeAzureBlobClient()
+		for name, stored := range tc.inBlobs {
+			client.blobs[name] = stored
+		}
+		client.getErr = tc.getErr
+		backend := &AzureBlobBackend{cfg: config.AzureBlob{Container: "prebid-cache", Prefix: "values/"}, client: client}
+
+		value, err := backend.Get(context.Background(), tc.inKey)
+
+		assert.Equal(t, tc.expectedValue, value, tc.desc)
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+	}
+}
+
+func TestAzureBlobPut(t *testing.T) {
+	tooLargeErr := &azcore.ResponseError{ErrorCode: string(bloberror.RequestBodyTooLarge), StatusCode: 413}
+
+	testCases := []struct {
+		desc             string
+		inPrefix         string
+		inTTLSeconds     int
+		putErr           error
+		expectedBlobName string
+		expectedExpires  bool
+		expectedErr      error
+	}{
+		{
+			desc:             "AzureBlobBackend.Put() with a TTL",
+			inPrefix:         "values/",
+			inTTLSeconds:     60,
+			expectedBlobName: "values/someKey",
+			expectedExpires:  true,
+		},
+		{
+			desc:             "AzureBlobBackend.Put() without a TTL",
+			inPrefix:         "values",
+			expectedBlobName: "values/someKey",
+		},
+		{
+			desc:             "AzureBlobBackend.Put() without a prefix",
+			inTTLSeconds:     60,
+			expectedBlobName: "someKey",
+			expectedExpires:  true,
+		},
+		{
+			desc:         "AzureBlobBackend.Put() value too large",
+			inPrefix:     "values/",
+			inTTLSeconds: 60,
+			putErr:       tooLargeErr,
+			expectedErr:  utils.NewBackendError(utils.ValueTooLarge, tooLargeErr),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeAzureBlobClient()
+		client.putErr = tc.putErr
+		backend := &AzureBlobBackend{cfg: config.AzureBlob{Container: "prebid-cache", Prefix: tc.inPrefix}, client: client}
+
+		err := backend.Put(context.Background(), "someKey", "someValue", tc.inTTLSeconds)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		if tc.expectedErr != nil {
+			continue
+		}
+		if assert.Len(t, client.puts, 1, tc.desc) {
+			put := client.puts[0]
+			assert.Equal(t, "prebid-cache", put.container, tc.desc)
+			assert.Equal(t, tc.expectedBlobName, put.name, tc.desc)
+			if tc.expectedExpires {
+				if assert.NotNil(t, put.metadata[azureBlobExpiresAtMetadata], tc.desc) {
+					expiresAt, err := strconv.ParseInt(*put.metadata[azureBlobExpiresAtMetadata], 10, 64)
+					assert.NoError(t, err, tc.desc)
+					assert.WithinDuration(t, time.Now().Add(time.Duration(tc.inTTLSeconds)*time.Second), time.Unix(expiresAt, 0), 2*time.Second, tc.desc)
+				}
+			} else {
+				assert.Empty(t, put.metadata, tc.desc)
+			}
+		}
+
+		value, err := backend.Get(context.Background(), "someKey")
+		assert.NoError(t, err, tc.desc)
+		assert.Equal(t, "someValue", value, tc.desc)
+	}
+}
+
+func TestAzureBlobCancelled(t *testing.T) {
+	client := newFakeAzureBlobClient()
+	backend := &AzureBlobBackend{cfg: config.AzureBlob{Container: "prebid-cache", Prefix: "values/"}, client: client}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := backend.Get(ctx, "defaultKey")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, backend.Put(ctx, "someKey", "someValue", 60))
+	assert.Empty(t, client.puts)
+}
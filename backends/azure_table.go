@@ -13,6 +13,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/prebid/prebid-cache/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 )
@@ -106,6 +107,9 @@ func (c *AzureTableBackend) Send(ctx context.Context, req *fasthttp.Request, res
 }
 
 func (c *AzureTableBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
 	if key == "" {
 		return "", fmt.Errorf("Invalid Key")
@@ -126,7 +130,7 @@ func (c *AzureTableBackend) Get(ctx context.Context, key string) (string, error)
 	err := c.Send(ctx, req, resp, "docs", resourceLink[1:])
 	if err != nil {
 		log.Debugf("Failed to make request")
-		return "", err
+		return "", classifyAzureError(err)
 	}
 
 	av := AzureValue{}
@@ -138,13 +142,16 @@ func (c *AzureTableBackend) Get(ctx context.Context, key string) (string, error)
 
 	if av.Value == "" {
 		log.Debugf("Response had empty value: %v", av)
-		return "", fmt.Errorf("Key not found")
+		return "", utils.KeyNotFoundError{}
 	}
 
 	return av.Value, nil
 }
 
 func (c *AzureTableBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	if key == "" {
 		return fmt.Errorf("Invalid Key")
@@ -181,10 +188,39 @@ func (c *AzureTableBackend) Put(ctx context.Context, key string, value string, t
 	if err != nil {
 		return err
 	}
-	err = c.Send(ctx, req, resp, "docs", "dbs/prebidcache/colls/cache")
+	if err := c.Send(ctx, req, resp, "docs", "dbs/prebidcache/colls/cache"); err != nil {
+		return classifyAzureError(err)
+	}
+	return azureStatusError(resp.StatusCode())
+}
+
+// classifyAzureError maps the errors of requests that got no response to their error code
+func classifyAzureError(err error) error {
+	if err == fasthttp.ErrTimeout {
+		return utils.NewBackendError(utils.Timeout, err)
+	}
 	return err
 }
 
+// azureStatusError returns the error for the status Azure responded to a write with, if it's not a
+// successful one
+func azureStatusError(status int) error {
+	if status < fasthttp.StatusMultipleChoices {
+		return nil
+	}
+
+	err := fmt.Errorf("Azure responded with status %d", status)
+	switch status {
+	case fasthttp.StatusConflict:
+		return utils.NewBackendError(utils.Conflict, err)
+	case fasthttp.StatusRequestEntityTooLarge:
+		return utils.NewBackendError(utils.ValueTooLarge, err)
+	case fasthttp.StatusRequestTimeout:
+		return utils.NewBackendError(utils.Timeout, err)
+	}
+	return utils.NewBackendError(utils.BackendUnavailable, err)
+}
+
 func (c *AzureTableBackend) makePartitionKey(objectKey string) string {
 	end := len(objectKey)
 	if end > 4 {
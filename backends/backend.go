@@ -9,3 +9,59 @@ type Backend interface {
 	Put(ctx context.Context, key string, value string, ttlSeconds int) error
 	Get(ctx context.Context, key string) (string, error)
 }
+
+// KeyScanner is implemented by the backends able to list the keys they hold, so that stored values can
+// be walked through in the background.
+type KeyScanner interface {
+	// ScanKeys returns up to count keys starting at cursor, along with the cursor the next call should
+	// start at. Scans start at cursor 0 and are over once the returned cursor is 0 again.
+	ScanKeys(ctx context.Context, cursor uint64, count int) ([]string, uint64, error)
+}
+
+// ConditionalPutter is implemented by the backends able to store a value only if its key doesn't hold
+// one yet, as a single operation.
+type ConditionalPutter interface {
+	// PutIfAbsent stores the value like Put does, unless key already holds a value that hasn't expired,
+	// in which case it returns a utils.KeyExistsError and leaves that value untouched.
+	PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) error
+}
+
+// VersionReporter is implemented by the backends able to tell which version of the server they're
+// connected to, so that the features that server is too old for can be turned off at startup.
+type VersionReporter interface {
+	ServerVersion(ctx context.Context) (string, error)
+}
+
+// ConnectionPoolReporter is implemented by the backends able to tell how busy the pool of connections
+// to their server is.
+type ConnectionPoolReporter interface {
+	// ConnectionPool returns the current stats of the pool, or false if the client doesn't report them
+	ConnectionPool() (ConnectionPoolStats, bool)
+}
+
+// ConnectionPoolStats are the connections a backend holds to its server. Pending is negative when the
+// client doesn't track the operations waiting for a connection.
+type ConnectionPoolStats struct {
+	// Active connections are the ones running an operation
+	Active int
+	// Size is the number of connections open, whether active or idle
+	Size    int
+	Pending int
+}
+
+// Batcher is implemented by the backends able to send many operations to their server in a single round
+// trip.
+type Batcher interface {
+	// GetBatch returns the values of keys in the same order, along with the error Get would have
+	// returned for each of them.
+	GetBatch(ctx context.Context, keys []string) ([]string, []error)
+	// PutBatch stores every value like Put does, and returns the error of each of them in the same order.
+	PutBatch(ctx context.Context, puts []BatchPut) []error
+}
+
+// BatchPut is a value stored through Batcher.PutBatch
+type BatchPut struct {
+	Key        string
+	Value      string
+	TTLSeconds int
+}
@@ -0,0 +1,114 @@
+package backends
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	as "github.com/aerospike/aerospike-client-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueFormat(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inValue        string
+		expectedFormat string
+	}{
+		{"XML value", "xml<tag/>", XML_PREFIX},
+		{"JSON value", `json{"field":"value"}`, JSON_PREFIX},
+		{"Prefix alone", "json", JSON_PREFIX},
+		{"Unknown prefix", "yaml: value", ""},
+		{"Empty value", "", ""},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedFormat, ValueFormat(tc.inValue), tc.desc)
+	}
+}
+
+// untouchableAerospikeClient flags any call that made it to the Aerospike client
+type untouchableAerospikeClient struct {
+	called bool
+}
+
+func (c *untouchableAerospikeClient) NewUuidKey(namespace string, key string) (*as.Key, error) {
+	c.called = true
+	return nil, nil
+}
+
+func (c *untouchableAerospikeClient) Get(policy *as.BasePolicy, key *as.Key) (*as.Record, error) {
+	c.called = true
+	return nil, nil
+}
+
+func (c *untouchableAerospikeClient) Put(policy *as.WritePolicy, key *as.Key, binMap as.BinMap) error {
+	c.called = true
+	return nil
+}
+
+// untouchableCouchbaseClient flags any call that made it to the Couchbase collection
+type untouchableCouchbaseClient struct {
+	called bool
+}
+
+func (c *untouchableCouchbaseClient) Get(ctx context.Context, key string) (string, error) {
+	c.called = true
+	return "", nil
+}
+
+func (c *untouchableCouchbaseClient) Upsert(ctx context.Context, key string, value string, expiry time.Duration) error {
+	c.called = true
+	return nil
+}
+
+func TestDoneContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	contexts := []struct {
+		desc        string
+		ctx         context.Context
+		expectedErr error
+	}{
+		{"canceled context", canceled, context.Canceled},
+		{"expired context", expired, context.DeadlineExceeded},
+	}
+
+	for _, c := range contexts {
+		aerospikeClient := &untouchableAerospikeClient{}
+		couchbaseClient := &untouchableCouchbaseClient{}
+		testCases := []struct {
+			desc          string
+			backend       Backend
+			clientWasUsed func() bool
+		}{
+			{
+				desc:          "Aerospike",
+				backend:       &AerospikeBackend{client: aerospikeClient},
+				clientWasUsed: func() bool { return aerospikeClient.called },
+			},
+			{
+				desc:          "Couchbase",
+				backend:       &CouchbaseBackend{client: couchbaseClient},
+				clientWasUsed: func() bool { return couchbaseClient.called },
+			},
+			{
+				desc:          "Memory",
+				backend:       NewMemoryBackend(),
+				clientWasUsed: func() bool { return false },
+			},
+		}
+
+		for _, tc := range testCases {
+			_, getErr := tc.backend.Get(c.ctx, "key")
+			putErr := tc.backend.Put(c.ctx, "key", "value", 0)
+
+			assert.Equal(t, c.expectedErr, getErr, "%s Get with %s", tc.desc, c.desc)
+			assert.Equal(t, c.expectedErr, putErr, "%s Put with %s", tc.desc, c.desc)
+			assert.False(t, tc.clientWasUsed(), "%s should not call its client with %s", tc.desc, c.desc)
+		}
+	}
+}
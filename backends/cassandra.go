@@ -2,16 +2,57 @@ package backends
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
 	log "github.com/sirupsen/logrus"
 )
 
+// The statements of every Get and Put. gocql prepares a statement the first time a session runs it and
+// reuses it after, as long as its text stays the same, so values must be bound rather than spliced in.
+const (
+	cassandraSelect = `SELECT value FROM cache WHERE key = ? LIMIT 1`
+	cassandraInsert = `INSERT INTO cache (key, value) VALUES (?, ?) USING TTL ?`
+)
+
+// CassandraDB is an interface that helps us communicate with a Cassandra cluster. Queries run the given
+// statement under the given context, so they're abandoned as soon as it's done.
+type CassandraDB interface {
+	Get(ctx context.Context, stmt string, key string) (string, error)
+	Put(ctx context.Context, stmt string, key string, value string, ttlSeconds int) error
+}
+
+// CassandraDBClient is a wrapper for the gocql session, which is shared by every query
+type CassandraDBClient struct {
+	session *gocql.Session
+}
+
+func (db CassandraDBClient) Get(ctx context.Context, stmt string, key string) (string, error) {
+	query := db.session.Query(stmt, key).Consistency(gocql.One)
+	// Queries come from a pool of gocql, which they go back to once they've run
+	defer query.Release()
+
+	var res string
+	err := query.WithContext(ctx).Scan(&res)
+
+	return res, err
+}
+
+func (db CassandraDBClient) Put(ctx context.Context, stmt string, key string, value string, ttlSeconds int) error {
+	query := db.session.Query(stmt, key, value, ttlSeconds)
+	defer query.Release()
+
+	return query.WithContext(ctx).Exec()
+}
+
 // Cassandra Object use to implement backend interface
 type Cassandra struct {
 	cluster *gocql.ClusterConfig
-	session *gocql.Session
+	client  CassandraDB
 }
 
 // NewCassandraBackend create a new cassandra backend
@@ -19,36 +60,107 @@ func NewCassandraBackend(cfg config.Cassandra) *Cassandra {
 	var err error
 
 	c := &Cassandra{}
-	c.cluster = gocql.NewCluster(cfg.Hosts)
-	c.cluster.Keyspace = cfg.Keyspace
-	c.cluster.Consistency = gocql.LocalOne
+	c.cluster, err = newCassandraCluster(cfg)
+	if err != nil {
+		log.Fatalf("Error creating Cassandra backend: %v", err)
+		panic("Cassandra failure. This shouldn't happen.")
+	}
 
-	c.session, err = c.cluster.CreateSession()
+	session, err := c.cluster.CreateSession()
 	if err != nil {
 		log.Fatalf("Error creating Cassandra backend: %v", err)
 		panic("Cassandra failure. This shouldn't happen.")
 	}
+	c.client = CassandraDBClient{session: session}
 
 	return c
 }
 
+// newCassandraCluster builds the configuration sessions get created from. The files TLS points to are
+// only loaded by gocql once connecting, so their absence gets caught here, where it reads better.
+func newCassandraCluster(cfg config.Cassandra) (*gocql.ClusterConfig, error) {
+	cluster := gocql.NewCluster(cfg.Hosts)
+	cluster.Keyspace = cfg.Keyspace
+	cluster.Consistency = gocql.LocalOne
+	// Left unset, the timeouts keep the gocql defaults
+	if cfg.ConnectTimeoutMillis > 0 {
+		cluster.ConnectTimeout = time.Duration(cfg.ConnectTimeoutMillis) * time.Millisecond
+	}
+	if cfg.QueryTimeoutMillis > 0 {
+		cluster.Timeout = time.Duration(cfg.QueryTimeoutMillis) * time.Millisecond
+	}
+
+	// Clusters that don't authenticate their clients ignore credentials, but they're only sent when set
+	if cfg.Username != "" && cfg.Password != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}
+	}
+
+	if cfg.TLS.Enabled {
+		files := []struct{ setting, path string }{
+			{"ca_file", cfg.TLS.CAFile},
+			{"cert_file", cfg.TLS.CertFile},
+			{"key_file", cfg.TLS.KeyFile},
+		}
+		for _, file := range files {
+			if file.path == "" {
+				continue
+			}
+			if _, err := os.Stat(file.path); err != nil {
+				return nil, fmt.Errorf("could not read config.backend.cassandra.tls.%s: %v", file.setting, err)
+			}
+		}
+		cluster.SslOpts = &gocql.SslOptions{
+			CaPath:                 cfg.TLS.CAFile,
+			CertPath:               cfg.TLS.CertFile,
+			KeyPath:                cfg.TLS.KeyFile,
+			EnableHostVerification: !cfg.TLS.InsecureSkipVerify,
+		}
+	}
+
+	return cluster, nil
+}
+
 func (c *Cassandra) Get(ctx context.Context, key string) (string, error) {
-	var res string
-	err := c.session.Query(`SELECT value FROM cache WHERE key = ? LIMIT 1`, key).
-		WithContext(ctx).
-		Consistency(gocql.One).
-		Scan(&res)
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
-	return res, err
+	res, err := c.client.Get(ctx, cassandraSelect, key)
+
+	return res, classifyCassandraError(err)
+}
+
+// DefaultTTLSeconds is the TTL of values put without one, because Cassandra would keep them forever
+func (c *Cassandra) DefaultTTLSeconds() int {
+	return 2400
 }
 
 func (c *Cassandra) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
-	if ttlSeconds == 0 {
-		ttlSeconds = 2400
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := c.client.Put(ctx, cassandraInsert, key, value, ttlSeconds)
+
+	return classifyCassandraError(err)
+}
+
+// classifyCassandraError maps the gocql errors that tell what went wrong to their error code. Others,
+// like gocql.ErrNoConnections, are returned as is and count as the cluster being unavailable.
+func classifyCassandraError(err error) error {
+	switch err.(type) {
+	case *gocql.RequestErrReadTimeout, *gocql.RequestErrWriteTimeout:
+		return utils.NewBackendError(utils.Timeout, err)
 	}
-	err := c.session.Query(`INSERT INTO cache (key, value) VALUES (?, ?) USING TTL ?`, key, value, ttlSeconds).
-		WithContext(ctx).
-		Exec()
 
+	switch err {
+	case gocql.ErrNotFound:
+		return utils.KeyNotFoundError{}
+	case gocql.ErrTimeoutNoResponse:
+		return utils.NewBackendError(utils.Timeout, err)
+	}
 	return err
 }
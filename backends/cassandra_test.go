@@ -0,0 +1,300 @@
+package backends
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCassandraTLSFiles creates empty CA, certificate and key files, which is enough for the cluster
+// config since gocql only loads them once connecting
+func writeCassandraTLSFiles(t *testing.T) (dir string, caFile string, certFile string, keyFile string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cassandra-tls")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	caFile = filepath.Join(dir, "ca.crt")
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	for _, file := range []string{caFile, certFile, keyFile} {
+		if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	return dir, caFile, certFile, keyFile
+}
+
+func TestNewCassandraCluster(t *testing.T) {
+	cluster, err := newCassandraCluster(config.Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"127.0.0.1"}, cluster.Hosts)
+	assert.Equal(t, "prebid", cluster.Keyspace)
+	assert.Equal(t, gocql.LocalOne, cluster.Consistency)
+	assert.Nil(t, cluster.SslOpts, "Plaintext connections shouldn't set SSL options")
+	assert.Nil(t, cluster.Authenticator, "Authentication should stay off without credentials")
+}
+
+func TestNewCassandraClusterTimeouts(t *testing.T) {
+	cluster, err := newCassandraCluster(config.Cassandra{Hosts: "127.0.0.1", ConnectTimeoutMillis: 1500, QueryTimeoutMillis: 250})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1500*time.Millisecond, cluster.ConnectTimeout)
+	assert.Equal(t, 250*time.Millisecond, cluster.Timeout)
+
+	defaults := gocql.NewCluster("127.0.0.1")
+	cluster, err = newCassandraCluster(config.Cassandra{Hosts: "127.0.0.1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, defaults.ConnectTimeout, cluster.ConnectTimeout, "Unset connect timeout should keep the driver's default")
+	assert.Equal(t, defaults.Timeout, cluster.Timeout, "Unset query timeout should keep the driver's default")
+}
+
+func TestNewCassandraClusterCredentials(t *testing.T) {
+	cluster, err := newCassandraCluster(config.Cassandra{Hosts: "127.0.0.1", Username: "user", Password: "secret"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, gocql.PasswordAuthenticator{Username: "user", Password: "secret"}, cluster.Authenticator)
+}
+
+func TestNewCassandraClusterTLS(t *testing.T) {
+	dir, caFile, certFile, keyFile := writeCassandraTLSFiles(t)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		desc                      string
+		inTLS                     config.CassandraTLS
+		expectedHostVerification  bool
+		expectedCertAndKeyPresent bool
+	}{
+		{
+			desc:                     "Server certificate verified against the certificate authority",
+			inTLS:                    config.CassandraTLS{Enabled: true, CAFile: caFile},
+			expectedHostVerification: true,
+		},
+		{
+			desc:                      "Client certificate",
+			inTLS:                     config.CassandraTLS{Enabled: true, CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+			expectedHostVerification:  true,
+			expectedCertAndKeyPresent: true,
+		},
+		{
+			desc:                     "Host verification skipped",
+			inTLS:                    config.CassandraTLS{Enabled: true, CAFile: caFile, InsecureSkipVerify: true},
+			expectedHostVerification: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		cluster, err := newCassandraCluster(config.Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid", TLS: tc.inTLS})
+		if !assert.NoError(t, err, tc.desc) || !assert.NotNil(t, cluster.SslOpts, tc.desc) {
+			continue
+		}
+		assert.Equal(t, caFile, cluster.SslOpts.CaPath, tc.desc)
+		assert.Equal(t, tc.expectedHostVerification, cluster.SslOpts.EnableHostVerification, tc.desc)
+		if tc.expectedCertAndKeyPresent {
+			assert.Equal(t, certFile, cluster.SslOpts.CertPath, tc.desc)
+			assert.Equal(t, keyFile, cluster.SslOpts.KeyPath, tc.desc)
+		} else {
+			assert.Empty(t, cluster.SslOpts.CertPath, tc.desc)
+			assert.Empty(t, cluster.SslOpts.KeyPath, tc.desc)
+		}
+	}
+}
+
+func TestNewCassandraClusterMissingTLSFiles(t *testing.T) {
+	dir, caFile, certFile, keyFile := writeCassandraTLSFiles(t)
+	defer os.RemoveAll(dir)
+	missingFile := filepath.Join(dir, "missing")
+
+	testCases := []struct {
+		desc             string
+		inTLS            config.CassandraTLS
+		expectedErrorMsg string
+	}{
+		{
+			desc:             "Missing certificate authority",
+			inTLS:            config.CassandraTLS{Enabled: true, CAFile: missingFile, CertFile: certFile, KeyFile: keyFile},
+			expectedErrorMsg: "config.backend.cassandra.tls.ca_file",
+		},
+		{
+			desc:             "Missing client certificate",
+			inTLS:            config.CassandraTLS{Enabled: true, CAFile: caFile, CertFile: missingFile, KeyFile: keyFile},
+			expectedErrorMsg: "config.backend.cassandra.tls.cert_file",
+		},
+		{
+			desc:             "Missing client key",
+			inTLS:            config.CassandraTLS{Enabled: true, CAFile: caFile, CertFile: certFile, KeyFile: missingFile},
+			expectedErrorMsg: "config.backend.cassandra.tls.key_file",
+		},
+	}
+
+	for _, tc := range testCases {
+		cluster, err := newCassandraCluster(config.Cassandra{Hosts: "127.0.0.1", TLS: tc.inTLS})
+		assert.Nil(t, cluster, tc.desc)
+		if assert.Error(t, err, tc.desc) {
+			assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.desc)
+		}
+	}
+}
+
+// errorProneCassandraClient fails every query with err or, if it's nil, hangs until the query's
+// context is done, like a cluster that stopped responding
+type errorProneCassandraClient struct {
+	err error
+}
+
+func (c *errorProneCassandraClient) Get(ctx context.Context, stmt string, key string) (string, error) {
+	return "", c.wait(ctx)
+}
+
+func (c *errorProneCassandraClient) Put(ctx context.Context, stmt string, key string, value string, ttlSeconds int) error {
+	return c.wait(ctx)
+}
+
+func (c *errorProneCassandraClient) wait(ctx context.Context) error {
+	if c.err != nil {
+		return c.err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCassandraTimeouts(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		inClientErr  error
+		inTimeout    time.Duration
+		expectedCode utils.ErrorCode
+	}{
+		{
+			desc:         "Query timed out by the driver",
+			inClientErr:  gocql.ErrTimeoutNoResponse,
+			inTimeout:    time.Second,
+			expectedCode: utils.Timeout,
+		},
+		{
+			desc:         "Read timed out by the cluster",
+			inClientErr:  &gocql.RequestErrReadTimeout{},
+			inTimeout:    time.Second,
+			expectedCode: utils.Timeout,
+		},
+		{
+			desc:         "Hung query abandoned once the deadline passes",
+			inTimeout:    10 * time.Millisecond,
+			expectedCode: utils.Timeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := &Cassandra{client: &errorProneCassandraClient{err: tc.inClientErr}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), tc.inTimeout)
+		_, err := backend.Get(ctx, "key")
+		if assert.Error(t, err, tc.desc) {
+			assert.Equal(t, tc.expectedCode, utils.ErrorCodeOf(err), "Get: %s", tc.desc)
+		}
+		err = backend.Put(ctx, "key", "xml<tag></tag>", 60)
+		if assert.Error(t, err, tc.desc) {
+			assert.Equal(t, tc.expectedCode, utils.ErrorCodeOf(err), "Put: %s", tc.desc)
+		}
+		cancel()
+	}
+}
+
+func TestCassandraCancelled(t *testing.T) {
+	backend := &Cassandra{client: &errorProneCassandraClient{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := backend.Get(ctx, "key")
+	assert.Equal(t, context.Canceled, err, "Get should be abandoned once the client goes away")
+
+	err = backend.Put(ctx, "key", "xml<tag></tag>", 60)
+	assert.Equal(t, context.Canceled, err, "Put shouldn't be sent once the client went away")
+}
+
+// memoryCassandraClient keeps its values in memory
+type memoryCassandraClient map[string]string
+
+func (c memoryCassandraClient) Get(ctx context.Context, stmt string, key string) (string, error) {
+	value, ok := c[key]
+	if !ok {
+		return "", gocql.ErrNotFound
+	}
+	return value, nil
+}
+
+func (c memoryCassandraClient) Put(ctx context.Context, stmt string, key string, value string, ttlSeconds int) error {
+	c[key] = value
+	return nil
+}
+
+// statementCassandraClient remembers the statements it's asked to run
+type statementCassandraClient struct {
+	memoryCassandraClient
+	statements []string
+}
+
+func (c *statementCassandraClient) Get(ctx context.Context, stmt string, key string) (string, error) {
+	c.statements = append(c.statements, stmt)
+	return c.memoryCassandraClient.Get(ctx, stmt, key)
+}
+
+func (c *statementCassandraClient) Put(ctx context.Context, stmt string, key string, value string, ttlSeconds int) error {
+	c.statements = append(c.statements, stmt)
+	return c.memoryCassandraClient.Put(ctx, stmt, key, value, ttlSeconds)
+}
+
+func TestCassandraStatements(t *testing.T) {
+	client := &statementCassandraClient{memoryCassandraClient: make(memoryCassandraClient)}
+	backend := &Cassandra{client: client}
+
+	_, err := backend.Get(context.Background(), "missing")
+	assert.IsType(t, utils.KeyNotFoundError{}, err, "Keys the select finds nothing for should be reported as not found")
+
+	assert.NoError(t, backend.Put(context.Background(), "key", "xml<tag></tag>", 60))
+	value, err := backend.Get(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "xml<tag></tag>", value)
+
+	assert.Equal(t, []string{cassandraSelect, cassandraInsert, cassandraSelect}, client.statements, "Every query should reuse the same statements")
+}
+
+func BenchmarkCassandraGet(b *testing.B) {
+	backend := &Cassandra{client: memoryCassandraClient{"key": "xml<tag></tag>"}}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		backend.Get(ctx, "key")
+	}
+}
+
+func BenchmarkCassandraPut(b *testing.B) {
+	backend := &Cassandra{client: make(memoryCassandraClient)}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		backend.Put(ctx, "key", "xml<tag></tag>", 60)
+	}
+}
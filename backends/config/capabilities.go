@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+)
+
+// capabilityCheckTimeout is how long the backend gets to report its version at startup
+const capabilityCheckTimeout = 5 * time.Second
+
+// conditionalPutMinVersions are the oldest server versions, by backend type, able to store values the
+// way the PutIfAbsent of their backend does. Backend types missing from it have no minimum.
+var conditionalPutMinVersions = map[config.BackendType]string{
+	// SET with both the EX and NX options
+	config.BackendRedis: "2.6.12",
+}
+
+// checkCapabilities asks the base backend for its server version, and turns off the features of cfg
+// that version doesn't support, or terminates the program if cfg.CapabilityCheck.FailOnUnsupported is
+// set. Features are left alone when the backend can't tell its version.
+func checkCapabilities(cfg *config.Backend, base backends.Backend) {
+	reporter, ok := base.(backends.VersionReporter)
+	if !ok {
+		log.Infof("Backend type %s can't report its version. Its capabilities won't be checked.", cfg.Type)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), capabilityCheckTimeout)
+	defer cancel()
+	version, err := reporter.ServerVersion(ctx)
+	if err != nil {
+		log.Warnf("Backend type %s failed to report its version. Its capabilities won't be checked: %v", cfg.Type, err)
+		return
+	}
+	log.Infof("Backend type %s runs version %s", cfg.Type, version)
+
+	if minVersion, ok := conditionalPutMinVersions[cfg.Type]; ok && cfg.CollisionGuard.Enabled && olderVersion(version, minVersion) {
+		if cfg.CapabilityCheck.FailOnUnsupported {
+			log.Fatalf("Backend type %s version %s can't store values conditionally, which config.backend.collision_guard needs. It takes version %s or later.", cfg.Type, version, minVersion)
+			panic("Error checking backend capabilities. This shouldn't happen.")
+		}
+		log.Warnf("Backend type %s version %s can't store values conditionally, so config.backend.collision_guard is disabled. It takes version %s or later.", cfg.Type, version, minVersion)
+		cfg.CollisionGuard.Enabled = false
+	}
+}
+
+// olderVersion tells whether the dotted version is older than minVersion. Only the leading digits of
+// every part are compared, so "7.0.0-rc1" counts as "7.0.0".
+func olderVersion(version string, minVersion string) bool {
+	parts := strings.Split(version, ".")
+	minParts := strings.Split(minVersion, ".")
+	for i, minPart := range minParts {
+		if i >= len(parts) {
+			return true
+		}
+		part, min := leadingNumber(parts[i]), leadingNumber(minPart)
+		if part != min {
+			return part < min
+		}
+	}
+	return false
+}
+
+func leadingNumber(part string) int {
+	end := 0
+	for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(part[:end])
+	return n
+}
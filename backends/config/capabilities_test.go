@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// versionedBackend is a memory backend that reports the version of a server
+type versionedBackend struct {
+	*backends.MemoryBackend
+	version string
+	err     error
+}
+
+func (b versionedBackend) ServerVersion(ctx context.Context) (string, error) {
+	return b.version, b.err
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	hook := test.NewGlobal()
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	testCases := []struct {
+		desc                   string
+		inBackend              backends.Backend
+		inFailOnUnsupported    bool
+		expectedCollisionGuard bool
+		expectedLogMsg         string
+		expectedLogLvl         logrus.Level
+	}{
+		{
+			desc:                   "Server too old to store values conditionally",
+			inBackend:              versionedBackend{MemoryBackend: backends.NewMemoryBackend(), version: "2.6.9"},
+			expectedCollisionGuard: false,
+			expectedLogMsg:         "Backend type redis version 2.6.9 can't store values conditionally, so config.backend.collision_guard is disabled. It takes version 2.6.12 or later.",
+			expectedLogLvl:         logrus.WarnLevel,
+		},
+		{
+			desc:                   "Server too old to store values conditionally, failing on unsupported features",
+			inBackend:              versionedBackend{MemoryBackend: backends.NewMemoryBackend(), version: "2.4.0"},
+			inFailOnUnsupported:    true,
+			expectedCollisionGuard: true,
+			expectedLogMsg:         "Backend type redis version 2.4.0 can't store values conditionally, which config.backend.collision_guard needs. It takes version 2.6.12 or later.",
+			expectedLogLvl:         logrus.FatalLevel,
+		},
+		{
+			desc:                   "Server recent enough",
+			inBackend:              versionedBackend{MemoryBackend: backends.NewMemoryBackend(), version: "7.0.0-rc1"},
+			expectedCollisionGuard: true,
+			expectedLogMsg:         "Backend type redis runs version 7.0.0-rc1",
+			expectedLogLvl:         logrus.InfoLevel,
+		},
+		{
+			desc:                   "Server that fails to report its version",
+			inBackend:              versionedBackend{MemoryBackend: backends.NewMemoryBackend(), err: errors.New("connection refused")},
+			expectedCollisionGuard: true,
+			expectedLogMsg:         "Backend type redis failed to report its version. Its capabilities won't be checked: connection refused",
+			expectedLogLvl:         logrus.WarnLevel,
+		},
+		{
+			desc:                   "Backend that can't report its version",
+			inBackend:              backends.NewMemoryBackend(),
+			expectedCollisionGuard: true,
+			expectedLogMsg:         "Backend type redis can't report its version. Its capabilities won't be checked.",
+			expectedLogLvl:         logrus.InfoLevel,
+		},
+	}
+
+	for _, tc := range testCases {
+		cfg := config.Backend{
+			Type:            config.BackendRedis,
+			CollisionGuard:  config.CollisionGuard{Enabled: true},
+			CapabilityCheck: config.CapabilityCheck{Enabled: true, FailOnUnsupported: tc.inFailOnUnsupported},
+		}
+
+		// checkCapabilities panics right after the fatal log, which doesn't terminate the test
+		func() {
+			defer func() { recover() }()
+			checkCapabilities(&cfg, tc.inBackend)
+		}()
+
+		assert.Equal(t, tc.expectedCollisionGuard, cfg.CollisionGuard.Enabled, tc.desc)
+		if assert.NotNil(t, hook.LastEntry(), tc.desc) {
+			assert.Equal(t, tc.expectedLogMsg, hook.LastEntry().Message, tc.desc)
+			assert.Equal(t, tc.expectedLogLvl, hook.LastEntry().Level, tc.desc)
+		}
+		hook.Reset()
+	}
+}
+
+func TestOlderVersion(t *testing.T) {
+	assert.True(t, olderVersion("2.6.11", "2.6.12"))
+	assert.True(t, olderVersion("2.6", "2.6.12"))
+	assert.True(t, olderVersion("1.10.0", "2.6.12"))
+	assert.False(t, olderVersion("2.6.12", "2.6.12"))
+	assert.False(t, olderVersion("2.10.0", "2.6.12"))
+	assert.False(t, olderVersion("10.0", "2.6.12"))
+}
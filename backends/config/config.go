@@ -1,35 +1,181 @@
 package config
 
 import (
+	"context"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/prebid/prebid-cache/backends"
 	"github.com/prebid/prebid-cache/backends/decorators"
 	"github.com/prebid/prebid-cache/compression"
 	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/envelope"
 	"github.com/prebid/prebid-cache/metrics"
 )
 
 func NewBackend(cfg config.Configuration, appMetrics *metrics.Metrics) backends.Backend {
-	backend := newBaseBackend(cfg.Backend, appMetrics)
-	backend = decorators.LimitTTLs(backend, cfg.RequestLimits.MaxTTLSeconds)
+	base := newBaseBackend(cfg.Backend, appMetrics)
+	if cfg.Backend.CapabilityCheck.Enabled {
+		checkCapabilities(&cfg.Backend, base)
+	}
+	reportConnectionPool(base, appMetrics)
+	backend := base
+	if cfg.Backend.Batching.Enabled {
+		backend = batchOperations(cfg.Backend, base, appMetrics)
+	}
+	if cfg.Backend.CollisionGuard.Enabled {
+		backend = guardCollisions(cfg.Backend, backend, base, appMetrics)
+	}
+	if cfg.Backend.VerifyWrites {
+		backend = decorators.VerifyWrites(backend, appMetrics)
+	}
+	// Inside the retries, so that operations don't hold their slot while backing off
+	if cfg.Backend.MaxConcurrency > 0 {
+		backend = decorators.LimitConcurrency(backend, cfg.Backend.MaxConcurrency, appMetrics)
+	}
+	if cfg.Standby.Enabled {
+		standby := newBaseBackend(cfg.Standby.Backend, appMetrics)
+		backend = decorators.Replicate(backend, standby, cfg.Standby, backends.DefaultTTLSeconds(standby), appMetrics)
+	}
+	if cfg.Backend.Retry.Enabled() {
+		backend = decorators.RetryTransientErrors(backend, cfg.Backend.Retry)
+	}
+	// Outside of the retries, so that an operation counts as a single failure however many times it was tried
+	if cfg.Backend.CircuitBreaker.Enabled {
+		backend = decorators.BreakCircuit(backend, cfg.Backend.CircuitBreaker, appMetrics)
+	}
+	// Inside write behind, whose queued values are served without reaching the backend
+	if cfg.Backend.CoalesceGets {
+		backend = decorators.CoalesceGets(backend)
+	}
+	// In front of the coalescing, so that only the Gets missing the near cache get coalesced
+	if cfg.Backend.NearCache.Enabled {
+		backend = nearCache(cfg.Backend.NearCache, backend, appMetrics)
+	}
+	if cfg.Backend.WriteBehind.Enabled {
+		backend = decorators.WriteBehind(backend, cfg.Backend.WriteBehind)
+	}
+	backend = applyEncryption(cfg.Encryption, backend)
 	if cfg.RequestLimits.MaxSize > 0 {
 		backend = decorators.EnforceSizeLimit(backend, cfg.RequestLimits.MaxSize)
 	}
-	// Metrics must be taken _before_ compression because it relies on the
-	// "json" or "xml" prefix on the payload. Compression might munge this.
+	// Metrics and TTL limits must be taken _before_ compression because they rely
+	// on the "json" or "xml" prefix on the payload. Compression might munge this.
 	// We should re-work this strategy at some point.
 	backend = applyCompression(cfg.Compression, backend)
+	startScrubber(cfg.Backend, base, backend, appMetrics)
+	backend = envelope.Versioned(backend, byte(cfg.Backend.ValueVersion))
+	backend = decorators.ApplyTTLRules(backend, cfg.RequestLimits, backends.DefaultTTLSeconds(base), appMetrics)
 	backend = decorators.LogMetrics(backend, appMetrics)
+	if cfg.Tracing.ExportsSpans() {
+		backend = decorators.TraceOperations(backend, cfg.Backend.Type)
+	}
+	// Every other decorator, the standby backend included, sees the prefixed keys
+	if cfg.Backend.KeyPrefix != "" {
+		backend = decorators.NamespaceKeys(backend, cfg.Backend.KeyPrefix)
+	}
+	if cfg.Priming.ServeKeys {
+		return exposeKeys(cfg.Backend, base, backend)
+	}
+	return backend
+}
+
+// scannableBackend lists the keys of the base backend next to the decorated one, for the admin server
+// to serve them
+type scannableBackend struct {
+	backends.Backend
+	backends.KeyScanner
+}
+
+// exposeKeys returns the decorated backend along with the keys of the base one, if it's able to list them.
+// Keys are listed without their prefix, the way clients of the decorated backend use them.
+func exposeKeys(cfg config.Backend, base backends.Backend, decorated backends.Backend) backends.Backend {
+	scanner, ok := base.(backends.KeyScanner)
+	if !ok {
+		log.Infof("Backend type %s can't list its keys. Other instances won't be able to prime from this one.", cfg.Type)
+		return decorated
+	}
+	if cfg.KeyPrefix != "" {
+		scanner = decorators.ScanNamespace(scanner, cfg.KeyPrefix, true)
+	}
+	return scannableBackend{Backend: decorated, KeyScanner: scanner}
+}
+
+// startScrubber starts scrubbing the stored values in the background if enabled and supported by the
+// base backend. The stored backend is the one values get read from, which must not decode envelopes.
+// Only the values of the key prefix get scrubbed, since other prefixes may store other value versions.
+func startScrubber(cfg config.Backend, base backends.Backend, stored backends.Backend, appMetrics *metrics.Metrics) {
+	if !cfg.Scrubber.Enabled {
+		return
+	}
+	scanner, ok := base.(backends.KeyScanner)
+	if !ok {
+		log.Infof("Backend type %s can't list its keys. Its values won't be scrubbed.", cfg.Type)
+		return
+	}
+	if cfg.KeyPrefix != "" {
+		scanner = decorators.ScanNamespace(scanner, cfg.KeyPrefix, false)
+	}
+	go envelope.NewScrubber(scanner, stored, appMetrics, cfg.Scrubber).Run(context.Background())
+}
+
+// batchOperations sends Puts and Gets to the base backend in batches, if it's able to run them
+func batchOperations(cfg config.Backend, base backends.Backend, appMetrics *metrics.Metrics) backends.Backend {
+	batcher, ok := base.(backends.Batcher)
+	if !ok {
+		log.Infof("Backend type %s can't run operations in batches. Its Puts and Gets won't be batched.", cfg.Type)
+		return base
+	}
+	return decorators.BatchOperations(batcher, cfg.Batching, appMetrics)
+}
+
+// guardCollisions stores values conditionally through the base backend, which config validation ensures
+// is able to, and any other value through the delegate
+func guardCollisions(cfg config.Backend, delegate backends.Backend, base backends.Backend, appMetrics *metrics.Metrics) backends.Backend {
+	conditional, ok := base.(backends.ConditionalPutter)
+	if !ok {
+		log.Fatalf("Backend type %s can't store values conditionally, which config.backend.collision_guard needs.", cfg.Type)
+		panic("Error guarding against key collisions. This shouldn't happen.")
+	}
+	return decorators.GuardCollisions(delegate, conditional, appMetrics)
+}
+
+// nearCacheShards splits the near cache like the memory backend does, so that concurrent Gets of
+// different keys rarely wait on each other
+const nearCacheShards = 16
+
+func nearCache(cfg config.NearCache, remote backends.Backend, appMetrics *metrics.Metrics) backends.Backend {
+	near := backends.NewBoundedMemoryBackend(config.Memory{MaxEntries: cfg.MaxEntries, MaxBytes: cfg.MaxBytes, Shards: nearCacheShards})
+	return decorators.Tiered(near, remote, cfg, appMetrics)
+}
+
+// applyEncryption encrypts values right before they're stored, past compression, since encrypted values
+// don't compress
+func applyEncryption(cfg config.Encryption, backend backends.Backend) backends.Backend {
+	if !cfg.Enabled {
+		return backend
+	}
+	key, err := cfg.LoadKey()
+	if err == nil {
+		backend, err = decorators.Encrypt(backend, key)
+	}
+	if err != nil {
+		log.Fatalf("Error encrypting values: %v", err)
+		panic("Error encrypting values. This shouldn't happen.")
+	}
 	return backend
 }
 
 func applyCompression(cfg config.Compression, backend backends.Backend) backends.Backend {
 	switch cfg.Type {
 	case config.CompressionNone:
-		return backend
+		return compression.Decompress(backend)
+	case config.CompressionGzip:
+		return compression.GzipCompress(backend)
 	case config.CompressionSnappy:
 		return compression.SnappyCompress(backend)
+	case config.CompressionZstd:
+		return compression.ZstdCompress(backend)
 	default:
 		log.Fatalf("Unknown compression type: %s", cfg.Type)
 	}
@@ -37,12 +183,64 @@ func applyCompression(cfg config.Compression, backend backends.Backend) backends
 	panic("Error applying compression. This shouldn't happen.")
 }
 
+// newMemoryBackend hooks the eviction metrics and logs to the memory backend, if enabled
+func newMemoryBackend(cfg config.Memory, appMetrics *metrics.Metrics) *backends.MemoryBackend {
+	backend := backends.NewBoundedMemoryBackend(cfg)
+	if cfg.EvictionMetrics {
+		backend.OnEvict(func(eviction backends.Eviction) {
+			appMetrics.RecordMemoryEviction(string(eviction.Reason), eviction.Age)
+		})
+	}
+	if cfg.LogEvictions {
+		backend.OnEvict(func(eviction backends.Eviction) {
+			log.Debugf("Evicted key %s from memory (%s) after %v", eviction.Key, eviction.Reason, eviction.Age)
+		})
+	}
+	return backend
+}
+
+// reportConnectionPool has the metrics read the connection pool of the base backend, if it reports one
+func reportConnectionPool(base backends.Backend, appMetrics *metrics.Metrics) {
+	reporter, ok := base.(backends.ConnectionPoolReporter)
+	if !ok {
+		return
+	}
+	stats, ok := reporter.ConnectionPool()
+	if !ok {
+		return
+	}
+
+	read := func(stat func(backends.ConnectionPoolStats) int) func() int {
+		return func() int {
+			stats, _ := reporter.ConnectionPool()
+			return stat(stats)
+		}
+	}
+	var pending func() int
+	if stats.Pending >= 0 {
+		pending = read(func(stats backends.ConnectionPoolStats) int { return stats.Pending })
+	}
+	appMetrics.RegisterConnectionPool(
+		read(func(stats backends.ConnectionPoolStats) int { return stats.Active }),
+		read(func(stats backends.ConnectionPoolStats) int { return stats.Size }),
+		pending,
+	)
+}
+
 func newBaseBackend(cfg config.Backend, appMetrics *metrics.Metrics) backends.Backend {
 	switch cfg.Type {
 	case config.BackendCassandra:
 		return backends.NewCassandraBackend(cfg.Cassandra)
+	case config.BackendCouchbase:
+		return backends.NewCouchbaseBackend(cfg.Couchbase)
+	case config.BackendEtcd:
+		return backends.NewEtcdBackend(cfg.Etcd)
 	case config.BackendMemory:
-		return backends.NewMemoryBackend()
+		return newMemoryBackend(cfg.Memory, appMetrics)
+	case config.BackendNATS:
+		return backends.NewNATSBackend(cfg.NATS)
+	case config.BackendPostgres:
+		return backends.NewPostgresBackend(cfg.Postgres)
 	case config.BackendMemcache:
 		return backends.NewMemcacheBackend(cfg.Memcache)
 	case config.BackendAzure:
@@ -51,6 +249,10 @@ func newBaseBackend(cfg config.Backend, appMetrics *metrics.Metrics) backends.Ba
 		return backends.NewAerospikeBackend(cfg.Aerospike, appMetrics)
 	case config.BackendRedis:
 		return backends.NewRedisBackend(cfg.Redis)
+	case config.BackendS3:
+		return backends.NewS3Backend(cfg.S3)
+	case config.BackendAzureBlob:
+		return backends.NewAzureBlobBackend(cfg.AzureBlob)
 	default:
 		log.Fatalf("Unknown backend type: %s", cfg.Type)
 	}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	prometheus "github.com/prebid/prebid-cache/metrics/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEncryption(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		inEncryption    config.Encryption
+		expectPlaintext bool
+	}{
+		{
+			desc:            "Encryption disabled stores values as they are",
+			inEncryption:    config.Encryption{Key: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+			expectPlaintext: true,
+		},
+		{
+			desc:         "Encryption enabled",
+			inEncryption: config.Encryption{Enabled: true, Key: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+		},
+	}
+
+	for _, tc := range testCases {
+		base := backends.NewMemoryBackend()
+		backend := applyEncryption(tc.inEncryption, base)
+
+		assert.NoError(t, backend.Put(context.Background(), "key", "jsontrue", 0), tc.desc)
+
+		stored, _ := base.Get(context.Background(), "key")
+		assert.Equal(t, tc.expectPlaintext, stored == "jsontrue", tc.desc)
+		read, err := backend.Get(context.Background(), "key")
+		assert.NoError(t, err, tc.desc)
+		assert.Equal(t, "jsontrue", read, tc.desc)
+	}
+}
+
+func TestBatchOperationsWithoutBatcher(t *testing.T) {
+	base := backends.NewMemoryBackend()
+	cfg := config.Backend{Type: config.BackendMemory, Batching: config.Batching{Enabled: true, WindowMillis: 2, MaxSize: 100}}
+
+	backend := batchOperations(cfg, base, metricstest.CreateMockMetrics())
+
+	assert.Equal(t, base, backend, "Backends unable to run batches should be left as they are")
+}
+
+// pooledBackend reports the stats it holds as those of its connection pool
+type pooledBackend struct {
+	backends.Backend
+	stats backends.ConnectionPoolStats
+}
+
+func (b *pooledBackend) ConnectionPool() (backends.ConnectionPoolStats, bool) {
+	return b.stats, true
+}
+
+// gaugeValues returns the value of every gauge the registry of engine gathers
+func gaugeValues(t *testing.T, engine *prometheus.PrometheusMetrics) map[string]float64 {
+	t.Helper()
+	families, err := engine.Registry.Gather()
+	assert.NoError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		if gauge := family.GetMetric()[0].GetGauge(); gauge != nil {
+			values[family.GetName()] = gauge.GetValue()
+		}
+	}
+	return values
+}
+
+func TestReportConnectionPool(t *testing.T) {
+	engine := prometheus.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendRedis)
+	base := &pooledBackend{Backend: backends.NewMemoryBackend(), stats: backends.ConnectionPoolStats{Active: 3, Size: 10, Pending: 2}}
+
+	reportConnectionPool(base, &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{engine}})
+
+	gauges := gaugeValues(t, engine)
+	assert.Equal(t, float64(3), gauges["prebid_cache_backend_pool_active_connections"])
+	assert.Equal(t, float64(10), gauges["prebid_cache_backend_pool_connections"])
+	assert.Equal(t, float64(2), gauges["prebid_cache_backend_pool_pending_acquisitions"])
+
+	base.stats = backends.ConnectionPoolStats{Active: 10, Size: 10, Pending: 7}
+	gauges = gaugeValues(t, engine)
+	assert.Equal(t, float64(10), gauges["prebid_cache_backend_pool_active_connections"], "Gauges should follow the pool")
+	assert.Equal(t, float64(7), gauges["prebid_cache_backend_pool_pending_acquisitions"], "Gauges should follow the pool")
+}
+
+func TestReportConnectionPoolWithoutPendingAcquisitions(t *testing.T) {
+	engine := prometheus.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendPostgres)
+	base := &pooledBackend{Backend: backends.NewMemoryBackend(), stats: backends.ConnectionPoolStats{Active: 1, Size: 4, Pending: -1}}
+
+	reportConnectionPool(base, &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{engine}})
+
+	gauges := gaugeValues(t, engine)
+	assert.Equal(t, float64(1), gauges["prebid_cache_backend_pool_active_connections"])
+	assert.Equal(t, float64(4), gauges["prebid_cache_backend_pool_connections"])
+	assert.NotContains(t, gauges, "prebid_cache_backend_pool_pending_acquisitions", "Stats the backend can't report shouldn't be registered")
+}
+
+func TestReportConnectionPoolWithoutPool(t *testing.T) {
+	engine := prometheus.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendCassandra)
+
+	reportConnectionPool(backends.NewMemoryBackend(), &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{engine}})
+
+	gauges := gaugeValues(t, engine)
+	assert.NotContains(t, gauges, "prebid_cache_backend_pool_active_connections")
+	assert.NotContains(t, gauges, "prebid_cache_backend_pool_connections")
+	assert.NotContains(t, gauges, "prebid_cache_backend_pool_pending_acquisitions")
+}
@@ -1,7 +1,21 @@
 package backends
 
+import "strings"
+
 // These strings are prefixed onto data put in the backend, to designate its type.
 const (
 	XML_PREFIX  = "xml"
 	JSON_PREFIX = "json"
 )
+
+// ValueFormat returns the format prefix of a value put in the backend, either XML_PREFIX or
+// JSON_PREFIX, or an empty string if it has neither. The data of the value follows the prefix.
+func ValueFormat(value string) string {
+	if strings.HasPrefix(value, XML_PREFIX) {
+		return XML_PREFIX
+	}
+	if strings.HasPrefix(value, JSON_PREFIX) {
+		return JSON_PREFIX
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// CouchbaseDB is an interface that helps us communicate with a Couchbase collection
+type CouchbaseDB interface {
+	Get(ctx context.Context, key string) (string, error)
+	Upsert(ctx context.Context, key string, value string, expiry time.Duration) error
+}
+
+// CouchbaseDBClient is a wrapper for the Couchbase collection that stores our values
+type CouchbaseDBClient struct {
+	collection *gocb.Collection
+	transcoder gocb.Transcoder
+}
+
+func (db CouchbaseDBClient) Get(ctx context.Context, key string) (string, error) {
+	res, err := db.collection.Get(key, &gocb.GetOptions{Transcoder: db.transcoder, Context: ctx})
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	if err := res.Content(&value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func (db CouchbaseDBClient) Upsert(ctx context.Context, key string, value string, expiry time.Duration) error {
+	_, err := db.collection.Upsert(key, value, &gocb.UpsertOptions{Expiry: expiry, Transcoder: db.transcoder, Context: ctx})
+	return err
+}
+
+// CouchbaseBackend stores values as raw string documents of a Couchbase collection and relies on the
+// document expiry to honor the TTL of every Put request
+type CouchbaseBackend struct {
+	cfg    config.Couchbase
+	client CouchbaseDB
+}
+
+// NewCouchbaseBackend connects to the Couchbase cluster and opens the configured bucket, scope and
+// collection. Empty scope and collection names fall back to the bucket defaults.
+func NewCouchbaseBackend(cfg config.Couchbase) *CouchbaseBackend {
+	cluster, err := gocb.Connect(cfg.ConnectionString, gocb.ClusterOptions{
+		Authenticator: gocb.PasswordAuthenticator{
+			Username: cfg.Username,
+			Password: cfg.Password,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Error creating Couchbase backend: %v", err)
+		panic("CouchbaseBackend failure. This shouldn't happen.")
+	}
+
+	bucket := cluster.Bucket(cfg.Bucket)
+	if err := bucket.WaitUntilReady(5*time.Second, nil); err != nil {
+		log.Fatalf("Error creating Couchbase backend: bucket %s is not ready: %v", cfg.Bucket, err)
+		panic("CouchbaseBackend failure. This shouldn't happen.")
+	}
+
+	scope := bucket.DefaultScope()
+	if cfg.Scope != "" {
+		scope = bucket.Scope(cfg.Scope)
+	}
+	collection := scope.Collection("_default")
+	if cfg.Collection != "" {
+		collection = scope.Collection(cfg.Collection)
+	}
+	log.Infof("Connected to Couchbase bucket %s at %s", cfg.Bucket, cfg.ConnectionString)
+
+	return &CouchbaseBackend{
+		cfg: cfg,
+		client: CouchbaseDBClient{
+			collection: collection,
+			transcoder: gocb.NewRawStringTranscoder(),
+		},
+	}
+}
+
+func (c *CouchbaseBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	value, err := c.client.Get(ctx, key)
+	if err != nil {
+		return "", classifyCouchbaseError(err)
+	}
+	return value, nil
+}
+
+func (c *CouchbaseBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := c.client.Upsert(ctx, key, value, time.Duration(ttlSeconds)*time.Second); err != nil {
+		return classifyCouchbaseError(err)
+	}
+	return nil
+}
+
+// classifyCouchbaseError maps the errors of the Couchbase SDK to their error code
+func classifyCouchbaseError(err error) error {
+	switch {
+	case errors.Is(err, gocb.ErrDocumentNotFound):
+		return utils.KeyNotFoundError{}
+	case errors.Is(err, gocb.ErrTimeout), errors.Is(err, gocb.ErrUnambiguousTimeout), errors.Is(err, gocb.ErrAmbiguousTimeout):
+		return utils.NewBackendError(utils.Timeout, err)
+	case errors.Is(err, gocb.ErrValueTooLarge):
+		return utils.NewBackendError(utils.ValueTooLarge, err)
+	case errors.Is(err, gocb.ErrDocumentExists), errors.Is(err, gocb.ErrCasMismatch):
+		return utils.NewBackendError(utils.Conflict, err)
+	}
+	return err
+}
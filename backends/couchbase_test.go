@@ -0,0 +1,134 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Mock Couchbase collection that keeps its documents in memory
+type fakeCouchbaseClient struct {
+	docs   map[string]string
+	expiry map[string]time.Duration
+	putErr error
+	getErr error
+}
+
+func newFakeCouchbaseClient() *fakeCouchbaseClient {
+	return &fakeCouchbaseClient{
+		docs:   map[string]string{"defaultKey": "Default value"},
+		expiry: map[string]time.Duration{},
+	}
+}
+
+func (c *fakeCouchbaseClient) Get(ctx context.Context, key string) (string, error) {
+	if c.getErr != nil {
+		return "", c.getErr
+	}
+	if value, found := c.docs[key]; found {
+		return value, nil
+	}
+	return "", gocb.ErrDocumentNotFound
+}
+
+func (c *fakeCouchbaseClient) Upsert(ctx context.Context, key string, value string, expiry time.Duration) error {
+	if c.putErr != nil {
+		return c.putErr
+	}
+	c.docs[key] = value
+	c.expiry[key] = expiry
+	return nil
+}
+
+func TestCouchbaseGet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inKey         string
+		getErr        error
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:          "CouchbaseBackend.Get() document found",
+			inKey:         "defaultKey",
+			expectedValue: "Default value",
+		},
+		{
+			desc:        "CouchbaseBackend.Get() document not found",
+			inKey:       "unknownKey",
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:        "CouchbaseBackend.Get() collection error other than a missing document",
+			inKey:       "defaultKey",
+			getErr:      gocb.ErrTimeout,
+			expectedErr: utils.NewBackendError(utils.Timeout, gocb.ErrTimeout),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeCouchbaseClient()
+		client.getErr = tc.getErr
+		backend := &CouchbaseBackend{client: client}
+
+		value, err := backend.Get(context.Background(), tc.inKey)
+
+		assert.Equal(t, tc.expectedValue, value, tc.desc)
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+	}
+}
+
+func TestCouchbasePut(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inKey          string
+		inValue        string
+		inTTLSeconds   int
+		putErr         error
+		expectedExpiry time.Duration
+		expectedErr    error
+	}{
+		{
+			desc:           "CouchbaseBackend.Put() stores the document with the requested ttl as its expiry",
+			inKey:          "someKey",
+			inValue:        "someValue",
+			inTTLSeconds:   60,
+			expectedExpiry: time.Minute,
+		},
+		{
+			desc:           "CouchbaseBackend.Put() with a zero ttl stores a document that doesn't expire",
+			inKey:          "someKey",
+			inValue:        "someValue",
+			expectedExpiry: 0,
+		},
+		{
+			desc:         "CouchbaseBackend.Put() collection error",
+			inKey:        "someKey",
+			inValue:      "someValue",
+			inTTLSeconds: 60,
+			putErr:       errors.New("some error"),
+			expectedErr:  errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeCouchbaseClient()
+		client.putErr = tc.putErr
+		backend := &CouchbaseBackend{client: client}
+
+		err := backend.Put(context.Background(), tc.inKey, tc.inValue, tc.inTTLSeconds)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		if tc.expectedErr == nil {
+			value, getErr := backend.Get(context.Background(), tc.inKey)
+			assert.NoError(t, getErr, tc.desc)
+			assert.Equal(t, tc.inValue, value, tc.desc)
+			assert.Equal(t, tc.expectedExpiry, client.expiry[tc.inKey], tc.desc)
+		}
+	}
+}
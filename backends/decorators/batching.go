@@ -0,0 +1,165 @@
+package decorators
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+)
+
+// batchTimeout bounds the batches holding an operation without a deadline
+const batchTimeout = 5 * time.Second
+
+// BatchOperations returns a backend whose Puts and Gets wait for up to the configured window for others of
+// the same kind, and are then sent to the batcher together as a single batch. Batches that reach the max
+// size are sent right away. Every caller gets the result of its own operation, or its context error if it
+// runs out of time waiting for it.
+func BatchOperations(batcher backends.Batcher, cfg config.Batching, m *metrics.Metrics) backends.Backend {
+	return &batchingBackend{
+		batcher: batcher,
+		window:  time.Duration(cfg.WindowMillis) * time.Millisecond,
+		maxSize: cfg.MaxSize,
+		metrics: m,
+	}
+}
+
+type batchingBackend struct {
+	batcher backends.Batcher
+	window  time.Duration
+	maxSize int
+	metrics *metrics.Metrics
+
+	mu sync.Mutex
+	// gets and puts are the batches still open to new operations, if any
+	gets *batch
+	puts *batch
+}
+
+// batch holds the operations of a single kind that came in within the same window
+type batch struct {
+	keys []string
+	puts []backends.BatchPut
+	// deadline is the latest deadline of the operations in the batch, unless one of them has none
+	deadline  time.Time
+	unbounded bool
+	timer     *time.Timer
+	// values and errs are set once the batch is sent, and done is closed right after
+	values []string
+	errs   []error
+	done   chan struct{}
+}
+
+func (bt *batch) size() int {
+	return len(bt.keys) + len(bt.puts)
+}
+
+func (bt *batch) extendDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		bt.unbounded = true
+	} else if deadline.After(bt.deadline) {
+		bt.deadline = deadline
+	}
+}
+
+// context lasts for as long as the operation of the batch with the latest deadline is willing to wait.
+// It doesn't carry the values of the contexts of the operations.
+func (bt *batch) context() (context.Context, context.CancelFunc) {
+	if bt.unbounded {
+		return context.WithTimeout(context.Background(), batchTimeout)
+	}
+	return context.WithDeadline(context.Background(), bt.deadline)
+}
+
+func (bt *batch) wait(ctx context.Context, i int) (string, error) {
+	select {
+	case <-bt.done:
+		if i < len(bt.values) {
+			return bt.values[i], bt.errs[i]
+		}
+		return "", bt.errs[i]
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (b *batchingBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	bt, i := b.join(ctx, &b.gets, b.sendGets, func(bt *batch) {
+		bt.keys = append(bt.keys, key)
+	})
+	return bt.wait(ctx, i)
+}
+
+func (b *batchingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bt, i := b.join(ctx, &b.puts, b.sendPuts, func(bt *batch) {
+		bt.puts = append(bt.puts, backends.BatchPut{Key: key, Value: value, TTLSeconds: ttlSeconds})
+	})
+	_, err := bt.wait(ctx, i)
+	return err
+}
+
+// join adds an operation to the open batch in slot, opening a new one if there's none, and returns that
+// batch along with the index of the operation in it. The operation that fills the batch sends it.
+func (b *batchingBackend) join(ctx context.Context, slot **batch, send func(*batch), add func(*batch)) (*batch, int) {
+	b.mu.Lock()
+	bt := *slot
+	if bt == nil {
+		bt = &batch{done: make(chan struct{})}
+		bt.timer = time.AfterFunc(b.window, func() { b.flush(slot, bt, send) })
+		*slot = bt
+	}
+	i := bt.size()
+	add(bt)
+	bt.extendDeadline(ctx)
+	full := bt.size() >= b.maxSize
+	if full {
+		bt.timer.Stop()
+		*slot = nil
+	}
+	b.mu.Unlock()
+
+	if full {
+		send(bt)
+	}
+	return bt, i
+}
+
+// flush sends the batch once its window is over, unless it filled up and got sent already
+func (b *batchingBackend) flush(slot **batch, bt *batch, send func(*batch)) {
+	b.mu.Lock()
+	if *slot != bt {
+		b.mu.Unlock()
+		return
+	}
+	*slot = nil
+	b.mu.Unlock()
+
+	send(bt)
+}
+
+func (b *batchingBackend) sendGets(bt *batch) {
+	ctx, cancel := bt.context()
+	defer cancel()
+
+	b.metrics.RecordGetBackendBatchSize(len(bt.keys))
+	bt.values, bt.errs = b.batcher.GetBatch(ctx, bt.keys)
+	close(bt.done)
+}
+
+func (b *batchingBackend) sendPuts(bt *batch) {
+	ctx, cancel := bt.context()
+	defer cancel()
+
+	b.metrics.RecordPutBackendBatchSize(len(bt.puts))
+	bt.errs = b.batcher.PutBatch(ctx, bt.puts)
+	close(bt.done)
+}
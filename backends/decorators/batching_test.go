@@ -0,0 +1,119 @@
+package decorators
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingBatcher runs batches against a memory backend, and remembers the size of every batch it got
+type countingBatcher struct {
+	backend  *backends.MemoryBackend
+	mu       sync.Mutex
+	getSizes []int
+	putSizes []int
+}
+
+func (b *countingBatcher) GetBatch(ctx context.Context, keys []string) ([]string, []error) {
+	b.mu.Lock()
+	b.getSizes = append(b.getSizes, len(keys))
+	b.mu.Unlock()
+
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		values[i], errs[i] = b.backend.Get(ctx, key)
+	}
+	return values, errs
+}
+
+func (b *countingBatcher) PutBatch(ctx context.Context, puts []backends.BatchPut) []error {
+	b.mu.Lock()
+	b.putSizes = append(b.putSizes, len(puts))
+	b.mu.Unlock()
+
+	errs := make([]error, len(puts))
+	for i, put := range puts {
+		errs[i] = b.backend.Put(ctx, put.Key, put.Value, put.TTLSeconds)
+	}
+	return errs
+}
+
+func TestBatchOperationsCoalescesConcurrentCalls(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	batcher := &countingBatcher{backend: backends.NewMemoryBackend()}
+	backend := BatchOperations(batcher, config.Batching{Enabled: true, WindowMillis: 50, MaxSize: 100}, m)
+
+	var wg sync.WaitGroup
+	putErrs := make([]error, 5)
+	for i := range putErrs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			putErrs[i] = backend.Put(context.Background(), fmt.Sprintf("key-%d", i), fmt.Sprintf("value-%d", i), 0)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, make([]error, 5), putErrs, "Every Put should succeed")
+	assert.Equal(t, []int{5}, batcher.putSizes, "Puts within the window should be sent as one batch")
+	assert.Equal(t, 5.0, metricstest.MockHistograms["puts.backends.batch_size"])
+
+	values := make([]string, 6)
+	getErrs := make([]error, 6)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], getErrs[i] = backend.Get(context.Background(), fmt.Sprintf("key-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{6}, batcher.getSizes, "Gets within the window should be sent as one batch")
+	assert.Equal(t, 6.0, metricstest.MockHistograms["gets.backends.batch_size"])
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, getErrs[i])
+		assert.Equal(t, fmt.Sprintf("value-%d", i), values[i], "Every Get should get the value of its own key")
+	}
+	assert.Equal(t, utils.KeyNotFoundError{}, getErrs[5], "Every Get should get the error of its own key")
+}
+
+func TestBatchOperationsSendsFullBatches(t *testing.T) {
+	batcher := &countingBatcher{backend: backends.NewMemoryBackend()}
+	// The window is far longer than the test, so only full batches get sent. They're sent concurrently,
+	// which the mock metrics can't take.
+	backend := BatchOperations(batcher, config.Batching{Enabled: true, WindowMillis: 60000, MaxSize: 2}, &metrics.Metrics{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, backend.Put(context.Background(), fmt.Sprintf("key-%d", i), "value", 0))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{2, 2}, batcher.putSizes)
+}
+
+func TestBatchOperationsHonorsDeadlines(t *testing.T) {
+	batcher := &countingBatcher{backend: backends.NewMemoryBackend()}
+	backend := BatchOperations(batcher, config.Batching{Enabled: true, WindowMillis: 60000, MaxSize: 100}, metricstest.CreateMockMetrics())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := backend.Get(ctx, "key")
+
+	assert.Equal(t, context.DeadlineExceeded, err, "Gets shouldn't wait past their deadline for the batch to be sent")
+}
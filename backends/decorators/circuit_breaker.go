@@ -0,0 +1,158 @@
+package decorators
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// CircuitOpenError is returned in place of running the operation while the circuit breaker is open
+type CircuitOpenError struct{}
+
+func (e CircuitOpenError) Error() string {
+	return "The backend circuit breaker is open"
+}
+
+func (e CircuitOpenError) ErrorCode() utils.ErrorCode {
+	return utils.BackendUnavailable
+}
+
+// BreakCircuit wraps the delegate so that once cfg.FailureThreshold operations in a row failed, the
+// following ones fail right away with a CircuitOpenError instead of piling up on a backend that's
+// struggling. Once cfg.CooldownMillis went by, the breaker goes half open and lets a single operation
+// through to probe the delegate. It closes if that one succeeds, and opens again otherwise. Every
+// transition is counted by the state it leads to.
+func BreakCircuit(delegate backends.Backend, cfg config.CircuitBreaker, m *metrics.Metrics) backends.Backend {
+	return &circuitBreaker{
+		delegate:  delegate,
+		metrics:   m,
+		threshold: cfg.FailureThreshold,
+		cooldown:  time.Duration(cfg.CooldownMillis) * time.Millisecond,
+		now:       time.Now,
+		state:     metrics.BreakerClosed,
+	}
+}
+
+type circuitBreaker struct {
+	delegate  backends.Backend
+	metrics   *metrics.Metrics
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *circuitBreaker) Get(ctx context.Context, key string) (string, error) {
+	probe, err := b.allow()
+	if err != nil {
+		return "", err
+	}
+	value, err := b.delegate.Get(ctx, key)
+	b.record(probe, err)
+	return value, err
+}
+
+func (b *circuitBreaker) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = b.delegate.Put(ctx, key, value, ttlSeconds)
+	b.record(probe, err)
+	return err
+}
+
+// allow tells whether an operation can run, and whether it's the one probing the delegate
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case metrics.BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, CircuitOpenError{}
+		}
+		b.transition(metrics.BreakerHalfOpen)
+	case metrics.BreakerHalfOpen:
+		if b.probing {
+			return false, CircuitOpenError{}
+		}
+	default:
+		return false, nil
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record moves the breaker according to the outcome of an operation it allowed
+func (b *circuitBreaker) record(probe bool, err error) {
+	// Operations abandoned by their client tell nothing about the health of the delegate
+	if err == context.Canceled {
+		if probe {
+			b.mutex.Lock()
+			b.probing = false
+			b.mutex.Unlock()
+		}
+		return
+	}
+	failed := isBackendFailure(err)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if probe {
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.failures = 0
+			b.transition(metrics.BreakerClosed)
+		}
+		return
+	}
+	// Operations allowed before the breaker opened may finish after it did
+	if b.state != metrics.BreakerClosed {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.open()
+	}
+}
+
+// open must be called with the mutex held
+func (b *circuitBreaker) open() {
+	b.openedAt = b.now()
+	b.transition(metrics.BreakerOpen)
+}
+
+// transition must be called with the mutex held
+func (b *circuitBreaker) transition(state string) {
+	log.Infof("Backend circuit breaker went from %s to %s", b.state, state)
+	b.state = state
+	b.metrics.RecordCircuitBreakerTransition(state)
+}
+
+// isBackendFailure tells whether err means the backend is unhealthy. Errors about the key or the value,
+// like a key that isn't there, are answers of a healthy backend.
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	return isTransient(err) || utils.ErrorCodeOf(err) == utils.Timeout
+}
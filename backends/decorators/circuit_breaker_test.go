@@ -0,0 +1,130 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCircuitBreaker = config.CircuitBreaker{Enabled: true, FailureThreshold: 3, CooldownMillis: 1000}
+
+// newTestCircuitBreaker returns a breaker around delegate whose clock only moves through the returned func
+func newTestCircuitBreaker(delegate *flakyBackend) (*circuitBreaker, func(time.Duration)) {
+	now := time.Unix(1600000000, 0)
+	breaker := BreakCircuit(delegate, testCircuitBreaker, metricstest.CreateMockMetrics()).(*circuitBreaker)
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	delegate := &flakyBackend{err: errors.New("connection refused"), failures: 10}
+	breaker, _ := newTestCircuitBreaker(delegate)
+
+	for i := 0; i < 3; i++ {
+		_, err := breaker.Get(context.Background(), "key")
+		assert.Equal(t, delegate.err, err, "Failures under the threshold should reach the client")
+	}
+
+	_, err := breaker.Get(context.Background(), "key")
+	assert.Equal(t, CircuitOpenError{}, err)
+	assert.Equal(t, CircuitOpenError{}, breaker.Put(context.Background(), "key", "value", 60))
+	assert.Equal(t, utils.BackendUnavailable, utils.ErrorCodeOf(err))
+	assert.Equal(t, 3, delegate.gets, "The delegate shouldn't be called while the breaker is open")
+	assert.Equal(t, 0, delegate.puts, "The delegate shouldn't be called while the breaker is open")
+	assert.Equal(t, int64(1), metricstest.MockCounters["backend.circuit_breaker.open"])
+}
+
+func TestCircuitBreakerRecovers(t *testing.T) {
+	delegate := &flakyBackend{err: errors.New("connection refused"), failures: 3}
+	breaker, advance := newTestCircuitBreaker(delegate)
+
+	for i := 0; i < 3; i++ {
+		breaker.Get(context.Background(), "key")
+	}
+	advance(999 * time.Millisecond)
+	_, err := breaker.Get(context.Background(), "key")
+	assert.Equal(t, CircuitOpenError{}, err, "The breaker should stay open until the cooldown is over")
+
+	advance(time.Millisecond)
+	value, err := breaker.Get(context.Background(), "key")
+	assert.NoError(t, err, "The healed backend should serve the probe")
+	assert.Equal(t, "some-value", value)
+	_, err = breaker.Get(context.Background(), "key")
+	assert.NoError(t, err, "The breaker should close after a successful probe")
+
+	assert.Equal(t, int64(1), metricstest.MockCounters["backend.circuit_breaker.open"])
+	assert.Equal(t, int64(1), metricstest.MockCounters["backend.circuit_breaker.half_open"])
+	assert.Equal(t, int64(1), metricstest.MockCounters["backend.circuit_breaker.closed"])
+}
+
+func TestCircuitBreakerFailedProbe(t *testing.T) {
+	delegate := &flakyBackend{err: errors.New("connection refused"), failures: 4}
+	breaker, advance := newTestCircuitBreaker(delegate)
+
+	for i := 0; i < 3; i++ {
+		breaker.Get(context.Background(), "key")
+	}
+	advance(time.Second)
+	_, err := breaker.Get(context.Background(), "key")
+	assert.Equal(t, delegate.err, err, "The probe should reach the delegate")
+
+	_, err = breaker.Get(context.Background(), "key")
+	assert.Equal(t, CircuitOpenError{}, err, "A failed probe should open the breaker for another cooldown")
+	advance(time.Second)
+	_, err = breaker.Get(context.Background(), "key")
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(2), metricstest.MockCounters["backend.circuit_breaker.open"])
+	assert.Equal(t, int64(2), metricstest.MockCounters["backend.circuit_breaker.half_open"])
+	assert.Equal(t, int64(1), metricstest.MockCounters["backend.circuit_breaker.closed"])
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	delegate := &flakyBackend{err: errors.New("connection refused"), failures: 3}
+	breaker, advance := newTestCircuitBreaker(delegate)
+
+	for i := 0; i < 3; i++ {
+		breaker.Get(context.Background(), "key")
+	}
+	advance(time.Second)
+
+	probe, err := breaker.allow()
+	assert.True(t, probe)
+	assert.NoError(t, err)
+	_, err = breaker.allow()
+	assert.Equal(t, CircuitOpenError{}, err, "Only one operation at a time should probe the delegate")
+
+	breaker.record(true, context.Canceled)
+	probe, err = breaker.allow()
+	assert.True(t, probe, "A probe abandoned by its client should let another one through")
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreakerIgnoresHealthyErrors(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		inErr error
+	}{
+		{desc: "Key not found", inErr: utils.KeyNotFoundError{}},
+		{desc: "Key conflict", inErr: utils.NewBackendError(utils.Conflict, errors.New("key exists"))},
+		{desc: "Client gone", inErr: context.Canceled},
+	}
+
+	for _, tc := range testCases {
+		delegate := &flakyBackend{err: tc.inErr, failures: 10}
+		breaker, _ := newTestCircuitBreaker(delegate)
+
+		for i := 0; i < 5; i++ {
+			_, err := breaker.Get(context.Background(), "key")
+			assert.Equal(t, tc.inErr, err, tc.desc)
+		}
+		assert.Equal(t, 5, delegate.gets, tc.desc)
+		assert.Equal(t, int64(0), metricstest.MockCounters["backend.circuit_breaker.open"], tc.desc)
+	}
+}
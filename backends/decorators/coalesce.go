@@ -0,0 +1,80 @@
+package decorators
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prebid/prebid-cache/backends"
+	log "github.com/sirupsen/logrus"
+)
+
+// CoalesceGets wraps the delegate so that concurrent Gets of the same key share a single call to it.
+// The first Get of a key calls the delegate, and those arriving before it's done wait for its value or
+// error, a utils.KeyNotFoundError included. Results aren't kept any longer than that: the next Get
+// calls the delegate again.
+//
+// Puts go straight to the delegate.
+func CoalesceGets(delegate backends.Backend) backends.Backend {
+	return &coalescingBackend{
+		delegate: delegate,
+		inFlight: make(map[string]*sharedGet),
+	}
+}
+
+type coalescingBackend struct {
+	delegate backends.Backend
+	mutex    sync.Mutex
+	inFlight map[string]*sharedGet
+}
+
+// sharedGet is a call to the delegate the Gets of a key wait on. The result is only read once done is
+// closed.
+type sharedGet struct {
+	done      chan struct{}
+	value     string
+	err       error
+	waiters   int
+	abandoned bool
+}
+
+func (b *coalescingBackend) Get(ctx context.Context, key string) (string, error) {
+	b.mutex.Lock()
+	if get, ok := b.inFlight[key]; ok {
+		get.waiters++
+		b.mutex.Unlock()
+		return b.wait(ctx, key, get)
+	}
+	get := &sharedGet{done: make(chan struct{})}
+	b.inFlight[key] = get
+	b.mutex.Unlock()
+
+	get.value, get.err = b.delegate.Get(ctx, key)
+	get.abandoned = ctx.Err() != nil
+
+	b.mutex.Lock()
+	delete(b.inFlight, key)
+	if get.waiters > 0 {
+		log.Debugf("Get of key %s was shared with %d other callers", key, get.waiters)
+	}
+	b.mutex.Unlock()
+	close(get.done)
+
+	return get.value, get.err
+}
+
+func (b *coalescingBackend) wait(ctx context.Context, key string, get *sharedGet) (string, error) {
+	select {
+	case <-get.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	// The caller that ran the Get gave up on it, which doesn't mean this one did
+	if get.abandoned {
+		return b.delegate.Get(ctx, key)
+	}
+	return get.value, get.err
+}
+
+func (b *coalescingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return b.delegate.Put(ctx, key, value, ttlSeconds)
+}
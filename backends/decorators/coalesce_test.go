@@ -0,0 +1,125 @@
+package decorators
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingGetBackend counts the Gets it serves, each of them blocking until release is closed
+type blockingGetBackend struct {
+	backends.Backend
+	release chan struct{}
+	mutex   sync.Mutex
+	gets    int
+}
+
+func (b *blockingGetBackend) Get(ctx context.Context, key string) (string, error) {
+	b.mutex.Lock()
+	b.gets++
+	b.mutex.Unlock()
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return b.Backend.Get(ctx, key)
+}
+
+// waitForWaiters blocks until count Gets of key wait on the one in flight
+func waitForWaiters(t *testing.T, backend *coalescingBackend, key string, count int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		backend.mutex.Lock()
+		get, ok := backend.inFlight[key]
+		waiting := ok && get.waiters == count
+		backend.mutex.Unlock()
+		if waiting {
+			return
+		}
+	}
+	t.Fatalf("%d Gets of key %s never waited on the one in flight", count, key)
+}
+
+func TestCoalesceGets(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inKey         string
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:          "Stored value",
+			inKey:         "stored",
+			expectedValue: "xml<tag></tag>",
+		},
+		{
+			desc:        "Missing key",
+			inKey:       "missing",
+			expectedErr: utils.KeyNotFoundError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		memory := backends.NewMemoryBackend()
+		memory.Put(context.Background(), "stored", "xml<tag></tag>", 0)
+		delegate := &blockingGetBackend{Backend: memory, release: make(chan struct{})}
+		backend := CoalesceGets(delegate).(*coalescingBackend)
+
+		const callers = 10
+		values := make([]string, callers)
+		errs := make([]error, callers)
+		var wg sync.WaitGroup
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				values[i], errs[i] = backend.Get(context.Background(), tc.inKey)
+			}(i)
+		}
+		waitForWaiters(t, backend, tc.inKey, callers-1)
+		close(delegate.release)
+		wg.Wait()
+
+		assert.Equal(t, 1, delegate.gets, "%s: concurrent Gets should share a single call", tc.desc)
+		for i := 0; i < callers; i++ {
+			assert.Equal(t, tc.expectedValue, values[i], tc.desc)
+			assert.Equal(t, tc.expectedErr, errs[i], tc.desc)
+		}
+
+		backend.Get(context.Background(), tc.inKey)
+		assert.Equal(t, 2, delegate.gets, "%s: results shouldn't be kept once the call is over", tc.desc)
+	}
+}
+
+func TestCoalesceGetsAbandoned(t *testing.T) {
+	memory := backends.NewMemoryBackend()
+	memory.Put(context.Background(), "key", "json{}", 0)
+	delegate := &blockingGetBackend{Backend: memory, release: make(chan struct{})}
+	backend := CoalesceGets(delegate).(*coalescingBackend)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := backend.Get(ctx, "key")
+		leaderErr <- err
+	}()
+	waitForWaiters(t, backend, "key", 0)
+
+	followerValue := make(chan string)
+	go func() {
+		value, _ := backend.Get(context.Background(), "key")
+		followerValue <- value
+	}()
+	waitForWaiters(t, backend, "key", 1)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-leaderErr)
+	close(delegate.release)
+	assert.Equal(t, "json{}", <-followerValue, "Waiters should run the Get themselves when the caller that ran it gave up")
+}
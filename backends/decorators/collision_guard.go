@@ -0,0 +1,58 @@
+package decorators
+
+import (
+	"context"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// GuardCollisions wraps the delegate so that the puts made with a context returned by WithPutIfAbsent
+// go through its PutIfAbsent, which fails with a utils.KeyExistsError if the key already holds a value.
+// The check and the write have to be a single operation of the backend, so the delegate must be the
+// base backend rather than one of its decorators. Every collision gets recorded.
+func GuardCollisions(delegate backends.Backend, conditional backends.ConditionalPutter, m *metrics.Metrics) backends.Backend {
+	return &collisionGuardedBackend{
+		delegate:    delegate,
+		conditional: conditional,
+		metrics:     m,
+	}
+}
+
+type putIfAbsentKey struct{}
+
+// WithPutIfAbsent returns a copy of ctx with which backends wrapped by GuardCollisions only store values
+// whose key doesn't hold one yet
+func WithPutIfAbsent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, putIfAbsentKey{}, true)
+}
+
+func putIfAbsent(ctx context.Context) bool {
+	onlyIfAbsent, _ := ctx.Value(putIfAbsentKey{}).(bool)
+	return onlyIfAbsent
+}
+
+type collisionGuardedBackend struct {
+	delegate    backends.Backend
+	conditional backends.ConditionalPutter
+	metrics     *metrics.Metrics
+}
+
+func (b *collisionGuardedBackend) Get(ctx context.Context, key string) (string, error) {
+	return b.delegate.Get(ctx, key)
+}
+
+func (b *collisionGuardedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if !putIfAbsent(ctx) {
+		return b.delegate.Put(ctx, key, value, ttlSeconds)
+	}
+
+	err := b.conditional.PutIfAbsent(ctx, key, value, ttlSeconds)
+	if _, collided := err.(utils.KeyExistsError); collided {
+		log.Warnf("Put of key %s collided with a value already stored under it", key)
+		b.metrics.RecordPutKeyCollision()
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package decorators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardCollisions(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		inPutIfAbsent      bool
+		expectedErr        error
+		expectedValue      string
+		expectedCollisions int64
+	}{
+		{
+			desc:          "Plain puts overwrite the stored value",
+			expectedValue: "new value",
+		},
+		{
+			desc:               "Conditional puts leave the stored value untouched",
+			inPutIfAbsent:      true,
+			expectedErr:        utils.KeyExistsError{},
+			expectedValue:      "stored value",
+			expectedCollisions: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		base := backends.NewMemoryBackend()
+		base.Put(context.Background(), "key", "stored value", 0)
+		backend := GuardCollisions(base, base, m)
+
+		ctx := context.Background()
+		if tc.inPutIfAbsent {
+			ctx = WithPutIfAbsent(ctx)
+		}
+		err := backend.Put(ctx, "key", "new value", 0)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		stored, _ := backend.Get(context.Background(), "key")
+		assert.Equal(t, tc.expectedValue, stored, tc.desc)
+		assert.Equal(t, tc.expectedCollisions, metricstest.MockCounters["puts.backends.key_collisions"], tc.desc)
+	}
+}
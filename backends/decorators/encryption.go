@@ -0,0 +1,94 @@
+package decorators
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// encryptionV1 starts every value encrypted with the configured key, followed by the nonce and the
+// AES-GCM sealed value. Values encrypted with other keys will get their own version byte once keys can
+// be rotated. It's non printable and distinct from the envelope and compression header bytes, so values
+// stored before encryption was turned on are never mistaken for encrypted ones.
+const encryptionV1 byte = 0x1d
+
+// Encrypt wraps the delegate so that values get encrypted with AES-GCM before being stored, and
+// decrypted when read. Values stored without encryption are read as they are. The key must be 16, 24
+// or 32 bytes long.
+func Encrypt(delegate backends.Backend, key []byte) (backends.Backend, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedBackend{
+		delegate: delegate,
+		aead:     aead,
+	}, nil
+}
+
+type encryptedBackend struct {
+	delegate backends.Backend
+	aead     cipher.AEAD
+}
+
+func (b *encryptedBackend) Get(ctx context.Context, key string) (string, error) {
+	stored, err := b.delegate.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if len(stored) == 0 || stored[0] != encryptionV1 {
+		return stored, nil
+	}
+
+	sealed := []byte(stored[1:])
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", &DecryptionError{Key: key, Err: errors.New("encrypted value is truncated")}
+	}
+	value, err := b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", &DecryptionError{Key: key, Err: err}
+	}
+	return string(value), nil
+}
+
+func (b *encryptedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	stored := make([]byte, 1+b.aead.NonceSize(), 1+b.aead.NonceSize()+len(value)+b.aead.Overhead())
+	stored[0] = encryptionV1
+	nonce := stored[1:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	stored = b.aead.Seal(stored, nonce, []byte(value), nil)
+	return b.delegate.Put(ctx, key, string(stored), ttlSeconds)
+}
+
+// DecryptionError is returned by backends wrapped by Encrypt when a stored value can't be decrypted,
+// like when it was encrypted with another key
+type DecryptionError struct {
+	Key string
+	Err error
+}
+
+func (e *DecryptionError) Error() string {
+	return "Value of key " + e.Key + " could not be decrypted: " + e.Err.Error()
+}
+
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode classifies values that can't be decrypted as corrupt, since they can't be served
+func (e *DecryptionError) ErrorCode() utils.ErrorCode {
+	return utils.Corrupt
+}
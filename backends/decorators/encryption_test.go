@@ -0,0 +1,74 @@
+package decorators
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	encryptionKey      = []byte("0123456789abcdef0123456789abcdef")
+	otherEncryptionKey = []byte("fedcba9876543210")
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	base := backends.NewMemoryBackend()
+	backend, err := Encrypt(base, encryptionKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	value := `json{"email":"someone@example.com"}`
+
+	assert.NoError(t, backend.Put(context.Background(), "key", value, 0))
+
+	stored, _ := base.Get(context.Background(), "key")
+	assert.Equal(t, encryptionV1, stored[0])
+	assert.NotContains(t, stored, "someone@example.com", "The stored value should be encrypted")
+	read, err := backend.Get(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, value, read)
+
+	// Every value gets its own nonce
+	assert.NoError(t, backend.Put(context.Background(), "other-key", value, 0))
+	otherStored, _ := base.Get(context.Background(), "other-key")
+	assert.NotEqual(t, stored, otherStored)
+}
+
+func TestEncryptWrongKey(t *testing.T) {
+	base := backends.NewMemoryBackend()
+	backend, _ := Encrypt(base, encryptionKey)
+	otherBackend, _ := Encrypt(base, otherEncryptionKey)
+	assert.NoError(t, backend.Put(context.Background(), "key", "jsontrue", 0))
+	base.Put(context.Background(), "truncated", string([]byte{encryptionV1, 0x01, 0x02}), 0)
+
+	m := metricstest.CreateMockMetrics()
+	monitored := LogMetrics(otherBackend, m)
+	for _, key := range []string{"key", "truncated"} {
+		_, err := monitored.Get(context.Background(), key)
+
+		if assert.IsType(t, &DecryptionError{}, err, key) {
+			assert.Equal(t, utils.Corrupt, utils.ErrorCodeOf(err), key)
+		}
+	}
+	assert.Equal(t, int64(2), metricstest.MockCounters["gets.backends.request.error"], "Failed decryptions should be counted as backend errors")
+}
+
+func TestEncryptReadsUnencryptedValues(t *testing.T) {
+	base := backends.NewMemoryBackend()
+	base.Put(context.Background(), "key", "xml<tag></tag>", 0)
+	backend, _ := Encrypt(base, encryptionKey)
+
+	read, err := backend.Get(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "xml<tag></tag>", read)
+}
+
+func TestEncryptInvalidKey(t *testing.T) {
+	_, err := Encrypt(backends.NewMemoryBackend(), []byte(strings.Repeat("k", 10)))
+	assert.Error(t, err)
+}
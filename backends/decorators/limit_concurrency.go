@@ -0,0 +1,65 @@
+package decorators
+
+import (
+	"context"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics"
+)
+
+// LimitConcurrency makes sure no more than maxConcurrent operations run on the delegate at the same time.
+// Unlike the endpoint decorator of the same name, operations past the limit aren't rejected: they wait
+// for one of those in flight to finish, and fail with the error of their context if it's done first.
+// The time operations spend waiting is recorded, which leaves out those that didn't have to.
+func LimitConcurrency(delegate backends.Backend, maxConcurrent int, m *metrics.Metrics) backends.Backend {
+	return &concurrencyLimitedBackend{
+		delegate: delegate,
+		metrics:  m,
+		inFlight: make(chan struct{}, maxConcurrent),
+	}
+}
+
+type concurrencyLimitedBackend struct {
+	delegate backends.Backend
+	metrics  *metrics.Metrics
+	inFlight chan struct{}
+}
+
+func (b *concurrencyLimitedBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := b.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer b.release()
+	return b.delegate.Get(ctx, key)
+}
+
+func (b *concurrencyLimitedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := b.acquire(ctx); err != nil {
+		return err
+	}
+	defer b.release()
+	return b.delegate.Put(ctx, key, value, ttlSeconds)
+}
+
+// acquire waits until fewer than the max operations are in flight, or until ctx is done
+func (b *concurrencyLimitedBackend) acquire(ctx context.Context) error {
+	select {
+	case b.inFlight <- struct{}{}:
+		return nil
+	default:
+	}
+
+	start := time.Now()
+	defer func() { b.metrics.RecordBackendConcurrencyWait(time.Since(start)) }()
+	select {
+	case b.inFlight <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *concurrencyLimitedBackend) release() {
+	<-b.inFlight
+}
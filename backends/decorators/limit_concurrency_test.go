@@ -0,0 +1,80 @@
+package decorators
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// holdingGetBackend sends the key of every Get to entered, then holds it until a value is sent to proceed
+type holdingGetBackend struct {
+	backends.Backend
+	entered chan string
+	proceed chan struct{}
+}
+
+func (b *holdingGetBackend) Get(ctx context.Context, key string) (string, error) {
+	b.entered <- key
+	<-b.proceed
+	return b.Backend.Get(ctx, key)
+}
+
+func TestLimitConcurrencyBlocksOperationsPastTheMax(t *testing.T) {
+	delegate := &holdingGetBackend{Backend: backends.NewMemoryBackend(), entered: make(chan string, 3), proceed: make(chan struct{})}
+	backend := LimitConcurrency(delegate, 2, metricstest.CreateMockMetrics())
+
+	done := make(chan struct{}, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		go func(key string) {
+			backend.Get(context.Background(), key)
+			done <- struct{}{}
+		}(key)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-delegate.entered:
+		case <-time.After(time.Second):
+			t.Fatal("The operations under the max should reach the delegate")
+		}
+	}
+	select {
+	case key := <-delegate.entered:
+		t.Fatalf("The Get of %s shouldn't reach the delegate while the max is in flight", key)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	delegate.proceed <- struct{}{}
+	select {
+	case <-delegate.entered:
+	case <-time.After(time.Second):
+		t.Fatal("The waiting operation should reach the delegate once one in flight finished")
+	}
+
+	delegate.proceed <- struct{}{}
+	delegate.proceed <- struct{}{}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	assert.Equal(t, int64(1), metricstest.MockCounters["backend.concurrency_wait.count"], "Only the operation that waited should be recorded")
+}
+
+func TestLimitConcurrencyGivesUpAtTheDeadline(t *testing.T) {
+	delegate := &holdingGetBackend{Backend: backends.NewMemoryBackend(), entered: make(chan string, 1), proceed: make(chan struct{})}
+	backend := LimitConcurrency(delegate, 1, metricstest.CreateMockMetrics())
+
+	go backend.Get(context.Background(), "a")
+	<-delegate.entered
+	defer close(delegate.proceed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := backend.Get(ctx, "b")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, context.DeadlineExceeded, backend.Put(ctx, "b", "some-value", 60))
+	assert.Equal(t, int64(2), metricstest.MockCounters["backend.concurrency_wait.count"], "Operations that gave up should be recorded too")
+}
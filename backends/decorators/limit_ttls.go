@@ -4,24 +4,94 @@ import (
 	"context"
 
 	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	log "github.com/sirupsen/logrus"
 )
 
 // LimitTTLs wraps the delegate and makes sure that it never gets TTLs which exceed the max.
 func LimitTTLs(delegate backends.Backend, maxTTLSeconds int) backends.Backend {
+	return LimitTTLsByRules(delegate, config.RequestLimits{MaxTTLSeconds: maxTTLSeconds})
+}
+
+// LimitTTLsByRules wraps the delegate and makes sure that it never gets TTLs which exceed any of the
+// limits found in cfg: the global max, the max for the value's format and the max of every size rule
+// the value matches. See resolveTTL for the precedence between them.
+func LimitTTLsByRules(delegate backends.Backend, cfg config.RequestLimits) backends.Backend {
+	return ApplyTTLRules(delegate, cfg, 0, nil)
+}
+
+// ApplyTTLRules is LimitTTLsByRules for a delegate that stores values put without a TTL for
+// backendDefaultTTL seconds. The delegate always gets the TTL resolved by backends.ResolveTTL, so that
+// values stored with any of the defaults are bound by the same limits as the rest.
+// Whenever the TTL of a value gets changed, the TTL applied is recorded, and the seconds cut from it if
+// it got lowered are recorded as extra TTL seconds, unless m is nil.
+func ApplyTTLRules(delegate backends.Backend, cfg config.RequestLimits, backendDefaultTTL int, m *metrics.Metrics) backends.Backend {
 	return ttlLimited{
-		Backend:       delegate,
-		maxTTLSeconds: maxTTLSeconds,
+		Backend:           delegate,
+		maxTTLSeconds:     cfg.MaxTTLSeconds,
+		formatMaxTTLs:     cfg.MaxTTLSecondsByFormat,
+		sizeRules:         cfg.TTLSizeRules,
+		minTTLSeconds:     cfg.MinTTLSeconds,
+		defaultTTL:        cfg.DefaultTTLSeconds,
+		formatDefaultTTLs: cfg.DefaultTTLSecondsByFormat,
+		backendDefaultTTL: backendDefaultTTL,
+		metrics:           m,
 	}
 }
 
 type ttlLimited struct {
 	backends.Backend
-	maxTTLSeconds int
+	maxTTLSeconds     int
+	formatMaxTTLs     map[string]int
+	sizeRules         []config.TTLSizeRule
+	minTTLSeconds     int
+	defaultTTL        int
+	formatDefaultTTLs map[string]int
+	backendDefaultTTL int
+	metrics           *metrics.Metrics
 }
 
 func (l ttlLimited) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
-	if l.maxTTLSeconds > ttlSeconds {
-		return l.Backend.Put(ctx, key, value, ttlSeconds)
+	ttl := l.resolveTTL(value, ttlSeconds)
+	if ttl != ttlSeconds {
+		log.Debugf("Applied ttl of %d seconds to key %s. Requested ttl was %d seconds", ttl, key, ttlSeconds)
+		if l.metrics != nil {
+			l.metrics.RecordAppliedTTLSeconds(float64(ttl))
+		}
+	}
+	if ttl < ttlSeconds && l.metrics != nil {
+		l.metrics.RecordExtraTTLSeconds(float64(ttlSeconds - ttl))
+	}
+	return l.Backend.Put(ctx, key, value, ttl)
+}
+
+// resolveTTL returns the TTL the value gets stored with. Every limit that applies to the value is
+// collected (the global max, the max configured for its "xml" or "json" format and the max of every
+// size rule whose threshold the value reaches) and the most restrictive one becomes the ceiling,
+// regardless of the order in which the limits were configured.
+//
+// Limits of zero or less are ignored, except for the global max which keeps its historical behavior
+// of applying to every request.
+func (l ttlLimited) resolveTTL(value string, ttlSeconds int) int {
+	format := backends.ValueFormat(value)
+	limit := l.maxTTLSeconds
+
+	if maxTTL := l.formatMaxTTLs[format]; maxTTL > 0 && maxTTL < limit {
+		limit = maxTTL
 	}
-	return l.Backend.Put(ctx, key, value, l.maxTTLSeconds)
+
+	for _, rule := range l.sizeRules {
+		if rule.MaxTTLSeconds > 0 && rule.MaxTTLSeconds < limit && len(value) >= rule.MinSizeBytes {
+			limit = rule.MaxTTLSeconds
+		}
+	}
+
+	return backends.ResolveTTL(ttlSeconds, backends.TTLRules{
+		FormatDefault:  l.formatDefaultTTLs[format],
+		GlobalDefault:  l.defaultTTL,
+		BackendDefault: l.backendDefaultTTL,
+		Floor:          l.minTTLSeconds,
+		Ceiling:        limit,
+	})
 }
@@ -2,9 +2,13 @@ package decorators_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestExcessiveTTL(t *testing.T) {
@@ -37,3 +41,180 @@ func (c *ttlCapturer) Put(ctx context.Context, key string, value string, ttlSeco
 func (c *ttlCapturer) Get(ctx context.Context, key string) (string, error) {
 	return "", nil
 }
+
+func TestOverlappingTTLRules(t *testing.T) {
+	limits := config.RequestLimits{
+		MaxTTLSeconds:         3600,
+		MaxTTLSecondsByFormat: map[string]int{"xml": 1800, "json": 0},
+		TTLSizeRules: []config.TTLSizeRule{
+			{MinSizeBytes: 100, MaxTTLSeconds: 600},
+			{MinSizeBytes: 10, MaxTTLSeconds: 1200},
+			{MinSizeBytes: 1000, MaxTTLSeconds: 2400},
+		},
+	}
+
+	testCases := []struct {
+		desc        string
+		inValue     string
+		inTTL       int
+		expectedTTL int
+	}{
+		{
+			desc:        "Small json value only hits the global max",
+			inValue:     "json1",
+			inTTL:       5000,
+			expectedTTL: 3600,
+		},
+		{
+			desc:        "Small xml value is capped by its format limit",
+			inValue:     "xml<a/>",
+			inTTL:       5000,
+			expectedTTL: 1800,
+		},
+		{
+			desc:        "Medium xml value, size rule is stricter than the format limit",
+			inValue:     "xml" + strings.Repeat("a", 20),
+			inTTL:       5000,
+			expectedTTL: 1200,
+		},
+		{
+			desc:        "Large xml value matches every size rule, the most restrictive one wins regardless of order",
+			inValue:     "xml" + strings.Repeat("a", 2000),
+			inTTL:       5000,
+			expectedTTL: 600,
+		},
+		{
+			desc:        "Large json value with a non-positive format limit is capped by the size rules",
+			inValue:     "json" + strings.Repeat("1", 2000),
+			inTTL:       5000,
+			expectedTTL: 600,
+		},
+		{
+			desc:        "Requested ttl below every applicable limit is kept",
+			inValue:     "xml" + strings.Repeat("a", 2000),
+			inTTL:       300,
+			expectedTTL: 300,
+		},
+		{
+			desc:        "Zero ttl is kept so the backend default applies",
+			inValue:     "xml" + strings.Repeat("a", 2000),
+			inTTL:       0,
+			expectedTTL: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		delegate := &ttlCapturer{}
+		wrapped := decorators.ApplyTTLRules(delegate, limits, 0, m)
+		wrapped.Put(context.Background(), "foo", tc.inValue, tc.inTTL)
+
+		assert.Equal(t, tc.expectedTTL, delegate.lastTTL, tc.desc)
+		assertAppliedTTL(t, tc.inTTL, tc.expectedTTL, tc.desc)
+	}
+}
+
+func TestTTLDefaults(t *testing.T) {
+	limits := config.RequestLimits{
+		MaxTTLSeconds:             3600,
+		MinTTLSeconds:             60,
+		DefaultTTLSeconds:         900,
+		DefaultTTLSecondsByFormat: map[string]int{"xml": 300},
+		TTLSizeRules:              []config.TTLSizeRule{{MinSizeBytes: 100, MaxTTLSeconds: 120}},
+	}
+
+	testCases := []struct {
+		desc        string
+		inValue     string
+		inTTL       int
+		expectedTTL int
+	}{
+		{
+			desc:        "Xml value without a ttl gets the xml default",
+			inValue:     "xml<a/>",
+			expectedTTL: 300,
+		},
+		{
+			desc:        "Json value without a ttl gets the global default",
+			inValue:     "json1",
+			expectedTTL: 900,
+		},
+		{
+			desc:        "Defaults are capped by the size rules",
+			inValue:     "xml" + strings.Repeat("a", 200),
+			expectedTTL: 120,
+		},
+		{
+			desc:        "Requested ttl below the floor is raised",
+			inValue:     "json1",
+			inTTL:       5,
+			expectedTTL: 60,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		delegate := &ttlCapturer{}
+		wrapped := decorators.ApplyTTLRules(delegate, limits, 2400, m)
+		wrapped.Put(context.Background(), "foo", tc.inValue, tc.inTTL)
+
+		assert.Equal(t, tc.expectedTTL, delegate.lastTTL, tc.desc)
+		assertAppliedTTL(t, tc.inTTL, tc.expectedTTL, tc.desc)
+	}
+}
+
+// assertAppliedTTL checks that the applied ttl got recorded if, and only if, it differs from the
+// requested one
+func assertAppliedTTL(t *testing.T, requestedTTL int, appliedTTL int, desc string) {
+	t.Helper()
+
+	expected := 0.0
+	if appliedTTL != requestedTTL {
+		expected = float64(appliedTTL)
+	}
+	assert.Equal(t, expected, metricstest.MockHistograms["applied_ttl_seconds"], "%s: applied ttl metric", desc)
+}
+
+func TestBackendDefaultTTL(t *testing.T) {
+	delegate := &ttlCapturer{}
+	wrapped := decorators.ApplyTTLRules(delegate, config.RequestLimits{MaxTTLSeconds: 2000}, 2400, nil)
+	wrapped.Put(context.Background(), "foo", "json1", 0)
+
+	assert.Equal(t, 2000, delegate.lastTTL, "The backend default should be capped like any other ttl")
+}
+
+func TestClampedTTLMetrics(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		inTTL               int
+		expectedTTL         int
+		expectedExtraTTLSec float64
+	}{
+		{
+			desc:        "Value without a ttl gets the backend default",
+			inTTL:       0,
+			expectedTTL: 1800,
+		},
+		{
+			desc:        "Requested ttl under the max is kept",
+			inTTL:       600,
+			expectedTTL: 600,
+		},
+		{
+			desc:                "Requested ttl over the max is lowered to it",
+			inTTL:               5000,
+			expectedTTL:         3600,
+			expectedExtraTTLSec: 1400,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		delegate := &ttlCapturer{}
+		wrapped := decorators.ApplyTTLRules(delegate, config.RequestLimits{MaxTTLSeconds: 3600}, 1800, m)
+		wrapped.Put(context.Background(), "foo", "json1", tc.inTTL)
+
+		assert.Equal(t, tc.expectedTTL, delegate.lastTTL, tc.desc)
+		assert.Equal(t, tc.expectedExtraTTLSec, metricstest.MockHistograms["extra_ttl_seconds"], "%s: only the seconds cut from requested ttls should be recorded", tc.desc)
+	}
+}
@@ -2,7 +2,6 @@ package decorators
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"github.com/prebid/prebid-cache/backends"
@@ -27,19 +26,26 @@ func (b *backendWithMetrics) Get(ctx context.Context, key string) (string, error
 			b.metrics.RecordKeyNotFoundError()
 		} else if _, isMissingUuidError := err.(utils.MissingKeyError); isMissingUuidError {
 			b.metrics.RecordMissingKeyError()
+		} else if utils.ErrorCodeOf(err) == utils.Corrupt {
+			b.metrics.RecordCorruptValue()
+		}
+		if utils.ErrorCodeOf(err) == utils.Timeout {
+			b.metrics.RecordGetBackendTimeout()
+		} else {
+			b.metrics.RecordGetBackendError()
 		}
-		b.metrics.RecordGetBackendError()
 	}
 	return val, err
 }
 
 func (b *backendWithMetrics) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
 
-	if strings.HasPrefix(value, backends.XML_PREFIX) {
+	switch backends.ValueFormat(value) {
+	case backends.XML_PREFIX:
 		b.metrics.RecordPutBackendXml()
-	} else if strings.HasPrefix(value, backends.JSON_PREFIX) {
+	case backends.JSON_PREFIX:
 		b.metrics.RecordPutBackendJson()
-	} else {
+	default:
 		b.metrics.RecordPutBackendInvalid()
 	}
 	if ttlSeconds != 0 {
@@ -49,6 +55,8 @@ func (b *backendWithMetrics) Put(ctx context.Context, key string, value string,
 	err := b.delegate.Put(ctx, key, value, ttlSeconds)
 	if err == nil {
 		b.metrics.RecordPutBackendDuration(time.Since(start))
+	} else if utils.ErrorCodeOf(err) == utils.Timeout {
+		b.metrics.RecordPutBackendTimeout()
 	} else {
 		b.metrics.RecordPutBackendError()
 	}
@@ -3,6 +3,7 @@ package decorators
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/prebid/prebid-cache/backends"
@@ -69,6 +70,11 @@ func TestGetErrorMetrics(t *testing.T) {
 					"gets.backend_error.missing_key",
 					utils.MissingKeyError{},
 				},
+				{
+					"Failed get backend request should be accounted as a corrupt value error",
+					"gets.backend_error.corrupt_value",
+					utils.NewBackendError(utils.Corrupt, errors.New("stored value is truncated")),
+				},
 			},
 		},
 	}
@@ -96,6 +102,30 @@ func TestGetErrorMetrics(t *testing.T) {
 	}
 }
 
+func TestTimeoutMetrics(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		inErr error
+	}{
+		{"Context deadline", context.DeadlineExceeded},
+		{"Wrapped context deadline", fmt.Errorf("get failed: %w", context.DeadlineExceeded)},
+		{"Timeout classified by the backend", utils.NewBackendError(utils.Timeout, errors.New("i/o timeout"))},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		backend := LogMetrics(&failedBackend{tc.inErr}, m)
+
+		backend.Get(context.Background(), "foo")
+		backend.Put(context.Background(), "foo", "xml<vast></vast>", 0)
+
+		assert.Equal(t, int64(1), metricstest.MockCounters["gets.backends.request.timeout"], tc.desc)
+		assert.Equal(t, int64(0), metricstest.MockCounters["gets.backends.request.error"], tc.desc)
+		assert.Equal(t, int64(1), metricstest.MockCounters["puts.backends.request.timeout"], tc.desc)
+		assert.Equal(t, int64(0), metricstest.MockCounters["puts.backends.request.error"], tc.desc)
+	}
+}
+
 func TestPutSuccessMetrics(t *testing.T) {
 
 	m := metricstest.CreateMockMetrics()
@@ -0,0 +1,67 @@
+package decorators
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prebid/prebid-cache/backends"
+)
+
+type namespacedBackend struct {
+	delegate backends.Backend
+	prefix   string
+}
+
+// NamespaceKeys prepends prefix to the key of every Get and Put, so that instances with different
+// prefixes can share a backend without reading each other's values. Callers keep using the bare keys.
+func NamespaceKeys(backend backends.Backend, prefix string) backends.Backend {
+	return &namespacedBackend{
+		delegate: backend,
+		prefix:   prefix,
+	}
+}
+
+func (b *namespacedBackend) Get(ctx context.Context, key string) (string, error) {
+	return b.delegate.Get(ctx, b.prefix+key)
+}
+
+func (b *namespacedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return b.delegate.Put(ctx, b.prefix+key, value, ttlSeconds)
+}
+
+type namespacedScanner struct {
+	delegate backends.KeyScanner
+	prefix   string
+	strip    bool
+}
+
+// ScanNamespace lists only the keys of scanner that start with prefix, leaving out those of other
+// namespaces. If strip is set the prefix is removed from them, so they can be used with a backend
+// decorated by NamespaceKeys. Otherwise they're listed as stored.
+func ScanNamespace(scanner backends.KeyScanner, prefix string, strip bool) backends.KeyScanner {
+	return &namespacedScanner{
+		delegate: scanner,
+		prefix:   prefix,
+		strip:    strip,
+	}
+}
+
+// ScanKeys may return fewer than count keys, or none at all before the scan is over, since the keys of
+// other namespaces are skipped.
+func (s *namespacedScanner) ScanKeys(ctx context.Context, cursor uint64, count int) ([]string, uint64, error) {
+	keys, next, err := s.delegate.ScanKeys(ctx, cursor, count)
+	if err != nil {
+		return nil, next, err
+	}
+	inNamespace := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, s.prefix) {
+			continue
+		}
+		if s.strip {
+			key = strings.TrimPrefix(key, s.prefix)
+		}
+		inNamespace = append(inNamespace, key)
+	}
+	return inNamespace, next, nil
+}
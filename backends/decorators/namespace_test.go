@@ -0,0 +1,79 @@
+package decorators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceKeys(t *testing.T) {
+	shared := backends.NewMemoryBackend()
+	staging := NamespaceKeys(shared, "staging:")
+	prod := NamespaceKeys(shared, "prod:")
+
+	assert.NoError(t, staging.Put(context.Background(), "36-char-uuid", "json{\"env\":\"staging\"}", 0))
+
+	_, err := prod.Get(context.Background(), "36-char-uuid")
+	assert.Equal(t, utils.NotFound, utils.ErrorCodeOf(err), "Other namespaces shouldn't read the value")
+
+	assert.NoError(t, prod.Put(context.Background(), "36-char-uuid", "json{\"env\":\"prod\"}", 0))
+
+	value, err := staging.Get(context.Background(), "36-char-uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, "json{\"env\":\"staging\"}", value, "Other namespaces shouldn't overwrite the value")
+
+	value, err = prod.Get(context.Background(), "36-char-uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, "json{\"env\":\"prod\"}", value)
+
+	stored, err := shared.Get(context.Background(), "staging:36-char-uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, "json{\"env\":\"staging\"}", stored, "Values should be stored under the prefixed key")
+}
+
+func TestScanNamespace(t *testing.T) {
+	shared := backends.NewMemoryBackend()
+	NamespaceKeys(shared, "prod:").Put(context.Background(), "a", "json{}", 0)
+	NamespaceKeys(shared, "staging:").Put(context.Background(), "b", "json{}", 0)
+	NamespaceKeys(shared, "staging:").Put(context.Background(), "c", "json{}", 0)
+
+	testCases := []struct {
+		desc         string
+		inStrip      bool
+		expectedKeys []string
+	}{
+		{
+			desc:         "Keys as clients use them",
+			inStrip:      true,
+			expectedKeys: []string{"b", "c"},
+		},
+		{
+			desc:         "Keys as stored",
+			expectedKeys: []string{"staging:b", "staging:c"},
+		},
+	}
+
+	for _, tc := range testCases {
+		scanner := ScanNamespace(shared, "staging:", tc.inStrip)
+
+		var keys []string
+		var cursor uint64
+		for {
+			var page []string
+			var err error
+			page, cursor, err = scanner.ScanKeys(context.Background(), cursor, 1)
+			if !assert.NoError(t, err, tc.desc) {
+				break
+			}
+			keys = append(keys, page...)
+			if cursor == 0 {
+				break
+			}
+		}
+
+		assert.Equal(t, tc.expectedKeys, keys, tc.desc)
+	}
+}
@@ -0,0 +1,117 @@
+package decorators
+
+import (
+	"context"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const replicationTimeout = 5 * time.Second
+
+// Replicate wraps the primary so that every value it stores successfully is also stored in the
+// standby by a pool of workers in the background. Puts return as soon as the primary is done, so the
+// standby never slows them down. When the replication queue is full, the value is dropped rather than
+// replicated, and the drop is counted.
+//
+// Values stored without a TTL are replicated with standbyDefaultTTL seconds, since the standby may keep
+// them forever otherwise.
+//
+// Gets are always served by the primary.
+func Replicate(primary backends.Backend, standby backends.Backend, cfg config.Standby, standbyDefaultTTL int, m *metrics.Metrics) backends.Backend {
+	b := &replicatedBackend{
+		primary:    primary,
+		standby:    standby,
+		metrics:    m,
+		queue:      make(chan *replication, cfg.QueueSize),
+		attempts:   cfg.MaxAttempts,
+		backoff:    time.Duration(cfg.BackoffMillis) * time.Millisecond,
+		defaultTTL: standbyDefaultTTL,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		go b.work()
+	}
+	return b
+}
+
+type replicatedBackend struct {
+	primary    backends.Backend
+	standby    backends.Backend
+	metrics    *metrics.Metrics
+	queue      chan *replication
+	attempts   int
+	backoff    time.Duration
+	defaultTTL int
+}
+
+type replication struct {
+	key        string
+	value      string
+	ttlSeconds int
+	storedAt   time.Time
+}
+
+func (b *replicatedBackend) Get(ctx context.Context, key string) (string, error) {
+	return b.primary.Get(ctx, key)
+}
+
+func (b *replicatedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := b.primary.Put(ctx, key, value, ttlSeconds); err != nil {
+		return err
+	}
+
+	select {
+	case b.queue <- &replication{key: key, value: value, ttlSeconds: ttlSeconds, storedAt: time.Now()}:
+	default:
+		log.Debugf("Replication queue is full. Key %s won't be replicated to the standby backend", key)
+		b.metrics.RecordReplicationDropped()
+	}
+	b.metrics.RecordReplicationQueueDepth(len(b.queue))
+	return nil
+}
+
+func (b *replicatedBackend) work() {
+	for r := range b.queue {
+		b.metrics.RecordReplicationQueueDepth(len(b.queue))
+		if err := b.replicate(r); err != nil {
+			log.Errorf("Replication of key %s to the standby backend failed: %v", r.key, err)
+			b.metrics.RecordReplicationError()
+			continue
+		}
+		b.metrics.RecordReplicationLag(time.Since(r.storedAt))
+	}
+}
+
+// replicate stores the value in the standby, retrying up to the configured attempts. The TTL gets
+// shortened by the time the value waited, so it expires from both backends at the same time. Values
+// without one get the default TTL of the standby.
+func (b *replicatedBackend) replicate(r *replication) error {
+	var err error
+	for attempt := 1; attempt <= b.attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(b.backoff)
+		}
+
+		ttlSeconds := r.ttlSeconds
+		if ttlSeconds > 0 {
+			ttlSeconds -= int(time.Since(r.storedAt) / time.Second)
+			if ttlSeconds <= 0 {
+				// The value already expired from the primary
+				return nil
+			}
+		} else {
+			ttlSeconds = b.defaultTTL
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), replicationTimeout)
+		err = b.standby.Put(ctx, r.key, r.value, ttlSeconds)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	influx "github.com/prebid/prebid-cache/metrics/influx"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReplicationMetrics records into Influx metrics because, unlike the mock ones, they can be
+// recorded by the replication workers while the test reads them
+func newReplicationMetrics() (*metrics.Metrics, *influx.InfluxMetrics) {
+	engine := influx.CreateInfluxMetrics()
+	return &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{engine}}, engine
+}
+
+func TestReplicate(t *testing.T) {
+	m, engine := newReplicationMetrics()
+	primary := backends.NewMemoryBackend()
+	standby := backends.NewMemoryBackend()
+	backend := Replicate(primary, standby, config.Standby{QueueSize: 10, Workers: 1, MaxAttempts: 1}, 0, m)
+
+	assert.NoError(t, backend.Put(context.Background(), "key", "value", 0))
+
+	value, err := primary.Get(context.Background(), "key")
+	assert.NoError(t, err, "Value should be stored in the primary backend")
+	assert.Equal(t, "value", value)
+
+	assert.Eventually(t, func() bool {
+		value, err := standby.Get(context.Background(), "key")
+		return err == nil && value == "value"
+	}, time.Second, time.Millisecond, "Value should be replicated to the standby backend")
+	assert.Eventually(t, func() bool { return engine.Replication.Lag.Count() == 1 }, time.Second, time.Millisecond, "Replication lag should be recorded")
+
+	value, err = backend.Get(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestReplicateDoesNotWaitForStandby(t *testing.T) {
+	m, _ := newReplicationMetrics()
+	primary := backends.NewMemoryBackend()
+	standby := &gatedBackend{Backend: backends.NewMemoryBackend(), gate: make(chan struct{})}
+	backend := Replicate(primary, standby, config.Standby{QueueSize: 10, Workers: 1, MaxAttempts: 1}, 0, m)
+
+	standby.wg.Add(1)
+	done := make(chan error)
+	go func() { done <- backend.Put(context.Background(), "key", "value", 0) }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatalf("Put should not wait for the standby backend")
+	}
+
+	close(standby.gate)
+	standby.wg.Wait()
+
+	value, err := standby.Backend.Get(context.Background(), "key")
+	assert.NoError(t, err, "Value should be replicated once the standby backend responds")
+	assert.Equal(t, "value", value)
+}
+
+func TestReplicateFullQueue(t *testing.T) {
+	m, engine := newReplicationMetrics()
+	primary := backends.NewMemoryBackend()
+	// No workers, so the queue never gets drained
+	backend := Replicate(primary, backends.NewMemoryBackend(), config.Standby{QueueSize: 1, Workers: 0, MaxAttempts: 1}, 0, m)
+
+	assert.NoError(t, backend.Put(context.Background(), "queued", "value", 0))
+	assert.NoError(t, backend.Put(context.Background(), "dropped", "value", 0), "A full queue should not fail the Put")
+
+	_, err := primary.Get(context.Background(), "dropped")
+	assert.NoError(t, err, "Value should be stored in the primary backend even if it's not replicated")
+	assert.Equal(t, int64(1), engine.Replication.Dropped.Count(), "Dropped replications should be counted")
+	assert.Equal(t, int64(1), engine.Replication.QueueDepth.Value(), "Queue depth should be recorded")
+}
+
+func TestReplicateRetries(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		failures       int
+		expectedPuts   int
+		expectedErrors int64
+	}{
+		{
+			desc:         "Standby recovers before running out of attempts",
+			failures:     2,
+			expectedPuts: 3,
+		},
+		{
+			desc:           "Standby fails every attempt",
+			failures:       5,
+			expectedPuts:   3,
+			expectedErrors: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		m, engine := newReplicationMetrics()
+		standby := &flakyBackend{err: errors.New("connection refused"), failures: test.failures}
+		backend := Replicate(backends.NewMemoryBackend(), standby, config.Standby{QueueSize: 10, Workers: 1, MaxAttempts: 3}, 0, m)
+
+		assert.NoError(t, backend.Put(context.Background(), "key", "value", 0), test.desc)
+
+		assert.Eventually(t, func() bool {
+			return engine.Replication.Lag.Count()+engine.Replication.Errors.Count() == 1
+		}, time.Second, time.Millisecond, test.desc)
+		assert.Equal(t, test.expectedPuts, standby.puts, test.desc)
+		assert.Equal(t, test.expectedErrors, engine.Replication.Errors.Count(), test.desc)
+	}
+}
+
+func TestReplicatePrimaryError(t *testing.T) {
+	m, engine := newReplicationMetrics()
+	standby := backends.NewMemoryBackend()
+	backend := Replicate(&failedBackend{errors.New("Failure")}, standby, config.Standby{QueueSize: 10, Workers: 0, MaxAttempts: 1}, 0, m)
+
+	assert.Error(t, backend.Put(context.Background(), "key", "value", 0), "Primary errors should be returned")
+	assert.Equal(t, int64(0), engine.Replication.QueueDepth.Value(), "Values the primary failed to store should not be replicated")
+}
+
+// ttlRecordingBackend sends the TTL of every value it's asked to store
+type ttlRecordingBackend struct {
+	ttls chan int
+}
+
+func (b *ttlRecordingBackend) Get(ctx context.Context, key string) (string, error) {
+	return "", nil
+}
+
+func (b *ttlRecordingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	b.ttls <- ttlSeconds
+	return nil
+}
+
+func TestReplicateStandbyDefaultTTL(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		inTTL       int
+		expectedTTL int
+	}{
+		{
+			desc:        "Values without a TTL get the default TTL of the standby",
+			inTTL:       0,
+			expectedTTL: 2400,
+		},
+		{
+			desc:        "Values with a TTL keep it",
+			inTTL:       60,
+			expectedTTL: 60,
+		},
+	}
+
+	for _, tc := range testCases {
+		m, _ := newReplicationMetrics()
+		standby := &ttlRecordingBackend{ttls: make(chan int, 1)}
+		backend := Replicate(backends.NewMemoryBackend(), standby, config.Standby{QueueSize: 10, Workers: 1, MaxAttempts: 1}, 2400, m)
+
+		assert.NoError(t, backend.Put(context.Background(), "key", "value", tc.inTTL), tc.desc)
+
+		select {
+		case ttl := <-standby.ttls:
+			assert.Equal(t, tc.expectedTTL, ttl, tc.desc)
+		case <-time.After(time.Second):
+			t.Errorf("Value should be replicated to the standby backend: %s", tc.desc)
+		}
+	}
+}
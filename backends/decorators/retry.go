@@ -0,0 +1,128 @@
+package decorators
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// RetryTransientErrors wraps the delegate so that operations failing with a transient error are tried
+// again, up to cfg.MaxAttempts times in total. Gets are always retried because they're idempotent.
+// Puts are only retried when cfg.RetryPuts is set: a Put that timed out may still have been stored,
+// and its retry could then fail or overwrite a value written in between.
+//
+// The backoff doubles after every attempt. Retries stop early if the backoff would outlast the deadline
+// of the operation's context, since the client would have given up by then.
+func RetryTransientErrors(delegate backends.Backend, cfg config.Retry) backends.Backend {
+	return &retryingBackend{
+		delegate:    delegate,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     time.Duration(cfg.BackoffMillis) * time.Millisecond,
+		maxBackoff:  time.Duration(cfg.MaxBackoffMillis) * time.Millisecond,
+		jitter:      cfg.Jitter,
+		retryPuts:   cfg.RetryPuts,
+		randFloat:   rand.Float64,
+	}
+}
+
+type maxAttemptsKey struct{}
+
+// WithMaxAttempts returns a copy of ctx with which backends wrapped by RetryTransientErrors try every
+// operation up to attempts times, rather than their configured max attempts.
+func WithMaxAttempts(ctx context.Context, attempts int) context.Context {
+	return context.WithValue(ctx, maxAttemptsKey{}, attempts)
+}
+
+// MaxAttemptsOverride returns the max attempts set in ctx by WithMaxAttempts, if any
+func MaxAttemptsOverride(ctx context.Context) (int, bool) {
+	attempts, ok := ctx.Value(maxAttemptsKey{}).(int)
+	return attempts, ok
+}
+
+type retryingBackend struct {
+	delegate    backends.Backend
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	jitter      float64
+	retryPuts   bool
+	randFloat   func() float64
+}
+
+func (b *retryingBackend) Get(ctx context.Context, key string) (string, error) {
+	var value string
+	err := b.retry(ctx, "Get", key, func() error {
+		var err error
+		value, err = b.delegate.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (b *retryingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	put := func() error {
+		return b.delegate.Put(ctx, key, value, ttlSeconds)
+	}
+	if !b.retryPuts {
+		return put()
+	}
+	return b.retry(ctx, "Put", key, put)
+}
+
+func (b *retryingBackend) retry(ctx context.Context, operation string, key string, do func() error) error {
+	maxAttempts := b.maxAttempts
+	if override, ok := MaxAttemptsOverride(ctx); ok {
+		maxAttempts = override
+	}
+
+	err := do()
+	for attempt := 2; attempt <= maxAttempts && isTransient(err); attempt++ {
+		wait := b.backoffBefore(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			log.Debugf("Not retrying %s of key %s after error: %v. The deadline would pass first", operation, key, err)
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		log.Debugf("Retrying %s of key %s after error: %v. Attempt %d of %d", operation, key, err, attempt, maxAttempts)
+		err = do()
+	}
+	return err
+}
+
+// backoffBefore returns how long to wait before the given attempt, the second one being the first retry.
+// Up to the jitter fraction of it is taken off at random.
+func (b *retryingBackend) backoffBefore(attempt int) time.Duration {
+	wait := b.backoff
+	// Uncapped backoffs stop doubling before they'd overflow
+	for i := 2; i < attempt && wait < math.MaxInt64/2 && (b.maxBackoff == 0 || wait < b.maxBackoff); i++ {
+		wait *= 2
+	}
+	if b.maxBackoff > 0 && wait > b.maxBackoff {
+		wait = b.maxBackoff
+	}
+	return wait - time.Duration(b.jitter*b.randFloat()*float64(wait))
+}
+
+// isTransient tells whether trying again could make a difference. Errors that describe the request
+// itself, a key that isn't there, or a value the backend can't take, won't go away on a retry.
+func isTransient(err error) bool {
+	switch err.(type) {
+	case nil, utils.KeyNotFoundError, utils.MissingKeyError, utils.KeyLengthError, *BadPayloadSize:
+		return false
+	}
+	switch utils.ErrorCodeOf(err) {
+	case utils.ValueTooLarge, utils.Conflict:
+		return false
+	}
+	return err != context.Canceled && err != context.DeadlineExceeded
+}
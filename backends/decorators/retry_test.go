@@ -0,0 +1,218 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyBackend fails every call with its error until it's been called failures times
+type flakyBackend struct {
+	err      error
+	failures int
+	gets     int
+	puts     int
+}
+
+func (b *flakyBackend) Get(ctx context.Context, key string) (string, error) {
+	b.gets++
+	if b.gets <= b.failures {
+		return "", b.err
+	}
+	return "some-value", nil
+}
+
+func (b *flakyBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	b.puts++
+	if b.puts <= b.failures {
+		return b.err
+	}
+	return nil
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	transientErr := errors.New("connection reset by peer")
+	conflictErr := utils.NewBackendError(utils.Conflict, errors.New("key exists"))
+
+	testCases := []struct {
+		desc           string
+		inCfg          config.Retry
+		inErr          error
+		inFailures     int
+		expectedGets   int
+		expectedGetErr error
+		expectedPuts   int
+		expectedPutErr error
+	}{
+		{
+			desc:           "Gets are retried on transient errors but Puts are not by default",
+			inCfg:          config.Retry{MaxAttempts: 3},
+			inErr:          transientErr,
+			inFailures:     1,
+			expectedGets:   2,
+			expectedPuts:   1,
+			expectedPutErr: transientErr,
+		},
+		{
+			desc:         "Puts are retried when opted in",
+			inCfg:        config.Retry{MaxAttempts: 3, RetryPuts: true},
+			inErr:        transientErr,
+			inFailures:   1,
+			expectedGets: 2,
+			expectedPuts: 2,
+		},
+		{
+			desc:           "Retries stop after the max attempts",
+			inCfg:          config.Retry{MaxAttempts: 3, RetryPuts: true},
+			inErr:          transientErr,
+			inFailures:     5,
+			expectedGets:   3,
+			expectedGetErr: transientErr,
+			expectedPuts:   3,
+			expectedPutErr: transientErr,
+		},
+		{
+			desc:           "Key not found is not retried",
+			inCfg:          config.Retry{MaxAttempts: 3, RetryPuts: true},
+			inErr:          utils.KeyNotFoundError{},
+			inFailures:     1,
+			expectedGets:   1,
+			expectedGetErr: utils.KeyNotFoundError{},
+			expectedPuts:   1,
+			expectedPutErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:           "Invalid keys are not retried",
+			inCfg:          config.Retry{MaxAttempts: 3, RetryPuts: true},
+			inErr:          utils.KeyLengthError{},
+			inFailures:     1,
+			expectedGets:   1,
+			expectedGetErr: utils.KeyLengthError{},
+			expectedPuts:   1,
+			expectedPutErr: utils.KeyLengthError{},
+		},
+		{
+			desc:           "Conflicts are not retried",
+			inCfg:          config.Retry{MaxAttempts: 3, RetryPuts: true},
+			inErr:          conflictErr,
+			inFailures:     1,
+			expectedGets:   1,
+			expectedGetErr: conflictErr,
+			expectedPuts:   1,
+			expectedPutErr: conflictErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		delegate := &flakyBackend{err: tc.inErr, failures: tc.inFailures}
+		backend := RetryTransientErrors(delegate, tc.inCfg)
+
+		_, getErr := backend.Get(context.Background(), "key")
+		putErr := backend.Put(context.Background(), "key", "value", 0)
+
+		assert.Equal(t, tc.expectedGetErr, getErr, tc.desc)
+		assert.Equal(t, tc.expectedGets, delegate.gets, tc.desc)
+		assert.Equal(t, tc.expectedPutErr, putErr, tc.desc)
+		assert.Equal(t, tc.expectedPuts, delegate.puts, tc.desc)
+	}
+}
+
+func TestRetryMaxAttemptsOverride(t *testing.T) {
+	transientErr := errors.New("connection reset by peer")
+
+	testCases := []struct {
+		desc         string
+		inOverride   int
+		expectedGets int
+	}{
+		{
+			desc:         "Override raises the max attempts",
+			inOverride:   5,
+			expectedGets: 5,
+		},
+		{
+			desc:         "Override lowers the max attempts",
+			inOverride:   1,
+			expectedGets: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		delegate := &flakyBackend{err: transientErr, failures: 10}
+		backend := RetryTransientErrors(delegate, config.Retry{MaxAttempts: 2})
+
+		_, err := backend.Get(WithMaxAttempts(context.Background(), tc.inOverride), "key")
+
+		assert.Equal(t, transientErr, err, tc.desc)
+		assert.Equal(t, tc.expectedGets, delegate.gets, tc.desc)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         config.Retry
+		inRand        float64
+		inAttempt     int
+		expectedDelay time.Duration
+	}{
+		{
+			desc:          "First retry waits the backoff",
+			inCfg:         config.Retry{BackoffMillis: 10, MaxBackoffMillis: 100},
+			inAttempt:     2,
+			expectedDelay: 10 * time.Millisecond,
+		},
+		{
+			desc:          "Backoff doubles after every attempt",
+			inCfg:         config.Retry{BackoffMillis: 10, MaxBackoffMillis: 100},
+			inAttempt:     4,
+			expectedDelay: 40 * time.Millisecond,
+		},
+		{
+			desc:          "Backoff is capped",
+			inCfg:         config.Retry{BackoffMillis: 10, MaxBackoffMillis: 100},
+			inAttempt:     10,
+			expectedDelay: 100 * time.Millisecond,
+		},
+		{
+			desc:          "Uncapped backoff",
+			inCfg:         config.Retry{BackoffMillis: 10},
+			inAttempt:     10,
+			expectedDelay: 2560 * time.Millisecond,
+		},
+		{
+			desc:          "Jitter takes a random fraction off",
+			inCfg:         config.Retry{BackoffMillis: 10, MaxBackoffMillis: 100, Jitter: 0.5},
+			inRand:        0.5,
+			inAttempt:     3,
+			expectedDelay: 15 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := RetryTransientErrors(&flakyBackend{}, tc.inCfg).(*retryingBackend)
+		backend.randFloat = func() float64 { return tc.inRand }
+
+		assert.Equal(t, tc.expectedDelay, backend.backoffBefore(tc.inAttempt), tc.desc)
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	transientErr := errors.New("no connections available")
+	delegate := &flakyBackend{err: transientErr, failures: 10}
+	backend := RetryTransientErrors(delegate, config.Retry{MaxAttempts: 5, BackoffMillis: 500})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := backend.Get(ctx, "key")
+
+	assert.Equal(t, transientErr, err)
+	assert.Equal(t, 1, delegate.gets, "Retries that would outlast the deadline shouldn't be attempted")
+	assert.True(t, time.Since(start) < 100*time.Millisecond, "The error should be returned without waiting for the deadline")
+}
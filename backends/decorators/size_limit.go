@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
 )
 
 // EnforceSizeLimit rejects payloads over a max size.
@@ -45,3 +46,9 @@ type BadPayloadSize struct {
 func (p *BadPayloadSize) Error() string {
 	return "Payload size " + strconv.Itoa(p.size) + " exceeded max " + strconv.Itoa(p.limit)
 }
+
+// ErrorCode classifies payloads over the limit as too large. Empty payloads never reach this far,
+// because the endpoints reject them first.
+func (p *BadPayloadSize) ErrorCode() utils.ErrorCode {
+	return utils.ValueTooLarge
+}
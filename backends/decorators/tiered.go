@@ -0,0 +1,79 @@
+package decorators
+
+import (
+	"context"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tiered puts the near backend in front of the remote one. Gets are served by near when it holds the
+// key, and otherwise by remote, whose value is then kept in near for cfg.TTLSeconds. Puts write through
+// to remote, and then either store the value in near too if cfg.PrimeOnPut is set, or drop the one it
+// held. The hits and misses of each tier are counted.
+//
+// A value overwritten in remote while a Get was reading it can be served by near until it expires
+// there, so cfg.TTLSeconds bounds how stale Gets can be.
+func Tiered(near *backends.MemoryBackend, remote backends.Backend, cfg config.NearCache, m *metrics.Metrics) backends.Backend {
+	return &tieredBackend{
+		near:       near,
+		remote:     remote,
+		metrics:    m,
+		ttlSeconds: cfg.TTLSeconds,
+		primeOnPut: cfg.PrimeOnPut,
+	}
+}
+
+type tieredBackend struct {
+	near       *backends.MemoryBackend
+	remote     backends.Backend
+	metrics    *metrics.Metrics
+	ttlSeconds int
+	primeOnPut bool
+}
+
+func (b *tieredBackend) Get(ctx context.Context, key string) (string, error) {
+	if value, err := b.near.Get(ctx, key); err == nil {
+		b.metrics.RecordTierLookup(metrics.TierNear, true)
+		return value, nil
+	}
+	b.metrics.RecordTierLookup(metrics.TierNear, false)
+
+	value, err := b.remote.Get(ctx, key)
+	if err != nil {
+		if utils.ErrorCodeOf(err) == utils.NotFound {
+			b.metrics.RecordTierLookup(metrics.TierRemote, false)
+		}
+		return "", err
+	}
+	b.metrics.RecordTierLookup(metrics.TierRemote, true)
+	b.keepNear(key, value, b.ttlSeconds)
+	return value, nil
+}
+
+func (b *tieredBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	err := b.remote.Put(ctx, key, value, ttlSeconds)
+	// A failed Put may still have overwritten the value in remote, so near can't be trusted with it
+	if err != nil || !b.primeOnPut {
+		b.near.Delete(context.Background(), key)
+		return err
+	}
+
+	nearTTL := b.ttlSeconds
+	if ttlSeconds > 0 && ttlSeconds < nearTTL {
+		nearTTL = ttlSeconds
+	}
+	b.keepNear(key, value, nearTTL)
+	return nil
+}
+
+// keepNear stores the value in near regardless of the caller's context, since remote already holds it
+// by then. Values too large for near are left to remote.
+func (b *tieredBackend) keepNear(key string, value string, ttlSeconds int) {
+	if err := b.near.Put(context.Background(), key, value, ttlSeconds); err != nil {
+		log.Debugf("Key %s won't be kept in the near cache: %v", key, err)
+	}
+}
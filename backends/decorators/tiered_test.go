@@ -0,0 +1,136 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingBackend counts the Gets and Puts that reach its delegate
+type countingBackend struct {
+	backends.Backend
+	gets int
+	puts int
+}
+
+func (b *countingBackend) Get(ctx context.Context, key string) (string, error) {
+	b.gets++
+	return b.Backend.Get(ctx, key)
+}
+
+func (b *countingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	b.puts++
+	return b.Backend.Put(ctx, key, value, ttlSeconds)
+}
+
+func newTestTiers(primeOnPut bool) (*backends.MemoryBackend, *countingBackend, backends.Backend) {
+	near := backends.NewBoundedMemoryBackend(config.Memory{MaxEntries: 10, Shards: 1})
+	remote := &countingBackend{Backend: backends.NewMemoryBackend()}
+	cfg := config.NearCache{Enabled: true, MaxEntries: 10, TTLSeconds: 5, PrimeOnPut: primeOnPut}
+	return near, remote, Tiered(near, remote, cfg, metricstest.CreateMockMetrics())
+}
+
+func TestTieredNearHit(t *testing.T) {
+	near, remote, backend := newTestTiers(false)
+	near.Put(context.Background(), "key", "near-value", 5)
+
+	value, err := backend.Get(context.Background(), "key")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "near-value", value)
+	assert.Equal(t, 0, remote.gets, "Keys held near shouldn't reach the remote backend")
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.backends.tier.near.hit"])
+}
+
+func TestTieredMissThenPopulate(t *testing.T) {
+	near, remote, backend := newTestTiers(false)
+	remote.Backend.Put(context.Background(), "key", "remote-value", 60)
+
+	for i := 0; i < 3; i++ {
+		value, err := backend.Get(context.Background(), "key")
+		assert.NoError(t, err)
+		assert.Equal(t, "remote-value", value)
+	}
+
+	assert.Equal(t, 1, remote.gets, "Only the first Get should reach the remote backend")
+	value, err := near.Get(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "remote-value", value, "The remote value should be kept near")
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.backends.tier.near.miss"])
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.backends.tier.remote.hit"])
+	assert.Equal(t, int64(2), metricstest.MockCounters["gets.backends.tier.near.hit"])
+}
+
+func TestTieredMissingKey(t *testing.T) {
+	near, _, backend := newTestTiers(false)
+
+	_, err := backend.Get(context.Background(), "missing")
+
+	assert.Equal(t, utils.KeyNotFoundError{}, err)
+	_, err = near.Get(context.Background(), "missing")
+	assert.Equal(t, utils.KeyNotFoundError{}, err, "Misses shouldn't be kept near")
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.backends.tier.remote.miss"])
+}
+
+func TestTieredWriteThrough(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		inPrimeOnPut bool
+		expectedGets int
+	}{
+		{
+			desc:         "Puts drop the value held near",
+			expectedGets: 2,
+		},
+		{
+			desc:         "Puts prime the near cache",
+			inPrimeOnPut: true,
+			expectedGets: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, remote, backend := newTestTiers(tc.inPrimeOnPut)
+		ctx := context.Background()
+
+		assert.NoError(t, backend.Put(ctx, "key", "first", 60), tc.desc)
+		value, _ := backend.Get(ctx, "key")
+		assert.Equal(t, "first", value, tc.desc)
+
+		assert.NoError(t, backend.Put(ctx, "key", "second", 60), tc.desc)
+		value, _ = backend.Get(ctx, "key")
+		assert.Equal(t, "second", value, "%s: Gets shouldn't serve an overwritten value", tc.desc)
+
+		stored, _ := remote.Backend.Get(ctx, "key")
+		assert.Equal(t, "second", stored, "%s: Puts should write through to the remote backend", tc.desc)
+		assert.Equal(t, 2, remote.puts, tc.desc)
+		assert.Equal(t, tc.expectedGets, remote.gets, tc.desc)
+	}
+}
+
+// failingPutBackend fails every Put
+type failingPutBackend struct {
+	backends.Backend
+}
+
+func (b *failingPutBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return errors.New("connection reset by peer")
+}
+
+func TestTieredFailedPut(t *testing.T) {
+	near := backends.NewBoundedMemoryBackend(config.Memory{MaxEntries: 10, Shards: 1})
+	near.Put(context.Background(), "key", "stale", 5)
+	remote := &failingPutBackend{Backend: backends.NewMemoryBackend()}
+	backend := Tiered(near, remote, config.NearCache{TTLSeconds: 5, PrimeOnPut: true}, metricstest.CreateMockMetrics())
+
+	assert.Error(t, backend.Put(context.Background(), "key", "new", 60))
+
+	_, err := near.Get(context.Background(), "key")
+	assert.Equal(t, utils.KeyNotFoundError{}, err, "A failed Put should drop the value held near")
+}
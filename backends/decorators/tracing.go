@@ -0,0 +1,74 @@
+package decorators
+
+import (
+	"context"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/tracing"
+	"github.com/prebid/prebid-cache/utils"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedBackend struct {
+	delegate    backends.Backend
+	backendType string
+}
+
+// TraceOperations writes a span for every Get and Put, as a child of the span of the request they
+// serve. Spans record the key, the type of the backend and the result of the operation.
+func TraceOperations(backend backends.Backend, backendType config.BackendType) backends.Backend {
+	return &tracedBackend{
+		delegate:    backend,
+		backendType: string(backendType),
+	}
+}
+
+func (b *tracedBackend) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := b.start(ctx, "backend.Get", key)
+	defer span.End()
+
+	val, err := b.delegate.Get(ctx, key)
+	if utils.ErrorCodeOf(err) == utils.NotFound {
+		// Misses are an answer, not a failure of the backend
+		span.SetAttributes(tracing.ResultAttribute.String(tracing.ResultMiss))
+	} else if err == nil {
+		span.SetAttributes(tracing.ResultAttribute.String(tracing.ResultHit))
+	} else {
+		recordSpanError(span, err)
+	}
+	return val, err
+}
+
+func (b *tracedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	ctx, span := b.start(ctx, "backend.Put", key)
+	defer span.End()
+
+	err := b.delegate.Put(ctx, key, value, ttlSeconds)
+	if err == nil {
+		span.SetAttributes(tracing.ResultAttribute.String(tracing.ResultOK))
+	} else {
+		recordSpanError(span, err)
+	}
+	return err
+}
+
+func (b *tracedBackend) start(ctx context.Context, name string, key string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			tracing.KeyAttribute.String(key),
+			tracing.BackendAttribute.String(b.backendType),
+		))
+}
+
+func recordSpanError(span trace.Span, err error) {
+	result := tracing.ResultError
+	if utils.ErrorCodeOf(err) == utils.Timeout {
+		result = tracing.ResultTimeout
+	}
+	span.SetAttributes(tracing.ResultAttribute.String(result))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,128 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/tracing"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans keeps the spans of every tracer in memory until the returned function is called
+func recordSpans() (*tracetest.InMemoryExporter, func()) {
+	previous := otel.GetTracerProvider()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	return exporter, func() { otel.SetTracerProvider(previous) }
+}
+
+func storedBackend(key, value string) backends.Backend {
+	backend := backends.NewMemoryBackend()
+	backend.Put(context.Background(), key, value, 0)
+	return backend
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]string {
+	attributes := make(map[attribute.Key]string, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value.Emit()
+	}
+	return attributes
+}
+
+func TestTraceOperations(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inBackend      backends.Backend
+		run            func(backend backends.Backend)
+		expectedName   string
+		expectedResult string
+		expectedStatus codes.Code
+	}{
+		{
+			desc:           "Get of a stored key",
+			inBackend:      storedBackend("foo", "json{}"),
+			run:            func(backend backends.Backend) { backend.Get(context.Background(), "foo") },
+			expectedName:   "backend.Get",
+			expectedResult: tracing.ResultHit,
+			expectedStatus: codes.Unset,
+		},
+		{
+			desc:           "Get of a missing key isn't an error",
+			inBackend:      &failedBackend{returnError: utils.KeyNotFoundError{}},
+			run:            func(backend backends.Backend) { backend.Get(context.Background(), "foo") },
+			expectedName:   "backend.Get",
+			expectedResult: tracing.ResultMiss,
+			expectedStatus: codes.Unset,
+		},
+		{
+			desc:           "Get that times out",
+			inBackend:      &failedBackend{returnError: context.DeadlineExceeded},
+			run:            func(backend backends.Backend) { backend.Get(context.Background(), "foo") },
+			expectedName:   "backend.Get",
+			expectedResult: tracing.ResultTimeout,
+			expectedStatus: codes.Error,
+		},
+		{
+			desc:           "Put",
+			inBackend:      backends.NewMemoryBackend(),
+			run:            func(backend backends.Backend) { backend.Put(context.Background(), "foo", "json{}", 0) },
+			expectedName:   "backend.Put",
+			expectedResult: tracing.ResultOK,
+			expectedStatus: codes.Unset,
+		},
+		{
+			desc:           "Put that fails",
+			inBackend:      &failedBackend{returnError: errors.New("connection refused")},
+			run:            func(backend backends.Backend) { backend.Put(context.Background(), "foo", "json{}", 0) },
+			expectedName:   "backend.Put",
+			expectedResult: tracing.ResultError,
+			expectedStatus: codes.Error,
+		},
+	}
+
+	exporter, restore := recordSpans()
+	defer restore()
+
+	for _, tc := range testCases {
+		exporter.Reset()
+
+		tc.run(TraceOperations(tc.inBackend, config.BackendRedis))
+
+		spans := exporter.GetSpans()
+		if !assert.Len(t, spans, 1, tc.desc) {
+			continue
+		}
+		assert.Equal(t, tc.expectedName, spans[0].Name, tc.desc)
+		assert.Equal(t, tc.expectedStatus, spans[0].Status.Code, tc.desc)
+		assert.Equal(t, map[attribute.Key]string{
+			tracing.KeyAttribute:     "foo",
+			tracing.BackendAttribute: "redis",
+			tracing.ResultAttribute:  tc.expectedResult,
+		}, spanAttributes(spans[0]), tc.desc)
+	}
+}
+
+func TestTraceOperationsJoinsTheRequestSpan(t *testing.T) {
+	exporter, restore := recordSpans()
+	defer restore()
+
+	ctx, requestSpan := tracing.Tracer().Start(context.Background(), "GET /cache")
+	TraceOperations(storedBackend("foo", "json{}"), config.BackendMemory).Get(ctx, "foo")
+	requestSpan.End()
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, spans[1].SpanContext.TraceID(), spans[0].SpanContext.TraceID(), "The backend span should belong to the trace of the request")
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID(), "The backend span should be a child of the request span")
+	}
+}
@@ -0,0 +1,71 @@
+package decorators
+
+import (
+	"context"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// VerifyWrites wraps the delegate so that every successful Put reads its value back, and fails with a
+// WriteVerificationError if the value can't be read or isn't the one written. This catches backends that
+// acknowledge writes they then drop, at the cost of one extra read per Put.
+func VerifyWrites(delegate backends.Backend, m *metrics.Metrics) backends.Backend {
+	return &verifyingBackend{
+		delegate: delegate,
+		metrics:  m,
+	}
+}
+
+type verifyingBackend struct {
+	delegate backends.Backend
+	metrics  *metrics.Metrics
+}
+
+func (b *verifyingBackend) Get(ctx context.Context, key string) (string, error) {
+	return b.delegate.Get(ctx, key)
+}
+
+func (b *verifyingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := b.delegate.Put(ctx, key, value, ttlSeconds); err != nil {
+		return err
+	}
+
+	stored, err := b.delegate.Get(ctx, key)
+	if err != nil {
+		// Running out of time to read the value back says nothing about whether it was stored
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return b.fail(key, "it can't be read back: "+err.Error())
+	}
+	if stored != value {
+		return b.fail(key, "a different value was read back")
+	}
+	return nil
+}
+
+func (b *verifyingBackend) fail(key string, reason string) error {
+	log.Errorf("Put of key %s failed verification: %s", key, reason)
+	b.metrics.RecordPutVerificationFailure()
+	return &WriteVerificationError{Key: key, Reason: reason}
+}
+
+// WriteVerificationError is returned by backends wrapped by VerifyWrites when a value they reported
+// stored didn't read back the same
+type WriteVerificationError struct {
+	Key    string
+	Reason string
+}
+
+func (e *WriteVerificationError) Error() string {
+	return "Value of key " + e.Key + " was not stored: " + e.Reason
+}
+
+// ErrorCode classifies failed verifications as the backend being unavailable, since it isn't storing
+// what it's asked to
+func (e *WriteVerificationError) ErrorCode() utils.ErrorCode {
+	return utils.BackendUnavailable
+}
@@ -0,0 +1,80 @@
+package decorators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// droppingBackend acknowledges every Put without storing it
+type droppingBackend struct {
+	backends.Backend
+}
+
+func (b *droppingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return nil
+}
+
+// corruptingBackend stores something else than the values it's given
+type corruptingBackend struct {
+	backends.Backend
+}
+
+func (b *corruptingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return b.Backend.Put(ctx, key, value[:len(value)/2], ttlSeconds)
+}
+
+func TestVerifyWrites(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		inDelegate      backends.Backend
+		expectedFailure bool
+	}{
+		{
+			desc:       "Value read back as written",
+			inDelegate: backends.NewMemoryBackend(),
+		},
+		{
+			desc:            "Backend silently drops the write",
+			inDelegate:      &droppingBackend{Backend: backends.NewMemoryBackend()},
+			expectedFailure: true,
+		},
+		{
+			desc:            "Backend stores a different value",
+			inDelegate:      &corruptingBackend{Backend: backends.NewMemoryBackend()},
+			expectedFailure: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		backend := VerifyWrites(tc.inDelegate, m)
+
+		err := backend.Put(context.Background(), "key", "json{\"field\":\"value\"}", 0)
+
+		if tc.expectedFailure {
+			if assert.IsType(t, &WriteVerificationError{}, err, tc.desc) {
+				assert.Equal(t, "key", err.(*WriteVerificationError).Key, tc.desc)
+				assert.Equal(t, utils.BackendUnavailable, utils.ErrorCodeOf(err), tc.desc)
+			}
+			assert.Equal(t, int64(1), metricstest.MockCounters["puts.backends.verification_failed"], tc.desc)
+		} else {
+			assert.NoError(t, err, tc.desc)
+			assert.Equal(t, int64(0), metricstest.MockCounters["puts.backends.verification_failed"], tc.desc)
+		}
+	}
+}
+
+func TestVerifyWritesPutError(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	backend := VerifyWrites(&failedBackend{returnError: utils.NewBackendError(utils.Timeout, context.DeadlineExceeded)}, m)
+
+	err := backend.Put(context.Background(), "key", "value", 0)
+
+	assert.Equal(t, utils.NewBackendError(utils.Timeout, context.DeadlineExceeded), err, "Errors of the Put itself should be returned as they are")
+	assert.Equal(t, int64(0), metricstest.MockCounters["puts.backends.verification_failed"], "Failed Puts are not verified")
+}
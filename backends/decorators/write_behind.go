@@ -0,0 +1,87 @@
+package decorators
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const writeBehindTimeout = 5 * time.Second
+
+// WriteBehind wraps the delegate so that Puts return as soon as the value is queued, while a pool of
+// workers writes the queued values to the delegate in the background. Values waiting to be written are
+// served by Get straight from the queue. When the queue is full, Puts write to the delegate themselves.
+//
+// Errors from background writes can't reach the client anymore, so they are only logged.
+func WriteBehind(delegate backends.Backend, cfg config.WriteBehind) backends.Backend {
+	b := &writeBehindBackend{
+		delegate: delegate,
+		pending:  make(map[string]*pendingPut),
+		queue:    make(chan *pendingPut, cfg.BufferSize),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		go b.work()
+	}
+	return b
+}
+
+type writeBehindBackend struct {
+	delegate backends.Backend
+	mu       sync.Mutex
+	pending  map[string]*pendingPut
+	queue    chan *pendingPut
+}
+
+type pendingPut struct {
+	key        string
+	value      string
+	ttlSeconds int
+}
+
+func (b *writeBehindBackend) Get(ctx context.Context, key string) (string, error) {
+	b.mu.Lock()
+	p, ok := b.pending[key]
+	b.mu.Unlock()
+	if ok {
+		return p.value, nil
+	}
+	return b.delegate.Get(ctx, key)
+}
+
+func (b *writeBehindBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	p := &pendingPut{key: key, value: value, ttlSeconds: ttlSeconds}
+
+	b.mu.Lock()
+	select {
+	case b.queue <- p:
+		b.pending[key] = p
+		b.mu.Unlock()
+		return nil
+	default:
+		b.mu.Unlock()
+	}
+
+	log.Debugf("Write behind queue is full. Writing key %s synchronously", key)
+	return b.delegate.Put(ctx, key, value, ttlSeconds)
+}
+
+func (b *writeBehindBackend) work() {
+	for p := range b.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), writeBehindTimeout)
+		if err := b.delegate.Put(ctx, p.key, p.value, p.ttlSeconds); err != nil {
+			log.Errorf("Write behind of key %s failed: %v", p.key, err)
+		}
+		cancel()
+
+		b.mu.Lock()
+		// A newer Put of the same key may be waiting in the queue
+		if b.pending[p.key] == p {
+			delete(b.pending, p.key)
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,71 @@
+package decorators
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedBackend only lets Puts through once its gate is opened
+type gatedBackend struct {
+	backends.Backend
+	gate chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (b *gatedBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	<-b.gate
+	defer b.wg.Done()
+	return b.Backend.Put(ctx, key, value, ttlSeconds)
+}
+
+func TestWriteBehind(t *testing.T) {
+	delegate := &gatedBackend{Backend: backends.NewMemoryBackend(), gate: make(chan struct{})}
+	backend := WriteBehind(delegate, config.WriteBehind{Enabled: true, BufferSize: 10, Workers: 1})
+
+	delegate.wg.Add(1)
+	assert.NoError(t, backend.Put(context.Background(), "key", "value", 0), "Put should return before the value is written")
+
+	_, err := delegate.Backend.Get(context.Background(), "key")
+	assert.Error(t, err, "Value should not be written yet")
+
+	value, err := backend.Get(context.Background(), "key")
+	assert.NoError(t, err, "Queued value should be served")
+	assert.Equal(t, "value", value)
+
+	close(delegate.gate)
+	delegate.wg.Wait()
+
+	value, err = delegate.Backend.Get(context.Background(), "key")
+	assert.NoError(t, err, "Value should be written in the background")
+	assert.Equal(t, "value", value)
+}
+
+func TestWriteBehindFullQueue(t *testing.T) {
+	delegate := &gatedBackend{Backend: backends.NewMemoryBackend(), gate: make(chan struct{})}
+	// No workers, so the queue never gets drained
+	backend := WriteBehind(delegate, config.WriteBehind{Enabled: true, BufferSize: 1, Workers: 0})
+
+	assert.NoError(t, backend.Put(context.Background(), "queued", "value", 0))
+
+	delegate.wg.Add(1)
+	done := make(chan error)
+	go func() { done <- backend.Put(context.Background(), "synchronous", "value", 0) }()
+
+	select {
+	case <-done:
+		t.Fatalf("Put should write synchronously once the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(delegate.gate)
+	assert.NoError(t, <-done)
+	value, err := delegate.Backend.Get(context.Background(), "synchronous")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
@@ -0,0 +1,176 @@
+package backends
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+
+	as_types "github.com/aerospike/aerospike-client-go/types"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/couchbase/gocb/v2"
+	"github.com/go-redis/redis"
+	"github.com/gocql/gocql"
+	"github.com/lib/pq"
+	"github.com/nats-io/nats.go"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// netTimeoutError is a net.Error like the ones the clients return when a read or write times out
+type netTimeoutError struct{}
+
+func (netTimeoutError) Error() string   { return "i/o timeout" }
+func (netTimeoutError) Timeout() bool   { return true }
+func (netTimeoutError) Temporary() bool { return true }
+
+type errorCodeTestCase struct {
+	desc         string
+	inErr        error
+	expectedCode utils.ErrorCode
+}
+
+func assertErrorCodes(t *testing.T, classify func(error) error, testCases []errorCodeTestCase) {
+	t.Helper()
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedCode, utils.ErrorCodeOf(classify(tc.inErr)), tc.desc)
+	}
+}
+
+func TestAerospikeErrorCodes(t *testing.T) {
+	assertErrorCodes(t, formatAerospikeError, []errorCodeTestCase{
+		{"Key not found", as_types.NewAerospikeError(as_types.KEY_NOT_FOUND_ERROR), utils.NotFound},
+		{"Timeout", as_types.NewAerospikeError(as_types.TIMEOUT), utils.Timeout},
+		{"Record too big", as_types.NewAerospikeError(as_types.RECORD_TOO_BIG), utils.ValueTooLarge},
+		{"Key already exists", as_types.NewAerospikeError(as_types.KEY_EXISTS_ERROR), utils.Conflict},
+		{"Generation mismatch", as_types.NewAerospikeError(as_types.GENERATION_ERROR), utils.Conflict},
+		{"Server not available", as_types.NewAerospikeError(as_types.SERVER_NOT_AVAILABLE), utils.BackendUnavailable},
+		{"Not an Aerospike error", errors.New("Nil record"), utils.BackendUnavailable},
+	})
+}
+
+func TestCassandraErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyCassandraError, []errorCodeTestCase{
+		{"Not found", gocql.ErrNotFound, utils.NotFound},
+		{"No response", gocql.ErrTimeoutNoResponse, utils.Timeout},
+		{"Read timeout", &gocql.RequestErrReadTimeout{}, utils.Timeout},
+		{"Write timeout", &gocql.RequestErrWriteTimeout{}, utils.Timeout},
+		{"Context deadline", context.DeadlineExceeded, utils.Timeout},
+		{"No hosts available", gocql.ErrNoConnections, utils.BackendUnavailable},
+	})
+}
+
+func TestEtcdErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyEtcdError, []errorCodeTestCase{
+		{"Request timed out", rpctypes.ErrTimeout, utils.Timeout},
+		{"gRPC request timed out", rpctypes.ErrGRPCTimeout, utils.Timeout},
+		{"Timed out on leader failure", rpctypes.ErrTimeoutDueToLeaderFail, utils.Timeout},
+		{"Timed out on connection lost", rpctypes.ErrTimeoutDueToConnectionLost, utils.Timeout},
+		{"Context deadline", context.DeadlineExceeded, utils.Timeout},
+		{"Request too large", rpctypes.ErrRequestTooLarge, utils.ValueTooLarge},
+		{"gRPC request too large", rpctypes.ErrGRPCRequestTooLarge, utils.ValueTooLarge},
+		{"No leader", rpctypes.ErrNoLeader, utils.BackendUnavailable},
+		{"No available endpoints", clientv3.ErrNoAvailableEndpoints, utils.BackendUnavailable},
+	})
+}
+
+func TestMemcacheErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyMemcacheError, []errorCodeTestCase{
+		{"Cache miss", memcache.ErrCacheMiss, utils.NotFound},
+		{"Not stored", memcache.ErrNotStored, utils.Conflict},
+		{"CAS conflict", memcache.ErrCASConflict, utils.Conflict},
+		{"Connect timeout", &memcache.ConnectTimeoutError{Addr: &net.TCPAddr{}}, utils.Timeout},
+		{"I/O timeout", netTimeoutError{}, utils.Timeout},
+		{"No servers", memcache.ErrNoServers, utils.BackendUnavailable},
+	})
+}
+
+func TestRedisErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyRedisError, []errorCodeTestCase{
+		{"Nil reply", redis.Nil, utils.NotFound},
+		{"I/O timeout", netTimeoutError{}, utils.Timeout},
+		{"Connection refused", errors.New("dial tcp: connection refused"), utils.BackendUnavailable},
+	})
+}
+
+func TestCouchbaseErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyCouchbaseError, []errorCodeTestCase{
+		{"Document not found", gocb.ErrDocumentNotFound, utils.NotFound},
+		{"Timeout", gocb.ErrTimeout, utils.Timeout},
+		{"Unambiguous timeout", gocb.ErrUnambiguousTimeout, utils.Timeout},
+		{"Ambiguous timeout", gocb.ErrAmbiguousTimeout, utils.Timeout},
+		{"Value too large", gocb.ErrValueTooLarge, utils.ValueTooLarge},
+		{"Document exists", gocb.ErrDocumentExists, utils.Conflict},
+		{"CAS mismatch", gocb.ErrCasMismatch, utils.Conflict},
+		{"Service not available", gocb.ErrServiceNotAvailable, utils.BackendUnavailable},
+	})
+}
+
+func TestNATSErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyNATSError, []errorCodeTestCase{
+		{"Key not found", nats.ErrKeyNotFound, utils.NotFound},
+		{"Timeout", nats.ErrTimeout, utils.Timeout},
+		{"Max payload", nats.ErrMaxPayload, utils.ValueTooLarge},
+		{"Key exists", nats.ErrKeyExists, utils.Conflict},
+		{"No servers", nats.ErrNoServers, utils.BackendUnavailable},
+		{"Connection closed", nats.ErrConnectionClosed, utils.BackendUnavailable},
+	})
+}
+
+func TestPostgresErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyPostgresError, []errorCodeTestCase{
+		{"No rows", sql.ErrNoRows, utils.NotFound},
+		{"Query canceled", &pq.Error{Code: postgresQueryCanceled}, utils.Timeout},
+		{"Program limit exceeded", &pq.Error{Code: postgresProgramLimit}, utils.ValueTooLarge},
+		{"String too long", &pq.Error{Code: postgresStringTooLong}, utils.ValueTooLarge},
+		{"Unique violation", &pq.Error{Code: postgresUniqueViolation}, utils.Conflict},
+		{"Serialization failure", &pq.Error{Code: postgresSerializationFail}, utils.Conflict},
+		{"Other Postgres error", &pq.Error{Code: "53300"}, utils.BackendUnavailable},
+		{"Connection refused", errors.New("connection refused"), utils.BackendUnavailable},
+	})
+}
+
+func TestAzureErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyAzureError, []errorCodeTestCase{
+		{"Timeout", fasthttp.ErrTimeout, utils.Timeout},
+		{"Connection refused", errors.New("dial tcp: connection refused"), utils.BackendUnavailable},
+	})
+
+	statusTestCases := []struct {
+		inStatus     int
+		expectedCode utils.ErrorCode
+	}{
+		{fasthttp.StatusConflict, utils.Conflict},
+		{fasthttp.StatusRequestEntityTooLarge, utils.ValueTooLarge},
+		{fasthttp.StatusRequestTimeout, utils.Timeout},
+		{fasthttp.StatusServiceUnavailable, utils.BackendUnavailable},
+		{fasthttp.StatusForbidden, utils.BackendUnavailable},
+	}
+	for _, tc := range statusTestCases {
+		assert.Equal(t, tc.expectedCode, utils.ErrorCodeOf(azureStatusError(tc.inStatus)), "Azure status %d", tc.inStatus)
+	}
+	assert.NoError(t, azureStatusError(fasthttp.StatusCreated), "Successful writes are not errors")
+}
+
+func TestS3ErrorCodes(t *testing.T) {
+	assertErrorCodes(t, classifyS3Error, []errorCodeTestCase{
+		{"No such key", awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil), utils.NotFound},
+		{"Bare 404", awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, ""), utils.NotFound},
+		{"Entity too large", awserr.New("EntityTooLarge", "", nil), utils.ValueTooLarge},
+		{"Request timeout", awserr.New("RequestTimeout", "", nil), utils.Timeout},
+		{"No such bucket", awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchBucket, "", nil), 404, ""), utils.BackendUnavailable},
+		{"Slow down", awserr.NewRequestFailure(awserr.New("SlowDown", "", nil), 503, ""), utils.BackendUnavailable},
+	})
+}
+
+func TestMemoryErrorCodes(t *testing.T) {
+	_, err := NewMemoryBackend().Get(context.Background(), "unknownKey")
+	assert.Equal(t, utils.NotFound, utils.ErrorCodeOf(err), "Missing keys should be reported as not found")
+}
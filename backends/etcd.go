@@ -0,0 +1,143 @@
+package backends
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdDB is an interface that helps us communicate with an etcd cluster
+type EtcdDB interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Grant(ctx context.Context, ttlSeconds int) (clientv3.LeaseID, error)
+	Put(ctx context.Context, key string, value string, lease clientv3.LeaseID) error
+}
+
+// EtcdDBClient is a wrapper for the etcd client that stores our values
+type EtcdDBClient struct {
+	client *clientv3.Client
+}
+
+func (db EtcdDBClient) Get(ctx context.Context, key string) (string, bool, error) {
+	resp, err := db.client.Get(ctx, key)
+	if err != nil || len(resp.Kvs) == 0 {
+		return "", false, err
+	}
+	return string(resp.Kvs[0].Value), true, nil
+}
+
+func (db EtcdDBClient) Grant(ctx context.Context, ttlSeconds int) (clientv3.LeaseID, error) {
+	resp, err := db.client.Grant(ctx, int64(ttlSeconds))
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	return resp.ID, nil
+}
+
+func (db EtcdDBClient) Put(ctx context.Context, key string, value string, lease clientv3.LeaseID) error {
+	if lease == clientv3.NoLease {
+		_, err := db.client.Put(ctx, key, value)
+		return err
+	}
+	_, err := db.client.Put(ctx, key, value, clientv3.WithLease(lease))
+	return err
+}
+
+// EtcdBackend stores values as etcd keys and relies on leases to honor the TTL of every Put request
+type EtcdBackend struct {
+	cfg    config.Etcd
+	client EtcdDB
+}
+
+// NewEtcdBackend connects to the etcd cluster found at the configured endpoints
+func NewEtcdBackend(cfg config.Etcd) *EtcdBackend {
+	etcdCfg := clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		DialTimeout: time.Duration(cfg.DialTimeoutMillis) * time.Millisecond,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := etcdTLSConfig(cfg.TLS)
+		if err != nil {
+			log.Fatalf("Error creating etcd backend: %v", err)
+			panic("EtcdBackend failure. This shouldn't happen.")
+		}
+		etcdCfg.TLS = tlsConfig
+	}
+
+	client, err := clientv3.New(etcdCfg)
+	if err != nil {
+		log.Fatalf("Error creating etcd backend: %v", err)
+		panic("EtcdBackend failure. This shouldn't happen.")
+	}
+	log.Infof("Connected to etcd at %v", cfg.Endpoints)
+
+	return &EtcdBackend{
+		cfg:    cfg,
+		client: EtcdDBClient{client: client},
+	}
+}
+
+// etcdTLSConfig loads the client certificate and the certificate authority the config points to.
+// Either one can be left out.
+func etcdTLSConfig(cfg config.EtcdTLS) (*tls.Config, error) {
+	return clientTLSConfig(cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+}
+
+func (e *EtcdBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	value, found, err := e.client.Get(ctx, key)
+	if err != nil {
+		return "", classifyEtcdError(err)
+	}
+	if !found {
+		return "", utils.KeyNotFoundError{}
+	}
+	return value, nil
+}
+
+func (e *EtcdBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Every value gets a lease of its own, so etcd deletes the key once its TTL is over. Values put
+	// with a zero TTL never expire.
+	lease := clientv3.NoLease
+	if ttlSeconds > 0 {
+		var err error
+		if lease, err = e.client.Grant(ctx, ttlSeconds); err != nil {
+			return classifyEtcdError(err)
+		}
+	}
+	return classifyEtcdError(e.client.Put(ctx, key, value, lease))
+}
+
+// classifyEtcdError maps the errors of the etcd client to their error code. The client turns the gRPC
+// errors of the server into their rpctypes equivalent, but either one is matched.
+func classifyEtcdError(err error) error {
+	switch {
+	case errors.Is(err, rpctypes.ErrTimeout), errors.Is(err, rpctypes.ErrGRPCTimeout),
+		errors.Is(err, rpctypes.ErrTimeoutDueToLeaderFail), errors.Is(err, rpctypes.ErrGRPCTimeoutDueToLeaderFail),
+		errors.Is(err, rpctypes.ErrTimeoutDueToConnectionLost), errors.Is(err, rpctypes.ErrGRPCTimeoutDueToConnectionLost):
+		return utils.NewBackendError(utils.Timeout, err)
+	case errors.Is(err, rpctypes.ErrRequestTooLarge), errors.Is(err, rpctypes.ErrGRPCRequestTooLarge):
+		return utils.NewBackendError(utils.ValueTooLarge, err)
+	case errors.Is(err, rpctypes.ErrNoLeader), errors.Is(err, rpctypes.ErrGRPCNoLeader),
+		errors.Is(err, clientv3.ErrNoAvailableEndpoints):
+		return utils.NewBackendError(utils.BackendUnavailable, err)
+	}
+	return err
+}
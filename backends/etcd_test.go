@@ -0,0 +1,157 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Mock etcd client that keeps its keys and leases in memory
+type fakeEtcdClient struct {
+	values    map[string]string
+	leases    map[string]clientv3.LeaseID
+	leaseTTLs map[clientv3.LeaseID]int
+	nextLease clientv3.LeaseID
+	getErr    error
+	grantErr  error
+	putErr    error
+}
+
+func newFakeEtcdClient() *fakeEtcdClient {
+	return &fakeEtcdClient{
+		values:    map[string]string{"defaultKey": "Default value"},
+		leases:    map[string]clientv3.LeaseID{},
+		leaseTTLs: map[clientv3.LeaseID]int{},
+	}
+}
+
+func (c *fakeEtcdClient) Get(ctx context.Context, key string) (string, bool, error) {
+	if c.getErr != nil {
+		return "", false, c.getErr
+	}
+	value, found := c.values[key]
+	return value, found, nil
+}
+
+func (c *fakeEtcdClient) Grant(ctx context.Context, ttlSeconds int) (clientv3.LeaseID, error) {
+	if c.grantErr != nil {
+		return clientv3.NoLease, c.grantErr
+	}
+	c.nextLease++
+	c.leaseTTLs[c.nextLease] = ttlSeconds
+	return c.nextLease, nil
+}
+
+func (c *fakeEtcdClient) Put(ctx context.Context, key string, value string, lease clientv3.LeaseID) error {
+	if c.putErr != nil {
+		return c.putErr
+	}
+	c.values[key] = value
+	c.leases[key] = lease
+	return nil
+}
+
+func TestEtcdGet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inKey         string
+		getErr        error
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:          "EtcdBackend.Get() key found",
+			inKey:         "defaultKey",
+			expectedValue: "Default value",
+		},
+		{
+			desc:        "EtcdBackend.Get() key not found",
+			inKey:       "unknownKey",
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:        "EtcdBackend.Get() client error",
+			inKey:       "defaultKey",
+			getErr:      errors.New("some error"),
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeEtcdClient()
+		client.getErr = tc.getErr
+		backend := &EtcdBackend{client: client}
+
+		value, err := backend.Get(context.Background(), tc.inKey)
+
+		assert.Equal(t, tc.expectedValue, value, tc.desc)
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+	}
+}
+
+func TestEtcdPut(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		inTTLSeconds     int
+		grantErr         error
+		putErr           error
+		expectLease      bool
+		expectedLeaseTTL int
+		expectedErr      error
+	}{
+		{
+			desc:             "EtcdBackend.Put() attaches the value to a lease of the requested ttl",
+			inTTLSeconds:     60,
+			expectLease:      true,
+			expectedLeaseTTL: 60,
+		},
+		{
+			desc: "EtcdBackend.Put() with a zero ttl stores a value without a lease",
+		},
+		{
+			desc:         "EtcdBackend.Put() lease couldn't be granted",
+			inTTLSeconds: 60,
+			grantErr:     errors.New("lease error"),
+			expectedErr:  errors.New("lease error"),
+		},
+		{
+			desc:         "EtcdBackend.Put() client error",
+			inTTLSeconds: 60,
+			putErr:       errors.New("some error"),
+			expectedErr:  errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeEtcdClient()
+		client.grantErr = tc.grantErr
+		client.putErr = tc.putErr
+		backend := &EtcdBackend{client: client}
+
+		err := backend.Put(context.Background(), "someKey", "someValue", tc.inTTLSeconds)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		if tc.expectedErr != nil {
+			_, stored := client.values["someKey"]
+			assert.False(t, stored, tc.desc)
+			continue
+		}
+
+		value, getErr := backend.Get(context.Background(), "someKey")
+		assert.NoError(t, getErr, tc.desc)
+		assert.Equal(t, "someValue", value, tc.desc)
+
+		lease := client.leases["someKey"]
+		if tc.expectLease {
+			assert.NotEqual(t, clientv3.NoLease, lease, tc.desc)
+			assert.Equal(t, tc.expectedLeaseTTL, client.leaseTTLs[lease], tc.desc)
+		} else {
+			assert.Equal(t, clientv3.NoLease, lease, tc.desc)
+			assert.Empty(t, client.leaseTTLs, tc.desc)
+		}
+	}
+}
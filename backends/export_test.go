@@ -0,0 +1,7 @@
+package backends
+
+// NewCassandraBackendWithClient returns a Cassandra backend that runs its queries with the given client,
+// for the tests of other packages
+func NewCassandraBackendWithClient(client CassandraDB) *Cassandra {
+	return &Cassandra{client: client}
+}
@@ -2,9 +2,11 @@ package backends
 
 import (
 	"context"
+	"net"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
 )
 
 // MemcacheConfig is used to configure the cluster
@@ -26,16 +28,24 @@ func NewMemcacheBackend(cfg config.Memcache) *Memcache {
 }
 
 func (mc *Memcache) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	res, err := mc.client.Get(key)
 
 	if err != nil {
-		return "", err
+		return "", classifyMemcacheError(err)
 	}
 
 	return string(res.Value), nil
 }
 
 func (mc *Memcache) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	err := mc.client.Set(&memcache.Item{
 		Expiration: int32(ttlSeconds),
 		Key:        key,
@@ -43,8 +53,26 @@ func (mc *Memcache) Put(ctx context.Context, key string, value string, ttlSecond
 	})
 
 	if err != nil {
-		return err
+		return classifyMemcacheError(err)
 	}
 
 	return nil
 }
+
+// classifyMemcacheError maps the errors of the memcache client to their error code
+func classifyMemcacheError(err error) error {
+	switch err {
+	case memcache.ErrCacheMiss:
+		return utils.KeyNotFoundError{}
+	case memcache.ErrNotStored, memcache.ErrCASConflict:
+		return utils.NewBackendError(utils.Conflict, err)
+	}
+
+	if _, ok := err.(*memcache.ConnectTimeoutError); ok {
+		return utils.NewBackendError(utils.Timeout, err)
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return utils.NewBackendError(utils.Timeout, err)
+	}
+	return err
+}
@@ -1,38 +1,284 @@
 package backends
 
 import (
+	"container/list"
 	"context"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
 )
 
+// MemoryBackend keeps the values in memory, spread across shards by the hash of their key. Each shard
+// has its own lock and, when a budget of entries or bytes is set, its own least recently used list, so
+// that neither contention nor eviction crosses shards. Values put with a TTL are evicted once it's over, the next
+// time they're read or when they're the least recently used value of a full shard.
 type MemoryBackend struct {
-	db map[string]string
+	shards []*memoryShard
+	hooks  []EvictionHook
+	now    func() time.Time
+}
+
+type memoryShard struct {
 	mu sync.Mutex
+	db map[string]*list.Element
+	// lru holds the entries from most to least recently used
+	lru        *list.List
+	maxEntries int
+	// bytes is the combined size of the entries held, which maxBytes caps
+	bytes    int
+	maxBytes int
+}
+
+type memoryEntry struct {
+	key      string
+	value    string
+	storedAt time.Time
+	// expiresAt is zero for values put without a TTL
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// size is what the entry counts for in the byte budget of its shard
+func (e *memoryEntry) size() int {
+	return len(e.key) + len(e.value)
+}
+
+// EvictionReason tells why the memory backend evicted a value
+type EvictionReason string
+
+const (
+	EvictedTTL    EvictionReason = "ttl"
+	EvictedLRU    EvictionReason = "lru"
+	EvictedManual EvictionReason = "manual"
+)
+
+// Eviction is a value the memory backend evicted
+type Eviction struct {
+	Key    string
+	Reason EvictionReason
+	// Age is how long the value had been stored for
+	Age time.Duration
+}
+
+// EvictionHook gets called with every value the memory backend evicts, once the shard the value was in
+// is unlocked, so hooks may use the backend themselves.
+type EvictionHook func(Eviction)
+
+// OnEvict adds a hook to call on every eviction. Hooks must be added before the backend is used.
+func (b *MemoryBackend) OnEvict(hook EvictionHook) {
+	b.hooks = append(b.hooks, hook)
 }
 
 func (b *MemoryBackend) Get(ctx context.Context, key string) (string, error) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
-	v, ok := b.db[key]
+	now := b.now()
+	shard := b.shardFor(key)
+	shard.mu.Lock()
+
+	elem, ok := shard.db[key]
 	if !ok {
-		return "", fmt.Errorf("Not found")
+		shard.mu.Unlock()
+		return "", utils.KeyNotFoundError{}
+	}
+	entry := elem.Value.(*memoryEntry)
+	if entry.expired(now) {
+		eviction := shard.evict(elem, EvictedTTL, now)
+		shard.mu.Unlock()
+		b.notify(eviction)
+		return "", utils.KeyNotFoundError{}
 	}
+	shard.lru.MoveToFront(elem)
+	value := entry.value
+	shard.mu.Unlock()
 
-	return v, nil
+	return value, nil
 }
 
 func (b *MemoryBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	return b.put(ctx, key, value, ttlSeconds, false)
+}
+
+// PutIfAbsent stores the value unless key holds one that hasn't expired yet. Expired values get
+// replaced, like they would have been evicted.
+func (b *MemoryBackend) PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return b.put(ctx, key, value, ttlSeconds, true)
+}
+
+func (b *MemoryBackend) put(ctx context.Context, key string, value string, ttlSeconds int, onlyIfAbsent bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := b.now()
+	var expiresAt time.Time
+	if ttlSeconds > 0 {
+		expiresAt = now.Add(time.Duration(ttlSeconds) * time.Second)
+	}
+
+	shard := b.shardFor(key)
+	newEntry := &memoryEntry{key: key, value: value, storedAt: now, expiresAt: expiresAt}
+	if shard.maxBytes > 0 && newEntry.size() > shard.maxBytes {
+		return utils.NewBackendError(utils.ValueTooLarge, fmt.Errorf("value of %d bytes doesn't fit the %d bytes of its memory shard", newEntry.size(), shard.maxBytes))
+	}
+
+	shard.mu.Lock()
+	if elem, ok := shard.db[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		if onlyIfAbsent && !entry.expired(now) {
+			shard.mu.Unlock()
+			return utils.KeyExistsError{}
+		}
+		shard.bytes += newEntry.size() - entry.size()
+		entry.value, entry.storedAt, entry.expiresAt = value, now, expiresAt
+		shard.lru.MoveToFront(elem)
+	} else {
+		shard.db[key] = shard.lru.PushFront(newEntry)
+		shard.bytes += newEntry.size()
+	}
 
-	b.db[key] = value
+	// The value just put is the most recently used, so it's never evicted to make room for itself
+	var evictions []Eviction
+	for shard.overBudget() {
+		// Values whose TTL is over count as expired even though they're evicted to make room
+		oldest := shard.lru.Back()
+		reason := EvictedLRU
+		if oldest.Value.(*memoryEntry).expired(now) {
+			reason = EvictedTTL
+		}
+		evictions = append(evictions, shard.evict(oldest, reason, now))
+	}
+	shard.mu.Unlock()
+
+	for _, eviction := range evictions {
+		b.notify(eviction)
+	}
+	return nil
+}
+
+// overBudget tells whether the shard, which must be locked by the caller, holds more than it should
+func (s *memoryShard) overBudget() bool {
+	return (s.maxEntries > 0 && s.lru.Len() > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// Delete evicts the value of key, if there's one
+func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shard := b.shardFor(key)
+	shard.mu.Lock()
+
+	elem, ok := shard.db[key]
+	if !ok {
+		shard.mu.Unlock()
+		return nil
+	}
+	eviction := shard.evict(elem, EvictedManual, b.now())
+	shard.mu.Unlock()
+	b.notify(eviction)
 	return nil
 }
 
+// evict removes the entry from the shard, which must be locked by the caller
+func (s *memoryShard) evict(elem *list.Element, reason EvictionReason, now time.Time) Eviction {
+	entry := elem.Value.(*memoryEntry)
+	s.lru.Remove(elem)
+	delete(s.db, entry.key)
+	s.bytes -= entry.size()
+	return Eviction{Key: entry.key, Reason: reason, Age: now.Sub(entry.storedAt)}
+}
+
+func (b *MemoryBackend) notify(eviction Eviction) {
+	for _, hook := range b.hooks {
+		hook(eviction)
+	}
+}
+
+// ScanKeys walks through the keys in lexical order. The cursor is the position of the next key in that order.
+func (b *MemoryBackend) ScanKeys(ctx context.Context, cursor uint64, count int) ([]string, uint64, error) {
+	keys := make([]string, 0)
+	for _, shard := range b.shards {
+		shard.mu.Lock()
+		for k := range shard.db {
+			keys = append(keys, k)
+		}
+		shard.mu.Unlock()
+	}
+	sort.Strings(keys)
+
+	if cursor >= uint64(len(keys)) {
+		return nil, 0, nil
+	}
+	end := cursor + uint64(count)
+	if end >= uint64(len(keys)) {
+		return keys[cursor:], 0, nil
+	}
+	return keys[cursor:end], end, nil
+}
+
+func (b *MemoryBackend) shardFor(key string) *memoryShard {
+	if len(b.shards) == 1 {
+		return b.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return b.shards[h.Sum32()%uint32(len(b.shards))]
+}
+
+// NewMemoryBackend returns a MemoryBackend without any limit on the number of values it holds
 func NewMemoryBackend() *MemoryBackend {
-	return &MemoryBackend{
-		db: make(map[string]string),
+	return NewBoundedMemoryBackend(config.Memory{Shards: 1})
+}
+
+// NewBoundedMemoryBackend returns a MemoryBackend split in cfg.Shards shards. The cfg.MaxEntries and
+// cfg.MaxBytes budgets are apportioned across them as evenly as possible, and once a shard is full,
+// storing a new value evicts the least recently used ones of that same shard until it fits. Values
+// larger than the byte budget of their shard are rejected. A budget of zero means no limit.
+func NewBoundedMemoryBackend(cfg config.Memory) *MemoryBackend {
+	numShards := cfg.Shards
+	if numShards < 1 {
+		numShards = 1
 	}
+
+	shards := make([]*memoryShard, numShards)
+	for i := range shards {
+		shards[i] = &memoryShard{
+			db:  make(map[string]*list.Element),
+			lru: list.New(),
+		}
+		if cfg.MaxEntries > 0 {
+			shards[i].maxEntries = cfg.MaxEntries / numShards
+			if i < cfg.MaxEntries%numShards {
+				shards[i].maxEntries++
+			}
+			// A budget smaller than the number of shards still lets every shard hold a value
+			if shards[i].maxEntries == 0 {
+				shards[i].maxEntries = 1
+			}
+		}
+		if cfg.MaxBytes > 0 {
+			shards[i].maxBytes = cfg.MaxBytes / numShards
+			if i < cfg.MaxBytes%numShards {
+				shards[i].maxBytes++
+			}
+			// Same for the byte budget, where zero would leave the shard unbounded
+			if shards[i].maxBytes == 0 {
+				shards[i].maxBytes = 1
+			}
+		}
+	}
+
+	return &MemoryBackend{shards: shards, now: time.Now}
 }
@@ -0,0 +1,256 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	prometheusMetrics "github.com/prebid/prebid-cache/metrics/prometheus"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackendShardLocalEviction(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{MaxEntries: 10, Shards: 4})
+
+	// 10 entries across 4 shards are apportioned as 3, 3, 2 and 2
+	budgets := make([]int, len(backend.shards))
+	total := 0
+	for i, shard := range backend.shards {
+		budgets[i] = shard.maxEntries
+		total += shard.maxEntries
+	}
+	assert.Equal(t, []int{3, 3, 2, 2}, budgets, "Budget should be apportioned evenly")
+	assert.Equal(t, 10, total, "Shard budgets should add up to the global budget")
+
+	// Fill shard 0 way over its budget and put a single value in every other shard
+	hot := backend.shards[0]
+	var hotKeys []string
+	coldKeys := map[*memoryShard]string{}
+	for i := 0; len(hotKeys) < 20 || len(coldKeys) < len(backend.shards)-1; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		shard := backend.shardFor(key)
+		if shard == hot && len(hotKeys) < 20 {
+			hotKeys = append(hotKeys, key)
+			assert.NoError(t, backend.Put(context.Background(), key, "value", 0))
+		} else if _, found := coldKeys[shard]; shard != hot && !found {
+			coldKeys[shard] = key
+			assert.NoError(t, backend.Put(context.Background(), key, "value", 0))
+		}
+	}
+
+	// The hot shard only kept its most recent values, within its own budget
+	assert.Len(t, hot.db, hot.maxEntries)
+	for i, key := range hotKeys {
+		_, err := backend.Get(context.Background(), key)
+		if i < len(hotKeys)-hot.maxEntries {
+			assert.Error(t, err, "Least recently used value %s should have been evicted", key)
+		} else {
+			assert.NoError(t, err, "Recent value %s should still be there", key)
+		}
+	}
+
+	// Eviction in the hot shard left the values of the other shards alone
+	for _, key := range coldKeys {
+		_, err := backend.Get(context.Background(), key)
+		assert.NoError(t, err, "Value %s of a cold shard should not have been evicted", key)
+	}
+
+	stored := 0
+	for _, shard := range backend.shards {
+		stored += len(shard.db)
+	}
+	assert.True(t, stored <= 10, "Backend should hold no more than the global budget. It holds %d values", stored)
+}
+
+func TestMemoryBackendLeastRecentlyUsed(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{MaxEntries: 2, Shards: 1})
+	ctx := context.Background()
+
+	backend.Put(ctx, "a", "value-a", 0)
+	backend.Put(ctx, "b", "value-b", 0)
+	backend.Get(ctx, "a") // "b" is now the least recently used
+	backend.Put(ctx, "c", "value-c", 0)
+
+	_, err := backend.Get(ctx, "b")
+	assert.Error(t, err, "Least recently used value should have been evicted")
+	value, err := backend.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-a", value)
+	value, err = backend.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-c", value)
+}
+
+func TestMemoryBackendUnbounded(t *testing.T) {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+
+	for i := 0; i < 1000; i++ {
+		backend.Put(ctx, fmt.Sprintf("key-%d", i), "value", 0)
+	}
+	keys, cursor, err := backend.ScanKeys(ctx, 0, 2000)
+
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1000)
+	assert.Equal(t, uint64(0), cursor)
+}
+
+func TestMemoryBackendEvictionMetrics(t *testing.T) {
+	promMetrics := prometheusMetrics.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendMemory)
+	appMetrics := &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{promMetrics}}
+
+	backend := NewBoundedMemoryBackend(config.Memory{MaxEntries: 2, Shards: 1})
+	now := time.Unix(1600000000, 0)
+	backend.now = func() time.Time { return now }
+
+	var evictions []Eviction
+	backend.OnEvict(func(eviction Eviction) {
+		evictions = append(evictions, eviction)
+		appMetrics.RecordMemoryEviction(string(eviction.Reason), eviction.Age)
+	})
+	ctx := context.Background()
+
+	// "a" expires 10 seconds after being stored, and is evicted when read 20 seconds later
+	backend.Put(ctx, "a", "value-a", 10)
+	backend.Put(ctx, "b", "value-b", 0)
+	now = now.Add(20 * time.Second)
+	_, err := backend.Get(ctx, "a")
+	assert.Equal(t, utils.KeyNotFoundError{}, err, "Expired value should not be found")
+
+	// "b" is the least recently used value once "c" and "d" fill the shard
+	backend.Put(ctx, "c", "value-c", 0)
+	now = now.Add(10 * time.Second)
+	backend.Put(ctx, "d", "value-d", 0)
+
+	assert.NoError(t, backend.Delete(ctx, "c"))
+	assert.NoError(t, backend.Delete(ctx, "c"), "Deleting a missing key should do nothing")
+
+	assert.Equal(t, []Eviction{
+		{Key: "a", Reason: EvictedTTL, Age: 20 * time.Second},
+		{Key: "b", Reason: EvictedLRU, Age: 30 * time.Second},
+		{Key: "c", Reason: EvictedManual, Age: 10 * time.Second},
+	}, evictions)
+
+	evictionCounters := promMetrics.Memory.Evictions
+	assert.Equal(t, 1.0, testutil.ToFloat64(evictionCounters.WithLabelValues("ttl")), "TTL evictions")
+	assert.Equal(t, 1.0, testutil.ToFloat64(evictionCounters.WithLabelValues("lru")), "LRU evictions")
+	assert.Equal(t, 1.0, testutil.ToFloat64(evictionCounters.WithLabelValues("manual")), "Manual evictions")
+
+	ages := dto.Metric{}
+	promMetrics.Memory.EvictedAge.Write(&ages)
+	assert.Equal(t, uint64(3), ages.GetHistogram().GetSampleCount(), "Every eviction should observe the age of the value")
+	assert.Equal(t, 60.0, ages.GetHistogram().GetSampleSum(), "Ages should add up to 20, 30 and 10 seconds")
+}
+
+func TestMemoryBackendLRUEvictsExpiredValuesAsTTL(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{MaxEntries: 1, Shards: 1})
+	now := time.Unix(1600000000, 0)
+	backend.now = func() time.Time { return now }
+
+	var evictions []Eviction
+	backend.OnEvict(func(eviction Eviction) { evictions = append(evictions, eviction) })
+
+	backend.Put(context.Background(), "a", "value-a", 5)
+	now = now.Add(time.Minute)
+	backend.Put(context.Background(), "b", "value-b", 5)
+
+	assert.Equal(t, []Eviction{{Key: "a", Reason: EvictedTTL, Age: time.Minute}}, evictions, "A value past its TTL should count as expired even when evicted to make room")
+}
+
+func TestMemoryBackendByteBudget(t *testing.T) {
+	// Every key and value pair below takes 8 bytes, so the shard holds three of them
+	backend := NewBoundedMemoryBackend(config.Memory{MaxBytes: 24, Shards: 1})
+	ctx := context.Background()
+
+	var evicted []string
+	backend.OnEvict(func(eviction Eviction) { evicted = append(evicted, eviction.Key) })
+
+	backend.Put(ctx, "a", "value-a", 0)
+	backend.Put(ctx, "b", "value-b", 0)
+	backend.Put(ctx, "c", "value-c", 0)
+	backend.Get(ctx, "a") // "b" is now the least recently used
+	assert.Empty(t, evicted, "Values within the budget should not be evicted")
+
+	// 15 bytes only fit once both "b" and "c" are evicted
+	assert.NoError(t, backend.Put(ctx, "d", "value-d-larger", 0))
+	assert.Equal(t, []string{"b", "c"}, evicted, "Least recently used values should be evicted until the new one fits")
+	assert.Equal(t, 23, backend.shards[0].bytes)
+
+	// Growing a stored value evicts the others, never the value itself
+	assert.NoError(t, backend.Put(ctx, "d", "value-d-even-larger", 0))
+	assert.Equal(t, []string{"b", "c", "a"}, evicted)
+	value, err := backend.Get(ctx, "d")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-d-even-larger", value)
+	assert.Equal(t, 20, backend.shards[0].bytes)
+
+	for _, key := range evicted {
+		_, err := backend.Get(ctx, key)
+		assert.Equal(t, utils.KeyNotFoundError{}, err, "Evicted value %s should not be found", key)
+	}
+}
+
+func TestMemoryBackendValueLargerThanShard(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{MaxBytes: 20, Shards: 2})
+	ctx := context.Background()
+
+	err := backend.Put(ctx, "key", "a value over ten bytes", 0)
+
+	assert.Equal(t, utils.ValueTooLarge, utils.ErrorCodeOf(err), "Values larger than the budget of a shard should be rejected")
+	_, err = backend.Get(ctx, "key")
+	assert.Equal(t, utils.KeyNotFoundError{}, err)
+	for _, shard := range backend.shards {
+		assert.Equal(t, 0, shard.bytes, "Rejected value should not count against the budget")
+	}
+}
+
+func TestMemoryBackendByteBudgetSmallerThanShards(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{MaxBytes: 2, Shards: 4})
+	ctx := context.Background()
+
+	for _, shard := range backend.shards {
+		assert.Equal(t, 1, shard.maxBytes, "Every shard should keep a share of the budget rather than be left unbounded")
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		err := backend.Put(ctx, key, "value", 0)
+		assert.Equal(t, utils.ValueTooLarge, utils.ErrorCodeOf(err), "No shard should hold a value over the budget: %s", key)
+	}
+}
+
+func TestMemoryBackendByteBudgetWithTTL(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{MaxBytes: 16, Shards: 1})
+	now := time.Unix(1600000000, 0)
+	backend.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	backend.Put(ctx, "a", "value-a", 5)
+	now = now.Add(10 * time.Second)
+
+	_, err := backend.Get(ctx, "a")
+	assert.Equal(t, utils.KeyNotFoundError{}, err, "Expired value should not be found")
+	assert.Equal(t, 0, backend.shards[0].bytes, "Expired value should stop counting against the budget once evicted")
+}
+
+func TestMemoryBackendPutIfAbsent(t *testing.T) {
+	backend := NewBoundedMemoryBackend(config.Memory{Shards: 1})
+	now := time.Unix(1600000000, 0)
+	backend.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.NoError(t, backend.PutIfAbsent(ctx, "key", "first", 5), "Absent keys should be stored")
+	assert.Equal(t, utils.KeyExistsError{}, backend.PutIfAbsent(ctx, "key", "second", 5), "Keys holding a value should be rejected")
+	value, _ := backend.Get(ctx, "key")
+	assert.Equal(t, "first", value, "Rejected puts should leave the stored value untouched")
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, backend.PutIfAbsent(ctx, "key", "third", 5), "Keys whose value expired should count as absent")
+	value, _ = backend.Get(ctx, "key")
+	assert.Equal(t, "third", value)
+	assert.Equal(t, len("key")+len("third"), backend.shards[0].bytes, "Replaced values should stop counting against the budget")
+}
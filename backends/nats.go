@@ -0,0 +1,131 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// NATSKV is an interface that helps us communicate with a NATS JetStream key-value bucket
+type NATSKV interface {
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+}
+
+// NATSKVClient is a wrapper for the JetStream key-value bucket that stores our values
+type NATSKVClient struct {
+	kv nats.KeyValue
+}
+
+func (db NATSKVClient) Get(key string) ([]byte, error) {
+	entry, err := db.kv.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Value(), nil
+}
+
+func (db NATSKVClient) Put(key string, value []byte) error {
+	_, err := db.kv.Put(key, value)
+	return err
+}
+
+// NATSBackend stores values in a NATS JetStream key-value bucket. JetStream expires values per bucket
+// rather than per key, so every value lives for the TTL the bucket was created with regardless of the
+// TTL of its Put request.
+type NATSBackend struct {
+	cfg    config.NATS
+	client NATSKV
+}
+
+// NewNATSBackend connects to the NATS server and binds to the configured bucket, creating it with the
+// configured TTL if it doesn't exist yet. The TTL of an existing bucket is left untouched.
+func NewNATSBackend(cfg config.NATS) *NATSBackend {
+	conn, err := nats.Connect(cfg.URL, natsAuthOptions(cfg)...)
+	if err != nil {
+		log.Fatalf("Error creating NATS backend: %v", err)
+		panic("NATSBackend failure. This shouldn't happen.")
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		log.Fatalf("Error creating NATS backend: JetStream is not available: %v", err)
+		panic("NATSBackend failure. This shouldn't happen.")
+	}
+
+	kv, err := js.KeyValue(cfg.Bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		log.Infof("Creating NATS key-value bucket %s with a TTL of %d seconds", cfg.Bucket, cfg.TTLSeconds)
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket: cfg.Bucket,
+			TTL:    time.Duration(cfg.TTLSeconds) * time.Second,
+		})
+	}
+	if err != nil {
+		log.Fatalf("Error creating NATS backend: bucket %s is not available: %v", cfg.Bucket, err)
+		panic("NATSBackend failure. This shouldn't happen.")
+	}
+	log.Infof("Connected to NATS key-value bucket %s at %s", cfg.Bucket, cfg.URL)
+
+	return &NATSBackend{
+		cfg:    cfg,
+		client: NATSKVClient{kv: kv},
+	}
+}
+
+// natsAuthOptions returns the options to authenticate with, in order of preference: a credentials
+// file, a token or a username and password.
+func natsAuthOptions(cfg config.NATS) []nats.Option {
+	switch {
+	case cfg.CredentialsFile != "":
+		return []nats.Option{nats.UserCredentials(cfg.CredentialsFile)}
+	case cfg.Token != "":
+		return []nats.Option{nats.Token(cfg.Token)}
+	case cfg.Username != "":
+		return []nats.Option{nats.UserInfo(cfg.Username, cfg.Password)}
+	}
+	return nil
+}
+
+func (n *NATSBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	value, err := n.client.Get(key)
+	if err != nil {
+		return "", classifyNATSError(err)
+	}
+	return string(value), nil
+}
+
+func (n *NATSBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := n.client.Put(key, []byte(value)); err != nil {
+		return classifyNATSError(err)
+	}
+	return nil
+}
+
+// classifyNATSError maps the errors of the NATS client and its key-value store to their error code
+func classifyNATSError(err error) error {
+	switch {
+	case errors.Is(err, nats.ErrKeyNotFound):
+		return utils.KeyNotFoundError{}
+	case errors.Is(err, nats.ErrTimeout):
+		return utils.NewBackendError(utils.Timeout, err)
+	case errors.Is(err, nats.ErrMaxPayload):
+		return utils.NewBackendError(utils.ValueTooLarge, err)
+	case errors.Is(err, nats.ErrKeyExists):
+		return utils.NewBackendError(utils.Conflict, err)
+	}
+	return err
+}
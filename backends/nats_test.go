@@ -0,0 +1,123 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Mock NATS key-value bucket that keeps its values in memory
+type fakeNATSKV struct {
+	values map[string][]byte
+	getErr error
+	putErr error
+}
+
+func newFakeNATSKV() *fakeNATSKV {
+	return &fakeNATSKV{
+		values: map[string][]byte{"defaultKey": []byte("Default value")},
+	}
+}
+
+func (kv *fakeNATSKV) Get(key string) ([]byte, error) {
+	if kv.getErr != nil {
+		return nil, kv.getErr
+	}
+	if value, found := kv.values[key]; found {
+		return value, nil
+	}
+	return nil, nats.ErrKeyNotFound
+}
+
+func (kv *fakeNATSKV) Put(key string, value []byte) error {
+	if kv.putErr != nil {
+		return kv.putErr
+	}
+	kv.values[key] = value
+	return nil
+}
+
+func TestNATSGet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inKey         string
+		getErr        error
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:          "NATSBackend.Get() key found",
+			inKey:         "defaultKey",
+			expectedValue: "Default value",
+		},
+		{
+			desc:        "NATSBackend.Get() key not found",
+			inKey:       "unknownKey",
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:        "NATSBackend.Get() bucket error other than a missing key",
+			inKey:       "defaultKey",
+			getErr:      nats.ErrTimeout,
+			expectedErr: utils.NewBackendError(utils.Timeout, nats.ErrTimeout),
+		},
+	}
+
+	for _, tc := range testCases {
+		kv := newFakeNATSKV()
+		kv.getErr = tc.getErr
+		backend := &NATSBackend{client: kv}
+
+		value, err := backend.Get(context.Background(), tc.inKey)
+
+		assert.Equal(t, tc.expectedValue, value, tc.desc)
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+	}
+}
+
+func TestNATSPut(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		inKey       string
+		inValue     string
+		putErr      error
+		expectedErr error
+	}{
+		{
+			desc:    "NATSBackend.Put() stores the value in the bucket",
+			inKey:   "someKey",
+			inValue: "someValue",
+		},
+		{
+			desc:    "NATSBackend.Put() overwrites an existing value",
+			inKey:   "defaultKey",
+			inValue: "someValue",
+		},
+		{
+			desc:        "NATSBackend.Put() bucket error",
+			inKey:       "someKey",
+			inValue:     "someValue",
+			putErr:      errors.New("some error"),
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		kv := newFakeNATSKV()
+		kv.putErr = tc.putErr
+		backend := &NATSBackend{client: kv}
+
+		err := backend.Put(context.Background(), tc.inKey, tc.inValue, 60)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		if tc.expectedErr == nil {
+			value, getErr := backend.Get(context.Background(), tc.inKey)
+			assert.NoError(t, getErr, tc.desc)
+			assert.Equal(t, tc.inValue, value, tc.desc)
+		}
+	}
+}
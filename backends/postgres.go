@@ -0,0 +1,148 @@
+package backends
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// PostgresBackend stores values in a Postgres table along with the time they expire at. Values put
+// without a TTL never expire. Expired rows are never returned, even before they get deleted.
+type PostgresBackend struct {
+	cfg         config.Postgres
+	db          *sql.DB
+	getQuery    string
+	putQuery    string
+	deleteQuery string
+}
+
+// NewPostgresBackend connects to the database and, if a cleanup interval is configured, starts deleting
+// the expired rows of the table periodically.
+func NewPostgresBackend(cfg config.Postgres) *PostgresBackend {
+	db, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		log.Fatalf("Error creating Postgres backend: %v", err)
+		panic("PostgresBackend failure. This shouldn't happen.")
+	}
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Error creating Postgres backend: %v", err)
+		panic("PostgresBackend failure. This shouldn't happen.")
+	}
+	log.Infof("Connected to Postgres table %s", cfg.Table)
+
+	backend := newPostgresBackend(cfg, db)
+	if cfg.CleanupIntervalSeconds > 0 {
+		go backend.cleanUp(time.Duration(cfg.CleanupIntervalSeconds) * time.Second)
+	}
+	return backend
+}
+
+// newPostgresBackend builds the queries for the configured table. The table name can't be passed as a
+// query argument, so it's validated by the config instead.
+func newPostgresBackend(cfg config.Postgres, db *sql.DB) *PostgresBackend {
+	return &PostgresBackend{
+		cfg: cfg,
+		db:  db,
+		getQuery: fmt.Sprintf(
+			"SELECT value FROM %s WHERE key = $1 AND (expires_at IS NULL OR expires_at > NOW())",
+			cfg.Table),
+		putQuery: fmt.Sprintf(
+			"INSERT INTO %s (key, value, expires_at) VALUES ($1, $2, CASE WHEN $3::integer > 0 THEN NOW() + $3::integer * INTERVAL '1 second' END) "+
+				"ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at",
+			cfg.Table),
+		deleteQuery: fmt.Sprintf("DELETE FROM %s WHERE expires_at <= NOW()", cfg.Table),
+	}
+}
+
+func (p *PostgresBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var value string
+	if err := p.db.QueryRowContext(ctx, p.getQuery, key).Scan(&value); err != nil {
+		return "", classifyPostgresError(err)
+	}
+	return value, nil
+}
+
+func (p *PostgresBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := p.db.ExecContext(ctx, p.putQuery, key, value, ttlSeconds); err != nil {
+		return classifyPostgresError(err)
+	}
+	return nil
+}
+
+// ConnectionPool returns the connections of the database handle. database/sql only counts the
+// operations that waited for a connection once they got one, so the pending ones aren't reported.
+func (p *PostgresBackend) ConnectionPool() (ConnectionPoolStats, bool) {
+	stats := p.db.Stats()
+	return ConnectionPoolStats{Active: stats.InUse, Size: stats.OpenConnections, Pending: -1}, true
+}
+
+// The SQLSTATE codes of the Postgres errors that get classified. See
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	postgresUniqueViolation   pq.ErrorCode = "23505"
+	postgresProgramLimit      pq.ErrorCode = "54000"
+	postgresQueryCanceled     pq.ErrorCode = "57014"
+	postgresStringTooLong     pq.ErrorCode = "22001"
+	postgresSerializationFail pq.ErrorCode = "40001"
+)
+
+// classifyPostgresError maps the errors of the database to their error code. Expired rows are filtered
+// out by the query, so they count as not found as well. Any other error is returned as is.
+func classifyPostgresError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.KeyNotFoundError{}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case postgresUniqueViolation, postgresSerializationFail:
+			return utils.NewBackendError(utils.Conflict, err)
+		case postgresProgramLimit, postgresStringTooLong:
+			return utils.NewBackendError(utils.ValueTooLarge, err)
+		case postgresQueryCanceled:
+			return utils.NewBackendError(utils.Timeout, err)
+		}
+	}
+	return err
+}
+
+func (p *PostgresBackend) cleanUp(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		deleted, err := p.deleteExpired(ctx)
+		cancel()
+		if err != nil {
+			log.Errorf("Error deleting expired values from Postgres table %s: %v", p.cfg.Table, err)
+			continue
+		}
+		log.Debugf("Deleted %d expired values from Postgres table %s", deleted, p.cfg.Table)
+	}
+}
+
+// deleteExpired deletes the rows that expired and returns how many there were
+func (p *PostgresBackend) deleteExpired(ctx context.Context) (int64, error) {
+	result, err := p.db.ExecContext(ctx, p.deleteQuery)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
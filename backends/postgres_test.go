@@ -0,0 +1,156 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	postgresGetQuery = "SELECT value FROM prebid_cache WHERE key = $1 AND (expires_at IS NULL OR expires_at > NOW())"
+	postgresPutQuery = "INSERT INTO prebid_cache (key, value, expires_at) VALUES ($1, $2, CASE WHEN $3::integer > 0 THEN NOW() + $3::integer * INTERVAL '1 second' END) " +
+		"ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at"
+	postgresDeleteQuery = "DELETE FROM prebid_cache WHERE expires_at <= NOW()"
+)
+
+func newMockPostgresBackend(t *testing.T) (*PostgresBackend, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Failed to create the mock database: %v", err)
+	}
+	return newPostgresBackend(config.Postgres{Table: "prebid_cache"}, db), mock
+}
+
+func TestPostgresGet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inKey         string
+		setExpect     func(mock sqlmock.Sqlmock)
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:  "PostgresBackend.Get() key found",
+			inKey: "someKey",
+			setExpect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(postgresGetQuery).WithArgs("someKey").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("someValue"))
+			},
+			expectedValue: "someValue",
+		},
+		{
+			// The query filters out expired rows, so Postgres returns none
+			desc:  "PostgresBackend.Get() key expired but not deleted yet",
+			inKey: "expiredKey",
+			setExpect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(postgresGetQuery).WithArgs("expiredKey").WillReturnRows(sqlmock.NewRows([]string{"value"}))
+			},
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:  "PostgresBackend.Get() key not found",
+			inKey: "unknownKey",
+			setExpect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(postgresGetQuery).WithArgs("unknownKey").WillReturnRows(sqlmock.NewRows([]string{"value"}))
+			},
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:  "PostgresBackend.Get() database error",
+			inKey: "someKey",
+			setExpect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(postgresGetQuery).WithArgs("someKey").WillReturnError(errors.New("connection refused"))
+			},
+			expectedErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		backend, mock := newMockPostgresBackend(t)
+		tc.setExpect(mock)
+
+		value, err := backend.Get(context.Background(), tc.inKey)
+
+		assert.Equal(t, tc.expectedValue, value, tc.desc)
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		assert.NoError(t, mock.ExpectationsWereMet(), tc.desc)
+	}
+}
+
+func TestPostgresPut(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		inTTLSeconds int
+		execErr      error
+		expectedErr  error
+	}{
+		{
+			desc:         "PostgresBackend.Put() stores the value with its TTL",
+			inTTLSeconds: 60,
+		},
+		{
+			desc:         "PostgresBackend.Put() stores the value without a TTL",
+			inTTLSeconds: 0,
+		},
+		{
+			desc:         "PostgresBackend.Put() database error",
+			inTTLSeconds: 60,
+			execErr:      errors.New("connection refused"),
+			expectedErr:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		backend, mock := newMockPostgresBackend(t)
+		expectation := mock.ExpectExec(postgresPutQuery).WithArgs("someKey", "someValue", tc.inTTLSeconds)
+		if tc.execErr != nil {
+			expectation.WillReturnError(tc.execErr)
+		} else {
+			expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		err := backend.Put(context.Background(), "someKey", "someValue", tc.inTTLSeconds)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		assert.NoError(t, mock.ExpectationsWereMet(), tc.desc)
+	}
+}
+
+func TestPostgresConnectionPool(t *testing.T) {
+	backend, mock := newMockPostgresBackend(t)
+	mock.ExpectQuery(postgresGetQuery).WithArgs("someKey").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("someValue"))
+	_, err := backend.Get(context.Background(), "someKey")
+	assert.NoError(t, err)
+
+	stats, ok := backend.ConnectionPool()
+	assert.True(t, ok)
+	assert.Equal(t, ConnectionPoolStats{Active: 0, Size: 1, Pending: -1}, stats, "The connection of the Get should be left idle in the pool")
+}
+
+func TestPostgresCanceledContext(t *testing.T) {
+	backend, mock := newMockPostgresBackend(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := backend.Get(ctx, "someKey")
+	assert.Equal(t, context.Canceled, err, "Get should not query a canceled context")
+	assert.Equal(t, context.Canceled, backend.Put(ctx, "someKey", "someValue", 60), "Put should not write with a canceled context")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDeleteExpired(t *testing.T) {
+	backend, mock := newMockPostgresBackend(t)
+	mock.ExpectExec(postgresDeleteQuery).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := backend.deleteExpired(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,74 +3,375 @@ package backends
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis"
 	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
 	log "github.com/sirupsen/logrus"
 )
 
-type Redis struct {
-	cfg    config.Redis
+// RedisDB is an interface that helps us communicate with an instance of a Redis database. Commands
+// are abandoned as soon as the given context is done.
+type RedisDB interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key string, value string, ttlSeconds int) error
+	PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) (bool, error)
+	Scan(ctx context.Context, cursor uint64, count int) ([]string, uint64, error)
+	Version(ctx context.Context) (string, error)
+	GetBatch(ctx context.Context, keys []string) ([]string, []error)
+	PutBatch(ctx context.Context, puts []BatchPut) []error
+}
+
+// RedisDBClient is a wrapper for the Redis client
+type RedisDBClient struct {
 	client *redis.Client
+	// commands holds a slot for every command still running, abandoned ones included
+	commands chan struct{}
 }
 
-func NewRedisBackend(cfg config.Redis) *Redis {
-	constr := cfg.Host + ":" + strconv.Itoa(cfg.Port)
+// newRedisDBClient wraps the client. It runs as many commands at once as the client has connections,
+// since any more would only wait for one.
+func newRedisDBClient(client *redis.Client) RedisDBClient {
+	return RedisDBClient{client: client, commands: make(chan struct{}, client.Options().PoolSize)}
+}
 
-	options := &redis.Options{
-		Addr:     constr,
-		Password: cfg.Password,
-		DB:       cfg.Db,
+// await runs the command until it's done or ctx is, whichever comes first. The Redis client carries
+// the context of its commands but doesn't watch it, so a command abandoned here still holds its
+// connection until Redis answers or the read timeout goes off. It keeps its slot until then too, so
+// that abandoned commands can't pile up past the size of the pool: once every slot is taken, commands
+// wait for one to drain, or for their ctx to be done. Results of abandoned commands are only written by
+// run, never read.
+func (db RedisDBClient) await(ctx context.Context, run func()) error {
+	select {
+	case db.commands <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 
-	if cfg.TLS.Enabled {
-		options = &redis.Options{
-			Addr:     constr,
-			Password: cfg.Password,
-			DB:       cfg.Db,
-			TLSConfig: &tls.Config{
-				InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
-			},
+	done := make(chan struct{})
+	go func() {
+		defer func() { <-db.commands }()
+		defer close(done)
+		run()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (db RedisDBClient) Get(ctx context.Context, key string) (string, error) {
+	var res string
+	var err error
+	if ctxErr := db.await(ctx, func() {
+		res, err = db.client.WithContext(ctx).Get(key).Result()
+	}); ctxErr != nil {
+		return "", ctxErr
+	}
+	return res, err
+}
+
+// Put issues a SET key value EX ttlSeconds. Values with a zero TTL never expire.
+func (db RedisDBClient) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	var err error
+	if ctxErr := db.await(ctx, func() {
+		err = db.client.WithContext(ctx).Set(key, value, time.Duration(ttlSeconds)*time.Second).Err()
+	}); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// PutIfAbsent issues a SET key value EX ttlSeconds NX, and tells whether the value got stored
+func (db RedisDBClient) PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) (bool, error) {
+	var stored bool
+	var err error
+	if ctxErr := db.await(ctx, func() {
+		stored, err = db.client.WithContext(ctx).SetNX(key, value, time.Duration(ttlSeconds)*time.Second).Result()
+	}); ctxErr != nil {
+		return false, ctxErr
+	}
+	return stored, err
+}
+
+func (db RedisDBClient) Scan(ctx context.Context, cursor uint64, count int) ([]string, uint64, error) {
+	var keys []string
+	var next uint64
+	var err error
+	if ctxErr := db.await(ctx, func() {
+		keys, next, err = db.client.WithContext(ctx).Scan(cursor, "", int64(count)).Result()
+	}); ctxErr != nil {
+		return nil, 0, ctxErr
+	}
+	return keys, next, err
+}
+
+// GetBatch pipelines a GET per key
+func (db RedisDBClient) GetBatch(ctx context.Context, keys []string) ([]string, []error) {
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+	if ctxErr := db.await(ctx, func() {
+		pipe := db.client.WithContext(ctx).Pipeline()
+		cmds := make([]*redis.StringCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Get(key)
+		}
+		// Exec only returns the first error, every command holds its own
+		pipe.Exec()
+
+		for i, cmd := range cmds {
+			values[i], errs[i] = cmd.Result()
 		}
+	}); ctxErr != nil {
+		return make([]string, len(keys)), repeatError(ctxErr, len(keys))
 	}
+	return values, errs
+}
 
-	client := redis.NewClient(options)
+// PutBatch pipelines a SET key value EX ttlSeconds per value
+func (db RedisDBClient) PutBatch(ctx context.Context, puts []BatchPut) []error {
+	errs := make([]error, len(puts))
+	if ctxErr := db.await(ctx, func() {
+		pipe := db.client.WithContext(ctx).Pipeline()
+		cmds := make([]*redis.StatusCmd, len(puts))
+		for i, put := range puts {
+			cmds[i] = pipe.Set(put.Key, put.Value, time.Duration(put.TTLSeconds)*time.Second)
+		}
+		pipe.Exec()
+
+		for i, cmd := range cmds {
+			errs[i] = cmd.Err()
+		}
+	}); ctxErr != nil {
+		return repeatError(ctxErr, len(puts))
+	}
+	return errs
+}
+
+// Version issues an INFO server, and returns the redis_version it reports
+func (db RedisDBClient) Version(ctx context.Context) (string, error) {
+	var info string
+	var err error
+	if ctxErr := db.await(ctx, func() {
+		info, err = db.client.WithContext(ctx).Info("server").Result()
+	}); ctxErr != nil {
+		return "", ctxErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return parseRedisVersion(info)
+}
+
+func parseRedisVersion(info string) (string, error) {
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "redis_version:") {
+			return strings.TrimPrefix(line, "redis_version:"), nil
+		}
+	}
+	return "", errors.New("INFO server didn't report a redis_version")
+}
+
+// ConnectionPool returns the connections of the Redis client. It doesn't count the commands waiting
+// for one.
+func (db RedisDBClient) ConnectionPool() ConnectionPoolStats {
+	stats := db.client.PoolStats()
+	return ConnectionPoolStats{
+		Active:  int(stats.TotalConns) - int(stats.IdleConns),
+		Size:    int(stats.TotalConns),
+		Pending: -1,
+	}
+}
+
+// RedisBackend stores values as plain Redis strings and relies on their expiration to honor the TTL of
+// every Put request
+type RedisBackend struct {
+	cfg    config.Redis
+	client RedisDB
+}
+
+// ConnectionPool reports the pool of the Redis client, if it has one
+func (b *RedisBackend) ConnectionPool() (ConnectionPoolStats, bool) {
+	pool, ok := b.client.(interface{ ConnectionPool() ConnectionPoolStats })
+	if !ok {
+		return ConnectionPoolStats{}, false
+	}
+	return pool.ConnectionPool(), true
+}
+
+func NewRedisBackend(cfg config.Redis) *RedisBackend {
+	client := newRedisClient(cfg)
 
 	_, err := client.Ping().Result()
 
 	if err != nil {
 		log.Fatalf("Error creating Redis backend: %v", err)
+		panic("RedisBackend failure. This shouldn't happen.")
 	}
 
-	log.Infof("Connected to Redis at %s:%d", cfg.Host, cfg.Port)
+	if cfg.UsesSentinel() {
+		log.Infof("Connected to Redis master %s through Sentinel at %v", cfg.MasterName, cfg.SentinelAddrs)
+	} else {
+		log.Infof("Connected to Redis at %s:%d", cfg.Host, cfg.Port)
+	}
 
-	return &Redis{
+	return &RedisBackend{
 		cfg:    cfg,
-		client: client,
+		client: newRedisDBClient(client),
+	}
+}
+
+// newRedisClient returns a client of the Redis at cfg.Host and cfg.Port or, if Sentinel is configured,
+// a failover client. The failover client asks the Sentinels for the address of the master every time
+// it opens a connection, so once a failed over master drops the connections to it, the next ones go to
+// the new master. Commands sent over the dropped connections fail like any other.
+func newRedisClient(cfg config.Redis) *redis.Client {
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		}
 	}
+
+	if cfg.UsesSentinel() {
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.MasterName,
+			SentinelAddrs: cfg.SentinelAddrs,
+			Password:      cfg.Password,
+			DB:            cfg.Db,
+			TLSConfig:     tlsConfig,
+		})
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:      cfg.Host + ":" + strconv.Itoa(cfg.Port),
+		Password:  cfg.Password,
+		DB:        cfg.Db,
+		TLSConfig: tlsConfig,
+	})
 }
 
-func (redis *Redis) Get(ctx context.Context, key string) (string, error) {
-	res, err := redis.client.Get(key).Result()
+func (redis *RedisBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	res, err := redis.client.Get(ctx, key)
 
 	if err != nil {
-		return "", err
+		return "", classifyRedisError(err)
 	}
 
-	return string(res), nil
+	return res, nil
+}
+
+// DefaultTTLSeconds is the TTL of values put without one, configured in minutes
+func (redis *RedisBackend) DefaultTTLSeconds() int {
+	return redis.cfg.Expiration * 60
 }
 
-func (redis *Redis) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
-	if ttlSeconds == 0 {
-		ttlSeconds = redis.cfg.Expiration * 60
+func (redis *RedisBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	err := redis.client.Set(key, value, time.Duration(ttlSeconds)*time.Second).Err()
+
+	err := redis.client.Put(ctx, key, value, ttlSeconds)
 
 	if err != nil {
+		return classifyRedisError(err)
+	}
+
+	return nil
+}
+
+func (redis *RedisBackend) PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
+	stored, err := redis.client.PutIfAbsent(ctx, key, value, ttlSeconds)
+	if err != nil {
+		return classifyRedisError(err)
+	}
+	if !stored {
+		return utils.KeyExistsError{}
+	}
+
 	return nil
 }
+
+// GetBatch reads every key in a single round trip
+func (redis *RedisBackend) GetBatch(ctx context.Context, keys []string) ([]string, []error) {
+	if err := ctx.Err(); err != nil {
+		return make([]string, len(keys)), repeatError(err, len(keys))
+	}
+
+	values, errs := redis.client.GetBatch(ctx, keys)
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = classifyRedisError(err)
+		}
+	}
+	return values, errs
+}
+
+// PutBatch stores every value in a single round trip
+func (redis *RedisBackend) PutBatch(ctx context.Context, puts []BatchPut) []error {
+	if err := ctx.Err(); err != nil {
+		return repeatError(err, len(puts))
+	}
+
+	errs := redis.client.PutBatch(ctx, puts)
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = classifyRedisError(err)
+		}
+	}
+	return errs
+}
+
+func repeatError(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (redis *RedisBackend) ScanKeys(ctx context.Context, cursor uint64, count int) ([]string, uint64, error) {
+	return redis.client.Scan(ctx, cursor, count)
+}
+
+// ServerVersion returns the version of the Redis server, or of the current master when going through
+// Sentinel
+func (redis *RedisBackend) ServerVersion(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return redis.client.Version(ctx)
+}
+
+// classifyRedisError maps the errors of the Redis client to their error code. redis.Nil is what Get
+// returns for keys that don't exist. Errors of the request's context are left as they are, so that
+// callers can tell the client went away from Redis timing out.
+func classifyRedisError(err error) error {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	if err == redis.Nil {
+		return utils.KeyNotFoundError{}
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return utils.NewBackendError(utils.Timeout, err)
+	}
+	return err
+}
@@ -0,0 +1,207 @@
+package backends
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedisServer speaks just enough of the Redis protocol to stand for a Sentinel or a master. Every
+// command other than the pub/sub ones gets answered with whatever the reply function returns for it.
+type fakeRedisServer struct {
+	listener net.Listener
+	reply    func(args []string) string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func startFakeRedisServer(t *testing.T, reply func(args []string) string) *fakeRedisServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start fake Redis server: %v", err)
+	}
+	s := &fakeRedisServer{listener: listener, reply: reply}
+	go s.serve()
+	return s
+}
+
+func (s *fakeRedisServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedisServer) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	subscribed := false
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToLower(args[0])
+		switch {
+		case cmd == "subscribe":
+			subscribed = true
+			io.WriteString(conn, "*3\r\n"+respBulk("subscribe")+respBulk(args[1])+":1\r\n")
+		case cmd == "ping" && subscribed:
+			io.WriteString(conn, respArray("pong", ""))
+		default:
+			io.WriteString(conn, s.reply(args))
+		}
+	}
+}
+
+// close stops the server and drops the connections to it, like a master that fails
+func (s *fakeRedisServer) close() {
+	s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	numArgs, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, numArgs)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+func respBulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func respArray(items ...string) string {
+	reply := fmt.Sprintf("*%d\r\n", len(items))
+	for _, item := range items {
+		reply += respBulk(item)
+	}
+	return reply
+}
+
+// newFakeRedisMaster returns a master that keeps the values it's SET in values
+func newFakeRedisMaster(t *testing.T) (*fakeRedisServer, *sync.Map) {
+	values := &sync.Map{}
+	master := startFakeRedisServer(t, func(args []string) string {
+		switch strings.ToLower(args[0]) {
+		case "ping":
+			return "+PONG\r\n"
+		case "set":
+			values.Store(args[1], args[2])
+			return "+OK\r\n"
+		case "get":
+			if value, ok := values.Load(args[1]); ok {
+				return respBulk(value.(string))
+			}
+			return "$-1\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	return master, values
+}
+
+// newFakeSentinel returns a Sentinel that answers with the address masterAddr returns
+func newFakeSentinel(t *testing.T, masterName string, masterAddr func() string) *fakeRedisServer {
+	return startFakeRedisServer(t, func(args []string) string {
+		if strings.ToLower(args[0]) != "sentinel" || len(args) < 3 || args[2] != masterName {
+			return "-ERR unknown command\r\n"
+		}
+		switch strings.ToLower(args[1]) {
+		case "get-master-addr-by-name":
+			host, port, _ := net.SplitHostPort(masterAddr())
+			return respArray(host, port)
+		case "sentinels":
+			return "*0\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+}
+
+func TestRedisSentinelFailover(t *testing.T) {
+	firstMaster, firstValues := newFakeRedisMaster(t)
+	secondMaster, secondValues := newFakeRedisMaster(t)
+	defer secondMaster.close()
+
+	var mu sync.Mutex
+	currentMaster := firstMaster
+	sentinel := newFakeSentinel(t, "mymaster", func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return currentMaster.addr()
+	})
+	defer sentinel.close()
+
+	cfg := config.Redis{SentinelAddrs: []string{sentinel.addr()}, MasterName: "mymaster"}
+	client := newRedisClient(cfg)
+	defer client.Close()
+	backend := &RedisBackend{cfg: cfg, client: newRedisDBClient(client)}
+
+	// Values go to the master the Sentinel points to
+	if assert.NoError(t, backend.Put(context.Background(), "someKey", "first", 10)) {
+		value, _ := firstValues.Load("someKey")
+		assert.Equal(t, "first", value, "Value should be stored in the first master")
+	}
+
+	// Fail over to the second master
+	firstMaster.close()
+	mu.Lock()
+	currentMaster = secondMaster
+	mu.Unlock()
+
+	_, err := backend.Get(context.Background(), "someKey")
+	assert.Error(t, err, "Commands sent over the connections to the failed master should fail")
+
+	// The next connection goes to the new master
+	if assert.NoError(t, backend.Put(context.Background(), "someKey", "second", 10), "Backend should reconnect to the new master") {
+		value, _ := secondValues.Load("someKey")
+		assert.Equal(t, "second", value, "Value should be stored in the second master")
+	}
+	value, err := backend.Get(context.Background(), "someKey")
+	assert.NoError(t, err)
+	assert.Equal(t, "second", value)
+}
@@ -0,0 +1,415 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Mock Redis client that always throws an error
+type errorProneRedisClient struct {
+	err error
+}
+
+func NewErrorProneRedisClient(err error) *errorProneRedisClient {
+	return &errorProneRedisClient{err: err}
+}
+
+func (c *errorProneRedisClient) Get(ctx context.Context, key string) (string, error) {
+	return "", c.err
+}
+
+func (c *errorProneRedisClient) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return c.err
+}
+
+func (c *errorProneRedisClient) PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) (bool, error) {
+	return false, c.err
+}
+
+func (c *errorProneRedisClient) Scan(ctx context.Context, cursor uint64, count int) ([]string, uint64, error) {
+	return nil, 0, c.err
+}
+
+func (c *errorProneRedisClient) Version(ctx context.Context) (string, error) {
+	return "", c.err
+}
+
+func (c *errorProneRedisClient) GetBatch(ctx context.Context, keys []string) ([]string, []error) {
+	return make([]string, len(keys)), repeatError(c.err, len(keys))
+}
+
+func (c *errorProneRedisClient) PutBatch(ctx context.Context, puts []BatchPut) []error {
+	return repeatError(c.err, len(puts))
+}
+
+// Mock Redis client that does not throw errors and remembers the TTL of every value
+type goodRedisClient struct {
+	values map[string]string
+	ttls   map[string]int
+}
+
+func NewGoodRedisClient() *goodRedisClient {
+	return &goodRedisClient{
+		values: map[string]string{"defaultKey": "Default value"},
+		ttls:   map[string]int{"defaultKey": 0},
+	}
+}
+
+func (c *goodRedisClient) Get(ctx context.Context, key string) (string, error) {
+	if value, found := c.values[key]; found {
+		return value, nil
+	}
+	return "", redis.Nil
+}
+
+func (c *goodRedisClient) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	c.values[key] = value
+	c.ttls[key] = ttlSeconds
+	return nil
+}
+
+func (c *goodRedisClient) PutIfAbsent(ctx context.Context, key string, value string, ttlSeconds int) (bool, error) {
+	if _, found := c.values[key]; found {
+		return false, nil
+	}
+	return true, c.Put(ctx, key, value, ttlSeconds)
+}
+
+func (c *goodRedisClient) Scan(ctx context.Context, cursor uint64, count int) ([]string, uint64, error) {
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	return keys, 0, nil
+}
+
+func (c *goodRedisClient) Version(ctx context.Context) (string, error) {
+	return "6.2.6", nil
+}
+
+func (c *goodRedisClient) GetBatch(ctx context.Context, keys []string) ([]string, []error) {
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		values[i], errs[i] = c.Get(ctx, key)
+	}
+	return values, errs
+}
+
+func (c *goodRedisClient) PutBatch(ctx context.Context, puts []BatchPut) []error {
+	errs := make([]error, len(puts))
+	for i, put := range puts {
+		errs[i] = c.Put(ctx, put.Key, put.Value, put.TTLSeconds)
+	}
+	return errs
+}
+
+func TestRedisClientGet(t *testing.T) {
+	redisBackend := &RedisBackend{}
+
+	type testInput struct {
+		client RedisDB
+		key    string
+	}
+
+	type testExpectedValues struct {
+		value string
+		err   error
+	}
+
+	testCases := []struct {
+		desc     string
+		in       testInput
+		expected testExpectedValues
+	}{
+		{
+			desc: "RedisBackend.Get() throws a redis.Nil error",
+			in: testInput{
+				client: NewErrorProneRedisClient(redis.Nil),
+				key:    "someKeyThatWontBeFound",
+			},
+			expected: testExpectedValues{
+				value: "",
+				err:   utils.KeyNotFoundError{},
+			},
+		},
+		{
+			desc: "RedisBackend.Get() throws an error different from redis.Nil",
+			in: testInput{
+				client: NewErrorProneRedisClient(errors.New("some other get error")),
+				key:    "someKey",
+			},
+			expected: testExpectedValues{
+				value: "",
+				err:   errors.New("some other get error"),
+			},
+		},
+		{
+			desc: "RedisBackend.Get() doesn't throw an error",
+			in: testInput{
+				client: NewGoodRedisClient(),
+				key:    "defaultKey",
+			},
+			expected: testExpectedValues{
+				value: "Default value",
+				err:   nil,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		redisBackend.client = tt.in.client
+
+		// Run test
+		actualValue, actualErr := redisBackend.Get(context.Background(), tt.in.key)
+
+		// Assertions
+		assert.Equal(t, tt.expected.value, actualValue, tt.desc)
+		assert.Equal(t, tt.expected.err, actualErr, tt.desc)
+	}
+}
+
+func TestRedisClientPut(t *testing.T) {
+	redisBackend := &RedisBackend{cfg: config.Redis{Expiration: 10}}
+
+	type testInput struct {
+		client     RedisDB
+		key        string
+		value      string
+		ttlSeconds int
+	}
+
+	type testExpectedValues struct {
+		ttlSeconds int
+		err        error
+	}
+
+	testCases := []struct {
+		desc     string
+		in       testInput
+		expected testExpectedValues
+	}{
+		{
+			desc: "RedisBackend.Put() throws an error",
+			in: testInput{
+				client:     NewErrorProneRedisClient(errors.New("some put error")),
+				key:        "someKey",
+				value:      "someValue",
+				ttlSeconds: 10,
+			},
+			expected: testExpectedValues{
+				err: errors.New("some put error"),
+			},
+		},
+		{
+			desc: "RedisBackend.Put() times out",
+			in: testInput{
+				client:     NewErrorProneRedisClient(netTimeoutError{}),
+				key:        "someKey",
+				value:      "someValue",
+				ttlSeconds: 10,
+			},
+			expected: testExpectedValues{
+				err: utils.NewBackendError(utils.Timeout, netTimeoutError{}),
+			},
+		},
+		{
+			desc: "RedisBackend.Put() stores the value with the TTL it was given",
+			in: testInput{
+				client:     NewGoodRedisClient(),
+				key:        "someKey",
+				value:      "someValue",
+				ttlSeconds: 10,
+			},
+			expected: testExpectedValues{
+				ttlSeconds: 10,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		redisBackend.client = tt.in.client
+
+		// Run test
+		actualErr := redisBackend.Put(context.Background(), tt.in.key, tt.in.value, tt.in.ttlSeconds)
+
+		// Assertions
+		assert.Equal(t, tt.expected.err, actualErr, tt.desc)
+		if goodClient, ok := tt.in.client.(*goodRedisClient); ok {
+			assert.Equal(t, tt.in.value, goodClient.values[tt.in.key], tt.desc)
+			assert.Equal(t, tt.expected.ttlSeconds, goodClient.ttls[tt.in.key], tt.desc)
+		}
+	}
+}
+
+func TestRedisBackendHonorsCanceledContexts(t *testing.T) {
+	redisBackend := &RedisBackend{client: NewGoodRedisClient()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := redisBackend.Get(ctx, "defaultKey")
+	assert.Equal(t, context.Canceled, err, "Get shouldn't reach Redis once the context is canceled")
+
+	err = redisBackend.Put(ctx, "someKey", "someValue", 10)
+	assert.Equal(t, context.Canceled, err, "Put shouldn't reach Redis once the context is canceled")
+}
+
+func TestRedisBackendAbandonsCommandsInFlight(t *testing.T) {
+	// The server never answers but PING, like a Redis stuck on a slow command
+	unblock := make(chan struct{})
+	server := startFakeRedisServer(t, func(args []string) string {
+		if strings.ToLower(args[0]) != "ping" {
+			<-unblock
+		}
+		return "+PONG\r\n"
+	})
+	defer server.close()
+	defer close(unblock)
+
+	host, port, _ := net.SplitHostPort(server.addr())
+	cfg := config.Redis{Host: host, Expiration: 10}
+	cfg.Port, _ = strconv.Atoi(port)
+	client := newRedisClient(cfg)
+	defer client.Close()
+	redisBackend := &RedisBackend{cfg: cfg, client: newRedisDBClient(client)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := redisBackend.Get(ctx, "someKey")
+	assert.Equal(t, context.Canceled, err, "Get should be abandoned once the context is canceled")
+	assert.True(t, time.Since(start) < time.Second, "Get shouldn't wait on Redis once the context is canceled")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = redisBackend.Put(ctx, "someKey", "someValue", 10)
+	assert.Equal(t, context.DeadlineExceeded, err, "Put should be abandoned once the deadline is over")
+	assert.True(t, time.Since(start) < time.Second, "Put shouldn't wait on Redis once the deadline is over")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	errs := redisBackend.PutBatch(ctx, []BatchPut{{Key: "someKey", Value: "someValue"}, {Key: "otherKey", Value: "otherValue"}})
+	assert.Equal(t, []error{context.Canceled, context.Canceled}, errs, "Batches should be abandoned once the context is canceled")
+}
+
+func TestRedisBackendBoundsAbandonedCommands(t *testing.T) {
+	// The server holds every GET until unblocked, like a Redis stuck on a slow command
+	var gets int32
+	unblock := make(chan struct{})
+	server := startFakeRedisServer(t, func(args []string) string {
+		if strings.ToLower(args[0]) == "get" {
+			atomic.AddInt32(&gets, 1)
+			<-unblock
+			return "$5\r\nvalue\r\n"
+		}
+		return "+PONG\r\n"
+	})
+	defer server.close()
+
+	client := redis.NewClient(&redis.Options{Addr: server.addr(), PoolSize: 2})
+	defer client.Close()
+	redisBackend := &RedisBackend{client: newRedisDBClient(client)}
+
+	abandonGets := func(n int) {
+		for i := 0; i < n; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			_, err := redisBackend.Get(ctx, "someKey")
+			cancel()
+			assert.Equal(t, context.DeadlineExceeded, err, "Get should be abandoned once the deadline is over")
+		}
+	}
+
+	// Both connections get held by abandoned commands, and every Get after waits for a slot
+	abandonGets(2)
+	goroutines := runtime.NumGoroutine()
+	abandonGets(8)
+	assert.True(t, runtime.NumGoroutine() <= goroutines, "Abandoned commands shouldn't pile up past the size of the pool")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&gets), "No more commands than the pool has connections should reach Redis")
+
+	close(unblock)
+	value, err := redisBackend.Get(context.Background(), "someKey")
+	assert.NoError(t, err, "Slots should drain once Redis answers the abandoned commands")
+	assert.Equal(t, "value", value)
+}
+
+func TestRedisClientPutIfAbsent(t *testing.T) {
+	client := NewGoodRedisClient()
+	redisBackend := &RedisBackend{client: client}
+
+	err := redisBackend.PutIfAbsent(context.Background(), "someKey", "someValue", 10)
+	assert.NoError(t, err, "Absent keys should be stored")
+	assert.Equal(t, "someValue", client.values["someKey"])
+
+	err = redisBackend.PutIfAbsent(context.Background(), "defaultKey", "someValue", 10)
+	assert.Equal(t, utils.KeyExistsError{}, err, "Keys holding a value should be rejected")
+	assert.Equal(t, "Default value", client.values["defaultKey"], "Rejected puts should leave the stored value untouched")
+
+	err = (&RedisBackend{client: NewErrorProneRedisClient(errors.New("some error"))}).PutIfAbsent(context.Background(), "someKey", "someValue", 10)
+	assert.Equal(t, errors.New("some error"), err, "Errors of the client should be returned")
+}
+
+func TestRedisClientBatches(t *testing.T) {
+	client := NewGoodRedisClient()
+	redisBackend := &RedisBackend{cfg: config.Redis{Expiration: 10}, client: client}
+
+	errs := redisBackend.PutBatch(context.Background(), []BatchPut{
+		{Key: "someKey", Value: "someValue", TTLSeconds: 10},
+		{Key: "otherKey", Value: "otherValue"},
+	})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, 10, client.ttls["someKey"], "Values should be stored with the TTL they were given")
+	assert.Equal(t, 0, client.ttls["otherKey"], "Values without a TTL get the default one from ResolveTTL, not the backend")
+
+	values, errs := redisBackend.GetBatch(context.Background(), []string{"otherKey", "missingKey", "someKey"})
+	assert.Equal(t, []string{"otherValue", "", "someValue"}, values)
+	assert.Equal(t, []error{nil, utils.KeyNotFoundError{}, nil}, errs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs = redisBackend.GetBatch(ctx, []string{"someKey", "otherKey"})
+	assert.Equal(t, []error{context.Canceled, context.Canceled}, errs, "Batches shouldn't reach Redis once the context is canceled")
+}
+
+// pooledRedisClient reports the stats it holds as those of its connection pool
+type pooledRedisClient struct {
+	goodRedisClient
+	stats ConnectionPoolStats
+}
+
+func (c *pooledRedisClient) ConnectionPool() ConnectionPoolStats {
+	return c.stats
+}
+
+func TestRedisConnectionPool(t *testing.T) {
+	stats := ConnectionPoolStats{Active: 2, Size: 5, Pending: -1}
+	pooled := &RedisBackend{client: &pooledRedisClient{goodRedisClient: *NewGoodRedisClient(), stats: stats}}
+
+	reported, ok := pooled.ConnectionPool()
+	assert.True(t, ok, "Clients with a pool should report it")
+	assert.Equal(t, stats, reported)
+
+	_, ok = (&RedisBackend{client: NewGoodRedisClient()}).ConnectionPool()
+	assert.False(t, ok, "Clients without a pool shouldn't report any")
+}
+
+func TestParseRedisVersion(t *testing.T) {
+	version, err := parseRedisVersion("# Server\r\nredis_version:2.6.9\r\nredis_git_sha1:00000000\r\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.6.9", version)
+
+	_, err = parseRedisVersion("# Server\r\nredis_git_sha1:00000000\r\n")
+	assert.Error(t, err, "INFO output without a version")
+}
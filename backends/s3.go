@@ -0,0 +1,163 @@
+package backends
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// s3ExpiresAtMetadata is the user metadata every object put with a TTL carries its expiration in, as a
+// unix time in seconds. S3 returns metadata keys in the canonical form of HTTP headers.
+const s3ExpiresAtMetadata = "Expires-At"
+
+// S3Client is an interface that helps us communicate with S3. The client of the AWS SDK implements it.
+type S3Client interface {
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// S3Backend stores every value as an object of a bucket. S3 can only expire objects through the
+// lifecycle rules of the bucket, which work in whole days, so the expiration of every value is stored
+// along with it and values past it are reported missing until a lifecycle rule deletes them.
+type S3Backend struct {
+	cfg    config.S3
+	client S3Client
+}
+
+// NewS3Backend creates the S3 client and makes sure the configured bucket can be reached
+func NewS3Backend(cfg config.S3) *S3Backend {
+	awsCfg := &aws.Config{S3ForcePathStyle: aws.Bool(cfg.ForcePathStyle)}
+	if cfg.Region != "" {
+		awsCfg.Region = aws.String(cfg.Region)
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		log.Fatalf("Error creating S3 backend: %v", err)
+		panic("S3Backend failure. This shouldn't happen.")
+	}
+	client := s3.New(sess)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(cfg.Bucket)}); err != nil {
+		log.Fatalf("Error creating S3 backend: bucket %s can't be reached: %v", cfg.Bucket, err)
+		panic("S3Backend failure. This shouldn't happen.")
+	}
+	log.Infof("Connected to S3 bucket %s", cfg.Bucket)
+
+	return &S3Backend{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	out, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	if err != nil {
+		return "", classifyS3Error(err)
+	}
+	defer out.Body.Close()
+
+	if s3Expired(out.Metadata, time.Now()) {
+		return "", utils.KeyNotFoundError{}
+	}
+
+	value, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// Put stores the value along with its expiration, which is also set as the Expires header of the
+// object so that anyone serving it straight from S3 doesn't cache it for longer
+func (b *S3Backend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(b.objectKey(key)),
+		Body:   strings.NewReader(value),
+	}
+	if ttlSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
+		input.Expires = aws.Time(expiresAt)
+		input.Metadata = map[string]*string{
+			s3ExpiresAtMetadata: aws.String(strconv.FormatInt(expiresAt.Unix(), 10)),
+		}
+	}
+
+	if _, err := b.client.PutObjectWithContext(ctx, input); err != nil {
+		return classifyS3Error(err)
+	}
+	return nil
+}
+
+// objectKey returns the key of the object that stores the value of key
+func (b *S3Backend) objectKey(key string) string {
+	if b.cfg.Prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(b.cfg.Prefix, "/") + "/" + key
+}
+
+// s3Expired tells whether the object with the given user metadata is past its expiration. Objects
+// stored without a TTL never expire.
+func s3Expired(metadata map[string]*string, now time.Time) bool {
+	for name, value := range metadata {
+		if !strings.EqualFold(name, s3ExpiresAtMetadata) || value == nil {
+			continue
+		}
+		expiresAt, err := strconv.ParseInt(*value, 10, 64)
+		return err == nil && !now.Before(time.Unix(expiresAt, 0))
+	}
+	return false
+}
+
+// classifyS3Error maps the errors of the S3 client to their error code. Missing objects come back as
+// a NoSuchKey error, or as a bare 404 when the request had no body to carry the code in.
+func classifyS3Error(err error) error {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	switch aerr.Code() {
+	case s3.ErrCodeNoSuchKey:
+		return utils.KeyNotFoundError{}
+	case "EntityTooLarge":
+		return utils.NewBackendError(utils.ValueTooLarge, err)
+	case "RequestTimeout":
+		return utils.NewBackendError(utils.Timeout, err)
+	case s3.ErrCodeNoSuchBucket:
+		return err
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotFound {
+		return utils.KeyNotFoundError{}
+	}
+	return err
+}
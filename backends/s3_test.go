@@ -0,0 +1,195 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeS3Object struct {
+	value    string
+	metadata map[string]*string
+}
+
+// Mock S3 client that keeps its objects in memory
+type fakeS3Client struct {
+	objects map[string]fakeS3Object
+	puts    []*s3.PutObjectInput
+	getErr  error
+	putErr  error
+}
+
+func newFakeS3Client() *fakeS3Client {
+	return &fakeS3Client{
+		objects: map[string]fakeS3Object{"values/defaultKey": {value: "Default value"}},
+	}
+}
+
+func (c *fakeS3Client) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+	object, found := c.objects[aws.StringValue(input.Key)]
+	if !found {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(object.value)), Metadata: object.metadata}, nil
+}
+
+func (c *fakeS3Client) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	if c.putErr != nil {
+		return nil, c.putErr
+	}
+	value, _ := ioutil.ReadAll(input.Body)
+	c.objects[aws.StringValue(input.Key)] = fakeS3Object{value: string(value), metadata: input.Metadata}
+	c.puts = append(c.puts, input)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Get(t *testing.T) {
+	expiredAt := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	notExpiredAt := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+
+	testCases := []struct {
+		desc          string
+		inKey         string
+		inObjects     map[string]fakeS3Object
+		getErr        error
+		expectedValue string
+		expectedErr   error
+	}{
+		{
+			desc:          "S3Backend.Get() key found",
+			inKey:         "defaultKey",
+			expectedValue: "Default value",
+		},
+		{
+			desc:        "S3Backend.Get() NoSuchKey",
+			inKey:       "unknownKey",
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:        "S3Backend.Get() bare 404",
+			inKey:       "defaultKey",
+			getErr:      awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "request-id"),
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:  "S3Backend.Get() value past its expiration",
+			inKey: "expiredKey",
+			inObjects: map[string]fakeS3Object{
+				"values/expiredKey": {value: "Expired value", metadata: map[string]*string{"Expires-At": aws.String(expiredAt)}},
+			},
+			expectedErr: utils.KeyNotFoundError{},
+		},
+		{
+			desc:  "S3Backend.Get() value within its expiration",
+			inKey: "freshKey",
+			inObjects: map[string]fakeS3Object{
+				"values/freshKey": {value: "Fresh value", metadata: map[string]*string{"Expires-At": aws.String(notExpiredAt)}},
+			},
+			expectedValue: "Fresh value",
+		},
+		{
+			desc:        "S3Backend.Get() other S3 error",
+			inKey:       "defaultKey",
+			getErr:      errors.New("connection refused"),
+			expectedErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeS3Client()
+		for key, object := range tc.inObjects {
+			client.objects[key] = object
+		}
+		client.getErr = tc.getErr
+		backend := &S3Backend{cfg: config.S3{Bucket: "prebid-cache", Prefix: "values/"}, client: client}
+
+		value, err := backend.Get(context.Background(), tc.inKey)
+
+		assert.Equal(t, tc.expectedValue, value, tc.desc)
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+	}
+}
+
+func TestS3Put(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		inPrefix          string
+		inTTLSeconds      int
+		putErr            error
+		expectedObjectKey string
+		expectedExpires   bool
+		expectedErr       error
+	}{
+		{
+			desc:              "S3Backend.Put() with a TTL",
+			inPrefix:          "values/",
+			inTTLSeconds:      60,
+			expectedObjectKey: "values/someKey",
+			expectedExpires:   true,
+		},
+		{
+			desc:              "S3Backend.Put() without a TTL",
+			inPrefix:          "values",
+			expectedObjectKey: "values/someKey",
+		},
+		{
+			desc:              "S3Backend.Put() without a prefix",
+			inTTLSeconds:      60,
+			expectedObjectKey: "someKey",
+			expectedExpires:   true,
+		},
+		{
+			desc:         "S3Backend.Put() value too large",
+			inPrefix:     "values/",
+			inTTLSeconds: 60,
+			putErr:       awserr.New("EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", nil),
+			expectedErr:  utils.NewBackendError(utils.ValueTooLarge, awserr.New("EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", nil)),
+		},
+	}
+
+	for _, tc := range testCases {
+		client := newFakeS3Client()
+		client.putErr = tc.putErr
+		backend := &S3Backend{cfg: config.S3{Bucket: "prebid-cache", Prefix: tc.inPrefix}, client: client}
+
+		err := backend.Put(context.Background(), "someKey", "someValue", tc.inTTLSeconds)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		if tc.expectedErr != nil {
+			continue
+		}
+		if assert.Len(t, client.puts, 1, tc.desc) {
+			put := client.puts[0]
+			assert.Equal(t, "prebid-cache", aws.StringValue(put.Bucket), tc.desc)
+			assert.Equal(t, tc.expectedObjectKey, aws.StringValue(put.Key), tc.desc)
+			if tc.expectedExpires {
+				if assert.NotNil(t, put.Expires, tc.desc) {
+					assert.WithinDuration(t, time.Now().Add(time.Duration(tc.inTTLSeconds)*time.Second), *put.Expires, 2*time.Second, tc.desc)
+				}
+				assert.Equal(t, strconv.FormatInt(put.Expires.Unix(), 10), aws.StringValue(put.Metadata[s3ExpiresAtMetadata]), tc.desc)
+			} else {
+				assert.Nil(t, put.Expires, tc.desc)
+				assert.Empty(t, put.Metadata, tc.desc)
+			}
+		}
+
+		value, err := backend.Get(context.Background(), "someKey")
+		assert.NoError(t, err, tc.desc)
+		assert.Equal(t, "someValue", value, tc.desc)
+	}
+}
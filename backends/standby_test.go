@@ -0,0 +1,49 @@
+package backends_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	influx "github.com/prebid/prebid-cache/metrics/influx"
+	"github.com/stretchr/testify/assert"
+)
+
+// ttlCassandraClient sends the TTL of every value it's asked to store
+type ttlCassandraClient struct {
+	ttls chan int
+}
+
+func (c *ttlCassandraClient) Get(ctx context.Context, stmt string, key string) (string, error) {
+	return "", nil
+}
+
+func (c *ttlCassandraClient) Put(ctx context.Context, stmt string, key string, value string, ttlSeconds int) error {
+	c.ttls <- ttlSeconds
+	return nil
+}
+
+// TestCassandraStandbyDefaultTTL wires the backends the way NewBackend does. The memory primary has no
+// default TTL, so values put without one must still expire from the Cassandra standby.
+func TestCassandraStandbyDefaultTTL(t *testing.T) {
+	m := &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{influx.CreateInfluxMetrics()}}
+	client := &ttlCassandraClient{ttls: make(chan int, 1)}
+	primary := backends.NewMemoryBackend()
+	standby := backends.NewCassandraBackendWithClient(client)
+
+	backend := decorators.Replicate(primary, standby, config.Standby{QueueSize: 10, Workers: 1, MaxAttempts: 1}, backends.DefaultTTLSeconds(standby), m)
+	backend = decorators.ApplyTTLRules(backend, config.RequestLimits{MaxTTLSeconds: 3600}, backends.DefaultTTLSeconds(primary), m)
+
+	assert.NoError(t, backend.Put(context.Background(), "key", "xml<tag></tag>", 0))
+
+	select {
+	case ttl := <-client.ttls:
+		assert.Equal(t, 2400, ttl, "The standby should store the value with its default TTL")
+	case <-time.After(time.Second):
+		t.Errorf("Value should be replicated to the standby backend")
+	}
+}
@@ -0,0 +1,57 @@
+package backends
+
+// TTLDefaulter is implemented by backends that keep values put without a TTL for a time of their own
+type TTLDefaulter interface {
+	DefaultTTLSeconds() int
+}
+
+// DefaultTTLSeconds returns the TTL the backend gives to values put without one, or zero if it has none
+func DefaultTTLSeconds(backend Backend) int {
+	if defaulter, ok := backend.(TTLDefaulter); ok {
+		return defaulter.DefaultTTLSeconds()
+	}
+	return 0
+}
+
+// TTLRules are the settings that decide the TTL of a value. Defaults and the floor are ignored when
+// they're zero or less.
+type TTLRules struct {
+	FormatDefault  int
+	GlobalDefault  int
+	BackendDefault int
+	Floor          int
+	// Ceiling is the most restrictive max TTL that applies to the value. Unlike the other rules, it
+	// always applies.
+	Ceiling int
+}
+
+// ResolveTTL returns the TTL a value gets stored with. It's the first positive one of, in order:
+//
+//  1. the TTL requested by the client
+//  2. the default TTL of the value's format
+//  3. the global default TTL
+//  4. the default TTL of the backend
+//
+// which then gets raised to the floor and lowered to the ceiling. If floor and ceiling conflict, the
+// ceiling wins. If none of the TTLs is positive, the value is stored without a TTL and neither bound
+// applies.
+func ResolveTTL(requestedSeconds int, rules TTLRules) int {
+	ttl := requestedSeconds
+	for _, fallback := range []int{rules.FormatDefault, rules.GlobalDefault, rules.BackendDefault} {
+		if ttl > 0 {
+			break
+		}
+		ttl = fallback
+	}
+	if ttl <= 0 {
+		return 0
+	}
+
+	if ttl < rules.Floor {
+		ttl = rules.Floor
+	}
+	if ttl > rules.Ceiling {
+		ttl = rules.Ceiling
+	}
+	return ttl
+}
@@ -0,0 +1,199 @@
+package backends
+
+import (
+	"testing"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTTLPrecedence(t *testing.T) {
+	const (
+		client         = 100
+		formatDefault  = 200
+		globalDefault  = 300
+		backendDefault = 400
+		ceiling        = 3600
+	)
+
+	testCases := []struct {
+		desc        string
+		inRequested int
+		inRules     TTLRules
+		expectedTTL int
+	}{
+		{
+			desc:        "Nothing set",
+			inRules:     TTLRules{Ceiling: ceiling},
+			expectedTTL: 0,
+		},
+		{
+			desc:        "Backend default only",
+			inRules:     TTLRules{BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: backendDefault,
+		},
+		{
+			desc:        "Global default only",
+			inRules:     TTLRules{GlobalDefault: globalDefault, Ceiling: ceiling},
+			expectedTTL: globalDefault,
+		},
+		{
+			desc:        "Global default wins over backend default",
+			inRules:     TTLRules{GlobalDefault: globalDefault, BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: globalDefault,
+		},
+		{
+			desc:        "Format default only",
+			inRules:     TTLRules{FormatDefault: formatDefault, Ceiling: ceiling},
+			expectedTTL: formatDefault,
+		},
+		{
+			desc:        "Format default wins over backend default",
+			inRules:     TTLRules{FormatDefault: formatDefault, BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: formatDefault,
+		},
+		{
+			desc:        "Format default wins over global default",
+			inRules:     TTLRules{FormatDefault: formatDefault, GlobalDefault: globalDefault, Ceiling: ceiling},
+			expectedTTL: formatDefault,
+		},
+		{
+			desc:        "Format default wins over every other default",
+			inRules:     TTLRules{FormatDefault: formatDefault, GlobalDefault: globalDefault, BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: formatDefault,
+		},
+		{
+			desc:        "Client TTL only",
+			inRequested: client,
+			inRules:     TTLRules{Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over backend default",
+			inRequested: client,
+			inRules:     TTLRules{BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over global default",
+			inRequested: client,
+			inRules:     TTLRules{GlobalDefault: globalDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over global and backend defaults",
+			inRequested: client,
+			inRules:     TTLRules{GlobalDefault: globalDefault, BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over format default",
+			inRequested: client,
+			inRules:     TTLRules{FormatDefault: formatDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over format and backend defaults",
+			inRequested: client,
+			inRules:     TTLRules{FormatDefault: formatDefault, BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over format and global defaults",
+			inRequested: client,
+			inRules:     TTLRules{FormatDefault: formatDefault, GlobalDefault: globalDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+		{
+			desc:        "Client TTL wins over every default",
+			inRequested: client,
+			inRules:     TTLRules{FormatDefault: formatDefault, GlobalDefault: globalDefault, BackendDefault: backendDefault, Ceiling: ceiling},
+			expectedTTL: client,
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedTTL, ResolveTTL(test.inRequested, test.inRules), test.desc)
+	}
+}
+
+func TestResolveTTLBounds(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		inRequested int
+		inRules     TTLRules
+		expectedTTL int
+	}{
+		{
+			desc:        "Client TTL below the floor is raised",
+			inRequested: 10,
+			inRules:     TTLRules{Floor: 60, Ceiling: 3600},
+			expectedTTL: 60,
+		},
+		{
+			desc:        "Client TTL above the ceiling is lowered",
+			inRequested: 5000,
+			inRules:     TTLRules{Floor: 60, Ceiling: 3600},
+			expectedTTL: 3600,
+		},
+		{
+			desc:        "Client TTL within bounds is kept",
+			inRequested: 600,
+			inRules:     TTLRules{Floor: 60, Ceiling: 3600},
+			expectedTTL: 600,
+		},
+		{
+			desc:        "Format default below the floor is raised",
+			inRules:     TTLRules{FormatDefault: 30, Floor: 60, Ceiling: 3600},
+			expectedTTL: 60,
+		},
+		{
+			desc:        "Global default above the ceiling is lowered",
+			inRules:     TTLRules{GlobalDefault: 7200, Ceiling: 3600},
+			expectedTTL: 3600,
+		},
+		{
+			desc:        "Backend default above the ceiling is lowered",
+			inRules:     TTLRules{BackendDefault: 7200, Ceiling: 3600},
+			expectedTTL: 3600,
+		},
+		{
+			desc:        "Backend default below the floor is raised",
+			inRules:     TTLRules{BackendDefault: 10, Floor: 60, Ceiling: 3600},
+			expectedTTL: 60,
+		},
+		{
+			desc:        "Ceiling wins over a conflicting floor",
+			inRequested: 100,
+			inRules:     TTLRules{Floor: 600, Ceiling: 300},
+			expectedTTL: 300,
+		},
+		{
+			desc:        "Floor doesn't give a TTL to values stored without one",
+			inRules:     TTLRules{Floor: 60, Ceiling: 3600},
+			expectedTTL: 0,
+		},
+		{
+			desc:        "Negative client TTL falls back to the defaults",
+			inRequested: -1,
+			inRules:     TTLRules{GlobalDefault: 300, Ceiling: 3600},
+			expectedTTL: 300,
+		},
+		{
+			desc:        "Zero ceiling still applies",
+			inRequested: 100,
+			inRules:     TTLRules{},
+			expectedTTL: 0,
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedTTL, ResolveTTL(test.inRequested, test.inRules), test.desc)
+	}
+}
+
+func TestDefaultTTLSeconds(t *testing.T) {
+	assert.Equal(t, 2400, DefaultTTLSeconds(&Cassandra{}), "Cassandra has a default TTL of its own")
+	assert.Equal(t, 600, DefaultTTLSeconds(&RedisBackend{cfg: config.Redis{Expiration: 10}}), "Redis has its expiration configured in minutes")
+	assert.Equal(t, 0, DefaultTTLSeconds(NewMemoryBackend()), "The memory backend keeps values without a TTL")
+}
@@ -0,0 +1,92 @@
+package compression
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// Compressed values are stored behind a two byte header: magicByte, followed by the id of the codec that
+// compressed them. Values stored without compression start either with the "json" or "xml" format
+// prefix or with a non printable envelope version byte well below magicByte, so they can never be
+// mistaken for compressed ones.
+const (
+	magicByte  byte = 0x1c
+	headerSize      = 2
+
+	gzipID   byte = 0x01
+	snappyID byte = 0x02
+	zstdID   byte = 0x03
+)
+
+// codec compresses values with a single algorithm
+type codec interface {
+	encode(value []byte) ([]byte, error)
+	decode(compressed []byte) ([]byte, error)
+}
+
+// codecs are the codecs able to decompress a value, by the id in its header. Every compressor reads any
+// of them, so values remain readable after switching compression types.
+var codecs = map[byte]codec{
+	gzipID:   gzipCodec{},
+	snappyID: snappyCodec{},
+	zstdID:   newZstdCodec(),
+}
+
+// Decompress only decompresses the values read from the backend, and stores new values as they are. It
+// keeps the values compressed before turning compression off readable.
+func Decompress(backend backends.Backend) backends.Backend {
+	return &compressor{delegate: backend}
+}
+
+// compressor stores values compressed by the codec of id, or as they are if id is zero. Values read
+// without a header are decompressed by legacy, if set, or returned as they are.
+type compressor struct {
+	delegate backends.Backend
+	id       byte
+	legacy   codec
+}
+
+func (c *compressor) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	if c.id == 0 {
+		return c.delegate.Put(ctx, key, value, ttlSeconds)
+	}
+
+	compressed, err := codecs[c.id].encode([]byte(value))
+	if err != nil {
+		return err
+	}
+	stored := make([]byte, 0, headerSize+len(compressed))
+	stored = append(stored, magicByte, c.id)
+	stored = append(stored, compressed...)
+	return c.delegate.Put(ctx, key, string(stored), ttlSeconds)
+}
+
+func (c *compressor) Get(ctx context.Context, key string) (string, error) {
+	stored, err := c.delegate.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(stored) < headerSize || stored[0] != magicByte {
+		if c.legacy == nil {
+			return stored, nil
+		}
+		return decodeWith(c.legacy, stored)
+	}
+	valueCodec, ok := codecs[stored[1]]
+	if !ok {
+		return "", utils.NewBackendError(utils.Corrupt, fmt.Errorf("value of key %s is compressed with unknown codec %d", key, stored[1]))
+	}
+	return decodeWith(valueCodec, stored[headerSize:])
+}
+
+func decodeWith(valueCodec codec, compressed string) (string, error) {
+	decompressed, err := valueCodec.decode([]byte(compressed))
+	if err != nil {
+		return "", utils.NewBackendError(utils.Corrupt, err)
+	}
+	return string(decompressed), nil
+}
@@ -0,0 +1,72 @@
+package compression
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	value := "json" + strings.Repeat(`{"field":"value"}`, 100)
+	compressors := map[string]func(backends.Backend) backends.Backend{
+		"gzip":   GzipCompress,
+		"snappy": SnappyCompress,
+		"zstd":   ZstdCompress,
+	}
+
+	for name, compress := range compressors {
+		base := backends.NewMemoryBackend()
+		backend := compress(base)
+
+		assert.NoError(t, backend.Put(context.Background(), "key", value, 0), name)
+
+		stored, _ := base.Get(context.Background(), "key")
+		assert.Less(t, len(stored), len(value), "%s: the stored value should be compressed", name)
+		assert.Equal(t, magicByte, stored[0], name)
+		read, err := backend.Get(context.Background(), "key")
+		assert.NoError(t, err, name)
+		assert.Equal(t, value, read, name)
+
+		// Any other compressor, or none at all, reads it too
+		for otherName, otherCompress := range compressors {
+			read, err = otherCompress(base).Get(context.Background(), "key")
+			assert.NoError(t, err, "%s read by %s", name, otherName)
+			assert.Equal(t, value, read, "%s read by %s", name, otherName)
+		}
+		read, err = Decompress(base).Get(context.Background(), "key")
+		assert.NoError(t, err, "%s read without compression", name)
+		assert.Equal(t, value, read, "%s read without compression", name)
+	}
+}
+
+func TestCompressionLegacyValues(t *testing.T) {
+	base := backends.NewMemoryBackend()
+	base.Put(context.Background(), "uncompressed", `json{"field":"value"}`, 0)
+	base.Put(context.Background(), "legacy-snappy", string(snappy.Encode(nil, []byte(`xml<tag></tag>`))), 0)
+
+	for _, backend := range []backends.Backend{Decompress(base), GzipCompress(base), ZstdCompress(base)} {
+		read, err := backend.Get(context.Background(), "uncompressed")
+		assert.NoError(t, err)
+		assert.Equal(t, `json{"field":"value"}`, read, "Values stored without compression should be read as they are")
+	}
+
+	read, err := SnappyCompress(base).Get(context.Background(), "legacy-snappy")
+	assert.NoError(t, err)
+	assert.Equal(t, `xml<tag></tag>`, read, "Snappy values stored without a header should still be read")
+}
+
+func TestCompressionCorruptValues(t *testing.T) {
+	base := backends.NewMemoryBackend()
+	base.Put(context.Background(), "unknown-codec", string([]byte{magicByte, 0x7f, 'a'}), 0)
+	base.Put(context.Background(), "truncated", string([]byte{magicByte, gzipID, 0x1f, 0x8b}), 0)
+
+	for _, key := range []string{"unknown-codec", "truncated"} {
+		_, err := ZstdCompress(base).Get(context.Background(), key)
+		assert.Equal(t, utils.Corrupt, utils.ErrorCodeOf(err), key)
+	}
+}
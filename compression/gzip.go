@@ -0,0 +1,41 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/prebid/prebid-cache/backends"
+)
+
+// GzipCompress runs gzip compression on data before saving it in the backend. It compresses better
+// than snappy, but takes longer.
+func GzipCompress(backend backends.Backend) backends.Backend {
+	return &compressor{
+		delegate: backend,
+		id:       gzipID,
+	}
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) encode(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(value); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) decode(compressed []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
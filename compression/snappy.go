@@ -1,38 +1,28 @@
 package compression
 
 import (
-	"context"
-
 	"github.com/golang/snappy"
 	"github.com/prebid/prebid-cache/backends"
 )
 
 // SnappyCompress runs snappy compression on data before saving it in the backend.
 // For more info, see https://en.wikipedia.org/wiki/Snappy_(compression)
+//
+// Older versions stored snappy values without a header, so values without one are read as snappy too.
 func SnappyCompress(backend backends.Backend) backends.Backend {
-	return &snappyCompressor{
+	return &compressor{
 		delegate: backend,
+		id:       snappyID,
+		legacy:   snappyCodec{},
 	}
 }
 
-type snappyCompressor struct {
-	delegate backends.Backend
-}
+type snappyCodec struct{}
 
-func (s *snappyCompressor) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
-	return s.delegate.Put(ctx, key, string(snappy.Encode(nil, []byte(value))), ttlSeconds)
+func (snappyCodec) encode(value []byte) ([]byte, error) {
+	return snappy.Encode(nil, value), nil
 }
 
-func (s *snappyCompressor) Get(ctx context.Context, key string) (string, error) {
-	compressed, err := s.delegate.Get(ctx, key)
-	if err != nil {
-		return "", err
-	}
-
-	decompressed, err := snappy.Decode(nil, []byte(compressed))
-	if err != nil {
-		return "", err
-	}
-
-	return string(decompressed), nil
+func (snappyCodec) decode(compressed []byte) ([]byte, error) {
+	return snappy.Decode(nil, compressed)
 }
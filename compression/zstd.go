@@ -0,0 +1,37 @@
+package compression
+
+import (
+	"github.com/klauspost/compress/zstd"
+	"github.com/prebid/prebid-cache/backends"
+)
+
+// ZstdCompress runs zstd compression on data before saving it in the backend. It compresses about as
+// well as gzip, at close to the speed of snappy.
+func ZstdCompress(backend backends.Backend) backends.Backend {
+	return &compressor{
+		delegate: backend,
+		id:       zstdID,
+	}
+}
+
+// zstdCodec shares a single encoder and decoder, whose EncodeAll and DecodeAll are safe to call
+// concurrently
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCodec() zstdCodec {
+	// Neither fails without options
+	encoder, _ := zstd.NewWriter(nil)
+	decoder, _ := zstd.NewReader(nil)
+	return zstdCodec{encoder: encoder, decoder: decoder}
+}
+
+func (c zstdCodec) encode(value []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(value, nil), nil
+}
+
+func (c zstdCodec) decode(compressed []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(compressed, nil)
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -10,30 +13,277 @@ type Backend struct {
 	Type      BackendType `mapstructure:"type"`
 	Aerospike Aerospike   `mapstructure:"aerospike"`
 	Azure     Azure       `mapstructure:"azure"`
+	AzureBlob AzureBlob   `mapstructure:"azure_blob"`
 	Cassandra Cassandra   `mapstructure:"cassandra"`
+	Couchbase Couchbase   `mapstructure:"couchbase"`
+	Etcd      Etcd        `mapstructure:"etcd"`
 	Memcache  Memcache    `mapstructure:"memcache"`
+	Memory    Memory      `mapstructure:"memory"`
+	NATS      NATS        `mapstructure:"nats"`
+	Postgres  Postgres    `mapstructure:"postgres"`
 	Redis     Redis       `mapstructure:"redis"`
+	S3        S3          `mapstructure:"s3"`
+	// ValueVersion is the envelope version new values get stored with. Values stored with any
+	// known version can be read regardless, so a new version should only be written once every
+	// instance runs a build that reads it.
+	ValueVersion int `mapstructure:"value_version"`
+	// KeyPrefix is prepended to every key stored, so that instances with different prefixes can share
+	// the same keyspace, table or bucket without reading each other's values. Clients keep using the
+	// bare keys. The standby backend gets the prefixed keys as well.
+	KeyPrefix string `mapstructure:"key_prefix"`
+	// AllowKeyManagement lets PUT requests store values under keys of their own instead of generated
+	// UUIDs, and GET requests read keys that aren't UUIDs.
+	AllowKeyManagement bool `mapstructure:"allow_key_management"`
+	// KeyFormat is the format of the keys generated for values whose client didn't set one
+	KeyFormat      KeyFormat      `mapstructure:"key_format"`
+	Scrubber       Scrubber       `mapstructure:"scrubber"`
+	Retry          Retry          `mapstructure:"retry"`
+	CircuitBreaker CircuitBreaker `mapstructure:"circuit_breaker"`
+	WriteBehind    WriteBehind    `mapstructure:"write_behind"`
+	Batching       Batching       `mapstructure:"batching"`
+	// VerifyWrites reads every value back once stored, and fails the PUT request if it isn't there.
+	// It costs a read per value, so it's meant for tracking down backends that lose writes.
+	VerifyWrites bool `mapstructure:"verify_writes"`
+	// CoalesceGets has concurrent Gets of the same key share a single read from the backend. Nothing
+	// is cached: a Get arriving once the read is over reads again.
+	CoalesceGets   bool           `mapstructure:"coalesce_gets"`
+	NearCache      NearCache      `mapstructure:"near_cache"`
+	CollisionGuard CollisionGuard `mapstructure:"collision_guard"`
+	// MaxConcurrency caps the operations in flight on the backend. Operations past it wait for one of
+	// those to finish, for as long as their request allows. Zero leaves the backend unbounded.
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+	// CapabilityCheck asks the backend for its server version at startup, and turns off the features
+	// that version doesn't support.
+	CapabilityCheck CapabilityCheck `mapstructure:"capability_check"`
 }
 
 func (cfg *Backend) validateAndLog() error {
 
 	log.Infof("config.backend.type: %s", cfg.Type)
+	if cfg.ValueVersion < 0 || cfg.ValueVersion > 1 {
+		return fmt.Errorf("invalid config.backend.value_version: %d. It must be 0 or 1.", cfg.ValueVersion)
+	}
+	if cfg.ValueVersion > 0 {
+		log.Infof("config.backend.value_version: %d", cfg.ValueVersion)
+	}
+	if strings.IndexFunc(cfg.KeyPrefix, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("invalid config.backend.key_prefix: %q. It must not contain whitespace.", cfg.KeyPrefix)
+	}
+	if cfg.KeyPrefix != "" {
+		log.Infof("config.backend.key_prefix: %s", cfg.KeyPrefix)
+	}
+	if cfg.AllowKeyManagement {
+		log.Infof("config.backend.allow_key_management: %t", cfg.AllowKeyManagement)
+	}
+	switch cfg.KeyFormat {
+	case "", KeyFormatUUIDv4:
+	case KeyFormatUUIDv7:
+		log.Infof("config.backend.key_format: %s", cfg.KeyFormat)
+	default:
+		return fmt.Errorf(`invalid config.backend.key_format: %s. It must be "uuidv4" or "uuidv7".`, cfg.KeyFormat)
+	}
+	if err := cfg.Scrubber.validateAndLog(); err != nil {
+		return err
+	}
+	if err := cfg.Retry.validateAndLog(); err != nil {
+		return err
+	}
+	if err := cfg.CircuitBreaker.validateAndLog(); err != nil {
+		return err
+	}
+	if err := cfg.WriteBehind.validateAndLog(); err != nil {
+		return err
+	}
+	if err := cfg.Batching.validateAndLog(); err != nil {
+		return err
+	}
+	if cfg.VerifyWrites {
+		if cfg.WriteBehind.Enabled {
+			return fmt.Errorf("config.backend.verify_writes can't be enabled along with config.backend.write_behind, whose values are stored after the PUT request got its response")
+		}
+		log.Infof("config.backend.verify_writes: %t", cfg.VerifyWrites)
+	}
+	if cfg.CoalesceGets {
+		log.Infof("config.backend.coalesce_gets: %t", cfg.CoalesceGets)
+	}
+	if cfg.MaxConcurrency < 0 {
+		return fmt.Errorf("invalid config.backend.max_concurrency: %d. It must not be negative.", cfg.MaxConcurrency)
+	}
+	if cfg.MaxConcurrency > 0 {
+		log.Infof("config.backend.max_concurrency: %d", cfg.MaxConcurrency)
+	}
+	if err := cfg.NearCache.validateAndLog(); err != nil {
+		return err
+	}
+	if err := cfg.validateCollisionGuardAndLog(); err != nil {
+		return err
+	}
+	if cfg.CapabilityCheck.Enabled {
+		log.Infof("config.backend.capability_check.enabled: %t", cfg.CapabilityCheck.Enabled)
+		log.Infof("config.backend.capability_check.fail_on_unsupported: %t", cfg.CapabilityCheck.FailOnUnsupported)
+	}
+	return cfg.validateTypeAndLog()
+}
+
+// CapabilityCheck turns off the enabled features the backend server is too old for, with a warning, or
+// terminates the program if FailOnUnsupported is set. Only the backends able to report their server
+// version get checked.
+type CapabilityCheck struct {
+	Enabled           bool `mapstructure:"enabled"`
+	FailOnUnsupported bool `mapstructure:"fail_on_unsupported"`
+}
+
+// CollisionGuard stores the values under generated keys only if those keys don't hold a value yet. On
+// the off chance they do, the key gets generated again up to MaxRetries times. Conditional puts cost
+// more than plain ones on most backends, so the guard is opt-in.
+type CollisionGuard struct {
+	Enabled    bool `mapstructure:"enabled"`
+	MaxRetries int  `mapstructure:"max_retries"`
+}
+
+func (cfg *Backend) validateCollisionGuardAndLog() error {
+	if !cfg.CollisionGuard.Enabled {
+		return nil
+	}
+	switch cfg.Type {
+	case BackendMemory, BackendRedis:
+	default:
+		return fmt.Errorf("config.backend.collision_guard can't be enabled with config.backend.type %s. Only the memory and redis backends store values conditionally.", cfg.Type)
+	}
+	if cfg.WriteBehind.Enabled {
+		return fmt.Errorf("config.backend.collision_guard can't be enabled along with config.backend.write_behind, whose values are stored after the PUT request got its response")
+	}
+	if cfg.CollisionGuard.MaxRetries < 0 {
+		return fmt.Errorf("invalid config.backend.collision_guard.max_retries: %d. It must not be negative.", cfg.CollisionGuard.MaxRetries)
+	}
+	log.Infof("config.backend.collision_guard.enabled: %t", cfg.CollisionGuard.Enabled)
+	log.Infof("config.backend.collision_guard.max_retries: %d", cfg.CollisionGuard.MaxRetries)
+	return nil
+}
+
+// validateTypeAndLog validates the settings of the configured backend type only
+func (cfg *Backend) validateTypeAndLog() error {
 	switch cfg.Type {
 	case BackendAerospike:
 		return cfg.Aerospike.validateAndLog()
 	case BackendAzure:
 		return cfg.Azure.validateAndLog()
+	case BackendAzureBlob:
+		return cfg.AzureBlob.validateAndLog()
 	case BackendCassandra:
 		return cfg.Cassandra.validateAndLog()
+	case BackendCouchbase:
+		return cfg.Couchbase.validateAndLog()
+	case BackendEtcd:
+		return cfg.Etcd.validateAndLog()
 	case BackendMemcache:
 		return cfg.Memcache.validateAndLog()
+	case BackendNATS:
+		return cfg.NATS.validateAndLog()
+	case BackendPostgres:
+		return cfg.Postgres.validateAndLog()
 	case BackendRedis:
 		return cfg.Redis.validateAndLog()
+	case BackendS3:
+		return cfg.S3.validateAndLog()
 	case BackendMemory:
-		return nil
+		return cfg.Memory.validateAndLog()
 	default:
-		return fmt.Errorf(`invalid config.backend.type: %s. It must be "aerospike", "azure", "cassandra", "memcache", "redis", or "memory".`, cfg.Type)
+		return fmt.Errorf(`invalid config.backend.type: %s. It must be "aerospike", "azure", "azure_blob", "cassandra", "couchbase", "etcd", "memcache", "nats", "postgres", "redis", "s3", or "memory".`, cfg.Type)
+	}
+	return nil
+}
+
+// problems lists the settings the configured backend type can't connect without. The backend
+// settings are found under path, which differs for the standby backend.
+func (cfg *Backend) problems(path string) []string {
+	var missing []string
+	require := func(set bool, field string) {
+		if !set {
+			missing = append(missing, fmt.Sprintf("%s.%s is required by config.backend.type %s", path, field, cfg.Type))
+		}
+	}
+	switch cfg.Type {
+	case BackendAerospike:
+		require(cfg.Aerospike.Host != "" || len(cfg.Aerospike.Hosts) > 0, "aerospike.host or aerospike.hosts")
+		require(cfg.Aerospike.Port > 0, "aerospike.port")
+		require(cfg.Aerospike.Namespace != "", "aerospike.namespace")
+	case BackendAzure:
+		require(cfg.Azure.Account != "", "azure.account")
+		require(cfg.Azure.Key != "", "azure.key")
+	case BackendAzureBlob:
+		require(cfg.AzureBlob.Account != "", "azure_blob.account")
+		require(cfg.AzureBlob.Container != "", "azure_blob.container")
+	case BackendCassandra:
+		require(cfg.Cassandra.Hosts != "", "cassandra.hosts")
+		require(cfg.Cassandra.Keyspace != "", "cassandra.keyspace")
+	case BackendCouchbase:
+		require(cfg.Couchbase.ConnectionString != "", "couchbase.connection_string")
+		require(cfg.Couchbase.Bucket != "", "couchbase.bucket")
+	case BackendEtcd:
+		require(len(cfg.Etcd.Endpoints) > 0, "etcd.endpoints")
+	case BackendMemcache:
+		require(len(cfg.Memcache.Hosts) > 0, "memcache.hosts")
+	case BackendNATS:
+		require(cfg.NATS.URL != "", "nats.url")
+		require(cfg.NATS.Bucket != "", "nats.bucket")
+	case BackendPostgres:
+		require(cfg.Postgres.DSN != "", "postgres.dsn")
+		require(cfg.Postgres.Table != "", "postgres.table")
+	case BackendRedis:
+		if cfg.Redis.UsesSentinel() {
+			require(cfg.Redis.MasterName != "", "redis.master_name")
+		} else {
+			require(cfg.Redis.Host != "", "redis.host")
+			require(cfg.Redis.Port > 0, "redis.port")
+		}
+	case BackendS3:
+		require(cfg.S3.Bucket != "", "s3.bucket")
+	case BackendMemory:
+	case "":
+		missing = append(missing, fmt.Sprintf("%s.type is required", path))
+	default:
+		missing = append(missing, fmt.Sprintf(`unknown %s.type: %s. It must be "aerospike", "azure", "azure_blob", "cassandra", "couchbase", "etcd", "memcache", "nats", "postgres", "redis", "s3", or "memory".`, path, cfg.Type))
+	}
+	return missing
+}
+
+// Standby is a second backend every successful Put gets replicated to in the background, so that
+// it can take over if the primary backend is lost. Only the type of its Backend and the settings of
+// that type are used.
+type Standby struct {
+	Enabled       bool    `mapstructure:"enabled"`
+	Backend       Backend `mapstructure:"backend"`
+	QueueSize     int     `mapstructure:"queue_size"`
+	Workers       int     `mapstructure:"workers"`
+	MaxAttempts   int     `mapstructure:"max_attempts"`
+	BackoffMillis int     `mapstructure:"backoff_ms"`
+}
+
+func (cfg *Standby) validateAndLog() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.QueueSize <= 0 {
+		return fmt.Errorf("invalid config.standby.queue_size: %d. It must be greater than zero.", cfg.QueueSize)
+	}
+	if cfg.Workers <= 0 {
+		return fmt.Errorf("invalid config.standby.workers: %d. It must be greater than zero.", cfg.Workers)
+	}
+	if cfg.MaxAttempts < 1 {
+		return fmt.Errorf("invalid config.standby.max_attempts: %d. It must be at least 1.", cfg.MaxAttempts)
 	}
+	if cfg.BackoffMillis < 0 {
+		return fmt.Errorf("invalid config.standby.backoff_ms: %d. It must not be negative.", cfg.BackoffMillis)
+	}
+	log.Infof("config.standby.backend.type: %s", cfg.Backend.Type)
+	if err := cfg.Backend.validateTypeAndLog(); err != nil {
+		return fmt.Errorf("invalid config.standby.backend: %v", err)
+	}
+	log.Infof("config.standby.queue_size: %d", cfg.QueueSize)
+	log.Infof("config.standby.workers: %d", cfg.Workers)
+	log.Infof("config.standby.max_attempts: %d", cfg.MaxAttempts)
+	log.Infof("config.standby.backoff_ms: %d", cfg.BackoffMillis)
 	return nil
 }
 
@@ -42,10 +292,26 @@ type BackendType string
 const (
 	BackendAerospike BackendType = "aerospike"
 	BackendAzure     BackendType = "azure"
+	BackendAzureBlob BackendType = "azure_blob"
 	BackendCassandra BackendType = "cassandra"
+	BackendCouchbase BackendType = "couchbase"
+	BackendEtcd      BackendType = "etcd"
 	BackendMemcache  BackendType = "memcache"
 	BackendMemory    BackendType = "memory"
+	BackendNATS      BackendType = "nats"
+	BackendPostgres  BackendType = "postgres"
 	BackendRedis     BackendType = "redis"
+	BackendS3        BackendType = "s3"
+)
+
+type KeyFormat string
+
+const (
+	// KeyFormatUUIDv4 generates random UUIDs
+	KeyFormatUUIDv4 KeyFormat = "uuidv4"
+	// KeyFormatUUIDv7 generates UUIDs that start with the time they were generated at, so keys
+	// generated close in time sort close together, which backends like Cassandra compact better
+	KeyFormatUUIDv7 KeyFormat = "uuidv7"
 )
 
 type Aerospike struct {
@@ -56,6 +322,33 @@ type Aerospike struct {
 	Namespace  string   `mapstructure:"namespace"`
 	User       string   `mapstructure:"user"`
 	Password   string   `mapstructure:"password"`
+	// CommitLevel is how many replicas must store a value before its write succeeds: "all" of them, or
+	// only the "master" one
+	CommitLevel AerospikeCommitLevel `mapstructure:"commit_level"`
+	// SocketTimeoutMillis bounds the network activity of a single attempt of a read or write, and
+	// TotalTimeoutMillis all of its attempts. Zero keeps the client's default.
+	SocketTimeoutMillis int          `mapstructure:"socket_timeout_ms"`
+	TotalTimeoutMillis  int          `mapstructure:"total_timeout_ms"`
+	TLS                 AerospikeTLS `mapstructure:"tls"`
+}
+
+type AerospikeCommitLevel string
+
+const (
+	AerospikeCommitAll    AerospikeCommitLevel = "all"
+	AerospikeCommitMaster AerospikeCommitLevel = "master"
+)
+
+// AerospikeTLS secures the connection with an Aerospike Enterprise cluster. Name is the TLS name the
+// certificates of the nodes were issued for. CertFile and KeyFile are only needed when the cluster
+// authenticates its clients with certificates, and CAFile when its certificates aren't issued by a
+// certificate authority the system trusts.
+type AerospikeTLS struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Name     string `mapstructure:"name"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	CAFile   string `mapstructure:"ca_file"`
 }
 
 func (cfg *Aerospike) validateAndLog() error {
@@ -66,12 +359,38 @@ func (cfg *Aerospike) validateAndLog() error {
 	if cfg.Port <= 0 {
 		return fmt.Errorf("Cannot connect to Aerospike host at port %d", cfg.Port)
 	}
+	switch cfg.CommitLevel {
+	case "", AerospikeCommitAll, AerospikeCommitMaster:
+	default:
+		return fmt.Errorf(`invalid config.backend.aerospike.commit_level: %s. It must be "all" or "master".`, cfg.CommitLevel)
+	}
+	if cfg.SocketTimeoutMillis < 0 {
+		return fmt.Errorf("invalid config.backend.aerospike.socket_timeout_ms: %d. It must not be negative.", cfg.SocketTimeoutMillis)
+	}
+	if cfg.TotalTimeoutMillis < 0 {
+		return fmt.Errorf("invalid config.backend.aerospike.total_timeout_ms: %d. It must not be negative.", cfg.TotalTimeoutMillis)
+	}
+	if cfg.TLS.Enabled && cfg.TLS.Name == "" {
+		return fmt.Errorf("config.backend.aerospike.tls.name is required to connect over TLS")
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("config.backend.aerospike.tls.cert_file and config.backend.aerospike.tls.key_file must be set together")
+	}
 	log.Infof("config.backend.aerospike.default_ttl_seconds: %d", cfg.DefaultTTL)
 	log.Infof("config.backend.aerospike.host: %s", cfg.Host)
 	log.Infof("config.backend.aerospike.hosts: %v", cfg.Hosts)
 	log.Infof("config.backend.aerospike.port: %d", cfg.Port)
 	log.Infof("config.backend.aerospike.namespace: %s", cfg.Namespace)
 	log.Infof("config.backend.aerospike.user: %s", cfg.User)
+	log.Infof("config.backend.aerospike.commit_level: %s", cfg.CommitLevel)
+	log.Infof("config.backend.aerospike.socket_timeout_ms: %d", cfg.SocketTimeoutMillis)
+	log.Infof("config.backend.aerospike.total_timeout_ms: %d", cfg.TotalTimeoutMillis)
+	log.Infof("config.backend.aerospike.tls.enabled: %t", cfg.TLS.Enabled)
+	if cfg.TLS.Enabled {
+		log.Infof("config.backend.aerospike.tls.name: %s", cfg.TLS.Name)
+		log.Infof("config.backend.aerospike.tls.cert_file: %s", cfg.TLS.CertFile)
+		log.Infof("config.backend.aerospike.tls.ca_file: %s", cfg.TLS.CAFile)
+	}
 
 	return nil
 }
@@ -87,14 +406,387 @@ func (cfg *Azure) validateAndLog() error {
 	return nil
 }
 
+// AzureBlob stores every value as a blob of Container, under Prefix. Blob lifecycle rules work in whole
+// days, so values past their TTL are reported missing until a lifecycle rule deletes them. Requests are
+// authenticated with AccountKey or, when it's empty, with the managed identity of the host.
+type AzureBlob struct {
+	Account   string `mapstructure:"account"`
+	Container string `mapstructure:"container"`
+	Prefix    string `mapstructure:"prefix"`
+	// AccountKey is the shared key of the storage account
+	AccountKey string `mapstructure:"account_key"`
+	// ManagedIdentityClientID picks the user-assigned managed identity to authenticate with, for hosts
+	// that have several. Empty uses the system-assigned one.
+	ManagedIdentityClientID string `mapstructure:"managed_identity_client_id"`
+	// Endpoint replaces https://{account}.blob.core.windows.net, like for the Azurite emulator
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+func (cfg *AzureBlob) validateAndLog() error {
+	if cfg.Account == "" {
+		return fmt.Errorf("Cannot store values in Azure Blob Storage without a config.backend.azure_blob.account")
+	}
+	if cfg.Container == "" {
+		return fmt.Errorf("Cannot store values in Azure Blob Storage without a config.backend.azure_blob.container")
+	}
+	if cfg.AccountKey != "" && cfg.ManagedIdentityClientID != "" {
+		return fmt.Errorf("config.backend.azure_blob.account_key and config.backend.azure_blob.managed_identity_client_id can't be set together")
+	}
+	log.Infof("config.backend.azure_blob.account: %s", cfg.Account)
+	log.Infof("config.backend.azure_blob.container: %s", cfg.Container)
+	log.Infof("config.backend.azure_blob.prefix: %s", cfg.Prefix)
+	log.Infof("config.backend.azure_blob.endpoint: %s", cfg.Endpoint)
+	log.Infof("config.backend.azure_blob.managed_identity_client_id: %s", cfg.ManagedIdentityClientID)
+	return nil
+}
+
 type Cassandra struct {
 	Hosts    string `mapstructure:"hosts"`
 	Keyspace string `mapstructure:"keyspace"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// ConnectTimeoutMillis bounds dialing a host and QueryTimeoutMillis waiting for the response to a
+	// query. Zero keeps the driver's default.
+	ConnectTimeoutMillis int          `mapstructure:"connect_timeout_ms"`
+	QueryTimeoutMillis   int          `mapstructure:"query_timeout_ms"`
+	TLS                  CassandraTLS `mapstructure:"tls"`
+}
+
+// CassandraTLS holds the files used to secure the connection with the Cassandra cluster. CertFile
+// and KeyFile are only needed when the cluster authenticates its clients with certificates.
+type CassandraTLS struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CAFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
 }
 
 func (cfg *Cassandra) validateAndLog() error {
+	if (cfg.Username == "") != (cfg.Password == "") {
+		return fmt.Errorf("config.backend.cassandra.username and config.backend.cassandra.password must be set together")
+	}
+	if cfg.ConnectTimeoutMillis < 0 {
+		return fmt.Errorf("invalid config.backend.cassandra.connect_timeout_ms: %d. It must not be negative.", cfg.ConnectTimeoutMillis)
+	}
+	if cfg.QueryTimeoutMillis < 0 {
+		return fmt.Errorf("invalid config.backend.cassandra.query_timeout_ms: %d. It must not be negative.", cfg.QueryTimeoutMillis)
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("config.backend.cassandra.tls.cert_file and config.backend.cassandra.tls.key_file must be set together")
+	}
 	log.Infof("config.backend.cassandra.hosts: %s", cfg.Hosts)
 	log.Infof("config.backend.cassandra.keyspace: %s", cfg.Keyspace)
+	log.Infof("config.backend.cassandra.username: %s", cfg.Username)
+	log.Infof("config.backend.cassandra.connect_timeout_ms: %d", cfg.ConnectTimeoutMillis)
+	log.Infof("config.backend.cassandra.query_timeout_ms: %d", cfg.QueryTimeoutMillis)
+	log.Infof("config.backend.cassandra.tls.enabled: %t", cfg.TLS.Enabled)
+	if cfg.TLS.Enabled {
+		log.Infof("config.backend.cassandra.tls.ca_file: %s", cfg.TLS.CAFile)
+		log.Infof("config.backend.cassandra.tls.cert_file: %s", cfg.TLS.CertFile)
+		log.Infof("config.backend.cassandra.tls.insecure_skip_verify: %t", cfg.TLS.InsecureSkipVerify)
+	}
+	return nil
+}
+
+// Scrubber walks through the stored values in the background, verifying the checksums of the sampled
+// ones. Only backends able to list their keys can be scrubbed.
+type Scrubber struct {
+	Enabled       bool    `mapstructure:"enabled"`
+	KeysPerSecond int     `mapstructure:"keys_per_second"`
+	SampleRate    float64 `mapstructure:"sample_rate"`
+}
+
+func (cfg *Scrubber) validateAndLog() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.KeysPerSecond <= 0 {
+		return fmt.Errorf("invalid config.backend.scrubber.keys_per_second: %d. It must be greater than zero.", cfg.KeysPerSecond)
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		return fmt.Errorf("invalid config.backend.scrubber.sample_rate: %v. It must be greater than 0 and no more than 1.", cfg.SampleRate)
+	}
+	log.Infof("config.backend.scrubber.enabled: %t", cfg.Enabled)
+	log.Infof("config.backend.scrubber.keys_per_second: %d", cfg.KeysPerSecond)
+	log.Infof("config.backend.scrubber.sample_rate: %v", cfg.SampleRate)
+	return nil
+}
+
+// Retry tries backend operations that failed with a transient error again. Gets are idempotent and get
+// retried whenever retries are enabled, Puts only if RetryPuts is set. The backoff starts at BackoffMillis
+// and doubles after every attempt, up to MaxBackoffMillis if set.
+type Retry struct {
+	MaxAttempts      int  `mapstructure:"max_attempts"`
+	BackoffMillis    int  `mapstructure:"backoff_ms"`
+	MaxBackoffMillis int  `mapstructure:"max_backoff_ms"`
+	RetryPuts        bool `mapstructure:"retry_puts"`
+	// Jitter is the fraction of every backoff that's randomized, so that the retries of operations that
+	// failed together don't all hit the backend at once again
+	Jitter float64 `mapstructure:"jitter"`
+	// OverrideHeader names the request header that sets the max attempts of that request alone,
+	// capped at MaxAttemptsCeiling. Requests can't override the max attempts if it's left empty.
+	OverrideHeader     string `mapstructure:"override_header"`
+	MaxAttemptsCeiling int    `mapstructure:"max_attempts_ceiling"`
+	// OverrideClients are the values of OverrideClientHeader, like API keys, of the clients allowed to
+	// override the max attempts. The override header of any other request is ignored.
+	OverrideClientHeader string   `mapstructure:"override_client_header"`
+	OverrideClients      []string `mapstructure:"override_clients"`
+}
+
+// Enabled tells whether any request could have its failed backend operations retried
+func (cfg *Retry) Enabled() bool {
+	return cfg.MaxAttempts > 1 || cfg.OverrideHeader != ""
+}
+
+func (cfg *Retry) validateAndLog() error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	if cfg.BackoffMillis < 0 {
+		return fmt.Errorf("invalid config.backend.retry.backoff_ms: %d. It must not be negative.", cfg.BackoffMillis)
+	}
+	if cfg.MaxBackoffMillis != 0 && cfg.MaxBackoffMillis < cfg.BackoffMillis {
+		return fmt.Errorf("invalid config.backend.retry.max_backoff_ms: %d. It must be 0, for no cap, or at least config.backend.retry.backoff_ms.", cfg.MaxBackoffMillis)
+	}
+	if cfg.Jitter < 0 || cfg.Jitter > 1 {
+		return fmt.Errorf("invalid config.backend.retry.jitter: %v. It must be between 0 and 1.", cfg.Jitter)
+	}
+	if cfg.OverrideHeader != "" && cfg.MaxAttemptsCeiling < 1 {
+		return fmt.Errorf("invalid config.backend.retry.max_attempts_ceiling: %d. It must be at least 1 when config.backend.retry.override_header is set.", cfg.MaxAttemptsCeiling)
+	}
+	if cfg.OverrideHeader != "" && (cfg.OverrideClientHeader == "" || len(cfg.OverrideClients) == 0) {
+		return fmt.Errorf("config.backend.retry.override_client_header and config.backend.retry.override_clients must be set when config.backend.retry.override_header is, so that only those clients can override the max attempts")
+	}
+	log.Infof("config.backend.retry.max_attempts: %d", cfg.MaxAttempts)
+	log.Infof("config.backend.retry.backoff_ms: %d", cfg.BackoffMillis)
+	log.Infof("config.backend.retry.max_backoff_ms: %d", cfg.MaxBackoffMillis)
+	log.Infof("config.backend.retry.jitter: %v", cfg.Jitter)
+	log.Infof("config.backend.retry.retry_puts: %t", cfg.RetryPuts)
+	if cfg.OverrideHeader != "" {
+		log.Infof("config.backend.retry.override_header: %s", cfg.OverrideHeader)
+		log.Infof("config.backend.retry.max_attempts_ceiling: %d", cfg.MaxAttemptsCeiling)
+		log.Infof("config.backend.retry.override_client_header: %s", cfg.OverrideClientHeader)
+		log.Infof("config.backend.retry.override_clients: %d clients", len(cfg.OverrideClients))
+	}
+	return nil
+}
+
+// CircuitBreaker stops sending operations to the backend once FailureThreshold of them in a row failed,
+// and fails them right away instead. After CooldownMillis, a single operation is let through to probe
+// the backend: the breaker closes again if it succeeds, and stays open for another cooldown otherwise.
+type CircuitBreaker struct {
+	Enabled          bool `mapstructure:"enabled"`
+	FailureThreshold int  `mapstructure:"failure_threshold"`
+	CooldownMillis   int  `mapstructure:"cooldown_ms"`
+}
+
+func (cfg *CircuitBreaker) validateAndLog() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.FailureThreshold < 1 {
+		return fmt.Errorf("invalid config.backend.circuit_breaker.failure_threshold: %d. It must be at least 1.", cfg.FailureThreshold)
+	}
+	if cfg.CooldownMillis <= 0 {
+		return fmt.Errorf("invalid config.backend.circuit_breaker.cooldown_ms: %d. It must be greater than zero.", cfg.CooldownMillis)
+	}
+	log.Infof("config.backend.circuit_breaker.enabled: %t", cfg.Enabled)
+	log.Infof("config.backend.circuit_breaker.failure_threshold: %d", cfg.FailureThreshold)
+	log.Infof("config.backend.circuit_breaker.cooldown_ms: %d", cfg.CooldownMillis)
+	return nil
+}
+
+// NearCache keeps the values read from the backend in memory for TTLSeconds, so that hot keys are served
+// without reaching the backend. Values are evicted from the least recently used once the cache holds
+// MaxEntries values, or MaxBytes bytes if set. Values stored are written to the backend, and also kept in
+// the cache if PrimeOnPut is set.
+type NearCache struct {
+	Enabled    bool `mapstructure:"enabled"`
+	MaxEntries int  `mapstructure:"max_entries"`
+	MaxBytes   int  `mapstructure:"max_bytes"`
+	TTLSeconds int  `mapstructure:"ttl_seconds"`
+	PrimeOnPut bool `mapstructure:"prime_on_put"`
+}
+
+func (cfg *NearCache) validateAndLog() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MaxEntries < 1 {
+		return fmt.Errorf("invalid config.backend.near_cache.max_entries: %d. It must be at least 1.", cfg.MaxEntries)
+	}
+	if cfg.MaxBytes < 0 {
+		return fmt.Errorf("invalid config.backend.near_cache.max_bytes: %d. It must not be negative.", cfg.MaxBytes)
+	}
+	if cfg.TTLSeconds < 1 {
+		return fmt.Errorf("invalid config.backend.near_cache.ttl_seconds: %d. It must be at least 1.", cfg.TTLSeconds)
+	}
+	log.Infof("config.backend.near_cache.enabled: %t", cfg.Enabled)
+	log.Infof("config.backend.near_cache.max_entries: %d", cfg.MaxEntries)
+	if cfg.MaxBytes > 0 {
+		log.Infof("config.backend.near_cache.max_bytes: %d", cfg.MaxBytes)
+	}
+	log.Infof("config.backend.near_cache.ttl_seconds: %d", cfg.TTLSeconds)
+	log.Infof("config.backend.near_cache.prime_on_put: %t", cfg.PrimeOnPut)
+	return nil
+}
+
+// WriteBehind queues Puts up to BufferSize and has Workers write them to the backend in the background.
+// Values are then not durably stored by the time the PUT request gets its response, which is signaled
+// with a 202 unless RespondAccepted is turned off for clients that can't handle it.
+type WriteBehind struct {
+	Enabled         bool `mapstructure:"enabled"`
+	BufferSize      int  `mapstructure:"buffer_size"`
+	Workers         int  `mapstructure:"workers"`
+	RespondAccepted bool `mapstructure:"respond_accepted"`
+}
+
+// RespondsAccepted tells whether PUT requests should be answered with a 202 Accepted
+func (cfg *WriteBehind) RespondsAccepted() bool {
+	return cfg.Enabled && cfg.RespondAccepted
+}
+
+func (cfg *WriteBehind) validateAndLog() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.BufferSize <= 0 {
+		return fmt.Errorf("invalid config.backend.write_behind.buffer_size: %d. It must be greater than zero.", cfg.BufferSize)
+	}
+	if cfg.Workers <= 0 {
+		return fmt.Errorf("invalid config.backend.write_behind.workers: %d. It must be greater than zero.", cfg.Workers)
+	}
+	log.Infof("config.backend.write_behind.enabled: %t", cfg.Enabled)
+	log.Infof("config.backend.write_behind.buffer_size: %d", cfg.BufferSize)
+	log.Infof("config.backend.write_behind.workers: %d", cfg.Workers)
+	log.Infof("config.backend.write_behind.respond_accepted: %t", cfg.RespondAccepted)
+	return nil
+}
+
+// Batching holds the Puts and Gets for up to WindowMillis, and sends the ones that came in meanwhile to
+// the backend as a single batch of up to MaxSize operations. Only the backends able to run operations in
+// batches get them batched.
+type Batching struct {
+	Enabled      bool `mapstructure:"enabled"`
+	WindowMillis int  `mapstructure:"window_ms"`
+	MaxSize      int  `mapstructure:"max_size"`
+}
+
+func (cfg *Batching) validateAndLog() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.WindowMillis <= 0 {
+		return fmt.Errorf("invalid config.backend.batching.window_ms: %d. It must be greater than zero.", cfg.WindowMillis)
+	}
+	if cfg.MaxSize <= 0 {
+		return fmt.Errorf("invalid config.backend.batching.max_size: %d. It must be greater than zero.", cfg.MaxSize)
+	}
+	log.Infof("config.backend.batching.enabled: %t", cfg.Enabled)
+	log.Infof("config.backend.batching.window_ms: %d", cfg.WindowMillis)
+	log.Infof("config.backend.batching.max_size: %d", cfg.MaxSize)
+	return nil
+}
+
+type Couchbase struct {
+	ConnectionString string `mapstructure:"connection_string"`
+	Bucket           string `mapstructure:"bucket"`
+	Scope            string `mapstructure:"scope"`
+	Collection       string `mapstructure:"collection"`
+	Username         string `mapstructure:"username"`
+	Password         string `mapstructure:"password"`
+}
+
+func (cfg *Couchbase) validateAndLog() error {
+	if cfg.ConnectionString == "" {
+		return fmt.Errorf("Cannot connect to Couchbase with an empty config.backend.couchbase.connection_string")
+	}
+	if cfg.Bucket == "" {
+		return fmt.Errorf("Cannot store values in Couchbase without a config.backend.couchbase.bucket")
+	}
+	log.Infof("config.backend.couchbase.connection_string: %s", cfg.ConnectionString)
+	log.Infof("config.backend.couchbase.bucket: %s", cfg.Bucket)
+	log.Infof("config.backend.couchbase.scope: %s", cfg.Scope)
+	log.Infof("config.backend.couchbase.collection: %s", cfg.Collection)
+	log.Infof("config.backend.couchbase.username: %s", cfg.Username)
+	return nil
+}
+
+type Etcd struct {
+	Endpoints         []string `mapstructure:"endpoints"`
+	DialTimeoutMillis int      `mapstructure:"dial_timeout_ms"`
+	Username          string   `mapstructure:"username"`
+	Password          string   `mapstructure:"password"`
+	TLS               EtcdTLS  `mapstructure:"tls"`
+}
+
+// EtcdTLS holds the files used to secure the connection with the etcd cluster. CertFile and KeyFile
+// are only needed when the cluster authenticates its clients with certificates.
+type EtcdTLS struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	CAFile   string `mapstructure:"ca_file"`
+}
+
+func (cfg *Etcd) validateAndLog() error {
+	if len(cfg.Endpoints) == 0 {
+		return fmt.Errorf("Cannot connect to etcd without any config.backend.etcd.endpoints")
+	}
+	if cfg.DialTimeoutMillis < 0 {
+		return fmt.Errorf("invalid config.backend.etcd.dial_timeout_ms: %d. It must not be negative.", cfg.DialTimeoutMillis)
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("config.backend.etcd.tls.cert_file and config.backend.etcd.tls.key_file must be set together")
+	}
+	log.Infof("config.backend.etcd.endpoints: %v", cfg.Endpoints)
+	log.Infof("config.backend.etcd.dial_timeout_ms: %d", cfg.DialTimeoutMillis)
+	log.Infof("config.backend.etcd.username: %s", cfg.Username)
+	log.Infof("config.backend.etcd.tls.enabled: %t", cfg.TLS.Enabled)
+	return nil
+}
+
+// Memory splits the in-memory backend in Shards, each with its own lock. MaxEntries is the number of
+// values all shards can hold together before evicting the least recently used ones. Zero means no limit.
+type Memory struct {
+	MaxEntries int `mapstructure:"max_entries"`
+	// MaxBytes caps the combined size of the keys and values held. Like MaxEntries, it's split evenly
+	// across the shards, so no value can be larger than the share of a single shard.
+	MaxBytes int `mapstructure:"max_bytes"`
+	Shards   int `mapstructure:"shards"`
+	// EvictionMetrics counts the evicted values by the reason they were evicted for, and records how
+	// long they had been stored for
+	EvictionMetrics bool `mapstructure:"eviction_metrics"`
+	// LogEvictions logs the key of every evicted value at the debug level
+	LogEvictions bool `mapstructure:"log_evictions"`
+}
+
+func (cfg *Memory) validateAndLog() error {
+	if cfg.Shards < 1 {
+		return fmt.Errorf("invalid config.backend.memory.shards: %d. It must be at least 1.", cfg.Shards)
+	}
+	if cfg.MaxEntries < 0 {
+		return fmt.Errorf("invalid config.backend.memory.max_entries: %d. It must not be negative.", cfg.MaxEntries)
+	}
+	if cfg.MaxBytes < 0 {
+		return fmt.Errorf("invalid config.backend.memory.max_bytes: %d. It must not be negative.", cfg.MaxBytes)
+	}
+	if cfg.MaxEntries > 0 {
+		log.Infof("config.backend.memory.max_entries: %d", cfg.MaxEntries)
+	}
+	if cfg.MaxBytes > 0 {
+		log.Infof("config.backend.memory.max_bytes: %d", cfg.MaxBytes)
+	}
+	if cfg.MaxEntries > 0 || cfg.MaxBytes > 0 {
+		log.Infof("config.backend.memory.shards: %d", cfg.Shards)
+	}
+	if cfg.EvictionMetrics {
+		log.Infof("config.backend.memory.eviction_metrics: %t", cfg.EvictionMetrics)
+	}
+	if cfg.LogEvictions {
+		log.Infof("config.backend.memory.log_evictions: %t", cfg.LogEvictions)
+	}
 	return nil
 }
 
@@ -107,13 +799,83 @@ func (cfg *Memcache) validateAndLog() error {
 	return nil
 }
 
+// NATS stores values in a JetStream key-value bucket. TTLSeconds is only used to create the bucket if
+// it doesn't exist, since JetStream expires every value of a bucket after the same TTL.
+type NATS struct {
+	URL             string `mapstructure:"url"`
+	Bucket          string `mapstructure:"bucket"`
+	TTLSeconds      int    `mapstructure:"ttl_seconds"`
+	CredentialsFile string `mapstructure:"credentials_file"`
+	Token           string `mapstructure:"token"`
+	Username        string `mapstructure:"username"`
+	Password        string `mapstructure:"password"`
+}
+
+func (cfg *NATS) validateAndLog() error {
+	if cfg.URL == "" {
+		return fmt.Errorf("Cannot connect to NATS with an empty config.backend.nats.url")
+	}
+	if cfg.Bucket == "" {
+		return fmt.Errorf("Cannot store values in NATS without a config.backend.nats.bucket")
+	}
+	if cfg.TTLSeconds < 0 {
+		return fmt.Errorf("invalid config.backend.nats.ttl_seconds: %d. It must not be negative.", cfg.TTLSeconds)
+	}
+	log.Infof("config.backend.nats.url: %s", cfg.URL)
+	log.Infof("config.backend.nats.bucket: %s", cfg.Bucket)
+	log.Infof("config.backend.nats.ttl_seconds: %d", cfg.TTLSeconds)
+	log.Infof("config.backend.nats.credentials_file: %s", cfg.CredentialsFile)
+	log.Infof("config.backend.nats.username: %s", cfg.Username)
+	return nil
+}
+
+// Postgres stores values in Table, which must have at least these columns:
+//
+//	key TEXT PRIMARY KEY, value TEXT NOT NULL, expires_at TIMESTAMPTZ
+//
+// Expired rows are deleted every CleanupIntervalSeconds. Zero leaves them to be deleted by someone else.
+type Postgres struct {
+	DSN                    string `mapstructure:"dsn"`
+	Table                  string `mapstructure:"table"`
+	CleanupIntervalSeconds int    `mapstructure:"cleanup_interval_seconds"`
+}
+
+// postgresTable matches table names, optionally qualified by their schema. The table name is part of
+// every query, so anything else is rejected.
+var postgresTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+func (cfg *Postgres) validateAndLog() error {
+	if cfg.DSN == "" {
+		return fmt.Errorf("Cannot connect to Postgres with an empty config.backend.postgres.dsn")
+	}
+	if !postgresTable.MatchString(cfg.Table) {
+		return fmt.Errorf("invalid config.backend.postgres.table: %q. It must be a table name, optionally qualified by its schema.", cfg.Table)
+	}
+	if cfg.CleanupIntervalSeconds < 0 {
+		return fmt.Errorf("invalid config.backend.postgres.cleanup_interval_seconds: %d. It must not be negative.", cfg.CleanupIntervalSeconds)
+	}
+	// The DSN isn't logged because it usually carries the password
+	log.Infof("config.backend.postgres.table: %s", cfg.Table)
+	log.Infof("config.backend.postgres.cleanup_interval_seconds: %d", cfg.CleanupIntervalSeconds)
+	return nil
+}
+
+// Redis connects to Host and Port, unless SentinelAddrs and MasterName are set. Then the current
+// master of MasterName is looked up through the Sentinels, and looked up again when it fails over.
 type Redis struct {
-	Host       string   `mapstructure:"host"`
-	Port       int      `mapstructure:"port"`
-	Password   string   `mapstructure:"password"`
-	Db         int      `mapstructure:"db"`
-	Expiration int      `mapstructure:"expiration"`
-	TLS        RedisTLS `mapstructure:"tls"`
+	Host          string   `mapstructure:"host"`
+	Port          int      `mapstructure:"port"`
+	SentinelAddrs []string `mapstructure:"sentinel_addrs"`
+	MasterName    string   `mapstructure:"master_name"`
+	Password      string   `mapstructure:"password"`
+	Db            int      `mapstructure:"db"`
+	Expiration    int      `mapstructure:"expiration"`
+	TLS           RedisTLS `mapstructure:"tls"`
+}
+
+// UsesSentinel tells whether the master gets looked up through Redis Sentinel
+func (cfg *Redis) UsesSentinel() bool {
+	return len(cfg.SentinelAddrs) > 0
 }
 
 type RedisTLS struct {
@@ -122,11 +884,48 @@ type RedisTLS struct {
 }
 
 func (cfg *Redis) validateAndLog() error {
-	log.Infof("config.backend.redis.host: %s", cfg.Host)
-	log.Infof("config.backend.redis.port: %d", cfg.Port)
+	if cfg.UsesSentinel() {
+		if cfg.MasterName == "" {
+			return fmt.Errorf("Cannot look up the Redis master through Sentinel without a config.backend.redis.master_name")
+		}
+		log.Infof("config.backend.redis.sentinel_addrs: %v", cfg.SentinelAddrs)
+		log.Infof("config.backend.redis.master_name: %s", cfg.MasterName)
+	} else {
+		if cfg.MasterName != "" {
+			return fmt.Errorf("Cannot look up the Redis master %s without config.backend.redis.sentinel_addrs", cfg.MasterName)
+		}
+		log.Infof("config.backend.redis.host: %s", cfg.Host)
+		log.Infof("config.backend.redis.port: %d", cfg.Port)
+	}
 	log.Infof("config.backend.redis.db: %d", cfg.Db)
 	log.Infof("config.backend.redis.expiration: %d", cfg.Expiration)
 	log.Infof("config.backend.redis.tls.enabled: %t", cfg.TLS.Enabled)
 	log.Infof("config.backend.redis.tls.insecure_skip_verify: %t", cfg.TLS.InsecureSkipVerify)
 	return nil
 }
+
+// S3 stores every value as an object of Bucket, under Prefix. S3 doesn't expire objects on its own, so
+// values past their TTL are reported missing, and a lifecycle rule of the bucket should delete objects
+// older than the longest TTL. Region, Endpoint and the credentials fall back to the AWS environment
+// when left empty.
+type S3 struct {
+	Bucket   string `mapstructure:"bucket"`
+	Prefix   string `mapstructure:"prefix"`
+	Region   string `mapstructure:"region"`
+	Endpoint string `mapstructure:"endpoint"`
+	// ForcePathStyle addresses the bucket in the path rather than in the host name, which is what
+	// most S3 compatible stores expect
+	ForcePathStyle bool `mapstructure:"force_path_style"`
+}
+
+func (cfg *S3) validateAndLog() error {
+	if cfg.Bucket == "" {
+		return fmt.Errorf("Cannot store values in S3 without a config.backend.s3.bucket")
+	}
+	log.Infof("config.backend.s3.bucket: %s", cfg.Bucket)
+	log.Infof("config.backend.s3.prefix: %s", cfg.Prefix)
+	log.Infof("config.backend.s3.region: %s", cfg.Region)
+	log.Infof("config.backend.s3.endpoint: %s", cfg.Endpoint)
+	log.Infof("config.backend.s3.force_path_style: %t", cfg.ForcePathStyle)
+	return nil
+}
@@ -55,6 +55,48 @@ func TestAerospikeValidateAndLog(t *testing.T) {
 			hasError:      true,
 			expectedError: fmt.Errorf("Cannot connect to Aerospike host at port 0"),
 		},
+		{
+			desc: "aerospike policies and TLS passed in",
+			inCfg: Aerospike{
+				Hosts:               []string{"foo.com"},
+				Port:                4333,
+				CommitLevel:         AerospikeCommitMaster,
+				SocketTimeoutMillis: 100,
+				TotalTimeoutMillis:  250,
+				TLS:                 AerospikeTLS{Enabled: true, Name: "foo-cluster", CAFile: "ca.pem"},
+			},
+			hasError: false,
+		},
+		{
+			desc: "aerospike.commit_level unknown",
+			inCfg: Aerospike{
+				Hosts:       []string{"foo.com"},
+				Port:        3000,
+				CommitLevel: "quorum",
+			},
+			hasError:      true,
+			expectedError: fmt.Errorf(`invalid config.backend.aerospike.commit_level: quorum. It must be "all" or "master".`),
+		},
+		{
+			desc: "aerospike.total_timeout_ms negative",
+			inCfg: Aerospike{
+				Hosts:              []string{"foo.com"},
+				Port:               3000,
+				TotalTimeoutMillis: -1,
+			},
+			hasError:      true,
+			expectedError: fmt.Errorf("invalid config.backend.aerospike.total_timeout_ms: -1. It must not be negative."),
+		},
+		{
+			desc: "aerospike.tls.name missing",
+			inCfg: Aerospike{
+				Hosts: []string{"foo.com"},
+				Port:  4333,
+				TLS:   AerospikeTLS{Enabled: true},
+			},
+			hasError:      true,
+			expectedError: fmt.Errorf("config.backend.aerospike.tls.name is required to connect over TLS"),
+		},
 	}
 
 	for _, test := range testCases {
@@ -67,3 +109,735 @@ func TestAerospikeValidateAndLog(t *testing.T) {
 		}
 	}
 }
+
+func TestCouchbaseValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Couchbase
+		expectedError error
+	}{
+		{
+			desc: "couchbase.connection_string and couchbase.bucket passed in",
+			inCfg: Couchbase{
+				ConnectionString: "couchbase://127.0.0.1",
+				Bucket:           "prebid",
+			},
+		},
+		{
+			desc: "couchbase.connection_string missing",
+			inCfg: Couchbase{
+				Bucket: "prebid",
+			},
+			expectedError: fmt.Errorf("Cannot connect to Couchbase with an empty config.backend.couchbase.connection_string"),
+		},
+		{
+			desc: "couchbase.bucket missing",
+			inCfg: Couchbase{
+				ConnectionString: "couchbase://127.0.0.1",
+			},
+			expectedError: fmt.Errorf("Cannot store values in Couchbase without a config.backend.couchbase.bucket"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestCassandraValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Cassandra
+		expectedError error
+	}{
+		{
+			desc:  "Plaintext",
+			inCfg: Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid"},
+		},
+		{
+			desc: "Server certificate verified against a certificate authority",
+			inCfg: Cassandra{
+				Hosts:    "127.0.0.1",
+				Keyspace: "prebid",
+				TLS:      CassandraTLS{Enabled: true, CAFile: "ca.crt"},
+			},
+		},
+		{
+			desc: "Client certificate passed in",
+			inCfg: Cassandra{
+				Hosts:    "127.0.0.1",
+				Keyspace: "prebid",
+				TLS:      CassandraTLS{Enabled: true, CAFile: "ca.crt", CertFile: "client.crt", KeyFile: "client.key"},
+			},
+		},
+		{
+			desc:  "Credentials passed in",
+			inCfg: Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid", Username: "user", Password: "secret"},
+		},
+		{
+			desc:          "Username without its password",
+			inCfg:         Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid", Username: "user"},
+			expectedError: fmt.Errorf("config.backend.cassandra.username and config.backend.cassandra.password must be set together"),
+		},
+		{
+			desc:          "Negative connect timeout",
+			inCfg:         Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid", ConnectTimeoutMillis: -1},
+			expectedError: fmt.Errorf("invalid config.backend.cassandra.connect_timeout_ms: -1. It must not be negative."),
+		},
+		{
+			desc:          "Negative query timeout",
+			inCfg:         Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid", QueryTimeoutMillis: -1},
+			expectedError: fmt.Errorf("invalid config.backend.cassandra.query_timeout_ms: -1. It must not be negative."),
+		},
+		{
+			desc: "Client key without its certificate",
+			inCfg: Cassandra{
+				Hosts:    "127.0.0.1",
+				Keyspace: "prebid",
+				TLS:      CassandraTLS{Enabled: true, KeyFile: "client.key"},
+			},
+			expectedError: fmt.Errorf("config.backend.cassandra.tls.cert_file and config.backend.cassandra.tls.key_file must be set together"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestEtcdValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Etcd
+		expectedError error
+	}{
+		{
+			desc:  "etcd.endpoints passed in",
+			inCfg: Etcd{Endpoints: []string{"127.0.0.1:2379"}},
+		},
+		{
+			desc: "etcd client certificate passed in",
+			inCfg: Etcd{
+				Endpoints: []string{"127.0.0.1:2379"},
+				TLS:       EtcdTLS{Enabled: true, CertFile: "client.crt", KeyFile: "client.key"},
+			},
+		},
+		{
+			desc:          "etcd.endpoints missing",
+			inCfg:         Etcd{},
+			expectedError: fmt.Errorf("Cannot connect to etcd without any config.backend.etcd.endpoints"),
+		},
+		{
+			desc:          "Negative etcd.dial_timeout_ms",
+			inCfg:         Etcd{Endpoints: []string{"127.0.0.1:2379"}, DialTimeoutMillis: -1},
+			expectedError: fmt.Errorf("invalid config.backend.etcd.dial_timeout_ms: -1. It must not be negative."),
+		},
+		{
+			desc: "etcd client certificate without its key",
+			inCfg: Etcd{
+				Endpoints: []string{"127.0.0.1:2379"},
+				TLS:       EtcdTLS{Enabled: true, CertFile: "client.crt"},
+			},
+			expectedError: fmt.Errorf("config.backend.etcd.tls.cert_file and config.backend.etcd.tls.key_file must be set together"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestNATSValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         NATS
+		expectedError error
+	}{
+		{
+			desc:  "nats.url and nats.bucket passed in",
+			inCfg: NATS{URL: "nats://127.0.0.1:4222", Bucket: "prebid", TTLSeconds: 3600},
+		},
+		{
+			desc:          "nats.url missing",
+			inCfg:         NATS{Bucket: "prebid"},
+			expectedError: fmt.Errorf("Cannot connect to NATS with an empty config.backend.nats.url"),
+		},
+		{
+			desc:          "nats.bucket missing",
+			inCfg:         NATS{URL: "nats://127.0.0.1:4222"},
+			expectedError: fmt.Errorf("Cannot store values in NATS without a config.backend.nats.bucket"),
+		},
+		{
+			desc:          "Negative nats.ttl_seconds",
+			inCfg:         NATS{URL: "nats://127.0.0.1:4222", Bucket: "prebid", TTLSeconds: -1},
+			expectedError: fmt.Errorf("invalid config.backend.nats.ttl_seconds: -1. It must not be negative."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestPostgresValidateAndLog(t *testing.T) {
+	const dsn = "postgres://prebid@127.0.0.1:5432/prebid"
+
+	testCases := []struct {
+		desc          string
+		inCfg         Postgres
+		expectedError error
+	}{
+		{
+			desc:  "postgres.dsn and postgres.table passed in",
+			inCfg: Postgres{DSN: dsn, Table: "prebid_cache", CleanupIntervalSeconds: 60},
+		},
+		{
+			desc:  "postgres.table qualified by its schema",
+			inCfg: Postgres{DSN: dsn, Table: "cache.values"},
+		},
+		{
+			desc:          "postgres.dsn missing",
+			inCfg:         Postgres{Table: "prebid_cache"},
+			expectedError: fmt.Errorf("Cannot connect to Postgres with an empty config.backend.postgres.dsn"),
+		},
+		{
+			desc:          "postgres.table missing",
+			inCfg:         Postgres{DSN: dsn},
+			expectedError: fmt.Errorf(`invalid config.backend.postgres.table: "". It must be a table name, optionally qualified by its schema.`),
+		},
+		{
+			desc:          "postgres.table that isn't an identifier",
+			inCfg:         Postgres{DSN: dsn, Table: "cache; DROP TABLE users"},
+			expectedError: fmt.Errorf(`invalid config.backend.postgres.table: "cache; DROP TABLE users". It must be a table name, optionally qualified by its schema.`),
+		},
+		{
+			desc:          "Negative postgres.cleanup_interval_seconds",
+			inCfg:         Postgres{DSN: dsn, Table: "prebid_cache", CleanupIntervalSeconds: -1},
+			expectedError: fmt.Errorf("invalid config.backend.postgres.cleanup_interval_seconds: -1. It must not be negative."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestRedisValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Redis
+		expectedError error
+	}{
+		{
+			desc:  "redis.host and redis.port passed in",
+			inCfg: Redis{Host: "127.0.0.1", Port: 6379},
+		},
+		{
+			desc:  "redis.sentinel_addrs and redis.master_name passed in",
+			inCfg: Redis{SentinelAddrs: []string{"127.0.0.1:26379"}, MasterName: "mymaster"},
+		},
+		{
+			desc:          "redis.sentinel_addrs without redis.master_name",
+			inCfg:         Redis{SentinelAddrs: []string{"127.0.0.1:26379"}},
+			expectedError: fmt.Errorf("Cannot look up the Redis master through Sentinel without a config.backend.redis.master_name"),
+		},
+		{
+			desc:          "redis.master_name without redis.sentinel_addrs",
+			inCfg:         Redis{Host: "127.0.0.1", Port: 6379, MasterName: "mymaster"},
+			expectedError: fmt.Errorf("Cannot look up the Redis master mymaster without config.backend.redis.sentinel_addrs"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestAzureBlobValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         AzureBlob
+		expectedError error
+	}{
+		{
+			desc:  "Account key",
+			inCfg: AzureBlob{Account: "prebidcache", Container: "values", AccountKey: "key"},
+		},
+		{
+			desc:  "Managed identity",
+			inCfg: AzureBlob{Account: "prebidcache", Container: "values", ManagedIdentityClientID: "client-id"},
+		},
+		{
+			desc:          "azure_blob.account missing",
+			inCfg:         AzureBlob{Container: "values"},
+			expectedError: fmt.Errorf("Cannot store values in Azure Blob Storage without a config.backend.azure_blob.account"),
+		},
+		{
+			desc:          "azure_blob.container missing",
+			inCfg:         AzureBlob{Account: "prebidcache"},
+			expectedError: fmt.Errorf("Cannot store values in Azure Blob Storage without a config.backend.azure_blob.container"),
+		},
+		{
+			desc:          "Account key along with a managed identity",
+			inCfg:         AzureBlob{Account: "prebidcache", Container: "values", AccountKey: "key", ManagedIdentityClientID: "client-id"},
+			expectedError: fmt.Errorf("config.backend.azure_blob.account_key and config.backend.azure_blob.managed_identity_client_id can't be set together"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestS3ValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         S3
+		expectedError error
+	}{
+		{
+			desc:  "s3.bucket passed in",
+			inCfg: S3{Bucket: "prebid-cache", Prefix: "values/", Region: "us-east-1"},
+		},
+		{
+			desc:          "s3.bucket missing",
+			inCfg:         S3{Prefix: "values/", Region: "us-east-1"},
+			expectedError: fmt.Errorf("Cannot store values in S3 without a config.backend.s3.bucket"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestMemoryValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Memory
+		expectedError error
+	}{
+		{
+			desc:  "Unbounded",
+			inCfg: Memory{Shards: 16},
+		},
+		{
+			desc:  "Entry and byte budgets",
+			inCfg: Memory{Shards: 16, MaxEntries: 1000, MaxBytes: 1048576},
+		},
+		{
+			desc:          "No shards",
+			inCfg:         Memory{Shards: 0},
+			expectedError: fmt.Errorf("invalid config.backend.memory.shards: 0. It must be at least 1."),
+		},
+		{
+			desc:          "Negative byte budget",
+			inCfg:         Memory{Shards: 16, MaxBytes: -1},
+			expectedError: fmt.Errorf("invalid config.backend.memory.max_bytes: -1. It must not be negative."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBackendValueVersionValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inVersion     int
+		expectedError error
+	}{
+		{
+			desc:      "Legacy raw values",
+			inVersion: 0,
+		},
+		{
+			desc:      "Version 1 envelopes",
+			inVersion: 1,
+		},
+		{
+			desc:          "Unknown version",
+			inVersion:     2,
+			expectedError: fmt.Errorf("invalid config.backend.value_version: 2. It must be 0 or 1."),
+		},
+		{
+			desc:          "Negative version",
+			inVersion:     -1,
+			expectedError: fmt.Errorf("invalid config.backend.value_version: -1. It must be 0 or 1."),
+		},
+	}
+
+	for _, test := range testCases {
+		cfg := Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, ValueVersion: test.inVersion}
+		assert.Equal(t, test.expectedError, cfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBackendKeyPrefixValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inPrefix      string
+		expectedError error
+	}{
+		{
+			desc: "No prefix",
+		},
+		{
+			desc:     "Prefix",
+			inPrefix: "staging:",
+		},
+		{
+			desc:          "Prefix with whitespace",
+			inPrefix:      "staging ",
+			expectedError: fmt.Errorf("invalid config.backend.key_prefix: \"staging \". It must not contain whitespace."),
+		},
+	}
+
+	for _, test := range testCases {
+		cfg := Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, KeyPrefix: test.inPrefix}
+		assert.Equal(t, test.expectedError, cfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBackendKeyFormatValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inFormat      KeyFormat
+		expectedError error
+	}{
+		{
+			desc:     "Random keys",
+			inFormat: KeyFormatUUIDv4,
+		},
+		{
+			desc:     "Time-ordered keys",
+			inFormat: KeyFormatUUIDv7,
+		},
+		{
+			desc:          "Unknown format",
+			inFormat:      "ulid",
+			expectedError: fmt.Errorf(`invalid config.backend.key_format: ulid. It must be "uuidv4" or "uuidv7".`),
+		},
+	}
+
+	for _, test := range testCases {
+		cfg := Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, KeyFormat: test.inFormat}
+		assert.Equal(t, test.expectedError, cfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBackendMaxConcurrencyValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inMax         int
+		expectedError error
+	}{
+		{
+			desc: "Unbounded",
+		},
+		{
+			desc:  "Bounded",
+			inMax: 64,
+		},
+		{
+			desc:          "Negative",
+			inMax:         -1,
+			expectedError: fmt.Errorf("invalid config.backend.max_concurrency: -1. It must not be negative."),
+		},
+	}
+
+	for _, test := range testCases {
+		cfg := Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, MaxConcurrency: test.inMax}
+		assert.Equal(t, test.expectedError, cfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBackendVerifyWritesValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Backend
+		expectedError error
+	}{
+		{
+			desc:  "Verified writes",
+			inCfg: Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, VerifyWrites: true},
+		},
+		{
+			desc:  "Writes behind without verification",
+			inCfg: Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, WriteBehind: WriteBehind{Enabled: true, BufferSize: 10, Workers: 1}},
+		},
+		{
+			desc:          "Verified writes behind",
+			inCfg:         Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, VerifyWrites: true, WriteBehind: WriteBehind{Enabled: true, BufferSize: 10, Workers: 1}},
+			expectedError: fmt.Errorf("config.backend.verify_writes can't be enabled along with config.backend.write_behind, whose values are stored after the PUT request got its response"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBackendCollisionGuardValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Backend
+		expectedError error
+	}{
+		{
+			desc:  "Guarded memory backend",
+			inCfg: Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, CollisionGuard: CollisionGuard{Enabled: true, MaxRetries: 3}},
+		},
+		{
+			desc:  "Guarded memory backend that never regenerates keys",
+			inCfg: Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, CollisionGuard: CollisionGuard{Enabled: true}},
+		},
+		{
+			desc:  "Disabled guard with negative retries",
+			inCfg: Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, CollisionGuard: CollisionGuard{MaxRetries: -1}},
+		},
+		{
+			desc:          "Guarded backend that can't store values conditionally",
+			inCfg:         Backend{Type: BackendCassandra, CollisionGuard: CollisionGuard{Enabled: true, MaxRetries: 3}},
+			expectedError: fmt.Errorf("config.backend.collision_guard can't be enabled with config.backend.type cassandra. Only the memory and redis backends store values conditionally."),
+		},
+		{
+			desc:          "Guarded writes behind",
+			inCfg:         Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, CollisionGuard: CollisionGuard{Enabled: true, MaxRetries: 3}, WriteBehind: WriteBehind{Enabled: true, BufferSize: 10, Workers: 1}},
+			expectedError: fmt.Errorf("config.backend.collision_guard can't be enabled along with config.backend.write_behind, whose values are stored after the PUT request got its response"),
+		},
+		{
+			desc:          "Negative retries",
+			inCfg:         Backend{Type: BackendMemory, Memory: Memory{Shards: 1}, CollisionGuard: CollisionGuard{Enabled: true, MaxRetries: -1}},
+			expectedError: fmt.Errorf("invalid config.backend.collision_guard.max_retries: -1. It must not be negative."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestScrubberValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Scrubber
+		expectedError error
+	}{
+		{
+			desc:  "Disabled scrubber isn't validated",
+			inCfg: Scrubber{Enabled: false, KeysPerSecond: -1},
+		},
+		{
+			desc:  "Valid scrubber",
+			inCfg: Scrubber{Enabled: true, KeysPerSecond: 10, SampleRate: 0.5},
+		},
+		{
+			desc:          "Zero keys per second",
+			inCfg:         Scrubber{Enabled: true, KeysPerSecond: 0, SampleRate: 1},
+			expectedError: fmt.Errorf("invalid config.backend.scrubber.keys_per_second: 0. It must be greater than zero."),
+		},
+		{
+			desc:          "Sample rate out of range",
+			inCfg:         Scrubber{Enabled: true, KeysPerSecond: 10, SampleRate: 1.5},
+			expectedError: fmt.Errorf("invalid config.backend.scrubber.sample_rate: 1.5. It must be greater than 0 and no more than 1."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestBatchingValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Batching
+		expectedError error
+	}{
+		{
+			desc:  "Disabled batching isn't validated",
+			inCfg: Batching{WindowMillis: -1},
+		},
+		{
+			desc:  "Valid batching",
+			inCfg: Batching{Enabled: true, WindowMillis: 2, MaxSize: 100},
+		},
+		{
+			desc:          "Zero window",
+			inCfg:         Batching{Enabled: true, WindowMillis: 0, MaxSize: 100},
+			expectedError: fmt.Errorf("invalid config.backend.batching.window_ms: 0. It must be greater than zero."),
+		},
+		{
+			desc:          "Negative max size",
+			inCfg:         Batching{Enabled: true, WindowMillis: 2, MaxSize: -1},
+			expectedError: fmt.Errorf("invalid config.backend.batching.max_size: -1. It must be greater than zero."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestRetryValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         Retry
+		expectedError error
+	}{
+		{
+			desc:  "Retries disabled",
+			inCfg: Retry{MaxAttempts: 1, BackoffMillis: -1},
+		},
+		{
+			desc:  "Max attempts override with a ceiling",
+			inCfg: Retry{MaxAttempts: 1, OverrideHeader: "X-Max-Attempts", MaxAttemptsCeiling: 5, OverrideClientHeader: "X-Api-Key", OverrideClients: []string{"priority-client"}},
+		},
+		{
+			desc:          "Negative backoff",
+			inCfg:         Retry{MaxAttempts: 3, BackoffMillis: -1},
+			expectedError: fmt.Errorf("invalid config.backend.retry.backoff_ms: -1. It must not be negative."),
+		},
+		{
+			desc:          "Max backoff under the backoff",
+			inCfg:         Retry{MaxAttempts: 3, BackoffMillis: 10, MaxBackoffMillis: 5},
+			expectedError: fmt.Errorf("invalid config.backend.retry.max_backoff_ms: 5. It must be 0, for no cap, or at least config.backend.retry.backoff_ms."),
+		},
+		{
+			desc:          "Jitter over 1",
+			inCfg:         Retry{MaxAttempts: 3, BackoffMillis: 10, Jitter: 1.5},
+			expectedError: fmt.Errorf("invalid config.backend.retry.jitter: 1.5. It must be between 0 and 1."),
+		},
+		{
+			desc:          "Max attempts override without a ceiling",
+			inCfg:         Retry{MaxAttempts: 3, OverrideHeader: "X-Max-Attempts"},
+			expectedError: fmt.Errorf("invalid config.backend.retry.max_attempts_ceiling: 0. It must be at least 1 when config.backend.retry.override_header is set."),
+		},
+		{
+			desc:          "Max attempts override open to every client",
+			inCfg:         Retry{MaxAttempts: 3, OverrideHeader: "X-Max-Attempts", MaxAttemptsCeiling: 5, OverrideClientHeader: "X-Api-Key"},
+			expectedError: fmt.Errorf("config.backend.retry.override_client_header and config.backend.retry.override_clients must be set when config.backend.retry.override_header is, so that only those clients can override the max attempts"),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestCircuitBreakerValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         CircuitBreaker
+		expectedError error
+	}{
+		{
+			desc:  "Circuit breaker disabled",
+			inCfg: CircuitBreaker{FailureThreshold: 0, CooldownMillis: -1},
+		},
+		{
+			desc:  "Valid circuit breaker",
+			inCfg: CircuitBreaker{Enabled: true, FailureThreshold: 5, CooldownMillis: 10000},
+		},
+		{
+			desc:          "No failure threshold",
+			inCfg:         CircuitBreaker{Enabled: true, CooldownMillis: 10000},
+			expectedError: fmt.Errorf("invalid config.backend.circuit_breaker.failure_threshold: 0. It must be at least 1."),
+		},
+		{
+			desc:          "No cooldown",
+			inCfg:         CircuitBreaker{Enabled: true, FailureThreshold: 5},
+			expectedError: fmt.Errorf("invalid config.backend.circuit_breaker.cooldown_ms: 0. It must be greater than zero."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestNearCacheValidateAndLog(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inCfg         NearCache
+		expectedError error
+	}{
+		{
+			desc:  "Near cache disabled",
+			inCfg: NearCache{MaxEntries: 0, TTLSeconds: -1},
+		},
+		{
+			desc:  "Valid near cache",
+			inCfg: NearCache{Enabled: true, MaxEntries: 100, TTLSeconds: 5, PrimeOnPut: true},
+		},
+		{
+			desc:          "Unbounded near cache",
+			inCfg:         NearCache{Enabled: true, TTLSeconds: 5},
+			expectedError: fmt.Errorf("invalid config.backend.near_cache.max_entries: 0. It must be at least 1."),
+		},
+		{
+			desc:          "Negative max bytes",
+			inCfg:         NearCache{Enabled: true, MaxEntries: 100, MaxBytes: -1, TTLSeconds: 5},
+			expectedError: fmt.Errorf("invalid config.backend.near_cache.max_bytes: -1. It must not be negative."),
+		},
+		{
+			desc:          "No TTL",
+			inCfg:         NearCache{Enabled: true, MaxEntries: 100},
+			expectedError: fmt.Errorf("invalid config.backend.near_cache.ttl_seconds: 0. It must be at least 1."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
+
+func TestStandbyValidateAndLog(t *testing.T) {
+	memory := Backend{Type: BackendMemory, Memory: Memory{Shards: 1}}
+
+	testCases := []struct {
+		desc          string
+		inCfg         Standby
+		expectedError error
+	}{
+		{
+			desc:  "Standby disabled",
+			inCfg: Standby{QueueSize: -1},
+		},
+		{
+			desc:  "Valid standby",
+			inCfg: Standby{Enabled: true, Backend: memory, QueueSize: 10, Workers: 1, MaxAttempts: 1},
+		},
+		{
+			desc:          "Empty queue",
+			inCfg:         Standby{Enabled: true, Backend: memory, Workers: 1, MaxAttempts: 1},
+			expectedError: fmt.Errorf("invalid config.standby.queue_size: 0. It must be greater than zero."),
+		},
+		{
+			desc:          "No workers",
+			inCfg:         Standby{Enabled: true, Backend: memory, QueueSize: 10, MaxAttempts: 1},
+			expectedError: fmt.Errorf("invalid config.standby.workers: 0. It must be greater than zero."),
+		},
+		{
+			desc:          "No attempts",
+			inCfg:         Standby{Enabled: true, Backend: memory, QueueSize: 10, Workers: 1},
+			expectedError: fmt.Errorf("invalid config.standby.max_attempts: 0. It must be at least 1."),
+		},
+		{
+			desc:          "Negative backoff",
+			inCfg:         Standby{Enabled: true, Backend: memory, QueueSize: 10, Workers: 1, MaxAttempts: 1, BackoffMillis: -1},
+			expectedError: fmt.Errorf("invalid config.standby.backoff_ms: -1. It must not be negative."),
+		},
+		{
+			desc:          "Invalid standby backend",
+			inCfg:         Standby{Enabled: true, Backend: Backend{Type: BackendMemory}, QueueSize: 10, Workers: 1, MaxAttempts: 1},
+			expectedError: fmt.Errorf("invalid config.standby.backend: invalid config.backend.memory.shards: 0. It must be at least 1."),
+		},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expectedError, test.inCfg.validateAndLog(), test.desc)
+	}
+}
@@ -1,6 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
@@ -43,7 +49,57 @@ func setConfigDefaults(v *viper.Viper) {
 	v.SetDefault("admin_port", 2525)
 	v.SetDefault("index_response", "This application stores short-term data for use in Prebid.")
 	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "text")
 	v.SetDefault("backend.type", "memory")
+	v.SetDefault("backend.value_version", 0)
+	v.SetDefault("backend.key_prefix", "")
+	v.SetDefault("backend.allow_key_management", false)
+	v.SetDefault("backend.key_format", "uuidv4")
+	v.SetDefault("backend.memory.max_entries", 0)
+	v.SetDefault("backend.memory.max_bytes", 0)
+	v.SetDefault("backend.memory.shards", 16)
+	v.SetDefault("backend.memory.eviction_metrics", false)
+	v.SetDefault("backend.memory.log_evictions", false)
+	v.SetDefault("backend.scrubber.enabled", false)
+	v.SetDefault("backend.scrubber.keys_per_second", 10)
+	v.SetDefault("backend.scrubber.sample_rate", 1.0)
+	v.SetDefault("backend.retry.max_attempts", 1)
+	v.SetDefault("backend.retry.backoff_ms", 10)
+	v.SetDefault("backend.retry.max_backoff_ms", 1000)
+	v.SetDefault("backend.retry.jitter", 0.2)
+	v.SetDefault("backend.retry.retry_puts", false)
+	v.SetDefault("backend.retry.override_header", "")
+	v.SetDefault("backend.retry.max_attempts_ceiling", 0)
+	v.SetDefault("backend.retry.override_client_header", "X-Api-Key")
+	v.SetDefault("backend.retry.override_clients", []string{})
+	v.SetDefault("backend.circuit_breaker.enabled", false)
+	v.SetDefault("backend.circuit_breaker.failure_threshold", 5)
+	v.SetDefault("backend.circuit_breaker.cooldown_ms", 10000)
+	v.SetDefault("backend.write_behind.enabled", false)
+	v.SetDefault("backend.write_behind.buffer_size", 1000)
+	v.SetDefault("backend.write_behind.workers", 4)
+	v.SetDefault("backend.write_behind.respond_accepted", true)
+	v.SetDefault("backend.batching.enabled", false)
+	v.SetDefault("backend.batching.window_ms", 2)
+	v.SetDefault("backend.batching.max_size", 100)
+	v.SetDefault("backend.verify_writes", false)
+	v.SetDefault("backend.coalesce_gets", false)
+	v.SetDefault("backend.max_concurrency", 0)
+	v.SetDefault("backend.near_cache.enabled", false)
+	v.SetDefault("backend.near_cache.max_entries", 10000)
+	v.SetDefault("backend.near_cache.max_bytes", 0)
+	v.SetDefault("backend.near_cache.ttl_seconds", 5)
+	v.SetDefault("backend.near_cache.prime_on_put", false)
+	v.SetDefault("backend.collision_guard.enabled", false)
+	v.SetDefault("backend.collision_guard.max_retries", 3)
+	v.SetDefault("backend.capability_check.enabled", false)
+	v.SetDefault("backend.capability_check.fail_on_unsupported", false)
+	v.SetDefault("standby.enabled", false)
+	v.SetDefault("standby.backend.type", "")
+	v.SetDefault("standby.queue_size", 10000)
+	v.SetDefault("standby.workers", 2)
+	v.SetDefault("standby.max_attempts", 3)
+	v.SetDefault("standby.backoff_ms", 100)
 	v.SetDefault("backend.aerospike.host", "")
 	v.SetDefault("backend.aerospike.hosts", []string{})
 	v.SetDefault("backend.aerospike.port", 0)
@@ -51,36 +107,174 @@ func setConfigDefaults(v *viper.Viper) {
 	v.SetDefault("backend.aerospike.user", "")
 	v.SetDefault("backend.aerospike.password", "")
 	v.SetDefault("backend.aerospike.default_ttl_seconds", 0)
+	v.SetDefault("backend.aerospike.commit_level", "all")
+	v.SetDefault("backend.aerospike.socket_timeout_ms", 0)
+	v.SetDefault("backend.aerospike.total_timeout_ms", 0)
+	v.SetDefault("backend.aerospike.tls.enabled", false)
+	v.SetDefault("backend.aerospike.tls.name", "")
+	v.SetDefault("backend.aerospike.tls.cert_file", "")
+	v.SetDefault("backend.aerospike.tls.key_file", "")
+	v.SetDefault("backend.aerospike.tls.ca_file", "")
 	v.SetDefault("backend.azure.account", "")
 	v.SetDefault("backend.azure.key", "")
+	v.SetDefault("backend.azure_blob.account", "")
+	v.SetDefault("backend.azure_blob.container", "")
+	v.SetDefault("backend.azure_blob.prefix", "")
+	v.SetDefault("backend.azure_blob.account_key", "")
+	v.SetDefault("backend.azure_blob.managed_identity_client_id", "")
+	v.SetDefault("backend.azure_blob.endpoint", "")
 	v.SetDefault("backend.cassandra.hosts", "")
 	v.SetDefault("backend.cassandra.keyspace", "")
+	v.SetDefault("backend.cassandra.username", "")
+	v.SetDefault("backend.cassandra.password", "")
+	v.SetDefault("backend.cassandra.connect_timeout_ms", 2000)
+	v.SetDefault("backend.cassandra.query_timeout_ms", 500)
+	v.SetDefault("backend.cassandra.tls.enabled", false)
+	v.SetDefault("backend.cassandra.tls.ca_file", "")
+	v.SetDefault("backend.cassandra.tls.cert_file", "")
+	v.SetDefault("backend.cassandra.tls.key_file", "")
+	v.SetDefault("backend.cassandra.tls.insecure_skip_verify", false)
+	v.SetDefault("backend.couchbase.connection_string", "")
+	v.SetDefault("backend.couchbase.bucket", "")
+	v.SetDefault("backend.couchbase.scope", "")
+	v.SetDefault("backend.couchbase.collection", "")
+	v.SetDefault("backend.couchbase.username", "")
+	v.SetDefault("backend.couchbase.password", "")
+	v.SetDefault("backend.etcd.endpoints", []string{})
+	v.SetDefault("backend.etcd.dial_timeout_ms", 5000)
+	v.SetDefault("backend.etcd.username", "")
+	v.SetDefault("backend.etcd.password", "")
+	v.SetDefault("backend.etcd.tls.enabled", false)
+	v.SetDefault("backend.etcd.tls.cert_file", "")
+	v.SetDefault("backend.etcd.tls.key_file", "")
+	v.SetDefault("backend.etcd.tls.ca_file", "")
 	v.SetDefault("backend.memcache.hosts", []string{})
+	v.SetDefault("backend.nats.url", "")
+	v.SetDefault("backend.nats.bucket", "")
+	v.SetDefault("backend.nats.ttl_seconds", 0)
+	v.SetDefault("backend.nats.credentials_file", "")
+	v.SetDefault("backend.nats.token", "")
+	v.SetDefault("backend.nats.username", "")
+	v.SetDefault("backend.nats.password", "")
+	v.SetDefault("backend.postgres.dsn", "")
+	v.SetDefault("backend.postgres.table", "")
+	v.SetDefault("backend.postgres.cleanup_interval_seconds", 60)
 	v.SetDefault("backend.redis.host", "")
 	v.SetDefault("backend.redis.port", 0)
+	v.SetDefault("backend.redis.sentinel_addrs", []string{})
+	v.SetDefault("backend.redis.master_name", "")
 	v.SetDefault("backend.redis.password", "")
 	v.SetDefault("backend.redis.db", 0)
 	v.SetDefault("backend.redis.expiration", 0)
 	v.SetDefault("backend.redis.tls.enabled", false)
 	v.SetDefault("backend.redis.tls.insecure_skip_verify", false)
+	v.SetDefault("backend.s3.bucket", "")
+	v.SetDefault("backend.s3.prefix", "")
+	v.SetDefault("backend.s3.region", "")
+	v.SetDefault("backend.s3.endpoint", "")
+	v.SetDefault("backend.s3.force_path_style", false)
 	v.SetDefault("compression.type", "snappy")
 	v.SetDefault("metrics.influx.host", "")
 	v.SetDefault("metrics.influx.database", "")
 	v.SetDefault("metrics.influx.username", "")
 	v.SetDefault("metrics.influx.password", "")
 	v.SetDefault("metrics.influx.enabled", false)
+	v.SetDefault("metrics.influx.flush_interval_seconds", 10)
+	v.SetDefault("metrics.prometheus.host", "")
 	v.SetDefault("metrics.prometheus.port", 0)
 	v.SetDefault("metrics.prometheus.namespace", "")
 	v.SetDefault("metrics.prometheus.subsystem", "")
 	v.SetDefault("metrics.prometheus.timeout_ms", 0)
 	v.SetDefault("metrics.prometheus.enabled", false)
+	v.SetDefault("metrics.prometheus.get_duration_sample_rate", 1.0)
+	v.SetDefault("metrics.prometheus.put_duration_sample_rate", 1.0)
+	v.SetDefault("metrics.prometheus.allow_reset", false)
+	v.SetDefault("metrics.prometheus.label_allow_lists", map[string][]string{})
+	v.SetDefault("metrics.statsd.host", "")
+	v.SetDefault("metrics.statsd.port", 8125)
+	v.SetDefault("metrics.statsd.prefix", "prebid_cache")
+	v.SetDefault("metrics.statsd.sample_rate", 1.0)
+	v.SetDefault("metrics.statsd.enabled", false)
+	v.SetDefault("metrics.user_agents.enabled", false)
+	v.SetDefault("metrics.user_agents.prebid_server", []string{"prebid-server", "Go-http-client"})
+	v.SetDefault("metrics.user_agents.browser", []string{"Mozilla"})
 	v.SetDefault("rate_limiter.enabled", true)
 	v.SetDefault("rate_limiter.num_requests", 100)
+	v.SetDefault("rate_limiter.max_concurrent_gets", 0)
+	v.SetDefault("rate_limiter.max_concurrent_puts", 0)
+	v.SetDefault("rate_limiter.per_client_puts.enabled", false)
+	v.SetDefault("rate_limiter.per_client_puts.puts_per_second", 10)
+	v.SetDefault("rate_limiter.per_client_puts.burst", 20)
+	v.SetDefault("rate_limiter.per_client_puts.max_clients", 10000)
+	v.SetDefault("rate_limiter.per_client_puts.api_key_header", "X-Api-Key")
+	v.SetDefault("rate_limiter.per_client_puts.api_keys", []string{})
 	v.SetDefault("request_limits.allow_setting_keys", false)
 	v.SetDefault("request_limits.max_size_bytes", 10*1024)
 	v.SetDefault("request_limits.max_num_values", 10)
+	v.SetDefault("request_limits.max_request_size_bytes", 0)
 	v.SetDefault("request_limits.max_ttl_seconds", 3600)
+	v.SetDefault("request_limits.min_ttl_seconds", 0)
+	v.SetDefault("request_limits.default_ttl_seconds", 0)
+	v.SetDefault("request_limits.validate_xml", false)
+	v.SetDefault("request_limits.validate_json", false)
 	v.SetDefault("routes.allow_public_write", true)
+	v.SetDefault("put_quotas.enabled", false)
+	v.SetDefault("put_quotas.header", "X-Api-Key")
+	v.SetDefault("put_quotas.window_seconds", 3600)
+	v.SetDefault("auth.enabled", false)
+	v.SetDefault("auth.allowed_cidrs", []string{})
+	v.SetDefault("auth.trusted_proxy_depth", 0)
+	v.SetDefault("cors.enabled", false)
+	v.SetDefault("cors.allowed_origins", []string{})
+	v.SetDefault("cors.allowed_methods", []string{"GET", "HEAD"})
+	v.SetDefault("cors.allowed_headers", []string{})
+	v.SetDefault("cors.max_age_seconds", 600)
+	v.SetDefault("routes.get_metadata_headers", false)
+	v.SetDefault("routes.get_staleness_headers", false)
+	v.SetDefault("routes.get_path_keys", false)
+	v.SetDefault("routes.get_max_header_bytes", 0)
+	v.SetDefault("routes.get_honor_accept", false)
+	v.SetDefault("routes.get_batch.enabled", false)
+	v.SetDefault("routes.get_batch.max_keys", 10)
+	v.SetDefault("routes.get_batch.workers", 4)
+	v.SetDefault("routes.get_batch.max_response_bytes", 0)
+	v.SetDefault("routes.health.liveness_path", "/healthz")
+	v.SetDefault("routes.health.readiness_path", "/readyz")
+	v.SetDefault("routes.error_codes", false)
+	v.SetDefault("correlation.enabled", false)
+	v.SetDefault("correlation.client_id_header", "X-Client-Id")
+	v.SetDefault("request_id.enabled", true)
+	v.SetDefault("request_id.header", "X-Request-ID")
+	v.SetDefault("client_deadlines.enabled", false)
+	v.SetDefault("client_deadlines.header", "X-Deadline-Ms")
+	v.SetDefault("client_deadlines.max_ms", 500)
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.sample_rate", 0.01)
+	v.SetDefault("tracing.otlp_endpoint", "")
+	v.SetDefault("server.read_timeout_ms", 15000)
+	v.SetDefault("server.write_timeout_ms", 15000)
+	v.SetDefault("server.idle_timeout_ms", 60000)
+	v.SetDefault("tls.enabled", false)
+	v.SetDefault("tls.cert_file", "")
+	v.SetDefault("tls.key_file", "")
+	v.SetDefault("encryption.enabled", false)
+	v.SetDefault("encryption.key", "")
+	v.SetDefault("encryption.key_file", "")
+	v.SetDefault("file_descriptors.enabled", false)
+	v.SetDefault("file_descriptors.warn_fraction", 0.8)
+	v.SetDefault("file_descriptors.check_interval_seconds", 30)
+	v.SetDefault("priming.serve_keys", false)
+	v.SetDefault("priming.enabled", false)
+	v.SetDefault("priming.peer_url", "")
+	v.SetDefault("priming.batch_size", 10)
+	v.SetDefault("priming.workers", 4)
+	v.SetDefault("priming.timeout_ms", 60000)
+	v.SetDefault("response_compression.enabled", false)
+	v.SetDefault("response_compression.allow_paths", []string{})
+	v.SetDefault("response_compression.deny_paths", []string{})
+	v.SetDefault("response_compression.min_size_bytes", 0)
+	v.SetDefault("request_decompression.enabled", false)
+	v.SetDefault("request_decompression.max_size_bytes", 1024*1024)
 }
 
 func setConfigFilePath(v *viper.Viper, filename string) {
@@ -104,9 +298,25 @@ type Configuration struct {
 	RateLimiting  RateLimiting  `mapstructure:"rate_limiter"`
 	RequestLimits RequestLimits `mapstructure:"request_limits"`
 	Backend       Backend       `mapstructure:"backend"`
+	Standby       Standby       `mapstructure:"standby"`
 	Compression   Compression   `mapstructure:"compression"`
 	Metrics       Metrics       `mapstructure:"metrics"`
 	Routes        Routes        `mapstructure:"routes"`
+
+	ResponseCompression  ResponseCompression  `mapstructure:"response_compression"`
+	RequestDecompression RequestDecompression `mapstructure:"request_decompression"`
+	PutQuotas            PutQuotas            `mapstructure:"put_quotas"`
+	Auth                 Auth                 `mapstructure:"auth"`
+	CORS                 CORS                 `mapstructure:"cors"`
+	Correlation          Correlation          `mapstructure:"correlation"`
+	RequestID            RequestID            `mapstructure:"request_id"`
+	ClientDeadlines      ClientDeadlines      `mapstructure:"client_deadlines"`
+	Tracing              Tracing              `mapstructure:"tracing"`
+	Server               Server               `mapstructure:"server"`
+	TLS                  TLS                  `mapstructure:"tls"`
+	Encryption           Encryption           `mapstructure:"encryption"`
+	FileDescriptors      FileDescriptors      `mapstructure:"file_descriptors"`
+	Priming              Priming              `mapstructure:"priming"`
 }
 
 // ValidateAndLog validates the config, terminating the program on any errors.
@@ -122,20 +332,89 @@ func (cfg *Configuration) ValidateAndLog() {
 	if err := cfg.Backend.validateAndLog(); err != nil {
 		log.Fatalf("%s", err.Error())
 	}
+	if cfg.RequestLimits.AllowSettingKeys && !cfg.Backend.AllowKeyManagement {
+		log.Info("config.request_limits.allow_setting_keys is being deprecated in favor of config.backend.allow_key_management")
+		cfg.Backend.AllowKeyManagement = true
+	}
+	if err := cfg.Standby.validateAndLog(); err != nil {
+		log.Fatalf("%s", err.Error())
+	}
 
 	cfg.Compression.validateAndLog()
 	cfg.Metrics.validateAndLog()
 	cfg.Routes.validateAndLog()
+	cfg.ResponseCompression.validateAndLog()
+	cfg.RequestDecompression.validateAndLog()
+	cfg.PutQuotas.validateAndLog()
+	cfg.Auth.validateAndLog()
+	cfg.CORS.validateAndLog()
+	cfg.Correlation.validateAndLog(cfg.Backend.ValueVersion)
+	cfg.RequestID.validateAndLog()
+	cfg.ClientDeadlines.validateAndLog()
+	cfg.Tracing.validateAndLog()
+	cfg.Server.validateAndLog()
+	cfg.TLS.validateAndLog()
+	cfg.Encryption.validateAndLog()
+	cfg.FileDescriptors.validateAndLog()
+	cfg.Priming.validateAndLog()
+}
+
+// Validate checks the fields the selected backends and metrics engines can't run without, before anything
+// gets logged or started. Every problem found is listed in the returned error, one per line, so that a
+// broken config can be fixed in a single pass rather than one restart at a time.
+func (cfg *Configuration) Validate() error {
+	var problems []string
+	if cfg.Port <= 0 {
+		problems = append(problems, fmt.Sprintf("config.port must be greater than zero. Got %d", cfg.Port))
+	}
+	if cfg.AdminPort <= 0 {
+		problems = append(problems, fmt.Sprintf("config.admin_port must be greater than zero. Got %d", cfg.AdminPort))
+	}
+	problems = append(problems, cfg.Backend.problems("config.backend")...)
+	if cfg.Standby.Enabled {
+		problems = append(problems, cfg.Standby.Backend.problems("config.standby.backend")...)
+	}
+	problems = append(problems, cfg.Metrics.problems(cfg.Port, cfg.AdminPort)...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
 }
 
 type Log struct {
-	Level LogLevel `mapstructure:"level"`
+	Level  LogLevel  `mapstructure:"level"`
+	Format LogFormat `mapstructure:"format"`
 }
 
 func (cfg *Log) validateAndLog() {
 	log.Infof("config.log.level: %s", cfg.Level)
+	switch cfg.Format {
+	case TextFormat, JSONFormat:
+		log.Infof("config.log.format: %s", cfg.Format)
+	default:
+		log.Fatalf(`invalid config.log.format: %s. It must be "text" or "json".`, cfg.Format)
+	}
+}
+
+// Formatter returns the logrus formatter of the configured format. Unknown formats fall back to text,
+// so that the error validateAndLog reports about them is readable.
+func (cfg *Log) Formatter() log.Formatter {
+	if cfg.Format == JSONFormat {
+		return &log.JSONFormatter{}
+	}
+	return &log.TextFormatter{}
 }
 
+// LogFormat is how log entries are written: as human readable text, or as one JSON object per line for
+// log pipelines to ingest
+type LogFormat string
+
+const (
+	TextFormat LogFormat = "text"
+	JSONFormat LogFormat = "json"
+)
+
 type LogLevel string
 
 const (
@@ -151,25 +430,116 @@ const (
 type RateLimiting struct {
 	Enabled              bool  `mapstructure:"enabled"`
 	MaxRequestsPerSecond int64 `mapstructure:"num_requests"`
+	// MaxConcurrentGets and MaxConcurrentPuts cap the number of requests each endpoint handles at
+	// the same time. Zero means no limit.
+	MaxConcurrentGets int             `mapstructure:"max_concurrent_gets"`
+	MaxConcurrentPuts int             `mapstructure:"max_concurrent_puts"`
+	PerClientPuts     ClientRateLimit `mapstructure:"per_client_puts"`
 }
 
 func (cfg *RateLimiting) validateAndLog() {
 	log.Infof("config.rate_limiter.enabled: %t", cfg.Enabled)
 	log.Infof("config.rate_limiter.num_requests: %d", cfg.MaxRequestsPerSecond)
+	if cfg.MaxConcurrentGets > 0 {
+		log.Infof("config.rate_limiter.max_concurrent_gets: %d", cfg.MaxConcurrentGets)
+	}
+	if cfg.MaxConcurrentPuts > 0 {
+		log.Infof("config.rate_limiter.max_concurrent_puts: %d", cfg.MaxConcurrentPuts)
+	}
+	cfg.PerClientPuts.validateAndLog()
+}
+
+// ClientRateLimit gives every client a bucket of Burst tokens, refilled at PutsPerSecond, and takes a
+// token for each of its PUT requests. Clients are told apart by the value of their APIKeyHeader, or by
+// their IP when they don't send one, read as config.auth.trusted_proxy_depth says. Only the buckets of
+// the MaxClients most recent clients are kept: others start over with a full bucket.
+type ClientRateLimit struct {
+	Enabled       bool    `mapstructure:"enabled"`
+	PutsPerSecond float64 `mapstructure:"puts_per_second"`
+	Burst         int     `mapstructure:"burst"`
+	MaxClients    int     `mapstructure:"max_clients"`
+	APIKeyHeader  string  `mapstructure:"api_key_header"`
+	// APIKeys are the values of APIKeyHeader that get a bucket of their own. Requests sending any other
+	// value are throttled by IP, like those that send none. The keys with a put quota are added to them
+	// when put quotas read the same header.
+	APIKeys []string `mapstructure:"api_keys"`
+}
+
+func (cfg *ClientRateLimit) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.PutsPerSecond <= 0 {
+		log.Fatalf("invalid config.rate_limiter.per_client_puts.puts_per_second: %v. It must be greater than zero.", cfg.PutsPerSecond)
+	}
+	if cfg.Burst < 1 {
+		log.Fatalf("invalid config.rate_limiter.per_client_puts.burst: %d. It must be at least 1.", cfg.Burst)
+	}
+	if cfg.MaxClients <= 0 {
+		log.Fatalf("invalid config.rate_limiter.per_client_puts.max_clients: %d. It must be greater than zero.", cfg.MaxClients)
+	}
+	log.Infof("config.rate_limiter.per_client_puts.enabled: %t", cfg.Enabled)
+	log.Infof("config.rate_limiter.per_client_puts.puts_per_second: %v", cfg.PutsPerSecond)
+	log.Infof("config.rate_limiter.per_client_puts.burst: %d", cfg.Burst)
+	log.Infof("config.rate_limiter.per_client_puts.max_clients: %d", cfg.MaxClients)
+	log.Infof("config.rate_limiter.per_client_puts.api_key_header: %s", cfg.APIKeyHeader)
+	log.Infof("config.rate_limiter.per_client_puts.api_keys: %d keys", len(cfg.APIKeys))
 }
 
 type RequestLimits struct {
-	MaxSize          int  `mapstructure:"max_size_bytes"`
-	MaxNumValues     int  `mapstructure:"max_num_values"`
-	MaxTTLSeconds    int  `mapstructure:"max_ttl_seconds"`
-	AllowSettingKeys bool `mapstructure:"allow_setting_keys"`
+	MaxSize      int `mapstructure:"max_size_bytes"`
+	MaxNumValues int `mapstructure:"max_num_values"`
+	// MaxRequestSize caps the size of whole PUT request bodies. It's ignored when zero.
+	MaxRequestSize        int            `mapstructure:"max_request_size_bytes"`
+	MaxTTLSeconds         int            `mapstructure:"max_ttl_seconds"`
+	MaxTTLSecondsByFormat map[string]int `mapstructure:"max_ttl_seconds_by_format"`
+	TTLSizeRules          []TTLSizeRule  `mapstructure:"ttl_size_rules"`
+	AllowSettingKeys      bool           `mapstructure:"allow_setting_keys"`
+	// MinTTLSeconds, DefaultTTLSeconds and DefaultTTLSecondsByFormat are ignored when zero. See
+	// backends.ResolveTTL for how they combine with the TTL requested by the client.
+	MinTTLSeconds             int            `mapstructure:"min_ttl_seconds"`
+	DefaultTTLSeconds         int            `mapstructure:"default_ttl_seconds"`
+	DefaultTTLSecondsByFormat map[string]int `mapstructure:"default_ttl_seconds_by_format"`
+	// ValidateXML rejects XML values that aren't well-formed, rather than storing them for players to
+	// choke on. ValidateJSON does the same for JSON values.
+	ValidateXML  bool `mapstructure:"validate_xml"`
+	ValidateJSON bool `mapstructure:"validate_json"`
+}
+
+// TTLSizeRule caps the TTL of any value whose size is at least MinSizeBytes.
+type TTLSizeRule struct {
+	MinSizeBytes  int `mapstructure:"min_size_bytes"`
+	MaxTTLSeconds int `mapstructure:"max_ttl_seconds"`
 }
 
 func (cfg *RequestLimits) validateAndLog() {
 	log.Infof("config.request_limits.allow_setting_keys: %v", cfg.AllowSettingKeys)
 	log.Infof("config.request_limits.max_ttl_seconds: %d", cfg.MaxTTLSeconds)
+	for format, maxTTL := range cfg.MaxTTLSecondsByFormat {
+		log.Infof("config.request_limits.max_ttl_seconds_by_format.%s: %d", format, maxTTL)
+	}
+	for i, rule := range cfg.TTLSizeRules {
+		log.Infof("config.request_limits.ttl_size_rules[%d]: min_size_bytes=%d max_ttl_seconds=%d", i, rule.MinSizeBytes, rule.MaxTTLSeconds)
+	}
+	if cfg.MinTTLSeconds > 0 {
+		if cfg.MinTTLSeconds > cfg.MaxTTLSeconds {
+			log.Fatalf("invalid config.request_limits.min_ttl_seconds: %d. It must not exceed config.request_limits.max_ttl_seconds.", cfg.MinTTLSeconds)
+		}
+		log.Infof("config.request_limits.min_ttl_seconds: %d", cfg.MinTTLSeconds)
+	}
+	if cfg.DefaultTTLSeconds > 0 {
+		log.Infof("config.request_limits.default_ttl_seconds: %d", cfg.DefaultTTLSeconds)
+	}
+	for format, defaultTTL := range cfg.DefaultTTLSecondsByFormat {
+		log.Infof("config.request_limits.default_ttl_seconds_by_format.%s: %d", format, defaultTTL)
+	}
 	log.Infof("config.request_limits.max_size_bytes: %d", cfg.MaxSize)
 	log.Infof("config.request_limits.max_num_values: %d", cfg.MaxNumValues)
+	log.Infof("config.request_limits.validate_xml: %v", cfg.ValidateXML)
+	log.Infof("config.request_limits.validate_json: %v", cfg.ValidateJSON)
+	if cfg.MaxRequestSize > 0 {
+		log.Infof("config.request_limits.max_request_size_bytes: %d", cfg.MaxRequestSize)
+	}
 }
 
 type Compression struct {
@@ -178,26 +548,449 @@ type Compression struct {
 
 func (cfg *Compression) validateAndLog() {
 	switch cfg.Type {
-	case CompressionNone:
-		fallthrough
-	case CompressionSnappy:
+	case CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd:
 		log.Infof("config.compression.type: %s", cfg.Type)
 	default:
-		log.Fatalf(`invalid config.compression.type: %s. It must be "none" or "snappy"`, cfg.Type)
+		log.Fatalf(`invalid config.compression.type: %s. It must be "none", "gzip", "snappy" or "zstd"`, cfg.Type)
 	}
 }
 
+// ResponseCompression gzips the HTTP responses of the endpoints whose path starts with any of AllowPaths,
+// or of every endpoint if AllowPaths is empty. DenyPaths take precedence over AllowPaths. Bodies shorter
+// than MinSizeBytes are left uncompressed.
+type ResponseCompression struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	AllowPaths   []string `mapstructure:"allow_paths"`
+	DenyPaths    []string `mapstructure:"deny_paths"`
+	MinSizeBytes int      `mapstructure:"min_size_bytes"`
+}
+
+func (cfg *ResponseCompression) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	log.Infof("config.response_compression.enabled: %t", cfg.Enabled)
+	log.Infof("config.response_compression.allow_paths: %v", cfg.AllowPaths)
+	log.Infof("config.response_compression.deny_paths: %v", cfg.DenyPaths)
+	if cfg.MinSizeBytes < 0 {
+		log.Fatalf("invalid config.response_compression.min_size_bytes: %d. It must not be negative.", cfg.MinSizeBytes)
+	}
+	log.Infof("config.response_compression.min_size_bytes: %d", cfg.MinSizeBytes)
+}
+
+// RequestDecompression decompresses the gzip bodies of PUT requests. Bodies that decompress to more than
+// MaxSizeBytes get a 413.
+type RequestDecompression struct {
+	Enabled      bool `mapstructure:"enabled"`
+	MaxSizeBytes int  `mapstructure:"max_size_bytes"`
+}
+
+func (cfg *RequestDecompression) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.MaxSizeBytes <= 0 {
+		log.Fatalf("invalid config.request_decompression.max_size_bytes: %d. It must be greater than zero.", cfg.MaxSizeBytes)
+	}
+	log.Infof("config.request_decompression.enabled: %t", cfg.Enabled)
+	log.Infof("config.request_decompression.max_size_bytes: %d", cfg.MaxSizeBytes)
+}
+
+// Correlation stores the client id found in ClientIDHeader along with every value a PUT request
+// writes, so that GET requests can log who wrote the value they read and how long before. It's off by
+// default because of the storage it takes, and because client ids may be sensitive.
+type Correlation struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	ClientIDHeader string `mapstructure:"client_id_header"`
+}
+
+// validateAndLog needs the value version new values are stored with, because version 0 values can't
+// carry the client id
+func (cfg *Correlation) validateAndLog(valueVersion int) {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.ClientIDHeader == "" {
+		log.Fatalf("invalid config.correlation.client_id_header: it must not be empty when correlation is enabled.")
+	}
+	if valueVersion < 1 {
+		log.Fatalf("invalid config.correlation.enabled: correlation needs config.backend.value_version to be 1 or higher.")
+	}
+	log.Infof("config.correlation.enabled: %t", cfg.Enabled)
+	log.Infof("config.correlation.client_id_header: %s", cfg.ClientIDHeader)
+}
+
+// RequestID has every request carry an id, taken from Header when the client sends a valid one and
+// generated otherwise. The id is sent back in the same header and logged along with the request, so a
+// request can be followed from service to service.
+type RequestID struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Header  string `mapstructure:"header"`
+}
+
+func (cfg *RequestID) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.Header == "" {
+		log.Fatalf("invalid config.request_id.header: it must not be empty when request ids are enabled.")
+	}
+	log.Infof("config.request_id.enabled: %t", cfg.Enabled)
+	log.Infof("config.request_id.header: %s", cfg.Header)
+}
+
+// ClientDeadlines lets GET and PUT requests tell, in milliseconds through Header, how long they're
+// willing to wait for their backend operations. Deadlines above MaxMillis are lowered to it. Requests
+// without a valid deadline get the server's own.
+type ClientDeadlines struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Header    string `mapstructure:"header"`
+	MaxMillis int    `mapstructure:"max_ms"`
+}
+
+func (cfg *ClientDeadlines) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.Header == "" {
+		log.Fatalf("invalid config.client_deadlines.header: it must not be empty when client deadlines are enabled.")
+	}
+	if cfg.MaxMillis < 1 {
+		log.Fatalf("invalid config.client_deadlines.max_ms: %d. It must be positive.", cfg.MaxMillis)
+	}
+	log.Infof("config.client_deadlines.enabled: %t", cfg.Enabled)
+	log.Infof("config.client_deadlines.header: %s", cfg.Header)
+	log.Infof("config.client_deadlines.max_ms: %d", cfg.MaxMillis)
+}
+
+// Tracing writes a span for a SampleRate fraction of the GET and PUT requests. Requests that carry a W3C
+// traceparent header are traced or not as the sampled flag of their caller says, whatever SampleRate is.
+// Spans get logged, unless OTLPEndpoint is set: then they're sent to that OpenTelemetry collector, along
+// with spans for the backend operations of every request.
+type Tracing struct {
+	Enabled    bool    `mapstructure:"enabled"`
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// OTLPEndpoint is the URL of the collector, such as http://localhost:4318. Spans are posted to the
+	// /v1/traces path of the collector if the URL doesn't have one.
+	OTLPEndpoint string `mapstructure:"otlp_endpoint"`
+}
+
+// ExportsSpans tells whether spans get sent to an OpenTelemetry collector
+func (cfg Tracing) ExportsSpans() bool {
+	return cfg.Enabled && cfg.OTLPEndpoint != ""
+}
+
+func (cfg *Tracing) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		log.Fatalf("invalid config.tracing.sample_rate: %v. It must be between 0 and 1.", cfg.SampleRate)
+	}
+	if cfg.OTLPEndpoint != "" {
+		endpoint, err := url.Parse(cfg.OTLPEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			log.Fatalf("invalid config.tracing.otlp_endpoint: %s. It must be an http or https URL.", cfg.OTLPEndpoint)
+		}
+	}
+	log.Infof("config.tracing.enabled: %t", cfg.Enabled)
+	log.Infof("config.tracing.sample_rate: %v", cfg.SampleRate)
+	log.Infof("config.tracing.otlp_endpoint: %s", cfg.OTLPEndpoint)
+}
+
+// Server bounds how long clients of the main port may take to send a request, to read its response
+// and to send the next request over the same connection, so that slow clients can't hold connections
+// open forever.
+type Server struct {
+	ReadTimeoutMillis  int `mapstructure:"read_timeout_ms"`
+	WriteTimeoutMillis int `mapstructure:"write_timeout_ms"`
+	IdleTimeoutMillis  int `mapstructure:"idle_timeout_ms"`
+}
+
+func (cfg *Server) validateAndLog() {
+	if cfg.ReadTimeoutMillis <= 0 {
+		log.Fatalf("invalid config.server.read_timeout_ms: %d. It must be greater than zero.", cfg.ReadTimeoutMillis)
+	}
+	if cfg.WriteTimeoutMillis <= 0 {
+		log.Fatalf("invalid config.server.write_timeout_ms: %d. It must be greater than zero.", cfg.WriteTimeoutMillis)
+	}
+	if cfg.IdleTimeoutMillis <= 0 {
+		log.Fatalf("invalid config.server.idle_timeout_ms: %d. It must be greater than zero.", cfg.IdleTimeoutMillis)
+	}
+	log.Infof("config.server.read_timeout_ms: %d", cfg.ReadTimeoutMillis)
+	log.Infof("config.server.write_timeout_ms: %d", cfg.WriteTimeoutMillis)
+	log.Infof("config.server.idle_timeout_ms: %d", cfg.IdleTimeoutMillis)
+}
+
+func (cfg *Server) ReadTimeout() time.Duration {
+	return time.Duration(cfg.ReadTimeoutMillis) * time.Millisecond
+}
+
+func (cfg *Server) WriteTimeout() time.Duration {
+	return time.Duration(cfg.WriteTimeoutMillis) * time.Millisecond
+}
+
+func (cfg *Server) IdleTimeout() time.Duration {
+	return time.Duration(cfg.IdleTimeoutMillis) * time.Millisecond
+}
+
+// TLS serves the main port over TLS, with the certificate and private key of CertFile and KeyFile.
+// The admin and Prometheus ports are left as they are.
+type TLS struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+}
+
+func (cfg *TLS) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		log.Fatalf("config.tls.cert_file and config.tls.key_file are required to serve over TLS")
+	}
+	log.Infof("config.tls.enabled: %t", cfg.Enabled)
+	log.Infof("config.tls.cert_file: %s", cfg.CertFile)
+	log.Infof("config.tls.key_file: %s", cfg.KeyFile)
+}
+
+// Encryption encrypts values with AES-GCM before storing them. The key is either Key or the contents of
+// KeyFile, base64 encoded, and must decode to 16, 24 or 32 bytes for AES-128, AES-192 or AES-256.
+type Encryption struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Key     string `mapstructure:"key"`
+	KeyFile string `mapstructure:"key_file"`
+}
+
+func (cfg *Encryption) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if (cfg.Key == "") == (cfg.KeyFile == "") {
+		log.Fatalf("Exactly one of config.encryption.key and config.encryption.key_file is required to encrypt values")
+	} else if _, err := cfg.LoadKey(); err != nil {
+		log.Fatalf("invalid config.encryption key: %v", err)
+	}
+	log.Infof("config.encryption.enabled: %t", cfg.Enabled)
+	if cfg.KeyFile != "" {
+		log.Infof("config.encryption.key_file: %s", cfg.KeyFile)
+	}
+}
+
+// LoadKey returns the decoded key, reading it from KeyFile if set
+func (cfg *Encryption) LoadKey() ([]byte, error) {
+	encoded := cfg.Key
+	if cfg.KeyFile != "" {
+		contents, err := ioutil.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(contents)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("the key must be base64 encoded: %v", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("the key is %d bytes long. It must be 16, 24 or 32 bytes long.", len(key))
+}
+
+// FileDescriptors checks how many file descriptors the process has open against its soft limit, on
+// startup and then every CheckIntervalSeconds. Checks that find more than WarnFraction of the limit
+// open log a warning, ahead of the accept errors running out of them causes.
+type FileDescriptors struct {
+	Enabled              bool    `mapstructure:"enabled"`
+	WarnFraction         float64 `mapstructure:"warn_fraction"`
+	CheckIntervalSeconds int     `mapstructure:"check_interval_seconds"`
+}
+
+func (cfg *FileDescriptors) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.WarnFraction <= 0 || cfg.WarnFraction > 1 {
+		log.Fatalf("invalid config.file_descriptors.warn_fraction: %v. It must be greater than 0 and no more than 1.", cfg.WarnFraction)
+	}
+	if cfg.CheckIntervalSeconds <= 0 {
+		log.Fatalf("invalid config.file_descriptors.check_interval_seconds: %d. It must be greater than zero.", cfg.CheckIntervalSeconds)
+	}
+	log.Infof("config.file_descriptors.enabled: %t", cfg.Enabled)
+	log.Infof("config.file_descriptors.warn_fraction: %v", cfg.WarnFraction)
+	log.Infof("config.file_descriptors.check_interval_seconds: %d", cfg.CheckIntervalSeconds)
+}
+
+// Priming copies the values of a peer instance into the backend on startup, before serving requests,
+// so that new instances don't start cold. Keys are listed through the admin server of PeerURL, which
+// must set ServeKeys, and their values are read BatchSize at a time through its GET /cache batches,
+// which must be enabled for at least as many keys. Workers batches are read at once, and priming stops
+// after TimeoutMillis whether or not every value was copied.
+type Priming struct {
+	ServeKeys     bool   `mapstructure:"serve_keys"`
+	Enabled       bool   `mapstructure:"enabled"`
+	PeerURL       string `mapstructure:"peer_url"`
+	BatchSize     int    `mapstructure:"batch_size"`
+	Workers       int    `mapstructure:"workers"`
+	TimeoutMillis int    `mapstructure:"timeout_ms"`
+}
+
+func (cfg *Priming) validateAndLog() {
+	if cfg.ServeKeys {
+		log.Infof("config.priming.serve_keys: %t", cfg.ServeKeys)
+	}
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.PeerURL == "" {
+		log.Fatalf("config.priming.peer_url is required to prime the backend")
+	}
+	if cfg.BatchSize <= 0 {
+		log.Fatalf("invalid config.priming.batch_size: %d. It must be greater than zero.", cfg.BatchSize)
+	}
+	if cfg.Workers <= 0 {
+		log.Fatalf("invalid config.priming.workers: %d. It must be greater than zero.", cfg.Workers)
+	}
+	if cfg.TimeoutMillis <= 0 {
+		log.Fatalf("invalid config.priming.timeout_ms: %d. It must be greater than zero.", cfg.TimeoutMillis)
+	}
+	log.Infof("config.priming.enabled: %t", cfg.Enabled)
+	log.Infof("config.priming.peer_url: %s", cfg.PeerURL)
+	log.Infof("config.priming.batch_size: %d", cfg.BatchSize)
+	log.Infof("config.priming.workers: %d", cfg.Workers)
+	log.Infof("config.priming.timeout_ms: %d", cfg.TimeoutMillis)
+}
+
+func (cfg *Priming) Timeout() time.Duration {
+	return time.Duration(cfg.TimeoutMillis) * time.Millisecond
+}
+
+// PutQuotas limits how many values and bytes every API key can store per window of WindowSeconds. The
+// API key is read from Header. Requests without it, or with a key that has no quota, are never limited.
+type PutQuotas struct {
+	Enabled       bool       `mapstructure:"enabled"`
+	Header        string     `mapstructure:"header"`
+	WindowSeconds int        `mapstructure:"window_seconds"`
+	Quotas        []PutQuota `mapstructure:"quotas"`
+}
+
+// PutQuota is the quota of a single API key. A max of zero leaves that dimension unlimited.
+type PutQuota struct {
+	APIKey     string `mapstructure:"api_key"`
+	MaxEntries int    `mapstructure:"max_entries"`
+	MaxBytes   int    `mapstructure:"max_bytes"`
+}
+
+func (cfg *PutQuotas) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.Header == "" {
+		log.Fatalf("invalid config.put_quotas.header: it must not be empty when put quotas are enabled.")
+	}
+	if cfg.WindowSeconds <= 0 {
+		log.Fatalf("invalid config.put_quotas.window_seconds: %d. It must be greater than zero.", cfg.WindowSeconds)
+	}
+	log.Infof("config.put_quotas.header: %s", cfg.Header)
+	log.Infof("config.put_quotas.window_seconds: %d", cfg.WindowSeconds)
+	for i, quota := range cfg.Quotas {
+		if quota.APIKey == "" {
+			log.Fatalf("invalid config.put_quotas.quotas[%d].api_key: it must not be empty.", i)
+		}
+		if quota.MaxEntries < 0 || quota.MaxBytes < 0 {
+			log.Fatalf("invalid config.put_quotas.quotas[%d]: max_entries and max_bytes must not be negative.", i)
+		}
+		log.Infof("config.put_quotas.quotas[%d]: max_entries=%d max_bytes=%d", i, quota.MaxEntries, quota.MaxBytes)
+	}
+}
+
+// Auth only lets clients within AllowedCIDRs write values. The client IP is the address of the peer,
+// unless the server runs behind TrustedProxyDepth proxies: then it's the address the farthest of them
+// appended to the X-Forwarded-For header. Entries to the left of it could have been sent by the client.
+type Auth struct {
+	Enabled           bool     `mapstructure:"enabled"`
+	AllowedCIDRs      []string `mapstructure:"allowed_cidrs"`
+	TrustedProxyDepth int      `mapstructure:"trusted_proxy_depth"`
+}
+
+func (cfg *Auth) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if len(cfg.AllowedCIDRs) == 0 {
+		log.Fatalf("invalid config.auth.allowed_cidrs: it must not be empty when auth is enabled.")
+	}
+	if _, err := cfg.AllowedNetworks(); err != nil {
+		log.Fatalf("invalid config.auth.allowed_cidrs: %v", err)
+	}
+	if cfg.TrustedProxyDepth < 0 {
+		log.Fatalf("invalid config.auth.trusted_proxy_depth: %d. It must not be negative.", cfg.TrustedProxyDepth)
+	}
+	log.Infof("config.auth.enabled: %t", cfg.Enabled)
+	log.Infof("config.auth.allowed_cidrs: %v", cfg.AllowedCIDRs)
+	log.Infof("config.auth.trusted_proxy_depth: %d", cfg.TrustedProxyDepth)
+}
+
+// AllowedNetworks parses AllowedCIDRs
+func (cfg *Auth) AllowedNetworks() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cfg.AllowedCIDRs))
+	for _, cidr := range cfg.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// CORS lets browser pages served from AllowedOrigins call the public port. Origins may hold a single
+// "*" wildcard, such as "https://*.example.com". Browsers cache the answer to preflight requests for
+// MaxAgeSeconds. When disabled, the public port answers requests from any origin.
+type CORS struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+	MaxAgeSeconds  int      `mapstructure:"max_age_seconds"`
+}
+
+func (cfg *CORS) validateAndLog() {
+	if !cfg.Enabled {
+		return
+	}
+	if len(cfg.AllowedOrigins) == 0 {
+		log.Fatalf("invalid config.cors.allowed_origins: it must not be empty when CORS is enabled.")
+	}
+	if len(cfg.AllowedMethods) == 0 {
+		log.Fatalf("invalid config.cors.allowed_methods: it must not be empty when CORS is enabled.")
+	}
+	if cfg.MaxAgeSeconds < 0 {
+		log.Fatalf("invalid config.cors.max_age_seconds: %d. It must not be negative.", cfg.MaxAgeSeconds)
+	}
+	log.Infof("config.cors.enabled: %t", cfg.Enabled)
+	log.Infof("config.cors.allowed_origins: %v", cfg.AllowedOrigins)
+	log.Infof("config.cors.allowed_methods: %v", cfg.AllowedMethods)
+	log.Infof("config.cors.allowed_headers: %v", cfg.AllowedHeaders)
+	log.Infof("config.cors.max_age_seconds: %d", cfg.MaxAgeSeconds)
+}
+
 type CompressionType string
 
 const (
 	CompressionNone   CompressionType = "none"
+	CompressionGzip   CompressionType = "gzip"
 	CompressionSnappy CompressionType = "snappy"
+	CompressionZstd   CompressionType = "zstd"
 )
 
 type Metrics struct {
 	Type       MetricsType       `mapstructure:"type"`
 	Influx     InfluxMetrics     `mapstructure:"influx"`
 	Prometheus PrometheusMetrics `mapstructure:"prometheus"`
+	Statsd     StatsdMetrics     `mapstructure:"statsd"`
+	UserAgents UserAgentTagging  `mapstructure:"user_agents"`
 }
 
 func (cfg *Metrics) validateAndLog() {
@@ -212,15 +1005,21 @@ func (cfg *Metrics) validateAndLog() {
 		cfg.Prometheus.Enabled = true
 	}
 
-	metricsEnabled := cfg.Influx.Enabled || cfg.Prometheus.Enabled
+	if cfg.Statsd.Enabled {
+		cfg.Statsd.validateAndLog()
+	}
+
+	cfg.UserAgents.validateAndLog()
+
+	metricsEnabled := cfg.Influx.Enabled || cfg.Prometheus.Enabled || cfg.Statsd.Enabled
 	if cfg.Type == MetricsNone || cfg.Type == "" {
 		if !metricsEnabled {
 			log.Infof("Prebid Cache will run without metrics")
 		}
 	} else if cfg.Type != MetricsInflux {
-		// Was any other metrics system besides "InfluxDB" or "Prometheus" specified in `cfg.Type`?
+		// Was any other metrics system besides "InfluxDB", "Prometheus" or "StatsD" specified in `cfg.Type`?
 		if metricsEnabled {
-			// Prometheus, Influx or both, are enabled. Log a message explaining that `prebid-cache` will
+			// Prometheus, Influx or StatsD are enabled. Log a message explaining that `prebid-cache` will
 			// continue with supported metrics and non-supported metrics will be disabled
 			log.Infof("Prebid Cache will run without unsupported metrics \"%s\".", cfg.Type)
 		} else {
@@ -231,6 +1030,67 @@ func (cfg *Metrics) validateAndLog() {
 	}
 }
 
+// problems lists the settings the enabled metrics engines can't export without. The ports of the
+// main and admin servers are needed to tell whether the Prometheus listener would clash with them.
+// Those servers bind to every interface, so the listener clashes on their ports whatever host it binds to.
+func (cfg *Metrics) problems(port int, adminPort int) []string {
+	var missing []string
+	if cfg.Type == MetricsInflux || cfg.Influx.Enabled {
+		if cfg.Influx.Host == "" {
+			missing = append(missing, "config.metrics.influx.host is required by influx metrics")
+		}
+		if cfg.Influx.Database == "" {
+			missing = append(missing, "config.metrics.influx.database is required by influx metrics")
+		}
+	}
+	if cfg.Prometheus.Enabled {
+		switch cfg.Prometheus.Port {
+		case 0:
+			missing = append(missing, "config.metrics.prometheus.port is required by prometheus metrics")
+		case port, adminPort:
+			missing = append(missing, fmt.Sprintf("config.metrics.prometheus.port %d must differ from config.port and config.admin_port", cfg.Prometheus.Port))
+		}
+		if cfg.Prometheus.Namespace == "" {
+			missing = append(missing, "config.metrics.prometheus.namespace is required by prometheus metrics")
+		}
+		if cfg.Prometheus.Subsystem == "" {
+			missing = append(missing, "config.metrics.prometheus.subsystem is required by prometheus metrics")
+		}
+	}
+	if cfg.Statsd.Enabled {
+		if cfg.Statsd.Host == "" {
+			missing = append(missing, "config.metrics.statsd.host is required by statsd metrics")
+		}
+		if cfg.Statsd.Port <= 0 {
+			missing = append(missing, "config.metrics.statsd.port is required by statsd metrics")
+		}
+	}
+	switch cfg.Type {
+	case "", MetricsNone, MetricsInflux:
+	default:
+		if !cfg.Prometheus.Enabled && !cfg.Statsd.Enabled && !cfg.Influx.Enabled {
+			missing = append(missing, fmt.Sprintf(`unsupported config.metrics.type: %s. Set it to "influx" or "none", or enable prometheus or statsd metrics.`, cfg.Type))
+		}
+	}
+	return missing
+}
+
+// UserAgentTagging counts the requests by the class of their User-Agent header. Requests whose user
+// agent contains any of the PrebidServer substrings are classified as "prebid-server", those that
+// contain any of the Browser substrings as "browser", and the rest as "other". Matching ignores case.
+type UserAgentTagging struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	PrebidServer []string `mapstructure:"prebid_server"`
+	Browser      []string `mapstructure:"browser"`
+}
+
+func (cfg *UserAgentTagging) validateAndLog() {
+	if cfg.Enabled {
+		log.Infof("config.metrics.user_agents.prebid_server: %v", cfg.PrebidServer)
+		log.Infof("config.metrics.user_agents.browser: %v", cfg.Browser)
+	}
+}
+
 type MetricsType string
 
 const (
@@ -244,6 +1104,16 @@ type InfluxMetrics struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	Enabled  bool   `mapstructure:"enabled"`
+	// FlushIntervalSeconds is how often the metrics get written to Influx, or every ten seconds if left unset
+	FlushIntervalSeconds int `mapstructure:"flush_interval_seconds"`
+}
+
+// FlushInterval returns how often the metrics get written to Influx
+func (influxMetricsConfig *InfluxMetrics) FlushInterval() time.Duration {
+	if influxMetricsConfig.FlushIntervalSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(influxMetricsConfig.FlushIntervalSeconds) * time.Second
 }
 
 func (influxMetricsConfig *InfluxMetrics) validateAndLog() {
@@ -258,12 +1128,29 @@ func (influxMetricsConfig *InfluxMetrics) validateAndLog() {
 	log.Infof("config.metrics.influx.database: %s", influxMetricsConfig.Database)
 }
 
+// PrometheusMetrics are served for scraping on a listener of their own, apart from the main and admin
+// servers, so that they can be firewalled separately.
 type PrometheusMetrics struct {
+	// Host is the address the metrics listener binds to, like "127.0.0.1" to keep it off the network.
+	// Empty binds to every interface.
+	Host             string `mapstructure:"host"`
 	Port             int    `mapstructure:"port"`
 	Namespace        string `mapstructure:"namespace"`
 	Subsystem        string `mapstructure:"subsystem"`
 	TimeoutMillisRaw int    `mapstructure:"timeout_ms"`
 	Enabled          bool   `mapstructure:"enabled"`
+	// GetDurationSampleRate and PutDurationSampleRate are the fractions of GET and PUT request
+	// durations that get observed. A value of 1, or 0 if left unset, observes every request.
+	// Request counters are never sampled.
+	GetDurationSampleRate float64 `mapstructure:"get_duration_sample_rate"`
+	PutDurationSampleRate float64 `mapstructure:"put_duration_sample_rate"`
+	// AllowReset adds POST /admin/metrics/reset to the admin server, which zeroes every Prometheus
+	// metric without a restart. Meant for tests and staging: production dashboards would read every
+	// reset as a drop in the counters.
+	AllowReset bool `mapstructure:"allow_reset"`
+	// LabelAllowLists caps the cardinality of the metrics. Labels listed here only get recorded with
+	// the values they list, and any other value is recorded as "other". Unlisted labels are left as is.
+	LabelAllowLists map[string][]string `mapstructure:"label_allow_lists"`
 }
 
 func (promMetricsConfig *PrometheusMetrics) validateAndLog() {
@@ -279,19 +1166,223 @@ func (promMetricsConfig *PrometheusMetrics) validateAndLog() {
 	}
 	log.Infof("config.metrics.prometheus.namespace: %s", promMetricsConfig.Namespace)
 	log.Infof("config.metrics.prometheus.subsystem: %s", promMetricsConfig.Subsystem)
+	if promMetricsConfig.Host != "" {
+		log.Infof("config.metrics.prometheus.host: %s", promMetricsConfig.Host)
+	}
 	log.Infof("config.metrics.prometheus.port: %d", promMetricsConfig.Port)
+
+	validateAndLogSampleRate("get_duration_sample_rate", promMetricsConfig.GetDurationSampleRate)
+	validateAndLogSampleRate("put_duration_sample_rate", promMetricsConfig.PutDurationSampleRate)
+	if promMetricsConfig.AllowReset {
+		log.Warnf("config.metrics.prometheus.allow_reset is enabled. Any client of the admin server can zero the Prometheus metrics, so don't enable it in production.")
+	}
+	labels := make([]string, 0, len(promMetricsConfig.LabelAllowLists))
+	for label := range promMetricsConfig.LabelAllowLists {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		log.Infof("config.metrics.prometheus.label_allow_lists.%s: %v", label, promMetricsConfig.LabelAllowLists[label])
+	}
+}
+
+// validateAndLogSampleRate only logs the rates that actually drop observations
+func validateAndLogSampleRate(name string, rate float64) {
+	if rate < 0 || rate > 1 {
+		log.Fatalf("config.metrics.prometheus.%s must be a value between 0 and 1. Got %v", name, rate)
+	}
+	if rate > 0 && rate < 1 {
+		log.Infof("config.metrics.prometheus.%s: %v", name, rate)
+	}
 }
 
 func (m *PrometheusMetrics) Timeout() time.Duration {
 	return time.Duration(m.TimeoutMillisRaw) * time.Millisecond
 }
 
+// StatsdMetrics sends every metric to a StatsD agent over UDP as soon as it's recorded, with its name
+// prefixed by Prefix. Counters and timers are sampled at SampleRate, and the agent scales them back up.
+type StatsdMetrics struct {
+	Host       string  `mapstructure:"host"`
+	Port       int     `mapstructure:"port"`
+	Prefix     string  `mapstructure:"prefix"`
+	SampleRate float64 `mapstructure:"sample_rate"`
+	Enabled    bool    `mapstructure:"enabled"`
+}
+
+func (statsdMetricsConfig *StatsdMetrics) validateAndLog() {
+	if statsdMetricsConfig.Host == "" {
+		log.Fatalf(`Despite being enabled, statsd metrics came with no host info: config.metrics.statsd.host = "".`)
+	}
+	if statsdMetricsConfig.Port <= 0 {
+		log.Fatalf("Despite being enabled, statsd metrics came with an invalid port number: config.metrics.statsd.port = %d", statsdMetricsConfig.Port)
+	}
+	if statsdMetricsConfig.SampleRate <= 0 || statsdMetricsConfig.SampleRate > 1 {
+		log.Fatalf("config.metrics.statsd.sample_rate must be greater than 0 and no greater than 1. Got %v", statsdMetricsConfig.SampleRate)
+	}
+	log.Infof("config.metrics.statsd.host: %s", statsdMetricsConfig.Host)
+	log.Infof("config.metrics.statsd.port: %d", statsdMetricsConfig.Port)
+	log.Infof("config.metrics.statsd.prefix: %s", statsdMetricsConfig.Prefix)
+	log.Infof("config.metrics.statsd.sample_rate: %v", statsdMetricsConfig.SampleRate)
+}
+
 type Routes struct {
 	AllowPublicWrite bool `mapstructure:"allow_public_write"`
+	// GetMetadataHeaders adds the format, size, creation time and remaining TTL of the value to the
+	// headers of GET /cache responses, as far as the value was stored with them.
+	GetMetadataHeaders bool `mapstructure:"get_metadata_headers"`
+	// GetStalenessHeaders adds an Age header, counted from the creation time of the value, and a
+	// Cache-Control max-age from its remaining TTL to GET /cache responses, so that the caches in front
+	// of Prebid Cache don't serve values for longer than they're stored. Values stored without their
+	// creation time or TTL get no Age or Cache-Control respectively.
+	GetStalenessHeaders bool `mapstructure:"get_staleness_headers"`
+	// GetPathKeys also serves "GET /cache/{uuid}", which reads the same values as "GET /cache?uuid={uuid}"
+	GetPathKeys bool `mapstructure:"get_path_keys"`
+	// GetCustomHeaders are set on every successful GET /cache response. Their names are canonicalized,
+	// so they can be written in any case.
+	GetCustomHeaders map[string]string `mapstructure:"get_custom_headers"`
+	// GetContentTypes overrides the Content-Type of GET /cache responses by the format of the value,
+	// "json" or "xml". Formats left out keep their default, application/json or application/xml.
+	GetContentTypes map[string]string `mapstructure:"get_content_types"`
+	// GetMaxHeaderBytes caps the size of the headers of GET /cache responses, counting the name, the
+	// value and the separators of every header set by Prebid Cache. When the cap would be exceeded,
+	// the metadata headers are left out first, the staleness headers next and the custom headers last.
+	// Zero doesn't cap them.
+	GetMaxHeaderBytes int `mapstructure:"get_max_header_bytes"`
+	// GetHonorAccept responds to GET /cache requests with a 406 when their Accept header doesn't allow the
+	// Content-Type of the stored value, after GetContentTypes overrides it. Requests without an Accept
+	// header get any value.
+	GetHonorAccept bool `mapstructure:"get_honor_accept"`
+	// GetBatch serves "GET /cache?uuid=a&uuid=b" with the values of every key at once
+	GetBatch GetBatch `mapstructure:"get_batch"`
+	// Health serves the liveness and readiness probes of orchestrators like Kubernetes
+	Health Health `mapstructure:"health"`
+	// ErrorCodes has GET and POST /cache add the code of backend errors to the body of their responses,
+	// like {"error": "...", "code": "backend_error", "backend_code": "Timeout"}, so clients don't need to
+	// parse the messages of every backend.
+	ErrorCodes bool `mapstructure:"error_codes"`
+}
+
+// GetBatch lets GET /cache requests pass more than one key, for which they get a JSON array with an
+// element per key. Requests with a single key are served as usual.
+type GetBatch struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxKeys caps the keys a single request may pass, and Workers how many of them are read from the
+	// backend at once
+	MaxKeys int `mapstructure:"max_keys"`
+	Workers int `mapstructure:"workers"`
+	// MaxResponseBytes caps the encoded size of the values of a response. Once the keys read so far
+	// exceed it, the rest aren't read, and the response gets a 206 with the values that fit, in order,
+	// and its truncated flag set. Zero doesn't cap them.
+	MaxResponseBytes int `mapstructure:"max_response_bytes"`
+}
+
+// Health sets the paths of the health probes, which are served by both the public and the admin
+// servers. The liveness probe responds with a 200 for as long as the process is up, while the readiness
+// probe reads a key from the backend and responds with a 503 if it fails. Leaving a path empty doesn't
+// serve its probe.
+type Health struct {
+	LivenessPath  string `mapstructure:"liveness_path"`
+	ReadinessPath string `mapstructure:"readiness_path"`
+}
+
+// healthReservedPaths are served by other handlers, which the health probes can't replace
+var healthReservedPaths = map[string]bool{
+	"/":                    true,
+	"/status":              true,
+	"/cache":               true,
+	"/admin/metrics/reset": true,
+	"/admin/keys":          true,
+}
+
+func (cfg *Health) validateAndLog() {
+	probes := []struct {
+		name string
+		path string
+	}{
+		{"liveness_path", cfg.LivenessPath},
+		{"readiness_path", cfg.ReadinessPath},
+	}
+	for _, probe := range probes {
+		if probe.path == "" {
+			continue
+		}
+		if !strings.HasPrefix(probe.path, "/") || healthReservedPaths[probe.path] || strings.HasPrefix(probe.path, "/cache/") {
+			log.Fatalf("invalid config.routes.health.%s: %s. It must start with a / and not be served by another route.", probe.name, probe.path)
+		} else {
+			log.Infof("config.routes.health.%s: %s", probe.name, probe.path)
+		}
+	}
+	if cfg.LivenessPath != "" && cfg.LivenessPath == cfg.ReadinessPath {
+		log.Fatalf("config.routes.health.liveness_path and config.routes.health.readiness_path must not be the same.")
+	}
 }
 
 func (cfg *Routes) validateAndLog() {
 	if !cfg.AllowPublicWrite {
 		log.Infof("Main server will only accept GET requests")
 	}
+	if cfg.GetMetadataHeaders {
+		log.Infof("config.routes.get_metadata_headers: %t", cfg.GetMetadataHeaders)
+	}
+	if cfg.GetStalenessHeaders {
+		log.Infof("config.routes.get_staleness_headers: %t", cfg.GetStalenessHeaders)
+	}
+	if cfg.GetPathKeys {
+		log.Infof("config.routes.get_path_keys: %t", cfg.GetPathKeys)
+	}
+	names := make([]string, 0, len(cfg.GetCustomHeaders))
+	for name := range cfg.GetCustomHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Infof("config.routes.get_custom_headers.%s: %s", name, cfg.GetCustomHeaders[name])
+	}
+	formats := make([]string, 0, len(cfg.GetContentTypes))
+	for format := range cfg.GetContentTypes {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	for _, format := range formats {
+		if format != "json" && format != "xml" {
+			log.Fatalf("invalid config.routes.get_content_types.%s. Content types can only be set for the json and xml formats.", format)
+		} else if cfg.GetContentTypes[format] == "" {
+			log.Fatalf("config.routes.get_content_types.%s must not be empty.", format)
+		} else {
+			log.Infof("config.routes.get_content_types.%s: %s", format, cfg.GetContentTypes[format])
+		}
+	}
+	if cfg.GetMaxHeaderBytes < 0 {
+		log.Fatalf("invalid config.routes.get_max_header_bytes: %d. It must not be negative.", cfg.GetMaxHeaderBytes)
+	}
+	if cfg.GetMaxHeaderBytes > 0 {
+		log.Infof("config.routes.get_max_header_bytes: %d", cfg.GetMaxHeaderBytes)
+	}
+	if cfg.GetHonorAccept {
+		log.Infof("config.routes.get_honor_accept: %t", cfg.GetHonorAccept)
+	}
+	if cfg.GetBatch.Enabled {
+		log.Infof("config.routes.get_batch.enabled: %t", cfg.GetBatch.Enabled)
+		if cfg.GetBatch.MaxKeys <= 0 {
+			log.Fatalf("invalid config.routes.get_batch.max_keys: %d. It must be greater than zero.", cfg.GetBatch.MaxKeys)
+		} else {
+			log.Infof("config.routes.get_batch.max_keys: %d", cfg.GetBatch.MaxKeys)
+		}
+		if cfg.GetBatch.Workers <= 0 {
+			log.Fatalf("invalid config.routes.get_batch.workers: %d. It must be greater than zero.", cfg.GetBatch.Workers)
+		} else {
+			log.Infof("config.routes.get_batch.workers: %d", cfg.GetBatch.Workers)
+		}
+		if cfg.GetBatch.MaxResponseBytes < 0 {
+			log.Fatalf("invalid config.routes.get_batch.max_response_bytes: %d. It must not be negative.", cfg.GetBatch.MaxResponseBytes)
+		}
+		if cfg.GetBatch.MaxResponseBytes > 0 {
+			log.Infof("config.routes.get_batch.max_response_bytes: %d", cfg.GetBatch.MaxResponseBytes)
+		}
+	}
+	cfg.Health.validateAndLog()
+	if cfg.ErrorCodes {
+		log.Infof("config.routes.error_codes: %t", cfg.ErrorCodes)
+	}
 }
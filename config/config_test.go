@@ -1,7 +1,10 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -39,32 +42,86 @@ func TestEnvConfig(t *testing.T) {
 }
 
 func TestLogValidateAndLog(t *testing.T) {
-
 	// logrus entries will be recorded to this `hook` object so we can compare and assert them
 	hook := test.NewGlobal()
 
-	// Define object to run `validateAndLog()` on
-	configLogObject := Log{
-		Level: Debug,
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
 	}
 
-	// run test
-	configLogObject.validateAndLog()
-
-	// Assert logrus entries
-	if !assert.Equal(t, 1, len(hook.Entries), "No entries were logged to logrus.") {
-		return
+	testCases := []struct {
+		description     string
+		inLog           *Log
+		expectedLogInfo []logComponents
+	}{
+		{
+			description: "Text format",
+			inLog:       &Log{Level: Debug, Format: TextFormat},
+			expectedLogInfo: []logComponents{
+				{msg: "config.log.level: debug", lvl: logrus.InfoLevel},
+				{msg: "config.log.format: text", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "JSON format",
+			inLog:       &Log{Level: Info, Format: JSONFormat},
+			expectedLogInfo: []logComponents{
+				{msg: "config.log.level: info", lvl: logrus.InfoLevel},
+				{msg: "config.log.format: json", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Unknown format, expect fatal level log entry",
+			inLog:       &Log{Level: Info, Format: "xml"},
+			expectedLogInfo: []logComponents{
+				{msg: "config.log.level: info", lvl: logrus.InfoLevel},
+				{msg: `invalid config.log.format: xml. It must be "text" or "json".`, lvl: logrus.FatalLevel},
+			},
+		},
 	}
-	if !assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level) {
-		return
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inLog.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
 	}
-	if !assert.Equal(t, "config.log.level: debug", hook.LastEntry().Message) {
-		return
+}
+
+func TestLogFormatter(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter((&Log{Format: JSONFormat}).Formatter())
+
+	logger.WithFields(logrus.Fields{"method": "POST", "status": 500}).Error("POST /cache had an unexpected error: connection refused")
+
+	var entry map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(out.Bytes(), &entry), "JSON entries should be parseable: %s", out.String()) {
+		assert.Equal(t, "error", entry["level"])
+		assert.Equal(t, "POST /cache had an unexpected error: connection refused", entry["msg"])
+		assert.Equal(t, "POST", entry["method"])
+		assert.Equal(t, float64(500), entry["status"])
+		assert.NotEmpty(t, entry["time"])
 	}
 
-	// Reset logrus
-	hook.Reset()
-	assert.Nil(t, hook.LastEntry())
+	assert.IsType(t, &logrus.TextFormatter{}, (&Log{Format: TextFormat}).Formatter())
+	assert.IsType(t, &logrus.TextFormatter{}, (&Log{}).Formatter(), "Text should be the default format")
 }
 
 func TestCheckMetricsEnabled(t *testing.T) {
@@ -613,6 +670,68 @@ func TestInfluxValidateAndLog(t *testing.T) {
 	}
 }
 
+func TestStatsdValidateAndLog(t *testing.T) {
+	hook := test.NewGlobal()
+
+	validLogs := []string{
+		"config.metrics.statsd.host: localhost",
+		"config.metrics.statsd.port: 8125",
+		"config.metrics.statsd.prefix: prebid_cache",
+		"config.metrics.statsd.sample_rate: 0.5",
+	}
+	testCases := []struct {
+		desc          string
+		inCfg         StatsdMetrics
+		expectedFatal []string
+	}{
+		{
+			desc:  "Valid config",
+			inCfg: StatsdMetrics{Host: "localhost", Port: 8125, Prefix: "prebid_cache", SampleRate: 0.5},
+		},
+		{
+			desc:          "Blank host",
+			inCfg:         StatsdMetrics{Host: "", Port: 8125, Prefix: "prebid_cache", SampleRate: 0.5},
+			expectedFatal: []string{`Despite being enabled, statsd metrics came with no host info: config.metrics.statsd.host = "".`},
+		},
+		{
+			desc:          "Zero port",
+			inCfg:         StatsdMetrics{Host: "localhost", Port: 0, Prefix: "prebid_cache", SampleRate: 0.5},
+			expectedFatal: []string{"Despite being enabled, statsd metrics came with an invalid port number: config.metrics.statsd.port = 0"},
+		},
+		{
+			desc:          "Zero sample rate",
+			inCfg:         StatsdMetrics{Host: "localhost", Port: 8125, Prefix: "prebid_cache", SampleRate: 0},
+			expectedFatal: []string{"config.metrics.statsd.sample_rate must be greater than 0 and no greater than 1. Got 0"},
+		},
+		{
+			desc:          "Sample rate over 1",
+			inCfg:         StatsdMetrics{Host: "localhost", Port: 8125, Prefix: "prebid_cache", SampleRate: 1.5},
+			expectedFatal: []string{"config.metrics.statsd.sample_rate must be greater than 0 and no greater than 1. Got 1.5"},
+		},
+	}
+
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		tc.inCfg.validateAndLog()
+
+		var fatals []string
+		for _, entry := range hook.Entries {
+			if entry.Level == logrus.FatalLevel {
+				fatals = append(fatals, entry.Message)
+			}
+		}
+		assert.Equal(t, tc.expectedFatal, fatals, tc.desc)
+		if len(tc.expectedFatal) == 0 && assert.Len(t, hook.Entries, len(validLogs), tc.desc) {
+			for i, msg := range validLogs {
+				assert.Equal(t, msg, hook.Entries[i].Message, tc.desc)
+			}
+		}
+		hook.Reset()
+	}
+}
+
 func TestPrometheusValidateAndLog(t *testing.T) {
 
 	type logComponents struct {
@@ -736,6 +855,130 @@ func TestPrometheusValidateAndLog(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "[5] Valid config that samples GET durations. Expect the sample rate in log",
+			prometheusConfig: &PrometheusMetrics{
+				Port:                  8080,
+				Namespace:             "prebid",
+				Subsystem:             "cache",
+				GetDurationSampleRate: 0.25,
+				PutDurationSampleRate: 1,
+			},
+			//out
+			expectError: false,
+			expectedLogInfo: []logComponents{
+				{
+					msg: "config.metrics.prometheus.namespace: prebid",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.subsystem: cache",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.port: 8080",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.get_duration_sample_rate: 0.25",
+					lvl: logrus.InfoLevel,
+				},
+			},
+		},
+		{
+			description: "[6] PUT duration sample rate greater than 1. Expect error",
+			prometheusConfig: &PrometheusMetrics{
+				Port:                  8080,
+				Namespace:             "prebid",
+				Subsystem:             "cache",
+				PutDurationSampleRate: 2,
+			},
+			//out
+			expectError: true,
+			expectedLogInfo: []logComponents{
+				{
+					msg: "config.metrics.prometheus.namespace: prebid",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.subsystem: cache",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.port: 8080",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.put_duration_sample_rate must be a value between 0 and 1. Got 2",
+					lvl: logrus.FatalLevel,
+				},
+			},
+		},
+		{
+			description: "[7] Metrics reset allowed. Expect warning",
+			prometheusConfig: &PrometheusMetrics{
+				Port:       8080,
+				Namespace:  "prebid",
+				Subsystem:  "cache",
+				AllowReset: true,
+			},
+			//out
+			expectError: false,
+			expectedLogInfo: []logComponents{
+				{
+					msg: "config.metrics.prometheus.namespace: prebid",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.subsystem: cache",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.port: 8080",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.allow_reset is enabled. Any client of the admin server can zero the Prometheus metrics, so don't enable it in production.",
+					lvl: logrus.WarnLevel,
+				},
+			},
+		},
+		{
+			description: "[8] Label allow-lists. Expect them in log",
+			prometheusConfig: &PrometheusMetrics{
+				Port:      8080,
+				Namespace: "prebid",
+				Subsystem: "cache",
+				LabelAllowLists: map[string][]string{
+					"user_agent": {"prebid-server"},
+					"format":     {"json", "xml"},
+				},
+			},
+			//out
+			expectError: false,
+			expectedLogInfo: []logComponents{
+				{
+					msg: "config.metrics.prometheus.namespace: prebid",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.subsystem: cache",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.port: 8080",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.label_allow_lists.format: [json xml]",
+					lvl: logrus.InfoLevel,
+				},
+				{
+					msg: "config.metrics.prometheus.label_allow_lists.user_agent: [prebid-server]",
+					lvl: logrus.InfoLevel,
+				},
+			},
+		},
 	}
 
 	// logrus entries will be recorded to this `hook` object so we can compare and assert them
@@ -791,7 +1034,7 @@ func TestCompressionValidateAndLog(t *testing.T) {
 			description:    "Blank compression type, expect fatal level log entry",
 			compressionCfg: &Compression{Type: CompressionType("")},
 			expectedLogInfo: []logComponents{
-				{msg: `invalid config.compression.type: . It must be "none" or "snappy"`, lvl: logrus.FatalLevel},
+				{msg: `invalid config.compression.type: . It must be "none", "gzip", "snappy" or "zstd"`, lvl: logrus.FatalLevel},
 			},
 		},
 		{
@@ -808,11 +1051,25 @@ func TestCompressionValidateAndLog(t *testing.T) {
 				{msg: "config.compression.type: snappy", lvl: logrus.InfoLevel},
 			},
 		},
+		{
+			description:    "Valid compression type 'gzip', expect info level log entry",
+			compressionCfg: &Compression{Type: CompressionGzip},
+			expectedLogInfo: []logComponents{
+				{msg: "config.compression.type: gzip", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Valid compression type 'zstd', expect info level log entry",
+			compressionCfg: &Compression{Type: CompressionZstd},
+			expectedLogInfo: []logComponents{
+				{msg: "config.compression.type: zstd", lvl: logrus.InfoLevel},
+			},
+		},
 		{
 			description:    "Unsupported compression, expect fatal level log entry",
 			compressionCfg: &Compression{Type: CompressionType("UnknownCompressionType")},
 			expectedLogInfo: []logComponents{
-				{msg: `invalid config.compression.type: UnknownCompressionType. It must be "none" or "snappy"`, lvl: logrus.FatalLevel},
+				{msg: `invalid config.compression.type: UnknownCompressionType. It must be "none", "gzip", "snappy" or "zstd"`, lvl: logrus.FatalLevel},
 			},
 		},
 	}
@@ -936,15 +1193,25 @@ func TestConfigurationValidateAndLog(t *testing.T) {
 		{msg: fmt.Sprintf("config.port: %d", expectedConfig.Port), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.admin_port: %d", expectedConfig.AdminPort), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.log.level: %s", expectedConfig.Log.Level), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.log.format: %s", expectedConfig.Log.Format), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.rate_limiter.enabled: %t", expectedConfig.RateLimiting.Enabled), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.rate_limiter.num_requests: %d", expectedConfig.RateLimiting.MaxRequestsPerSecond), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.request_limits.allow_setting_keys: %v", expectedConfig.RequestLimits.AllowSettingKeys), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.request_limits.max_ttl_seconds: %d", expectedConfig.RequestLimits.MaxTTLSeconds), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.request_limits.max_size_bytes: %d", expectedConfig.RequestLimits.MaxSize), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.request_limits.max_num_values: %d", expectedConfig.RequestLimits.MaxNumValues), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.request_limits.validate_xml: %v", expectedConfig.RequestLimits.ValidateXML), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.request_limits.validate_json: %v", expectedConfig.RequestLimits.ValidateJSON), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.backend.type: %s", expectedConfig.Backend.Type), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("config.compression.type: %s", expectedConfig.Compression.Type), lvl: logrus.InfoLevel},
 		{msg: fmt.Sprintf("Prebid Cache will run without metrics"), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.routes.health.liveness_path: %s", expectedConfig.Routes.Health.LivenessPath), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.routes.health.readiness_path: %s", expectedConfig.Routes.Health.ReadinessPath), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.request_id.enabled: %t", expectedConfig.RequestID.Enabled), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.request_id.header: %s", expectedConfig.RequestID.Header), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.server.read_timeout_ms: %d", expectedConfig.Server.ReadTimeoutMillis), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.server.write_timeout_ms: %d", expectedConfig.Server.WriteTimeoutMillis), lvl: logrus.InfoLevel},
+		{msg: fmt.Sprintf("config.server.idle_timeout_ms: %d", expectedConfig.Server.IdleTimeoutMillis), lvl: logrus.InfoLevel},
 	}
 
 	// Run test
@@ -963,6 +1230,139 @@ func TestConfigurationValidateAndLog(t *testing.T) {
 	assert.Nil(t, hook.LastEntry())
 }
 
+func TestConfigurationValidate(t *testing.T) {
+	hook := test.NewGlobal()
+
+	testCases := []struct {
+		desc             string
+		modify           func(cfg *Configuration)
+		expectedProblems []string
+	}{
+		{
+			desc:   "Defaults",
+			modify: func(cfg *Configuration) {},
+		},
+		{
+			desc: "Every field the backend and metrics need is set",
+			modify: func(cfg *Configuration) {
+				cfg.Backend = Backend{Type: BackendCassandra, Cassandra: Cassandra{Hosts: "127.0.0.1", Keyspace: "prebid"}}
+				cfg.Standby = Standby{Enabled: true, Backend: Backend{Type: BackendRedis, Redis: Redis{Host: "127.0.0.1", Port: 6379}}}
+				cfg.Metrics.Prometheus = PrometheusMetrics{Enabled: true, Port: 8080, Namespace: "prebid", Subsystem: "cache"}
+				cfg.Metrics.Statsd = StatsdMetrics{Enabled: true, Host: "127.0.0.1", Port: 8125}
+			},
+		},
+		{
+			desc: "Backend missing its required fields",
+			modify: func(cfg *Configuration) {
+				cfg.Backend = Backend{Type: BackendCassandra}
+			},
+			expectedProblems: []string{
+				"config.backend.cassandra.hosts is required by config.backend.type cassandra",
+				"config.backend.cassandra.keyspace is required by config.backend.type cassandra",
+			},
+		},
+		{
+			desc: "Unknown backend type",
+			modify: func(cfg *Configuration) {
+				cfg.Backend.Type = "mongo"
+			},
+			expectedProblems: []string{
+				`unknown config.backend.type: mongo. It must be "aerospike", "azure", "azure_blob", "cassandra", "couchbase", "etcd", "memcache", "nats", "postgres", "redis", "s3", or "memory".`,
+			},
+		},
+		{
+			desc: "Redis through Sentinel only needs the master name",
+			modify: func(cfg *Configuration) {
+				cfg.Backend = Backend{Type: BackendRedis, Redis: Redis{SentinelAddrs: []string{"127.0.0.1:26379"}}}
+			},
+			expectedProblems: []string{
+				"config.backend.redis.master_name is required by config.backend.type redis",
+			},
+		},
+		{
+			desc: "Standby backend missing its required fields",
+			modify: func(cfg *Configuration) {
+				cfg.Standby = Standby{Enabled: true, Backend: Backend{Type: BackendS3}}
+			},
+			expectedProblems: []string{
+				"config.standby.backend.s3.bucket is required by config.backend.type s3",
+			},
+		},
+		{
+			desc: "Metrics engines missing their required fields",
+			modify: func(cfg *Configuration) {
+				cfg.Metrics.Type = MetricsInflux
+				cfg.Metrics.Prometheus = PrometheusMetrics{Enabled: true, Port: cfg.AdminPort}
+				cfg.Metrics.Statsd = StatsdMetrics{Enabled: true}
+			},
+			expectedProblems: []string{
+				"config.metrics.influx.host is required by influx metrics",
+				"config.metrics.influx.database is required by influx metrics",
+				"config.metrics.prometheus.port 2525 must differ from config.port and config.admin_port",
+				"config.metrics.prometheus.namespace is required by prometheus metrics",
+				"config.metrics.prometheus.subsystem is required by prometheus metrics",
+				"config.metrics.statsd.host is required by statsd metrics",
+				"config.metrics.statsd.port is required by statsd metrics",
+			},
+		},
+		{
+			desc: "Prometheus on the port of the main server, bound to a host of its own",
+			modify: func(cfg *Configuration) {
+				cfg.Metrics.Prometheus = PrometheusMetrics{Enabled: true, Host: "127.0.0.1", Port: cfg.Port, Namespace: "prebid", Subsystem: "cache"}
+			},
+			expectedProblems: []string{
+				"config.metrics.prometheus.port 2424 must differ from config.port and config.admin_port",
+			},
+		},
+		{
+			desc: "Prometheus on the port of the main server, bound to every interface",
+			modify: func(cfg *Configuration) {
+				cfg.Metrics.Prometheus = PrometheusMetrics{Enabled: true, Port: cfg.Port, Namespace: "prebid", Subsystem: "cache"}
+			},
+			expectedProblems: []string{
+				"config.metrics.prometheus.port 2424 must differ from config.port and config.admin_port",
+			},
+		},
+		{
+			desc: "Unsupported metrics type with no other engine enabled",
+			modify: func(cfg *Configuration) {
+				cfg.Metrics.Type = "datadog"
+			},
+			expectedProblems: []string{
+				`unsupported config.metrics.type: datadog. Set it to "influx" or "none", or enable prometheus or statsd metrics.`,
+			},
+		},
+		{
+			desc: "Problems of every section get reported together",
+			modify: func(cfg *Configuration) {
+				cfg.Port = 0
+				cfg.Backend = Backend{Type: BackendNATS, NATS: NATS{URL: "nats://127.0.0.1:4222"}}
+				cfg.Metrics.Prometheus = PrometheusMetrics{Enabled: true, Namespace: "prebid", Subsystem: "cache"}
+			},
+			expectedProblems: []string{
+				"config.port must be greater than zero. Got 0",
+				"config.backend.nats.bucket is required by config.backend.type nats",
+				"config.metrics.prometheus.port is required by prometheus metrics",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		cfg := getExpectedDefaultConfig()
+		tc.modify(&cfg)
+
+		err := cfg.Validate()
+
+		if len(tc.expectedProblems) == 0 {
+			assert.NoError(t, err, tc.desc)
+		} else if assert.Error(t, err, tc.desc) {
+			assert.Equal(t, "invalid config:\n  "+strings.Join(tc.expectedProblems, "\n  "), err.Error(), tc.desc)
+		}
+		assert.Empty(t, hook.Entries, "%s: validating shouldn't log", tc.desc)
+		hook.Reset()
+	}
+}
+
 func TestPrometheusTimeoutDuration(t *testing.T) {
 	prometheusConfig := &PrometheusMetrics{
 		TimeoutMillisRaw: 5,
@@ -973,6 +1373,11 @@ func TestPrometheusTimeoutDuration(t *testing.T) {
 	assert.Equal(t, expectedTimeout, actualTimeout)
 }
 
+func TestInfluxFlushInterval(t *testing.T) {
+	assert.Equal(t, 30*time.Second, (&InfluxMetrics{FlushIntervalSeconds: 30}).FlushInterval())
+	assert.Equal(t, 10*time.Second, (&InfluxMetrics{}).FlushInterval(), "An unset interval should fall back to ten seconds")
+}
+
 func TestRoutesValidateAndLog(t *testing.T) {
 	// logrus entries will be recorded to this `hook` object so we can compare and assert them
 	hook := test.NewGlobal()
@@ -999,22 +1404,154 @@ func TestRoutesValidateAndLog(t *testing.T) {
 			inRoutesConfig:  &Routes{AllowPublicWrite: true},
 			expectedLogInfo: []logComponents{},
 		},
-	}
-
-	//substitute logger exit function so execution doesn't get interrupted
-	defer func() { logrus.StandardLogger().ExitFunc = nil }()
-	logrus.StandardLogger().ExitFunc = func(int) {}
-
-	for _, tc := range testCases {
-		// Run test
-		tc.inRoutesConfig.validateAndLog()
-
-		// Assert logrus expected entries
-		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
-			for i := 0; i < len(tc.expectedLogInfo); i++ {
-				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
-				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
-			}
+		{
+			description:    "GET requests can pass their key in the path, log info level message",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetPathKeys: true},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_path_keys: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Custom headers and a header size cap, log info level messages",
+			inRoutesConfig: &Routes{
+				AllowPublicWrite:  true,
+				GetCustomHeaders:  map[string]string{"X-Region": "us-east", "X-Cluster": "blue"},
+				GetMaxHeaderBytes: 1024,
+			},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_custom_headers.X-Cluster: blue", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_custom_headers.X-Region: us-east", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_max_header_bytes: 1024", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "GET requests honor their Accept header, log info level message",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetHonorAccept: true},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_honor_accept: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Content type overrides, log info level messages",
+			inRoutesConfig: &Routes{
+				AllowPublicWrite: true,
+				GetContentTypes:  map[string]string{"xml": "text/xml", "json": "application/vnd.prebid+json"},
+			},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_content_types.json: application/vnd.prebid+json", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_content_types.xml: text/xml", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Content type override of an unknown format, expect fatal level log entry",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetContentTypes: map[string]string{"html": "text/html"}},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.routes.get_content_types.html. Content types can only be set for the json and xml formats.", lvl: logrus.FatalLevel},
+			},
+		},
+		{
+			description:    "Empty content type override, expect fatal level log entry",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetContentTypes: map[string]string{"xml": ""}},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_content_types.xml must not be empty.", lvl: logrus.FatalLevel},
+			},
+		},
+		{
+			description:    "GET responses tell how fresh the value is, log info level message",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetStalenessHeaders: true},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_staleness_headers: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Backend errors are reported with their code, log info level message",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, ErrorCodes: true},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.error_codes: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Batch GET requests, log info level messages",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetBatch: GetBatch{Enabled: true, MaxKeys: 20, Workers: 8, MaxResponseBytes: 65536}},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_batch.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_batch.max_keys: 20", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_batch.workers: 8", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_batch.max_response_bytes: 65536", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Batch GET requests with a negative response cap, expect a fatal level log entry",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetBatch: GetBatch{Enabled: true, MaxKeys: 20, Workers: 8, MaxResponseBytes: -1}},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_batch.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_batch.max_keys: 20", lvl: logrus.InfoLevel},
+				{msg: "config.routes.get_batch.workers: 8", lvl: logrus.InfoLevel},
+				{msg: "invalid config.routes.get_batch.max_response_bytes: -1. It must not be negative.", lvl: logrus.FatalLevel},
+			},
+		},
+		{
+			description:     "Batch GET requests disabled, their limits are ignored",
+			inRoutesConfig:  &Routes{AllowPublicWrite: true, GetBatch: GetBatch{MaxKeys: -1}},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description:    "Batch GET requests without a key cap or workers, expect fatal level log entries",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetBatch: GetBatch{Enabled: true}},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.get_batch.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "invalid config.routes.get_batch.max_keys: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.routes.get_batch.workers: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+			},
+		},
+		{
+			description:    "Health probes, log info level messages",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, Health: Health{LivenessPath: "/healthz", ReadinessPath: "/readyz"}},
+			expectedLogInfo: []logComponents{
+				{msg: "config.routes.health.liveness_path: /healthz", lvl: logrus.InfoLevel},
+				{msg: "config.routes.health.readiness_path: /readyz", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Health probes on paths of other routes, expect fatal level log entries",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, Health: Health{LivenessPath: "/status", ReadinessPath: "/cache/ready"}},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.routes.health.liveness_path: /status. It must start with a / and not be served by another route.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.routes.health.readiness_path: /cache/ready. It must start with a / and not be served by another route.", lvl: logrus.FatalLevel},
+			},
+		},
+		{
+			description:    "Relative health probe path and both probes on the same path, expect fatal level log entries",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, Health: Health{LivenessPath: "health", ReadinessPath: "health"}},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.routes.health.liveness_path: health. It must start with a / and not be served by another route.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.routes.health.readiness_path: health. It must start with a / and not be served by another route.", lvl: logrus.FatalLevel},
+				{msg: "config.routes.health.liveness_path and config.routes.health.readiness_path must not be the same.", lvl: logrus.FatalLevel},
+			},
+		},
+		{
+			description:    "Negative header size cap, expect fatal level log entry",
+			inRoutesConfig: &Routes{AllowPublicWrite: true, GetMaxHeaderBytes: -1},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.routes.get_max_header_bytes: -1. It must not be negative.", lvl: logrus.FatalLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inRoutesConfig.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
 		}
 
 		//Reset log after every test and assert successful reset
@@ -1051,15 +1588,67 @@ func getExpectedDefaultConfig() Configuration {
 		AdminPort:     2525,
 		IndexResponse: "This application stores short-term data for use in Prebid.",
 		Log: Log{
-			Level: Info,
+			Level:  Info,
+			Format: TextFormat,
 		},
 		Backend: Backend{
-			Type: BackendMemory,
+			Type:      BackendMemory,
+			KeyFormat: KeyFormatUUIDv4,
+			Cassandra: Cassandra{
+				ConnectTimeoutMillis: 2000,
+				QueryTimeoutMillis:   500,
+			},
+			Etcd: Etcd{
+				Endpoints:         []string{},
+				DialTimeoutMillis: 5000,
+			},
 			Memcache: Memcache{
 				Hosts: []string{},
 			},
+			Memory: Memory{
+				Shards: 16,
+			},
+			Postgres: Postgres{
+				CleanupIntervalSeconds: 60,
+			},
 			Aerospike: Aerospike{
-				Hosts: []string{},
+				Hosts:       []string{},
+				CommitLevel: AerospikeCommitAll,
+			},
+			Redis: Redis{
+				SentinelAddrs: []string{},
+			},
+			Scrubber: Scrubber{
+				KeysPerSecond: 10,
+				SampleRate:    1,
+			},
+			Retry: Retry{
+				MaxAttempts:          1,
+				BackoffMillis:        10,
+				MaxBackoffMillis:     1000,
+				Jitter:               0.2,
+				OverrideClientHeader: "X-Api-Key",
+				OverrideClients:      []string{},
+			},
+			CircuitBreaker: CircuitBreaker{
+				FailureThreshold: 5,
+				CooldownMillis:   10000,
+			},
+			NearCache: NearCache{
+				MaxEntries: 10000,
+				TTLSeconds: 5,
+			},
+			WriteBehind: WriteBehind{
+				BufferSize:      1000,
+				Workers:         4,
+				RespondAccepted: true,
+			},
+			Batching: Batching{
+				WindowMillis: 2,
+				MaxSize:      100,
+			},
+			CollisionGuard: CollisionGuard{
+				MaxRetries: 3,
 			},
 		},
 		Compression: Compression{
@@ -1068,14 +1657,102 @@ func getExpectedDefaultConfig() Configuration {
 		RateLimiting: RateLimiting{
 			Enabled:              true,
 			MaxRequestsPerSecond: 100,
+			PerClientPuts: ClientRateLimit{
+				PutsPerSecond: 10,
+				Burst:         20,
+				MaxClients:    10000,
+				APIKeyHeader:  "X-Api-Key",
+				APIKeys:       []string{},
+			},
 		},
 		RequestLimits: RequestLimits{
 			MaxSize:       10240,
 			MaxNumValues:  10,
 			MaxTTLSeconds: 3600,
 		},
+		Metrics: Metrics{
+			Influx: InfluxMetrics{
+				FlushIntervalSeconds: 10,
+			},
+			Prometheus: PrometheusMetrics{
+				GetDurationSampleRate: 1,
+				PutDurationSampleRate: 1,
+				LabelAllowLists:       map[string][]string{},
+			},
+			Statsd: StatsdMetrics{
+				Port:       8125,
+				Prefix:     "prebid_cache",
+				SampleRate: 1,
+			},
+			UserAgents: UserAgentTagging{
+				PrebidServer: []string{"prebid-server", "Go-http-client"},
+				Browser:      []string{"Mozilla"},
+			},
+		},
 		Routes: Routes{
 			AllowPublicWrite: true,
+			GetBatch: GetBatch{
+				MaxKeys: 10,
+				Workers: 4,
+			},
+			Health: Health{
+				LivenessPath:  "/healthz",
+				ReadinessPath: "/readyz",
+			},
+		},
+		ResponseCompression: ResponseCompression{
+			AllowPaths: []string{},
+			DenyPaths:  []string{},
+		},
+		RequestDecompression: RequestDecompression{
+			MaxSizeBytes: 1048576,
+		},
+		PutQuotas: PutQuotas{
+			Header:        "X-Api-Key",
+			WindowSeconds: 3600,
+		},
+		Auth: Auth{
+			AllowedCIDRs: []string{},
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{},
+			AllowedMethods: []string{"GET", "HEAD"},
+			AllowedHeaders: []string{},
+			MaxAgeSeconds:  600,
+		},
+		Correlation: Correlation{
+			ClientIDHeader: "X-Client-Id",
+		},
+		RequestID: RequestID{
+			Enabled: true,
+			Header:  "X-Request-ID",
+		},
+		ClientDeadlines: ClientDeadlines{
+			Header:    "X-Deadline-Ms",
+			MaxMillis: 500,
+		},
+		Tracing: Tracing{
+			SampleRate: 0.01,
+		},
+		Server: Server{
+			ReadTimeoutMillis:  15000,
+			WriteTimeoutMillis: 15000,
+			IdleTimeoutMillis:  60000,
+		},
+		FileDescriptors: FileDescriptors{
+			WarnFraction:         0.8,
+			CheckIntervalSeconds: 30,
+		},
+		Priming: Priming{
+			BatchSize:     10,
+			Workers:       4,
+			TimeoutMillis: 60000,
+		},
+		Standby: Standby{
+			QueueSize:     10000,
+			Workers:       2,
+			MaxAttempts:   3,
+			BackoffMillis: 100,
 		},
 	}
 }
@@ -1087,51 +1764,168 @@ func getExpectedFullConfigForTestFile() Configuration {
 		AdminPort:     2525,
 		IndexResponse: "Any index response",
 		Log: Log{
-			Level: Info,
+			Level:  Info,
+			Format: JSONFormat,
 		},
 		RateLimiting: RateLimiting{
 			Enabled:              false,
 			MaxRequestsPerSecond: 150,
+			PerClientPuts: ClientRateLimit{
+				Enabled:       true,
+				PutsPerSecond: 2.5,
+				Burst:         5,
+				MaxClients:    500,
+				APIKeyHeader:  "X-Partner-Key",
+				APIKeys:       []string{"partner-1", "partner-2"},
+			},
 		},
 		RequestLimits: RequestLimits{
-			MaxSize:          10240,
-			MaxNumValues:     10,
-			MaxTTLSeconds:    5000,
-			AllowSettingKeys: true,
+			MaxSize:           10240,
+			MaxNumValues:      10,
+			MaxRequestSize:    204800,
+			MaxTTLSeconds:     5000,
+			AllowSettingKeys:  true,
+			MinTTLSeconds:     30,
+			DefaultTTLSeconds: 1200,
+			DefaultTTLSecondsByFormat: map[string]int{
+				"xml": 600,
+			},
+			ValidateXML:  true,
+			ValidateJSON: true,
 		},
 		Backend: Backend{
-			Type: BackendMemory,
+			Type:               BackendMemory,
+			KeyPrefix:          "staging-",
+			AllowKeyManagement: true,
+			KeyFormat:          KeyFormatUUIDv7,
+			Scrubber: Scrubber{
+				Enabled:       true,
+				KeysPerSecond: 50,
+				SampleRate:    0.5,
+			},
+			Retry: Retry{
+				MaxAttempts:          3,
+				BackoffMillis:        5,
+				MaxBackoffMillis:     200,
+				RetryPuts:            true,
+				Jitter:               0.5,
+				OverrideClientHeader: "X-Api-Key",
+				OverrideClients:      []string{},
+			},
+			CircuitBreaker: CircuitBreaker{
+				Enabled:          true,
+				FailureThreshold: 10,
+				CooldownMillis:   2000,
+			},
+			WriteBehind: WriteBehind{
+				Enabled:         true,
+				BufferSize:      200,
+				Workers:         2,
+				RespondAccepted: false,
+			},
+			Batching: Batching{
+				Enabled:      true,
+				WindowMillis: 5,
+				MaxSize:      50,
+			},
+			CoalesceGets:   true,
+			MaxConcurrency: 64,
+			NearCache: NearCache{
+				Enabled:    true,
+				MaxEntries: 500,
+				MaxBytes:   1048576,
+				TTLSeconds: 2,
+				PrimeOnPut: true,
+			},
+			CollisionGuard: CollisionGuard{
+				MaxRetries: 5,
+			},
+			CapabilityCheck: CapabilityCheck{
+				Enabled:           true,
+				FailOnUnsupported: true,
+			},
 			Aerospike: Aerospike{
-				DefaultTTL: 3600,
-				Host:       "aerospike.prebid.com",
-				Hosts:      []string{"aerospike2.prebid.com", "aerospike3.prebid.com"},
-				Port:       3000,
-				Namespace:  "whatever",
-				User:       "foo",
-				Password:   "bar",
+				DefaultTTL:          3600,
+				Host:                "aerospike.prebid.com",
+				Hosts:               []string{"aerospike2.prebid.com", "aerospike3.prebid.com"},
+				Port:                3000,
+				Namespace:           "whatever",
+				User:                "foo",
+				Password:            "bar",
+				CommitLevel:         AerospikeCommitMaster,
+				SocketTimeoutMillis: 100,
+				TotalTimeoutMillis:  250,
+				TLS: AerospikeTLS{
+					Enabled:  true,
+					Name:     "aerospike-cluster",
+					CertFile: "/etc/prebid-cache/aerospike-client.pem",
+					KeyFile:  "/etc/prebid-cache/aerospike-client.key",
+					CAFile:   "/etc/prebid-cache/aerospike-ca.pem",
+				},
 			},
 			Azure: Azure{
 				Account: "azure-account-here",
 				Key:     "azure-key-here",
 			},
+			AzureBlob: AzureBlob{
+				Account:    "prebidcache",
+				Container:  "values",
+				Prefix:     "cache/",
+				AccountKey: "azure-blob-key",
+				Endpoint:   "http://127.0.0.1:10000/prebidcache",
+			},
 			Cassandra: Cassandra{
-				Hosts:    "127.0.0.1",
-				Keyspace: "prebid",
+				Hosts:                "127.0.0.1",
+				Keyspace:             "prebid",
+				Username:             "cassandra-user",
+				Password:             "cassandra-password",
+				ConnectTimeoutMillis: 1000,
+				QueryTimeoutMillis:   250,
+				TLS: CassandraTLS{
+					Enabled:  true,
+					CAFile:   "/etc/prebid-cache/cassandra-ca.crt",
+					CertFile: "/etc/prebid-cache/cassandra-client.crt",
+					KeyFile:  "/etc/prebid-cache/cassandra-client.key",
+				},
+			},
+			Etcd: Etcd{
+				Endpoints:         []string{},
+				DialTimeoutMillis: 5000,
 			},
 			Memcache: Memcache{
 				Hosts: []string{"10.0.0.1:11211", "127.0.0.1"},
 			},
+			Memory: Memory{
+				MaxBytes:        1048576,
+				Shards:          16,
+				EvictionMetrics: true,
+				LogEvictions:    true,
+			},
+			Postgres: Postgres{
+				DSN:                    "postgres://prebid@postgres.prebid.com:5432/prebid",
+				Table:                  "cache.values",
+				CleanupIntervalSeconds: 30,
+			},
 			Redis: Redis{
-				Host:       "127.0.0.1",
-				Port:       6379,
-				Password:   "redis-password",
-				Db:         1,
-				Expiration: 1,
+				Host:          "127.0.0.1",
+				Port:          6379,
+				SentinelAddrs: []string{"sentinel-1.prebid.com:26379", "sentinel-2.prebid.com:26379"},
+				MasterName:    "prebid-cache",
+				Password:      "redis-password",
+				Db:            1,
+				Expiration:    1,
 				TLS: RedisTLS{
 					Enabled:            false,
 					InsecureSkipVerify: false,
 				},
 			},
+			S3: S3{
+				Bucket:         "prebid-cache",
+				Prefix:         "values/",
+				Region:         "us-east-1",
+				Endpoint:       "http://127.0.0.1:9000",
+				ForcePathStyle: true,
+			},
 		},
 		Compression: Compression{
 			Type: CompressionType("snappy"),
@@ -1139,22 +1933,1140 @@ func getExpectedFullConfigForTestFile() Configuration {
 		Metrics: Metrics{
 			Type: MetricsType("none"),
 			Influx: InfluxMetrics{
-				Host:     "metrics-host",
-				Database: "metrics-database",
-				Username: "metrics-username",
-				Password: "metrics-password",
-				Enabled:  true,
+				Host:                 "metrics-host",
+				Database:             "metrics-database",
+				Username:             "metrics-username",
+				Password:             "metrics-password",
+				Enabled:              true,
+				FlushIntervalSeconds: 30,
 			},
 			Prometheus: PrometheusMetrics{
-				Port:             8080,
-				Namespace:        "prebid",
-				Subsystem:        "cache",
-				TimeoutMillisRaw: 100,
-				Enabled:          true,
+				Host:                  "127.0.0.1",
+				Port:                  8080,
+				Namespace:             "prebid",
+				Subsystem:             "cache",
+				TimeoutMillisRaw:      100,
+				Enabled:               true,
+				GetDurationSampleRate: 0.1,
+				PutDurationSampleRate: 1,
+				AllowReset:            true,
+				LabelAllowLists: map[string][]string{
+					"format":     {"json", "xml"},
+					"user_agent": {"prebid-server"},
+				},
+			},
+			Statsd: StatsdMetrics{
+				Host:       "statsd-host",
+				Port:       9125,
+				Prefix:     "cache",
+				SampleRate: 0.5,
+				Enabled:    true,
+			},
+			UserAgents: UserAgentTagging{
+				Enabled:      true,
+				PrebidServer: []string{"prebid-server"},
+				Browser:      []string{"Mozilla", "Safari"},
 			},
 		},
 		Routes: Routes{
-			AllowPublicWrite: true,
+			AllowPublicWrite:    true,
+			GetMetadataHeaders:  true,
+			GetStalenessHeaders: true,
+			GetPathKeys:         true,
+			GetCustomHeaders:    map[string]string{"x-cache-region": "us-east"},
+			GetContentTypes:     map[string]string{"xml": "text/xml"},
+			GetMaxHeaderBytes:   1024,
+			GetHonorAccept:      true,
+			GetBatch: GetBatch{
+				Enabled:          true,
+				MaxKeys:          20,
+				Workers:          8,
+				MaxResponseBytes: 65536,
+			},
+			Health: Health{
+				LivenessPath:  "/live",
+				ReadinessPath: "/ready",
+			},
+			ErrorCodes: true,
+		},
+		ResponseCompression: ResponseCompression{
+			Enabled:      true,
+			AllowPaths:   []string{"/cache"},
+			DenyPaths:    []string{"/status"},
+			MinSizeBytes: 1024,
+		},
+		RequestDecompression: RequestDecompression{
+			Enabled:      true,
+			MaxSizeBytes: 204800,
+		},
+		PutQuotas: PutQuotas{
+			Enabled:       true,
+			Header:        "X-Partner-Key",
+			WindowSeconds: 60,
+			Quotas: []PutQuota{
+				{APIKey: "partner-a", MaxEntries: 1000},
+				{APIKey: "partner-b", MaxEntries: 100, MaxBytes: 1048576},
+			},
+		},
+		Auth: Auth{
+			Enabled:           true,
+			AllowedCIDRs:      []string{"10.0.0.0/8", "2001:db8::/32"},
+			TrustedProxyDepth: 1,
+		},
+		CORS: CORS{
+			Enabled:        true,
+			AllowedOrigins: []string{"https://*.publisher.com", "https://player.example.com"},
+			AllowedMethods: []string{"GET"},
+			AllowedHeaders: []string{"X-Requested-With"},
+			MaxAgeSeconds:  3600,
+		},
+		Correlation: Correlation{
+			Enabled:        true,
+			ClientIDHeader: "X-Partner-Id",
+		},
+		RequestID: RequestID{
+			Header: "X-Correlation-Id",
+		},
+		ClientDeadlines: ClientDeadlines{
+			Enabled:   true,
+			Header:    "X-Partner-Deadline-Ms",
+			MaxMillis: 300,
+		},
+		Tracing: Tracing{
+			Enabled:      true,
+			SampleRate:   0.05,
+			OTLPEndpoint: "http://otel-collector:4318",
+		},
+		Server: Server{
+			ReadTimeoutMillis:  5000,
+			WriteTimeoutMillis: 10000,
+			IdleTimeoutMillis:  120000,
+		},
+		TLS: TLS{
+			Enabled:  true,
+			CertFile: "/etc/prebid-cache/tls.crt",
+			KeyFile:  "/etc/prebid-cache/tls.key",
+		},
+		Encryption: Encryption{
+			Enabled: true,
+			Key:     "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		},
+		FileDescriptors: FileDescriptors{
+			Enabled:              true,
+			WarnFraction:         0.9,
+			CheckIntervalSeconds: 10,
+		},
+		Priming: Priming{
+			ServeKeys:     true,
+			Enabled:       true,
+			PeerURL:       "http://10.0.0.1:2525",
+			BatchSize:     20,
+			Workers:       2,
+			TimeoutMillis: 30000,
+		},
+		Standby: Standby{
+			Enabled: true,
+			Backend: Backend{
+				Type: BackendRedis,
+				Redis: Redis{
+					Host: "standby-redis.prebid.com",
+					Port: 6379,
+				},
+			},
+			QueueSize:     5000,
+			Workers:       4,
+			MaxAttempts:   5,
+			BackoffMillis: 250,
+		},
+	}
+}
+
+func TestCorrelationValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inCorrelation   *Correlation
+		inValueVersion  int
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Correlation disabled, nothing gets logged",
+			inCorrelation:   &Correlation{ClientIDHeader: "X-Client-Id"},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description:    "Correlation enabled on versioned values",
+			inCorrelation:  &Correlation{Enabled: true, ClientIDHeader: "X-Client-Id"},
+			inValueVersion: 1,
+			expectedLogInfo: []logComponents{
+				{msg: "config.correlation.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.correlation.client_id_header: X-Client-Id", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Correlation enabled on legacy values, expect fatal level log entry",
+			inCorrelation:  &Correlation{Enabled: true, ClientIDHeader: "X-Client-Id"},
+			inValueVersion: 0,
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.correlation.enabled: correlation needs config.backend.value_version to be 1 or higher.", lvl: logrus.FatalLevel},
+				{msg: "config.correlation.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.correlation.client_id_header: X-Client-Id", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:    "Correlation enabled without a header, expect fatal level log entry",
+			inCorrelation:  &Correlation{Enabled: true},
+			inValueVersion: 1,
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.correlation.client_id_header: it must not be empty when correlation is enabled.", lvl: logrus.FatalLevel},
+				{msg: "config.correlation.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.correlation.client_id_header: ", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inCorrelation.validateAndLog(tc.inValueVersion)
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestRequestIDValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inRequestID     *RequestID
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Request ids disabled, nothing gets logged",
+			inRequestID:     &RequestID{Header: "X-Request-ID"},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Request ids enabled",
+			inRequestID: &RequestID{Enabled: true, Header: "X-Request-ID"},
+			expectedLogInfo: []logComponents{
+				{msg: "config.request_id.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.request_id.header: X-Request-ID", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Request ids enabled without a header, expect fatal level log entry",
+			inRequestID: &RequestID{Enabled: true},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.request_id.header: it must not be empty when request ids are enabled.", lvl: logrus.FatalLevel},
+				{msg: "config.request_id.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.request_id.header: ", lvl: logrus.InfoLevel},
+			},
 		},
 	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inRequestID.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestClientDeadlinesValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description       string
+		inClientDeadlines *ClientDeadlines
+		expectedLogInfo   []logComponents
+	}{
+		{
+			description:       "Client deadlines disabled, nothing gets logged",
+			inClientDeadlines: &ClientDeadlines{Header: "X-Deadline-Ms", MaxMillis: 500},
+			expectedLogInfo:   []logComponents{},
+		},
+		{
+			description:       "Client deadlines enabled",
+			inClientDeadlines: &ClientDeadlines{Enabled: true, Header: "X-Deadline-Ms", MaxMillis: 500},
+			expectedLogInfo: []logComponents{
+				{msg: "config.client_deadlines.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.client_deadlines.header: X-Deadline-Ms", lvl: logrus.InfoLevel},
+				{msg: "config.client_deadlines.max_ms: 500", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:       "Client deadlines enabled without a header, expect fatal level log entry",
+			inClientDeadlines: &ClientDeadlines{Enabled: true, MaxMillis: 500},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.client_deadlines.header: it must not be empty when client deadlines are enabled.", lvl: logrus.FatalLevel},
+				{msg: "config.client_deadlines.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.client_deadlines.header: ", lvl: logrus.InfoLevel},
+				{msg: "config.client_deadlines.max_ms: 500", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:       "Client deadlines enabled without a max, expect fatal level log entry",
+			inClientDeadlines: &ClientDeadlines{Enabled: true, Header: "X-Deadline-Ms"},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.client_deadlines.max_ms: 0. It must be positive.", lvl: logrus.FatalLevel},
+				{msg: "config.client_deadlines.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.client_deadlines.header: X-Deadline-Ms", lvl: logrus.InfoLevel},
+				{msg: "config.client_deadlines.max_ms: 0", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inClientDeadlines.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestTracingValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inTracing       *Tracing
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Tracing disabled, nothing gets logged",
+			inTracing:       &Tracing{SampleRate: 2},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Tracing enabled",
+			inTracing:   &Tracing{Enabled: true, SampleRate: 0.1},
+			expectedLogInfo: []logComponents{
+				{msg: "config.tracing.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.sample_rate: 0.1", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.otlp_endpoint: ", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Tracing enabled with an OTLP endpoint",
+			inTracing:   &Tracing{Enabled: true, SampleRate: 0.1, OTLPEndpoint: "https://collector:4318/v1/traces"},
+			expectedLogInfo: []logComponents{
+				{msg: "config.tracing.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.sample_rate: 0.1", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.otlp_endpoint: https://collector:4318/v1/traces", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "OTLP endpoint without a scheme, expect fatal level log entry",
+			inTracing:   &Tracing{Enabled: true, SampleRate: 0.1, OTLPEndpoint: "collector:4318"},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.tracing.otlp_endpoint: collector:4318. It must be an http or https URL.", lvl: logrus.FatalLevel},
+				{msg: "config.tracing.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.sample_rate: 0.1", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.otlp_endpoint: collector:4318", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Sample rate above 1, expect fatal level log entry",
+			inTracing:   &Tracing{Enabled: true, SampleRate: 1.5},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.tracing.sample_rate: 1.5. It must be between 0 and 1.", lvl: logrus.FatalLevel},
+				{msg: "config.tracing.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.sample_rate: 1.5", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.otlp_endpoint: ", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Negative sample rate, expect fatal level log entry",
+			inTracing:   &Tracing{Enabled: true, SampleRate: -0.5},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.tracing.sample_rate: -0.5. It must be between 0 and 1.", lvl: logrus.FatalLevel},
+				{msg: "config.tracing.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.sample_rate: -0.5", lvl: logrus.InfoLevel},
+				{msg: "config.tracing.otlp_endpoint: ", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inTracing.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestServerValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inServer        *Server
+		expectedLogInfo []logComponents
+	}{
+		{
+			description: "Valid timeouts",
+			inServer:    &Server{ReadTimeoutMillis: 5000, WriteTimeoutMillis: 10000, IdleTimeoutMillis: 120000},
+			expectedLogInfo: []logComponents{
+				{msg: "config.server.read_timeout_ms: 5000", lvl: logrus.InfoLevel},
+				{msg: "config.server.write_timeout_ms: 10000", lvl: logrus.InfoLevel},
+				{msg: "config.server.idle_timeout_ms: 120000", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Timeouts that would let clients hold connections forever, expect fatal level log entries",
+			inServer:    &Server{ReadTimeoutMillis: 0, WriteTimeoutMillis: -1, IdleTimeoutMillis: 0},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.server.read_timeout_ms: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.server.write_timeout_ms: -1. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.server.idle_timeout_ms: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "config.server.read_timeout_ms: 0", lvl: logrus.InfoLevel},
+				{msg: "config.server.write_timeout_ms: -1", lvl: logrus.InfoLevel},
+				{msg: "config.server.idle_timeout_ms: 0", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inServer.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	cfg := Server{ReadTimeoutMillis: 5000, WriteTimeoutMillis: 1500, IdleTimeoutMillis: 120000}
+
+	assert.Equal(t, 5*time.Second, cfg.ReadTimeout())
+	assert.Equal(t, 1500*time.Millisecond, cfg.WriteTimeout())
+	assert.Equal(t, 2*time.Minute, cfg.IdleTimeout())
+}
+
+func TestClientRateLimitValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inLimit         *ClientRateLimit
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Limit disabled, nothing gets logged",
+			inLimit:         &ClientRateLimit{PutsPerSecond: -1},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Valid limit",
+			inLimit:     &ClientRateLimit{Enabled: true, PutsPerSecond: 0.5, Burst: 3, MaxClients: 100, APIKeyHeader: "X-Api-Key"},
+			expectedLogInfo: []logComponents{
+				{msg: "config.rate_limiter.per_client_puts.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.puts_per_second: 0.5", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.burst: 3", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.max_clients: 100", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.api_key_header: X-Api-Key", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.api_keys: 0 keys", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Limit that would reject every PUT or grow without bounds, expect fatal level log entries",
+			inLimit:     &ClientRateLimit{Enabled: true},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.rate_limiter.per_client_puts.puts_per_second: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.rate_limiter.per_client_puts.burst: 0. It must be at least 1.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.rate_limiter.per_client_puts.max_clients: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "config.rate_limiter.per_client_puts.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.puts_per_second: 0", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.burst: 0", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.max_clients: 0", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.api_key_header: ", lvl: logrus.InfoLevel},
+				{msg: "config.rate_limiter.per_client_puts.api_keys: 0 keys", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inLimit.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestAuthValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inAuth          *Auth
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Auth disabled, nothing gets logged",
+			inAuth:          &Auth{AllowedCIDRs: []string{"not a cidr"}},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Valid networks",
+			inAuth:      &Auth{Enabled: true, AllowedCIDRs: []string{"10.0.0.0/8", "2001:db8::/32"}, TrustedProxyDepth: 2},
+			expectedLogInfo: []logComponents{
+				{msg: "config.auth.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.auth.allowed_cidrs: [10.0.0.0/8 2001:db8::/32]", lvl: logrus.InfoLevel},
+				{msg: "config.auth.trusted_proxy_depth: 2", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "No networks would reject every write, expect fatal level log entry",
+			inAuth:      &Auth{Enabled: true},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.auth.allowed_cidrs: it must not be empty when auth is enabled.", lvl: logrus.FatalLevel},
+				{msg: "config.auth.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.auth.allowed_cidrs: []", lvl: logrus.InfoLevel},
+				{msg: "config.auth.trusted_proxy_depth: 0", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Unparsable network and negative depth, expect fatal level log entries",
+			inAuth:      &Auth{Enabled: true, AllowedCIDRs: []string{"10.0.0.1"}, TrustedProxyDepth: -1},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.auth.allowed_cidrs: invalid CIDR address: 10.0.0.1", lvl: logrus.FatalLevel},
+				{msg: "invalid config.auth.trusted_proxy_depth: -1. It must not be negative.", lvl: logrus.FatalLevel},
+				{msg: "config.auth.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.auth.allowed_cidrs: [10.0.0.1]", lvl: logrus.InfoLevel},
+				{msg: "config.auth.trusted_proxy_depth: -1", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inAuth.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestCORSValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inCORS          *CORS
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "CORS disabled, nothing gets logged",
+			inCORS:          &CORS{MaxAgeSeconds: -1},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Valid CORS",
+			inCORS:      &CORS{Enabled: true, AllowedOrigins: []string{"https://*.example.com"}, AllowedMethods: []string{"GET", "HEAD"}, MaxAgeSeconds: 600},
+			expectedLogInfo: []logComponents{
+				{msg: "config.cors.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.cors.allowed_origins: [https://*.example.com]", lvl: logrus.InfoLevel},
+				{msg: "config.cors.allowed_methods: [GET HEAD]", lvl: logrus.InfoLevel},
+				{msg: "config.cors.allowed_headers: []", lvl: logrus.InfoLevel},
+				{msg: "config.cors.max_age_seconds: 600", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "No origins, no methods and a negative max age, expect fatal level log entries",
+			inCORS:      &CORS{Enabled: true, MaxAgeSeconds: -1},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.cors.allowed_origins: it must not be empty when CORS is enabled.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.cors.allowed_methods: it must not be empty when CORS is enabled.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.cors.max_age_seconds: -1. It must not be negative.", lvl: logrus.FatalLevel},
+				{msg: "config.cors.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.cors.allowed_origins: []", lvl: logrus.InfoLevel},
+				{msg: "config.cors.allowed_methods: []", lvl: logrus.InfoLevel},
+				{msg: "config.cors.allowed_headers: []", lvl: logrus.InfoLevel},
+				{msg: "config.cors.max_age_seconds: -1", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inCORS.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestTLSValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inTLS           *TLS
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "TLS disabled, nothing gets logged",
+			inTLS:           &TLS{},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "TLS enabled",
+			inTLS:       &TLS{Enabled: true, CertFile: "tls.crt", KeyFile: "tls.key"},
+			expectedLogInfo: []logComponents{
+				{msg: "config.tls.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tls.cert_file: tls.crt", lvl: logrus.InfoLevel},
+				{msg: "config.tls.key_file: tls.key", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Key file missing, expect fatal level log entry",
+			inTLS:       &TLS{Enabled: true, CertFile: "tls.crt"},
+			expectedLogInfo: []logComponents{
+				{msg: "config.tls.cert_file and config.tls.key_file are required to serve over TLS", lvl: logrus.FatalLevel},
+				{msg: "config.tls.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.tls.cert_file: tls.crt", lvl: logrus.InfoLevel},
+				{msg: "config.tls.key_file: ", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inTLS.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestEncryptionValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	keyFile, err := ioutil.TempFile("", "encryption.key")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.Remove(keyFile.Name())
+	keyFile.WriteString("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n")
+	keyFile.Close()
+
+	testCases := []struct {
+		description     string
+		inEncryption    *Encryption
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Encryption disabled, nothing gets logged",
+			inEncryption:    &Encryption{Key: "not base64"},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description:  "Key set in the config",
+			inEncryption: &Encryption{Enabled: true, Key: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+			expectedLogInfo: []logComponents{
+				{msg: "config.encryption.enabled: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:  "Key read from a file",
+			inEncryption: &Encryption{Enabled: true, KeyFile: keyFile.Name()},
+			expectedLogInfo: []logComponents{
+				{msg: "config.encryption.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.encryption.key_file: " + keyFile.Name(), lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:  "No key, expect fatal level log entry",
+			inEncryption: &Encryption{Enabled: true},
+			expectedLogInfo: []logComponents{
+				{msg: "Exactly one of config.encryption.key and config.encryption.key_file is required to encrypt values", lvl: logrus.FatalLevel},
+				{msg: "config.encryption.enabled: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:  "Key of the wrong length, expect fatal level log entry",
+			inEncryption: &Encryption{Enabled: true, Key: "c2hvcnQ="},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.encryption key: the key is 5 bytes long. It must be 16, 24 or 32 bytes long.", lvl: logrus.FatalLevel},
+				{msg: "config.encryption.enabled: true", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inEncryption.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestFileDescriptorsValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inFDs           *FileDescriptors
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Guard disabled, nothing gets logged",
+			inFDs:           &FileDescriptors{},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Guard enabled",
+			inFDs:       &FileDescriptors{Enabled: true, WarnFraction: 0.8, CheckIntervalSeconds: 30},
+			expectedLogInfo: []logComponents{
+				{msg: "config.file_descriptors.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.file_descriptors.warn_fraction: 0.8", lvl: logrus.InfoLevel},
+				{msg: "config.file_descriptors.check_interval_seconds: 30", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Fraction over the whole limit, expect fatal level log entry",
+			inFDs:       &FileDescriptors{Enabled: true, WarnFraction: 1.5, CheckIntervalSeconds: 30},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.file_descriptors.warn_fraction: 1.5. It must be greater than 0 and no more than 1.", lvl: logrus.FatalLevel},
+				{msg: "config.file_descriptors.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.file_descriptors.warn_fraction: 1.5", lvl: logrus.InfoLevel},
+				{msg: "config.file_descriptors.check_interval_seconds: 30", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "No interval, expect fatal level log entry",
+			inFDs:       &FileDescriptors{Enabled: true, WarnFraction: 0.8},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.file_descriptors.check_interval_seconds: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "config.file_descriptors.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.file_descriptors.warn_fraction: 0.8", lvl: logrus.InfoLevel},
+				{msg: "config.file_descriptors.check_interval_seconds: 0", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inFDs.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestPrimingValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description     string
+		inPriming       *Priming
+		expectedLogInfo []logComponents
+	}{
+		{
+			description:     "Priming disabled, nothing gets logged",
+			inPriming:       &Priming{BatchSize: -1},
+			expectedLogInfo: []logComponents{},
+		},
+		{
+			description: "Keys served to peers",
+			inPriming:   &Priming{ServeKeys: true},
+			expectedLogInfo: []logComponents{
+				{msg: "config.priming.serve_keys: true", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "Priming enabled",
+			inPriming:   &Priming{Enabled: true, PeerURL: "http://10.0.0.1:2525", BatchSize: 10, Workers: 4, TimeoutMillis: 60000},
+			expectedLogInfo: []logComponents{
+				{msg: "config.priming.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.priming.peer_url: http://10.0.0.1:2525", lvl: logrus.InfoLevel},
+				{msg: "config.priming.batch_size: 10", lvl: logrus.InfoLevel},
+				{msg: "config.priming.workers: 4", lvl: logrus.InfoLevel},
+				{msg: "config.priming.timeout_ms: 60000", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description: "No peer, batch size, workers or timeout, expect fatal level log entries",
+			inPriming:   &Priming{Enabled: true},
+			expectedLogInfo: []logComponents{
+				{msg: "config.priming.peer_url is required to prime the backend", lvl: logrus.FatalLevel},
+				{msg: "invalid config.priming.batch_size: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.priming.workers: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "invalid config.priming.timeout_ms: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "config.priming.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.priming.peer_url: ", lvl: logrus.InfoLevel},
+				{msg: "config.priming.batch_size: 0", lvl: logrus.InfoLevel},
+				{msg: "config.priming.workers: 0", lvl: logrus.InfoLevel},
+				{msg: "config.priming.timeout_ms: 0", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inPriming.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestResponseCompressionValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description           string
+		inResponseCompression *ResponseCompression
+		expectedLogInfo       []logComponents
+	}{
+		{
+			description:           "Response compression disabled, nothing gets logged",
+			inResponseCompression: &ResponseCompression{MinSizeBytes: -1},
+			expectedLogInfo:       []logComponents{},
+		},
+		{
+			description:           "Response compression enabled",
+			inResponseCompression: &ResponseCompression{Enabled: true, AllowPaths: []string{"/cache"}, MinSizeBytes: 1024},
+			expectedLogInfo: []logComponents{
+				{msg: "config.response_compression.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.response_compression.allow_paths: [/cache]", lvl: logrus.InfoLevel},
+				{msg: "config.response_compression.deny_paths: []", lvl: logrus.InfoLevel},
+				{msg: "config.response_compression.min_size_bytes: 1024", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:           "Negative min size, expect fatal level log entry",
+			inResponseCompression: &ResponseCompression{Enabled: true, MinSizeBytes: -1},
+			expectedLogInfo: []logComponents{
+				{msg: "config.response_compression.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.response_compression.allow_paths: []", lvl: logrus.InfoLevel},
+				{msg: "config.response_compression.deny_paths: []", lvl: logrus.InfoLevel},
+				{msg: "invalid config.response_compression.min_size_bytes: -1. It must not be negative.", lvl: logrus.FatalLevel},
+				{msg: "config.response_compression.min_size_bytes: -1", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inResponseCompression.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
+}
+
+func TestRequestDecompressionValidateAndLog(t *testing.T) {
+	// logrus entries will be recorded to this `hook` object so we can compare and assert them
+	hook := test.NewGlobal()
+
+	type logComponents struct {
+		msg string
+		lvl logrus.Level
+	}
+
+	testCases := []struct {
+		description            string
+		inRequestDecompression *RequestDecompression
+		expectedLogInfo        []logComponents
+	}{
+		{
+			description:            "Request decompression disabled, nothing gets logged",
+			inRequestDecompression: &RequestDecompression{},
+			expectedLogInfo:        []logComponents{},
+		},
+		{
+			description:            "Request decompression enabled",
+			inRequestDecompression: &RequestDecompression{Enabled: true, MaxSizeBytes: 1048576},
+			expectedLogInfo: []logComponents{
+				{msg: "config.request_decompression.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.request_decompression.max_size_bytes: 1048576", lvl: logrus.InfoLevel},
+			},
+		},
+		{
+			description:            "No max size, expect fatal level log entry",
+			inRequestDecompression: &RequestDecompression{Enabled: true},
+			expectedLogInfo: []logComponents{
+				{msg: "invalid config.request_decompression.max_size_bytes: 0. It must be greater than zero.", lvl: logrus.FatalLevel},
+				{msg: "config.request_decompression.enabled: true", lvl: logrus.InfoLevel},
+				{msg: "config.request_decompression.max_size_bytes: 0", lvl: logrus.InfoLevel},
+			},
+		},
+	}
+
+	//substitute logger exit function so execution doesn't get interrupted
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	logrus.StandardLogger().ExitFunc = func(int) {}
+
+	for _, tc := range testCases {
+		// Run test
+		tc.inRequestDecompression.validateAndLog()
+
+		// Assert logrus expected entries
+		if assert.Len(t, hook.Entries, len(tc.expectedLogInfo), tc.description) {
+			for i := 0; i < len(tc.expectedLogInfo); i++ {
+				assert.Equal(t, tc.expectedLogInfo[i].msg, hook.Entries[i].Message, tc.description+":message")
+				assert.Equal(t, tc.expectedLogInfo[i].lvl, hook.Entries[i].Level, tc.description+":log level")
+			}
+		}
+
+		//Reset log after every test and assert successful reset
+		hook.Reset()
+		assert.Nil(t, hook.LastEntry())
+	}
 }
@@ -2,3 +2,17 @@ package endpoints
 
 // Status code for errors due to a downstream dependency timeout.
 const HttpDependencyTimeout = 597
+
+// Headers GET /cache describes the stored value with, if enabled
+const (
+	MetadataFormatHeader       = "X-Cache-Format"
+	MetadataSizeHeader         = "X-Cache-Size"
+	MetadataCreatedHeader      = "X-Cache-Created"
+	MetadataTTLRemainingHeader = "X-Cache-TTL-Remaining"
+)
+
+// Headers GET /cache tells downstream caches how fresh the stored value is with, if enabled
+const (
+	AgeHeader          = "Age"
+	CacheControlHeader = "Cache-Control"
+)
@@ -0,0 +1,82 @@
+package decorators
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// ForwardedForHeader lists the addresses of the client and of every proxy but the last one a request
+// went through
+const ForwardedForHeader = "X-Forwarded-For"
+
+// AllowWriters rejects with a 403 the requests of clients outside the networks of cfg. Rejected
+// requests are counted as forbidden rejections. The handler is returned untouched if auth is disabled.
+func AllowWriters(handler httprouter.Handle, m *metrics.Metrics, cfg config.Auth) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+	networks, err := cfg.AllowedNetworks()
+	if err != nil {
+		log.Fatalf("invalid config.auth.allowed_cidrs: %v", err)
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		ip := clientIP(req, cfg.TrustedProxyDepth)
+		if !containsIP(networks, ip) {
+			Logger(req.Context()).Debugf("%s %s rejected for client %v", req.Method, req.URL.Path, ip)
+			m.RecordRejectedRequest(metrics.RejectedForbidden)
+			utils.WriteError(resp, "This client isn't allowed to write values.", http.StatusForbidden)
+			return
+		}
+		handler(resp, req, params)
+	}
+}
+
+// clientIP returns the address of the client behind trustedDepth proxies. Each proxy appends the
+// address of its peer to the X-Forwarded-For header, so the client is the entry the farthest trusted
+// proxy appended. Anything left of it came from the client and could be spoofed. Returns nil if the
+// address can't be parsed.
+func clientIP(req *http.Request, trustedDepth int) net.IP {
+	if trustedDepth == 0 {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			host = req.RemoteAddr
+		}
+		return net.ParseIP(host)
+	}
+
+	var forwarded []string
+	for _, header := range req.Header[ForwardedForHeader] {
+		for _, addr := range strings.Split(header, ",") {
+			forwarded = append(forwarded, strings.TrimSpace(addr))
+		}
+	}
+	if len(forwarded) == 0 {
+		return nil
+	}
+	// With fewer entries than trusted proxies, the first one was appended by a trusted proxy too
+	i := len(forwarded) - trustedDepth
+	if i < 0 {
+		i = 0
+	}
+	return net.ParseIP(forwarded[i])
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowWriters(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		inDepth          int
+		inRemoteAddr     string
+		inForwardedFor   []string
+		expectedStatus   int
+		expectedRejected int64
+	}{
+		{
+			desc:           "Peer within an allowed network",
+			inRemoteAddr:   "10.1.2.3:51000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:             "Peer outside the allowed networks",
+			inRemoteAddr:     "203.0.113.7:51000",
+			expectedStatus:   http.StatusForbidden,
+			expectedRejected: 1,
+		},
+		{
+			desc:             "Forwarded header is ignored without trusted proxies",
+			inRemoteAddr:     "203.0.113.7:51000",
+			inForwardedFor:   []string{"10.1.2.3"},
+			expectedStatus:   http.StatusForbidden,
+			expectedRejected: 1,
+		},
+		{
+			desc:           "Client behind a trusted proxy",
+			inDepth:        1,
+			inRemoteAddr:   "192.168.0.1:51000",
+			inForwardedFor: []string{"10.1.2.3"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:             "Entries left of the trusted proxies are spoofable",
+			inDepth:          1,
+			inRemoteAddr:     "192.168.0.1:51000",
+			inForwardedFor:   []string{"10.1.2.3, 203.0.113.7"},
+			expectedStatus:   http.StatusForbidden,
+			expectedRejected: 1,
+		},
+		{
+			desc:           "Entries of several headers behind two trusted proxies",
+			inDepth:        2,
+			inRemoteAddr:   "192.168.0.2:51000",
+			inForwardedFor: []string{"198.51.100.1, 2001:db8::1", "192.168.0.1"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:             "Missing forwarded header behind a trusted proxy",
+			inDepth:          1,
+			inRemoteAddr:     "10.1.2.3:51000",
+			expectedStatus:   http.StatusForbidden,
+			expectedRejected: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		handler := AllowWriters(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+			w.WriteHeader(http.StatusOK)
+		}, m, config.Auth{
+			Enabled:           true,
+			AllowedCIDRs:      []string{"10.0.0.0/8", "2001:db8::/32"},
+			TrustedProxyDepth: tc.inDepth,
+		})
+
+		req := httptest.NewRequest("POST", "/cache", nil)
+		req.RemoteAddr = tc.inRemoteAddr
+		for _, forwarded := range tc.inForwardedFor {
+			req.Header.Add(ForwardedForHeader, forwarded)
+		}
+		resp := httptest.NewRecorder()
+		handler(resp, req, nil)
+
+		assert.Equal(t, tc.expectedStatus, resp.Code, tc.desc)
+		assert.Equal(t, tc.expectedRejected, metricstest.MockCounters["requests.rejected.forbidden"], tc.desc)
+	}
+}
+
+func TestAllowWritersDisabled(t *testing.T) {
+	called := false
+	handler := AllowWriters(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		called = true
+	}, metricstest.CreateMockMetrics(), config.Auth{AllowedCIDRs: []string{"10.0.0.0/8"}})
+
+	req := httptest.NewRequest("POST", "/cache", nil)
+	req.RemoteAddr = "203.0.113.7:51000"
+	handler(httptest.NewRecorder(), req, nil)
+
+	assert.True(t, called, "Every client should be allowed to write when auth is disabled")
+}
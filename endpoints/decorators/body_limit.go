@@ -0,0 +1,62 @@
+package decorators
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// ErrRequestBodyTooLarge is what reading the body of a request limited by LimitRequestBodies returns
+// once the body goes over the limit.
+var ErrRequestBodyTooLarge = errors.New("Request body too large")
+
+// LimitRequestBodies rejects requests whose body is larger than maxBytes with a 413. Requests that
+// declare a larger Content-Length are rejected before their body is read. The bodies of the others,
+// including chunked ones that don't declare their length, are cut off at maxBytes so that handlers
+// reading them fail with ErrRequestBodyTooLarge. A maxBytes of zero or less doesn't limit the handler
+// at all.
+func LimitRequestBodies(handler httprouter.Handle, maxBytes int) httprouter.Handle {
+	if maxBytes <= 0 {
+		return handler
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if req.ContentLength > int64(maxBytes) {
+			utils.WriteError(resp, ErrRequestBodyTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		req.Body = &limitedBody{
+			ReadCloser: http.MaxBytesReader(resp, req.Body, int64(maxBytes)),
+			remaining:  int64(maxBytes),
+		}
+		handler(resp, req, params)
+	}
+}
+
+// limitedBody turns the error http.MaxBytesReader returns past the limit into ErrRequestBodyTooLarge,
+// which, unlike that one, callers can compare against.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if err != nil && err != io.EOF && b.remaining <= 0 {
+		return n, ErrRequestBodyTooLarge
+	}
+	return n, err
+}
+
+// writeBodyReadError responds to requests whose body couldn't be read
+func writeBodyReadError(resp http.ResponseWriter, err error) {
+	if err == ErrRequestBodyTooLarge {
+		utils.WriteError(resp, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	utils.WriteError(resp, "Failed to read the request body.", http.StatusBadRequest)
+}
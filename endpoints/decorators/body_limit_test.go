@@ -0,0 +1,105 @@
+package decorators
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// chunkedBody hides the type of its reader so that httptest.NewRequest can't tell the length of the
+// body, like it happens with chunked requests
+type chunkedBody struct {
+	io.Reader
+}
+
+func TestLimitRequestBodies(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		inMaxBytes       int
+		inBody           io.Reader
+		expectedCalled   bool
+		expectedStatus   int
+		expectedReadBody string
+		expectedReadErr  error
+	}{
+		{
+			desc:           "Declared Content-Length over the limit is rejected before the handler runs",
+			inMaxBytes:     10,
+			inBody:         strings.NewReader("more than ten bytes"),
+			expectedCalled: false,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:             "Declared Content-Length within the limit",
+			inMaxBytes:       10,
+			inBody:           strings.NewReader("ten bytes!"),
+			expectedCalled:   true,
+			expectedStatus:   http.StatusOK,
+			expectedReadBody: "ten bytes!",
+		},
+		{
+			desc:             "Chunked body within the limit",
+			inMaxBytes:       10,
+			inBody:           chunkedBody{strings.NewReader("short")},
+			expectedCalled:   true,
+			expectedStatus:   http.StatusOK,
+			expectedReadBody: "short",
+		},
+		{
+			desc:             "Chunked body over the limit is cut off",
+			inMaxBytes:       10,
+			inBody:           chunkedBody{strings.NewReader("more than ten bytes")},
+			expectedCalled:   true,
+			expectedStatus:   http.StatusOK,
+			expectedReadBody: "more than ",
+			expectedReadErr:  ErrRequestBodyTooLarge,
+		},
+		{
+			desc:             "No limit",
+			inMaxBytes:       0,
+			inBody:           chunkedBody{strings.NewReader("more than ten bytes")},
+			expectedCalled:   true,
+			expectedStatus:   http.StatusOK,
+			expectedReadBody: "more than ten bytes",
+		},
+	}
+
+	for _, tc := range testCases {
+		var called bool
+		var readBody []byte
+		var readErr error
+		handler := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			called = true
+			readBody, readErr = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		}
+
+		rr := httptest.NewRecorder()
+		LimitRequestBodies(handler, tc.inMaxBytes)(rr, httptest.NewRequest("POST", "/cache", tc.inBody), nil)
+
+		assert.Equal(t, tc.expectedCalled, called, tc.desc)
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		if tc.expectedCalled {
+			assert.Equal(t, tc.expectedReadBody, string(readBody), tc.desc)
+			assert.Equal(t, tc.expectedReadErr, readErr, tc.desc)
+		}
+	}
+}
+
+func TestLimitRequestBodiesWithPutQuotas(t *testing.T) {
+	handler := LimitRequestBodies(EnforcePutQuotas(storedOK, metricstest.CreateMockMetrics(), testPutQuotas), 10)
+
+	req := httptest.NewRequest("POST", "/cache", chunkedBody{strings.NewReader(`{"puts":[{"type":"json","value":"some-value"}]}`)})
+	req.Header.Set("X-Api-Key", "small")
+	rr := httptest.NewRecorder()
+	handler(rr, req, nil)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, "Reading a body over the limit to count it against a quota should get a 413")
+}
@@ -0,0 +1,139 @@
+package decorators
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/prebid/prebid-cache/config"
+)
+
+// CompressResponses gzips the responses of the requests whose path is allowed by cfg, as long as the
+// client accepts gzip. A path is allowed when it doesn't start with any of cfg.DenyPaths and either
+// cfg.AllowPaths is empty or the path starts with one of them. Bodies shorter than cfg.MinSizeBytes are
+// sent uncompressed.
+func CompressResponses(next http.Handler, cfg config.ResponseCompression) http.Handler {
+	if !cfg.Enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if !acceptsGzip(req) || !compressPath(req.URL.Path, cfg) {
+			next.ServeHTTP(resp, req)
+			return
+		}
+
+		gzipWriter := &gzipResponseWriter{delegate: resp, minSize: cfg.MinSizeBytes}
+		defer gzipWriter.close()
+		next.ServeHTTP(gzipWriter, req)
+	})
+}
+
+func compressPath(path string, cfg config.ResponseCompression) bool {
+	for _, prefix := range cfg.DenyPaths {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	if len(cfg.AllowPaths) == 0 {
+		return true
+	}
+	for _, prefix := range cfg.AllowPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body written by the handler. The status code is held back until
+// minSize bytes of body have been written, so that shorter bodies can be sent uncompressed. Responses
+// that can't carry a body, like a 204 or a 304, are passed through untouched.
+type gzipResponseWriter struct {
+	delegate    http.ResponseWriter
+	minSize     int
+	gz          *gzip.Writer
+	statusCode  int
+	buffer      []byte
+	wroteHeader bool
+	started     bool
+}
+
+func (w *gzipResponseWriter) Header() http.Header {
+	return w.delegate.Header()
+}
+
+func (w *gzipResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+
+	if statusCode < http.StatusOK || statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		w.start(false)
+	} else if w.minSize == 0 {
+		w.start(true)
+	}
+}
+
+func (w *gzipResponseWriter) Write(bytes []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.delegate.Header().Get("Content-Type") == "" {
+			w.delegate.Header().Set("Content-Type", http.DetectContentType(bytes))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.started {
+		if w.gz == nil {
+			return w.delegate.Write(bytes)
+		}
+		return w.gz.Write(bytes)
+	}
+
+	w.buffer = append(w.buffer, bytes...)
+	if len(w.buffer) >= w.minSize {
+		w.start(true)
+		buffered := w.buffer
+		w.buffer = nil
+		if _, err := w.gz.Write(buffered); err != nil {
+			return 0, err
+		}
+	}
+	return len(bytes), nil
+}
+
+// start sends the status code, along with the gzip headers if the body gets compressed
+func (w *gzipResponseWriter) start(compress bool) {
+	w.started = true
+	if compress {
+		header := w.delegate.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		w.gz = gzip.NewWriter(w.delegate)
+	}
+	w.delegate.WriteHeader(w.statusCode)
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.started {
+		w.start(false)
+		w.delegate.Write(w.buffer)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
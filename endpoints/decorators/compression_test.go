@@ -0,0 +1,190 @@
+package decorators
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCompressionTestHandler(cfg config.ResponseCompression) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cache", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"value":"some cached value"}`))
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/admin/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":1}`))
+	})
+	return CompressResponses(mux, cfg)
+}
+
+func TestCompressResponses(t *testing.T) {
+	cfg := config.ResponseCompression{
+		Enabled:    true,
+		AllowPaths: []string{"/cache"},
+		DenyPaths:  []string{"/admin"},
+	}
+
+	testCases := []struct {
+		desc             string
+		inPath           string
+		inAcceptEncoding string
+		expectGzip       bool
+		expectedBody     string
+	}{
+		{
+			desc:             "Allowed GET path gets compressed",
+			inPath:           "/cache",
+			inAcceptEncoding: "gzip, deflate",
+			expectGzip:       true,
+			expectedBody:     `{"value":"some cached value"}`,
+		},
+		{
+			desc:             "Allowed GET path isn't compressed for clients that don't accept gzip",
+			inPath:           "/cache",
+			inAcceptEncoding: "deflate",
+			expectedBody:     `{"value":"some cached value"}`,
+		},
+		{
+			desc:             "Denied admin path isn't compressed",
+			inPath:           "/admin/stats",
+			inAcceptEncoding: "gzip",
+			expectedBody:     `{"keys":1}`,
+		},
+		{
+			desc:             "Path that's not allowed isn't compressed",
+			inPath:           "/status",
+			inAcceptEncoding: "gzip",
+		},
+	}
+
+	handler := newCompressionTestHandler(cfg)
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.inPath, nil)
+		req.Header.Set("Accept-Encoding", tc.inAcceptEncoding)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		body := rec.Body.String()
+		if tc.expectGzip {
+			assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), tc.desc)
+			reader, err := gzip.NewReader(rec.Body)
+			if assert.NoError(t, err, tc.desc) {
+				decompressed, _ := ioutil.ReadAll(reader)
+				body = string(decompressed)
+			}
+		} else {
+			assert.Empty(t, rec.Header().Get("Content-Encoding"), tc.desc)
+		}
+		assert.Equal(t, tc.expectedBody, body, tc.desc)
+	}
+}
+
+func TestCompressResponsesDisabled(t *testing.T) {
+	handler := newCompressionTestHandler(config.ResponseCompression{Enabled: false})
+
+	req := httptest.NewRequest("GET", "/cache", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"value":"some cached value"}`, rec.Body.String())
+}
+
+func TestCompressResponsesNoContent(t *testing.T) {
+	handler := newCompressionTestHandler(config.ResponseCompression{Enabled: true})
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"), "Responses without body should not be compressed")
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestCompressResponsesMinSize(t *testing.T) {
+	largeBody := strings.Repeat(`{"value":"some cached value"}`, 100)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		// Written in chunks shorter than the threshold
+		for i := 0; i < 100; i++ {
+			w.Write([]byte(`{"value":"some cached value"}`))
+		}
+	})
+	handler := CompressResponses(mux, config.ResponseCompression{Enabled: true, MinSizeBytes: 1024})
+
+	testCases := []struct {
+		desc             string
+		inPath           string
+		inAcceptEncoding string
+		expectGzip       bool
+		expectedStatus   int
+		expectedBody     string
+	}{
+		{
+			desc:             "Body shorter than the threshold isn't compressed",
+			inPath:           "/small",
+			inAcceptEncoding: "gzip",
+			expectedStatus:   http.StatusNotFound,
+			expectedBody:     `{"error":"not found"}`,
+		},
+		{
+			desc:             "Body longer than the threshold gets compressed",
+			inPath:           "/large",
+			inAcceptEncoding: "gzip",
+			expectGzip:       true,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     largeBody,
+		},
+		{
+			desc:           "Body longer than the threshold isn't compressed for clients that don't accept gzip",
+			inPath:         "/large",
+			expectedStatus: http.StatusCreated,
+			expectedBody:   largeBody,
+		},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.inPath, nil)
+		req.Header.Set("Accept-Encoding", tc.inAcceptEncoding)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.expectedStatus, rec.Code, tc.desc)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), tc.desc)
+		body := rec.Body.String()
+		if tc.expectGzip {
+			assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), tc.desc)
+			assert.Less(t, rec.Body.Len(), len(largeBody), tc.desc)
+			reader, err := gzip.NewReader(rec.Body)
+			if assert.NoError(t, err, tc.desc) {
+				decompressed, _ := ioutil.ReadAll(reader)
+				body = string(decompressed)
+			}
+		} else {
+			assert.Empty(t, rec.Header().Get("Content-Encoding"), tc.desc)
+		}
+		assert.Equal(t, tc.expectedBody, body, tc.desc)
+	}
+}
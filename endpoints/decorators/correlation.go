@@ -0,0 +1,55 @@
+package decorators
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/envelope"
+)
+
+// maxClientIDLength keeps clients from growing every value they store through the client id header
+const maxClientIDLength = 128
+
+// CorrelateWrites stores the client id that PUT requests carry in the configured header along with every
+// value they write. Requests without it write their values as usual. The handler is returned untouched
+// if correlation is disabled.
+func CorrelateWrites(handler httprouter.Handle, cfg config.Correlation) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if clientID := req.Header.Get(cfg.ClientIDHeader); clientID != "" {
+			if len(clientID) > maxClientIDLength {
+				clientID = clientID[:maxClientIDLength]
+			}
+			req = req.WithContext(envelope.WithWriter(req.Context(), clientID))
+		}
+		handler(resp, req, params)
+	}
+}
+
+// CorrelateReads logs the client id that wrote the value a GET request reads, and how long before the
+// read it was written. Values written without a client id are not logged. The handler is returned
+// untouched if correlation is disabled.
+func CorrelateReads(handler httprouter.Handle, cfg config.Correlation) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		ctx, md := envelope.WithMetadata(req.Context())
+		handler(resp, req.WithContext(ctx), params)
+
+		if md.Writer != "" {
+			uuid := params.ByName("uuid")
+			if uuid == "" {
+				uuid = req.URL.Query().Get("uuid")
+			}
+			writeToRead := time.Since(md.CreatedAt) / time.Second
+			Logger(req.Context()).Infof("GET /cache uuid=%s: written by %s %d seconds before this read", uuid, md.Writer, writeToRead)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package decorators
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// HonorClientDeadlines lets requests set how long their backend operations may take through the
+// configured header, in milliseconds, counted from when the request arrives. Deadlines above
+// cfg.MaxMillis are lowered to it, and values other than a positive number are ignored. The handler is returned untouched if client deadlines are
+// disabled.
+func HonorClientDeadlines(handler httprouter.Handle, cfg config.ClientDeadlines) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if timeout, ok := clientTimeout(req.Header.Get(cfg.Header), cfg.MaxMillis); ok {
+			req = req.WithContext(WithClientDeadline(req.Context(), time.Now().Add(timeout)))
+		}
+		handler(resp, req, params)
+	}
+}
+
+func clientTimeout(header string, maxMillis int) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	millis, err := strconv.Atoi(header)
+	if err != nil || millis < 1 {
+		log.Debugf("Ignoring invalid client deadline: %q", header)
+		return 0, false
+	}
+	if millis > maxMillis {
+		millis = maxMillis
+	}
+	return time.Duration(millis) * time.Millisecond, true
+}
+
+type clientDeadlineKey struct{}
+
+// WithClientDeadline returns a copy of ctx that carries when the client stops waiting for the backend
+// operations of its request
+func WithClientDeadline(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, clientDeadlineKey{}, deadline)
+}
+
+// ClientDeadline returns the deadline set in ctx by WithClientDeadline, if any
+func ClientDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(clientDeadlineKey{}).(time.Time)
+	return deadline, ok
+}
@@ -0,0 +1,79 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHonorClientDeadlines(t *testing.T) {
+	cfg := config.ClientDeadlines{Enabled: true, Header: "X-Deadline-Ms", MaxMillis: 300}
+
+	testCases := []struct {
+		desc            string
+		inCfg           config.ClientDeadlines
+		inValue         string
+		expectedSet     bool
+		expectedTimeout time.Duration
+	}{
+		{
+			desc:            "Deadline within the max",
+			inCfg:           cfg,
+			inValue:         "50",
+			expectedSet:     true,
+			expectedTimeout: 50 * time.Millisecond,
+		},
+		{
+			desc:            "Deadline beyond the max is clamped",
+			inCfg:           cfg,
+			inValue:         "5000",
+			expectedSet:     true,
+			expectedTimeout: 300 * time.Millisecond,
+		},
+		{
+			desc:    "Zero deadlines are ignored",
+			inCfg:   cfg,
+			inValue: "0",
+		},
+		{
+			desc:    "Values other than numbers are ignored",
+			inCfg:   cfg,
+			inValue: "soon",
+		},
+		{
+			desc:    "Missing deadline",
+			inCfg:   cfg,
+			inValue: "",
+		},
+		{
+			desc:    "Deadlines are ignored when disabled",
+			inCfg:   config.ClientDeadlines{Header: "X-Deadline-Ms", MaxMillis: 300},
+			inValue: "50",
+		},
+	}
+
+	for _, tc := range testCases {
+		var set bool
+		var deadline time.Time
+		handler := HonorClientDeadlines(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			deadline, set = ClientDeadline(r.Context())
+		}, tc.inCfg)
+
+		req := httptest.NewRequest("GET", "/cache?uuid=foo", nil)
+		req.Header.Set("X-Deadline-Ms", tc.inValue)
+		arrival := time.Now()
+		handler(httptest.NewRecorder(), req, nil)
+
+		assert.Equal(t, tc.expectedSet, set, tc.desc)
+		if tc.expectedSet {
+			assert.WithinDuration(t, arrival.Add(tc.expectedTimeout), deadline, 10*time.Millisecond, "%s: the deadline should count from the request's arrival", tc.desc)
+		} else {
+			assert.True(t, deadline.IsZero(), tc.desc)
+		}
+	}
+}
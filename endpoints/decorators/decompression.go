@@ -0,0 +1,75 @@
+package decorators
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// DecompressRequestBodies lets clients send gzip request bodies, with a Content-Encoding: gzip header,
+// which get decompressed before the handler reads them. Everything past the handler, including the
+// backend size metrics, sees the decompressed body. Bodies that decompress to more than
+// cfg.MaxSizeBytes make handlers fail with ErrRequestBodyTooLarge, and bodies that aren't valid gzip
+// get a 400.
+func DecompressRequestBodies(handler httprouter.Handle, cfg config.RequestDecompression) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if !strings.EqualFold(strings.TrimSpace(req.Header.Get("Content-Encoding")), "gzip") {
+			handler(resp, req, params)
+			return
+		}
+
+		gz, err := gzip.NewReader(req.Body)
+		if err == ErrRequestBodyTooLarge {
+			writeBodyReadError(resp, err)
+			return
+		}
+		if err != nil {
+			utils.WriteError(resp, "Malformed gzip request body.", http.StatusBadRequest)
+			return
+		}
+		req.Body = &gzipBody{
+			gz:         gz,
+			compressed: req.Body,
+			remaining:  int64(cfg.MaxSizeBytes),
+		}
+		req.Header.Del("Content-Encoding")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		handler(resp, req, params)
+	}
+}
+
+// gzipBody decompresses a request body, failing with ErrRequestBodyTooLarge as soon as it decompresses
+// past the limit rather than once the whole body has been decompressed.
+type gzipBody struct {
+	gz         *gzip.Reader
+	compressed io.ReadCloser
+	remaining  int64
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	// Reading a byte past the limit tells bodies right at the limit apart from larger ones
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.gz.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, ErrRequestBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *gzipBody) Close() error {
+	b.gz.Close()
+	return b.compressed.Close()
+}
@@ -0,0 +1,108 @@
+package decorators
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipBytes(t *testing.T, body string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(body))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressRequestBodies(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		inBody            []byte
+		inContentEncoding string
+		expectedCalled    bool
+		expectedStatus    int
+		expectedReadBody  string
+		expectedReadErr   error
+	}{
+		{
+			desc:             "Uncompressed body is passed through",
+			inBody:           []byte("not compressed at all"),
+			expectedCalled:   true,
+			expectedStatus:   http.StatusOK,
+			expectedReadBody: "not compressed at all",
+		},
+		{
+			desc:              "Gzip body gets decompressed",
+			inBody:            gzipBytes(t, "ten bytes!"),
+			inContentEncoding: "GZIP",
+			expectedCalled:    true,
+			expectedStatus:    http.StatusOK,
+			expectedReadBody:  "ten bytes!",
+		},
+		{
+			desc:              "Gzip body that decompresses past the limit is cut off",
+			inBody:            gzipBytes(t, "more than ten bytes"),
+			inContentEncoding: "gzip",
+			expectedCalled:    true,
+			expectedStatus:    http.StatusOK,
+			expectedReadBody:  "more than t",
+			expectedReadErr:   ErrRequestBodyTooLarge,
+		},
+		{
+			desc:              "Malformed gzip body is rejected before the handler runs",
+			inBody:            []byte("not gzip at all"),
+			inContentEncoding: "gzip",
+			expectedCalled:    false,
+			expectedStatus:    http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		var called bool
+		var readBody []byte
+		var readErr error
+		var readEncoding string
+		handler := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			called = true
+			readEncoding = r.Header.Get("Content-Encoding")
+			readBody, readErr = ioutil.ReadAll(r.Body)
+		}
+		req := httptest.NewRequest("POST", "/cache", bytes.NewReader(tc.inBody))
+		if tc.inContentEncoding != "" {
+			req.Header.Set("Content-Encoding", tc.inContentEncoding)
+		}
+		rr := httptest.NewRecorder()
+
+		DecompressRequestBodies(handler, config.RequestDecompression{Enabled: true, MaxSizeBytes: 10})(rr, req, nil)
+
+		assert.Equal(t, tc.expectedCalled, called, tc.desc)
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		if tc.expectedCalled {
+			assert.Equal(t, tc.expectedReadBody, string(readBody), tc.desc)
+			assert.Equal(t, tc.expectedReadErr, readErr, tc.desc)
+			assert.Empty(t, readEncoding, "%s: handlers should see a plain body", tc.desc)
+		}
+	}
+}
+
+func TestDecompressRequestBodiesDisabled(t *testing.T) {
+	var readBody []byte
+	handler := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		readBody, _ = ioutil.ReadAll(r.Body)
+	}
+	compressed := gzipBytes(t, "ten bytes!")
+	req := httptest.NewRequest("POST", "/cache", bytes.NewReader(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	DecompressRequestBodies(handler, config.RequestDecompression{Enabled: false, MaxSizeBytes: 10})(httptest.NewRecorder(), req, nil)
+
+	assert.Equal(t, compressed, readBody)
+}
@@ -0,0 +1,34 @@
+package decorators
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+)
+
+// ReportErrorCodes has the endpoints add the error code backend errors are classified under to the body
+// of their responses. The handler is returned untouched if error codes are disabled.
+func ReportErrorCodes(handler httprouter.Handle, cfg config.Routes) httprouter.Handle {
+	if !cfg.ErrorCodes {
+		return handler
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		handler(resp, req.WithContext(WithErrorCodes(req.Context())), params)
+	}
+}
+
+type errorCodesKey struct{}
+
+// WithErrorCodes returns a copy of ctx that asks for backend errors to be reported with their code
+func WithErrorCodes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, errorCodesKey{}, true)
+}
+
+// ErrorCodesEnabled tells whether ctx was set up by WithErrorCodes
+func ErrorCodesEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(errorCodesKey{}).(bool)
+	return enabled
+}
@@ -0,0 +1,41 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportErrorCodes(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		inCfg           config.Routes
+		expectedEnabled bool
+	}{
+		{
+			desc:            "Error codes enabled",
+			inCfg:           config.Routes{ErrorCodes: true},
+			expectedEnabled: true,
+		},
+		{
+			desc:            "Error codes disabled",
+			inCfg:           config.Routes{},
+			expectedEnabled: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		var enabled bool
+		handler := ReportErrorCodes(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			enabled = ErrorCodesEnabled(r.Context())
+		}, tc.inCfg)
+
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/cache?uuid=abc", nil), nil)
+
+		assert.Equal(t, tc.expectedEnabled, enabled, tc.desc)
+	}
+}
@@ -0,0 +1,35 @@
+package decorators
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// LimitConcurrency makes sure no more than maxConcurrent requests get handled at the same time. Requests
+// that arrive while the limit is reached are not queued: they get a 503 with a Retry-After header right
+// away, so the endpoint sheds load instead of piling up goroutines. A maxConcurrent of zero or less
+// doesn't limit the handler at all. Rejected requests are counted as overload rejections.
+//
+// Each call creates its own limit, so wrapping the GET and the PUT handlers separately keeps a burst of
+// one from using up the capacity of the other.
+func LimitConcurrency(handler httprouter.Handle, m *metrics.Metrics, maxConcurrent int) httprouter.Handle {
+	if maxConcurrent <= 0 {
+		return handler
+	}
+
+	inFlight := make(chan struct{}, maxConcurrent)
+	return httprouter.Handle(func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		select {
+		case inFlight <- struct{}{}:
+			defer func() { <-inFlight }()
+			handler(resp, req, params)
+		default:
+			m.RecordRejectedRequest(metrics.RejectedOverload)
+			resp.Header().Set("Retry-After", "1")
+			utils.WriteError(resp, "Too many concurrent requests. Try again later.", http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var blockingPut = func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}
+	var get = func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	m := metricstest.CreateMockMetrics()
+	put := LimitConcurrency(blockingPut, m, 1)
+	limitedGet := LimitConcurrency(get, m, 10)
+
+	// Take up the only PUT slot
+	var wg sync.WaitGroup
+	firstPut := httptest.NewRecorder()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		put(firstPut, httptest.NewRequest("POST", "/cache", nil), nil)
+	}()
+	<-started
+
+	// PUTs over the limit are rejected right away
+	rejectedPut := httptest.NewRecorder()
+	put(rejectedPut, httptest.NewRequest("POST", "/cache", nil), nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rejectedPut.Code, "PUT over the limit should be rejected")
+	assert.Equal(t, "1", rejectedPut.Header().Get("Retry-After"), "Rejected PUT should tell the client when to retry")
+	assert.Equal(t, int64(1), metricstest.MockCounters["requests.rejected.overload"], "Rejected PUT should be counted as an overload")
+
+	// GETs are served while the PUT limit is reached
+	var getWg sync.WaitGroup
+	getResponses := make([]*httptest.ResponseRecorder, 10)
+	for i := range getResponses {
+		getResponses[i] = httptest.NewRecorder()
+		getWg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer getWg.Done()
+			limitedGet(rec, httptest.NewRequest("GET", "/cache", nil), nil)
+		}(getResponses[i])
+	}
+	getWg.Wait()
+	for i, rec := range getResponses {
+		assert.Equal(t, http.StatusOK, rec.Code, "GET %d should not be affected by the PUT limit", i)
+	}
+
+	// Once the PUT in flight is done, the slot can be taken again
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, firstPut.Code)
+
+	go func() { <-started }()
+	secondPut := httptest.NewRecorder()
+	put(secondPut, httptest.NewRequest("POST", "/cache", nil), nil)
+	assert.Equal(t, http.StatusOK, secondPut.Code, "PUT under the limit should be handled")
+}
+
+func TestLimitConcurrencyDisabled(t *testing.T) {
+	var handler = func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	rec := httptest.NewRecorder()
+	LimitConcurrency(handler, metricstest.CreateMockMetrics(), 0)(rec, httptest.NewRequest("GET", "/cache", nil), nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRejectedRequestsByReason(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var put = func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		if req.Header.Get("X-Block") != "" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := LimitConcurrency(EnforcePutQuotas(put, m, testPutQuotas), m, 1)
+
+	// Use up the quota of the "small" API key
+	assert.Equal(t, http.StatusOK, doPut(handler, "small", 3))
+	assert.Equal(t, http.StatusTooManyRequests, doPut(handler, "small", 1))
+
+	// Take up the only slot, so that the next request gets shed
+	done := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[]}`))
+		req.Header.Set("X-Block", "true")
+		handler(httptest.NewRecorder(), req, nil)
+		close(done)
+	}()
+	<-started
+	assert.Equal(t, http.StatusServiceUnavailable, doPut(handler, "large", 1))
+	close(release)
+	<-done
+
+	assert.Equal(t, int64(1), metricstest.MockCounters["requests.rejected.quota"], "Quota rejections")
+	assert.Equal(t, int64(1), metricstest.MockCounters["requests.rejected.overload"], "Overload rejections")
+	assert.Equal(t, int64(0), metricstest.MockCounters["requests.rejected.rate_limit"], "Rate limit rejections")
+}
@@ -0,0 +1,64 @@
+package decorators
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prebid/prebid-cache/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ScopeLogs has the entries Logger returns for a request carry its method and path, and the type of
+// the backend that serves it, so that entries can be told apart once they reach a log pipeline
+func ScopeLogs(handler http.Handler, backendType config.BackendType) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ctx := WithLogFields(req.Context(), log.Fields{
+			"method":  req.Method,
+			"path":    req.URL.Path,
+			"backend": string(backendType),
+		})
+		handler.ServeHTTP(resp, req.WithContext(ctx))
+	})
+}
+
+type logFieldsKey struct{}
+
+// WithLogFields returns a copy of ctx whose Logger entries carry fields, along with the ones ctx
+// already had
+func WithLogFields(ctx context.Context, fields log.Fields) context.Context {
+	current := logFieldsOf(ctx)
+	merged := make(log.Fields, len(current)+len(fields))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, logFieldsKey{}, merged)
+}
+
+func logFieldsOf(ctx context.Context) log.Fields {
+	fields, _ := ctx.Value(logFieldsKey{}).(log.Fields)
+	return fields
+}
+
+// PropagateLogging returns a copy of ctx that carries the request id and the log fields of from
+func PropagateLogging(ctx context.Context, from context.Context) context.Context {
+	if id := RequestIDOf(from); id != "" {
+		ctx = WithRequestID(ctx, id)
+	}
+	if fields := logFieldsOf(from); fields != nil {
+		ctx = context.WithValue(ctx, logFieldsKey{}, fields)
+	}
+	return ctx
+}
+
+// Logger returns the logger for the request ctx belongs to. Its entries carry the fields of
+// WithLogFields and the request id, in the request_id field.
+func Logger(ctx context.Context) *log.Entry {
+	entry := log.WithFields(logFieldsOf(ctx))
+	if id := RequestIDOf(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+	return entry
+}
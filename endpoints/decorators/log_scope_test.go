@@ -0,0 +1,45 @@
+package decorators
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeLogs(t *testing.T) {
+	hook := test.NewGlobal()
+	handler := ScopeLogs(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Logger(r.Context()).WithField("status", http.StatusInternalServerError).Error("POST /cache had an unexpected error")
+	}), config.BackendRedis)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/cache", nil))
+
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, logrus.Fields{
+			"method":  "POST",
+			"path":    "/cache",
+			"backend": "redis",
+			"status":  http.StatusInternalServerError,
+		}, hook.Entries[0].Data)
+	}
+}
+
+func TestPropagateLogging(t *testing.T) {
+	hook := test.NewGlobal()
+	requestCtx := WithLogFields(WithRequestID(context.Background(), "pbs-1234"), logrus.Fields{"method": "GET"})
+
+	ctx := PropagateLogging(context.Background(), requestCtx)
+	ctx = WithLogFields(ctx, logrus.Fields{"uuid": "some-key"})
+	Logger(ctx).Error("GET /cache had an unexpected error")
+
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, logrus.Fields{"method": "GET", "uuid": "some-key", "request_id": "pbs-1234"}, hook.Entries[0].Data)
+	}
+	assert.Equal(t, logrus.Fields{"method": "GET"}, logFieldsOf(requestCtx), "Adding fields shouldn't change the ones of the parent context")
+}
@@ -8,8 +8,10 @@ import (
 )
 
 const (
-	PostMethod = 1
-	GetMethod  = 2
+	PostMethod   = 1
+	GetMethod    = 2
+	DeleteMethod = 3
+	TouchMethod  = 4
 )
 
 type metricsFunctions struct {
@@ -17,6 +19,7 @@ type metricsFunctions struct {
 	RecordDuration   func(duration time.Duration)
 	RecordBadRequest func()
 	RecordError      func()
+	RecordUserAgent  func(class string)
 }
 
 func assignMetricsFunctions(m *metrics.Metrics, method int) *metricsFunctions {
@@ -27,11 +30,23 @@ func assignMetricsFunctions(m *metrics.Metrics, method int) *metricsFunctions {
 		metrics.RecordDuration = m.RecordPutDuration
 		metrics.RecordBadRequest = m.RecordPutBadRequest
 		metrics.RecordError = m.RecordPutError
+		metrics.RecordUserAgent = m.RecordPutUserAgent
 	case GetMethod:
 		metrics.RecordTotal = m.RecordGetTotal
 		metrics.RecordDuration = m.RecordGetDuration
 		metrics.RecordBadRequest = m.RecordGetBadRequest
 		metrics.RecordError = m.RecordGetError
+		metrics.RecordUserAgent = m.RecordGetUserAgent
+	case DeleteMethod:
+		metrics.RecordTotal = m.RecordDeleteTotal
+		metrics.RecordDuration = m.RecordDeleteDuration
+		metrics.RecordBadRequest = m.RecordDeleteBadRequest
+		metrics.RecordError = m.RecordDeleteError
+	case TouchMethod:
+		metrics.RecordTotal = m.RecordTouchTotal
+		metrics.RecordDuration = m.RecordTouchDuration
+		metrics.RecordBadRequest = m.RecordTouchBadRequest
+		metrics.RecordError = m.RecordTouchError
 	}
 	return metrics
 }
@@ -39,6 +54,9 @@ func assignMetricsFunctions(m *metrics.Metrics, method int) *metricsFunctions {
 type writerWithStatus struct {
 	delegate   http.ResponseWriter
 	statusCode int
+	// writeFailed tells whether the response couldn't be written, e.g. because it took longer than the
+	// write timeout of the server. The client never got statusCode then.
+	writeFailed bool
 }
 
 func (w *writerWithStatus) WriteHeader(statusCode int) {
@@ -50,7 +68,15 @@ func (w *writerWithStatus) WriteHeader(statusCode int) {
 }
 
 func (w *writerWithStatus) Write(bytes []byte) (int, error) {
-	return w.delegate.Write(bytes)
+	// Go writes a 200 header before the body if the handler didn't write one
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	n, err := w.delegate.Write(bytes)
+	if err != nil {
+		w.writeFailed = true
+	}
+	return n, err
 }
 
 func (w *writerWithStatus) Header() http.Header {
@@ -68,6 +94,11 @@ func MonitorHttp(handler httprouter.Handle, m *metrics.Metrics, method int) http
 		start := time.Now()
 		handler(&wrapper, req, params)
 		respCode := wrapper.statusCode
+		// Responses that never made it to the client are errors, whatever status the handler wrote
+		if wrapper.writeFailed {
+			mf.RecordError()
+			return
+		}
 		// If the calling function never calls WriterHeader explicitly, Go auto-fills it with a 200
 		if respCode == 0 || respCode >= 200 && respCode < 300 {
 			mf.RecordDuration(time.Since(start))
@@ -78,3 +109,14 @@ func MonitorHttp(handler httprouter.Handle, m *metrics.Metrics, method int) http
 		}
 	})
 }
+
+// MonitorEndToEnd observes the time every request takes from the moment it reaches the handler until
+// the handler returns. It's meant to wrap every other handler, so the observed time includes rate
+// limiting, queueing for a concurrency slot and backend retries.
+func MonitorEndToEnd(handler http.Handler, m *metrics.Metrics) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		handler.ServeHTTP(resp, req)
+		m.RecordEndToEndDuration(time.Since(start))
+	})
+}
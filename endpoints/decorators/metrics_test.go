@@ -1,12 +1,15 @@
 package decorators
 
 import (
-	"github.com/julienschmidt/httprouter"
-	"github.com/prebid/prebid-cache/metrics/metricstest"
-	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetRequestSuccessMetrics(t *testing.T) {
@@ -107,6 +110,122 @@ func TestPutReqWriteBytesMetrics(t *testing.T) {
 	assert.Greater(t, metricstest.MockHistograms["puts.current_url.duration"], 0.00, "Successful put request duration should be greater than zero")
 }
 
+func TestWriteTimeoutMetrics(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handled := make(chan struct{})
+	monitoredHandler := MonitorHttp(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		// Bigger than the buffer of the response, so that it reaches the connection past its deadline
+		w.Write(make([]byte, 1024*1024))
+	}, m, GetMethod)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		monitoredHandler(w, r, nil)
+		close(handled)
+	}))
+	server.Config.WriteTimeout = 20 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL + "/cache?uuid=foo"); err == nil {
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	<-handled
+
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.current_url.request.total"], "Timed out get request should have been accounted in the request totals")
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.current_url.request.error"], "Timed out get request should have been accounted under the error label, whatever status the handler wrote")
+	assert.Equal(t, 0.00, metricstest.MockHistograms["gets.current_url.duration"], "Timed out get request should not be observed as a success")
+}
+
+func TestDeleteAndTouchRequestMetrics(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		inMethod            int
+		inStatus            int
+		expectedPrefix      string
+		expectedBadRequests int64
+		expectedErrors      int64
+		expectedDuration    bool
+	}{
+		{
+			desc:             "Successful delete request",
+			inMethod:         DeleteMethod,
+			inStatus:         http.StatusNoContent,
+			expectedPrefix:   "deletes",
+			expectedDuration: true,
+		},
+		{
+			desc:                "Bad delete request",
+			inMethod:            DeleteMethod,
+			inStatus:            http.StatusNotFound,
+			expectedPrefix:      "deletes",
+			expectedBadRequests: 1,
+		},
+		{
+			desc:           "Failed delete request",
+			inMethod:       DeleteMethod,
+			inStatus:       http.StatusInternalServerError,
+			expectedPrefix: "deletes",
+			expectedErrors: 1,
+		},
+		{
+			desc:             "Successful touch request",
+			inMethod:         TouchMethod,
+			inStatus:         http.StatusOK,
+			expectedPrefix:   "touches",
+			expectedDuration: true,
+		},
+		{
+			desc:                "Bad touch request",
+			inMethod:            TouchMethod,
+			inStatus:            http.StatusBadRequest,
+			expectedPrefix:      "touches",
+			expectedBadRequests: 1,
+		},
+		{
+			desc:           "Failed touch request",
+			inMethod:       TouchMethod,
+			inStatus:       http.StatusBadGateway,
+			expectedPrefix: "touches",
+			expectedErrors: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		status := tc.inStatus
+		doRequest(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+			w.WriteHeader(status)
+		}, tc.inMethod)
+
+		assert.Equal(t, int64(1), metricstest.MockCounters[tc.expectedPrefix+".current_url.request.total"], tc.desc)
+		assert.Equal(t, tc.expectedBadRequests, metricstest.MockCounters[tc.expectedPrefix+".current_url.request.bad_request"], tc.desc)
+		assert.Equal(t, tc.expectedErrors, metricstest.MockCounters[tc.expectedPrefix+".current_url.request.error"], tc.desc)
+		if tc.expectedDuration {
+			assert.Greater(t, metricstest.MockHistograms[tc.expectedPrefix+".current_url.duration"], 0.00, tc.desc)
+		} else {
+			assert.Equal(t, 0.00, metricstest.MockHistograms[tc.expectedPrefix+".current_url.duration"], tc.desc)
+		}
+		assert.Equal(t, int64(0), metricstest.MockCounters["gets.current_url.request.total"], tc.desc)
+		assert.Equal(t, int64(0), metricstest.MockCounters["puts.current_url.request.total"], tc.desc)
+	}
+}
+
+func TestEndToEndDurationMetrics(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handler := MonitorEndToEnd(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}), m)
+
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/cache?uuid=foo", nil))
+	}
+
+	assert.Equal(t, int64(3), metricstest.MockCounters["requests.end_to_end_duration.count"], "End to end duration should be observed once per request, whatever its response")
+	assert.Greater(t, metricstest.MockHistograms["requests.end_to_end_duration"], 0.00, "End to end duration should be greater than zero")
+}
+
 func doRequest(handler func(http.ResponseWriter, *http.Request, httprouter.Params), method int) {
 	m := metricstest.CreateMockMetrics()
 	monitoredHandler := MonitorHttp(handler, m, method)
@@ -0,0 +1,151 @@
+package decorators
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// EnforcePutQuotas rejects the PUT requests of API keys that stored as many values or bytes as their
+// quota allows in the current window. Running out of entries gets a 429 and running out of bytes a
+// 507. Only requests that succeed count against the quota. The handler is returned untouched if quotas
+// are disabled.
+func EnforcePutQuotas(handler httprouter.Handle, m *metrics.Metrics, cfg config.PutQuotas) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	tracker := newQuotaTracker(cfg)
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		apiKey := req.Header.Get(cfg.Header)
+		if _, limited := tracker.quotas[apiKey]; !limited {
+			handler(resp, req, params)
+			return
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			writeBodyReadError(resp, err)
+			return
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		entries, size := putUsage(body)
+		if status, err := tracker.reserve(apiKey, entries, size); err != nil {
+			Logger(req.Context()).Debugf("POST /cache rejected for API key %s: %v", apiKey, err)
+			m.RecordPutQuotaRejection()
+			m.RecordRejectedRequest(metrics.RejectedQuota)
+			utils.WriteError(resp, err.Error(), status)
+			return
+		}
+
+		wrapper := writerWithStatus{delegate: resp}
+		handler(&wrapper, req, params)
+		if wrapper.statusCode >= http.StatusMultipleChoices {
+			tracker.release(apiKey, entries, size)
+		}
+	}
+}
+
+// putUsage returns how many values a PUT request body stores and their size. Bodies that can't be
+// parsed count as nothing, since the handler rejects them anyway.
+func putUsage(body []byte) (entries int, size int) {
+	var put struct {
+		Puts []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"puts"`
+	}
+	if err := json.Unmarshal(body, &put); err != nil {
+		return 0, 0
+	}
+	for _, p := range put.Puts {
+		size += len(p.Value)
+	}
+	return len(put.Puts), size
+}
+
+type quotaUsage struct {
+	windowStart time.Time
+	entries     int
+	bytes       int
+}
+
+// quotaTracker keeps the usage of every API key with a quota over fixed windows
+type quotaTracker struct {
+	quotas map[string]config.PutQuota
+	window time.Duration
+	now    func() time.Time
+
+	mutex sync.Mutex
+	usage map[string]*quotaUsage
+}
+
+func newQuotaTracker(cfg config.PutQuotas) *quotaTracker {
+	quotas := make(map[string]config.PutQuota, len(cfg.Quotas))
+	for _, quota := range cfg.Quotas {
+		quotas[quota.APIKey] = quota
+	}
+	return &quotaTracker{
+		quotas: quotas,
+		window: time.Duration(cfg.WindowSeconds) * time.Second,
+		now:    time.Now,
+		usage:  make(map[string]*quotaUsage, len(quotas)),
+	}
+}
+
+// reserve adds the entries and bytes to the usage of the API key, unless that takes it over its quota.
+// In that case the usage is left untouched, and the status to reject the request with is returned.
+func (t *quotaTracker) reserve(apiKey string, entries int, size int) (int, error) {
+	quota := t.quotas[apiKey]
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	usage := t.currentUsage(apiKey)
+	if quota.MaxEntries > 0 && usage.entries+entries > quota.MaxEntries {
+		return http.StatusTooManyRequests, fmt.Errorf("Quota of %d values per window exceeded", quota.MaxEntries)
+	}
+	if quota.MaxBytes > 0 && usage.bytes+size > quota.MaxBytes {
+		return http.StatusInsufficientStorage, fmt.Errorf("Quota of %d bytes per window exceeded", quota.MaxBytes)
+	}
+	usage.entries += entries
+	usage.bytes += size
+	return http.StatusOK, nil
+}
+
+// release gives back what a failed request reserved, as long as its window is still current
+func (t *quotaTracker) release(apiKey string, entries int, size int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	usage := t.currentUsage(apiKey)
+	usage.entries -= entries
+	usage.bytes -= size
+	if usage.entries < 0 {
+		usage.entries = 0
+	}
+	if usage.bytes < 0 {
+		usage.bytes = 0
+	}
+}
+
+// currentUsage must be called with the mutex held. It starts a new window if the last one is over.
+func (t *quotaTracker) currentUsage(apiKey string) *quotaUsage {
+	now := t.now()
+	usage, ok := t.usage[apiKey]
+	if !ok || now.Sub(usage.windowStart) >= t.window {
+		usage = &quotaUsage{windowStart: now}
+		t.usage[apiKey] = usage
+	}
+	return usage
+}
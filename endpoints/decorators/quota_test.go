@@ -0,0 +1,110 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPutQuotas = config.PutQuotas{
+	Enabled:       true,
+	Header:        "X-Api-Key",
+	WindowSeconds: 60,
+	Quotas: []config.PutQuota{
+		{APIKey: "small", MaxEntries: 3},
+		{APIKey: "large", MaxEntries: 100},
+		{APIKey: "bytes", MaxBytes: 20},
+	},
+}
+
+func storedOK(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func doPut(handler httprouter.Handle, apiKey string, numValues int) int {
+	values := make([]string, numValues)
+	for i := range values {
+		values[i] = `{"type":"json","value":"some-value"}`
+	}
+	req := httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[`+strings.Join(values, ",")+`]}`))
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	rr := httptest.NewRecorder()
+	handler(rr, req, nil)
+	return rr.Code
+}
+
+func TestEnforcePutQuotas(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handler := EnforcePutQuotas(storedOK, m, testPutQuotas)
+
+	assert.Equal(t, http.StatusOK, doPut(handler, "small", 2), "Within quota")
+	assert.Equal(t, http.StatusOK, doPut(handler, "large", 2), "Within quota")
+	assert.Equal(t, http.StatusTooManyRequests, doPut(handler, "small", 2), "A request that would go over the quota should be rejected")
+	assert.Equal(t, http.StatusOK, doPut(handler, "small", 1), "A request that fits in what's left of the quota should be stored")
+	assert.Equal(t, http.StatusTooManyRequests, doPut(handler, "small", 1), "Quota should be used up")
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, doPut(handler, "large", 2), "Other keys should not be affected by a used up quota")
+	}
+	assert.Equal(t, http.StatusOK, doPut(handler, "unknown", 50), "Keys without a quota should not be limited")
+	assert.Equal(t, http.StatusOK, doPut(handler, "", 50), "Requests without a key should not be limited")
+
+	assert.Equal(t, int64(2), metricstest.MockCounters["puts.current_url.quota_rejected"])
+}
+
+func TestEnforcePutQuotasBytes(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handler := EnforcePutQuotas(storedOK, m, testPutQuotas)
+
+	// Every value is `"some-value"`, 12 bytes long
+	assert.Equal(t, http.StatusOK, doPut(handler, "bytes", 1))
+	assert.Equal(t, http.StatusInsufficientStorage, doPut(handler, "bytes", 1))
+	assert.Equal(t, int64(1), metricstest.MockCounters["puts.current_url.quota_rejected"])
+}
+
+func TestEnforcePutQuotasOnlyCountsStoredValues(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	failing := func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	handler := EnforcePutQuotas(failing, m, testPutQuotas)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusInternalServerError, doPut(handler, "small", 3), "Failed requests should not use up the quota")
+	}
+	assert.Equal(t, int64(0), metricstest.MockCounters["puts.current_url.quota_rejected"])
+}
+
+func TestQuotaWindow(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tracker := newQuotaTracker(testPutQuotas)
+	tracker.now = func() time.Time { return now }
+
+	_, err := tracker.reserve("small", 3, 0)
+	assert.NoError(t, err)
+	status, err := tracker.reserve("small", 1, 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+
+	now = now.Add(time.Minute)
+	_, err = tracker.reserve("small", 3, 0)
+	assert.NoError(t, err, "Quota should be renewed once the window is over")
+}
+
+func TestEnforcePutQuotasDisabled(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	cfg := testPutQuotas
+	cfg.Enabled = false
+	handler := EnforcePutQuotas(storedOK, m, cfg)
+
+	assert.Equal(t, http.StatusOK, doPut(handler, "small", 10))
+}
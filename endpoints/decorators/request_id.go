@@ -0,0 +1,62 @@
+package decorators
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRequestIDLength keeps clients from flooding the logs through the request id header
+const maxRequestIDLength = 128
+
+// TagRequestIDs gives every request an id, which it sends back in the configured header and which
+// Logger adds to the entries logged for the request. Clients pick the id by sending it in that same
+// header; ids that are longer than maxRequestIDLength or that have characters other than printable
+// ASCII are replaced by a generated one. The handler is returned untouched if request ids are disabled.
+func TagRequestIDs(handler http.Handler, cfg config.RequestID) http.Handler {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(cfg.Header)
+		if !validRequestID(id) {
+			var err error
+			if id, err = utils.GenerateRandomId(); err != nil {
+				log.Errorf("%s %s: failed to generate a request id: %v", req.Method, req.URL.Path, err)
+				handler.ServeHTTP(resp, req)
+				return
+			}
+		}
+		resp.Header().Set(cfg.Header, id)
+		handler.ServeHTTP(resp, req.WithContext(WithRequestID(req.Context(), id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx that carries the id of the request it belongs to
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDOf returns the request id ctx carries, or an empty string if it carries none
+func RequestIDOf(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
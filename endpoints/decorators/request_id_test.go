@@ -0,0 +1,84 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveWithRequestID returns the response to a request carrying header, and the request id the handler saw
+func serveWithRequestID(cfg config.RequestID, header string) (*httptest.ResponseRecorder, string) {
+	var seen string
+	handler := TagRequestIDs(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDOf(r.Context())
+		Logger(r.Context()).Info("GET /cache")
+	}), cfg)
+
+	req := httptest.NewRequest("GET", "/cache?uuid=some-key", nil)
+	if header != "" {
+		req.Header.Set("X-Request-ID", header)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, seen
+}
+
+func TestTagRequestIDsSupplied(t *testing.T) {
+	hook := test.NewGlobal()
+	cfg := config.RequestID{Enabled: true, Header: "X-Request-ID"}
+
+	rr, seen := serveWithRequestID(cfg, "pbs-1234")
+
+	assert.Equal(t, "pbs-1234", seen, "The id the client sent should be reused")
+	assert.Equal(t, "pbs-1234", rr.Header().Get("X-Request-ID"), "The id should be echoed back")
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, "pbs-1234", hook.Entries[0].Data["request_id"], "Log entries should carry the id")
+	}
+}
+
+func TestTagRequestIDsGenerated(t *testing.T) {
+	cfg := config.RequestID{Enabled: true, Header: "X-Request-ID"}
+
+	testCases := []struct {
+		desc     string
+		inHeader string
+	}{
+		{desc: "No id sent", inHeader: ""},
+		{desc: "Id too long", inHeader: strings.Repeat("a", maxRequestIDLength+1)},
+		{desc: "Id with control characters", inHeader: "pbs-1234\x1b[2J"},
+	}
+
+	for _, tc := range testCases {
+		hook := test.NewGlobal()
+		rr, seen := serveWithRequestID(cfg, tc.inHeader)
+
+		_, err := uuid.FromString(seen)
+		assert.NoError(t, err, "%s: a UUID should be generated", tc.desc)
+		assert.Equal(t, seen, rr.Header().Get("X-Request-ID"), "%s: the generated id should be echoed back", tc.desc)
+		if assert.Len(t, hook.Entries, 1, tc.desc) {
+			assert.Equal(t, seen, hook.Entries[0].Data["request_id"], "%s: log entries should carry the id", tc.desc)
+		}
+	}
+
+	_, first := serveWithRequestID(cfg, "")
+	_, second := serveWithRequestID(cfg, "")
+	assert.NotEqual(t, first, second, "Every request should get an id of its own")
+}
+
+func TestTagRequestIDsDisabled(t *testing.T) {
+	hook := test.NewGlobal()
+
+	rr, seen := serveWithRequestID(config.RequestID{Header: "X-Request-ID"}, "pbs-1234")
+
+	assert.Empty(t, seen)
+	assert.Empty(t, rr.Header().Get("X-Request-ID"))
+	if assert.Len(t, hook.Entries, 1) {
+		assert.NotContains(t, hook.Entries[0].Data, "request_id")
+	}
+}
@@ -0,0 +1,54 @@
+package decorators
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	backendDecorators "github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// OverrideMaxAttempts lets the clients of cfg.OverrideClients set the max attempts of their own backend
+// operations through the configured override header. Clients are told apart by their value of
+// cfg.OverrideClientHeader, and the override header of any other client is ignored. Overrides above
+// cfg.MaxAttemptsCeiling are lowered to it, and values other than a positive number are ignored. The
+// max attempts each override ends up with are recorded. The handler is returned untouched if no
+// override header is configured.
+func OverrideMaxAttempts(handler httprouter.Handle, m *metrics.Metrics, cfg config.Retry) httprouter.Handle {
+	if cfg.OverrideHeader == "" {
+		return handler
+	}
+	clients := make(map[string]bool, len(cfg.OverrideClients))
+	for _, client := range cfg.OverrideClients {
+		clients[client] = true
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if client := req.Header.Get(cfg.OverrideClientHeader); client != "" && clients[client] {
+			if attempts, ok := maxAttemptsOverride(req.Header.Get(cfg.OverrideHeader), cfg.MaxAttemptsCeiling); ok {
+				Logger(req.Context()).Debugf("%s %s overrides the backend max attempts: %d", req.Method, req.URL.Path, attempts)
+				m.RecordMaxAttemptsOverride(attempts)
+				req = req.WithContext(backendDecorators.WithMaxAttempts(req.Context(), attempts))
+			}
+		}
+		handler(resp, req, params)
+	}
+}
+
+func maxAttemptsOverride(header string, ceiling int) (int, bool) {
+	if header == "" {
+		return 0, false
+	}
+	attempts, err := strconv.Atoi(header)
+	if err != nil || attempts < 1 {
+		log.Debugf("Ignoring invalid max attempts override: %q", header)
+		return 0, false
+	}
+	if attempts > ceiling {
+		return ceiling, true
+	}
+	return attempts, true
+}
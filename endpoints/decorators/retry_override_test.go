@@ -0,0 +1,106 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	backendDecorators "github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverrideMaxAttempts(t *testing.T) {
+	cfg := config.Retry{
+		MaxAttempts:          2,
+		OverrideHeader:       "X-Max-Attempts",
+		MaxAttemptsCeiling:   4,
+		OverrideClientHeader: "X-Api-Key",
+		OverrideClients:      []string{"priority-client"},
+	}
+
+	testCases := []struct {
+		desc             string
+		inClient         string
+		inHeader         string
+		inValue          string
+		expectedOverride bool
+		expectedAttempts int
+	}{
+		{
+			desc:             "Override within the ceiling",
+			inClient:         "priority-client",
+			inHeader:         "X-Max-Attempts",
+			inValue:          "3",
+			expectedOverride: true,
+			expectedAttempts: 3,
+		},
+		{
+			desc:             "Override up to the ceiling",
+			inClient:         "priority-client",
+			inHeader:         "X-Max-Attempts",
+			inValue:          "4",
+			expectedOverride: true,
+			expectedAttempts: 4,
+		},
+		{
+			desc:             "Override beyond the ceiling is clamped",
+			inClient:         "priority-client",
+			inHeader:         "X-Max-Attempts",
+			inValue:          "50",
+			expectedOverride: true,
+			expectedAttempts: 4,
+		},
+		{
+			desc:     "Zero attempts are ignored",
+			inClient: "priority-client",
+			inHeader: "X-Max-Attempts",
+			inValue:  "0",
+		},
+		{
+			desc:     "Values other than numbers are ignored",
+			inClient: "priority-client",
+			inHeader: "X-Max-Attempts",
+			inValue:  "many",
+		},
+		{
+			desc:     "Headers other than the configured one are ignored",
+			inClient: "priority-client",
+			inHeader: "X-Retries",
+			inValue:  "3",
+		},
+		{
+			desc:     "Clients outside the allow-list can't override",
+			inClient: "other-client",
+			inHeader: "X-Max-Attempts",
+			inValue:  "3",
+		},
+		{
+			desc:     "Requests without a client can't override",
+			inHeader: "X-Max-Attempts",
+			inValue:  "3",
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		var override bool
+		var attempts int
+		handler := OverrideMaxAttempts(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			attempts, override = backendDecorators.MaxAttemptsOverride(r.Context())
+		}, m, cfg)
+
+		req := httptest.NewRequest("GET", "/cache?uuid=foo", nil)
+		if tc.inClient != "" {
+			req.Header.Set("X-Api-Key", tc.inClient)
+		}
+		req.Header.Set(tc.inHeader, tc.inValue)
+		handler(httptest.NewRecorder(), req, nil)
+
+		assert.Equal(t, tc.expectedOverride, override, tc.desc)
+		assert.Equal(t, tc.expectedAttempts, attempts, tc.desc)
+		assert.Equal(t, float64(tc.expectedAttempts), metricstest.MockHistograms["backend.max_attempts_override"], tc.desc)
+	}
+}
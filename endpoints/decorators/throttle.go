@@ -0,0 +1,123 @@
+package decorators
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// ThrottleClientPuts rejects with a 429 the PUT requests of clients that ran out of tokens in their
+// bucket. The Retry-After header tells them how many seconds until the next token. Clients sending one
+// of the configured API keys are throttled by key, and the others by the IP found trustedProxyDepth
+// proxies away. Rejected requests are counted as throttled rejections. The handler is returned untouched
+// if the limit is disabled.
+func ThrottleClientPuts(handler httprouter.Handle, m *metrics.Metrics, cfg config.ClientRateLimit, trustedProxyDepth int) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+
+	buckets := newTokenBuckets(cfg)
+	apiKeys := make(map[string]bool, len(cfg.APIKeys))
+	for _, apiKey := range cfg.APIKeys {
+		apiKeys[apiKey] = true
+	}
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		client := throttledClient(req, cfg.APIKeyHeader, apiKeys, trustedProxyDepth)
+		if wait := buckets.take(client); wait > 0 {
+			Logger(req.Context()).Debugf("POST /cache throttled for client %s", client)
+			m.RecordRejectedRequest(metrics.RejectedThrottled)
+			resp.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			utils.WriteError(resp, "Too many requests from this client. Try again later.", http.StatusTooManyRequests)
+			return
+		}
+		handler(resp, req, params)
+	}
+}
+
+// throttledClient names the bucket of a request. Nothing authenticates the API key header, so keys other
+// than the known ones are ignored: a client sending a new key with every request would otherwise get a
+// full bucket every time, and push the buckets of other clients out. The prefixes keep an API key from
+// sharing its bucket with an IP that happens to be spelled the same.
+func throttledClient(req *http.Request, apiKeyHeader string, apiKeys map[string]bool, trustedProxyDepth int) string {
+	if apiKeyHeader != "" {
+		if apiKey := req.Header.Get(apiKeyHeader); apiKeys[apiKey] {
+			return "key:" + apiKey
+		}
+	}
+	if ip := clientIP(req, trustedProxyDepth); ip != nil {
+		return "ip:" + ip.String()
+	}
+	// Clients without an address can't be told apart, so they share a bucket
+	return "ip:unknown"
+}
+
+type tokenBucket struct {
+	client   string
+	tokens   float64
+	lastFill time.Time
+}
+
+// tokenBuckets keeps the buckets of the most recent clients. The least recently seen one is dropped
+// when a new client would take the count over maxClients.
+type tokenBuckets struct {
+	rate       float64
+	burst      float64
+	maxClients int
+	now        func() time.Time
+
+	mutex   sync.Mutex
+	recency *list.List
+	buckets map[string]*list.Element
+}
+
+func newTokenBuckets(cfg config.ClientRateLimit) *tokenBuckets {
+	return &tokenBuckets{
+		rate:       cfg.PutsPerSecond,
+		burst:      float64(cfg.Burst),
+		maxClients: cfg.MaxClients,
+		now:        time.Now,
+		recency:    list.New(),
+		buckets:    make(map[string]*list.Element),
+	}
+}
+
+// take removes a token from the bucket of client. If there is none left, the bucket is left untouched
+// and the time until the next token is returned instead.
+func (b *tokenBuckets) take(client string) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	bucket := b.bucketOf(client, now)
+	bucket.tokens = math.Min(b.burst, bucket.tokens+now.Sub(bucket.lastFill).Seconds()*b.rate)
+	bucket.lastFill = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / b.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return 0
+}
+
+// bucketOf must be called with the mutex held. New clients start with a full bucket.
+func (b *tokenBuckets) bucketOf(client string, now time.Time) *tokenBucket {
+	if elem, ok := b.buckets[client]; ok {
+		b.recency.MoveToFront(elem)
+		return elem.Value.(*tokenBucket)
+	}
+	if b.recency.Len() >= b.maxClients {
+		oldest := b.recency.Back()
+		b.recency.Remove(oldest)
+		delete(b.buckets, oldest.Value.(*tokenBucket).client)
+	}
+	bucket := &tokenBucket{client: client, tokens: b.burst, lastFill: now}
+	b.buckets[client] = b.recency.PushFront(bucket)
+	return bucket
+}
@@ -0,0 +1,124 @@
+package decorators
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testClientRateLimit = config.ClientRateLimit{
+	Enabled:       true,
+	PutsPerSecond: 1,
+	Burst:         3,
+	MaxClients:    10,
+	APIKeyHeader:  "X-Api-Key",
+	APIKeys:       []string{"burst", "other"},
+}
+
+func TestThrottleClientPutsBurst(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handler := ThrottleClientPuts(storedOK, m, testClientRateLimit, 0)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doPut(handler, "burst", 1), "PUTs within the burst should pass")
+	}
+
+	req := httptest.NewRequest("POST", "/cache", nil)
+	req.Header.Set("X-Api-Key", "burst")
+	resp := httptest.NewRecorder()
+	handler(resp, req, nil)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code, "PUTs beyond the burst should be throttled")
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+	assert.Equal(t, int64(1), metricstest.MockCounters["requests.rejected.throttled"])
+	assert.Equal(t, http.StatusOK, doPut(handler, "other", 1), "Other API keys should have their own bucket")
+}
+
+func TestThrottleClientPutsByIP(t *testing.T) {
+	handler := ThrottleClientPuts(storedOK, metricstest.CreateMockMetrics(), testClientRateLimit, 1)
+
+	put := func(forwardedFor string) int {
+		req := httptest.NewRequest("POST", "/cache", nil)
+		req.RemoteAddr = "192.168.0.1:51000"
+		req.Header.Set(ForwardedForHeader, forwardedFor)
+		resp := httptest.NewRecorder()
+		handler(resp, req, nil)
+		return resp.Code
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, put("10.1.2.3"))
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, put("10.1.2.3"), "The client behind the proxy should be throttled")
+	assert.Equal(t, http.StatusOK, put("10.1.2.4"), "Clients behind the same proxy should have their own bucket")
+}
+
+func TestThrottleClientPutsUnknownKeys(t *testing.T) {
+	cfg := testClientRateLimit
+	cfg.MaxClients = 2
+	handler := ThrottleClientPuts(storedOK, metricstest.CreateMockMetrics(), cfg, 0)
+
+	// Both clients send their requests from the address httptest gives them
+	assert.Equal(t, http.StatusOK, doPut(handler, "burst", 1))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doPut(handler, fmt.Sprintf("rotated-%d", i), 1), "PUTs within the burst should pass")
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doPut(handler, "rotated-3", 1), "Rotating unknown keys should share the bucket of the client IP")
+	assert.Equal(t, http.StatusOK, doPut(handler, "burst", 1), "Unknown keys shouldn't evict the buckets of known ones")
+}
+
+func TestTokenBucketsSteadyTraffic(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	buckets := newTokenBuckets(testClientRateLimit)
+	buckets.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, time.Duration(0), buckets.take("steady"), "A PUT per second should never be throttled")
+		now = now.Add(time.Second)
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), buckets.take("steady"))
+	}
+	assert.Equal(t, time.Second, buckets.take("steady"), "An empty bucket should wait for its next token")
+
+	now = now.Add(250 * time.Millisecond)
+	assert.Equal(t, 750*time.Millisecond, buckets.take("steady"))
+}
+
+func TestTokenBucketsEviction(t *testing.T) {
+	cfg := testClientRateLimit
+	cfg.MaxClients = 2
+	now := time.Unix(1600000000, 0)
+	buckets := newTokenBuckets(cfg)
+	buckets.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		buckets.take("first")
+	}
+	buckets.take("second")
+	buckets.take("first")
+	buckets.take("third")
+
+	assert.Len(t, buckets.buckets, 2)
+	assert.Equal(t, 2, buckets.recency.Len())
+	assert.Contains(t, buckets.buckets, "first", "The most recent clients should be kept")
+	assert.NotContains(t, buckets.buckets, "second", "The least recent client should be evicted")
+	assert.NotEqual(t, time.Duration(0), buckets.take("first"), "Kept clients should keep their empty bucket")
+}
+
+func TestThrottleClientPutsDisabled(t *testing.T) {
+	cfg := testClientRateLimit
+	cfg.Enabled = false
+	handler := ThrottleClientPuts(storedOK, metricstest.CreateMockMetrics(), cfg, 0)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, doPut(handler, "burst", 1))
+	}
+}
@@ -0,0 +1,162 @@
+package decorators
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// TraceParentHeader carries the W3C trace context of the caller
+const TraceParentHeader = "traceparent"
+
+// Trace is the trace a request belongs to
+type Trace struct {
+	TraceID string
+	// ParentID is the span of the caller, if the request came with a trace context
+	ParentID string
+	SpanID   string
+	Sampled  bool
+}
+
+// SampleTraces decides, at the head of every request, whether it gets traced. Requests that carry a
+// valid traceparent header join the trace of their caller and follow its sampling decision, so that
+// traces aren't left with holes. The others start a new trace, sampled with probability cfg.SampleRate.
+// Sampled requests log a span once handled, or send it to the OpenTelemetry collector if cfg has one.
+// The handler is returned untouched if tracing is disabled.
+func SampleTraces(handler httprouter.Handle, cfg config.Tracing) httprouter.Handle {
+	if !cfg.Enabled {
+		return handler
+	}
+	if cfg.ExportsSpans() {
+		return exportSpans(handler)
+	}
+
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		trace, ok := parseTraceParent(req.Header.Get(TraceParentHeader))
+		if !ok {
+			trace = Trace{TraceID: randomHex(16), Sampled: rand.Float64() < cfg.SampleRate}
+		}
+		trace.SpanID = randomHex(8)
+		req = req.WithContext(WithTrace(req.Context(), trace))
+
+		if !trace.Sampled {
+			handler(resp, req, params)
+			return
+		}
+
+		wrapper := writerWithStatus{delegate: resp}
+		start := time.Now()
+		handler(&wrapper, req, params)
+		logSpan(trace, req, wrapper.statusCode, time.Since(start))
+	}
+}
+
+// parseTraceParent reads a traceparent header of the form "version-traceid-parentid-flags". Invalid
+// headers are ignored, as the W3C spec asks.
+func parseTraceParent(header string) (Trace, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || parts[0] == "ff" || (parts[0] == "00" && len(parts) > 4) {
+		return Trace{}, false
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || !isHex(traceID, 32) || !isHex(parentID, 16) || !isHex(flags, 2) {
+		return Trace{}, false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return Trace{}, false
+	}
+	flagBits, _ := hex.DecodeString(flags)
+	return Trace{TraceID: traceID, ParentID: parentID, Sampled: flagBits[0]&0x01 == 0x01}, true
+}
+
+// isHex tells whether s is made of size lowercase hex digits
+func isHex(s string, size int) bool {
+	if len(s) != size {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(numBytes int) string {
+	id := make([]byte, numBytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func logSpan(trace Trace, req *http.Request, status int, duration time.Duration) {
+	// If the handler never calls WriteHeader explicitly, Go auto-fills it with a 200
+	if status == 0 {
+		status = http.StatusOK
+	}
+	log.WithFields(log.Fields{
+		"trace_id":    trace.TraceID,
+		"parent_id":   trace.ParentID,
+		"span_id":     trace.SpanID,
+		"status":      status,
+		"duration_ms": float64(duration) / float64(time.Millisecond),
+	}).Infof("span %s %s", req.Method, req.URL.Path)
+}
+
+type traceKey struct{}
+
+// WithTrace returns a copy of ctx that carries the trace of its request
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceOf returns the trace set in ctx by WithTrace, if any
+func TraceOf(ctx context.Context) (Trace, bool) {
+	trace, ok := ctx.Value(traceKey{}).(Trace)
+	return trace, ok
+}
+
+// exportSpans leaves sampling to the tracer provider set up by tracing.Start, which reads the trace
+// context of the caller from the request headers. GET spans record the key they asked for; the keys of
+// a PUT are recorded by the spans of the backend.
+func exportSpans(handler httprouter.Handle) httprouter.Handle {
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracing.Tracer().Start(ctx, req.Method+" /cache",
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+			oteltrace.WithAttributes(attribute.String("http.method", req.Method)))
+		defer span.End()
+
+		if req.Method == http.MethodGet {
+			key := params.ByName("uuid")
+			if key == "" {
+				key = req.URL.Query().Get("uuid")
+			}
+			span.SetAttributes(tracing.KeyAttribute.String(key))
+		}
+
+		wrapper := writerWithStatus{delegate: resp}
+		handler(&wrapper, req.WithContext(ctx), params)
+
+		status := wrapper.statusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
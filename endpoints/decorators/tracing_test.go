@@ -0,0 +1,257 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	testTraceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentID = "00f067aa0ba902b7"
+)
+
+// traceOfRequest runs a request with the given traceparent header through the handler and returns the
+// trace the handler saw
+func traceOfRequest(cfg config.Tracing, traceParent string) (Trace, bool) {
+	var trace Trace
+	var found bool
+	handler := SampleTraces(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		trace, found = TraceOf(r.Context())
+	}, cfg)
+
+	req := httptest.NewRequest("GET", "/cache", nil)
+	if traceParent != "" {
+		req.Header.Set(TraceParentHeader, traceParent)
+	}
+	handler(httptest.NewRecorder(), req, nil)
+	return trace, found
+}
+
+func TestSampleTracesRate(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	const numRequests = 4000
+	cfg := config.Tracing{Enabled: true, SampleRate: 0.25}
+
+	sampled := 0
+	for i := 0; i < numRequests; i++ {
+		trace, found := traceOfRequest(cfg, "")
+		if assert.True(t, found, "Every request should belong to a trace") && trace.Sampled {
+			sampled++
+		}
+	}
+
+	fraction := float64(sampled) / numRequests
+	assert.InDelta(t, 0.25, fraction, 0.05, "About a quarter of the requests should be traced")
+	assert.Len(t, hook.AllEntries(), sampled, "Only sampled requests should log a span")
+}
+
+func TestSampleTracesHonorsIncomingDecision(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		inSampleRate     float64
+		inTraceParent    string
+		expectedSampled  bool
+		expectedTraceID  string
+		expectedParentID string
+	}{
+		{
+			desc:             "Incoming sampled flag wins over a zero sample rate",
+			inSampleRate:     0,
+			inTraceParent:    "00-" + testTraceID + "-" + testParentID + "-01",
+			expectedSampled:  true,
+			expectedTraceID:  testTraceID,
+			expectedParentID: testParentID,
+		},
+		{
+			desc:             "Incoming unsampled flag wins over a sample rate of one",
+			inSampleRate:     1,
+			inTraceParent:    "00-" + testTraceID + "-" + testParentID + "-00",
+			expectedSampled:  false,
+			expectedTraceID:  testTraceID,
+			expectedParentID: testParentID,
+		},
+		{
+			desc:            "Malformed traceparent is ignored",
+			inSampleRate:    1,
+			inTraceParent:   "00-not-a-trace-01",
+			expectedSampled: true,
+		},
+		{
+			desc:            "All zero trace ID is ignored",
+			inSampleRate:    0,
+			inTraceParent:   "00-00000000000000000000000000000000-" + testParentID + "-01",
+			expectedSampled: false,
+		},
+	}
+
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	for _, tc := range testCases {
+		trace, found := traceOfRequest(config.Tracing{Enabled: true, SampleRate: tc.inSampleRate}, tc.inTraceParent)
+
+		if assert.True(t, found, tc.desc) {
+			assert.Equal(t, tc.expectedSampled, trace.Sampled, tc.desc)
+			assert.Len(t, trace.SpanID, 16, tc.desc)
+			assert.Equal(t, tc.expectedParentID, trace.ParentID, tc.desc)
+			if tc.expectedTraceID != "" {
+				assert.Equal(t, tc.expectedTraceID, trace.TraceID, tc.desc)
+			} else {
+				assert.Len(t, trace.TraceID, 32, "%s: a new trace should be started", tc.desc)
+			}
+		}
+	}
+}
+
+func TestSampleTracesLogsSpans(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	handler := SampleTraces(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusNotFound)
+	}, config.Tracing{Enabled: true, SampleRate: 0})
+
+	req := httptest.NewRequest("GET", "/cache", nil)
+	req.Header.Set(TraceParentHeader, "00-"+testTraceID+"-"+testParentID+"-01")
+	handler(httptest.NewRecorder(), req, nil)
+
+	if assert.Len(t, hook.AllEntries(), 1) {
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.InfoLevel, entry.Level)
+		assert.Equal(t, "span GET /cache", entry.Message)
+		assert.Equal(t, testTraceID, entry.Data["trace_id"])
+		assert.Equal(t, testParentID, entry.Data["parent_id"])
+		assert.Equal(t, http.StatusNotFound, entry.Data["status"])
+	}
+}
+
+func TestSampleTracesDisabled(t *testing.T) {
+	_, found := traceOfRequest(config.Tracing{SampleRate: 1}, "00-"+testTraceID+"-"+testParentID+"-01")
+	assert.False(t, found, "Requests should not be traced when tracing is disabled")
+}
+
+// recordSpans keeps the spans of every tracer in memory, and reads trace contexts from traceparent
+// headers, until the returned function is called
+func recordSpans() (*tracetest.InMemoryExporter, func()) {
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exporter, func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}
+}
+
+func TestSampleTracesExportsSpans(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		inRequest          *http.Request
+		inParams           httprouter.Params
+		inStatus           int
+		expectedName       string
+		expectedAttributes map[attribute.Key]string
+		expectedStatus     codes.Code
+	}{
+		{
+			desc:         "GET with the key in its query",
+			inRequest:    httptest.NewRequest("GET", "/cache?uuid=foo", nil),
+			inStatus:     http.StatusNotFound,
+			expectedName: "GET /cache",
+			expectedAttributes: map[attribute.Key]string{
+				"http.method":      "GET",
+				"http.status_code": "404",
+				"cache.key":        "foo",
+			},
+			expectedStatus: codes.Unset,
+		},
+		{
+			desc:         "GET with the key in its path",
+			inRequest:    httptest.NewRequest("GET", "/cache/foo", nil),
+			inParams:     httprouter.Params{{Key: "uuid", Value: "foo"}},
+			expectedName: "GET /cache",
+			expectedAttributes: map[attribute.Key]string{
+				"http.method":      "GET",
+				"http.status_code": "200",
+				"cache.key":        "foo",
+			},
+			expectedStatus: codes.Unset,
+		},
+		{
+			desc:         "Failed POST",
+			inRequest:    httptest.NewRequest("POST", "/cache", nil),
+			inStatus:     http.StatusInternalServerError,
+			expectedName: "POST /cache",
+			expectedAttributes: map[attribute.Key]string{
+				"http.method":      "POST",
+				"http.status_code": "500",
+			},
+			expectedStatus: codes.Error,
+		},
+	}
+
+	exporter, restore := recordSpans()
+	defer restore()
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	cfg := config.Tracing{Enabled: true, SampleRate: 1, OTLPEndpoint: "http://localhost:4318"}
+	for _, tc := range testCases {
+		exporter.Reset()
+		handler := SampleTraces(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+			if tc.inStatus != 0 {
+				w.WriteHeader(tc.inStatus)
+			}
+		}, cfg)
+
+		tc.inRequest.Header.Set(TraceParentHeader, "00-"+testTraceID+"-"+testParentID+"-01")
+		handler(httptest.NewRecorder(), tc.inRequest, tc.inParams)
+
+		spans := exporter.GetSpans()
+		if !assert.Len(t, spans, 1, tc.desc) {
+			continue
+		}
+		assert.Equal(t, tc.expectedName, spans[0].Name, tc.desc)
+		assert.Equal(t, testTraceID, spans[0].SpanContext.TraceID().String(), "%s: the span should join the trace of the caller", tc.desc)
+		assert.Equal(t, testParentID, spans[0].Parent.SpanID().String(), "%s: the span should be a child of the caller", tc.desc)
+		assert.Equal(t, tc.expectedStatus, spans[0].Status.Code, tc.desc)
+
+		attributes := make(map[attribute.Key]string, len(spans[0].Attributes))
+		for _, kv := range spans[0].Attributes {
+			attributes[kv.Key] = kv.Value.Emit()
+		}
+		assert.Equal(t, tc.expectedAttributes, attributes, tc.desc)
+	}
+	assert.Empty(t, hook.AllEntries(), "Exported spans should not be logged")
+}
+
+func TestSampleTracesExportsBackendSpansAsChildren(t *testing.T) {
+	exporter, restore := recordSpans()
+	defer restore()
+
+	handler := SampleTraces(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		_, span := otel.Tracer("backend").Start(r.Context(), "backend.Get")
+		span.End()
+	}, config.Tracing{Enabled: true, SampleRate: 1, OTLPEndpoint: "http://localhost:4318"})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/cache?uuid=foo", nil), nil)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID(), "Spans started by the handler should be children of the request span")
+	}
+}
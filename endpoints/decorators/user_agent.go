@@ -0,0 +1,75 @@
+package decorators
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+)
+
+// User agent classes. Keeping them few keeps the cardinality of the metrics labeled with them low.
+const (
+	UserAgentPrebidServer = "prebid-server"
+	UserAgentBrowser      = "browser"
+	UserAgentOther        = "other"
+)
+
+// UserAgentClassifier buckets User-Agent headers into one of the user agent classes
+type UserAgentClassifier struct {
+	prebidServer []string
+	browser      []string
+}
+
+func NewUserAgentClassifier(cfg config.UserAgentTagging) *UserAgentClassifier {
+	return &UserAgentClassifier{
+		prebidServer: lowerAll(cfg.PrebidServer),
+		browser:      lowerAll(cfg.Browser),
+	}
+}
+
+// Classify returns the class of the user agent. Prebid Server substrings are checked first, so a
+// user agent matching both lists counts as Prebid Server traffic.
+func (c *UserAgentClassifier) Classify(userAgent string) string {
+	userAgent = strings.ToLower(userAgent)
+	if containsAny(userAgent, c.prebidServer) {
+		return UserAgentPrebidServer
+	}
+	if containsAny(userAgent, c.browser) {
+		return UserAgentBrowser
+	}
+	return UserAgentOther
+}
+
+// TagUserAgents counts every request handled by handler under the class of its user agent. The
+// handler is returned untouched if the tagging is disabled or the method has no user agent metrics.
+func TagUserAgents(handler httprouter.Handle, m *metrics.Metrics, method int, cfg config.UserAgentTagging) httprouter.Handle {
+	mf := assignMetricsFunctions(m, method)
+	if !cfg.Enabled || mf.RecordUserAgent == nil {
+		return handler
+	}
+
+	classifier := NewUserAgentClassifier(cfg)
+	return func(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		mf.RecordUserAgent(classifier.Classify(req.UserAgent()))
+		handler(resp, req, params)
+	}
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if substring != "" && strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, v := range values {
+		lowered = append(lowered, strings.ToLower(v))
+	}
+	return lowered
+}
@@ -0,0 +1,96 @@
+package decorators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testUserAgentTagging = config.UserAgentTagging{
+	Enabled:      true,
+	PrebidServer: []string{"prebid-server", "Go-http-client"},
+	Browser:      []string{"Mozilla"},
+}
+
+func TestClassifyUserAgent(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inUserAgent   string
+		expectedClass string
+	}{
+		{
+			desc:          "Prebid Server",
+			inUserAgent:   "prebid-server/0.150.0",
+			expectedClass: UserAgentPrebidServer,
+		},
+		{
+			desc:          "Go HTTP client, matched regardless of case",
+			inUserAgent:   "go-http-client/1.1",
+			expectedClass: UserAgentPrebidServer,
+		},
+		{
+			desc:          "Browser",
+			inUserAgent:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0 Safari/537.36",
+			expectedClass: UserAgentBrowser,
+		},
+		{
+			desc:          "Unknown user agent",
+			inUserAgent:   "masscan/1.3",
+			expectedClass: UserAgentOther,
+		},
+		{
+			desc:          "Missing user agent",
+			inUserAgent:   "",
+			expectedClass: UserAgentOther,
+		},
+	}
+
+	classifier := NewUserAgentClassifier(testUserAgentTagging)
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedClass, classifier.Classify(tc.inUserAgent), tc.desc)
+	}
+}
+
+func TestTagUserAgents(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handler := TagUserAgents(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {}, m, GetMethod, testUserAgentTagging)
+
+	for _, userAgent := range []string{"prebid-server/0.150.0", "Mozilla/5.0", "curl/7.68.0", "zgrab/0.x"} {
+		req := httptest.NewRequest("GET", "/cache?uuid=foo", nil)
+		req.Header.Set("User-Agent", userAgent)
+		handler(httptest.NewRecorder(), req, nil)
+	}
+
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.current_url.user_agent.prebid-server"])
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.current_url.user_agent.browser"])
+	assert.Equal(t, int64(2), metricstest.MockCounters["gets.current_url.user_agent.other"])
+	assert.Equal(t, int64(0), metricstest.MockCounters["puts.current_url.user_agent.other"])
+}
+
+func TestTagUserAgentsDisabled(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handler := TagUserAgents(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {}, m, PostMethod, config.UserAgentTagging{})
+
+	req := httptest.NewRequest("POST", "/cache", nil)
+	req.Header.Set("User-Agent", "prebid-server/0.150.0")
+	handler(httptest.NewRecorder(), req, nil)
+
+	assert.Equal(t, int64(0), metricstest.MockCounters["puts.current_url.user_agent.prebid-server"])
+}
+
+func TestTagUserAgentsWithoutMetrics(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	handled := false
+	handler := TagUserAgents(func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) { handled = true }, m, DeleteMethod, testUserAgentTagging)
+
+	req := httptest.NewRequest("DELETE", "/cache?uuid=foo", nil)
+	req.Header.Set("User-Agent", "prebid-server/0.150.0")
+	handler(httptest.NewRecorder(), req, nil)
+
+	assert.True(t, handled, "Requests of methods without user agent metrics should still be handled")
+}
@@ -0,0 +1,18 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// writeBackendError responds to an error returned by the backend with msg and status. If the request
+// asks for error codes, the body also carries the code err is classified under.
+func writeBackendError(w http.ResponseWriter, r *http.Request, err error, msg string, status int) {
+	body := utils.ErrorResponse{Error: msg, Code: utils.ResponseCodeOf(status)}
+	if decorators.ErrorCodesEnabled(r.Context()) {
+		body.BackendCode = utils.ErrorCodeOf(err)
+	}
+	utils.WriteErrorResponse(w, body, status)
+}
@@ -4,47 +4,112 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/prebid/prebid-cache/backends"
+	backendDecorators "github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/envelope"
+	"github.com/prebid/prebid-cache/metrics"
 	"github.com/prebid/prebid-cache/utils"
-	log "github.com/sirupsen/logrus"
 )
 
-func NewGetHandler(backend backends.Backend, allowKeys bool) func(http.ResponseWriter, *http.Request, httprouter.Params) {
+// NewGetHandler serves "GET /cache" requests, along with the batches of keys of routes.GetBatch.
+// Responses carry the custom headers of the routes config and, if enabled, what's known about the stored
+// value in their headers, as far as they fit within the header size cap. Every key looked up in the
+// backend gets recorded as a hit or a miss.
+func NewGetHandler(backend backends.Backend, m *metrics.Metrics, allowKeys bool, routes config.Routes) func(http.ResponseWriter, *http.Request, httprouter.Params) {
+	customHeaders := canonicalHeaders(routes.GetCustomHeaders)
+
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		id, err, status := parseUUID(r, allowKeys)
+		if ids := batchKeys(r, ps, routes.GetBatch); ids != nil {
+			serveBatch(w, r, backend, m, ids, allowKeys, routes.GetBatch)
+			return
+		}
+
+		id, err, status := parseUUID(r, ps, allowKeys)
 		if err != nil {
-			handleException(w, err, status, id)
+			handleException(w, r, err, status, id)
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		ctx, cancel, clientDeadline := operationContext(r)
 		defer cancel()
 
+		var md *envelope.Metadata
+		if routes.GetMetadataHeaders || routes.GetStalenessHeaders {
+			ctx, md = envelope.WithMetadata(ctx)
+		}
+
 		value, err := backend.Get(ctx, id)
+		recordLookup(m, err)
 		if err != nil {
-			handleException(w, err, http.StatusNotFound, id)
+			if clientDeadline && ctx.Err() == context.DeadlineExceeded {
+				handleBackendException(w, r, err, http.StatusGatewayTimeout, id)
+				return
+			}
+			if _, ok := err.(backendDecorators.CircuitOpenError); ok {
+				handleBackendException(w, r, err, http.StatusServiceUnavailable, id)
+				return
+			}
+			if utils.ErrorCodeOf(err) == utils.Corrupt {
+				handleBackendException(w, r, err, http.StatusBadGateway, id)
+				return
+			}
+			handleBackendException(w, r, err, http.StatusNotFound, id)
 			return
 		}
 
-		if err, status := writeGetResponse(w, id, value); err != nil {
-			handleException(w, err, status, id)
+		// Headers go from the last to the first to be left out: custom, staleness and then metadata ones
+		optionalHeaders := append([]responseHeader{}, customHeaders...)
+		now := time.Now()
+		if routes.GetStalenessHeaders {
+			optionalHeaders = append(optionalHeaders, stalenessHeaders(*md, now)...)
+		}
+		if routes.GetMetadataHeaders {
+			optionalHeaders = append(optionalHeaders, metadataHeaders(*md, now)...)
+		}
+
+		var accept string
+		if routes.GetHonorAccept {
+			accept = r.Header.Get("Accept")
+		}
+		if err, status := writeGetResponse(w, r, id, value, routes.GetContentTypes, accept, optionalHeaders, routes.GetMaxHeaderBytes); err != nil {
+			handleException(w, r, err, status, id)
 			return
 		}
 		return
 	}
 }
 
+// recordLookup records the result of a backend get as a hit if it found a value, or as a miss if the
+// key held none. Any other error is neither.
+func recordLookup(m *metrics.Metrics, err error) {
+	if err == nil {
+		m.RecordGetBackendKeyHit()
+	} else if _, isKeyNotFound := err.(utils.KeyNotFoundError); isKeyNotFound {
+		m.RecordGetBackendKeyMiss()
+	}
+}
+
 type GetResponse struct {
 	Value interface{} `json:"value"`
 }
 
-func parseUUID(r *http.Request, allowKeys bool) (string, error, int) {
-	id := r.URL.Query().Get("uuid")
+// parseUUID reads the key from the path of "GET /cache/{uuid}" requests, or from the query of "GET /cache"
+// ones otherwise
+func parseUUID(r *http.Request, ps httprouter.Params, allowKeys bool) (string, error, int) {
+	id := ps.ByName("uuid")
+	if id == "" {
+		id = r.URL.Query().Get("uuid")
+	}
 	if id == "" {
 		return "", utils.MissingKeyError{}, http.StatusBadRequest
 	}
@@ -56,40 +121,211 @@ func parseUUID(r *http.Request, allowKeys bool) (string, error, int) {
 	return id, nil, http.StatusOK
 }
 
-func writeGetResponse(w http.ResponseWriter, id string, value string) (error, int) {
-	if strings.HasPrefix(value, backends.XML_PREFIX) {
-		w.Header().Set("Content-Type", "application/xml")
-		w.Write([]byte(value)[len(backends.XML_PREFIX):])
-	} else if strings.HasPrefix(value, backends.JSON_PREFIX) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(value)[len(backends.JSON_PREFIX):])
-	} else {
+// backendTimeout is how long backend operations may take, unless the client set a deadline of its own
+const backendTimeout = 500 * time.Millisecond
+
+// operationContext returns the context backend operations run under, and whether its deadline is the
+// one the client set. Every backend operation of a request shares it, so that together they don't take
+// longer than the client waits.
+func operationContext(r *http.Request) (context.Context, context.CancelFunc, bool) {
+	deadline, clientDeadline := decorators.ClientDeadline(r.Context())
+	if !clientDeadline {
+		deadline = time.Now().Add(backendTimeout)
+	}
+	ctx, cancel := context.WithDeadline(backendContext(r), deadline)
+	return ctx, cancel, clientDeadline
+}
+
+// backendContext returns the context to call the backend with. It doesn't derive from the request's
+// context, which gets cancelled as soon as the client goes away, but it keeps the max attempts
+// override, what the request is logged with, the writer and the metadata the request may have come with.
+func backendContext(r *http.Request) context.Context {
+	ctx := context.Background()
+	if attempts, ok := backendDecorators.MaxAttemptsOverride(r.Context()); ok {
+		ctx = backendDecorators.WithMaxAttempts(ctx, attempts)
+	}
+	ctx = decorators.PropagateLogging(ctx, r.Context())
+	return envelope.Propagate(ctx, r.Context())
+}
+
+// responseHeader is a header GET /cache responses may carry
+type responseHeader struct {
+	name  string
+	value string
+}
+
+// size is what the header adds to the response: its name and value, the ": " between them and the
+// line break after them
+func (h responseHeader) size() int {
+	return len(h.name) + len(h.value) + 4
+}
+
+// canonicalHeaders returns the configured headers with their names canonicalized, sorted by name
+func canonicalHeaders(configured map[string]string) []responseHeader {
+	headers := make([]responseHeader, 0, len(configured))
+	for name, value := range configured {
+		headers = append(headers, responseHeader{name: http.CanonicalHeaderKey(name), value: value})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].name < headers[j].name })
+	return headers
+}
+
+// metadataHeaders returns the headers describing the stored value. Values stored without their
+// creation time or TTL get neither header.
+func metadataHeaders(md envelope.Metadata, now time.Time) []responseHeader {
+	var headers []responseHeader
+	if md.Format != "" {
+		headers = append(headers, responseHeader{MetadataFormatHeader, md.Format})
+	}
+	headers = append(headers, responseHeader{MetadataSizeHeader, strconv.Itoa(md.Size)})
+	if !md.CreatedAt.IsZero() {
+		headers = append(headers, responseHeader{MetadataCreatedHeader, md.CreatedAt.UTC().Format(http.TimeFormat)})
+	}
+	if remaining, ok := md.Remaining(now); ok {
+		headers = append(headers, responseHeader{MetadataTTLRemainingHeader, strconv.Itoa(int(remaining / time.Second))})
+	}
+	return headers
+}
+
+// stalenessHeaders returns the headers telling downstream caches how long ago the value was stored and
+// for how much longer they may keep it. Values stored without their creation time get no Age, and
+// values stored without a TTL no Cache-Control.
+func stalenessHeaders(md envelope.Metadata, now time.Time) []responseHeader {
+	var headers []responseHeader
+	if !md.CreatedAt.IsZero() {
+		age := now.Sub(md.CreatedAt)
+		if age < 0 {
+			age = 0
+		}
+		headers = append(headers, responseHeader{AgeHeader, strconv.Itoa(int(age / time.Second))})
+	}
+	if remaining, ok := md.Remaining(now); ok {
+		headers = append(headers, responseHeader{CacheControlHeader, fmt.Sprintf("max-age=%d", int(remaining/time.Second))})
+	}
+	return headers
+}
+
+// writeGetResponse writes the value with the Content-Type of its format, unless contentTypes overrides it.
+// Values whose Content-Type isn't allowed by accept get a 406 instead. An empty accept allows any.
+func writeGetResponse(w http.ResponseWriter, r *http.Request, id string, value string, contentTypes map[string]string, accept string, optionalHeaders []responseHeader, maxHeaderBytes int) (error, int) {
+	var contentType string
+	format := backends.ValueFormat(value)
+	switch format {
+	case backends.XML_PREFIX:
+		contentType = "application/xml"
+	case backends.JSON_PREFIX:
+		contentType = "application/json"
+	default:
 		return errors.New("Cache data was corrupted. Cannot determine type."), http.StatusInternalServerError
 	}
+	body := value[len(format):]
+	if override, ok := contentTypes[format]; ok {
+		contentType = override
+	}
+	if !acceptable(accept, contentType) {
+		return fmt.Errorf("The %s value can't be served as %s.", format, accept), http.StatusNotAcceptable
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	writeOptionalHeaders(w, r, id, optionalHeaders, maxHeaderBytes)
+	w.Write([]byte(body))
 	return nil, http.StatusOK
 }
 
+// acceptable tells whether the media ranges of an Accept header allow contentType. An empty header allows
+// any, and ranges with a zero quality allow none.
+func acceptable(accept string, contentType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		if quality, err := strconv.ParseFloat(params["q"], 64); err == nil && quality == 0 {
+			continue
+		}
+		if rangeType == "*/*" || rangeType == mediaType {
+			return true
+		}
+		if strings.HasSuffix(rangeType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rangeType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOptionalHeaders sets the optional headers, which come in order of priority. If setting all of
+// them would take the response headers past maxBytes, the lowest priority ones are left out until the
+// rest fit. Headers set before, like the Content-Type, are never left out but count towards the cap.
+func writeOptionalHeaders(w http.ResponseWriter, r *http.Request, id string, optional []responseHeader, maxBytes int) {
+	kept := optional
+	if maxBytes > 0 {
+		size := 0
+		for name, values := range w.Header() {
+			for _, value := range values {
+				size += responseHeader{name, value}.size()
+			}
+		}
+		for _, header := range optional {
+			size += header.size()
+		}
+
+		for size > maxBytes && len(kept) > 0 {
+			size -= kept[len(kept)-1].size()
+			kept = kept[:len(kept)-1]
+		}
+		if len(kept) < len(optional) {
+			leftOut := make([]string, 0, len(optional)-len(kept))
+			for _, header := range optional[len(kept):] {
+				leftOut = append(leftOut, header.name)
+			}
+			decorators.Logger(r.Context()).Warnf("GET /cache uuid=%s: left out the %s headers to keep the response headers within %d bytes", id, strings.Join(leftOut, ", "), maxBytes)
+		}
+	}
+
+	for _, header := range kept {
+		w.Header().Set(header.name, header.value)
+	}
+}
+
 // handleException will prefix error messages with "GET /cache" and, if uuid string list is passed, will
 // follow with the first element of it in the following fashion: "uuid=FIRST_ELEMENT_ON_UUID_PARAM".
 // Expects non-nil error
-func handleException(w http.ResponseWriter, err error, status int, uuid string) {
+func handleException(w http.ResponseWriter, r *http.Request, err error, status int, uuid string) {
+	msg := exceptionMessage(err, uuid)
+	logError(r.Context(), err, msg, status)
 
-	var msg string
-	if len(uuid) > 0 {
-		msg = fmt.Sprintf("GET /cache uuid=%s: %s", uuid, err.Error())
-	} else {
-		msg = fmt.Sprintf("GET /cache: %s", err.Error())
-	}
+	utils.WriteError(w, msg, status)
+}
 
-	logError(err, msg)
+// handleBackendException is handleException for the errors returned by the backend, which may be
+// reported along with their error code
+func handleBackendException(w http.ResponseWriter, r *http.Request, err error, status int, uuid string) {
+	msg := exceptionMessage(err, uuid)
+	logError(r.Context(), err, msg, status)
 
-	http.Error(w, msg, status)
+	writeBackendError(w, r, err, msg, status)
+}
+
+func exceptionMessage(err error, uuid string) string {
+	if len(uuid) > 0 {
+		return fmt.Sprintf("GET /cache uuid=%s: %s", uuid, err.Error())
+	}
+	return fmt.Sprintf("GET /cache: %s", err.Error())
 }
 
-func logError(err error, msg string) {
+func logError(ctx context.Context, err error, msg string, status int) {
+	logger := decorators.Logger(ctx).WithField("status", status)
 	if _, isKeyNotFound := err.(utils.KeyNotFoundError); isKeyNotFound {
-		log.Debug(msg)
+		logger.Debug(msg)
 	} else {
-		log.Error(msg)
+		logger.Error(msg)
 	}
 }
@@ -0,0 +1,201 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/envelope"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// GetBatchResponse is the body of GET /cache requests passing more than one key. It has an element per
+// key, in the order the keys were passed in. Responses exceeding the configured size are Truncated: they
+// only have the elements of the leading keys that fit.
+type GetBatchResponse struct {
+	Responses []GetBatchResponseObject `json:"responses"`
+	Truncated bool                     `json:"truncated,omitempty"`
+}
+
+// GetBatchResponseObject is what was found under one of the keys. Value holds json values as they are and
+// xml values as a JSON string, like the values of POST /cache requests. TTLSeconds is what's left of the
+// TTL of the value, rounded up, if it was stored with its TTL. Keys without a value are marked NotFound,
+// and keys that couldn't be read carry the Error that prevented it.
+type GetBatchResponseObject struct {
+	UUID       string          `json:"uuid"`
+	Type       string          `json:"type,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	TTLSeconds int             `json:"ttlseconds,omitempty"`
+	NotFound   bool            `json:"not_found,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// batchKeys returns the keys of a GET /cache request if there's more than one of them and batches are
+// enabled. Keys in the path can't be batched.
+func batchKeys(r *http.Request, ps httprouter.Params, cfg config.GetBatch) []string {
+	if !cfg.Enabled || ps.ByName("uuid") != "" {
+		return nil
+	}
+	if ids := r.URL.Query()["uuid"]; len(ids) > 1 {
+		return ids
+	}
+	return nil
+}
+
+// serveBatch reads every key from the backend, with up to cfg.Workers of them being read at once, and
+// responds with what was found under each of them. Keys stop being read once the values read so far
+// exceed cfg.MaxResponseBytes, so that large values don't pile up in memory.
+func serveBatch(w http.ResponseWriter, r *http.Request, backend backends.Backend, m *metrics.Metrics, ids []string, allowKeys bool, cfg config.GetBatch) {
+	if len(ids) > cfg.MaxKeys {
+		utils.WriteError(w, fmt.Sprintf("GET /cache: More keys than allowed: %d", cfg.MaxKeys), http.StatusBadRequest)
+		return
+	}
+	for _, id := range ids {
+		if id == "" {
+			handleException(w, r, utils.MissingKeyError{}, http.StatusBadRequest, "")
+			return
+		}
+	}
+
+	ctx, cancel, _ := operationContext(r)
+	defer cancel()
+
+	resps := GetBatchResponse{Responses: make([]GetBatchResponseObject, len(ids))}
+	indexes := make(chan int)
+	workers := cfg.Workers
+	if workers > len(ids) {
+		workers = len(ids)
+	}
+
+	// Keys left unread once the cap was exceeded keep a zero element, without a UUID
+	var readBytes int64
+	capped := cfg.MaxResponseBytes > 0
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if capped && atomic.LoadInt64(&readBytes) > int64(cfg.MaxResponseBytes) {
+					continue
+				}
+				resps.Responses[i] = getBatchElement(ctx, backend, m, ids[i], allowKeys)
+				atomic.AddInt64(&readBytes, int64(len(resps.Responses[i].Value)))
+			}
+		}()
+	}
+	for i := range ids {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	status := http.StatusOK
+	if capped {
+		if err := truncateBatch(&resps, cfg.MaxResponseBytes); err != nil {
+			handleException(w, r, err, http.StatusInternalServerError, "")
+			return
+		}
+		if resps.Truncated {
+			status = http.StatusPartialContent
+		}
+	}
+	body, err := marshalUnescaped(resps)
+	if err != nil {
+		handleException(w, r, err, http.StatusInternalServerError, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// truncateBatch keeps the leading elements of resps whose encoded size adds up to no more than maxBytes,
+// and flags resps as Truncated if any had to be dropped. An element that wasn't read ends the response
+// too, since the elements after it must not be served in its place.
+func truncateBatch(resps *GetBatchResponse, maxBytes int) error {
+	size := 0
+	for i, resp := range resps.Responses {
+		if resp.UUID == "" {
+			resps.Responses, resps.Truncated = resps.Responses[:i], true
+			return nil
+		}
+		encoded, err := marshalUnescaped(resp)
+		if err != nil {
+			return err
+		}
+		if size += len(encoded); size > maxBytes {
+			resps.Responses, resps.Truncated = resps.Responses[:i], true
+			return nil
+		}
+	}
+	return nil
+}
+
+func getBatchElement(ctx context.Context, backend backends.Backend, m *metrics.Metrics, id string, allowKeys bool) GetBatchResponseObject {
+	resp := GetBatchResponseObject{UUID: id}
+	// Like single key requests, keys that can't be UUIDs aren't looked up
+	if len(id) != 36 && !allowKeys {
+		resp.NotFound = true
+		return resp
+	}
+
+	// Every key gets a Metadata of its own, since the keys of the batch are read concurrently
+	ctx, md := envelope.WithOwnMetadata(ctx)
+	value, err := backend.Get(ctx, id)
+	recordLookup(m, err)
+	if err != nil {
+		if _, isKeyNotFound := err.(utils.KeyNotFoundError); isKeyNotFound {
+			resp.NotFound = true
+			return resp
+		}
+		decorators.Logger(ctx).Errorf("GET /cache uuid=%s: %v", id, err)
+		resp.Error = err.Error()
+		return resp
+	}
+
+	format := backends.ValueFormat(value)
+	data := value[len(format):]
+	switch {
+	case format == backends.XML_PREFIX:
+		resp.Type = format
+		resp.Value, err = marshalUnescaped(data)
+	case format == backends.JSON_PREFIX && json.Valid([]byte(data)):
+		resp.Type = format
+		resp.Value = json.RawMessage(data)
+	default:
+		err = errors.New("Cache data was corrupted. Cannot determine type.")
+	}
+	if err != nil {
+		decorators.Logger(ctx).Errorf("GET /cache uuid=%s: %v", id, err)
+		return GetBatchResponseObject{UUID: id, Error: err.Error()}
+	}
+	if remaining, ok := md.Remaining(time.Now()); ok {
+		resp.TTLSeconds = int((remaining + time.Second - 1) / time.Second)
+	}
+	return resp
+}
+
+// marshalUnescaped encodes v as JSON without escaping the <, > and & of xml values, which json.Marshal
+// would turn into unicode escapes
+func marshalUnescaped(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,34 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// readinessKey is read from the backend by readiness probes. Nothing is stored under it, so a reachable
+// backend responds with a utils.KeyNotFoundError.
+const readinessKey = "prebid-cache-readiness-probe"
+
+// Liveness responds with a 200 for as long as the process is able to serve requests
+func Liveness(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// NewReadinessHandler makes a round trip to the backend on every request, and responds with a 503 if
+// the backend fails, so that orchestrators stop routing traffic to servers that can't serve it
+func NewReadinessHandler(backend backends.Backend) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if _, err := backend.Get(r.Context(), readinessKey); err != nil {
+			if _, isKeyNotFound := err.(utils.KeyNotFoundError); !isKeyNotFound {
+				decorators.Logger(r.Context()).WithField("status", http.StatusServiceUnavailable).Errorf("GET %s: backend is not ready: %v", r.URL.Path, err)
+				utils.WriteError(w, "Backend is not ready", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -2,17 +2,32 @@ package endpoints
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/prebid/prebid-cache/backends"
+	backendDecorators "github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/envelope"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	prometheusMetrics "github.com/prebid/prebid-cache/metrics/prometheus"
 	"github.com/prebid/prebid-cache/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
@@ -63,8 +78,8 @@ func expectStored(t *testing.T, putBody string, expectedGet string, expectedMime
 	router := httprouter.New()
 	backend := backends.NewMemoryBackend()
 
-	router.POST("/cache", NewPutHandler(backend, 10, true))
-	router.GET("/cache", NewGetHandler(backend, true))
+	router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
 
 	uuid, putTrace := doMockPut(t, router, putBody)
 	if putTrace.Code != http.StatusOK {
@@ -91,7 +106,7 @@ func expectStored(t *testing.T, putBody string, expectedGet string, expectedMime
 func expectFailedPut(t *testing.T, requestBody string) {
 	backend := backends.NewMemoryBackend()
 	router := httprouter.New()
-	router.POST("/cache", NewPutHandler(backend, 10, true))
+	router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
 
 	_, putTrace := doMockPut(t, router, requestBody)
 	if putTrace.Code != http.StatusBadRequest {
@@ -197,7 +212,7 @@ func TestGetHandler(t *testing.T) {
 			testInput{uuid: ""},
 			testOutput{
 				responseCode: http.StatusBadRequest,
-				responseBody: "GET /cache: missing required parameter uuid\n",
+				responseBody: `{"error":"GET /cache: missing required parameter uuid","code":"bad_request"}` + "\n",
 				logEntries: []logEntry{
 					{
 						msg: "GET /cache: missing required parameter uuid",
@@ -211,7 +226,7 @@ func TestGetHandler(t *testing.T) {
 			testInput{uuid: "non-36-char-key-maps-to-json"},
 			testOutput{
 				responseCode: http.StatusNotFound,
-				responseBody: "GET /cache uuid=non-36-char-key-maps-to-json: invalid uuid length\n",
+				responseBody: `{"error":"GET /cache uuid=non-36-char-key-maps-to-json: invalid uuid length","code":"not_found"}` + "\n",
 				logEntries: []logEntry{
 					{
 						msg: "GET /cache uuid=non-36-char-key-maps-to-json: invalid uuid length",
@@ -237,7 +252,7 @@ func TestGetHandler(t *testing.T) {
 			testInput{uuid: "uuid-not-found-and-links-to-no-value"},
 			testOutput{
 				responseCode: http.StatusNotFound,
-				responseBody: "GET /cache uuid=uuid-not-found-and-links-to-no-value: Key not found\n",
+				responseBody: `{"error":"GET /cache uuid=uuid-not-found-and-links-to-no-value: Key not found","code":"not_found"}` + "\n",
 				logEntries: []logEntry{
 					{
 						msg: "GET /cache uuid=uuid-not-found-and-links-to-no-value: Key not found",
@@ -251,7 +266,7 @@ func TestGetHandler(t *testing.T) {
 			testInput{uuid: "36-char-key-maps-to-non-xml-nor-json"},
 			testOutput{
 				responseCode: http.StatusInternalServerError,
-				responseBody: "GET /cache uuid=36-char-key-maps-to-non-xml-nor-json: Cache data was corrupted. Cannot determine type.\n",
+				responseBody: `{"error":"GET /cache uuid=36-char-key-maps-to-non-xml-nor-json: Cache data was corrupted. Cannot determine type.","code":"backend_error"}` + "\n",
 				logEntries: []logEntry{
 					{
 						msg: "GET /cache uuid=36-char-key-maps-to-non-xml-nor-json: Cache data was corrupted. Cannot determine type.",
@@ -288,7 +303,7 @@ func TestGetHandler(t *testing.T) {
 		// Set up test object
 		backend := newMockBackend()
 		router := httprouter.New()
-		router.GET("/cache", NewGetHandler(backend, test.in.allowKeys))
+		router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, test.in.allowKeys, config.Routes{}))
 
 		// Run test
 		getResults := doMockGet(t, router, test.in.uuid)
@@ -325,6 +340,52 @@ func TestReadinessCheck(t *testing.T) {
 	}
 }
 
+func TestHealthProbes(t *testing.T) {
+	storedBackend := backends.NewMemoryBackend()
+	storedBackend.Put(context.Background(), readinessKey, "json{}", 0)
+
+	testCases := []struct {
+		desc           string
+		inBackend      backends.Backend
+		expectedStatus int
+	}{
+		{
+			desc:           "Key not found, the backend is ready",
+			inBackend:      backends.NewMemoryBackend(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Key found, the backend is ready",
+			inBackend:      storedBackend,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Backend error, the backend is not ready",
+			inBackend:      &failingBackend{err: errors.New("connection refused")},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			desc:           "Backend timeout, the backend is not ready",
+			inBackend:      &failingBackend{err: utils.NewBackendError(utils.Timeout, context.DeadlineExceeded)},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		router := httprouter.New()
+		router.GET("/healthz", Liveness)
+		router.GET("/readyz", NewReadinessHandler(tc.inBackend))
+
+		liveness := httptest.NewRecorder()
+		router.ServeHTTP(liveness, httptest.NewRequest("GET", "/healthz", nil))
+		assert.Equal(t, http.StatusOK, liveness.Code, tc.desc+": liveness")
+
+		readiness := httptest.NewRecorder()
+		router.ServeHTTP(readiness, httptest.NewRequest("GET", "/readyz", nil))
+		assert.Equal(t, tc.expectedStatus, readiness.Code, tc.desc+": readiness")
+	}
+}
+
 func TestMultiPutRequestGotStored(t *testing.T) {
 	// Test case: request with more than one element in the "puts" array
 	type aTest struct {
@@ -358,8 +419,8 @@ func TestMultiPutRequestGotStored(t *testing.T) {
 	router := httprouter.New()
 	backend := backends.NewMemoryBackend()
 
-	router.POST("/cache", NewPutHandler(backend, 10, true))
-	router.GET("/cache", NewGetHandler(backend, true))
+	router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
 
 	rr := httptest.NewRecorder()
 
@@ -415,7 +476,7 @@ func TestEmptyPutRequests(t *testing.T) {
 	router := httprouter.New()
 	backend := backends.NewMemoryBackend()
 
-	router.POST("/cache", NewPutHandler(backend, 10, true))
+	router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
 
 	for i, test := range testCases {
 		rr := httptest.NewRecorder()
@@ -457,8 +518,8 @@ func benchmarkPutHandler(b *testing.B, testCase string) {
 	router := httprouter.New()
 	backend := backends.NewMemoryBackend()
 
-	router.POST("/cache", NewPutHandler(backend, 10, true))
-	router.GET("/cache", NewGetHandler(backend, true))
+	router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
 
 	rr := httptest.NewRecorder()
 
@@ -527,3 +588,1848 @@ func newMockBackend() *mockBackend {
 		},
 	}
 }
+
+func TestPutResponseStatus(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		inRespondAccepted bool
+		expectedStatus    int
+	}{
+		{
+			desc:              "Synchronous writes respond with a 200",
+			inRespondAccepted: false,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			desc:              "Asynchronous writes respond with a 202",
+			inRespondAccepted: true,
+			expectedStatus:    http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range testCases {
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backends.NewMemoryBackend(), &metrics.Metrics{}, 10, 0, true, tc.inRespondAccepted, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":"plain text"}]}`))
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		var parsed PutResponse
+		if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &parsed), tc.desc) && assert.Len(t, parsed.Responses, 1, tc.desc) {
+			assert.NotEmpty(t, parsed.Responses[0].UUID, "Response should include the UUID. %s", tc.desc)
+		}
+	}
+}
+
+// unreachableBackend fails every Get with a transient error and counts how many times it was tried
+type unreachableBackend struct {
+	gets int
+}
+
+func (b *unreachableBackend) Get(ctx context.Context, key string) (string, error) {
+	b.gets++
+	return "", fmt.Errorf("connection refused")
+}
+
+func (b *unreachableBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return fmt.Errorf("connection refused")
+}
+
+func TestGetMaxAttemptsOverride(t *testing.T) {
+	retryCfg := config.Retry{MaxAttempts: 2, OverrideHeader: "X-Max-Attempts", MaxAttemptsCeiling: 4, OverrideClientHeader: "X-Api-Key", OverrideClients: []string{"priority-client"}}
+
+	testCases := []struct {
+		desc         string
+		inHeader     string
+		expectedGets int
+	}{
+		{
+			desc:         "No override uses the configured max attempts",
+			expectedGets: 2,
+		},
+		{
+			desc:         "Override raises the max attempts",
+			inHeader:     "3",
+			expectedGets: 3,
+		},
+		{
+			desc:         "Override beyond the ceiling is clamped to it",
+			inHeader:     "10",
+			expectedGets: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := &unreachableBackend{}
+		handler := NewGetHandler(backendDecorators.RetryTransientErrors(backend, retryCfg), &metrics.Metrics{}, true, config.Routes{})
+		router := httprouter.New()
+		router.GET("/cache", decorators.OverrideMaxAttempts(handler, &metrics.Metrics{}, retryCfg))
+
+		request, _ := http.NewRequest("GET", "/cache?uuid=some-key", nil)
+		request.Header.Set("X-Api-Key", "priority-client")
+		if tc.inHeader != "" {
+			request.Header.Set("X-Max-Attempts", tc.inHeader)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.Equal(t, tc.expectedGets, backend.gets, tc.desc)
+	}
+}
+
+func TestGetMetadataHeaders(t *testing.T) {
+	backend := envelope.Versioned(backends.NewMemoryBackend(), envelope.Version1)
+	value := `json{"field":"value"}`
+	assert.NoError(t, backend.Put(context.Background(), "some-key", value, 60))
+
+	metadataHeaders := []string{MetadataFormatHeader, MetadataSizeHeader, MetadataCreatedHeader, MetadataTTLRemainingHeader}
+
+	// Enabled
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{GetMetadataHeaders: true}))
+	rr := doMockGet(t, router, "some-key")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "json", rr.Header().Get(MetadataFormatHeader))
+	assert.Equal(t, fmt.Sprintf("%d", len(value)), rr.Header().Get(MetadataSizeHeader))
+	created, err := http.ParseTime(rr.Header().Get(MetadataCreatedHeader))
+	if assert.NoError(t, err, "Creation time should be an HTTP date") {
+		assert.WithinDuration(t, time.Now(), created, 2*time.Second)
+	}
+	remaining, err := strconv.Atoi(rr.Header().Get(MetadataTTLRemainingHeader))
+	if assert.NoError(t, err, "Remaining TTL should be a number of seconds") {
+		assert.True(t, remaining >= 58 && remaining <= 60, "Remaining TTL should be close to the stored TTL. Got %d", remaining)
+	}
+
+	// Disabled
+	router = httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
+	rr = doMockGet(t, router, "some-key")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	for _, header := range metadataHeaders {
+		assert.Empty(t, rr.Header().Get(header), "%s should not be set when disabled", header)
+	}
+}
+
+func TestGetMetadataHeadersOfLegacyValues(t *testing.T) {
+	delegate := backends.NewMemoryBackend()
+	assert.NoError(t, delegate.Put(context.Background(), "some-key", "xml<tag></tag>", 60))
+
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(envelope.Versioned(delegate, envelope.Version1), &metrics.Metrics{}, true, config.Routes{GetMetadataHeaders: true}))
+	rr := doMockGet(t, router, "some-key")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "xml", rr.Header().Get(MetadataFormatHeader))
+	assert.Equal(t, "14", rr.Header().Get(MetadataSizeHeader))
+	assert.Empty(t, rr.Header().Get(MetadataCreatedHeader), "Legacy values don't know when they were stored")
+	assert.Empty(t, rr.Header().Get(MetadataTTLRemainingHeader), "Legacy values don't know their TTL")
+}
+
+// truncatingBackend returns the values of its delegate without their last bytes, the way a backend
+// cutting reads short would
+type truncatingBackend struct {
+	backends.Backend
+	cut int
+}
+
+func (b *truncatingBackend) Get(ctx context.Context, key string) (string, error) {
+	value, err := b.Backend.Get(ctx, key)
+	if err != nil || len(value) < b.cut {
+		return value, err
+	}
+	return value[:len(value)-b.cut], nil
+}
+
+func TestGetTruncatedValue(t *testing.T) {
+	delegate := backends.NewMemoryBackend()
+	assert.NoError(t, envelope.Versioned(delegate, envelope.Version1).Put(context.Background(), "some-key", `json{"field":"value"}`, 60))
+
+	m := metricstest.CreateMockMetrics()
+	backend := backendDecorators.LogMetrics(envelope.Versioned(&truncatingBackend{Backend: delegate, cut: 5}, envelope.Version1), m)
+
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
+	rr := doMockGet(t, router, "some-key")
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code, "Truncated values should be reported as corrupt")
+	assert.NotContains(t, rr.Body.String(), `{"field"`, "Truncated values shouldn't be served")
+	assert.Equal(t, int64(1), metricstest.MockCounters["gets.backend_error.corrupt_value"])
+}
+
+func TestGetStalenessHeaders(t *testing.T) {
+	delegate := backends.NewMemoryBackend()
+	stored, err := envelope.Encode(envelope.Version1, envelope.Header{
+		Format:     "json",
+		CreatedAt:  time.Now().Add(-30 * time.Second).Unix(),
+		TTLSeconds: 120,
+	}, `json{"field":"value"}`)
+	assert.NoError(t, err)
+	assert.NoError(t, delegate.Put(context.Background(), "some-key", stored, 120))
+	assert.NoError(t, delegate.Put(context.Background(), "legacy-key", "xml<tag></tag>", 120))
+	backend := envelope.Versioned(delegate, envelope.Version1)
+
+	// Enabled
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{GetStalenessHeaders: true}))
+	rr := doMockGet(t, router, "some-key")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	age, err := strconv.Atoi(rr.Header().Get(AgeHeader))
+	if assert.NoError(t, err, "Age should be a number of seconds") {
+		assert.True(t, age >= 30 && age <= 32, "Age should be the time elapsed since the value was stored. Got %d", age)
+	}
+	var maxAge int
+	_, err = fmt.Sscanf(rr.Header().Get(CacheControlHeader), "max-age=%d", &maxAge)
+	if assert.NoError(t, err, "Cache-Control should carry a max-age") {
+		assert.True(t, maxAge >= 88 && maxAge <= 90, "max-age should be the remaining TTL. Got %d", maxAge)
+	}
+	assert.Empty(t, rr.Header().Get(MetadataCreatedHeader), "Metadata headers are enabled separately")
+
+	// Values stored without their creation time or TTL
+	rr = doMockGet(t, router, "legacy-key")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get(AgeHeader), "Legacy values don't know when they were stored")
+	assert.Empty(t, rr.Header().Get(CacheControlHeader), "Legacy values don't know their TTL")
+
+	// Disabled
+	router = httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
+	rr = doMockGet(t, router, "some-key")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get(AgeHeader), "Age should not be set when disabled")
+	assert.Empty(t, rr.Header().Get(CacheControlHeader), "Cache-Control should not be set when disabled")
+}
+
+func TestCorrelatedGetReportsWriter(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	correlationCfg := config.Correlation{Enabled: true, ClientIDHeader: "X-Client-Id"}
+	backend := envelope.Versioned(backends.NewMemoryBackend(), envelope.Version1)
+
+	router := httprouter.New()
+	router.POST("/cache", decorators.CorrelateWrites(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), correlationCfg))
+	router.GET("/cache", decorators.CorrelateReads(NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}), correlationCfg))
+
+	putRequest, _ := http.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true,"key":"correlated-key"}]}`))
+	putRequest.Header.Set("X-Client-Id", "partner-a")
+	putRecorder := httptest.NewRecorder()
+	router.ServeHTTP(putRecorder, putRequest)
+	assert.Equal(t, http.StatusOK, putRecorder.Code)
+
+	hook.Reset()
+	getRecorder := doMockGet(t, router, "correlated-key")
+	assert.Equal(t, http.StatusOK, getRecorder.Code)
+	assert.Equal(t, "true", getRecorder.Body.String())
+
+	if assert.NotNil(t, hook.LastEntry(), "The GET request should log who wrote the value") {
+		assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+		// Creation times are stored in whole seconds, so the value may look up to a second older
+		assert.Regexp(t, `^GET /cache uuid=correlated-key: written by partner-a [01] seconds before this read$`, hook.LastEntry().Message)
+	}
+
+	// Values written without a client id are not correlated
+	uuid, _ := doMockPut(t, router, `{"puts":[{"type":"json","value":true}]}`)
+	hook.Reset()
+	doMockGet(t, router, uuid)
+	assert.Nil(t, hook.LastEntry(), "Values written without a client id should not be logged")
+}
+
+func TestMetricsReset(t *testing.T) {
+	promMetrics := prometheusMetrics.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendMemory)
+	promMetrics.RecordPutTotal()
+
+	testCases := []struct {
+		desc           string
+		inMetrics      *metrics.Metrics
+		expectedStatus int
+	}{
+		{
+			desc:           "Prometheus metrics get reset",
+			inMetrics:      &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{promMetrics}},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			desc:           "No Prometheus metrics to reset",
+			inMetrics:      &metrics.Metrics{},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		router := httprouter.New()
+		router.POST("/admin/metrics/reset", NewMetricsResetHandler(test.inMetrics))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/admin/metrics/reset", nil))
+
+		assert.Equal(t, test.expectedStatus, rr.Code, test.desc)
+	}
+	putTotals := promMetrics.Puts.RequestStatus.With(prometheus.Labels{prometheusMetrics.StatusKey: prometheusMetrics.TotalsVal})
+	assert.Equal(t, 0.0, testutil.ToFloat64(putTotals), "Put totals should read zero after the reset")
+}
+
+func TestGetHitsAndMisses(t *testing.T) {
+	backend := backends.NewMemoryBackend()
+	backend.Put(context.Background(), "stored-key", "jsontrue", 60)
+
+	testCases := []struct {
+		desc           string
+		inQuery        string
+		expectedHits   float64
+		expectedMisses float64
+	}{
+		{
+			desc:         "Key holding a value",
+			inQuery:      "uuid=stored-key",
+			expectedHits: 1,
+		},
+		{
+			desc:           "Key without a value",
+			inQuery:        "uuid=missing-key",
+			expectedMisses: 1,
+		},
+		{
+			desc:           "Batch of keys with and without a value",
+			inQuery:        "uuid=stored-key&uuid=missing-key&uuid=stored-key",
+			expectedHits:   2,
+			expectedMisses: 1,
+		},
+		{
+			desc:    "Key that's never looked up",
+			inQuery: "uuid=",
+		},
+	}
+
+	for _, tc := range testCases {
+		promMetrics := prometheusMetrics.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendMemory)
+		m := &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{promMetrics}}
+		routes := config.Routes{GetBatch: config.GetBatch{Enabled: true, MaxKeys: 10, Workers: 2}}
+		router := httprouter.New()
+		router.GET("/cache", NewGetHandler(backend, m, true, routes))
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/cache?"+tc.inQuery, nil))
+
+		hits := promMetrics.GetsBackend.Results.With(prometheus.Labels{prometheusMetrics.ResultKey: prometheusMetrics.HitVal})
+		misses := promMetrics.GetsBackend.Results.With(prometheus.Labels{prometheusMetrics.ResultKey: prometheusMetrics.MissVal})
+		assert.Equal(t, tc.expectedHits, testutil.ToFloat64(hits), "%s: hits", tc.desc)
+		assert.Equal(t, tc.expectedMisses, testutil.ToFloat64(misses), "%s: misses", tc.desc)
+	}
+}
+
+func TestGetContentTypes(t *testing.T) {
+	backend := backends.NewMemoryBackend()
+	backend.Put(context.Background(), "xml-key", "xml<tag></tag>", 60)
+	backend.Put(context.Background(), "json-key", `json{"field":"value"}`, 60)
+
+	testCases := []struct {
+		desc             string
+		inContentTypes   map[string]string
+		expectedXMLType  string
+		expectedJSONType string
+	}{
+		{
+			desc:             "Defaults",
+			expectedXMLType:  "application/xml",
+			expectedJSONType: "application/json",
+		},
+		{
+			desc:             "XML overridden",
+			inContentTypes:   map[string]string{"xml": "text/xml"},
+			expectedXMLType:  "text/xml",
+			expectedJSONType: "application/json",
+		},
+		{
+			desc:             "Both overridden",
+			inContentTypes:   map[string]string{"xml": "text/xml; charset=utf-8", "json": "application/vnd.prebid+json"},
+			expectedXMLType:  "text/xml; charset=utf-8",
+			expectedJSONType: "application/vnd.prebid+json",
+		},
+	}
+
+	for _, tc := range testCases {
+		router := httprouter.New()
+		router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{GetContentTypes: tc.inContentTypes}))
+
+		xmlRecorder := doMockGet(t, router, "xml-key")
+		assert.Equal(t, http.StatusOK, xmlRecorder.Code, tc.desc)
+		assert.Equal(t, "<tag></tag>", xmlRecorder.Body.String(), tc.desc)
+		assert.Equal(t, tc.expectedXMLType, xmlRecorder.Header().Get("Content-Type"), tc.desc)
+
+		jsonRecorder := doMockGet(t, router, "json-key")
+		assert.Equal(t, http.StatusOK, jsonRecorder.Code, tc.desc)
+		assert.Equal(t, tc.expectedJSONType, jsonRecorder.Header().Get("Content-Type"), tc.desc)
+	}
+}
+
+func TestGetHonorAccept(t *testing.T) {
+	backend := backends.NewMemoryBackend()
+	backend.Put(context.Background(), "xml-key", "xml<tag></tag>", 60)
+
+	testCases := []struct {
+		desc           string
+		inHonorAccept  bool
+		inContentTypes map[string]string
+		inAccept       string
+		expectedStatus int
+	}{
+		{
+			desc:           "Accept allows the stored format",
+			inHonorAccept:  true,
+			inAccept:       "application/json, application/xml;q=0.5",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Accept allows any subtype of the stored format",
+			inHonorAccept:  true,
+			inAccept:       "application/*",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Request without an Accept header",
+			inHonorAccept:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Accept doesn't allow the stored format",
+			inHonorAccept:  true,
+			inAccept:       "application/json",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+		{
+			desc:           "Accept excludes the stored format with a zero quality",
+			inHonorAccept:  true,
+			inAccept:       "application/xml;q=0",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+		{
+			desc:           "Accept allows the overridden Content-Type",
+			inHonorAccept:  true,
+			inContentTypes: map[string]string{"xml": "text/xml; charset=utf-8"},
+			inAccept:       "text/xml",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Accept ignored by default",
+			inAccept:       "application/json",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockMetrics := metricstest.CreateMockMetrics()
+		routes := config.Routes{GetHonorAccept: tc.inHonorAccept, GetContentTypes: tc.inContentTypes}
+		router := httprouter.New()
+		router.GET("/cache", decorators.MonitorHttp(NewGetHandler(backend, &metrics.Metrics{}, true, routes), mockMetrics, decorators.GetMethod))
+
+		request := httptest.NewRequest("GET", "/cache?uuid=xml-key", nil)
+		if tc.inAccept != "" {
+			request.Header.Set("Accept", tc.inAccept)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		if tc.expectedStatus == http.StatusOK {
+			assert.Equal(t, "<tag></tag>", rr.Body.String(), tc.desc)
+			assert.Equal(t, int64(0), metricstest.MockCounters["gets.current_url.request.bad_request"], tc.desc)
+		} else {
+			assert.Equal(t, int64(1), metricstest.MockCounters["gets.current_url.request.bad_request"], "%s: should be recorded as a bad request", tc.desc)
+		}
+	}
+}
+
+func TestGetMaxHeaderBytes(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	backend := envelope.Versioned(backends.NewMemoryBackend(), envelope.Version1)
+	assert.NoError(t, backend.Put(context.Background(), "some-key", `json{"field":"value"}`, 60))
+
+	// Every custom header takes 115 bytes, and the Content-Type takes 32
+	customHeaders := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		customHeaders[fmt.Sprintf("x-custom-%02d", i)] = strings.Repeat("v", 100)
+	}
+
+	testCases := []struct {
+		desc             string
+		inMaxHeaderBytes int
+		expectedCustom   int
+		expectedMetadata []string
+		expectedWarning  bool
+	}{
+		{
+			desc:             "No cap",
+			expectedCustom:   10,
+			expectedMetadata: []string{MetadataFormatHeader, MetadataSizeHeader, MetadataCreatedHeader, MetadataTTLRemainingHeader},
+		},
+		{
+			desc:             "Cap leaves room for the custom headers and some of the metadata ones",
+			inMaxHeaderBytes: 32 + 10*115 + 22,
+			expectedCustom:   10,
+			expectedMetadata: []string{MetadataFormatHeader},
+			expectedWarning:  true,
+		},
+		{
+			desc:             "Cap leaves room for some of the custom headers",
+			inMaxHeaderBytes: 700,
+			expectedCustom:   5,
+			expectedWarning:  true,
+		},
+		{
+			desc:             "Cap leaves room for the Content-Type only",
+			inMaxHeaderBytes: 40,
+			expectedWarning:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		hook.Reset()
+		routes := config.Routes{GetMetadataHeaders: true, GetCustomHeaders: customHeaders, GetMaxHeaderBytes: tc.inMaxHeaderBytes}
+		router := httprouter.New()
+		router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, routes))
+
+		rr := doMockGet(t, router, "some-key")
+
+		assert.Equal(t, http.StatusOK, rr.Code, tc.desc)
+		assert.Equal(t, `{"field":"value"}`, rr.Body.String(), tc.desc)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), tc.desc)
+		if tc.inMaxHeaderBytes > 0 {
+			headerBytes := 0
+			for name, values := range rr.Header() {
+				for _, value := range values {
+					headerBytes += len(name) + len(value) + 4
+				}
+			}
+			assert.True(t, headerBytes <= tc.inMaxHeaderBytes, "%s: headers take %d bytes", tc.desc, headerBytes)
+		}
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("X-Custom-%02d", i)
+			assert.Equal(t, i < tc.expectedCustom, rr.Header().Get(name) != "", "%s: %s", tc.desc, name)
+		}
+		for _, name := range []string{MetadataFormatHeader, MetadataSizeHeader, MetadataCreatedHeader, MetadataTTLRemainingHeader} {
+			expected := false
+			for _, kept := range tc.expectedMetadata {
+				expected = expected || kept == name
+			}
+			assert.Equal(t, expected, rr.Header().Get(name) != "", "%s: %s", tc.desc, name)
+		}
+		if tc.expectedWarning {
+			if assert.NotNil(t, hook.LastEntry(), tc.desc) {
+				assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level, tc.desc)
+				assert.Contains(t, hook.LastEntry().Message, fmt.Sprintf("to keep the response headers within %d bytes", tc.inMaxHeaderBytes), tc.desc)
+			}
+		} else {
+			assert.Nil(t, hook.LastEntry(), tc.desc)
+		}
+	}
+}
+
+func TestGetWithPathKey(t *testing.T) {
+	backend := backends.NewMemoryBackend()
+	assert.NoError(t, backend.Put(context.Background(), "36-char-key-maaaaaaaaaaaaaaaaaaaaaaa", `json{"field":"value"}`, 60))
+	assert.NoError(t, backend.Put(context.Background(), "some-key", "xml<tag></tag>", 60))
+
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, false, config.Routes{}))
+	router.GET("/cache/:uuid", NewGetHandler(backend, &metrics.Metrics{}, false, config.Routes{}))
+
+	testCases := []struct {
+		desc           string
+		inKey          string
+		expectedStatus int
+	}{
+		{
+			desc:           "Hit",
+			inKey:          "36-char-key-maaaaaaaaaaaaaaaaaaaaaaa",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Miss",
+			inKey:          "36-char-key-missaaaaaaaaaaaaaaaaaaaa",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			desc:           "Key that isn't a UUID",
+			inKey:          "some-key",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		queryRecorder := doMockGet(t, router, tc.inKey)
+
+		pathRecorder := httptest.NewRecorder()
+		router.ServeHTTP(pathRecorder, httptest.NewRequest("GET", "/cache/"+tc.inKey, nil))
+
+		assert.Equal(t, tc.expectedStatus, queryRecorder.Code, tc.desc)
+		assert.Equal(t, queryRecorder.Code, pathRecorder.Code, "%s: path and query keys should get the same status", tc.desc)
+		assert.Equal(t, queryRecorder.Body.String(), pathRecorder.Body.String(), "%s: path and query keys should get the same body", tc.desc)
+		assert.Equal(t, queryRecorder.Header().Get("Content-Type"), pathRecorder.Header().Get("Content-Type"), tc.desc)
+	}
+}
+
+// slowBackend takes delay to respond to every operation, unless their context expires first. It keeps
+// the deadline of the last operation.
+type slowBackend struct {
+	delay    time.Duration
+	deadline time.Time
+}
+
+func (b *slowBackend) Get(ctx context.Context, key string) (string, error) {
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	return `json"slow value"`, nil
+}
+
+func (b *slowBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return b.wait(ctx)
+}
+
+func (b *slowBackend) wait(ctx context.Context) error {
+	b.deadline, _ = ctx.Deadline()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(b.delay):
+		return nil
+	}
+}
+
+func TestClientDeadlines(t *testing.T) {
+	deadlinesCfg := config.ClientDeadlines{Enabled: true, Header: "X-Deadline-Ms", MaxMillis: 500}
+
+	testCases := []struct {
+		desc           string
+		inDeadline     string
+		expectedStatus int
+		expectedWithin time.Duration
+	}{
+		{
+			desc:           "Short client deadline expires before the backend responds",
+			inDeadline:     "20",
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedWithin: 20 * time.Millisecond,
+		},
+		{
+			desc:           "No client deadline gets the server default",
+			expectedStatus: http.StatusOK,
+			expectedWithin: backendTimeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := &slowBackend{delay: 200 * time.Millisecond}
+		router := httprouter.New()
+		router.GET("/cache", decorators.HonorClientDeadlines(NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}), deadlinesCfg))
+		router.POST("/cache", decorators.HonorClientDeadlines(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), deadlinesCfg))
+
+		requests := map[string]*http.Request{
+			"GET": httptest.NewRequest("GET", "/cache?uuid=some-key", nil),
+			"PUT": httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true}]}`)),
+		}
+		for method, request := range requests {
+			if tc.inDeadline != "" {
+				request.Header.Set("X-Deadline-Ms", tc.inDeadline)
+			}
+			rr := httptest.NewRecorder()
+			start := time.Now()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "%s: %s", method, tc.desc)
+			assert.WithinDuration(t, start.Add(tc.expectedWithin), backend.deadline, 50*time.Millisecond, "%s: %s", method, tc.desc)
+			if tc.expectedStatus == http.StatusGatewayTimeout {
+				assert.True(t, time.Since(start) < backend.delay, "%s: %s should respond before the backend does", method, tc.desc)
+			}
+		}
+	}
+}
+
+func TestClientDeadlineCoversBatch(t *testing.T) {
+	deadlinesCfg := config.ClientDeadlines{Enabled: true, Header: "X-Deadline-Ms", MaxMillis: 500}
+	backend := &slowBackend{delay: 40 * time.Millisecond}
+	router := httprouter.New()
+	router.POST("/cache", decorators.HonorClientDeadlines(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), deadlinesCfg))
+
+	// Each put fits within the deadline, but the three of them don't
+	request := httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":1},{"type":"json","value":2},{"type":"json","value":3}]}`))
+	request.Header.Set("X-Deadline-Ms", "100")
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code, "The batch should fail once the client deadline passes")
+	assert.WithinDuration(t, start.Add(100*time.Millisecond), backend.deadline, 20*time.Millisecond, "The last put should get what's left of the client deadline")
+	assert.True(t, time.Since(start) < 3*backend.delay, "The batch should respond by the client deadline")
+}
+
+// failingBackend fails every Get and Put with its error
+type failingBackend struct {
+	err error
+}
+
+func (b *failingBackend) Get(ctx context.Context, key string) (string, error) {
+	return "", b.err
+}
+
+func (b *failingBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return b.err
+}
+
+func TestErrorCodes(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inErr          error
+		expectedCode   utils.ErrorCode
+		expectedStatus map[string]int
+	}{
+		{
+			desc:           "Backend unavailable",
+			inErr:          errors.New("gocql: no hosts available in the pool"),
+			expectedCode:   utils.BackendUnavailable,
+			expectedStatus: map[string]int{"GET": http.StatusNotFound, "PUT": http.StatusInternalServerError},
+		},
+		{
+			desc:           "Value too large",
+			inErr:          utils.NewBackendError(utils.ValueTooLarge, errors.New("Record too big")),
+			expectedCode:   utils.ValueTooLarge,
+			expectedStatus: map[string]int{"GET": http.StatusNotFound, "PUT": http.StatusInternalServerError},
+		},
+		{
+			desc:           "Conflict",
+			inErr:          utils.NewBackendError(utils.Conflict, errors.New("Key already exists")),
+			expectedCode:   utils.Conflict,
+			expectedStatus: map[string]int{"GET": http.StatusNotFound, "PUT": http.StatusInternalServerError},
+		},
+		{
+			desc:           "Not found",
+			inErr:          utils.KeyNotFoundError{},
+			expectedCode:   utils.NotFound,
+			expectedStatus: map[string]int{"GET": http.StatusNotFound, "PUT": http.StatusInternalServerError},
+		},
+		{
+			desc:           "Timeout",
+			inErr:          context.DeadlineExceeded,
+			expectedCode:   utils.Timeout,
+			expectedStatus: map[string]int{"GET": http.StatusNotFound, "PUT": HttpDependencyTimeout},
+		},
+	}
+
+	routesCfg := config.Routes{ErrorCodes: true}
+	for _, tc := range testCases {
+		backend := &failingBackend{err: tc.inErr}
+		router := httprouter.New()
+		router.GET("/cache", decorators.ReportErrorCodes(NewGetHandler(backend, &metrics.Metrics{}, true, routesCfg), routesCfg))
+		router.POST("/cache", decorators.ReportErrorCodes(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), routesCfg))
+
+		for method, request := range newErrorCodeRequests() {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			var body utils.ErrorResponse
+			assert.Equal(t, tc.expectedStatus[method], rr.Code, "%s: %s", method, tc.desc)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "%s: %s", method, tc.desc)
+			if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body), "%s: %s", method, tc.desc) {
+				assert.Equal(t, tc.expectedCode, body.BackendCode, "%s: %s", method, tc.desc)
+				assert.NotEmpty(t, body.Error, "%s: %s", method, tc.desc)
+			}
+		}
+	}
+}
+
+func TestErrorCodesOfOversizedValues(t *testing.T) {
+	routesCfg := config.Routes{ErrorCodes: true}
+	backend := backendDecorators.EnforceSizeLimit(backends.NewMemoryBackend(), 2)
+	router := httprouter.New()
+	router.POST("/cache", decorators.ReportErrorCodes(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), routesCfg))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, newErrorCodeRequests()["PUT"])
+
+	var body utils.ErrorResponse
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body)) {
+		assert.Equal(t, utils.ValueTooLarge, body.BackendCode)
+	}
+}
+
+func TestErrorCodesDisabled(t *testing.T) {
+	backend := &failingBackend{err: utils.NewBackendError(utils.Conflict, errors.New("Key already exists"))}
+	router := httprouter.New()
+	router.GET("/cache", decorators.ReportErrorCodes(NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}), config.Routes{}))
+	router.POST("/cache", decorators.ReportErrorCodes(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), config.Routes{}))
+
+	for method, request := range newErrorCodeRequests() {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var body utils.ErrorResponse
+		if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body), method) {
+			assert.Contains(t, body.Error, "Key already exists", method)
+			assert.Empty(t, body.BackendCode, "%s responses should leave the backend code out", method)
+		}
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	const value = `{"puts":[{"type":"json","value":"some-value"}]}`
+	memory := backends.NewMemoryBackend()
+	writers := config.Auth{Enabled: true, AllowedCIDRs: []string{"10.0.0.0/8"}}
+
+	testCases := []struct {
+		desc           string
+		inHandler      httprouter.Handle
+		inRequest      *http.Request
+		expectedStatus int
+		expectedCode   utils.ResponseCode
+	}{
+		{
+			desc:           "Malformed request body",
+			inHandler:      NewPutHandler(memory, &metrics.Metrics{}, 10, 100, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false),
+			inRequest:      httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":`)),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   utils.BadRequestCode,
+		},
+		{
+			desc:           "Client outside the allowed networks",
+			inHandler:      decorators.AllowWriters(NewPutHandler(memory, &metrics.Metrics{}, 10, 100, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), metricstest.CreateMockMetrics(), writers),
+			inRequest:      httptest.NewRequest("POST", "/cache", strings.NewReader(value)),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   utils.UnauthorizedCode,
+		},
+		{
+			desc:           "Key not found",
+			inHandler:      NewGetHandler(memory, &metrics.Metrics{}, true, config.Routes{}),
+			inRequest:      httptest.NewRequest("GET", "/cache?uuid=some-key", nil),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   utils.NotFoundCode,
+		},
+		{
+			desc:           "Value over the max size",
+			inHandler:      NewPutHandler(memory, &metrics.Metrics{}, 10, 4, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false),
+			inRequest:      httptest.NewRequest("POST", "/cache", strings.NewReader(value)),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   utils.PayloadTooLargeCode,
+		},
+		{
+			desc:           "Backend failure",
+			inHandler:      NewPutHandler(&failingBackend{err: errors.New("connection refused")}, &metrics.Metrics{}, 10, 100, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false),
+			inRequest:      httptest.NewRequest("POST", "/cache", strings.NewReader(value)),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   utils.BackendErrorCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		router := httprouter.New()
+		router.Handle(tc.inRequest.Method, "/cache", tc.inHandler)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, tc.inRequest)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), tc.desc)
+		var body map[string]string
+		if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body), tc.desc) {
+			assert.Equal(t, string(tc.expectedCode), body["code"], tc.desc)
+			assert.NotEmpty(t, body["error"], tc.desc)
+			assert.Len(t, body, 2, "%s: error responses should only carry the error and its code", tc.desc)
+		}
+	}
+}
+
+func TestRequestIDLogged(t *testing.T) {
+	hook := test.NewGlobal()
+	backend := &failingBackend{err: errors.New("connection refused")}
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
+	router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 100, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+	handler := decorators.TagRequestIDs(router, config.RequestID{Enabled: true, Header: "X-Request-ID"})
+
+	for method, request := range newErrorCodeRequests() {
+		hook.Reset()
+		request.Header.Set("X-Request-ID", "pbs-"+method)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, request)
+
+		assert.Equal(t, "pbs-"+method, rr.Header().Get("X-Request-ID"), method)
+		if assert.NotEmpty(t, hook.Entries, method) {
+			for _, entry := range hook.Entries {
+				assert.Equal(t, "pbs-"+method, entry.Data["request_id"], "%s: %s", method, entry.Message)
+			}
+		}
+	}
+}
+
+func newErrorCodeRequests() map[string]*http.Request {
+	return map[string]*http.Request{
+		"GET": httptest.NewRequest("GET", "/cache?uuid=some-key", nil),
+		"PUT": httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true}]}`)),
+	}
+}
+
+func TestPutRequestBodyLimit(t *testing.T) {
+	smallPut := `{"puts":[{"type":"json","value":true}]}`
+	largePut := `{"puts":[{"type":"json","value":"` + strings.Repeat("a", 100) + `"}]}`
+
+	testCases := []struct {
+		desc           string
+		inRequest      *http.Request
+		expectedStatus int
+	}{
+		{
+			desc:           "Declared Content-Length over the limit",
+			inRequest:      httptest.NewRequest("POST", "/cache", strings.NewReader(largePut)),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:           "Chunked body within the limit",
+			inRequest:      httptest.NewRequest("POST", "/cache", ioutil.NopCloser(strings.NewReader(smallPut))),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Chunked body over the limit",
+			inRequest:      httptest.NewRequest("POST", "/cache", ioutil.NopCloser(strings.NewReader(largePut))),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := backends.NewMemoryBackend()
+		router := httprouter.New()
+		router.POST("/cache", decorators.LimitRequestBodies(NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false), 64))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, tc.inRequest)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		keys, _, _ := backend.ScanKeys(context.Background(), 0, 10)
+		if tc.expectedStatus == http.StatusOK {
+			assert.Len(t, keys, 1, tc.desc)
+		} else {
+			assert.Empty(t, keys, "%s: nothing should be stored", tc.desc)
+		}
+	}
+}
+
+func TestPutGzipBodies(t *testing.T) {
+	value := `"` + strings.Repeat("a", 100) + `"`
+	put := `{"puts":[{"type":"json","value":` + value + `}]}`
+
+	testCases := []struct {
+		desc           string
+		inBody         string
+		inMaxBytes     int
+		expectedStatus int
+	}{
+		{
+			desc:           "Gzip body gets stored",
+			inBody:         put,
+			inMaxBytes:     1024,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Gzip body that decompresses past the limit",
+			inBody:         put,
+			inMaxBytes:     64,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:           "Malformed gzip stream",
+			inMaxBytes:     1024,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		var body bytes.Buffer
+		gz := gzip.NewWriter(&body)
+		gz.Write([]byte(tc.inBody))
+		gz.Close()
+		if tc.inBody == "" {
+			// Keep the gzip header, but cut the stream off half way through
+			body.Truncate(body.Len() / 2)
+		}
+
+		backend := backends.NewMemoryBackend()
+		mockMetrics := metricstest.CreateMockMetrics()
+		handler := NewPutHandler(backendDecorators.LogMetrics(backend, mockMetrics), mockMetrics, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false)
+		router := httprouter.New()
+		router.POST("/cache", decorators.DecompressRequestBodies(handler, config.RequestDecompression{Enabled: true, MaxSizeBytes: tc.inMaxBytes}))
+
+		request := httptest.NewRequest("POST", "/cache", &body)
+		request.Header.Set("Content-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		keys, _, _ := backend.ScanKeys(context.Background(), 0, 10)
+		if tc.expectedStatus == http.StatusOK {
+			assert.Len(t, keys, 1, tc.desc)
+			assert.Equal(t, float64(len(backends.JSON_PREFIX+value)), metricstest.MockHistograms["puts.backends.request_size_bytes"], "%s: the decompressed size should be recorded", tc.desc)
+		} else {
+			assert.Empty(t, keys, "%s: nothing should be stored", tc.desc)
+		}
+	}
+}
+
+func TestPutMaxNumValues(t *testing.T) {
+	validPut := `{"type":"json","value":true}`
+
+	testCases := []struct {
+		desc           string
+		inPuts         []string
+		expectedStatus int
+		expectedStored int
+	}{
+		{
+			desc:           "As many values as allowed",
+			inPuts:         []string{validPut, validPut, validPut},
+			expectedStatus: http.StatusOK,
+			expectedStored: 3,
+		},
+		{
+			desc:           "More values than allowed",
+			inPuts:         []string{validPut, validPut, validPut, validPut},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "No values",
+			inPuts:         []string{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Invalid value after valid ones",
+			inPuts:         []string{validPut, validPut, `{"type":"yaml","value":true}`},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := backends.NewMemoryBackend()
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 3, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[`+strings.Join(tc.inPuts, ",")+`]}`)))
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		keys, _, _ := backend.ScanKeys(context.Background(), 0, 10)
+		assert.Len(t, keys, tc.expectedStored, "%s: only valid requests should store values", tc.desc)
+		if tc.expectedStatus == http.StatusOK {
+			var resp PutResponse
+			if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), tc.desc) {
+				assert.Len(t, resp.Responses, len(tc.inPuts), tc.desc)
+			}
+		}
+	}
+}
+
+func TestPutValueSizeLimit(t *testing.T) {
+	// Values are measured as stored, along with their "json" prefix
+	const maxValueSize = 24
+	underLimit := `"` + strings.Repeat("a", maxValueSize-len(backends.JSON_PREFIX)-3) + `"`
+	overLimit := `"` + strings.Repeat("a", maxValueSize-len(backends.JSON_PREFIX)-1) + `"`
+
+	testCases := []struct {
+		desc           string
+		inValue        string
+		inGzip         bool
+		expectedStatus int
+	}{
+		{
+			desc:           "Value just under the limit",
+			inValue:        underLimit,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Value just over the limit",
+			inValue:        overLimit,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:           "Compressed body whose value is just over the limit once decompressed",
+			inValue:        overLimit,
+			inGzip:         true,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		body := []byte(`{"puts":[{"type":"json","value":` + tc.inValue + `}]}`)
+		if tc.inGzip {
+			var compressed bytes.Buffer
+			gz := gzip.NewWriter(&compressed)
+			gz.Write(body)
+			gz.Close()
+			body = compressed.Bytes()
+		}
+
+		backend := backends.NewMemoryBackend()
+		mockMetrics := metricstest.CreateMockMetrics()
+		handler := NewPutHandler(backend, mockMetrics, 10, maxValueSize, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false)
+		router := httprouter.New()
+		router.POST("/cache", decorators.DecompressRequestBodies(handler, config.RequestDecompression{Enabled: true, MaxSizeBytes: 1024}))
+
+		request := httptest.NewRequest("POST", "/cache", bytes.NewReader(body))
+		if tc.inGzip {
+			request.Header.Set("Content-Encoding", "gzip")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		keys, _, _ := backend.ScanKeys(context.Background(), 0, 10)
+		if tc.expectedStatus == http.StatusOK {
+			assert.Len(t, keys, 1, tc.desc)
+			assert.Equal(t, int64(0), metricstest.MockCounters["puts.backends.oversized"], tc.desc)
+		} else {
+			assert.Empty(t, keys, "%s: nothing should be stored", tc.desc)
+			assert.Contains(t, rr.Body.String(), "over the max of 24 bytes", tc.desc)
+			assert.Equal(t, int64(1), metricstest.MockCounters["puts.backends.oversized"], tc.desc)
+		}
+	}
+}
+
+// forgetfulBackend acknowledges every Put without storing it
+type forgetfulBackend struct {
+	backends.Backend
+}
+
+func (b *forgetfulBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return nil
+}
+
+func TestPutVerifyWrites(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inBackend      backends.Backend
+		expectedStatus int
+	}{
+		{
+			desc:           "Stored value is read back",
+			inBackend:      backends.NewMemoryBackend(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Backend silently drops the write",
+			inBackend:      &forgetfulBackend{Backend: backends.NewMemoryBackend()},
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		backend := backendDecorators.VerifyWrites(tc.inBackend, m)
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true}]}`)))
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		if tc.expectedStatus == http.StatusBadGateway {
+			assert.Equal(t, int64(1), metricstest.MockCounters["puts.backends.verification_failed"], tc.desc)
+		}
+	}
+}
+
+// collidingKeys returns a key generator handing out keys, in order, then failing
+func collidingKeys(keys ...string) func(config.KeyFormat) (string, error) {
+	return func(config.KeyFormat) (string, error) {
+		if len(keys) == 0 {
+			return "", errors.New("out of keys")
+		}
+		key := keys[0]
+		keys = keys[1:]
+		return key, nil
+	}
+}
+
+func TestPutCollisionGuard(t *testing.T) {
+	defer func(original func(config.KeyFormat) (string, error)) { generateKey = original }(generateKey)
+
+	testCases := []struct {
+		desc             string
+		inGuard          config.CollisionGuard
+		expectedStatus   int
+		expectedUUID     string
+		expectedTakenVal string
+	}{
+		{
+			desc:             "Collision resolved by a new key",
+			inGuard:          config.CollisionGuard{Enabled: true, MaxRetries: 3},
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "fresh-key",
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "No retries left to resolve the collision",
+			inGuard:          config.CollisionGuard{Enabled: true, MaxRetries: 0},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Guard disabled",
+			inGuard:          config.CollisionGuard{},
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "taken-key",
+			expectedTakenVal: "jsontrue",
+		},
+	}
+
+	for _, tc := range testCases {
+		generateKey = collidingKeys("taken-key", "fresh-key")
+		m := metricstest.CreateMockMetrics()
+		base := backends.NewMemoryBackend()
+		base.Put(context.Background(), "taken-key", "json\"stored before\"", 0)
+		backend := backendDecorators.GuardCollisions(base, base, m)
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, false, false, tc.inGuard, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true}]}`)))
+
+		if !assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc) {
+			continue
+		}
+		if tc.expectedStatus == http.StatusOK {
+			assert.Equal(t, `{"responses":[{"uuid":"`+tc.expectedUUID+`"}]}`, rr.Body.String(), tc.desc)
+			stored, err := base.Get(context.Background(), tc.expectedUUID)
+			assert.NoError(t, err, tc.desc)
+			assert.Equal(t, "jsontrue", stored, tc.desc)
+		}
+		taken, _ := base.Get(context.Background(), "taken-key")
+		assert.Equal(t, tc.expectedTakenVal, taken, tc.desc)
+		if tc.inGuard.Enabled {
+			assert.Equal(t, int64(1), metricstest.MockCounters["puts.backends.key_collisions"], tc.desc)
+		}
+	}
+}
+
+func TestPutCustomKeys(t *testing.T) {
+	defer func(original func(config.KeyFormat) (string, error)) { generateKey = original }(generateKey)
+
+	testCases := []struct {
+		desc             string
+		inAllowKeys      bool
+		inGuard          config.CollisionGuard
+		inPut            string
+		expectedStatus   int
+		expectedUUID     string
+		expectedTakenVal string
+	}{
+		{
+			desc:             "Generated key",
+			inAllowKeys:      true,
+			inPut:            `{"type":"json","value":true}`,
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "generated-key",
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Custom key",
+			inAllowKeys:      true,
+			inPut:            `{"type":"json","value":true,"key":"bid-123.vast"}`,
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "bid-123.vast",
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Custom key already holding a value gets a blank UUID",
+			inAllowKeys:      true,
+			inPut:            `{"type":"json","value":true,"key":"taken-key"}`,
+			expectedStatus:   http.StatusOK,
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Custom key already holding a value, checked by the collision guard",
+			inAllowKeys:      true,
+			inGuard:          config.CollisionGuard{Enabled: true, MaxRetries: 3},
+			inPut:            `{"type":"json","value":true,"key":"taken-key"}`,
+			expectedStatus:   http.StatusOK,
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Custom key overwritten on purpose",
+			inAllowKeys:      true,
+			inPut:            `{"type":"json","value":true,"key":"taken-key","allowOverwrite":true}`,
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "taken-key",
+			expectedTakenVal: "jsontrue",
+		},
+		{
+			desc:             "Custom key overwritten on purpose through the collision guard",
+			inAllowKeys:      true,
+			inGuard:          config.CollisionGuard{Enabled: true, MaxRetries: 3},
+			inPut:            `{"type":"json","value":true,"key":"taken-key","allowOverwrite":true}`,
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "taken-key",
+			expectedTakenVal: "jsontrue",
+		},
+		{
+			desc:             "Key management disabled ignores the custom key",
+			inPut:            `{"type":"json","value":true,"key":"taken-key"}`,
+			expectedStatus:   http.StatusOK,
+			expectedUUID:     "generated-key",
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Custom key with characters outside the charset",
+			inAllowKeys:      true,
+			inPut:            `{"type":"json","value":true,"key":"bid 123/vast"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedTakenVal: "json\"stored before\"",
+		},
+		{
+			desc:             "Custom key too long",
+			inAllowKeys:      true,
+			inPut:            `{"type":"json","value":true,"key":"` + strings.Repeat("k", maxKeyLength+1) + `"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedTakenVal: "json\"stored before\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		generateKey = collidingKeys("generated-key")
+		base := backends.NewMemoryBackend()
+		base.Put(context.Background(), "taken-key", "json\"stored before\"", 0)
+		var backend backends.Backend = base
+		if tc.inGuard.Enabled {
+			backend = backendDecorators.GuardCollisions(base, base, metricstest.CreateMockMetrics())
+		}
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backend, &metrics.Metrics{}, 10, 0, tc.inAllowKeys, false, tc.inGuard, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[`+tc.inPut+`]}`)))
+
+		if !assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc) {
+			continue
+		}
+		if tc.expectedStatus == http.StatusOK {
+			assert.Equal(t, `{"responses":[{"uuid":"`+tc.expectedUUID+`"}]}`, rr.Body.String(), tc.desc)
+		}
+		if tc.expectedUUID != "" {
+			stored, err := base.Get(context.Background(), tc.expectedUUID)
+			assert.NoError(t, err, tc.desc)
+			assert.Equal(t, "jsontrue", stored, tc.desc)
+		}
+		taken, _ := base.Get(context.Background(), "taken-key")
+		assert.Equal(t, tc.expectedTakenVal, taken, tc.desc)
+	}
+}
+
+func TestPutKeyFormats(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		inFormat        config.KeyFormat
+		expectedVersion string
+	}{
+		{
+			desc:            "Random keys",
+			inFormat:        config.KeyFormatUUIDv4,
+			expectedVersion: "4",
+		},
+		{
+			desc:            "Time-ordered keys",
+			inFormat:        config.KeyFormatUUIDv7,
+			expectedVersion: "7",
+		},
+	}
+
+	for _, tc := range testCases {
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backends.NewMemoryBackend(), &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, tc.inFormat, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true}]}`)))
+
+		var resp PutResponse
+		if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), tc.desc) && assert.Len(t, resp.Responses, 1, tc.desc) {
+			key := resp.Responses[0].UUID
+			assert.Len(t, key, 36, tc.desc)
+			assert.Equal(t, tc.expectedVersion, key[14:15], "The version digit of the key should match its format. "+tc.desc)
+		}
+	}
+}
+
+func TestGetBatch(t *testing.T) {
+	delegate := backends.NewMemoryBackend()
+	delegate.Put(context.Background(), "json-key", `json{"field":"value"}`, 60)
+	delegate.Put(context.Background(), "xml-key", "xml<tag></tag>", 60)
+	delegate.Put(context.Background(), "corrupt-key", "value without a format", 60)
+
+	// The mock metrics can't be recorded from several goroutines at once, see TestGetBatchWorkers
+	batchCfg := config.GetBatch{Enabled: true, MaxKeys: 3, Workers: 1}
+
+	testCases := []struct {
+		desc                string
+		inQuery             string
+		expectedStatus      int
+		expectedResponses   []GetBatchResponseObject
+		expectedBackendGets int64
+	}{
+		{
+			desc:           "All keys found",
+			inQuery:        "uuid=json-key&uuid=xml-key",
+			expectedStatus: http.StatusOK,
+			expectedResponses: []GetBatchResponseObject{
+				{UUID: "json-key", Type: "json", Value: json.RawMessage(`{"field":"value"}`)},
+				{UUID: "xml-key", Type: "xml", Value: json.RawMessage(`"<tag></tag>"`)},
+			},
+			expectedBackendGets: 2,
+		},
+		{
+			desc:           "Some keys missing",
+			inQuery:        "uuid=missing-key&uuid=json-key&uuid=corrupt-key",
+			expectedStatus: http.StatusOK,
+			expectedResponses: []GetBatchResponseObject{
+				{UUID: "missing-key", NotFound: true},
+				{UUID: "json-key", Type: "json", Value: json.RawMessage(`{"field":"value"}`)},
+				{UUID: "corrupt-key", Error: "Cache data was corrupted. Cannot determine type."},
+			},
+			expectedBackendGets: 3,
+		},
+		{
+			desc:           "More keys than allowed",
+			inQuery:        "uuid=json-key&uuid=xml-key&uuid=missing-key&uuid=corrupt-key",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "Empty key",
+			inQuery:        "uuid=json-key&uuid=",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		router := httprouter.New()
+		router.GET("/cache", NewGetHandler(backendDecorators.LogMetrics(delegate, m), m, true, config.Routes{GetBatch: batchCfg}))
+
+		request, _ := http.NewRequest("GET", "/cache?"+tc.inQuery, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		assert.Equal(t, tc.expectedBackendGets, metricstest.MockCounters["gets.backends.request.total"], "Every key should be read from the backend: %s", tc.desc)
+		if tc.expectedStatus != http.StatusOK {
+			continue
+		}
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), tc.desc)
+		var resp GetBatchResponse
+		if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), tc.desc) {
+			assert.Equal(t, tc.expectedResponses, resp.Responses, tc.desc)
+		}
+	}
+}
+
+func TestGetBatchDisabled(t *testing.T) {
+	backend := backends.NewMemoryBackend()
+	backend.Put(context.Background(), "json-key", `json{"field":"value"}`, 60)
+	backend.Put(context.Background(), "xml-key", "xml<tag></tag>", 60)
+
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{}))
+
+	request, _ := http.NewRequest("GET", "/cache?uuid=json-key&uuid=xml-key", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"field":"value"}`, rr.Body.String(), "Only the first key should be read when batches are disabled")
+}
+
+func TestGetBatchMaxResponseBytes(t *testing.T) {
+	// Every value is a JSON string of 1002 bytes, quotes included, and encodes into an element of a bit more
+	oversized := `"` + strings.Repeat("a", 1000) + `"`
+	delegate := backends.NewMemoryBackend()
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		delegate.Put(context.Background(), key, "json"+oversized, 60)
+	}
+
+	testCases := []struct {
+		desc                string
+		inMaxResponseBytes  int
+		expectedStatus      int
+		expectedKeys        []string
+		expectedTruncated   bool
+		expectedBackendGets int64
+	}{
+		{
+			desc:                "Values within the cap",
+			inMaxResponseBytes:  10000,
+			expectedStatus:      http.StatusOK,
+			expectedKeys:        []string{"key-1", "key-2", "key-3"},
+			expectedBackendGets: 3,
+		},
+		{
+			desc:                "Last value past the cap is left out",
+			inMaxResponseBytes:  2500,
+			expectedStatus:      http.StatusPartialContent,
+			expectedKeys:        []string{"key-1", "key-2"},
+			expectedTruncated:   true,
+			expectedBackendGets: 3,
+		},
+		{
+			desc:                "Keys past the cap aren't read",
+			inMaxResponseBytes:  1500,
+			expectedStatus:      http.StatusPartialContent,
+			expectedKeys:        []string{"key-1"},
+			expectedTruncated:   true,
+			expectedBackendGets: 2,
+		},
+		{
+			desc:                "First value past the cap",
+			inMaxResponseBytes:  500,
+			expectedStatus:      http.StatusPartialContent,
+			expectedKeys:        []string{},
+			expectedTruncated:   true,
+			expectedBackendGets: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		m := metricstest.CreateMockMetrics()
+		router := httprouter.New()
+		// A single worker reads the keys in order, and can record the mock metrics
+		batchCfg := config.GetBatch{Enabled: true, MaxKeys: 3, Workers: 1, MaxResponseBytes: tc.inMaxResponseBytes}
+		router.GET("/cache", NewGetHandler(backendDecorators.LogMetrics(delegate, m), m, true, config.Routes{GetBatch: batchCfg}))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/cache?uuid=key-1&uuid=key-2&uuid=key-3", nil))
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		assert.Equal(t, tc.expectedBackendGets, metricstest.MockCounters["gets.backends.request.total"], tc.desc)
+		var resp GetBatchResponse
+		if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), tc.desc) {
+			keys := []string{}
+			for _, element := range resp.Responses {
+				keys = append(keys, element.UUID)
+				assert.Equal(t, json.RawMessage(oversized), element.Value, tc.desc)
+			}
+			assert.Equal(t, tc.expectedKeys, keys, tc.desc)
+			assert.Equal(t, tc.expectedTruncated, resp.Truncated, tc.desc)
+		}
+	}
+}
+
+// inFlightBackend tracks how many gets are running at once. Every get waits until release is closed.
+type inFlightBackend struct {
+	backends.Backend
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	started     chan struct{}
+	release     chan struct{}
+}
+
+func (b *inFlightBackend) Get(ctx context.Context, key string) (string, error) {
+	b.mu.Lock()
+	b.inFlight++
+	if b.inFlight > b.maxInFlight {
+		b.maxInFlight = b.inFlight
+	}
+	b.mu.Unlock()
+	b.started <- struct{}{}
+
+	<-b.release
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+	return b.Backend.Get(ctx, key)
+}
+
+func TestGetBatchWorkers(t *testing.T) {
+	backend := &inFlightBackend{
+		Backend: backends.NewMemoryBackend(),
+		started: make(chan struct{}, 5),
+		release: make(chan struct{}),
+	}
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{GetBatch: config.GetBatch{Enabled: true, MaxKeys: 5, Workers: 2}}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		request, _ := http.NewRequest("GET", "/cache?uuid=a&uuid=b&uuid=c&uuid=d&uuid=e", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+		done <- rr
+	}()
+
+	// Let both workers start a get before releasing them
+	<-backend.started
+	<-backend.started
+	close(backend.release)
+	rr := <-done
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 2, backend.maxInFlight, "No more keys than workers should be read at once")
+	var resp GetBatchResponse
+	if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp)) {
+		assert.Len(t, resp.Responses, 5)
+	}
+}
+
+func TestGetBatchRemainingTTL(t *testing.T) {
+	backend := envelope.Versioned(backends.NewMemoryBackend(), 1)
+	backend.Put(context.Background(), "ttl-key", "jsontrue", 60)
+	backend.Put(context.Background(), "no-ttl-key", "jsonfalse", 0)
+	router := httprouter.New()
+	// Several workers read the keys at once, each with metadata of its own
+	router.GET("/cache", NewGetHandler(backend, &metrics.Metrics{}, true, config.Routes{GetBatch: config.GetBatch{Enabled: true, MaxKeys: 5, Workers: 2}}))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/cache?uuid=ttl-key&uuid=no-ttl-key", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp GetBatchResponse
+	if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp)) && assert.Len(t, resp.Responses, 2) {
+		// Creation times are stored in seconds, so up to a second may seem to have gone by already
+		assert.InDelta(t, 60, resp.Responses[0].TTLSeconds, 1)
+		assert.Equal(t, 0, resp.Responses[1].TTLSeconds)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	backend := backends.NewMemoryBackend()
+	for _, key := range []string{"a", "b", "c"} {
+		backend.Put(context.Background(), key, "jsontrue", 0)
+	}
+	router := httprouter.New()
+	router.GET("/admin/keys", NewKeysHandler(backend))
+
+	testCases := []struct {
+		desc           string
+		inQuery        string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			desc:           "First page",
+			inQuery:        "count=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"keys":["a","b"],"cursor":2}`,
+		},
+		{
+			desc:           "Last page",
+			inQuery:        "cursor=2&count=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"keys":["c"],"cursor":0}`,
+		},
+		{
+			desc:           "Default count",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"keys":["a","b","c"],"cursor":0}`,
+		},
+		{
+			desc:           "Past the last key",
+			inQuery:        "cursor=10",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"keys":[],"cursor":0}`,
+		},
+		{
+			desc:           "Invalid cursor",
+			inQuery:        "cursor=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"GET /admin/keys: invalid cursor: -1","code":"bad_request"}` + "\n",
+		},
+		{
+			desc:           "Count over the max",
+			inQuery:        "count=1001",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"GET /admin/keys: count must be between 1 and 1000","code":"bad_request"}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/admin/keys?"+tc.inQuery, nil))
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		assert.Equal(t, tc.expectedBody, rr.Body.String(), tc.desc)
+	}
+}
+
+// openCircuitBackend fails every operation the way a backend behind an open circuit breaker does
+type openCircuitBackend struct{}
+
+func (b openCircuitBackend) Get(ctx context.Context, key string) (string, error) {
+	return "", backendDecorators.CircuitOpenError{}
+}
+
+func (b openCircuitBackend) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	return backendDecorators.CircuitOpenError{}
+}
+
+func TestCircuitOpen(t *testing.T) {
+	router := httprouter.New()
+	router.GET("/cache", NewGetHandler(openCircuitBackend{}, &metrics.Metrics{}, true, config.Routes{}))
+	router.POST("/cache", NewPutHandler(openCircuitBackend{}, &metrics.Metrics{}, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+
+	rr := doMockGet(t, router, "some-key")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "GET requests should fail fast while the circuit is open")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[{"type":"json","value":true}]}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "POST requests should fail fast while the circuit is open")
+}
+
+func TestPutParseAndValidationErrors(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		inBody             string
+		expectedParse      int64
+		expectedValidation int64
+	}{
+		{desc: "Malformed body", inBody: `{"puts":[{"type":"json","value":`, expectedParse: 1},
+		{desc: "Body of the wrong shape", inBody: `{"puts":"some-value"}`, expectedParse: 1},
+		{desc: "Unknown type", inBody: `{"puts":[{"type":"yaml","value":"some-value"}]}`, expectedValidation: 1},
+		{desc: "Missing value", inBody: `{"puts":[{"type":"json"}]}`, expectedValidation: 1},
+		{desc: "Negative TTL", inBody: `{"puts":[{"type":"json","value":"some-value","ttlseconds":-1}]}`, expectedValidation: 1},
+		{desc: "Valid request", inBody: `{"puts":[{"type":"json","value":"some-value"}]}`},
+	}
+
+	for _, tc := range testCases {
+		mockMetrics := metricstest.CreateMockMetrics()
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backends.NewMemoryBackend(), mockMetrics, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(tc.inBody)))
+
+		if tc.expectedParse+tc.expectedValidation > 0 {
+			assert.Equal(t, http.StatusBadRequest, rr.Code, tc.desc)
+		} else {
+			assert.Equal(t, http.StatusOK, rr.Code, tc.desc)
+		}
+		assert.Equal(t, tc.expectedParse, metricstest.MockCounters["puts.current_url.json_parse_error"], "%s: parse errors", tc.desc)
+		assert.Equal(t, tc.expectedValidation, metricstest.MockCounters["puts.current_url.validation_error"], "%s: validation errors", tc.desc)
+	}
+}
+
+func TestPutFormats(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inType         string
+		inValue        string
+		expectedStatus int
+		expectedStored string
+	}{
+		{desc: "Upper case JSON", inType: "JSON", inValue: `{"field":"value"}`, expectedStatus: http.StatusOK, expectedStored: `json{"field":"value"}`},
+		{desc: "XML", inType: "xml", inValue: `"<tag></tag>"`, expectedStatus: http.StatusOK, expectedStored: "xml<tag></tag>"},
+		{desc: "XML with surrounding spaces", inType: " Xml ", inValue: `"<tag></tag>"`, expectedStatus: http.StatusOK, expectedStored: "xml<tag></tag>"},
+		{desc: "Empty type", inType: "", inValue: `"some-value"`, expectedStatus: http.StatusBadRequest},
+		{desc: "Unknown type", inType: "foo", inValue: `"some-value"`, expectedStatus: http.StatusBadRequest},
+		{desc: "Unknown type and no value", inType: "foo", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		put := `{"puts":[{"type":"` + tc.inType + `"}]}`
+		if tc.inValue != "" {
+			put = `{"puts":[{"type":"` + tc.inType + `","value":` + tc.inValue + `}]}`
+		}
+
+		backend := backends.NewMemoryBackend()
+		mockMetrics := metricstest.CreateMockMetrics()
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backend, mockMetrics, 10, 0, true, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, false, false))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(put)))
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		keys, _, _ := backend.ScanKeys(context.Background(), 0, 10)
+		if tc.expectedStatus == http.StatusOK {
+			if assert.Len(t, keys, 1, tc.desc) {
+				stored, err := backend.Get(context.Background(), keys[0])
+				assert.NoError(t, err, tc.desc)
+				assert.Equal(t, tc.expectedStored, stored, tc.desc)
+			}
+			assert.Equal(t, int64(0), metricstest.MockCounters["puts.backends.invalid_format"], tc.desc)
+		} else {
+			assert.Empty(t, keys, "%s: nothing should be stored", tc.desc)
+			assert.Contains(t, rr.Body.String(), "Type must be one of", tc.desc)
+			assert.Equal(t, int64(1), metricstest.MockCounters["puts.backends.invalid_format"], tc.desc)
+		}
+	}
+}
+
+func TestPutValueValidation(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		inValidate       bool
+		inPut            string
+		expectedStatus   int
+		expectedMessage  string
+		expectedInvalids int64
+	}{
+		{
+			desc:           "Valid XML",
+			inValidate:     true,
+			inPut:          `{"type":"xml","value":"<?xml version=\"1.0\"?><VAST version=\"3.0\"><Ad id=\"1\"></Ad></VAST>"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:             "Malformed XML",
+			inValidate:       true,
+			inPut:            `{"type":"xml","value":"<VAST version=\"3.0\"><Ad></VAST>"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedMessage:  "request.puts[0].value is not well-formed XML.",
+			expectedInvalids: 1,
+		},
+		{
+			desc:             "XML without elements",
+			inValidate:       true,
+			inPut:            `{"type":"xml","value":"just text"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedMessage:  "request.puts[0].value is not well-formed XML.",
+			expectedInvalids: 1,
+		},
+		{
+			desc:           "Malformed XML without validation",
+			inPut:          `{"type":"xml","value":"<VAST version=\"3.0\"><Ad></VAST>"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:           "Valid JSON",
+			inValidate:     true,
+			inPut:          `{"type":"json","value":{"field":[1,true,"value"]}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			desc:            "Malformed JSON",
+			inValidate:      true,
+			inPut:           `{"type":"json","value":{"field":}}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "is not valid JSON.",
+		},
+	}
+
+	for _, tc := range testCases {
+		backend := backends.NewMemoryBackend()
+		mockMetrics := metricstest.CreateMockMetrics()
+		router := httprouter.New()
+		router.POST("/cache", NewPutHandler(backend, mockMetrics, 10, 0, false, false, config.CollisionGuard{}, config.KeyFormatUUIDv4, tc.inValidate, tc.inValidate))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/cache", strings.NewReader(`{"puts":[`+tc.inPut+`]}`)))
+
+		assert.Equal(t, tc.expectedStatus, rr.Code, tc.desc)
+		keys, _, _ := backend.ScanKeys(context.Background(), 0, 10)
+		if tc.expectedStatus == http.StatusOK {
+			assert.Len(t, keys, 1, tc.desc)
+		} else {
+			assert.Empty(t, keys, "%s: nothing should be stored", tc.desc)
+			assert.Contains(t, rr.Body.String(), tc.expectedMessage, tc.desc)
+		}
+		assert.Equal(t, tc.expectedInvalids, metricstest.MockCounters["puts.backends.invalid_format"], tc.desc)
+	}
+}
@@ -0,0 +1,82 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+const (
+	// defaultKeysCount and maxKeysCount are how many keys GET /admin/keys lists when the request doesn't
+	// ask for a count, and at most
+	defaultKeysCount = 100
+	maxKeysCount     = 1000
+)
+
+// KeysResponse is a page of the keys held by the backend. Cursor is where the next page starts, or zero
+// after the last page.
+type KeysResponse struct {
+	Keys   []string `json:"keys"`
+	Cursor uint64   `json:"cursor"`
+}
+
+// NewKeysHandler serves "GET /admin/keys?cursor={cursor}&count={count}", which lists the keys of the
+// backend a page at a time, so that other instances can prime their backends from this one. Listing
+// starts at cursor 0, and keys stored or expired while listing may or may not be listed.
+func NewKeysHandler(scanner backends.KeyScanner) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		cursor, count, err := parseKeysPage(r)
+		if err != nil {
+			utils.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel, _ := operationContext(r)
+		defer cancel()
+
+		keys, next, err := scanner.ScanKeys(ctx, cursor, count)
+		if err != nil {
+			decorators.Logger(r.Context()).WithField("status", http.StatusInternalServerError).Errorf("GET /admin/keys: %v", err)
+			utils.WriteError(w, "Keys could not be listed", http.StatusInternalServerError)
+			return
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+
+		body, err := json.Marshal(KeysResponse{Keys: keys, Cursor: next})
+		if err != nil {
+			handleException(w, r, err, http.StatusInternalServerError, "")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+func parseKeysPage(r *http.Request) (uint64, int, error) {
+	var cursor uint64
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("GET /admin/keys: invalid cursor: %s", raw)
+		}
+		cursor = parsed
+	}
+
+	count := defaultKeysCount
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxKeysCount {
+			return 0, 0, fmt.Errorf("GET /admin/keys: count must be between 1 and %d", maxKeysCount)
+		}
+		count = parsed
+	}
+	return cursor, count, nil
+}
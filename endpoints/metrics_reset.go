@@ -0,0 +1,23 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+)
+
+// NewMetricsResetHandler zeroes the Prometheus metrics. Counters start over from zero right away, so
+// only tests and staging environments should route to it.
+func NewMetricsResetHandler(appMetrics *metrics.Metrics) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !appMetrics.ResetPrometheus() {
+			utils.WriteError(w, "Prometheus metrics are not enabled", http.StatusNotFound)
+			return
+		}
+		decorators.Logger(r.Context()).Warnf("Prometheus metrics were reset by %s", r.RemoteAddr)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
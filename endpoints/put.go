@@ -3,21 +3,79 @@ package endpoints
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/prebid/prebid-cache/backends"
 	backendDecorators "github.com/prebid/prebid-cache/backends/decorators"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/endpoints/decorators"
+	"github.com/prebid/prebid-cache/metrics"
 	"github.com/prebid/prebid-cache/utils"
-	"github.com/sirupsen/logrus"
 )
 
+// generateKey returns the keys values get stored under when clients don't set their own
+var generateKey = func(format config.KeyFormat) (string, error) {
+	if format == config.KeyFormatUUIDv7 {
+		return utils.GenerateTimeOrderedId()
+	}
+	return utils.GenerateRandomId()
+}
+
+// maxKeyLength leaves room for config.backend.key_prefix within the 250 bytes memcache takes
+const maxKeyLength = 128
+
+// validKey matches the keys clients may set, which are safe in URLs and in the keys of every backend
+var validKey = regexp.MustCompile(`^[A-Za-z0-9._~:-]+$`)
+
+// putFormat returns the format the type of a put stands for, which is one of backends.JSON_PREFIX and
+// backends.XML_PREFIX regardless of case and surrounding spaces. It returns false for any other type.
+func putFormat(putType string) (string, bool) {
+	switch format := strings.ToLower(strings.TrimSpace(putType)); format {
+	case backends.JSON_PREFIX, backends.XML_PREFIX:
+		return format, true
+	}
+	return "", false
+}
+
+// wellFormedXML tells whether value is XML with at least one element and no syntax errors
+func wellFormedXML(value string) bool {
+	decoder := xml.NewDecoder(strings.NewReader(value))
+	hasElement := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return hasElement
+		}
+		if err != nil {
+			return false
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			hasElement = true
+		}
+	}
+}
+
 // PutHandler serves "POST /cache" requests.
-func NewPutHandler(backend backends.Backend, maxNumValues int, allowKeys bool) func(http.ResponseWriter, *http.Request, httprouter.Params) {
+// If respondAccepted is set, successful requests get a 202 rather than a 200 to tell clients that the
+// values may not be durably stored yet, like when the backend writes them behind.
+// If collisionGuard is enabled, values only get stored under generated keys that don't hold one yet,
+// which takes a backend wrapped by decorators.GuardCollisions.
+// Values over maxValueSize bytes get a 413 without reaching the backend, unless maxValueSize is zero.
+// Generated keys are UUIDs of the version keyFormat says, which are all as long.
+// If allowKeys is set, values are stored under the key their client set, if any. Keys already holding
+// a value are left untouched unless the client allows overwriting them, and the value gets a blank
+// UUID instead.
+// If validateXML is set, XML values that aren't well-formed get a 400 and are counted as invalid formats.
+// validateJSON does the same for JSON values, though the parsing of the request already rejects most.
+func NewPutHandler(backend backends.Backend, m *metrics.Metrics, maxNumValues int, maxValueSize int, allowKeys bool, respondAccepted bool, collisionGuard config.CollisionGuard, keyFormat config.KeyFormat, validateXML bool, validateJSON bool) func(http.ResponseWriter, *http.Request, httprouter.Params) {
 	// TODO(future PR): Break this giant function apart
 	putAnyRequestPool := sync.Pool{
 		New: func() interface{} {
@@ -33,8 +91,12 @@ func NewPutHandler(backend backends.Backend, maxNumValues int, allowKeys bool) f
 
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		body, err := ioutil.ReadAll(r.Body)
+		if err == decorators.ErrRequestBodyTooLarge {
+			utils.WriteError(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Failed to read the request body.", http.StatusBadRequest)
+			utils.WriteError(w, "Failed to read the request body.", http.StatusBadRequest)
 			return
 		}
 		defer r.Body.Close()
@@ -45,12 +107,19 @@ func NewPutHandler(backend backends.Backend, maxNumValues int, allowKeys bool) f
 
 		err = json.Unmarshal(body, put)
 		if err != nil {
-			http.Error(w, "Request body "+string(body)+" is not valid JSON.", http.StatusBadRequest)
+			m.RecordPutParseError()
+			utils.WriteError(w, "Request body "+string(body)+" is not valid JSON.", http.StatusBadRequest)
 			return
 		}
 
+		// Requests that parse but don't hold valid puts are counted apart from those that don't parse
+		invalid := func(msg string) {
+			m.RecordPutValidationError()
+			utils.WriteError(w, msg, http.StatusBadRequest)
+		}
+
 		if len(put.Puts) > maxNumValues {
-			http.Error(w, fmt.Sprintf("More keys than allowed: %d", maxNumValues), http.StatusBadRequest)
+			invalid(fmt.Sprintf("More keys than allowed: %d", maxNumValues))
 			return
 		}
 
@@ -58,20 +127,36 @@ func NewPutHandler(backend backends.Backend, maxNumValues int, allowKeys bool) f
 		resps.Responses = make([]PutResponseObject, len(put.Puts))
 		defer putResponsePool.Put(resps)
 
+		// Every element gets validated before any is stored, so that invalid requests leave nothing behind
+		values := make([]string, len(put.Puts))
 		for i, p := range put.Puts {
+			format, ok := putFormat(p.Type)
+			if !ok {
+				m.RecordPutBackendInvalid()
+				invalid(fmt.Sprintf("Type must be one of [\"json\", \"xml\"]. Found %v", p.Type))
+				return
+			}
 			if len(p.Value) == 0 {
-				http.Error(w, "Missing value.", http.StatusBadRequest)
+				invalid("Missing value.")
 				return
 			}
 			if p.TTLSeconds < 0 {
-				http.Error(w, fmt.Sprintf("request.puts[%d].ttlseconds must not be negative.", p.TTLSeconds), http.StatusBadRequest)
+				invalid(fmt.Sprintf("request.puts[%d].ttlseconds must not be negative.", p.TTLSeconds))
+				return
+			}
+			if allowKeys && len(p.Key) > maxKeyLength {
+				invalid(fmt.Sprintf("request.puts[%d].key must not be longer than %d characters.", i, maxKeyLength))
+				return
+			}
+			if allowKeys && len(p.Key) > 0 && !validKey.MatchString(p.Key) {
+				invalid(fmt.Sprintf("request.puts[%d].key must only contain letters, digits and any of \"._~:-\".", i))
 				return
 			}
 
 			var toCache string
-			if p.Type == backends.XML_PREFIX {
+			if format == backends.XML_PREFIX {
 				if p.Value[0] != byte('"') || p.Value[len(p.Value)-1] != byte('"') {
-					http.Error(w, fmt.Sprintf("XML messages must have a String value. Found %v", p.Value), http.StatusBadRequest)
+					invalid(fmt.Sprintf("XML messages must have a String value. Found %v", p.Value))
 					return
 				}
 
@@ -79,26 +164,50 @@ func NewPutHandler(backend backends.Backend, maxNumValues int, allowKeys bool) f
 				// for example... so we'll need to un-escape it before we consider it to be XML content.
 				var interpreted string
 				json.Unmarshal(p.Value, &interpreted)
-				toCache = p.Type + interpreted
-			} else if p.Type == backends.JSON_PREFIX {
-				toCache = p.Type + string(p.Value)
+				if validateXML && !wellFormedXML(interpreted) {
+					m.RecordPutBackendInvalid()
+					invalid(fmt.Sprintf("request.puts[%d].value is not well-formed XML.", i))
+					return
+				}
+				toCache = format + interpreted
 			} else {
-				http.Error(w, fmt.Sprintf("Type must be one of [\"json\", \"xml\"]. Found %v", p.Type), http.StatusBadRequest)
-				return
+				if validateJSON && !json.Valid(p.Value) {
+					m.RecordPutBackendInvalid()
+					invalid(fmt.Sprintf("request.puts[%d].value is not valid JSON.", i))
+					return
+				}
+				toCache = format + string(p.Value)
 			}
 
-			if resps.Responses[i].UUID, err = utils.GenerateRandomId(); err != nil {
-				http.Error(w, fmt.Sprintf("Error generating version 4 UUID"), http.StatusInternalServerError)
+			if maxValueSize > 0 && len(toCache) > maxValueSize {
+				m.RecordPutOversized()
+				err := utils.NewBackendError(utils.ValueTooLarge, fmt.Errorf("value size %d exceeded max %d", len(toCache), maxValueSize))
+				writeBackendError(w, r, err, fmt.Sprintf("POST /cache element %d is %d bytes, over the max of %d bytes.", i, len(toCache), maxValueSize), http.StatusRequestEntityTooLarge)
+				return
 			}
+			values[i] = toCache
+		}
 
-			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
-			defer cancel()
+		ctx, cancel, clientDeadline := operationContext(r)
+		defer cancel()
+		for i, p := range put.Puts {
+			toCache := values[i]
 			// Only allow setting a provided key if configured (and ensure a key is provided).
-			if allowKeys && len(p.Key) > 0 {
-				s, err := backend.Get(ctx, p.Key)
-				if err != nil || len(s) == 0 {
-					resps.Responses[i].UUID = p.Key
-				} else {
+			customKey := allowKeys && len(p.Key) > 0
+			if customKey {
+				resps.Responses[i].UUID = p.Key
+			} else if resps.Responses[i].UUID, err = generateKey(keyFormat); err != nil {
+				utils.WriteError(w, "Error generating a key", http.StatusInternalServerError)
+				return
+			}
+
+			putCtx := ctx
+			// Collisions of custom keys leave the value unstored rather than moving it to a generated key
+			guarded := collisionGuard.Enabled && !customKey
+			if customKey && !p.AllowOverwrite {
+				if collisionGuard.Enabled {
+					putCtx = backendDecorators.WithPutIfAbsent(ctx)
+				} else if s, err := backend.Get(ctx, p.Key); err == nil && len(s) > 0 {
 					resps.Responses[i].UUID = ""
 				}
 			}
@@ -106,37 +215,74 @@ func NewPutHandler(backend backends.Backend, maxNumValues int, allowKeys bool) f
 			// Eventually we may want to provide error details, but as of today this is the only non-fatal error
 			// Future error details could go into a second property of the Responses object, such as "errors"
 			if len(resps.Responses[i].UUID) > 0 {
-				err = backend.Put(ctx, resps.Responses[i].UUID, toCache, p.TTLSeconds)
+				if guarded {
+					putCtx = backendDecorators.WithPutIfAbsent(ctx)
+				}
+				err = backend.Put(putCtx, resps.Responses[i].UUID, toCache, p.TTLSeconds)
+				if _, collided := err.(utils.KeyExistsError); collided && customKey {
+					resps.Responses[i].UUID = ""
+					continue
+				}
+				for retries := 0; guarded && retries < collisionGuard.MaxRetries; retries++ {
+					if _, collided := err.(utils.KeyExistsError); !collided {
+						break
+					}
+					if resps.Responses[i].UUID, err = generateKey(keyFormat); err != nil {
+						utils.WriteError(w, "Error generating a key", http.StatusInternalServerError)
+						return
+					}
+					err = backend.Put(putCtx, resps.Responses[i].UUID, toCache, p.TTLSeconds)
+				}
 				if err != nil {
 					if _, ok := err.(*backendDecorators.BadPayloadSize); ok {
-						http.Error(w, fmt.Sprintf("POST /cache element %d exceeded max size: %v", i, err), http.StatusBadRequest)
+						writeBackendError(w, r, err, fmt.Sprintf("POST /cache element %d exceeded max size: %v", i, err), http.StatusRequestEntityTooLarge)
+						return
+					}
+					if _, ok := err.(*backendDecorators.WriteVerificationError); ok {
+						writeBackendError(w, r, err, fmt.Sprintf("POST /cache element %d could not be verified: %v", i, err), http.StatusBadGateway)
+						return
+					}
+					if _, ok := err.(backendDecorators.CircuitOpenError); ok {
+						writeBackendError(w, r, err, "The backend is unavailable. Try again later.", http.StatusServiceUnavailable)
+						return
+					}
+					if _, ok := err.(utils.KeyExistsError); ok {
+						writeBackendError(w, r, err, fmt.Sprintf("POST /cache element %d collided with stored values under every key generated for it", i), http.StatusInternalServerError)
 						return
 					}
 
-					logrus.Error("POST /cache Error while writing to the backend: ", err)
+					logger := decorators.Logger(r.Context())
+					if clientDeadline && ctx.Err() == context.DeadlineExceeded {
+						logger.WithField("status", http.StatusGatewayTimeout).Error("POST /cache client deadline exceeded writing to the backend: ", err)
+						writeBackendError(w, r, err, "Client deadline exceeded writing value to the backend", http.StatusGatewayTimeout)
+						return
+					}
 					switch err {
 					case context.DeadlineExceeded:
-						logrus.Error("POST /cache timed out:", err)
-						http.Error(w, "Timeout writing value to the backend", HttpDependencyTimeout)
+						logger.WithField("status", HttpDependencyTimeout).Error("POST /cache timed out: ", err)
+						writeBackendError(w, r, err, "Timeout writing value to the backend", HttpDependencyTimeout)
 					default:
-						logrus.Error("POST /cache had an unexpected error:", err)
-						http.Error(w, err.Error(), http.StatusInternalServerError)
+						logger.WithField("status", http.StatusInternalServerError).Error("POST /cache had an unexpected error: ", err)
+						writeBackendError(w, r, err, err.Error(), http.StatusInternalServerError)
 					}
 					return
 				}
-				logrus.Tracef("PUT /cache uuid=%s", resps.Responses[i].UUID)
+				decorators.Logger(r.Context()).Tracef("PUT /cache uuid=%s", resps.Responses[i].UUID)
 			}
 
 		}
 
 		bytes, err := json.Marshal(resps)
 		if err != nil {
-			http.Error(w, "Failed to serialize UUIDs into JSON.", http.StatusInternalServerError)
+			utils.WriteError(w, "Failed to serialize UUIDs into JSON.", http.StatusInternalServerError)
 			return
 		}
 
 		/* Handles POST */
 		w.Header().Set("Content-Type", "application/json")
+		if respondAccepted {
+			w.WriteHeader(http.StatusAccepted)
+		}
 		w.Write(bytes)
 	}
 }
@@ -150,6 +296,8 @@ type PutObject struct {
 	TTLSeconds int             `json:"ttlseconds"`
 	Value      json.RawMessage `json:"value"`
 	Key        string          `json:"key"`
+	// AllowOverwrite lets the value replace the one stored under Key, if there's any
+	AllowOverwrite bool `json:"allowOverwrite"`
 }
 
 type PutResponseObject struct {
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/didip/tollbooth"
@@ -12,6 +13,7 @@ import (
 	"github.com/prebid/prebid-cache/endpoints"
 	"github.com/prebid/prebid-cache/endpoints/decorators"
 	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
 	"github.com/rs/cors"
 )
 
@@ -19,7 +21,16 @@ func NewAdminHandler(cfg config.Configuration, dataStore backends.Backend, appMe
 	router := httprouter.New()
 	addReadRoutes(cfg, dataStore, appMetrics, router)
 	addWriteRoutes(cfg, dataStore, appMetrics, router)
-	return router
+	if cfg.Metrics.Prometheus.Enabled && cfg.Metrics.Prometheus.AllowReset {
+		router.POST("/admin/metrics/reset", endpoints.NewMetricsResetHandler(appMetrics))
+	}
+	if scanner, ok := dataStore.(backends.KeyScanner); ok && cfg.Priming.ServeKeys {
+		router.GET("/admin/keys", endpoints.NewKeysHandler(scanner))
+	}
+	handler := decorators.CompressResponses(router, cfg.ResponseCompression)
+	handler = decorators.MonitorEndToEnd(handler, appMetrics)
+	handler = decorators.ScopeLogs(handler, cfg.Backend.Type)
+	return decorators.TagRequestIDs(handler, cfg.RequestID)
 }
 
 func NewPublicHandler(cfg config.Configuration, dataStore backends.Backend, appMetrics *metrics.Metrics) http.Handler {
@@ -29,29 +40,86 @@ func NewPublicHandler(cfg config.Configuration, dataStore backends.Backend, appM
 		addWriteRoutes(cfg, dataStore, appMetrics, router)
 	}
 
-	handler := handleCors(router)
-	handler = handleRateLimiting(handler, cfg.RateLimiting)
+	handler := decorators.CompressResponses(router, cfg.ResponseCompression)
+	handler = handleCors(handler, cfg.CORS)
+	handler = handleRateLimiting(handler, cfg.RateLimiting, appMetrics)
+	handler = decorators.MonitorEndToEnd(handler, appMetrics)
+	handler = decorators.ScopeLogs(handler, cfg.Backend.Type)
+	handler = decorators.TagRequestIDs(handler, cfg.RequestID)
 	return handler
 }
 
 func addReadRoutes(cfg config.Configuration, dataStore backends.Backend, appMetrics *metrics.Metrics, router *httprouter.Router) {
 	router.GET("/", endpoints.NewIndexHandler(cfg.IndexResponse)) //Default route handler
 	router.GET("/status", endpoints.Status)                       // Determines whether the server is ready for more traffic.
-	router.GET("/cache", decorators.MonitorHttp(endpoints.NewGetHandler(dataStore, cfg.RequestLimits.AllowSettingKeys), appMetrics, decorators.GetMethod))
+	if cfg.Routes.Health.LivenessPath != "" {
+		router.GET(cfg.Routes.Health.LivenessPath, endpoints.Liveness)
+	}
+	if cfg.Routes.Health.ReadinessPath != "" {
+		router.GET(cfg.Routes.Health.ReadinessPath, endpoints.NewReadinessHandler(dataStore))
+	}
+	getHandler := decorators.LimitConcurrency(endpoints.NewGetHandler(dataStore, appMetrics, cfg.Backend.AllowKeyManagement, cfg.Routes), appMetrics, cfg.RateLimiting.MaxConcurrentGets)
+	getHandler = decorators.CorrelateReads(getHandler, cfg.Correlation)
+	getHandler = decorators.OverrideMaxAttempts(getHandler, appMetrics, cfg.Backend.Retry)
+	getHandler = decorators.HonorClientDeadlines(getHandler, cfg.ClientDeadlines)
+	getHandler = decorators.ReportErrorCodes(getHandler, cfg.Routes)
+	getHandler = decorators.TagUserAgents(getHandler, appMetrics, decorators.GetMethod, cfg.Metrics.UserAgents)
+	getHandler = decorators.SampleTraces(getHandler, cfg.Tracing)
+	getHandler = decorators.MonitorHttp(getHandler, appMetrics, decorators.GetMethod)
+	router.GET("/cache", getHandler)
+	if cfg.Routes.GetPathKeys {
+		router.GET("/cache/:uuid", getHandler)
+	}
 }
 
 func addWriteRoutes(cfg config.Configuration, dataStore backends.Backend, appMetrics *metrics.Metrics, router *httprouter.Router) {
-	router.POST("/cache", decorators.MonitorHttp(endpoints.NewPutHandler(dataStore, cfg.RequestLimits.MaxNumValues, cfg.RequestLimits.AllowSettingKeys), appMetrics, decorators.PostMethod))
+	putHandler := decorators.LimitConcurrency(endpoints.NewPutHandler(dataStore, appMetrics, cfg.RequestLimits.MaxNumValues, cfg.RequestLimits.MaxSize, cfg.Backend.AllowKeyManagement, cfg.Backend.WriteBehind.RespondsAccepted(), cfg.Backend.CollisionGuard, cfg.Backend.KeyFormat, cfg.RequestLimits.ValidateXML, cfg.RequestLimits.ValidateJSON), appMetrics, cfg.RateLimiting.MaxConcurrentPuts)
+	putHandler = decorators.EnforcePutQuotas(putHandler, appMetrics, cfg.PutQuotas)
+	putHandler = decorators.CorrelateWrites(putHandler, cfg.Correlation)
+	putHandler = decorators.OverrideMaxAttempts(putHandler, appMetrics, cfg.Backend.Retry)
+	putHandler = decorators.HonorClientDeadlines(putHandler, cfg.ClientDeadlines)
+	putHandler = decorators.ReportErrorCodes(putHandler, cfg.Routes)
+	putHandler = decorators.TagUserAgents(putHandler, appMetrics, decorators.PostMethod, cfg.Metrics.UserAgents)
+	putHandler = decorators.DecompressRequestBodies(putHandler, cfg.RequestDecompression)
+	putHandler = decorators.LimitRequestBodies(putHandler, cfg.RequestLimits.MaxRequestSize)
+	putHandler = decorators.SampleTraces(putHandler, cfg.Tracing)
+	putHandler = decorators.ThrottleClientPuts(putHandler, appMetrics, clientRateLimit(cfg), cfg.Auth.TrustedProxyDepth)
+	putHandler = decorators.AllowWriters(putHandler, appMetrics, cfg.Auth)
+	router.POST("/cache", decorators.MonitorHttp(putHandler, appMetrics, decorators.PostMethod))
+}
+
+// clientRateLimit adds the API keys with a put quota to those throttled by key, if put quotas read
+// the API key from the same header
+func clientRateLimit(cfg config.Configuration) config.ClientRateLimit {
+	limit := cfg.RateLimiting.PerClientPuts
+	if !cfg.PutQuotas.Enabled || !strings.EqualFold(cfg.PutQuotas.Header, limit.APIKeyHeader) {
+		return limit
+	}
+	limit.APIKeys = append([]string{}, limit.APIKeys...)
+	for _, quota := range cfg.PutQuotas.Quotas {
+		limit.APIKeys = append(limit.APIKeys, quota.APIKey)
+	}
+	return limit
 }
 
-func handleCors(handler http.Handler) http.Handler {
-	coresCfg := cors.New(cors.Options{AllowCredentials: true, AllowOriginFunc: func(origin string) bool {
-		return true
-	}})
+func handleCors(handler http.Handler, cfg config.CORS) http.Handler {
+	if !cfg.Enabled {
+		coresCfg := cors.New(cors.Options{AllowCredentials: true, AllowOriginFunc: func(origin string) bool {
+			return true
+		}})
+		return coresCfg.Handler(handler)
+	}
+
+	coresCfg := cors.New(cors.Options{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedMethods: cfg.AllowedMethods,
+		AllowedHeaders: cfg.AllowedHeaders,
+		MaxAge:         cfg.MaxAgeSeconds,
+	})
 	return coresCfg.Handler(handler)
 }
 
-func handleRateLimiting(next http.Handler, cfg config.RateLimiting) http.Handler {
+func handleRateLimiting(next http.Handler, cfg config.RateLimiting, appMetrics *metrics.Metrics) http.Handler {
 	// Sip rate limiter when disabled
 	if !cfg.Enabled {
 		return next
@@ -61,8 +129,11 @@ func handleRateLimiting(next http.Handler, cfg config.RateLimiting) http.Handler
 		DefaultExpirationTTL: 1 * time.Hour,
 	})
 	limit.SetIPLookups([]string{"X-Forwarded-For", "X-Real-IP"})
-	limit.SetMessage(`{ "error": "rate limit" }`)
+	limit.SetMessage(`{"error":"rate limit","code":"` + string(utils.TooManyRequestsCode) + `"}`)
 	limit.SetMessageContentType("application/json")
+	limit.SetOnLimitReached(func(w http.ResponseWriter, r *http.Request) {
+		appMetrics.RecordRejectedRequest(metrics.RejectedRateLimit)
+	})
 
 	return tollbooth.LimitHandler(limit, next)
 }
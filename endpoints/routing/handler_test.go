@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCORS = config.CORS{
+	Enabled:        true,
+	AllowedOrigins: []string{"https://*.publisher.com"},
+	AllowedMethods: []string{"GET", "HEAD"},
+	AllowedHeaders: []string{"X-Requested-With"},
+	MaxAgeSeconds:  600,
+}
+
+func TestHandleCors(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		inCORS              config.CORS
+		inMethod            string
+		inOrigin            string
+		inRequestMethod     string
+		expectedAllowOrigin string
+		expectedAllowMethod string
+		expectedMaxAge      string
+		expectedCredentials string
+		expectedHandled     bool
+	}{
+		{
+			desc:                "Preflight of an allowed origin",
+			inCORS:              testCORS,
+			inMethod:            "OPTIONS",
+			inOrigin:            "https://www.publisher.com",
+			inRequestMethod:     "GET",
+			expectedAllowOrigin: "https://www.publisher.com",
+			expectedAllowMethod: "GET",
+			expectedMaxAge:      "600",
+		},
+		{
+			desc:            "Preflight of a method that isn't allowed",
+			inCORS:          testCORS,
+			inMethod:        "OPTIONS",
+			inOrigin:        "https://www.publisher.com",
+			inRequestMethod: "DELETE",
+		},
+		{
+			desc:                "GET from an allowed origin",
+			inCORS:              testCORS,
+			inMethod:            "GET",
+			inOrigin:            "https://www.publisher.com",
+			expectedAllowOrigin: "https://www.publisher.com",
+			expectedHandled:     true,
+		},
+		{
+			desc:            "GET from an origin that isn't allowed is served without CORS headers",
+			inCORS:          testCORS,
+			inMethod:        "GET",
+			inOrigin:        "https://www.elsewhere.com",
+			expectedHandled: true,
+		},
+		{
+			desc:                "Every origin is allowed when CORS is disabled",
+			inMethod:            "GET",
+			inOrigin:            "https://www.elsewhere.com",
+			expectedAllowOrigin: "https://www.elsewhere.com",
+			expectedCredentials: "true",
+			expectedHandled:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		handled := false
+		handler := handleCors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = true
+		}), tc.inCORS)
+
+		req := httptest.NewRequest(tc.inMethod, "/cache?uuid=foo", nil)
+		req.Header.Set("Origin", tc.inOrigin)
+		if tc.inRequestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", tc.inRequestMethod)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+
+		assert.Equal(t, tc.expectedHandled, handled, tc.desc)
+		assert.Equal(t, tc.expectedAllowOrigin, resp.Header().Get("Access-Control-Allow-Origin"), tc.desc)
+		assert.Equal(t, tc.expectedAllowMethod, resp.Header().Get("Access-Control-Allow-Methods"), tc.desc)
+		assert.Equal(t, tc.expectedMaxAge, resp.Header().Get("Access-Control-Max-Age"), tc.desc)
+		assert.Equal(t, tc.expectedCredentials, resp.Header().Get("Access-Control-Allow-Credentials"), tc.desc)
+	}
+}
+
+func TestMetricsNotRouted(t *testing.T) {
+	cfg := config.Configuration{Metrics: config.Metrics{Prometheus: config.PrometheusMetrics{Enabled: true, Port: 8080, AllowReset: true}}}
+	handlers := map[string]http.Handler{
+		"Public": NewPublicHandler(cfg, backends.NewMemoryBackend(), metricstest.CreateMockMetrics()),
+		"Admin":  NewAdminHandler(cfg, backends.NewMemoryBackend(), metricstest.CreateMockMetrics()),
+	}
+
+	for name, handler := range handlers {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, "%s handler shouldn't serve the metrics, which get a listener of their own", name)
+	}
+}
+
+func TestClientRateLimitAPIKeys(t *testing.T) {
+	quotas := config.PutQuotas{Enabled: true, Header: "x-api-key", Quotas: []config.PutQuota{{APIKey: "quota-key", MaxEntries: 10}}}
+
+	testCases := []struct {
+		desc            string
+		inPutQuotas     config.PutQuotas
+		expectedAPIKeys []string
+	}{
+		{
+			desc:            "Put quotas reading the same header",
+			inPutQuotas:     quotas,
+			expectedAPIKeys: []string{"configured-key", "quota-key"},
+		},
+		{
+			desc:            "Put quotas disabled",
+			inPutQuotas:     config.PutQuotas{Header: "X-Api-Key", Quotas: quotas.Quotas},
+			expectedAPIKeys: []string{"configured-key"},
+		},
+		{
+			desc:            "Put quotas reading another header",
+			inPutQuotas:     config.PutQuotas{Enabled: true, Header: "X-Partner-Key", Quotas: quotas.Quotas},
+			expectedAPIKeys: []string{"configured-key"},
+		},
+	}
+
+	for _, tc := range testCases {
+		cfg := config.Configuration{
+			RateLimiting: config.RateLimiting{PerClientPuts: config.ClientRateLimit{APIKeyHeader: "X-Api-Key", APIKeys: []string{"configured-key"}}},
+			PutQuotas:    tc.inPutQuotas,
+		}
+
+		assert.Equal(t, tc.expectedAPIKeys, clientRateLimit(cfg).APIKeys, tc.desc)
+		assert.Equal(t, []string{"configured-key"}, cfg.RateLimiting.PerClientPuts.APIKeys, "The configured keys shouldn't change: %s", tc.desc)
+	}
+}
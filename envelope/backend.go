@@ -0,0 +1,60 @@
+package envelope
+
+import (
+	"context"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+)
+
+// Versioned wraps the delegate so that values are stored laid out as the given version, and values of
+// any known version get decoded back to their raw form on the way out. Readers decode every version
+// regardless of the one being written, so a newer version can be rolled out to all instances before
+// any of them starts writing it.
+func Versioned(delegate backends.Backend, version byte) backends.Backend {
+	return &versioned{
+		delegate: delegate,
+		version:  version,
+		now:      time.Now,
+	}
+}
+
+type versioned struct {
+	delegate backends.Backend
+	version  byte
+	now      func() time.Time
+}
+
+func (v *versioned) Put(ctx context.Context, key string, value string, ttlSeconds int) error {
+	header := Header{
+		Format:     backends.ValueFormat(value),
+		CreatedAt:  v.now().Unix(),
+		TTLSeconds: ttlSeconds,
+		Writer:     writerOf(ctx),
+	}
+	stored, err := Encode(v.version, header, value)
+	if err != nil {
+		return err
+	}
+	return v.delegate.Put(ctx, key, stored, ttlSeconds)
+}
+
+func (v *versioned) Get(ctx context.Context, key string) (string, error) {
+	stored, err := v.delegate.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	env, err := Decode(stored)
+	if err != nil {
+		return "", err
+	}
+	// A truncated value would otherwise be served as if it were whole
+	if err := env.verifyLength(); err != nil {
+		return "", err
+	}
+	if md, ok := ctx.Value(metadataKey{}).(*Metadata); ok {
+		*md = metadataOf(env)
+	}
+	return env.Value, nil
+}
@@ -0,0 +1,208 @@
+// Package envelope defines how values are laid out in the backends, so that new fields can be stored
+// alongside a value without breaking the readers of values stored by older versions of Prebid Cache.
+//
+// Every stored value starts with a single version byte, followed by a layout specific to that version:
+//
+//	Version 0: the legacy raw value, exactly as Prebid Cache stored it before versioning existed. It has
+//	           no version byte at all. Legacy values always start with the "xml" or "json" format prefix,
+//	           so any value that starts with a printable character is read as version 0.
+//	Version 1: the byte 0x01, a 4-byte big-endian header length, a JSON encoded Header of that length
+//	           and the raw value. The header carries a checksum and the length of the raw value.
+//
+// Version bytes are taken from the non printable range (below 0x20) so they can never be mistaken for
+// the first character of a legacy value.
+package envelope
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"github.com/prebid/prebid-cache/utils"
+)
+
+const (
+	// Version0 is the legacy raw value, stored without any version byte
+	Version0 byte = 0x00
+	// Version1 prefixes the raw value with a JSON encoded Header
+	Version1 byte = 0x01
+
+	// LatestVersion is the newest layout this package knows how to read and write
+	LatestVersion = Version1
+
+	headerLengthSize = 4
+	firstPrintable   = 0x20
+)
+
+// Header holds the fields a version 1 envelope stores next to the value
+type Header struct {
+	Format string `json:"format,omitempty"`
+	// Checksum is the hex encoded CRC-32 of the raw value. It gets filled in by Encode.
+	Checksum string `json:"checksum,omitempty"`
+	// Length is the length in bytes of the raw value. It gets filled in by Encode, and values stored
+	// before it was introduced leave it out.
+	Length int `json:"length,omitempty"`
+	// CreatedAt is the unix time in seconds the value was stored at, and TTLSeconds the time to live
+	// it was stored with. Values stored before they were introduced leave them out.
+	CreatedAt  int64 `json:"created_at,omitempty"`
+	TTLSeconds int   `json:"ttl_seconds,omitempty"`
+	// Writer identifies the client that stored the value. It's only stored if the PUT request was
+	// correlated, see WithWriter.
+	Writer string `json:"writer,omitempty"`
+}
+
+// Envelope is a decoded stored value
+type Envelope struct {
+	Version byte
+	Header  Header
+	Value   string
+}
+
+// UnknownVersionError is returned when a stored value carries a version byte this build of Prebid
+// Cache doesn't know how to decode, most likely because it was written by a newer one.
+type UnknownVersionError struct {
+	Version byte
+}
+
+func (e UnknownVersionError) Error() string {
+	return fmt.Sprintf("stored value has unknown envelope version %d", e.Version)
+}
+
+// MalformedError is returned when a stored value announces a known version but its layout doesn't match it
+type MalformedError struct {
+	Version byte
+	Reason  string
+}
+
+func (e MalformedError) Error() string {
+	return fmt.Sprintf("stored value has a malformed version %d envelope: %s", e.Version, e.Reason)
+}
+
+// ChecksumMismatchError is returned when a stored value doesn't match the checksum it was stored with
+type ChecksumMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("stored value checksum %s doesn't match the expected %s", e.Actual, e.Expected)
+}
+
+func (e ChecksumMismatchError) ErrorCode() utils.ErrorCode {
+	return utils.Corrupt
+}
+
+// LengthMismatchError is returned when a stored value isn't as long as it was when stored, which
+// usually means the backend returned it truncated
+type LengthMismatchError struct {
+	Expected int
+	Actual   int
+}
+
+func (e LengthMismatchError) Error() string {
+	return fmt.Sprintf("stored value is %d bytes long instead of the expected %d", e.Actual, e.Expected)
+}
+
+func (e LengthMismatchError) ErrorCode() utils.ErrorCode {
+	return utils.Corrupt
+}
+
+// Verify checks the value against the length and checksum found in its header. Values stored without
+// them, like every version 0 value, can't be verified and always pass.
+func (e Envelope) Verify() error {
+	if err := e.verifyLength(); err != nil {
+		return err
+	}
+	if e.Header.Checksum == "" {
+		return nil
+	}
+	if actual := checksum(e.Value); actual != e.Header.Checksum {
+		return ChecksumMismatchError{Expected: e.Header.Checksum, Actual: actual}
+	}
+	return nil
+}
+
+// verifyLength checks the value against the length found in its header. Unlike the checksum, it's
+// cheap enough to check on every read.
+func (e Envelope) verifyLength() error {
+	if e.Header.Length > 0 && len(e.Value) != e.Header.Length {
+		return LengthMismatchError{Expected: e.Header.Length, Actual: len(e.Value)}
+	}
+	return nil
+}
+
+// Encode lays the value out as the given version. Version 0 returns the value untouched.
+func Encode(version byte, header Header, value string) (string, error) {
+	switch version {
+	case Version0:
+		return value, nil
+	case Version1:
+		return encodeV1(header, value)
+	default:
+		return "", UnknownVersionError{Version: version}
+	}
+}
+
+// Decode reads a stored value of any known version
+func Decode(stored string) (Envelope, error) {
+	if len(stored) == 0 || stored[0] >= firstPrintable {
+		return Envelope{Version: Version0, Value: stored}, nil
+	}
+
+	switch stored[0] {
+	case Version1:
+		return decodeV1(stored[1:])
+	default:
+		return Envelope{}, UnknownVersionError{Version: stored[0]}
+	}
+}
+
+func encodeV1(header Header, value string) (string, error) {
+	header.Checksum = checksum(value)
+	header.Length = len(value)
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.Grow(1 + headerLengthSize + len(headerBytes) + len(value))
+	sb.WriteByte(Version1)
+
+	var headerLength [headerLengthSize]byte
+	binary.BigEndian.PutUint32(headerLength[:], uint32(len(headerBytes)))
+	sb.Write(headerLength[:])
+	sb.Write(headerBytes)
+	sb.WriteString(value)
+
+	return sb.String(), nil
+}
+
+func decodeV1(body string) (Envelope, error) {
+	if len(body) < headerLengthSize {
+		return Envelope{}, MalformedError{Version: Version1, Reason: "missing header length"}
+	}
+	headerLength := int(binary.BigEndian.Uint32([]byte(body[:headerLengthSize])))
+	body = body[headerLengthSize:]
+
+	if headerLength > len(body) {
+		return Envelope{}, MalformedError{Version: Version1, Reason: fmt.Sprintf("header length %d exceeds the %d bytes left", headerLength, len(body))}
+	}
+
+	var header Header
+	if err := json.Unmarshal([]byte(body[:headerLength]), &header); err != nil {
+		return Envelope{}, MalformedError{Version: Version1, Reason: err.Error()}
+	}
+
+	return Envelope{
+		Version: Version1,
+		Header:  header,
+		Value:   body[headerLength:],
+	}, nil
+}
+
+func checksum(value string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(value)))
+}
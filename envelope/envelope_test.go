@@ -0,0 +1,221 @@
+package envelope
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		inStored         string
+		expectedEnvelope Envelope
+		expectedErr      error
+	}{
+		{
+			desc:             "Legacy raw json value decodes as version 0",
+			inStored:         `json{"field":"value"}`,
+			expectedEnvelope: Envelope{Version: Version0, Value: `json{"field":"value"}`},
+		},
+		{
+			desc:             "Legacy raw xml value decodes as version 0",
+			inStored:         "xml<tag></tag>",
+			expectedEnvelope: Envelope{Version: Version0, Value: "xml<tag></tag>"},
+		},
+		{
+			desc:             "Empty value decodes as version 0",
+			inStored:         "",
+			expectedEnvelope: Envelope{Version: Version0},
+		},
+		{
+			desc:     "Version 1 envelope",
+			inStored: "\x01\x00\x00\x00\x11" + `{"format":"json"}` + `json{"field":"value"}`,
+			expectedEnvelope: Envelope{
+				Version: Version1,
+				Header:  Header{Format: "json"},
+				Value:   `json{"field":"value"}`,
+			},
+		},
+		{
+			desc:     "Version 1 envelope with an empty header",
+			inStored: "\x01\x00\x00\x00\x02{}xml<tag></tag>",
+			expectedEnvelope: Envelope{
+				Version: Version1,
+				Value:   "xml<tag></tag>",
+			},
+		},
+		{
+			desc:        "Version 1 envelope too short to hold a header length",
+			inStored:    "\x01\x00\x00",
+			expectedErr: MalformedError{Version: Version1, Reason: "missing header length"},
+		},
+		{
+			desc:        "Version 1 envelope with a header length past the end of the value",
+			inStored:    "\x01\x00\x00\x00\x20{}",
+			expectedErr: MalformedError{Version: Version1, Reason: "header length 32 exceeds the 2 bytes left"},
+		},
+		{
+			desc:        "Unknown version",
+			inStored:    "\x07whatever",
+			expectedErr: UnknownVersionError{Version: 7},
+		},
+	}
+
+	for _, tc := range testCases {
+		env, err := Decode(tc.inStored)
+
+		assert.Equal(t, tc.expectedErr, err, tc.desc)
+		assert.Equal(t, tc.expectedEnvelope, env, tc.desc)
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	value := `json{"field":"value"}`
+
+	for _, version := range []byte{Version0, Version1} {
+		stored, err := Encode(version, Header{Format: backends.JSON_PREFIX}, value)
+		assert.NoError(t, err, "Encode version %d", version)
+
+		env, err := Decode(stored)
+		assert.NoError(t, err, "Decode version %d", version)
+		assert.Equal(t, version, env.Version, "Decode version %d", version)
+		assert.Equal(t, value, env.Value, "Decode version %d", version)
+	}
+}
+
+func TestEncodeUnknownVersion(t *testing.T) {
+	_, err := Encode(LatestVersion+1, Header{}, "xml<tag></tag>")
+	assert.Equal(t, UnknownVersionError{Version: LatestVersion + 1}, err)
+}
+
+func TestVersionedBackend(t *testing.T) {
+	delegate := backends.NewMemoryBackend()
+	legacy := Versioned(delegate, Version0)
+	current := Versioned(delegate, Version1)
+
+	assert.NoError(t, legacy.Put(context.Background(), "legacy", "xml<tag></tag>", 0))
+	assert.NoError(t, current.Put(context.Background(), "current", `json{"field":"value"}`, 0))
+
+	stored, _ := delegate.Get(context.Background(), "current")
+	assert.Equal(t, Version1, stored[0], "Values should be stored with the version byte")
+
+	// Both writers' values can be read no matter which version is being written
+	for _, backend := range []backends.Backend{legacy, current} {
+		value, err := backend.Get(context.Background(), "legacy")
+		assert.NoError(t, err)
+		assert.Equal(t, "xml<tag></tag>", value)
+
+		value, err = backend.Get(context.Background(), "current")
+		assert.NoError(t, err)
+		assert.Equal(t, `json{"field":"value"}`, value)
+	}
+}
+
+// truncatingBackend returns the values of its delegate cut down to their first bytes
+type truncatingBackend struct {
+	backends.Backend
+	bytes int
+}
+
+func (b *truncatingBackend) Get(ctx context.Context, key string) (string, error) {
+	value, err := b.Backend.Get(ctx, key)
+	if err != nil || len(value) <= b.bytes {
+		return value, err
+	}
+	return value[:b.bytes], nil
+}
+
+func TestVersionedBackendTruncatedValue(t *testing.T) {
+	delegate := backends.NewMemoryBackend()
+	assert.NoError(t, Versioned(delegate, Version1).Put(context.Background(), "key", `json{"field":"value"}`, 0))
+	stored, _ := delegate.Get(context.Background(), "key")
+
+	// Keep the whole header and cut the value short
+	backend := Versioned(&truncatingBackend{Backend: delegate, bytes: len(stored) - 3}, Version1)
+
+	value, err := backend.Get(context.Background(), "key")
+	assert.Empty(t, value, "Truncated values shouldn't be returned")
+	assert.Equal(t, LengthMismatchError{Expected: 21, Actual: 18}, err)
+	assert.Equal(t, utils.Corrupt, utils.ErrorCodeOf(err))
+}
+
+func TestVerify(t *testing.T) {
+	stored, err := Encode(Version1, Header{}, "xml<tag></tag>")
+	assert.NoError(t, err)
+	env, err := Decode(stored)
+	assert.NoError(t, err)
+	assert.NoError(t, env.Verify(), "Untouched value should match its checksum")
+
+	tampered := env
+	tampered.Value = "xml<tag></gat>"
+	assert.IsType(t, ChecksumMismatchError{}, tampered.Verify(), "Modified value should not match its checksum")
+
+	truncated := env
+	truncated.Value = "xml<tag>"
+	assert.Equal(t, LengthMismatchError{Expected: 14, Actual: 8}, truncated.Verify(), "Truncated value should not match its length")
+
+	legacy, err := Decode("xml<tag></tag>")
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Verify(), "Values without checksum can't be verified")
+}
+
+func TestVersionedMetadata(t *testing.T) {
+	storedAt := time.Unix(1600000000, 0)
+	delegate := backends.NewMemoryBackend()
+	backend := Versioned(delegate, Version1).(*versioned)
+	backend.now = func() time.Time { return storedAt }
+
+	assert.NoError(t, backend.Put(context.Background(), "current", `json{"field":"value"}`, 300))
+	assert.NoError(t, delegate.Put(context.Background(), "legacy", "xml<tag></tag>", 300))
+
+	ctx, md := WithMetadata(context.Background())
+	_, err := backend.Get(ctx, "current")
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{Format: "json", Size: 21, CreatedAt: storedAt, TTL: 5 * time.Minute}, *md)
+
+	remaining, ok := md.Remaining(storedAt.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 4*time.Minute, remaining)
+	remaining, ok = md.Remaining(storedAt.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), remaining, "Expired values have no time left")
+
+	ctx, md = WithMetadata(context.Background())
+	_, err = backend.Get(ctx, "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{Format: "xml", Size: 14}, *md)
+	_, ok = md.Remaining(storedAt)
+	assert.False(t, ok, "Legacy values don't know their remaining TTL")
+}
+
+func TestVersionedWriter(t *testing.T) {
+	backend := Versioned(backends.NewMemoryBackend(), Version1)
+
+	assert.NoError(t, backend.Put(WithWriter(context.Background(), "partner-a"), "correlated", "json{}", 0))
+	assert.NoError(t, backend.Put(context.Background(), "anonymous", "json{}", 0))
+
+	ctx, md := WithMetadata(context.Background())
+	_, err := backend.Get(ctx, "correlated")
+	assert.NoError(t, err)
+	assert.Equal(t, "partner-a", md.Writer, "The writer should be stored along with the value")
+
+	ctx, md = WithMetadata(context.Background())
+	_, err = backend.Get(ctx, "anonymous")
+	assert.NoError(t, err)
+	assert.Empty(t, md.Writer, "Values written without a writer should have none")
+}
+
+func TestPropagate(t *testing.T) {
+	from, md := WithMetadata(WithWriter(context.Background(), "partner-a"))
+
+	ctx := Propagate(context.Background(), from)
+
+	assert.Equal(t, "partner-a", writerOf(ctx))
+	_, shared := WithMetadata(ctx)
+	assert.True(t, md == shared, "The metadata should be shared with the original context")
+}
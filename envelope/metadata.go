@@ -0,0 +1,92 @@
+package envelope
+
+import (
+	"context"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+)
+
+// Metadata describes a value as it was stored. Values stored as version 0 only know their format
+// and size, so their CreatedAt and TTL are left as zero.
+type Metadata struct {
+	Format    string
+	Size      int
+	CreatedAt time.Time
+	TTL       time.Duration
+	Writer    string
+}
+
+// Remaining returns how much longer the value lives past now. It returns false if the value was stored
+// without its creation time or TTL.
+func (m Metadata) Remaining(now time.Time) (time.Duration, bool) {
+	if m.CreatedAt.IsZero() || m.TTL <= 0 {
+		return 0, false
+	}
+	remaining := m.CreatedAt.Add(m.TTL).Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx along with the Metadata that a Versioned backend fills in when it
+// reads a value with that context. The Metadata is left untouched if the read fails. If ctx already
+// carries a Metadata, ctx is returned as is along with it, so that everyone asking for it shares it.
+func WithMetadata(ctx context.Context) (context.Context, *Metadata) {
+	if md, ok := ctx.Value(metadataKey{}).(*Metadata); ok {
+		return ctx, md
+	}
+	md := &Metadata{}
+	return context.WithValue(ctx, metadataKey{}, md), md
+}
+
+// WithOwnMetadata is like WithMetadata, except that the returned Metadata is never the one ctx may
+// already carry. Reads made concurrently with the same context need it not to fill in the same Metadata.
+func WithOwnMetadata(ctx context.Context) (context.Context, *Metadata) {
+	md := &Metadata{}
+	return context.WithValue(ctx, metadataKey{}, md), md
+}
+
+type writerKey struct{}
+
+// WithWriter returns a copy of ctx along with the identity of the client writing values with it. A
+// Versioned backend stores it next to every value it writes with that context.
+func WithWriter(ctx context.Context, writer string) context.Context {
+	return context.WithValue(ctx, writerKey{}, writer)
+}
+
+func writerOf(ctx context.Context) string {
+	writer, _ := ctx.Value(writerKey{}).(string)
+	return writer
+}
+
+// Propagate returns ctx along with the writer and the Metadata carried by from, if any. It lets what
+// was attached to a request reach backend calls made with a context that doesn't derive from it.
+func Propagate(ctx context.Context, from context.Context) context.Context {
+	if writer := writerOf(from); writer != "" {
+		ctx = WithWriter(ctx, writer)
+	}
+	if md, ok := from.Value(metadataKey{}).(*Metadata); ok {
+		ctx = context.WithValue(ctx, metadataKey{}, md)
+	}
+	return ctx
+}
+
+func metadataOf(env Envelope) Metadata {
+	md := Metadata{
+		Format: env.Header.Format,
+		Size:   len(env.Value),
+		TTL:    time.Duration(env.Header.TTLSeconds) * time.Second,
+		Writer: env.Header.Writer,
+	}
+	if md.Format == "" {
+		md.Format = backends.ValueFormat(env.Value)
+	}
+	if env.Header.CreatedAt > 0 {
+		md.CreatedAt = time.Unix(env.Header.CreatedAt, 0)
+	}
+	return md
+}
@@ -0,0 +1,128 @@
+package envelope
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const scanBatchSize = 100
+
+// Scrubber walks through the keys of a backend in the background and verifies the checksum of a sample
+// of the stored values, so that silent corruption gets noticed before a client reads the value.
+type Scrubber struct {
+	scanner    backends.KeyScanner
+	store      backends.Backend
+	metrics    *metrics.Metrics
+	sampleRate float64
+	pace       time.Duration
+	randFloat  func() float64
+}
+
+// NewScrubber returns a Scrubber that lists keys with scanner and reads the values from store. Values
+// read from store are expected to still be laid out as stored envelopes, so store must not be wrapped
+// with Versioned.
+func NewScrubber(scanner backends.KeyScanner, store backends.Backend, m *metrics.Metrics, cfg config.Scrubber) *Scrubber {
+	return &Scrubber{
+		scanner:    scanner,
+		store:      store,
+		metrics:    m,
+		sampleRate: cfg.SampleRate,
+		pace:       time.Second / time.Duration(cfg.KeysPerSecond),
+		randFloat:  rand.Float64,
+	}
+}
+
+// Run scrubs the backend over and over until the context is done. Values get verified no faster than
+// the configured keys per second, to limit the load the scrubber adds on the backend.
+func (s *Scrubber) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.pace)
+	defer ticker.Stop()
+
+	wait := func() bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	}
+
+	for {
+		checked, corrupt, err := s.scrub(ctx, wait)
+		if err != nil {
+			log.Errorf("Scrubber could not scan the backend keys: %v", err)
+		} else {
+			log.Debugf("Scrubber pass finished. Verified %d values, %d of them corrupt", checked, corrupt)
+		}
+
+		// Also paces passes over backends with few or no keys
+		if !wait() {
+			return
+		}
+	}
+}
+
+// scrub makes a single pass through every key of the backend. wait gets called before every value is
+// read, and the pass stops early if it returns false. It returns the number of values verified and
+// how many of them were found corrupt.
+func (s *Scrubber) scrub(ctx context.Context, wait func() bool) (checked int, corrupt int, err error) {
+	var cursor uint64
+	for {
+		var keys []string
+		keys, cursor, err = s.scanner.ScanKeys(ctx, cursor, scanBatchSize)
+		if err != nil {
+			return checked, corrupt, err
+		}
+
+		for _, key := range keys {
+			if s.sampleRate < 1 && s.randFloat() >= s.sampleRate {
+				continue
+			}
+			if !wait() {
+				return checked, corrupt, nil
+			}
+			verified, ok := s.verify(ctx, key)
+			if verified {
+				checked++
+			}
+			if !ok {
+				corrupt++
+			}
+		}
+
+		if cursor == 0 {
+			return checked, corrupt, nil
+		}
+	}
+}
+
+// verify reads the value stored under key and checks its integrity. verified is false if the value
+// couldn't be read, which happens when it expired after the key was listed.
+func (s *Scrubber) verify(ctx context.Context, key string) (verified bool, ok bool) {
+	stored, err := s.store.Get(ctx, key)
+	if err != nil {
+		log.Debugf("Scrubber could not read the value of key %s: %v", key, err)
+		return false, true
+	}
+
+	env, err := Decode(stored)
+	if err == nil {
+		err = env.Verify()
+	} else if _, unknown := err.(UnknownVersionError); unknown {
+		// Most likely written by a newer Prebid Cache. It's not ours to judge.
+		return false, true
+	}
+
+	if err != nil {
+		log.Errorf("Stored value of key %s is corrupt: %v", key, err)
+		s.metrics.RecordCorruptValue()
+		return true, false
+	}
+	return true, true
+}
@@ -0,0 +1,97 @@
+package envelope
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func noWait() bool { return true }
+
+func TestScrubberFlagsCorruptValues(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	store := backends.NewMemoryBackend()
+	ctx := context.Background()
+
+	healthy, _ := Encode(Version1, Header{Format: backends.JSON_PREFIX}, `json{"field":"value"}`)
+	corrupt, _ := Encode(Version1, Header{Format: backends.JSON_PREFIX}, `json{"field":"value"}`)
+	corrupt = corrupt[:len(corrupt)-3] + `"}}`
+
+	store.Put(ctx, "healthy", healthy, 0)
+	store.Put(ctx, "legacy", "xml<tag></tag>", 0)
+	store.Put(ctx, "corrupt", corrupt, 0)
+	store.Put(ctx, "malformed", "\x01\x00\x00\x00\x20{}", 0)
+	store.Put(ctx, "newer", "\x02whatever", 0)
+
+	scrubber := NewScrubber(store, store, m, config.Scrubber{Enabled: true, KeysPerSecond: 10, SampleRate: 1})
+	checked, corrupted, err := scrubber.scrub(ctx, noWait)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, checked, "Every value but the one of an unknown version should be verified")
+	assert.Equal(t, 2, corrupted, "The corrupt and malformed values should be flagged")
+	assert.Equal(t, int64(2), metricstest.MockCounters["gets.backend_error.corrupt_value"])
+}
+
+func TestScrubberSampling(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	store := backends.NewMemoryBackend()
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		store.Put(ctx, key, "xml<tag></tag>", 0)
+	}
+
+	scrubber := NewScrubber(store, store, m, config.Scrubber{Enabled: true, KeysPerSecond: 10, SampleRate: 0.5})
+	draws := []float64{0.1, 0.6, 0.3, 0.9}
+	i := 0
+	scrubber.randFloat = func() float64 {
+		draw := draws[i%len(draws)]
+		i++
+		return draw
+	}
+
+	checked, _, err := scrubber.scrub(ctx, noWait)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, checked, "Only the sampled keys should be verified")
+}
+
+func TestScrubberScansInBatches(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	store := backends.NewMemoryBackend()
+	ctx := context.Background()
+	for i := 0; i < scanBatchSize*2+1; i++ {
+		store.Put(ctx, fmt.Sprintf("key-%03d", i), "xml<tag></tag>", 0)
+	}
+
+	scrubber := NewScrubber(store, store, m, config.Scrubber{Enabled: true, KeysPerSecond: 10, SampleRate: 1})
+	checked, corrupted, err := scrubber.scrub(ctx, noWait)
+
+	assert.NoError(t, err)
+	assert.Equal(t, scanBatchSize*2+1, checked)
+	assert.Equal(t, 0, corrupted)
+}
+
+func TestScrubberRunStopsWithContext(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	store := backends.NewMemoryBackend()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		NewScrubber(store, store, m, config.Scrubber{Enabled: true, KeysPerSecond: 1000, SampleRate: 1}).Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Scrubber should stop once its context is done")
+	}
+}
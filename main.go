@@ -2,14 +2,18 @@ package main
 
 import (
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/prebid/prebid-cache/backends"
 	backendConfig "github.com/prebid/prebid-cache/backends/config"
 	"github.com/prebid/prebid-cache/config"
 	"github.com/prebid/prebid-cache/endpoints/routing"
 	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/priming"
 	"github.com/prebid/prebid-cache/server"
+	"github.com/prebid/prebid-cache/tracing"
 )
 
 const configFileName = "config"
@@ -18,16 +22,38 @@ func main() {
 	log.SetOutput(os.Stdout)
 	cfg := config.NewConfig(configFileName)
 	setLogLevel(cfg.Log.Level)
+	log.SetFormatter(cfg.Log.Formatter())
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
 	cfg.ValidateAndLog()
 
+	stopTracing := tracing.Start(cfg.Tracing)
+	defer stopTracing()
+
 	appMetrics := metrics.CreateMetrics(cfg)
 	backend := backendConfig.NewBackend(cfg, appMetrics)
+	if cfg.Priming.Enabled {
+		primeBackend(cfg.Priming, backend)
+	}
 	publicHandler := routing.NewPublicHandler(cfg, backend, appMetrics)
 	adminHandler := routing.NewAdminHandler(cfg, backend, appMetrics)
 	go appMetrics.Export(cfg)
 	server.Listen(cfg, publicHandler, adminHandler, appMetrics)
 }
 
+// primeBackend copies the values of the peer instance before the servers start listening. Priming
+// stops on errors, and the servers start with whatever was copied by then.
+func primeBackend(cfg config.Priming, backend backends.Backend) {
+	start := time.Now()
+	copied, err := priming.Prime(cfg, backend)
+	if err != nil {
+		log.Errorf("Priming from %s stopped after %d values: %v", cfg.PeerURL, copied, err)
+		return
+	}
+	log.Infof("Primed %d values from %s in %v", copied, cfg.PeerURL, time.Since(start))
+}
+
 func setLogLevel(logLevel config.LogLevel) {
 	level, err := log.ParseLevel(string(logLevel))
 	if err != nil {
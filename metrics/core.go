@@ -6,9 +6,33 @@ import (
 	"github.com/prebid/prebid-cache/config"
 	influx "github.com/prebid/prebid-cache/metrics/influx"
 	prometheus "github.com/prebid/prebid-cache/metrics/prometheus"
+	statsd "github.com/prebid/prebid-cache/metrics/statsd"
 )
 
-// Metrics provides access to metric engines.
+// Reasons requests get rejected for before reaching the backend, which RecordRejectedRequest counts them by
+const (
+	RejectedRateLimit = "rate_limit"
+	RejectedQuota     = "quota"
+	RejectedOverload  = "overload"
+	RejectedForbidden = "forbidden"
+	RejectedThrottled = "throttled"
+)
+
+// States of the backend circuit breaker, which RecordCircuitBreakerTransition counts the transitions to
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// Tiers of a tiered backend, which RecordTierLookup counts the hits and misses of
+const (
+	TierNear   = "near"
+	TierRemote = "remote"
+)
+
+// Metrics provides access to metric engines. Every call is fanned out to each of them, so any
+// combination of Influx, Prometheus and StatsD can be enabled at once.
 type Metrics struct {
 	MetricEngines []CacheMetrics
 }
@@ -62,6 +86,182 @@ func (m Metrics) RecordGetDuration(duration time.Duration) {
 	}
 }
 
+func (m Metrics) RecordDeleteError() {
+	for _, me := range m.MetricEngines {
+		me.RecordDeleteError()
+	}
+}
+
+func (m Metrics) RecordDeleteBadRequest() {
+	for _, me := range m.MetricEngines {
+		me.RecordDeleteBadRequest()
+	}
+}
+
+func (m Metrics) RecordDeleteTotal() {
+	for _, me := range m.MetricEngines {
+		me.RecordDeleteTotal()
+	}
+}
+
+func (m Metrics) RecordDeleteDuration(duration time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordDeleteDuration(duration)
+	}
+}
+
+func (m Metrics) RecordTouchError() {
+	for _, me := range m.MetricEngines {
+		me.RecordTouchError()
+	}
+}
+
+func (m Metrics) RecordTouchBadRequest() {
+	for _, me := range m.MetricEngines {
+		me.RecordTouchBadRequest()
+	}
+}
+
+func (m Metrics) RecordTouchTotal() {
+	for _, me := range m.MetricEngines {
+		me.RecordTouchTotal()
+	}
+}
+
+func (m Metrics) RecordTouchDuration(duration time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordTouchDuration(duration)
+	}
+}
+
+func (m Metrics) RecordEndToEndDuration(duration time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordEndToEndDuration(duration)
+	}
+}
+
+// RecordPutParseError counts the PUT requests whose body isn't JSON, which RecordPutValidationError
+// doesn't count
+func (m Metrics) RecordPutParseError() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutParseError()
+	}
+}
+
+// RecordPutValidationError counts the PUT requests whose body is JSON, but not a valid request
+func (m Metrics) RecordPutValidationError() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutValidationError()
+	}
+}
+
+func (m Metrics) RecordPutQuotaRejection() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutQuotaRejection()
+	}
+}
+
+func (m Metrics) RecordRejectedRequest(reason string) {
+	for _, me := range m.MetricEngines {
+		me.RecordRejectedRequest(reason)
+	}
+}
+
+// RecordMaxAttemptsOverride records the max attempts a request got through the retry override header,
+// once lowered to the configured ceiling
+func (m Metrics) RecordMaxAttemptsOverride(attempts int) {
+	for _, me := range m.MetricEngines {
+		me.RecordMaxAttemptsOverride(attempts)
+	}
+}
+
+// RecordBackendConcurrencyWait records how long an operation waited for its turn at the backend
+func (m Metrics) RecordBackendConcurrencyWait(duration time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordBackendConcurrencyWait(duration)
+	}
+}
+
+func (m Metrics) RecordReplicationLag(duration time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordReplicationLag(duration)
+	}
+}
+
+func (m Metrics) RecordReplicationQueueDepth(depth int) {
+	for _, me := range m.MetricEngines {
+		me.RecordReplicationQueueDepth(depth)
+	}
+}
+
+func (m Metrics) RecordReplicationDropped() {
+	for _, me := range m.MetricEngines {
+		me.RecordReplicationDropped()
+	}
+}
+
+func (m Metrics) RecordReplicationError() {
+	for _, me := range m.MetricEngines {
+		me.RecordReplicationError()
+	}
+}
+
+func (m Metrics) RecordCircuitBreakerTransition(state string) {
+	for _, me := range m.MetricEngines {
+		me.RecordCircuitBreakerTransition(state)
+	}
+}
+
+func (m Metrics) RecordPutBackendBatchSize(size int) {
+	for _, me := range m.MetricEngines {
+		me.RecordPutBackendBatchSize(size)
+	}
+}
+
+func (m Metrics) RecordGetBackendBatchSize(size int) {
+	for _, me := range m.MetricEngines {
+		me.RecordGetBackendBatchSize(size)
+	}
+}
+
+func (m Metrics) RecordMemoryEviction(reason string, age time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordMemoryEviction(reason, age)
+	}
+}
+
+func (m Metrics) RecordFileDescriptors(open int, limit int) {
+	for _, me := range m.MetricEngines {
+		me.RecordFileDescriptors(open, limit)
+	}
+}
+
+func (m Metrics) RecordFileDescriptorWarning() {
+	for _, me := range m.MetricEngines {
+		me.RecordFileDescriptorWarning()
+	}
+}
+
+// RegisterConnectionPool has every engine able to read metrics when they get gathered read the stats
+// of the backend connection pool through the given funcs. Stats the backend can't report are nil.
+func (m Metrics) RegisterConnectionPool(active, size, pending func() int) {
+	for _, me := range m.MetricEngines {
+		me.RegisterConnectionPool(active, size, pending)
+	}
+}
+
+func (m Metrics) RecordPutUserAgent(class string) {
+	for _, me := range m.MetricEngines {
+		me.RecordPutUserAgent(class)
+	}
+}
+
+func (m Metrics) RecordGetUserAgent(class string) {
+	for _, me := range m.MetricEngines {
+		me.RecordGetUserAgent(class)
+	}
+}
+
 func (m Metrics) RecordPutBackendXml() {
 	for _, me := range m.MetricEngines {
 		me.RecordPutBackendXml()
@@ -98,12 +298,36 @@ func (m Metrics) RecordPutBackendError() {
 	}
 }
 
+func (m Metrics) RecordPutBackendTimeout() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutBackendTimeout()
+	}
+}
+
 func (m Metrics) RecordPutBackendSize(sizeInBytes float64) {
 	for _, me := range m.MetricEngines {
 		me.RecordPutBackendSize(sizeInBytes)
 	}
 }
 
+func (m Metrics) RecordPutVerificationFailure() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutVerificationFailure()
+	}
+}
+
+func (m Metrics) RecordPutKeyCollision() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutKeyCollision()
+	}
+}
+
+func (m Metrics) RecordPutOversized() {
+	for _, me := range m.MetricEngines {
+		me.RecordPutOversized()
+	}
+}
+
 func (m Metrics) RecordGetBackendDuration(duration time.Duration) {
 	for _, me := range m.MetricEngines {
 		me.RecordGetBackendDuration(duration)
@@ -122,6 +346,12 @@ func (m Metrics) RecordGetBackendError() {
 	}
 }
 
+func (m Metrics) RecordGetBackendTimeout() {
+	for _, me := range m.MetricEngines {
+		me.RecordGetBackendTimeout()
+	}
+}
+
 func (m Metrics) RecordKeyNotFoundError() {
 	for _, me := range m.MetricEngines {
 		me.RecordKeyNotFoundError()
@@ -134,6 +364,30 @@ func (m Metrics) RecordMissingKeyError() {
 	}
 }
 
+func (m Metrics) RecordCorruptValue() {
+	for _, me := range m.MetricEngines {
+		me.RecordCorruptValue()
+	}
+}
+
+func (m Metrics) RecordGetBackendKeyHit() {
+	for _, me := range m.MetricEngines {
+		me.RecordGetBackendKeyHit()
+	}
+}
+
+func (m Metrics) RecordGetBackendKeyMiss() {
+	for _, me := range m.MetricEngines {
+		me.RecordGetBackendKeyMiss()
+	}
+}
+
+func (m Metrics) RecordTierLookup(tier string, hit bool) {
+	for _, me := range m.MetricEngines {
+		me.RecordTierLookup(tier, hit)
+	}
+}
+
 func (m Metrics) RecordConnectionOpen() {
 	for _, me := range m.MetricEngines {
 		me.RecordConnectionOpen()
@@ -158,12 +412,32 @@ func (m Metrics) RecordAcceptConnectionErrors() {
 	}
 }
 
+func (m Metrics) RecordTLSHandshake(duration time.Duration) {
+	for _, me := range m.MetricEngines {
+		me.RecordTLSHandshake(duration)
+	}
+}
+
+func (m Metrics) RecordTLSHandshakeFailure(reason string) {
+	for _, me := range m.MetricEngines {
+		me.RecordTLSHandshakeFailure(reason)
+	}
+}
+
 func (m Metrics) RecordExtraTTLSeconds(value float64) {
 	for _, me := range m.MetricEngines {
 		me.RecordExtraTTLSeconds(value)
 	}
 }
 
+// RecordAppliedTTLSeconds records the TTL a value got stored with, whenever the TTL rules changed the
+// one it was put with
+func (m Metrics) RecordAppliedTTLSeconds(value float64) {
+	for _, me := range m.MetricEngines {
+		me.RecordAppliedTTLSeconds(value)
+	}
+}
+
 func (m Metrics) Export(cfg config.Configuration) {
 	for _, me := range m.MetricEngines {
 		me.Export(cfg.Metrics)
@@ -179,6 +453,18 @@ func (m Metrics) GetEngineRegistry(name string) interface{} {
 	return nil
 }
 
+// ResetPrometheus zeroes every metric of the Prometheus engine. It returns false if there's no
+// Prometheus engine to reset.
+func (m Metrics) ResetPrometheus() bool {
+	for _, me := range m.MetricEngines {
+		if promMetrics, ok := me.(*prometheus.PrometheusMetrics); ok {
+			promMetrics.Reset()
+			return true
+		}
+	}
+	return false
+}
+
 type CacheMetrics interface {
 	// Auxiliary functions
 	Export(cfg config.Metrics)
@@ -194,33 +480,76 @@ type CacheMetrics interface {
 	RecordGetBadRequest()
 	RecordGetTotal()
 	RecordGetDuration(duration time.Duration)
+	RecordDeleteError()
+	RecordDeleteBadRequest()
+	RecordDeleteTotal()
+	RecordDeleteDuration(duration time.Duration)
+	RecordTouchError()
+	RecordTouchBadRequest()
+	RecordTouchTotal()
+	RecordTouchDuration(duration time.Duration)
+	RecordEndToEndDuration(duration time.Duration)
+	RecordPutParseError()
+	RecordPutValidationError()
+	RecordPutQuotaRejection()
+	RecordRejectedRequest(reason string)
+	RecordPutUserAgent(class string)
+	RecordGetUserAgent(class string)
 	RecordPutBackendXml()
 	RecordPutBackendJson()
 	RecordPutBackendInvalid()
 	RecordPutBackendDefTTL()
 	RecordPutBackendDuration(duration time.Duration)
 	RecordPutBackendError()
+	RecordPutBackendTimeout()
 	RecordPutBackendSize(sizeInBytes float64)
+	RecordPutVerificationFailure()
+	RecordPutKeyCollision()
+	RecordPutOversized()
 	RecordGetBackendTotal()
 	RecordGetBackendDuration(duration time.Duration)
 	RecordGetBackendError()
+	RecordGetBackendTimeout()
 	RecordKeyNotFoundError()
 	RecordMissingKeyError()
+	RecordCorruptValue()
+	RecordGetBackendKeyHit()
+	RecordGetBackendKeyMiss()
+	RecordTierLookup(tier string, hit bool)
 	RecordConnectionOpen()
 	RecordConnectionClosed()
 	RecordCloseConnectionErrors()
 	RecordAcceptConnectionErrors()
+	RecordTLSHandshake(duration time.Duration)
+	RecordTLSHandshakeFailure(reason string)
 	RecordExtraTTLSeconds(value float64)
+	RecordAppliedTTLSeconds(value float64)
+	RecordReplicationLag(duration time.Duration)
+	RecordBackendConcurrencyWait(duration time.Duration)
+	RecordMaxAttemptsOverride(attempts int)
+	RecordReplicationQueueDepth(depth int)
+	RecordReplicationDropped()
+	RecordReplicationError()
+	RecordCircuitBreakerTransition(state string)
+	RecordPutBackendBatchSize(size int)
+	RecordGetBackendBatchSize(size int)
+	RecordMemoryEviction(reason string, age time.Duration)
+	RecordFileDescriptors(open int, limit int)
+	RecordFileDescriptorWarning()
+	RegisterConnectionPool(active, size, pending func() int)
 }
 
 func CreateMetrics(cfg config.Configuration) *Metrics {
-	engineList := make([]CacheMetrics, 0, 2)
+	engineList := make([]CacheMetrics, 0, 3)
 
 	if cfg.Metrics.Influx.Enabled {
 		engineList = append(engineList, influx.CreateInfluxMetrics())
 	}
 	if cfg.Metrics.Prometheus.Enabled {
-		engineList = append(engineList, prometheus.CreatePrometheusMetrics(cfg.Metrics.Prometheus))
+		engineList = append(engineList, prometheus.CreatePrometheusMetrics(cfg.Metrics.Prometheus, cfg.Backend.Type))
+	}
+	if cfg.Metrics.Statsd.Enabled {
+		engineList = append(engineList, statsd.CreateStatsdMetrics(cfg.Metrics.Statsd))
 	}
 	return &Metrics{MetricEngines: engineList}
 }
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	influx "github.com/prebid/prebid-cache/metrics/influx"
+	prometheus "github.com/prebid/prebid-cache/metrics/prometheus"
+	statsd "github.com/prebid/prebid-cache/metrics/statsd"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMetricsFansOutToEveryEngine(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer agent.Close()
+
+	cfg := config.Configuration{
+		Metrics: config.Metrics{
+			Influx:     config.InfluxMetrics{Enabled: true},
+			Prometheus: config.PrometheusMetrics{Enabled: true, Namespace: "prebid", Subsystem: "cache"},
+			Statsd: config.StatsdMetrics{
+				Enabled:    true,
+				Host:       "127.0.0.1",
+				Port:       agent.LocalAddr().(*net.UDPAddr).Port,
+				Prefix:     "prebid_cache",
+				SampleRate: 1,
+			},
+		},
+	}
+	m := CreateMetrics(cfg)
+
+	if !assert.Len(t, m.MetricEngines, 3) {
+		return
+	}
+	m.RecordPutTotal()
+
+	influxMetrics := m.MetricEngines[0].(*influx.InfluxMetrics)
+	assert.Equal(t, int64(1), influxMetrics.Puts.Request.Count(), "Influx didn't count the put")
+
+	promMetrics := m.MetricEngines[1].(*prometheus.PrometheusMetrics)
+	assert.Equal(t, 1.0, testutil.ToFloat64(promMetrics.Puts.RequestStatus.WithLabelValues(prometheus.TotalsVal)), "Prometheus didn't count the put")
+
+	assert.IsType(t, &statsd.StatsdMetrics{}, m.MetricEngines[2])
+	buf := make([]byte, 512)
+	agent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := agent.ReadFrom(buf)
+	if assert.NoError(t, err, "StatsD didn't send the put") {
+		assert.Equal(t, "prebid_cache.puts.current_url.request_count:1|c", string(buf[:n]))
+	}
+}
+
+func TestCreateMetricsWithoutEngines(t *testing.T) {
+	m := CreateMetrics(config.Configuration{})
+
+	assert.Empty(t, m.MetricEngines)
+	assert.NotPanics(t, m.RecordPutTotal, "Metrics without engines should record nothing")
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportBufferSize is the number of registry snapshots the Influx exporter holds while the
+// collector can't keep up. With the default flush interval, that covers ten minutes of outage.
+const ExportBufferSize = 60
+
+// DroppedBatchesMet counts the registry snapshots discarded because the export buffer was full
+const DroppedBatchesMet = "exporter.dropped_batches"
+
+// batchWriter sends a batch of points, encoded in the InfluxDB line protocol, to the collector
+type batchWriter interface {
+	Write(batch []byte) error
+}
+
+// influxHTTPWriter writes batches through the InfluxDB HTTP API
+type influxHTTPWriter struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+}
+
+func newInfluxHTTPWriter(host, database, username, password string, timeout time.Duration) influxHTTPWriter {
+	query := url.Values{}
+	query.Set("db", database)
+	query.Set("precision", "s")
+
+	return influxHTTPWriter{
+		client:   &http.Client{Timeout: timeout},
+		url:      strings.TrimRight(host, "/") + "/write?" + query.Encode(),
+		username: username,
+		password: password,
+	}
+}
+
+func (w influxHTTPWriter) Write(batch []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("influx responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// influxExporter takes a snapshot of the registry every interval and hands it to a separate goroutine
+// that writes it to the collector. Snapshots wait in a bounded buffer in between: if the collector is
+// slow or unreachable, the oldest snapshot gets dropped to make room for the newest one, so the
+// exporter never holds on to an unbounded amount of memory. Request handling only ever touches the
+// registry, which means it's never slowed down by the collector.
+type influxExporter struct {
+	registry metrics.Registry
+	interval time.Duration
+	writer   batchWriter
+	batches  chan []byte
+	dropped  metrics.Meter
+	done     chan struct{}
+}
+
+func newInfluxExporter(registry metrics.Registry, interval time.Duration, bufferSize int, writer batchWriter) *influxExporter {
+	return &influxExporter{
+		registry: registry,
+		interval: interval,
+		writer:   writer,
+		batches:  make(chan []byte, bufferSize),
+		dropped:  metrics.GetOrRegisterMeter(DroppedBatchesMet, registry),
+		done:     make(chan struct{}),
+	}
+}
+
+// run exports snapshots of the registry until stop gets called
+func (e *influxExporter) run() {
+	go e.send()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			e.enqueue(encodeRegistry(e.registry, now))
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func (e *influxExporter) stop() {
+	close(e.done)
+}
+
+// enqueue adds the batch to the buffer without ever blocking, dropping the oldest batches if it's full
+func (e *influxExporter) enqueue(batch []byte) {
+	if len(batch) == 0 {
+		return
+	}
+	for {
+		select {
+		case e.batches <- batch:
+			return
+		default:
+		}
+
+		select {
+		case <-e.batches:
+			e.dropped.Mark(1)
+		default:
+		}
+	}
+}
+
+// send writes the buffered batches one at a time. A batch that fails to be written is not retried,
+// because the next snapshot carries newer values for the same metrics anyway.
+func (e *influxExporter) send() {
+	for {
+		select {
+		case batch := <-e.batches:
+			if err := e.writer.Write(batch); err != nil {
+				logrus.Warnf("Unable to export metrics to Influx: %v", err)
+			}
+		case <-e.done:
+			return
+		}
+	}
+}
+
+var percentiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+var percentileFields = []string{"p50", "p75", "p95", "p99", "p999", "p9999"}
+
+// encodeRegistry writes every metric of the registry as a point of the line protocol. Measurement and
+// field names are the ones the go-metrics-influxdb reporter used, so existing dashboards keep working.
+func encodeRegistry(registry metrics.Registry, now time.Time) []byte {
+	var buf bytes.Buffer
+	registry.Each(func(name string, i interface{}) {
+		switch metric := i.(type) {
+		case metrics.Counter:
+			writePoint(&buf, name+".count", now, field{"value", metric.Count()})
+		case metrics.Gauge:
+			writePoint(&buf, name+".gauge", now, field{"value", metric.Value()})
+		case metrics.GaugeFloat64:
+			writePoint(&buf, name+".gauge", now, field{"value", metric.Value()})
+		case metrics.Histogram:
+			h := metric.Snapshot()
+			fields := append(sampleFields(h.Count(), h.Max(), h.Mean(), h.Min(), h.StdDev(), h.Variance()), percentileValues(h.Percentiles(percentiles))...)
+			writePoint(&buf, name+".histogram", now, fields...)
+		case metrics.Meter:
+			m := metric.Snapshot()
+			writePoint(&buf, name+".meter", now, rateFields(m.Count(), m.Rate1(), m.Rate5(), m.Rate15(), m.RateMean(), "mean")...)
+		case metrics.Timer:
+			t := metric.Snapshot()
+			fields := append(sampleFields(t.Count(), t.Max(), t.Mean(), t.Min(), t.StdDev(), t.Variance()), percentileValues(t.Percentiles(percentiles))...)
+			fields = append(fields, rateFields(t.Count(), t.Rate1(), t.Rate5(), t.Rate15(), t.RateMean(), "meanrate")[1:]...)
+			writePoint(&buf, name+".timer", now, fields...)
+		}
+	})
+	return buf.Bytes()
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func sampleFields(count, max int64, mean float64, min int64, stddev, variance float64) []field {
+	return []field{{"count", count}, {"max", max}, {"mean", mean}, {"min", min}, {"stddev", stddev}, {"variance", variance}}
+}
+
+func percentileValues(values []float64) []field {
+	fields := make([]field, len(values))
+	for i, v := range values {
+		fields[i] = field{percentileFields[i], v}
+	}
+	return fields
+}
+
+func rateFields(count int64, m1, m5, m15, mean float64, meanKey string) []field {
+	return []field{{"count", count}, {"m1", m1}, {"m5", m5}, {"m15", m15}, {meanKey, mean}}
+}
+
+func writePoint(buf *bytes.Buffer, measurement string, now time.Time, fields ...field) {
+	var encoded []string
+	for _, f := range fields {
+		switch v := f.value.(type) {
+		case int64:
+			encoded = append(encoded, f.key+"="+strconv.FormatInt(v, 10)+"i")
+		case float64:
+			// The line protocol has no representation for NaN or infinities
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			encoded = append(encoded, f.key+"="+strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	if len(encoded) == 0 {
+		return
+	}
+
+	buf.WriteString(measurementEscaper.Replace(measurement))
+	buf.WriteByte(' ')
+	buf.WriteString(strings.Join(encoded, ","))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(now.Unix(), 10))
+	buf.WriteByte('\n')
+}
+
+var measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWriter keeps the batches it's asked to write instead of sending them to a collector
+type fakeWriter struct {
+	mu      sync.Mutex
+	batches []string
+}
+
+func (w *fakeWriter) Write(batch []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, string(batch))
+	return nil
+}
+
+// lines returns the lines of the first batch written, if any
+func (w *fakeWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.batches) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(w.batches[0]), "\n")
+}
+
+func TestExportWritesRecordedEvents(t *testing.T) {
+	m := CreateInfluxMetrics()
+	m.RecordPutTotal()
+	m.RecordGetBackendKeyHit()
+	m.RecordConnectionOpen()
+	m.RecordConnectionOpen()
+	m.RecordExtraTTLSeconds(30)
+
+	writer := &fakeWriter{}
+	exporter := newInfluxExporter(m.Registry, time.Millisecond, 2, writer)
+	go exporter.run()
+	defer exporter.stop()
+
+	assert.Eventually(t, func() bool { return len(writer.lines()) > 0 }, 5*time.Second, time.Millisecond, "No batch was written")
+
+	expectedPrefixes := []string{
+		"prebidcache.puts.current_url.request_count.meter count=1i,",
+		"prebidcache.gets.backend.hit.meter count=1i,",
+		"prebidcache.connections.active_incoming.count value=2i ",
+		"prebidcache.extra_ttl_seconds.histogram count=1i,max=30i,",
+	}
+	lines := writer.lines()
+	for _, prefix := range expectedPrefixes {
+		found := false
+		for _, line := range lines {
+			if strings.HasPrefix(line, prefix) {
+				found = true
+				break
+			}
+		}
+		assert.True(t, found, "No point starts with %q", prefix)
+	}
+}
+
+func TestExportWithUnreachableCollector(t *testing.T) {
+	// A collector that accepts connections but never answers, so every write hangs until it times out
+	unblock := make(chan struct{})
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer collector.Close()
+	defer close(unblock)
+
+	m := CreateInfluxMetrics()
+	writer := newInfluxHTTPWriter(collector.URL, "database", "", "", time.Minute)
+	exporter := newInfluxExporter(m.Registry, time.Millisecond, 2, writer)
+	go exporter.run()
+	defer exporter.stop()
+
+	recorded := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			m.RecordPutTotal()
+			m.RecordPutDuration(time.Millisecond)
+		}
+		close(recorded)
+	}()
+
+	select {
+	case <-recorded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Recording metrics was blocked by the unreachable collector")
+	}
+	assert.Equal(t, int64(1000), m.Puts.Request.Count(), "Put requests were not recorded")
+
+	dropped := metrics.GetOrRegisterMeter(DroppedBatchesMet, m.Registry)
+	assert.Eventually(t, func() bool { return dropped.Count() > 0 }, 5*time.Second, 5*time.Millisecond, "Dropped batches were not counted")
+}
+
+func TestEnqueueDropsOldestBatches(t *testing.T) {
+	registry := metrics.NewRegistry()
+	exporter := newInfluxExporter(registry, time.Minute, 2, nil)
+
+	exporter.enqueue([]byte("first"))
+	exporter.enqueue([]byte("second"))
+	exporter.enqueue([]byte("third"))
+	exporter.enqueue(nil)
+
+	assert.Equal(t, int64(1), exporter.dropped.Count(), "Only the oldest batch should have been dropped")
+	assert.Equal(t, "second", string(<-exporter.batches))
+	assert.Equal(t, "third", string(<-exporter.batches))
+	assert.Len(t, exporter.batches, 0, "Empty batches should not be buffered")
+}
+
+func TestEncodeRegistry(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("puts.requests", registry).Inc(3)
+	metrics.GetOrRegisterGauge("connections active", registry).Update(7)
+	metrics.GetOrRegisterMeter("gets.current_url.request_count", registry).Mark(2)
+	metrics.GetOrRegisterTimer("gets.current_url.request_duration", registry).Update(time.Second)
+
+	lines := strings.Split(strings.TrimSpace(string(encodeRegistry(registry, time.Unix(1600000000, 0)))), "\n")
+
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines, "puts.requests.count value=3i 1600000000")
+	assert.Contains(t, lines, `connections\ active.gauge value=7i 1600000000`)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "gets.current_url.request_count.meter ") {
+			assert.Contains(t, line, "count=2i,m1=")
+			assert.Contains(t, line, ",mean=")
+		}
+		if strings.HasPrefix(line, "gets.current_url.request_duration.timer ") {
+			assert.Contains(t, line, "count=1i,max=1000000000i,")
+			assert.Contains(t, line, ",p50=1000000000,")
+			assert.Contains(t, line, ",meanrate=")
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/prebid/prebid-cache/config"
 	"github.com/rcrowley/go-metrics"
 	"github.com/sirupsen/logrus"
-	influxdb "github.com/vrischmann/go-metrics-influxdb"
 )
 
 var TenSeconds time.Duration = time.Second * 10
@@ -18,11 +17,32 @@ type InfluxMetrics struct {
 	Registry    metrics.Registry
 	Puts        *InfluxMetricsEntry
 	Gets        *InfluxMetricsEntry
+	Deletes     *InfluxMetricsEntry
+	Touches     *InfluxMetricsEntry
+	PutsByUA    *InfluxUserAgentMetrics
+	GetsByUA    *InfluxUserAgentMetrics
+	EndToEnd    metrics.Timer
+	BackendWait metrics.Timer
+	MaxAttempts metrics.Histogram
+	PutsQuota   metrics.Meter
+	PutsParse   metrics.Meter
+	PutsInvalid metrics.Meter
+	Rejections  *InfluxRejectionMetrics
 	PutsBackend *InfluxMetricsEntryByFormat
 	GetsBackend *InfluxMetricsEntry
 	GetsErr     *InfluxMetricsGetErrors
+	// GetsTimeout counts the gets that timed out, which aren't counted as errors
+	GetsTimeout metrics.Meter
+	GetsResults *InfluxGetResultMetrics
+	NearTier    *InfluxGetResultMetrics
+	RemoteTier  *InfluxGetResultMetrics
 	Connections *InfluxConnectionMetrics
 	ExtraTTL    *InfluxExtraTTL
+	Replication *InfluxReplicationMetrics
+	Breaker     *InfluxCircuitBreakerMetrics
+	Batches     *InfluxBatchMetrics
+	Memory      *InfluxMemoryMetrics
+	FDs         *InfluxFileDescriptorMetrics
 	MetricsName string
 }
 
@@ -33,6 +53,23 @@ type InfluxMetricsEntry struct {
 	Request    metrics.Meter
 }
 
+type InfluxUserAgentMetrics struct {
+	PrebidServer metrics.Meter
+	Browser      metrics.Meter
+	Other        metrics.Meter
+}
+
+// InfluxRejectionMetrics count the requests rejected before reaching the backend by the reason they were
+// rejected for
+type InfluxRejectionMetrics struct {
+	RateLimit metrics.Meter
+	Quota     metrics.Meter
+	Overload  metrics.Meter
+	Forbidden metrics.Meter
+	Throttled metrics.Meter
+	Other     metrics.Meter
+}
+
 type InfluxMetricsEntryByFormat struct {
 	Duration       metrics.Timer
 	Request        metrics.Meter
@@ -43,26 +80,88 @@ type InfluxMetricsEntryByFormat struct {
 	DefinesTTL     metrics.Meter
 	InvalidRequest metrics.Meter
 	RequestLength  metrics.Histogram
+	// VerificationFailed counts the puts whose value couldn't be read back as written
+	VerificationFailed metrics.Meter
+	// KeyCollisions counts the puts whose generated key already held a value
+	KeyCollisions metrics.Meter
+	// Oversized counts the values rejected for exceeding the max size before reaching the backend
+	Oversized metrics.Meter
+	// Timeouts counts the puts that timed out, which aren't counted as errors
+	Timeouts metrics.Meter
 }
 
 type InfluxConnectionMetrics struct {
 	ActiveConnections      metrics.Counter
 	ConnectionCloseErrors  metrics.Meter
 	ConnectionAcceptErrors metrics.Meter
+	// TLSHandshakes times the successful handshakes. Failed ones are counted by reason.
+	TLSHandshakes        metrics.Timer
+	TLSHandshakeFailures map[string]metrics.Meter
 }
 type InfluxExtraTTL struct {
-	ExtraTTLSeconds metrics.Histogram
+	ExtraTTLSeconds   metrics.Histogram
+	AppliedTTLSeconds metrics.Histogram
+}
+
+type InfluxReplicationMetrics struct {
+	Lag        metrics.Timer
+	QueueDepth metrics.Gauge
+	Dropped    metrics.Meter
+	Errors     metrics.Meter
+}
+
+// InfluxCircuitBreakerMetrics count the transitions of the backend circuit breaker to each state
+type InfluxCircuitBreakerMetrics struct {
+	Closed   metrics.Meter
+	Open     metrics.Meter
+	HalfOpen metrics.Meter
+}
+
+// InfluxBatchMetrics sample how many operations each batch sent to the backend holds
+type InfluxBatchMetrics struct {
+	PutSize metrics.Histogram
+	GetSize metrics.Histogram
+}
+
+// InfluxMemoryMetrics count the values the memory backend evicts by the reason they were evicted for
+type InfluxMemoryMetrics struct {
+	TTLEvictions    metrics.Meter
+	LRUEvictions    metrics.Meter
+	ManualEvictions metrics.Meter
+	EvictedAge      metrics.Timer
+}
+
+// InfluxFileDescriptorMetrics track the open file descriptors against their soft limit
+type InfluxFileDescriptorMetrics struct {
+	Open     metrics.Gauge
+	Limit    metrics.Gauge
+	Warnings metrics.Meter
 }
 
 type InfluxMetricsGetErrors struct {
 	KeyNotFoundErrors metrics.Meter
 	MissingKeyErrors  metrics.Meter
+	CorruptValues     metrics.Meter
+}
+
+// InfluxGetResultMetrics count the GET /cache lookups by whether the backend held a value for the key
+type InfluxGetResultMetrics struct {
+	Hits   metrics.Meter
+	Misses metrics.Meter
+}
+
+func NewInfluxGetResultMetrics(name string, r metrics.Registry) *InfluxGetResultMetrics {
+	return &InfluxGetResultMetrics{
+		Hits:   metrics.GetOrRegisterMeter(fmt.Sprintf("%s.hit", name), r),
+		Misses: metrics.GetOrRegisterMeter(fmt.Sprintf("%s.miss", name), r),
+	}
 }
 
 func NewInfluxGetErrorMetrics(name string, r metrics.Registry) *InfluxMetricsGetErrors {
 	return &InfluxMetricsGetErrors{
 		KeyNotFoundErrors: metrics.GetOrRegisterMeter(fmt.Sprintf("%s.key_not_found", name), r),
 		MissingKeyErrors:  metrics.GetOrRegisterMeter(fmt.Sprintf("%s.missing_key", name), r),
+		CorruptValues:     metrics.GetOrRegisterMeter(fmt.Sprintf("%s.corrupt_value", name), r),
 	}
 }
 
@@ -75,16 +174,40 @@ func NewInfluxMetricsEntry(name string, r metrics.Registry) *InfluxMetricsEntry
 	}
 }
 
+func NewInfluxUserAgentMetrics(name string, r metrics.Registry) *InfluxUserAgentMetrics {
+	return &InfluxUserAgentMetrics{
+		PrebidServer: metrics.GetOrRegisterMeter(fmt.Sprintf("%s.user_agent.prebid_server", name), r),
+		Browser:      metrics.GetOrRegisterMeter(fmt.Sprintf("%s.user_agent.browser", name), r),
+		Other:        metrics.GetOrRegisterMeter(fmt.Sprintf("%s.user_agent.other", name), r),
+	}
+}
+
+// mark counts a request under the meter of its user agent class. Unknown classes count as other.
+func (ua *InfluxUserAgentMetrics) mark(class string) {
+	switch class {
+	case "prebid-server":
+		ua.PrebidServer.Mark(1)
+	case "browser":
+		ua.Browser.Mark(1)
+	default:
+		ua.Other.Mark(1)
+	}
+}
+
 func NewInfluxMetricsEntryBackendPuts(name string, r metrics.Registry) *InfluxMetricsEntryByFormat {
 	return &InfluxMetricsEntryByFormat{
-		Duration:       metrics.GetOrRegisterTimer(fmt.Sprintf("%s.request_duration", name), r),
-		Errors:         metrics.GetOrRegisterMeter(fmt.Sprintf("%s.error_count", name), r),
-		BadRequest:     metrics.GetOrRegisterMeter(fmt.Sprintf("%s.bad_request_count", name), r),
-		JsonRequest:    metrics.GetOrRegisterMeter(fmt.Sprintf("%s.json_request_count", name), r),
-		XmlRequest:     metrics.GetOrRegisterMeter(fmt.Sprintf("%s.xml_request_count", name), r),
-		DefinesTTL:     metrics.GetOrRegisterMeter(fmt.Sprintf("%s.defines_ttl", name), r),
-		InvalidRequest: metrics.GetOrRegisterMeter(fmt.Sprintf("%s.unknown_request_count", name), r),
-		RequestLength:  metrics.GetOrRegisterHistogram(name+".request_size_bytes", r, metrics.NewExpDecaySample(1028, 0.015)),
+		Duration:           metrics.GetOrRegisterTimer(fmt.Sprintf("%s.request_duration", name), r),
+		Errors:             metrics.GetOrRegisterMeter(fmt.Sprintf("%s.error_count", name), r),
+		BadRequest:         metrics.GetOrRegisterMeter(fmt.Sprintf("%s.bad_request_count", name), r),
+		JsonRequest:        metrics.GetOrRegisterMeter(fmt.Sprintf("%s.json_request_count", name), r),
+		XmlRequest:         metrics.GetOrRegisterMeter(fmt.Sprintf("%s.xml_request_count", name), r),
+		DefinesTTL:         metrics.GetOrRegisterMeter(fmt.Sprintf("%s.defines_ttl", name), r),
+		InvalidRequest:     metrics.GetOrRegisterMeter(fmt.Sprintf("%s.unknown_request_count", name), r),
+		RequestLength:      metrics.GetOrRegisterHistogram(name+".request_size_bytes", r, metrics.NewExpDecaySample(1028, 0.015)),
+		VerificationFailed: metrics.GetOrRegisterMeter(fmt.Sprintf("%s.verification_failed", name), r),
+		KeyCollisions:      metrics.GetOrRegisterMeter(fmt.Sprintf("%s.key_collisions", name), r),
+		Oversized:          metrics.GetOrRegisterMeter(fmt.Sprintf("%s.oversized", name), r),
+		Timeouts:           metrics.GetOrRegisterMeter(fmt.Sprintf("%s.timeout_count", name), r),
 	}
 }
 
@@ -93,6 +216,67 @@ func NewInfluxConnectionMetrics(r metrics.Registry) *InfluxConnectionMetrics {
 		ActiveConnections:      metrics.GetOrRegisterCounter("connections.active_incoming", r),
 		ConnectionAcceptErrors: metrics.GetOrRegisterMeter("connections.accept_errors", r),
 		ConnectionCloseErrors:  metrics.GetOrRegisterMeter("connections.close_errors", r),
+		TLSHandshakes:          metrics.GetOrRegisterTimer("connections.tls_handshakes", r),
+		TLSHandshakeFailures: map[string]metrics.Meter{
+			"not_tls":         metrics.GetOrRegisterMeter("connections.tls_handshake_failures.not_tls", r),
+			"timeout":         metrics.GetOrRegisterMeter("connections.tls_handshake_failures.timeout", r),
+			"unsupported":     metrics.GetOrRegisterMeter("connections.tls_handshake_failures.unsupported", r),
+			"bad_certificate": metrics.GetOrRegisterMeter("connections.tls_handshake_failures.bad_certificate", r),
+			"closed":          metrics.GetOrRegisterMeter("connections.tls_handshake_failures.closed", r),
+			"other":           metrics.GetOrRegisterMeter("connections.tls_handshake_failures.other", r),
+		},
+	}
+}
+
+func NewInfluxRejectionMetrics(r metrics.Registry) *InfluxRejectionMetrics {
+	return &InfluxRejectionMetrics{
+		RateLimit: metrics.GetOrRegisterMeter("requests.rejected.rate_limit", r),
+		Quota:     metrics.GetOrRegisterMeter("requests.rejected.quota", r),
+		Overload:  metrics.GetOrRegisterMeter("requests.rejected.overload", r),
+		Forbidden: metrics.GetOrRegisterMeter("requests.rejected.forbidden", r),
+		Throttled: metrics.GetOrRegisterMeter("requests.rejected.throttled", r),
+		Other:     metrics.GetOrRegisterMeter("requests.rejected.other", r),
+	}
+}
+
+func NewInfluxReplicationMetrics(r metrics.Registry) *InfluxReplicationMetrics {
+	return &InfluxReplicationMetrics{
+		Lag:        metrics.GetOrRegisterTimer("replication.lag", r),
+		QueueDepth: metrics.GetOrRegisterGauge("replication.queue_depth", r),
+		Dropped:    metrics.GetOrRegisterMeter("replication.dropped", r),
+		Errors:     metrics.GetOrRegisterMeter("replication.errors", r),
+	}
+}
+
+func NewInfluxCircuitBreakerMetrics(r metrics.Registry) *InfluxCircuitBreakerMetrics {
+	return &InfluxCircuitBreakerMetrics{
+		Closed:   metrics.GetOrRegisterMeter("backend.circuit_breaker.closed", r),
+		Open:     metrics.GetOrRegisterMeter("backend.circuit_breaker.open", r),
+		HalfOpen: metrics.GetOrRegisterMeter("backend.circuit_breaker.half_open", r),
+	}
+}
+
+func NewInfluxBatchMetrics(r metrics.Registry) *InfluxBatchMetrics {
+	return &InfluxBatchMetrics{
+		PutSize: metrics.GetOrRegisterHistogram("puts.backend.batch_size", r, metrics.NewExpDecaySample(1028, 0.015)),
+		GetSize: metrics.GetOrRegisterHistogram("gets.backend.batch_size", r, metrics.NewExpDecaySample(1028, 0.015)),
+	}
+}
+
+func NewInfluxMemoryMetrics(r metrics.Registry) *InfluxMemoryMetrics {
+	return &InfluxMemoryMetrics{
+		TTLEvictions:    metrics.GetOrRegisterMeter("memory.evictions.ttl", r),
+		LRUEvictions:    metrics.GetOrRegisterMeter("memory.evictions.lru", r),
+		ManualEvictions: metrics.GetOrRegisterMeter("memory.evictions.manual", r),
+		EvictedAge:      metrics.GetOrRegisterTimer("memory.evicted_value_age", r),
+	}
+}
+
+func NewInfluxFileDescriptorMetrics(r metrics.Registry) *InfluxFileDescriptorMetrics {
+	return &InfluxFileDescriptorMetrics{
+		Open:     metrics.GetOrRegisterGauge("file_descriptors.open", r),
+		Limit:    metrics.GetOrRegisterGauge("file_descriptors.limit", r),
+		Warnings: metrics.GetOrRegisterMeter("file_descriptors.warnings", r),
 	}
 }
 
@@ -103,11 +287,34 @@ func CreateInfluxMetrics() *InfluxMetrics {
 		Registry:    r,
 		Puts:        NewInfluxMetricsEntry("puts.current_url", r),
 		Gets:        NewInfluxMetricsEntry("gets.current_url", r),
+		Deletes:     NewInfluxMetricsEntry("deletes.current_url", r),
+		Touches:     NewInfluxMetricsEntry("touches.current_url", r),
+		PutsByUA:    NewInfluxUserAgentMetrics("puts.current_url", r),
+		GetsByUA:    NewInfluxUserAgentMetrics("gets.current_url", r),
+		EndToEnd:    metrics.GetOrRegisterTimer("requests.end_to_end_duration", r),
+		BackendWait: metrics.GetOrRegisterTimer("backend.concurrency_wait", r),
+		MaxAttempts: metrics.GetOrRegisterHistogram("backend.max_attempts_override", r, metrics.NewExpDecaySample(1028, 0.015)),
+		PutsQuota:   metrics.GetOrRegisterMeter("puts.current_url.quota_rejected", r),
+		PutsParse:   metrics.GetOrRegisterMeter("puts.current_url.json_parse_error", r),
+		PutsInvalid: metrics.GetOrRegisterMeter("puts.current_url.validation_error", r),
+		Rejections:  NewInfluxRejectionMetrics(r),
 		PutsBackend: NewInfluxMetricsEntryBackendPuts("puts.backend", r),
 		GetsBackend: NewInfluxMetricsEntry("gets.backend", r),
 		GetsErr:     NewInfluxGetErrorMetrics("gets.backend_error", r),
+		GetsTimeout: metrics.GetOrRegisterMeter("gets.backend.timeout_count", r),
+		GetsResults: NewInfluxGetResultMetrics("gets.backend", r),
+		NearTier:    NewInfluxGetResultMetrics("gets.backend.tier.near", r),
+		RemoteTier:  NewInfluxGetResultMetrics("gets.backend.tier.remote", r),
 		Connections: NewInfluxConnectionMetrics(r),
-		ExtraTTL:    &InfluxExtraTTL{ExtraTTLSeconds: metrics.GetOrRegisterHistogram("extra_ttl_seconds", r, metrics.NewUniformSample(5000))},
+		ExtraTTL: &InfluxExtraTTL{
+			ExtraTTLSeconds:   metrics.GetOrRegisterHistogram("extra_ttl_seconds", r, metrics.NewUniformSample(5000)),
+			AppliedTTLSeconds: metrics.GetOrRegisterHistogram("applied_ttl_seconds", r, metrics.NewUniformSample(5000)),
+		},
+		Replication: NewInfluxReplicationMetrics(r),
+		Breaker:     NewInfluxCircuitBreakerMetrics(r),
+		Batches:     NewInfluxBatchMetrics(r),
+		Memory:      NewInfluxMemoryMetrics(r),
+		FDs:         NewInfluxFileDescriptorMetrics(r),
 		MetricsName: MetricsInfluxDB,
 	}
 
@@ -121,18 +328,14 @@ func CreateInfluxMetrics() *InfluxMetrics {
 }
 
 // Export begins sending metrics to the configured database.
-// This method blocks indefinitely, so it should probably be run in a goroutine.
+// This method blocks indefinitely, so it should probably be run in a goroutine. An unreachable
+// database never blocks the recording of metrics: snapshots that can't be sent in time get dropped.
 func (m InfluxMetrics) Export(cfg config.Metrics) {
 
-	logrus.Infof("Metrics will be exported to Influx with host=%s, db=%s, username=%s", cfg.Influx.Host, cfg.Influx.Database, cfg.Influx.Username)
-	influxdb.InfluxDB(
-		m.Registry,
-		TenSeconds,
-		cfg.Influx.Host,
-		cfg.Influx.Database,
-		cfg.Influx.Username,
-		cfg.Influx.Password,
-	)
+	logrus.Infof("Metrics will be exported to Influx every %s with host=%s, db=%s, username=%s", cfg.Influx.FlushInterval(), cfg.Influx.Host, cfg.Influx.Database, cfg.Influx.Username)
+	interval := cfg.Influx.FlushInterval()
+	writer := newInfluxHTTPWriter(cfg.Influx.Host, cfg.Influx.Database, cfg.Influx.Username, cfg.Influx.Password, interval)
+	newInfluxExporter(m.Registry, interval, ExportBufferSize, writer).run()
 	return
 }
 
@@ -176,6 +379,79 @@ func (m *InfluxMetrics) RecordGetDuration(duration time.Duration) {
 	m.Gets.Duration.Update(duration)
 }
 
+func (m *InfluxMetrics) RecordDeleteError() {
+	m.Deletes.Errors.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordDeleteBadRequest() {
+	m.Deletes.BadRequest.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordDeleteTotal() {
+	m.Deletes.Request.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordDeleteDuration(duration time.Duration) {
+	m.Deletes.Duration.Update(duration)
+}
+
+func (m *InfluxMetrics) RecordTouchError() {
+	m.Touches.Errors.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordTouchBadRequest() {
+	m.Touches.BadRequest.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordTouchTotal() {
+	m.Touches.Request.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordTouchDuration(duration time.Duration) {
+	m.Touches.Duration.Update(duration)
+}
+
+func (m *InfluxMetrics) RecordEndToEndDuration(duration time.Duration) {
+	m.EndToEnd.Update(duration)
+}
+
+func (m *InfluxMetrics) RecordPutParseError() {
+	m.PutsParse.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordPutValidationError() {
+	m.PutsInvalid.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordPutQuotaRejection() {
+	m.PutsQuota.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordRejectedRequest(reason string) {
+	switch reason {
+	case "rate_limit":
+		m.Rejections.RateLimit.Mark(1)
+	case "quota":
+		m.Rejections.Quota.Mark(1)
+	case "overload":
+		m.Rejections.Overload.Mark(1)
+	case "forbidden":
+		m.Rejections.Forbidden.Mark(1)
+	case "throttled":
+		m.Rejections.Throttled.Mark(1)
+	default:
+		m.Rejections.Other.Mark(1)
+	}
+}
+
+func (m *InfluxMetrics) RecordPutUserAgent(class string) {
+	m.PutsByUA.mark(class)
+}
+
+func (m *InfluxMetrics) RecordGetUserAgent(class string) {
+	m.GetsByUA.mark(class)
+}
+
 func (m *InfluxMetrics) RecordPutBackendXml() {
 	m.PutsBackend.XmlRequest.Mark(1)
 }
@@ -200,6 +476,10 @@ func (m *InfluxMetrics) RecordPutBackendError() {
 	m.PutsBackend.Errors.Mark(1)
 }
 
+func (m *InfluxMetrics) RecordPutBackendTimeout() {
+	m.PutsBackend.Timeouts.Mark(1)
+}
+
 func (m *InfluxMetrics) RecordGetBackendTotal() {
 	m.GetsBackend.Request.Mark(1)
 }
@@ -208,6 +488,18 @@ func (m *InfluxMetrics) RecordPutBackendSize(sizeInBytes float64) {
 	m.PutsBackend.RequestLength.Update(int64(sizeInBytes))
 }
 
+func (m *InfluxMetrics) RecordPutVerificationFailure() {
+	m.PutsBackend.VerificationFailed.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordPutKeyCollision() {
+	m.PutsBackend.KeyCollisions.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordPutOversized() {
+	m.PutsBackend.Oversized.Mark(1)
+}
+
 func (m *InfluxMetrics) RecordGetBackendDuration(duration time.Duration) {
 	m.GetsBackend.Duration.Update(duration)
 }
@@ -216,6 +508,10 @@ func (m *InfluxMetrics) RecordGetBackendError() {
 	m.GetsBackend.Errors.Mark(1)
 }
 
+func (m *InfluxMetrics) RecordGetBackendTimeout() {
+	m.GetsTimeout.Mark(1)
+}
+
 func (m *InfluxMetrics) RecordKeyNotFoundError() {
 	m.GetsErr.KeyNotFoundErrors.Mark(1)
 }
@@ -224,6 +520,35 @@ func (m *InfluxMetrics) RecordMissingKeyError() {
 	m.GetsErr.MissingKeyErrors.Mark(1)
 }
 
+func (m *InfluxMetrics) RecordCorruptValue() {
+	m.GetsErr.CorruptValues.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordGetBackendKeyHit() {
+	m.GetsResults.Hits.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordGetBackendKeyMiss() {
+	m.GetsResults.Misses.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordTierLookup(tier string, hit bool) {
+	var results *InfluxGetResultMetrics
+	switch tier {
+	case "near":
+		results = m.NearTier
+	case "remote":
+		results = m.RemoteTier
+	default:
+		return
+	}
+	if hit {
+		results.Hits.Mark(1)
+	} else {
+		results.Misses.Mark(1)
+	}
+}
+
 func (m *InfluxMetrics) RecordConnectionOpen() {
 	m.Connections.ActiveConnections.Inc(1)
 }
@@ -240,6 +565,104 @@ func (m *InfluxMetrics) RecordAcceptConnectionErrors() {
 	m.Connections.ConnectionAcceptErrors.Mark(1)
 }
 
+func (m *InfluxMetrics) RecordTLSHandshake(duration time.Duration) {
+	m.Connections.TLSHandshakes.Update(duration)
+}
+
+// RecordTLSHandshakeFailure counts unknown reasons as other
+func (m *InfluxMetrics) RecordTLSHandshakeFailure(reason string) {
+	meter, ok := m.Connections.TLSHandshakeFailures[reason]
+	if !ok {
+		meter = m.Connections.TLSHandshakeFailures["other"]
+	}
+	meter.Mark(1)
+}
+
 func (m *InfluxMetrics) RecordExtraTTLSeconds(value float64) {
 	m.ExtraTTL.ExtraTTLSeconds.Update(int64(value))
 }
+
+func (m *InfluxMetrics) RecordBackendConcurrencyWait(duration time.Duration) {
+	m.BackendWait.Update(duration)
+}
+
+func (m *InfluxMetrics) RecordAppliedTTLSeconds(value float64) {
+	m.ExtraTTL.AppliedTTLSeconds.Update(int64(value))
+}
+
+func (m *InfluxMetrics) RecordMaxAttemptsOverride(attempts int) {
+	m.MaxAttempts.Update(int64(attempts))
+}
+
+func (m *InfluxMetrics) RecordReplicationLag(duration time.Duration) {
+	m.Replication.Lag.Update(duration)
+}
+
+func (m *InfluxMetrics) RecordReplicationQueueDepth(depth int) {
+	m.Replication.QueueDepth.Update(int64(depth))
+}
+
+func (m *InfluxMetrics) RecordReplicationDropped() {
+	m.Replication.Dropped.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordReplicationError() {
+	m.Replication.Errors.Mark(1)
+}
+
+func (m *InfluxMetrics) RecordCircuitBreakerTransition(state string) {
+	switch state {
+	case "closed":
+		m.Breaker.Closed.Mark(1)
+	case "open":
+		m.Breaker.Open.Mark(1)
+	case "half_open":
+		m.Breaker.HalfOpen.Mark(1)
+	}
+}
+
+func (m *InfluxMetrics) RecordPutBackendBatchSize(size int) {
+	m.Batches.PutSize.Update(int64(size))
+}
+
+func (m *InfluxMetrics) RecordGetBackendBatchSize(size int) {
+	m.Batches.GetSize.Update(int64(size))
+}
+
+func (m *InfluxMetrics) RecordMemoryEviction(reason string, age time.Duration) {
+	switch reason {
+	case "ttl":
+		m.Memory.TTLEvictions.Mark(1)
+	case "lru":
+		m.Memory.LRUEvictions.Mark(1)
+	default:
+		m.Memory.ManualEvictions.Mark(1)
+	}
+	m.Memory.EvictedAge.Update(age)
+}
+
+// RegisterConnectionPool registers gauges that read the backend connection pool every time metrics get
+// exported. The stats whose func is nil aren't registered.
+func (m *InfluxMetrics) RegisterConnectionPool(active, size, pending func() int) {
+	gauges := map[string]func() int{
+		"backend.pool.active_connections":   active,
+		"backend.pool.connections":          size,
+		"backend.pool.pending_acquisitions": pending,
+	}
+	for name, read := range gauges {
+		if read == nil {
+			continue
+		}
+		read := read
+		m.Registry.GetOrRegister(name, metrics.NewFunctionalGauge(func() int64 { return int64(read()) }))
+	}
+}
+
+func (m *InfluxMetrics) RecordFileDescriptors(open int, limit int) {
+	m.FDs.Open.Update(int64(open))
+	m.FDs.Limit.Update(int64(limit))
+}
+
+func (m *InfluxMetrics) RecordFileDescriptorWarning() {
+	m.FDs.Warnings.Mark(1)
+}
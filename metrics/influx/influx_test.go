@@ -26,6 +26,39 @@ func TestRegisteredInfluxMetrics(t *testing.T) {
 		{"gets.current_url.error_count", "Meter"},
 		{"gets.current_url.bad_request_count", "Meter"},
 		{"gets.current_url.request_count", "Meter"},
+		// Deletes:
+		{"deletes.current_url.request_duration", "Timer"},
+		{"deletes.current_url.error_count", "Meter"},
+		{"deletes.current_url.bad_request_count", "Meter"},
+		{"deletes.current_url.request_count", "Meter"},
+		// Touches:
+		{"touches.current_url.request_duration", "Timer"},
+		{"touches.current_url.error_count", "Meter"},
+		{"touches.current_url.bad_request_count", "Meter"},
+		{"touches.current_url.request_count", "Meter"},
+		// Quotas:
+		{"puts.current_url.quota_rejected", "Meter"},
+		// Put body errors:
+		{"puts.current_url.json_parse_error", "Meter"},
+		{"puts.current_url.validation_error", "Meter"},
+		// Rejections:
+		{"requests.rejected.rate_limit", "Meter"},
+		{"requests.rejected.quota", "Meter"},
+		{"requests.rejected.overload", "Meter"},
+		{"requests.rejected.forbidden", "Meter"},
+		{"requests.rejected.throttled", "Meter"},
+		{"requests.rejected.other", "Meter"},
+		// End to end:
+		{"requests.end_to_end_duration", "Timer"},
+		{"backend.concurrency_wait", "Timer"},
+		{"backend.max_attempts_override", "Histogram"},
+		// User agents:
+		{"puts.current_url.user_agent.prebid_server", "Meter"},
+		{"puts.current_url.user_agent.browser", "Meter"},
+		{"puts.current_url.user_agent.other", "Meter"},
+		{"gets.current_url.user_agent.prebid_server", "Meter"},
+		{"gets.current_url.user_agent.browser", "Meter"},
+		{"gets.current_url.user_agent.other", "Meter"},
 		// PutsBackend:
 		{"puts.backend.request_duration", "Timer"},
 		{"puts.backend.error_count", "Meter"},
@@ -35,11 +68,22 @@ func TestRegisteredInfluxMetrics(t *testing.T) {
 		{"puts.backend.defines_ttl", "Meter"},
 		{"puts.backend.unknown_request_count", "Meter"},
 		{"puts.backend.request_size_bytes", "Histogram"},
+		{"puts.backend.verification_failed", "Meter"},
+		{"puts.backend.key_collisions", "Meter"},
+		{"puts.backend.oversized", "Meter"},
+		{"puts.backend.timeout_count", "Meter"},
 		// GetsBackend:
 		{"gets.backend.request_duration", "Timer"},
 		{"gets.backend.error_count", "Meter"},
 		{"gets.backend.bad_request_count", "Meter"},
 		{"gets.backend.request_count", "Meter"},
+		{"gets.backend.timeout_count", "Meter"},
+		{"gets.backend.hit", "Meter"},
+		{"gets.backend.miss", "Meter"},
+		{"gets.backend.tier.near.hit", "Meter"},
+		{"gets.backend.tier.near.miss", "Meter"},
+		{"gets.backend.tier.remote.hit", "Meter"},
+		{"gets.backend.tier.remote.miss", "Meter"},
 		// GetsBackErr:
 		{"gets.backend_error.key_not_found", "Meter"},
 		{"gets.backend_error.missing_key", "Meter"},
@@ -47,8 +91,37 @@ func TestRegisteredInfluxMetrics(t *testing.T) {
 		{"connections.active_incoming", "Counter"},
 		{"connections.accept_errors", "Meter"},
 		{"connections.close_errors", "Meter"},
+		{"connections.tls_handshakes", "Timer"},
+		{"connections.tls_handshake_failures.not_tls", "Meter"},
+		{"connections.tls_handshake_failures.timeout", "Meter"},
+		{"connections.tls_handshake_failures.unsupported", "Meter"},
+		{"connections.tls_handshake_failures.bad_certificate", "Meter"},
+		{"connections.tls_handshake_failures.closed", "Meter"},
+		{"connections.tls_handshake_failures.other", "Meter"},
 		// ExtraTTL:
 		{"extra_ttl_seconds", "Histogram"},
+		{"applied_ttl_seconds", "Histogram"},
+		// Replication:
+		{"replication.lag", "Timer"},
+		{"replication.queue_depth", "Gauge"},
+		{"replication.dropped", "Meter"},
+		{"replication.errors", "Meter"},
+		// Circuit breaker:
+		{"backend.circuit_breaker.closed", "Meter"},
+		{"backend.circuit_breaker.open", "Meter"},
+		{"backend.circuit_breaker.half_open", "Meter"},
+		// Batches:
+		{"puts.backend.batch_size", "Histogram"},
+		{"gets.backend.batch_size", "Histogram"},
+		// Memory backend:
+		{"memory.evictions.ttl", "Meter"},
+		{"memory.evictions.lru", "Meter"},
+		{"memory.evictions.manual", "Meter"},
+		{"memory.evicted_value_age", "Timer"},
+		// FDs:
+		{"file_descriptors.open", "Gauge"},
+		{"file_descriptors.limit", "Gauge"},
+		{"file_descriptors.warnings", "Meter"},
 	}
 
 	// Assertions
@@ -66,6 +139,8 @@ func TestRegisteredInfluxMetrics(t *testing.T) {
 			_, correctMetricType = actualMetricObject.(metrics.Counter)
 		case "Histogram":
 			_, correctMetricType = actualMetricObject.(metrics.Histogram)
+		case "Gauge":
+			_, correctMetricType = actualMetricObject.(metrics.Gauge)
 		}
 		assert.True(t, correctMetricType, "Metric %s was expected to be of type %s but it isn't", test.metricName, test.expectedMetricObject)
 	}
@@ -106,6 +181,22 @@ func TestRecordExtraTTLSeconds(t *testing.T) {
 	}
 }
 
+func TestRegisterConnectionPool(t *testing.T) {
+	m := CreateInfluxMetrics()
+	active := 3
+
+	m.RegisterConnectionPool(func() int { return active }, func() int { return 8 }, nil)
+	active = 5
+
+	if gauge, ok := m.Registry.Get("backend.pool.active_connections").(metrics.Gauge); assert.True(t, ok, "Active connections should be registered") {
+		assert.Equal(t, int64(5), gauge.Value(), "The gauge should read the pool when exported")
+	}
+	if gauge, ok := m.Registry.Get("backend.pool.connections").(metrics.Gauge); assert.True(t, ok, "Pool size should be registered") {
+		assert.Equal(t, int64(8), gauge.Value())
+	}
+	assert.Nil(t, m.Registry.Get("backend.pool.pending_acquisitions"), "Stats without a func shouldn't be registered")
+}
+
 func TestDurationRecorders(t *testing.T) {
 	var fiveSeconds time.Duration = time.Second * 5
 
@@ -171,6 +262,141 @@ func TestDurationRecorders(t *testing.T) {
 				},
 			},
 		},
+		{
+			"m.Deletes",
+			[]testCase{
+				{
+					description:    "Five second RecordDeleteDuration",
+					runTest:        func(im *InfluxMetrics) { im.RecordDeleteDuration(fiveSeconds) },
+					metricToAssert: m.Deletes.Duration,
+				},
+				{
+					description:    "record a generic delete error with RecordDeleteError",
+					runTest:        func(im *InfluxMetrics) { im.RecordDeleteError() },
+					metricToAssert: m.Deletes.Errors,
+				},
+				{
+					description:    "record an incoming bad delete request with RecordDeleteBadRequest",
+					runTest:        func(im *InfluxMetrics) { im.RecordDeleteBadRequest() },
+					metricToAssert: m.Deletes.BadRequest,
+				},
+				{
+					description:    "record an incoming non-bad delete request with RecordDeleteTotal",
+					runTest:        func(im *InfluxMetrics) { im.RecordDeleteTotal() },
+					metricToAssert: m.Deletes.Request,
+				},
+			},
+		},
+		{
+			"m.Touches",
+			[]testCase{
+				{
+					description:    "Five second RecordTouchDuration",
+					runTest:        func(im *InfluxMetrics) { im.RecordTouchDuration(fiveSeconds) },
+					metricToAssert: m.Touches.Duration,
+				},
+				{
+					description:    "record a generic touch error with RecordTouchError",
+					runTest:        func(im *InfluxMetrics) { im.RecordTouchError() },
+					metricToAssert: m.Touches.Errors,
+				},
+				{
+					description:    "record an incoming bad touch request with RecordTouchBadRequest",
+					runTest:        func(im *InfluxMetrics) { im.RecordTouchBadRequest() },
+					metricToAssert: m.Touches.BadRequest,
+				},
+				{
+					description:    "record an incoming non-bad touch request with RecordTouchTotal",
+					runTest:        func(im *InfluxMetrics) { im.RecordTouchTotal() },
+					metricToAssert: m.Touches.Request,
+				},
+			},
+		},
+		{
+			"m.EndToEnd",
+			[]testCase{
+				{
+					description:    "Five second RecordEndToEndDuration",
+					runTest:        func(im *InfluxMetrics) { im.RecordEndToEndDuration(fiveSeconds) },
+					metricToAssert: m.EndToEnd,
+				},
+			},
+		},
+		{
+			"m.BackendWait",
+			[]testCase{
+				{
+					description:    "Five second RecordBackendConcurrencyWait",
+					runTest:        func(im *InfluxMetrics) { im.RecordBackendConcurrencyWait(fiveSeconds) },
+					metricToAssert: m.BackendWait,
+				},
+			},
+		},
+		{
+			"m.MaxAttempts",
+			[]testCase{
+				{
+					description:    "record an override of one attempt with RecordMaxAttemptsOverride",
+					runTest:        func(im *InfluxMetrics) { im.RecordMaxAttemptsOverride(1) },
+					metricToAssert: m.MaxAttempts,
+				},
+			},
+		},
+		{
+			"m.PutsQuota",
+			[]testCase{
+				{
+					description:    "record a put request rejected over quota with RecordPutQuotaRejection",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutQuotaRejection() },
+					metricToAssert: m.PutsQuota,
+				},
+			},
+		},
+		{
+			"m.PutsParse",
+			[]testCase{
+				{
+					description:    "record a put request whose body isn't JSON with RecordPutParseError",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutParseError() },
+					metricToAssert: m.PutsParse,
+				},
+			},
+		},
+		{
+			"m.PutsInvalid",
+			[]testCase{
+				{
+					description:    "record an invalid put request with RecordPutValidationError",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutValidationError() },
+					metricToAssert: m.PutsInvalid,
+				},
+			},
+		},
+		{
+			"m.PutsByUA",
+			[]testCase{
+				{
+					description:    "record a put request from Prebid Server with RecordPutUserAgent",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutUserAgent("prebid-server") },
+					metricToAssert: m.PutsByUA.PrebidServer,
+				},
+				{
+					description:    "record a put request of an unknown user agent class with RecordPutUserAgent",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutUserAgent("crawler") },
+					metricToAssert: m.PutsByUA.Other,
+				},
+			},
+		},
+		{
+			"m.GetsByUA",
+			[]testCase{
+				{
+					description:    "record a get request from a browser with RecordGetUserAgent",
+					runTest:        func(im *InfluxMetrics) { im.RecordGetUserAgent("browser") },
+					metricToAssert: m.GetsByUA.Browser,
+				},
+			},
+		},
 		{
 			"m.PutsBackend",
 			[]testCase{
@@ -209,6 +435,26 @@ func TestDurationRecorders(t *testing.T) {
 					runTest:        func(im *InfluxMetrics) { im.RecordPutBackendSize(float64(1)) },
 					metricToAssert: m.PutsBackend.RequestLength,
 				},
+				{
+					description:    "record a put that couldn't be read back with RecordPutVerificationFailure",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutVerificationFailure() },
+					metricToAssert: m.PutsBackend.VerificationFailed,
+				},
+				{
+					description:    "record a generated key that already held a value with RecordPutKeyCollision",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutKeyCollision() },
+					metricToAssert: m.PutsBackend.KeyCollisions,
+				},
+				{
+					description:    "record a value over the max size with RecordPutOversized",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutOversized() },
+					metricToAssert: m.PutsBackend.Oversized,
+				},
+				{
+					description:    "record a put that timed out with RecordPutBackendTimeout",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutBackendTimeout() },
+					metricToAssert: m.PutsBackend.Timeouts,
+				},
 			},
 		},
 		{
@@ -231,6 +477,46 @@ func TestDurationRecorders(t *testing.T) {
 				},
 			},
 		},
+		{
+			"m.GetsResults",
+			[]testCase{
+				{
+					description:    "record a key the backend held a value for with RecordGetBackendKeyHit",
+					runTest:        func(im *InfluxMetrics) { im.RecordGetBackendKeyHit() },
+					metricToAssert: m.GetsResults.Hits,
+				},
+				{
+					description:    "record a key the backend held no value for with RecordGetBackendKeyMiss",
+					runTest:        func(im *InfluxMetrics) { im.RecordGetBackendKeyMiss() },
+					metricToAssert: m.GetsResults.Misses,
+				},
+			},
+		},
+		{
+			"m.NearTier and m.RemoteTier",
+			[]testCase{
+				{
+					description:    "record a hit of the near tier with RecordTierLookup",
+					runTest:        func(im *InfluxMetrics) { im.RecordTierLookup("near", true) },
+					metricToAssert: m.NearTier.Hits,
+				},
+				{
+					description:    "record a miss of the near tier with RecordTierLookup",
+					runTest:        func(im *InfluxMetrics) { im.RecordTierLookup("near", false) },
+					metricToAssert: m.NearTier.Misses,
+				},
+				{
+					description:    "record a hit of the remote tier with RecordTierLookup",
+					runTest:        func(im *InfluxMetrics) { im.RecordTierLookup("remote", true) },
+					metricToAssert: m.RemoteTier.Hits,
+				},
+				{
+					description:    "record a miss of the remote tier with RecordTierLookup",
+					runTest:        func(im *InfluxMetrics) { im.RecordTierLookup("remote", false) },
+					metricToAssert: m.RemoteTier.Misses,
+				},
+			},
+		},
 		{
 			"m.GetsBackErr",
 			[]testCase{
@@ -244,6 +530,21 @@ func TestDurationRecorders(t *testing.T) {
 					runTest:        func(im *InfluxMetrics) { im.RecordMissingKeyError() },
 					metricToAssert: m.GetsErr.MissingKeyErrors,
 				},
+				{
+					description:    "record a stored value that failed its integrity check with RecordCorruptValue",
+					runTest:        func(im *InfluxMetrics) { im.RecordCorruptValue() },
+					metricToAssert: m.GetsErr.CorruptValues,
+				},
+			},
+		},
+		{
+			"m.GetsTimeout",
+			[]testCase{
+				{
+					description:    "record a get that timed out with RecordGetBackendTimeout",
+					runTest:        func(im *InfluxMetrics) { im.RecordGetBackendTimeout() },
+					metricToAssert: m.GetsTimeout,
+				},
 			},
 		},
 		{
@@ -264,6 +565,21 @@ func TestDurationRecorders(t *testing.T) {
 					runTest:        func(im *InfluxMetrics) { im.RecordCloseConnectionErrors() },
 					metricToAssert: m.Connections.ConnectionCloseErrors,
 				},
+				{
+					description:    "Five second RecordTLSHandshake",
+					runTest:        func(im *InfluxMetrics) { im.RecordTLSHandshake(fiveSeconds) },
+					metricToAssert: m.Connections.TLSHandshakes,
+				},
+				{
+					description:    "record a handshake with a client that doesn't speak TLS with RecordTLSHandshakeFailure",
+					runTest:        func(im *InfluxMetrics) { im.RecordTLSHandshakeFailure("not_tls") },
+					metricToAssert: m.Connections.TLSHandshakeFailures["not_tls"],
+				},
+				{
+					description:    "record a handshake failure of an unknown reason as other with RecordTLSHandshakeFailure",
+					runTest:        func(im *InfluxMetrics) { im.RecordTLSHandshakeFailure("cosmic_rays") },
+					metricToAssert: m.Connections.TLSHandshakeFailures["other"],
+				},
 			},
 		},
 		{
@@ -274,6 +590,91 @@ func TestDurationRecorders(t *testing.T) {
 					runTest:        func(im *InfluxMetrics) { im.RecordExtraTTLSeconds(float64(1)) },
 					metricToAssert: m.ExtraTTL.ExtraTTLSeconds,
 				},
+				{
+					description:    "record an applied ttl with RecordAppliedTTLSeconds",
+					runTest:        func(im *InfluxMetrics) { im.RecordAppliedTTLSeconds(float64(1)) },
+					metricToAssert: m.ExtraTTL.AppliedTTLSeconds,
+				},
+			},
+		},
+		{
+			"m.Replication",
+			[]testCase{
+				{
+					description:    "Five second RecordReplicationLag",
+					runTest:        func(im *InfluxMetrics) { im.RecordReplicationLag(fiveSeconds) },
+					metricToAssert: m.Replication.Lag,
+				},
+				{
+					description:    "record one value waiting to be replicated with RecordReplicationQueueDepth",
+					runTest:        func(im *InfluxMetrics) { im.RecordReplicationQueueDepth(1) },
+					metricToAssert: m.Replication.QueueDepth,
+				},
+				{
+					description:    "record a value dropped from the replication queue with RecordReplicationDropped",
+					runTest:        func(im *InfluxMetrics) { im.RecordReplicationDropped() },
+					metricToAssert: m.Replication.Dropped,
+				},
+				{
+					description:    "record a value that failed to be replicated with RecordReplicationError",
+					runTest:        func(im *InfluxMetrics) { im.RecordReplicationError() },
+					metricToAssert: m.Replication.Errors,
+				},
+			},
+		},
+		{
+			"m.Breaker",
+			[]testCase{
+				{
+					description:    "record the breaker closing with RecordCircuitBreakerTransition",
+					runTest:        func(im *InfluxMetrics) { im.RecordCircuitBreakerTransition("closed") },
+					metricToAssert: m.Breaker.Closed,
+				},
+				{
+					description:    "record the breaker opening with RecordCircuitBreakerTransition",
+					runTest:        func(im *InfluxMetrics) { im.RecordCircuitBreakerTransition("open") },
+					metricToAssert: m.Breaker.Open,
+				},
+				{
+					description:    "record the breaker probing the backend with RecordCircuitBreakerTransition",
+					runTest:        func(im *InfluxMetrics) { im.RecordCircuitBreakerTransition("half_open") },
+					metricToAssert: m.Breaker.HalfOpen,
+				},
+			},
+		},
+		{
+			"m.Batches",
+			[]testCase{
+				{
+					description:    "record a batch of one value with RecordPutBackendBatchSize",
+					runTest:        func(im *InfluxMetrics) { im.RecordPutBackendBatchSize(1) },
+					metricToAssert: m.Batches.PutSize,
+				},
+				{
+					description:    "record a batch of one key with RecordGetBackendBatchSize",
+					runTest:        func(im *InfluxMetrics) { im.RecordGetBackendBatchSize(1) },
+					metricToAssert: m.Batches.GetSize,
+				},
+			},
+		},
+		{
+			"m.FDs",
+			[]testCase{
+				{
+					description:    "record one open file descriptor with RecordFileDescriptors",
+					runTest:        func(im *InfluxMetrics) { im.RecordFileDescriptors(1, 1024) },
+					metricToAssert: m.FDs.Open,
+				},
+				{
+					description:    "record a limit of one file descriptor with RecordFileDescriptors",
+					runTest:        func(im *InfluxMetrics) { im.RecordFileDescriptors(0, 1) },
+					metricToAssert: m.FDs.Limit,
+				},
+				{
+					description:    "record a check past the warning threshold with RecordFileDescriptorWarning",
+					runTest:        func(im *InfluxMetrics) { im.RecordFileDescriptorWarning() },
+					metricToAssert: m.FDs.Warnings,
+				},
 			},
 		},
 	}
@@ -291,6 +692,9 @@ func TestDurationRecorders(t *testing.T) {
 			} else if histogram, isHistogram := test.metricToAssert.(metrics.Histogram); isHistogram {
 				assert.Equal(t, int64(1), histogram.Sum(), "Group '%s'. Desc: %s", group.groupDesc, test.description)
 
+			} else if gauge, isGauge := test.metricToAssert.(metrics.Gauge); isGauge {
+				assert.Equal(t, int64(1), gauge.Value(), "Group '%s'. Desc: %s", group.groupDesc, test.description)
+
 			} else if counter, isCounter := test.metricToAssert.(metrics.Counter); isCounter {
 				if strings.HasPrefix(test.description, "Increase") {
 					assert.Equal(t, int64(1), counter.Count(), "Group '%s'. Desc: %s", group.groupDesc, test.description)
@@ -16,11 +16,21 @@ func CreateMockMetrics() *metrics.Metrics {
 	MockHistograms = make(map[string]float64, 6)
 	MockHistograms["puts.current_url.duration"] = 0.00
 	MockHistograms["gets.current_url.duration"] = 0.00
+	MockHistograms["deletes.current_url.duration"] = 0.00
+	MockHistograms["touches.current_url.duration"] = 0.00
 	MockHistograms["puts.backends.request_duration"] = 0.00
 	MockHistograms["puts.backends.request_size_bytes"] = 0.00
 	MockHistograms["gets.backends.duration"] = 0.00
 	MockHistograms["connections.connections_opened"] = 0.00
 	MockHistograms["extra_ttl_seconds"] = 0.00
+	MockHistograms["applied_ttl_seconds"] = 0.00
+	MockHistograms["requests.end_to_end_duration"] = 0.00
+	MockHistograms["replication.lag"] = 0.00
+	MockHistograms["backend.concurrency_wait"] = 0.00
+	MockHistograms["backend.max_attempts_override"] = 0.00
+	MockHistograms["puts.backends.batch_size"] = 0.00
+	MockHistograms["gets.backends.batch_size"] = 0.00
+	MockHistograms["memory.evicted_value_age"] = 0.00
 
 	MockCounters = make(map[string]int64, 16)
 	MockCounters["puts.current_url.request.total"] = 0
@@ -29,6 +39,18 @@ func CreateMockMetrics() *metrics.Metrics {
 	MockCounters["gets.current_url.request.total"] = 0
 	MockCounters["gets.current_url.request.error"] = 0
 	MockCounters["gets.current_url.request.bad_request"] = 0
+	MockCounters["deletes.current_url.request.total"] = 0
+	MockCounters["deletes.current_url.request.error"] = 0
+	MockCounters["deletes.current_url.request.bad_request"] = 0
+	MockCounters["touches.current_url.request.total"] = 0
+	MockCounters["touches.current_url.request.error"] = 0
+	MockCounters["touches.current_url.request.bad_request"] = 0
+	MockCounters["puts.current_url.user_agent.prebid-server"] = 0
+	MockCounters["puts.current_url.user_agent.browser"] = 0
+	MockCounters["puts.current_url.user_agent.other"] = 0
+	MockCounters["gets.current_url.user_agent.prebid-server"] = 0
+	MockCounters["gets.current_url.user_agent.browser"] = 0
+	MockCounters["gets.current_url.user_agent.other"] = 0
 	MockCounters["puts.backends.add"] = 0
 	MockCounters["puts.backends.json"] = 0
 	MockCounters["puts.backends.xml"] = 0
@@ -36,13 +58,41 @@ func CreateMockMetrics() *metrics.Metrics {
 	MockCounters["puts.backends.defines_ttl"] = 0
 	MockCounters["puts.backends.request.error"] = 0
 	MockCounters["puts.backends.request.bad_request"] = 0
+	MockCounters["puts.backends.request.timeout"] = 0
+	MockCounters["puts.backends.verification_failed"] = 0
+	MockCounters["puts.backends.key_collisions"] = 0
+	MockCounters["puts.backends.oversized"] = 0
 	MockCounters["gets.backends.request.total"] = 0
 	MockCounters["gets.backends.request.error"] = 0
 	MockCounters["gets.backends.request.bad_request"] = 0
+	MockCounters["gets.backends.request.timeout"] = 0
 	MockCounters["gets.backend_error.key_not_found"] = 0
 	MockCounters["gets.backend_error.missing_key"] = 0
+	MockCounters["gets.backend_error.corrupt_value"] = 0
+	MockCounters["gets.backend.hit"] = 0
+	MockCounters["gets.backend.miss"] = 0
 	MockCounters["connections.connection_error.accept"] = 0
 	MockCounters["connections.connection_error.close"] = 0
+	MockCounters["connections.tls_handshakes"] = 0
+	MockCounters["requests.end_to_end_duration.count"] = 0
+	MockCounters["backend.concurrency_wait.count"] = 0
+	MockCounters["puts.current_url.quota_rejected"] = 0
+	MockCounters["puts.current_url.json_parse_error"] = 0
+	MockCounters["puts.current_url.validation_error"] = 0
+	MockCounters["requests.rejected.rate_limit"] = 0
+	MockCounters["requests.rejected.quota"] = 0
+	MockCounters["requests.rejected.overload"] = 0
+	MockCounters["requests.rejected.forbidden"] = 0
+	MockCounters["requests.rejected.throttled"] = 0
+	MockCounters["replication.queue_depth"] = 0
+	MockCounters["replication.dropped"] = 0
+	MockCounters["replication.errors"] = 0
+	MockCounters["memory.evictions.ttl"] = 0
+	MockCounters["memory.evictions.lru"] = 0
+	MockCounters["memory.evictions.manual"] = 0
+	MockCounters["file_descriptors.open"] = 0
+	MockCounters["file_descriptors.limit"] = 0
+	MockCounters["file_descriptors.warnings"] = 0
 
 	return &metrics.Metrics{
 		MetricEngines: []metrics.CacheMetrics{
@@ -90,6 +140,52 @@ func (m *MockMetrics) RecordGetTotal() {
 func (m *MockMetrics) RecordGetDuration(duration time.Duration) {
 	MockHistograms["gets.current_url.duration"] = mockDuration.Seconds()
 }
+func (m *MockMetrics) RecordDeleteError() {
+	MockCounters["deletes.current_url.request.error"] = MockCounters["deletes.current_url.request.error"] + 1
+}
+func (m *MockMetrics) RecordDeleteBadRequest() {
+	MockCounters["deletes.current_url.request.bad_request"] = MockCounters["deletes.current_url.request.bad_request"] + 1
+}
+func (m *MockMetrics) RecordDeleteTotal() {
+	MockCounters["deletes.current_url.request.total"] = MockCounters["deletes.current_url.request.total"] + 1
+}
+func (m *MockMetrics) RecordDeleteDuration(duration time.Duration) {
+	MockHistograms["deletes.current_url.duration"] = mockDuration.Seconds()
+}
+func (m *MockMetrics) RecordTouchError() {
+	MockCounters["touches.current_url.request.error"] = MockCounters["touches.current_url.request.error"] + 1
+}
+func (m *MockMetrics) RecordTouchBadRequest() {
+	MockCounters["touches.current_url.request.bad_request"] = MockCounters["touches.current_url.request.bad_request"] + 1
+}
+func (m *MockMetrics) RecordTouchTotal() {
+	MockCounters["touches.current_url.request.total"] = MockCounters["touches.current_url.request.total"] + 1
+}
+func (m *MockMetrics) RecordTouchDuration(duration time.Duration) {
+	MockHistograms["touches.current_url.duration"] = mockDuration.Seconds()
+}
+func (m *MockMetrics) RecordEndToEndDuration(duration time.Duration) {
+	MockHistograms["requests.end_to_end_duration"] = mockDuration.Seconds()
+	MockCounters["requests.end_to_end_duration.count"] = MockCounters["requests.end_to_end_duration.count"] + 1
+}
+func (m *MockMetrics) RecordPutParseError() {
+	MockCounters["puts.current_url.json_parse_error"] = MockCounters["puts.current_url.json_parse_error"] + 1
+}
+func (m *MockMetrics) RecordPutValidationError() {
+	MockCounters["puts.current_url.validation_error"] = MockCounters["puts.current_url.validation_error"] + 1
+}
+func (m *MockMetrics) RecordPutQuotaRejection() {
+	MockCounters["puts.current_url.quota_rejected"] = MockCounters["puts.current_url.quota_rejected"] + 1
+}
+func (m *MockMetrics) RecordRejectedRequest(reason string) {
+	MockCounters["requests.rejected."+reason] = MockCounters["requests.rejected."+reason] + 1
+}
+func (m *MockMetrics) RecordPutUserAgent(class string) {
+	MockCounters["puts.current_url.user_agent."+class] = MockCounters["puts.current_url.user_agent."+class] + 1
+}
+func (m *MockMetrics) RecordGetUserAgent(class string) {
+	MockCounters["gets.current_url.user_agent."+class] = MockCounters["gets.current_url.user_agent."+class] + 1
+}
 func (m *MockMetrics) RecordPutBackendXml() {
 	MockCounters["puts.backends.xml"] = MockCounters["puts.backends.xml"] + 1
 }
@@ -108,6 +204,18 @@ func (m *MockMetrics) RecordPutBackendDuration(duration time.Duration) {
 func (m *MockMetrics) RecordPutBackendError() {
 	MockCounters["puts.backends.request.error"] = MockCounters["puts.backends.request.error"] + 1
 }
+func (m *MockMetrics) RecordPutBackendTimeout() {
+	MockCounters["puts.backends.request.timeout"] = MockCounters["puts.backends.request.timeout"] + 1
+}
+func (m *MockMetrics) RecordPutVerificationFailure() {
+	MockCounters["puts.backends.verification_failed"] = MockCounters["puts.backends.verification_failed"] + 1
+}
+func (m *MockMetrics) RecordPutKeyCollision() {
+	MockCounters["puts.backends.key_collisions"] = MockCounters["puts.backends.key_collisions"] + 1
+}
+func (m *MockMetrics) RecordPutOversized() {
+	MockCounters["puts.backends.oversized"] = MockCounters["puts.backends.oversized"] + 1
+}
 func (m *MockMetrics) RecordPutBackendSize(sizeInBytes float64) {
 	MockHistograms["puts.backends.request_size_bytes"] = sizeInBytes
 }
@@ -120,12 +228,31 @@ func (m *MockMetrics) RecordGetBackendTotal() {
 func (m *MockMetrics) RecordGetBackendError() {
 	MockCounters["gets.backends.request.error"] = MockCounters["gets.backends.request.error"] + 1
 }
+func (m *MockMetrics) RecordGetBackendTimeout() {
+	MockCounters["gets.backends.request.timeout"] = MockCounters["gets.backends.request.timeout"] + 1
+}
 func (m *MockMetrics) RecordKeyNotFoundError() {
 	MockCounters["gets.backend_error.key_not_found"] = MockCounters["gets.backend_error.key_not_found"] + 1
 }
 func (m *MockMetrics) RecordMissingKeyError() {
 	MockCounters["gets.backend_error.missing_key"] = MockCounters["gets.backend_error.missing_key"] + 1
 }
+func (m *MockMetrics) RecordCorruptValue() {
+	MockCounters["gets.backend_error.corrupt_value"] = MockCounters["gets.backend_error.corrupt_value"] + 1
+}
+func (m *MockMetrics) RecordGetBackendKeyHit() {
+	MockCounters["gets.backend.hit"] = MockCounters["gets.backend.hit"] + 1
+}
+func (m *MockMetrics) RecordGetBackendKeyMiss() {
+	MockCounters["gets.backend.miss"] = MockCounters["gets.backend.miss"] + 1
+}
+func (m *MockMetrics) RecordTierLookup(tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	MockCounters["gets.backends.tier."+tier+"."+result] = MockCounters["gets.backends.tier."+tier+"."+result] + 1
+}
 func (m *MockMetrics) RecordConnectionOpen() {
 	MockHistograms["connections.connections_opened"] = MockHistograms["connections.connections_opened"] + 1
 }
@@ -138,6 +265,61 @@ func (m *MockMetrics) RecordCloseConnectionErrors() {
 func (m *MockMetrics) RecordAcceptConnectionErrors() {
 	MockCounters["connections.connection_error.accept"] = MockCounters["connections.connection_error.accept"] + 1
 }
+func (m *MockMetrics) RecordTLSHandshake(duration time.Duration) {
+	MockCounters["connections.tls_handshakes"] = MockCounters["connections.tls_handshakes"] + 1
+}
+func (m *MockMetrics) RecordTLSHandshakeFailure(reason string) {
+	MockCounters["connections.tls_handshake_failures."+reason] = MockCounters["connections.tls_handshake_failures."+reason] + 1
+}
 func (m *MockMetrics) RecordExtraTTLSeconds(value float64) {
 	MockHistograms["extra_ttl_seconds"] = value
 }
+func (m *MockMetrics) RecordAppliedTTLSeconds(value float64) {
+	MockHistograms["applied_ttl_seconds"] = value
+}
+func (m *MockMetrics) RecordReplicationLag(duration time.Duration) {
+	MockHistograms["replication.lag"] = mockDuration.Seconds()
+}
+func (m *MockMetrics) RecordMaxAttemptsOverride(attempts int) {
+	MockHistograms["backend.max_attempts_override"] = float64(attempts)
+}
+func (m *MockMetrics) RecordBackendConcurrencyWait(duration time.Duration) {
+	MockHistograms["backend.concurrency_wait"] = mockDuration.Seconds()
+	MockCounters["backend.concurrency_wait.count"] = MockCounters["backend.concurrency_wait.count"] + 1
+}
+func (m *MockMetrics) RecordReplicationQueueDepth(depth int) {
+	MockCounters["replication.queue_depth"] = int64(depth)
+}
+func (m *MockMetrics) RecordReplicationDropped() {
+	MockCounters["replication.dropped"] = MockCounters["replication.dropped"] + 1
+}
+func (m *MockMetrics) RecordReplicationError() {
+	MockCounters["replication.errors"] = MockCounters["replication.errors"] + 1
+}
+func (m *MockMetrics) RecordCircuitBreakerTransition(state string) {
+	MockCounters["backend.circuit_breaker."+state] = MockCounters["backend.circuit_breaker."+state] + 1
+}
+func (m *MockMetrics) RecordPutBackendBatchSize(size int) {
+	MockHistograms["puts.backends.batch_size"] = float64(size)
+}
+func (m *MockMetrics) RecordGetBackendBatchSize(size int) {
+	MockHistograms["gets.backends.batch_size"] = float64(size)
+}
+func (m *MockMetrics) RecordMemoryEviction(reason string, age time.Duration) {
+	MockCounters["memory.evictions."+reason] = MockCounters["memory.evictions."+reason] + 1
+	MockHistograms["memory.evicted_value_age"] = age.Seconds()
+}
+func (m *MockMetrics) RecordFileDescriptors(open int, limit int) {
+	MockCounters["file_descriptors.open"] = int64(open)
+	MockCounters["file_descriptors.limit"] = int64(limit)
+}
+func (m *MockMetrics) RegisterConnectionPool(active, size, pending func() int) {
+	for name, read := range map[string]func() int{"backend.pool.active_connections": active, "backend.pool.connections": size, "backend.pool.pending_acquisitions": pending} {
+		if read != nil {
+			MockCounters[name] = int64(read())
+		}
+	}
+}
+func (m *MockMetrics) RecordFileDescriptorWarning() {
+	MockCounters["file_descriptors.warnings"] = MockCounters["file_descriptors.warnings"] + 1
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelAllowLists holds the only values each allow-listed label can be recorded with. Any other value
+// of those labels gets recorded as OtherVal, so new inputs can't grow the number of series.
+type labelAllowLists map[string]map[string]bool
+
+func newLabelAllowLists(cfg map[string][]string) labelAllowLists {
+	allowLists := make(labelAllowLists, len(cfg))
+	for label, values := range cfg {
+		allowed := make(map[string]bool, len(values)+1)
+		for _, value := range values {
+			allowed[value] = true
+		}
+		allowed[OtherVal] = true
+		allowLists[label] = allowed
+	}
+	return allowLists
+}
+
+// apply returns labels with the values left out of their allow-list replaced by OtherVal
+func (l labelAllowLists) apply(labels prometheus.Labels) prometheus.Labels {
+	if len(l) == 0 {
+		return labels
+	}
+	applied := make(prometheus.Labels, len(labels))
+	for label, value := range labels {
+		applied[label] = l.value(label, value)
+	}
+	return applied
+}
+
+func (l labelAllowLists) value(label string, value string) string {
+	allowed, listed := l[label]
+	if !listed || allowed[value] {
+		return value
+	}
+	return OtherVal
+}
+
+// applyToValues returns the values every label of labelsWithValues can still be recorded with
+func (l labelAllowLists) applyToValues(labelsWithValues map[string][]string) map[string][]string {
+	if len(l) == 0 {
+		return labelsWithValues
+	}
+	applied := make(map[string][]string, len(labelsWithValues))
+	for label, values := range labelsWithValues {
+		seen := make(map[string]bool, len(values))
+		for _, value := range values {
+			value = l.value(label, value)
+			if !seen[value] {
+				seen[value] = true
+				applied[label] = append(applied[label], value)
+			}
+		}
+	}
+	return applied
+}
@@ -4,13 +4,34 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func preloadLabelValues(m *PrometheusMetrics) {
-	preloadLabelValuesForCounter(m.Puts.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, TotalsVal}})
-	preloadLabelValuesForCounter(m.Gets.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, TotalsVal}})
-	preloadLabelValuesForCounter(m.PutsBackend.PutBackendRequests, map[string][]string{FormatKey: {XmlVal, JsonVal, InvFormatVal, DefinesTTLVal, ErrorVal}})
-	preloadLabelValuesForCounter(m.GetsBackend.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, TotalsVal}})
-	preloadLabelValuesForCounter(m.GetsBackend.ErrorsByType, map[string][]string{TypeKey: {KeyNotFoundVal, MissingKeyVal}})
-	preloadLabelValuesForCounter(m.Connections.ConnectionsErrors, map[string][]string{ConnErrorKey: {CloseVal, AcceptVal}})
+// preloadLabelValues creates the series of every value the labels can be recorded with, so that they
+// show up before being first recorded
+func preloadLabelValues(m *PrometheusCollectors, allowLists labelAllowLists, backend string) {
+	preload := func(counter *prometheus.CounterVec, labelsWithValues map[string][]string) {
+		preloadLabelValuesForCounter(counter, allowLists.applyToValues(labelsWithValues))
+	}
+	preloadHistogram := func(histogram *prometheus.HistogramVec, labelsWithValues map[string][]string) {
+		preloadLabelValuesForHistogram(histogram, allowLists.applyToValues(labelsWithValues))
+	}
+	preload(m.Puts.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, ParseErrorVal, InvalidVal, TotalsVal}})
+	preload(m.Gets.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, TotalsVal}})
+	preload(m.Deletes.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, TotalsVal}})
+	preload(m.Touches.RequestStatus, map[string][]string{StatusKey: {ErrorVal, BadRequestVal, TotalsVal}})
+	preload(m.Rejections, map[string][]string{ReasonKey: rejectionReasonVals})
+	preload(m.Puts.ByUserAgent, map[string][]string{UserAgentKey: userAgentVals})
+	preload(m.Gets.ByUserAgent, map[string][]string{UserAgentKey: userAgentVals})
+	preload(m.PutsBackend.PutBackendRequests, map[string][]string{FormatKey: putBackendFormatVals, BackendKey: {backend}})
+	preload(m.PutsBackend.RequestStatus, map[string][]string{StatusKey: {TimeoutVal}, BackendKey: {backend}})
+	preload(m.GetsBackend.RequestStatus, map[string][]string{StatusKey: {ErrorVal, TimeoutVal, BadRequestVal, TotalsVal}, BackendKey: {backend}})
+	preload(m.GetsBackend.ErrorsByType, map[string][]string{TypeKey: {KeyNotFoundVal, MissingKeyVal, CorruptVal}})
+	preload(m.GetsBackend.Results, map[string][]string{ResultKey: {HitVal, MissVal}})
+	preload(m.GetsBackend.TierResults, map[string][]string{TierKey: {NearVal, RemoteVal}, ResultKey: {HitVal, MissVal}})
+	preload(m.Connections.ConnectionsErrors, map[string][]string{ConnErrorKey: {CloseVal, AcceptVal}})
+	preload(m.Connections.TLSHandshakeFailures, map[string][]string{ReasonKey: tlsFailureReasonVals})
+	preload(m.Memory.Evictions, map[string][]string{ReasonKey: evictionReasonVals})
+	preload(m.Breaker, map[string][]string{StateKey: breakerStateVals})
+	preloadHistogram(m.PutsBackend.Duration, map[string][]string{BackendKey: {backend}})
+	preloadHistogram(m.GetsBackend.Duration, map[string][]string{BackendKey: {backend}})
 }
 
 func preloadLabelValuesForCounter(counter *prometheus.CounterVec, labelsWithValues map[string][]string) {
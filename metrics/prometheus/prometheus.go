@@ -2,10 +2,13 @@ package metrics
 
 import (
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/prebid/prebid-cache/config"
 	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -14,76 +17,265 @@ const (
 	FormatKey    string = "format"
 	ConnErrorKey string = "connection_error"
 	TypeKey      string = "type"
+	UserAgentKey string = "user_agent"
+	ReasonKey    string = "reason"
+	ResultKey    string = "result"
+	BackendKey   string = "backend"
+	StateKey     string = "state"
+	TierKey      string = "tier"
 
 	// Label values
-	TotalsVal      string = "total"
-	ErrorVal       string = "error"
-	KeyNotFoundVal string = "key_not_found"
-	MissingKeyVal  string = "missing_key"
-	BadRequestVal  string = "bad_request"
-	JsonVal        string = "json"
-	XmlVal         string = "xml"
-	DefinesTTLVal  string = "defines_ttl"
-	InvFormatVal   string = "invalid_format"
-	CloseVal       string = "close"
-	AcceptVal      string = "accept"
+	TotalsVal       string = "total"
+	ErrorVal        string = "error"
+	KeyNotFoundVal  string = "key_not_found"
+	MissingKeyVal   string = "missing_key"
+	CorruptVal      string = "corrupt_value"
+	BadRequestVal   string = "bad_request"
+	ParseErrorVal   string = "json_parse_error"
+	InvalidVal      string = "validation_error"
+	JsonVal         string = "json"
+	XmlVal          string = "xml"
+	DefinesTTLVal   string = "defines_ttl"
+	InvFormatVal    string = "invalid_format"
+	CloseVal        string = "close"
+	AcceptVal       string = "accept"
+	PrebidServerVal string = "prebid-server"
+	BrowserVal      string = "browser"
+	OtherVal        string = "other"
+	TTLVal          string = "ttl"
+	LRUVal          string = "lru"
+	ManualVal       string = "manual"
+	NotTLSVal       string = "not_tls"
+	TimeoutVal      string = "timeout"
+	UnsupportedVal  string = "unsupported"
+	BadCertVal      string = "bad_certificate"
+	ClientClosedVal string = "closed"
+	RateLimitVal    string = "rate_limit"
+	QuotaVal        string = "quota"
+	OverloadVal     string = "overload"
+	ForbiddenVal    string = "forbidden"
+	ThrottledVal    string = "throttled"
+	HitVal          string = "hit"
+	MissVal         string = "miss"
+	BrkClosedVal    string = "closed"
+	BrkOpenVal      string = "open"
+	BrkHalfOpenVal  string = "half_open"
+	NearVal         string = "near"
+	RemoteVal       string = "remote"
 
 	// Metric names
 	PutRequestMet  string = "puts_request"
 	PutReqDurMet   string = "puts_request_duration"
 	GetRequestMet  string = "gets_request"
 	GetReqDurMet   string = "gets_request_duration"
+	DelRequestMet  string = "deletes_request"
+	DelReqDurMet   string = "deletes_request_duration"
+	TchRequestMet  string = "touches_request"
+	TchReqDurMet   string = "touches_request_duration"
+	PutReqByUAMet  string = "puts_request_by_user_agent"
+	GetReqByUAMet  string = "gets_request_by_user_agent"
+	EndToEndDurMet string = "request_end_to_end_duration"
+	PutQuotaMet    string = "puts_quota_rejected"
+	RejectedMet    string = "requests_rejected"
 	PutBackendMet  string = "puts_backend"
+	PutBackStatMet string = "puts_backend_status"
 	PutBackDurMet  string = "puts_backend_duration"
 	PutBackSizeMet string = "puts_backend_request_size_bytes"
+	PutVerifyMet   string = "puts_backend_verification_failed"
+	PutCollideMet  string = "puts_backend_key_collisions"
+	PutOversizeMet string = "puts_backend_oversized"
 	GetBackendMet  string = "gets_backend"
 	GetBackendErr  string = "gets_backend_error"
 	GetBackDurMet  string = "gets_backend_duration"
+	GetResultMet   string = "gets_backend_results"
+	GetTierMet     string = "gets_backend_tier_results"
 	ConnOpenedMet  string = "connection_opened"
 	ConnClosedMet  string = "connection_closed"
+	TLSHandMet     string = "tls_handshakes"
+	TLSHandFailMet string = "tls_handshake_failures"
+	TLSHandDurMet  string = "tls_handshake_duration"
 	ExtraTTLMet    string = "extra_ttl_seconds"
+	AppliedTTLMet  string = "applied_ttl_seconds"
+	ReplLagMet     string = "replication_lag"
+	BackWaitMet    string = "backend_concurrency_wait"
+	MaxAttemptsMet string = "backend_max_attempts_override"
+	ReplQueueMet   string = "replication_queue_depth"
+	ReplDropMet    string = "replication_dropped"
+	ReplErrMet     string = "replication_errors"
+	BreakerMet     string = "backend_circuit_breaker_transitions"
+	PutBatchMet    string = "puts_backend_batch_size"
+	GetBatchMet    string = "gets_backend_batch_size"
+	MemEvictMet    string = "memory_evictions"
+	MemEvictAgeMet string = "memory_evicted_value_age_seconds"
+	FDOpenMet      string = "file_descriptors_open"
+	FDLimitMet     string = "file_descriptors_limit"
+	FDWarnMet      string = "file_descriptors_warnings"
+	PoolActiveMet  string = "backend_pool_active_connections"
+	PoolSizeMet    string = "backend_pool_connections"
+	PoolPendMet    string = "backend_pool_pending_acquisitions"
 
 	MetricsPrometheus = "Prometheus"
 )
 
+// putBackendFormatVals are the only values the FormatKey label of the put backend counter can take.
+// Keeping the set closed keeps the cardinality of the counter from drifting.
+var putBackendFormatVals = []string{XmlVal, JsonVal, InvFormatVal, DefinesTTLVal, ErrorVal}
+
+// userAgentVals are the buckets requests get classified in by their user agent
+var userAgentVals = []string{PrebidServerVal, BrowserVal, OtherVal}
+
+// evictionReasonVals are the reasons the memory backend evicts values for
+var evictionReasonVals = []string{TTLVal, LRUVal, ManualVal}
+
+// rejectionReasonVals are the reasons requests get rejected for before reaching the backend
+var rejectionReasonVals = []string{RateLimitVal, QuotaVal, OverloadVal, ForbiddenVal, ThrottledVal, OtherVal}
+
+// breakerStateVals are the states the backend circuit breaker transitions to
+var breakerStateVals = []string{BrkClosedVal, BrkOpenVal, BrkHalfOpenVal}
+
+// tlsFailureReasonVals are the classes TLS handshake failures are counted under
+var tlsFailureReasonVals = []string{NotTLSVal, TimeoutVal, UnsupportedVal, BadCertVal, ClientClosedVal, OtherVal}
+
 type PrometheusMetrics struct {
+	*PrometheusCollectors
+	MetricsName string
+
+	// mu guards PrometheusCollectors, which Reset replaces
+	mu                    sync.RWMutex
+	cfg                   config.PrometheusMetrics
+	allowLists            labelAllowLists
+	backend               string
+	getDurationSampleRate float64
+	putDurationSampleRate float64
+	randFloat             func() float64
+	// pool reads the connection pool of the backend into gauges, which Reset registers again
+	pool connectionPool
+}
+
+// connectionPool reads the stats of the backend connection pool. The stats the backend can't report
+// are nil.
+type connectionPool struct {
+	active  func() int
+	size    func() int
+	pending func() int
+}
+
+// PrometheusCollectors are the metrics recorded since the engine was created or last reset, along with
+// the registry they're gathered from
+type PrometheusCollectors struct {
 	Registry    *prometheus.Registry
 	Puts        *PrometheusRequestStatusMetric
 	Gets        *PrometheusRequestStatusMetric
+	Deletes     *PrometheusRequestStatusMetric
+	Touches     *PrometheusRequestStatusMetric
+	EndToEnd    prometheus.Histogram
+	Rejections  *prometheus.CounterVec
 	PutsBackend *PrometheusRequestStatusMetricByFormat
-	GetsBackend *PrometheusRequestStatusMetric
+	GetsBackend *PrometheusBackendGetMetric
 	Connections *PrometheusConnectionMetrics
 	ExtraTTL    *PrometheusExtraTTLMetrics
-	MetricsName string
+	Replication *PrometheusReplicationMetrics
+	BackendWait prometheus.Histogram
+	MaxAttempts prometheus.Histogram
+	Breaker     *prometheus.CounterVec
+	Batches     *PrometheusBatchMetrics
+	Memory      *PrometheusMemoryMetrics
+	FDs         *PrometheusFileDescriptorMetrics
 }
 
 type PrometheusRequestStatusMetric struct {
-	Duration      prometheus.Histogram
-	RequestStatus *prometheus.CounterVec
-	ErrorsByType  *prometheus.CounterVec
+	Duration        prometheus.Histogram
+	RequestStatus   *prometheus.CounterVec
+	ErrorsByType    *prometheus.CounterVec
+	ByUserAgent     *prometheus.CounterVec
+	QuotaRejections prometheus.Counter
 }
 
+// PrometheusRequestStatusMetricByFormat and PrometheusBackendGetMetric label their durations and requests
+// with the type of the configured backend, so that stores can be compared across instances
 type PrometheusRequestStatusMetricByFormat struct {
-	Duration           prometheus.Histogram
-	PutBackendRequests *prometheus.CounterVec
-	RequestLength      prometheus.Histogram
+	Duration             *prometheus.HistogramVec
+	PutBackendRequests   *prometheus.CounterVec
+	RequestStatus        *prometheus.CounterVec
+	RequestLength        prometheus.Histogram
+	VerificationFailures prometheus.Counter
+	KeyCollisions        prometheus.Counter
+	Oversized            prometheus.Counter
+}
+
+type PrometheusBackendGetMetric struct {
+	Duration      *prometheus.HistogramVec
+	RequestStatus *prometheus.CounterVec
+	ErrorsByType  *prometheus.CounterVec
+	Results       *prometheus.CounterVec
+	TierResults   *prometheus.CounterVec
 }
 
 type PrometheusConnectionMetrics struct {
-	ConnectionsErrors *prometheus.CounterVec
-	ConnectionsClosed prometheus.Counter
-	ConnectionsOpened prometheus.Counter
+	ConnectionsErrors    *prometheus.CounterVec
+	ConnectionsClosed    prometheus.Counter
+	ConnectionsOpened    prometheus.Counter
+	TLSHandshakes        prometheus.Counter
+	TLSHandshakeFailures *prometheus.CounterVec
+	TLSHandshakeDuration prometheus.Histogram
 }
 
 type PrometheusExtraTTLMetrics struct {
-	ExtraTTLSeconds prometheus.Histogram
+	ExtraTTLSeconds   prometheus.Histogram
+	AppliedTTLSeconds prometheus.Histogram
+}
+
+type PrometheusReplicationMetrics struct {
+	Lag        prometheus.Histogram
+	QueueDepth prometheus.Gauge
+	Dropped    prometheus.Counter
+	Errors     prometheus.Counter
+}
+
+type PrometheusBatchMetrics struct {
+	PutSize prometheus.Histogram
+	GetSize prometheus.Histogram
+}
+
+type PrometheusMemoryMetrics struct {
+	Evictions  *prometheus.CounterVec
+	EvictedAge prometheus.Histogram
+}
+
+type PrometheusFileDescriptorMetrics struct {
+	Open     prometheus.Gauge
+	Limit    prometheus.Gauge
+	Warnings prometheus.Counter
+}
+
+// CreatePrometheusMetrics labels the backend metrics with backendType, the type of the configured backend
+func CreatePrometheusMetrics(cfg config.PrometheusMetrics, backendType config.BackendType) *PrometheusMetrics {
+	allowLists := newLabelAllowLists(cfg.LabelAllowLists)
+	return &PrometheusMetrics{
+		PrometheusCollectors:  newPrometheusCollectors(cfg, allowLists, string(backendType)),
+		MetricsName:           MetricsPrometheus,
+		cfg:                   cfg,
+		allowLists:            allowLists,
+		backend:               string(backendType),
+		getDurationSampleRate: cfg.GetDurationSampleRate,
+		putDurationSampleRate: cfg.PutDurationSampleRate,
+		randFloat:             rand.Float64,
+	}
 }
 
-func CreatePrometheusMetrics(cfg config.PrometheusMetrics) *PrometheusMetrics {
+func newPrometheusCollectors(cfg config.PrometheusMetrics, allowLists labelAllowLists, backend string) *PrometheusCollectors {
 	timeBuckets := []float64{0.001, 0.002, 0.005, 0.01, 0.025, 0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 1}
 	requestSizeBuckets := []float64{0, 4096, 8192, 16384, 32768, 65536, 131072, 262144, 524288, 1048576}
+	// Replication lag includes the time values wait in the queue and any retries, so it runs longer
+	lagBuckets := []float64{0.001, 0.01, 0.1, 0.5, 1, 2, 5, 10, 30, 60}
+	// Values are evicted anywhere from seconds to days after being stored
+	ageBuckets := []float64{1, 10, 60, 300, 900, 1800, 3600, 7200, 21600, 86400}
+	batchBuckets := []float64{1, 2, 5, 10, 20, 50, 100, 200, 500}
+	// Values live anywhere from a minute to a few days
+	ttlBuckets := []float64{60, 300, 600, 1800, 3600, 7200, 21600, 86400, 259200}
+	attemptBuckets := []float64{1, 2, 3, 4, 5, 6, 8, 10, 15, 20}
 	registry := prometheus.NewRegistry()
-	promMetrics := &PrometheusMetrics{
+	collectors := &PrometheusCollectors{
 		Registry: registry,
 		Puts: &PrometheusRequestStatusMetric{
 			Duration: newHistogram(cfg, registry,
@@ -96,6 +288,12 @@ func CreatePrometheusMetrics(cfg config.PrometheusMetrics) *PrometheusMetrics {
 				"Count of total requests to Prebid Server labeled by status.",
 				[]string{StatusKey},
 			),
+			ByUserAgent: newCounterVecWithLabels(cfg, registry,
+				PutReqByUAMet,
+				"Count of put requests labeled by the class of their user agent.",
+				[]string{UserAgentKey},
+			),
+			QuotaRejections: newSingleCounter(cfg, registry, PutQuotaMet, "Count of put requests rejected because their API key ran out of quota."),
 		},
 		Gets: &PrometheusRequestStatusMetric{
 			Duration: newHistogram(cfg, registry,
@@ -108,40 +306,104 @@ func CreatePrometheusMetrics(cfg config.PrometheusMetrics) *PrometheusMetrics {
 				"Count of total get requests to Prebid Server labeled by status.",
 				[]string{StatusKey},
 			),
+			ByUserAgent: newCounterVecWithLabels(cfg, registry,
+				GetReqByUAMet,
+				"Count of get requests labeled by the class of their user agent.",
+				[]string{UserAgentKey},
+			),
 		},
-		PutsBackend: &PrometheusRequestStatusMetricByFormat{
+		Deletes: &PrometheusRequestStatusMetric{
+			Duration: newHistogram(cfg, registry,
+				DelReqDurMet,
+				"Duration in seconds Prebid Cache takes to process delete requests.",
+				timeBuckets,
+			),
+			RequestStatus: newCounterVecWithLabels(cfg, registry,
+				DelRequestMet,
+				"Count of total delete requests to Prebid Cache labeled by status.",
+				[]string{StatusKey},
+			),
+		},
+		Touches: &PrometheusRequestStatusMetric{
 			Duration: newHistogram(cfg, registry,
+				TchReqDurMet,
+				"Duration in seconds Prebid Cache takes to process touch requests.",
+				timeBuckets,
+			),
+			RequestStatus: newCounterVecWithLabels(cfg, registry,
+				TchRequestMet,
+				"Count of total touch requests to Prebid Cache labeled by status.",
+				[]string{StatusKey},
+			),
+		},
+		EndToEnd: newHistogram(cfg, registry,
+			EndToEndDurMet,
+			"Duration in seconds from the moment Prebid Cache accepts a request until it responds, including any queueing and backend retries.",
+			timeBuckets,
+		),
+		BackendWait: newHistogram(cfg, registry,
+			BackWaitMet,
+			"Duration in seconds backend operations wait for one of the operations in flight to finish.",
+			timeBuckets,
+		),
+		Rejections: newCounterVecWithLabels(cfg, registry,
+			RejectedMet,
+			"Count of requests rejected before reaching the backend labeled by the reason they were rejected for.",
+			[]string{ReasonKey},
+		),
+		PutsBackend: &PrometheusRequestStatusMetricByFormat{
+			Duration: newHistogramVector(cfg, registry,
 				PutBackDurMet,
-				"Duration in seconds Prebid Cache takes to process backend put requests.",
+				"Duration in seconds Prebid Cache takes to process backend put requests labeled by backend type.",
+				[]string{BackendKey},
 				timeBuckets,
 			),
 			PutBackendRequests: newCounterVecWithLabels(cfg, registry,
 				PutBackendMet,
-				"Count of total requests to Prebid Cache labeled by format, status and whether or not it comes with TTL",
-				[]string{FormatKey},
+				"Count of total requests to Prebid Cache labeled by format, status, whether or not it comes with TTL and backend type",
+				[]string{FormatKey, BackendKey},
+			),
+			RequestStatus: newCounterVecWithLabels(cfg, registry,
+				PutBackStatMet,
+				"Count of backend put requests that timed out labeled by status and backend type.",
+				[]string{StatusKey, BackendKey},
 			),
 			RequestLength: newHistogram(cfg, registry,
 				PutBackSizeMet,
 				"Size in bytes of a backend put request.",
 				requestSizeBuckets,
 			),
+			VerificationFailures: newSingleCounter(cfg, registry, PutVerifyMet, "Count of backend puts whose value couldn't be read back as it was written."),
+			KeyCollisions:        newSingleCounter(cfg, registry, PutCollideMet, "Count of backend puts whose generated key already held a value."),
+			Oversized:            newSingleCounter(cfg, registry, PutOversizeMet, "Count of values rejected before reaching the backend for exceeding the max size."),
 		},
-		GetsBackend: &PrometheusRequestStatusMetric{
-			Duration: newHistogram(cfg, registry,
+		GetsBackend: &PrometheusBackendGetMetric{
+			Duration: newHistogramVector(cfg, registry,
 				GetBackDurMet,
-				"Duration in seconds Prebid Cache takes to process backend get requests.",
+				"Duration in seconds Prebid Cache takes to process backend get requests labeled by backend type.",
+				[]string{BackendKey},
 				timeBuckets,
 			),
 			RequestStatus: newCounterVecWithLabels(cfg, registry,
 				GetBackendMet,
-				"Count of total backend get requests to Prebid Server labeled by status.",
-				[]string{StatusKey},
+				"Count of total backend get requests to Prebid Server labeled by status and backend type.",
+				[]string{StatusKey, BackendKey},
 			),
 			ErrorsByType: newCounterVecWithLabels(cfg, registry,
 				GetBackendErr,
 				"Account for the most frequent type of get errors in the backend",
 				[]string{TypeKey},
 			),
+			Results: newCounterVecWithLabels(cfg, registry,
+				GetResultMet,
+				"Count of GET /cache lookups labeled by whether the backend held a value for the key.",
+				[]string{ResultKey},
+			),
+			TierResults: newCounterVecWithLabels(cfg, registry,
+				GetTierMet,
+				"Count of lookups in each tier of a tiered backend labeled by tier and by whether it held a value for the key.",
+				[]string{TierKey, ResultKey},
+			),
 		},
 		Connections: &PrometheusConnectionMetrics{
 			ConnectionsClosed: newSingleCounter(cfg, registry, ConnClosedMet, "Count the number of closed connections"),
@@ -151,6 +413,17 @@ func CreatePrometheusMetrics(cfg config.PrometheusMetrics) *PrometheusMetrics {
 				"Count the number of connection accept errors or connection close errors",
 				[]string{ConnErrorKey},
 			),
+			TLSHandshakes: newSingleCounter(cfg, registry, TLSHandMet, "Count of successful TLS handshakes."),
+			TLSHandshakeFailures: newCounterVecWithLabels(cfg, registry,
+				TLSHandFailMet,
+				"Count of failed TLS handshakes labeled by the class of their failure.",
+				[]string{ReasonKey},
+			),
+			TLSHandshakeDuration: newHistogram(cfg, registry,
+				TLSHandDurMet,
+				"Duration in seconds of successful TLS handshakes.",
+				timeBuckets,
+			),
 		},
 		ExtraTTL: &PrometheusExtraTTLMetrics{
 			ExtraTTLSeconds: newHistogram(cfg, registry,
@@ -158,20 +431,73 @@ func CreatePrometheusMetrics(cfg config.PrometheusMetrics) *PrometheusMetrics {
 				"Extra time to live in seconds specified",
 				timeBuckets,
 			),
+			AppliedTTLSeconds: newHistogram(cfg, registry,
+				AppliedTTLMet,
+				"Time to live in seconds values got stored with, whenever the TTL rules changed the requested one.",
+				ttlBuckets,
+			),
+		},
+		Replication: &PrometheusReplicationMetrics{
+			Lag: newHistogram(cfg, registry,
+				ReplLagMet,
+				"Seconds from the moment a value is stored in the primary backend until it's replicated to the standby.",
+				lagBuckets,
+			),
+			QueueDepth: newGauge(cfg, registry, ReplQueueMet, "Number of values waiting to be replicated to the standby backend."),
+			Dropped:    newSingleCounter(cfg, registry, ReplDropMet, "Count of values not replicated to the standby backend because the queue was full."),
+			Errors:     newSingleCounter(cfg, registry, ReplErrMet, "Count of values the standby backend failed to store after every attempt."),
+		},
+		MaxAttempts: newHistogram(cfg, registry,
+			MaxAttemptsMet,
+			"Max backend attempts of the requests that overrode them, once lowered to the configured ceiling.",
+			attemptBuckets,
+		),
+		Breaker: newCounterVecWithLabels(cfg, registry,
+			BreakerMet,
+			"Count of the transitions of the backend circuit breaker labeled by the state it transitioned to.",
+			[]string{StateKey},
+		),
+		Batches: &PrometheusBatchMetrics{
+			PutSize: newHistogram(cfg, registry,
+				PutBatchMet,
+				"Number of values stored in each batch sent to the backend.",
+				batchBuckets,
+			),
+			GetSize: newHistogram(cfg, registry,
+				GetBatchMet,
+				"Number of keys read in each batch sent to the backend.",
+				batchBuckets,
+			),
+		},
+		Memory: &PrometheusMemoryMetrics{
+			Evictions: newCounterVecWithLabels(cfg, registry,
+				MemEvictMet,
+				"Count of values the memory backend evicted labeled by the reason they were evicted for.",
+				[]string{ReasonKey},
+			),
+			EvictedAge: newHistogram(cfg, registry,
+				MemEvictAgeMet,
+				"Seconds the values the memory backend evicted had been stored for.",
+				ageBuckets,
+			),
+		},
+		FDs: &PrometheusFileDescriptorMetrics{
+			Open:     newGauge(cfg, registry, FDOpenMet, "Number of file descriptors Prebid Cache has open, as of the last check."),
+			Limit:    newGauge(cfg, registry, FDLimitMet, "Soft limit on the number of file descriptors Prebid Cache can open, as of the last check."),
+			Warnings: newSingleCounter(cfg, registry, FDWarnMet, "Count of checks that found the open file descriptors past the configured fraction of their limit."),
 		},
-		MetricsName: MetricsPrometheus,
 	}
 
 	// Should be the equivalent of the following influx collectors
 	// go metrics.CaptureRuntimeMemStats(m.Registry, flushTime)
 	// go metrics.CaptureDebugGCStats(m.Registry, flushTime)
 	collectorNamespace := fmt.Sprintf("%s_%s", cfg.Namespace, cfg.Subsystem)
-	promMetrics.Registry.MustRegister(
+	registerCollector(collectors.Registry,
 		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{Namespace: collectorNamespace}),
 	)
 
-	preloadLabelValues(promMetrics)
-	return promMetrics
+	preloadLabelValues(collectors, allowLists, backend)
+	return collectors
 }
 
 func newCounterVecWithLabels(cfg config.PrometheusMetrics, registry *prometheus.Registry, name string, help string, labels []string) *prometheus.CounterVec {
@@ -181,8 +507,10 @@ func newCounterVecWithLabels(cfg config.PrometheusMetrics, registry *prometheus.
 		Name:      name,
 		Help:      help,
 	}
-	counterVec := prometheus.NewCounterVec(opts, labels)
-	registry.MustRegister(counterVec)
+	counterVec, ok := registerCollector(registry, prometheus.NewCounterVec(opts, labels)).(*prometheus.CounterVec)
+	if !ok {
+		log.Fatalf("Prometheus metric %s is already registered as a collector other than a counter vector", name)
+	}
 	return counterVec
 }
 
@@ -193,11 +521,27 @@ func newSingleCounter(cfg config.PrometheusMetrics, registry *prometheus.Registr
 		Name:      name,
 		Help:      help,
 	}
-	counter := prometheus.NewCounter(opts)
-	registry.MustRegister(counter)
+	counter, ok := registerCollector(registry, prometheus.NewCounter(opts)).(prometheus.Counter)
+	if !ok {
+		log.Fatalf("Prometheus metric %s is already registered as a collector other than a counter", name)
+	}
 	return counter
 }
 
+func newGauge(cfg config.PrometheusMetrics, registry *prometheus.Registry, name string, help string) prometheus.Gauge {
+	opts := prometheus.GaugeOpts{
+		Namespace: cfg.Namespace,
+		Subsystem: cfg.Subsystem,
+		Name:      name,
+		Help:      help,
+	}
+	gauge, ok := registerCollector(registry, prometheus.NewGauge(opts)).(prometheus.Gauge)
+	if !ok {
+		log.Fatalf("Prometheus metric %s is already registered as a collector other than a gauge", name)
+	}
+	return gauge
+}
+
 func newHistogram(cfg config.PrometheusMetrics, registry *prometheus.Registry, name, help string, buckets []float64) prometheus.Histogram {
 	opts := prometheus.HistogramOpts{
 		Namespace: cfg.Namespace,
@@ -206,12 +550,76 @@ func newHistogram(cfg config.PrometheusMetrics, registry *prometheus.Registry, n
 		Help:      help,
 		Buckets:   buckets,
 	}
-	histogram := prometheus.NewHistogram(opts)
-	registry.MustRegister(histogram)
+	histogram, ok := registerCollector(registry, prometheus.NewHistogram(opts)).(prometheus.Histogram)
+	if !ok {
+		log.Fatalf("Prometheus metric %s is already registered as a collector other than a histogram", name)
+	}
 	return histogram
 }
 
-func (m PrometheusMetrics) Export(cfg config.Metrics) {
+func newHistogramVector(cfg config.PrometheusMetrics, registry *prometheus.Registry, name, help string, labels []string, buckets []float64) *prometheus.HistogramVec {
+	opts := prometheus.HistogramOpts{
+		Namespace: cfg.Namespace,
+		Subsystem: cfg.Subsystem,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}
+	histogramVec, ok := registerCollector(registry, prometheus.NewHistogramVec(opts, labels)).(*prometheus.HistogramVec)
+	if !ok {
+		log.Fatalf("Prometheus metric %s is already registered as a collector other than a histogram vector", name)
+	}
+	return histogramVec
+}
+
+// registerCollector adds the collector to the registry and returns the collector the metric should be
+// recorded into. If an identical collector was registered before, that one gets reused instead of
+// panicking like prometheus.MustRegister would. Any other registration error is fatal.
+func registerCollector(registry *prometheus.Registry, collector prometheus.Collector) prometheus.Collector {
+	if err := registry.Register(collector); err != nil {
+		if alreadyRegistered, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return alreadyRegistered.ExistingCollector
+		}
+		log.Fatalf("Failed to register Prometheus metric: %v", err)
+	}
+	return collector
+}
+
+// registerConnectionPool registers a gauge for every stat of the pool the backend reports
+func registerConnectionPool(cfg config.PrometheusMetrics, registry *prometheus.Registry, pool connectionPool) {
+	gauges := []struct {
+		name string
+		help string
+		read func() int
+	}{
+		{PoolActiveMet, "Number of connections to the backend running an operation.", pool.active},
+		{PoolSizeMet, "Number of connections open to the backend, whether running an operation or idle.", pool.size},
+		{PoolPendMet, "Number of backend operations waiting for a connection.", pool.pending},
+	}
+	for _, gauge := range gauges {
+		if gauge.read == nil {
+			continue
+		}
+		read := gauge.read
+		registerCollector(registry, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: cfg.Subsystem,
+			Name:      gauge.name,
+			Help:      gauge.help,
+		}, func() float64 { return float64(read()) }))
+	}
+}
+
+// sampled decides whether an observation makes it into a histogram given its sample rate. Rates
+// outside of the (0, 1) range are not sampled at all, so every observation is kept.
+func (m *PrometheusMetrics) sampled(rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	return m.randFloat() < rate
+}
+
+func (m *PrometheusMetrics) Export(cfg config.Metrics) {
 }
 
 func (m *PrometheusMetrics) GetMetricsEngineName() string {
@@ -219,109 +627,361 @@ func (m *PrometheusMetrics) GetMetricsEngineName() string {
 }
 
 func (m *PrometheusMetrics) GetEngineRegistry() interface{} {
-	return m.Registry
+	return m.collectors().Registry
+}
+
+// Reset replaces every collector, and the registry they're gathered from, by new ones that start from
+// zero. Metrics recorded while the reset is in progress may be counted before it and get lost.
+func (m *PrometheusMetrics) Reset() {
+	collectors := newPrometheusCollectors(m.cfg, m.allowLists, m.backend)
+
+	m.mu.Lock()
+	registerConnectionPool(m.cfg, collectors.Registry, m.pool)
+	m.PrometheusCollectors = collectors
+	m.mu.Unlock()
+}
+
+// RegisterConnectionPool registers gauges that read the backend connection pool every time metrics get
+// gathered. The stats whose func is nil aren't registered, nor are any if this is never called.
+func (m *PrometheusMetrics) RegisterConnectionPool(active, size, pending func() int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pool = connectionPool{active: active, size: size, pending: pending}
+	registerConnectionPool(m.cfg, m.PrometheusCollectors.Registry, m.pool)
+}
+
+// collectors returns the collectors metrics get recorded into. Recording methods must go through it
+// rather than reading the collectors directly, which would race with Reset.
+func (m *PrometheusMetrics) collectors() *PrometheusCollectors {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.PrometheusCollectors
+}
+
+// incCounter counts one under the given labels, once the label allow-lists are applied to them. Every
+// labeled counter must be incremented through it.
+func (m *PrometheusMetrics) incCounter(counter *prometheus.CounterVec, labels prometheus.Labels) {
+	counter.With(m.allowLists.apply(labels)).Inc()
+}
+
+// observeHistogram observes the value under the given labels, once the label allow-lists are applied to
+// them
+func (m *PrometheusMetrics) observeHistogram(histogram *prometheus.HistogramVec, labels prometheus.Labels, value float64) {
+	histogram.With(m.allowLists.apply(labels)).Observe(value)
 }
 
 func (m *PrometheusMetrics) RecordPutError() {
-	m.Puts.RequestStatus.With(prometheus.Labels{StatusKey: ErrorVal}).Inc()
+	m.incCounter(m.collectors().Puts.RequestStatus, prometheus.Labels{StatusKey: ErrorVal})
 }
 
 func (m *PrometheusMetrics) RecordPutBadRequest() {
-	m.Puts.RequestStatus.With(prometheus.Labels{StatusKey: BadRequestVal}).Inc()
+	m.incCounter(m.collectors().Puts.RequestStatus, prometheus.Labels{StatusKey: BadRequestVal})
 }
 
 func (m *PrometheusMetrics) RecordPutTotal() {
-	m.Puts.RequestStatus.With(prometheus.Labels{StatusKey: TotalsVal}).Inc()
+	m.incCounter(m.collectors().Puts.RequestStatus, prometheus.Labels{StatusKey: TotalsVal})
 }
 
 func (m *PrometheusMetrics) RecordPutDuration(duration time.Duration) {
-	m.Puts.Duration.Observe(duration.Seconds())
+	if m.sampled(m.putDurationSampleRate) {
+		m.collectors().Puts.Duration.Observe(duration.Seconds())
+	}
 }
 
 func (m *PrometheusMetrics) RecordGetError() {
-	m.Gets.RequestStatus.With(prometheus.Labels{StatusKey: ErrorVal}).Inc()
+	m.incCounter(m.collectors().Gets.RequestStatus, prometheus.Labels{StatusKey: ErrorVal})
 }
 
 func (m *PrometheusMetrics) RecordGetBadRequest() {
-	m.Gets.RequestStatus.With(prometheus.Labels{StatusKey: BadRequestVal}).Inc()
+	m.incCounter(m.collectors().Gets.RequestStatus, prometheus.Labels{StatusKey: BadRequestVal})
 }
 
 func (m *PrometheusMetrics) RecordGetTotal() {
-	m.Gets.RequestStatus.With(prometheus.Labels{StatusKey: TotalsVal}).Inc()
+	m.incCounter(m.collectors().Gets.RequestStatus, prometheus.Labels{StatusKey: TotalsVal})
 }
 
 func (m *PrometheusMetrics) RecordGetDuration(duration time.Duration) {
-	m.Gets.Duration.Observe(duration.Seconds())
+	if m.sampled(m.getDurationSampleRate) {
+		m.collectors().Gets.Duration.Observe(duration.Seconds())
+	}
+}
+
+func (m *PrometheusMetrics) RecordDeleteError() {
+	m.incCounter(m.collectors().Deletes.RequestStatus, prometheus.Labels{StatusKey: ErrorVal})
+}
+
+func (m *PrometheusMetrics) RecordDeleteBadRequest() {
+	m.incCounter(m.collectors().Deletes.RequestStatus, prometheus.Labels{StatusKey: BadRequestVal})
+}
+
+func (m *PrometheusMetrics) RecordDeleteTotal() {
+	m.incCounter(m.collectors().Deletes.RequestStatus, prometheus.Labels{StatusKey: TotalsVal})
+}
+
+func (m *PrometheusMetrics) RecordDeleteDuration(duration time.Duration) {
+	m.collectors().Deletes.Duration.Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) RecordTouchError() {
+	m.incCounter(m.collectors().Touches.RequestStatus, prometheus.Labels{StatusKey: ErrorVal})
+}
+
+func (m *PrometheusMetrics) RecordTouchBadRequest() {
+	m.incCounter(m.collectors().Touches.RequestStatus, prometheus.Labels{StatusKey: BadRequestVal})
+}
+
+func (m *PrometheusMetrics) RecordTouchTotal() {
+	m.incCounter(m.collectors().Touches.RequestStatus, prometheus.Labels{StatusKey: TotalsVal})
+}
+
+func (m *PrometheusMetrics) RecordTouchDuration(duration time.Duration) {
+	m.collectors().Touches.Duration.Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) RecordEndToEndDuration(duration time.Duration) {
+	m.collectors().EndToEnd.Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) RecordPutParseError() {
+	m.incCounter(m.collectors().Puts.RequestStatus, prometheus.Labels{StatusKey: ParseErrorVal})
+}
+
+func (m *PrometheusMetrics) RecordPutValidationError() {
+	m.incCounter(m.collectors().Puts.RequestStatus, prometheus.Labels{StatusKey: InvalidVal})
+}
+
+func (m *PrometheusMetrics) RecordPutQuotaRejection() {
+	m.collectors().Puts.QuotaRejections.Inc()
+}
+
+func (m *PrometheusMetrics) RecordRejectedRequest(reason string) {
+	m.incCounter(m.collectors().Rejections, prometheus.Labels{ReasonKey: rejectionReasonLabel(reason)})
+}
+
+// rejectionReasonLabel keeps the reason label within rejectionReasonVals, counting any other reason as
+// OtherVal
+func rejectionReasonLabel(reason string) string {
+	for _, known := range rejectionReasonVals {
+		if reason == known {
+			return reason
+		}
+	}
+	return OtherVal
+}
+
+func (m *PrometheusMetrics) RecordPutUserAgent(class string) {
+	m.incCounter(m.collectors().Puts.ByUserAgent, prometheus.Labels{UserAgentKey: userAgentLabel(class)})
+}
+
+func (m *PrometheusMetrics) RecordGetUserAgent(class string) {
+	m.incCounter(m.collectors().Gets.ByUserAgent, prometheus.Labels{UserAgentKey: userAgentLabel(class)})
+}
+
+// userAgentLabel keeps the user agent label within userAgentVals. Any class outside of them is
+// counted as OtherVal.
+func userAgentLabel(class string) string {
+	for _, known := range userAgentVals {
+		if class == known {
+			return class
+		}
+	}
+	return OtherVal
 }
 
 func (m *PrometheusMetrics) RecordPutBackendXml() {
-	m.PutsBackend.PutBackendRequests.With(prometheus.Labels{FormatKey: XmlVal}).Inc()
+	m.recordPutBackendFormat(XmlVal)
 }
 
 func (m *PrometheusMetrics) RecordPutBackendJson() {
-	m.PutsBackend.PutBackendRequests.With(prometheus.Labels{FormatKey: JsonVal}).Inc()
+	m.recordPutBackendFormat(JsonVal)
 }
 
 func (m *PrometheusMetrics) RecordPutBackendInvalid() {
-	m.PutsBackend.PutBackendRequests.With(prometheus.Labels{FormatKey: InvFormatVal}).Inc()
+	m.recordPutBackendFormat(InvFormatVal)
 }
 
 func (m *PrometheusMetrics) RecordPutBackendDefTTL() {
-	m.PutsBackend.PutBackendRequests.With(prometheus.Labels{FormatKey: DefinesTTLVal}).Inc()
+	m.recordPutBackendFormat(DefinesTTLVal)
+}
+
+// recordPutBackendFormat counts a backend put under the given format label. Formats outside of
+// putBackendFormatVals are counted as InvFormatVal rather than creating a new label value.
+func (m *PrometheusMetrics) recordPutBackendFormat(format string) {
+	for _, known := range putBackendFormatVals {
+		if format == known {
+			m.incCounter(m.collectors().PutsBackend.PutBackendRequests, prometheus.Labels{FormatKey: format, BackendKey: m.backend})
+			return
+		}
+	}
+	m.incCounter(m.collectors().PutsBackend.PutBackendRequests, prometheus.Labels{FormatKey: InvFormatVal, BackendKey: m.backend})
 }
 
 func (m *PrometheusMetrics) RecordPutBackendDuration(duration time.Duration) {
-	m.PutsBackend.Duration.Observe(duration.Seconds())
+	m.observeHistogram(m.collectors().PutsBackend.Duration, prometheus.Labels{BackendKey: m.backend}, duration.Seconds())
 }
 
 func (m *PrometheusMetrics) RecordPutBackendError() {
-	m.PutsBackend.PutBackendRequests.With(prometheus.Labels{FormatKey: ErrorVal}).Inc()
+	m.recordPutBackendFormat(ErrorVal)
+}
+
+func (m *PrometheusMetrics) RecordPutBackendTimeout() {
+	m.incCounter(m.collectors().PutsBackend.RequestStatus, prometheus.Labels{StatusKey: TimeoutVal, BackendKey: m.backend})
 }
 
 func (m *PrometheusMetrics) RecordPutBackendSize(sizeInBytes float64) {
-	m.PutsBackend.RequestLength.Observe(sizeInBytes)
+	m.collectors().PutsBackend.RequestLength.Observe(sizeInBytes)
+}
+
+func (m *PrometheusMetrics) RecordPutVerificationFailure() {
+	m.collectors().PutsBackend.VerificationFailures.Inc()
+}
+
+func (m *PrometheusMetrics) RecordPutKeyCollision() {
+	m.collectors().PutsBackend.KeyCollisions.Inc()
+}
+
+func (m *PrometheusMetrics) RecordPutOversized() {
+	m.collectors().PutsBackend.Oversized.Inc()
 }
 
 func (m *PrometheusMetrics) RecordGetBackendTotal() {
-	m.GetsBackend.RequestStatus.With(prometheus.Labels{StatusKey: TotalsVal}).Inc()
+	m.incCounter(m.collectors().GetsBackend.RequestStatus, prometheus.Labels{StatusKey: TotalsVal, BackendKey: m.backend})
 }
 
 func (m *PrometheusMetrics) RecordGetBackendDuration(duration time.Duration) {
-	m.GetsBackend.Duration.Observe(duration.Seconds())
+	m.observeHistogram(m.collectors().GetsBackend.Duration, prometheus.Labels{BackendKey: m.backend}, duration.Seconds())
 }
 
 func (m *PrometheusMetrics) RecordGetBackendError() {
-	m.GetsBackend.RequestStatus.With(prometheus.Labels{StatusKey: ErrorVal}).Inc()
+	m.incCounter(m.collectors().GetsBackend.RequestStatus, prometheus.Labels{StatusKey: ErrorVal, BackendKey: m.backend})
+}
+
+func (m *PrometheusMetrics) RecordGetBackendTimeout() {
+	m.incCounter(m.collectors().GetsBackend.RequestStatus, prometheus.Labels{StatusKey: TimeoutVal, BackendKey: m.backend})
 }
 
 func (m *PrometheusMetrics) RecordGetBackendBadRequest() {
-	m.GetsBackend.RequestStatus.With(prometheus.Labels{StatusKey: BadRequestVal}).Inc()
+	m.incCounter(m.collectors().GetsBackend.RequestStatus, prometheus.Labels{StatusKey: BadRequestVal, BackendKey: m.backend})
 }
 
 func (m *PrometheusMetrics) RecordKeyNotFoundError() {
-	m.GetsBackend.ErrorsByType.With(prometheus.Labels{TypeKey: KeyNotFoundVal}).Inc()
+	m.incCounter(m.collectors().GetsBackend.ErrorsByType, prometheus.Labels{TypeKey: KeyNotFoundVal})
 }
 
 func (m *PrometheusMetrics) RecordMissingKeyError() {
-	m.GetsBackend.ErrorsByType.With(prometheus.Labels{TypeKey: MissingKeyVal}).Inc()
+	m.incCounter(m.collectors().GetsBackend.ErrorsByType, prometheus.Labels{TypeKey: MissingKeyVal})
+}
+
+func (m *PrometheusMetrics) RecordCorruptValue() {
+	m.incCounter(m.collectors().GetsBackend.ErrorsByType, prometheus.Labels{TypeKey: CorruptVal})
+}
+
+func (m *PrometheusMetrics) RecordGetBackendKeyHit() {
+	m.incCounter(m.collectors().GetsBackend.Results, prometheus.Labels{ResultKey: HitVal})
+}
+
+func (m *PrometheusMetrics) RecordGetBackendKeyMiss() {
+	m.incCounter(m.collectors().GetsBackend.Results, prometheus.Labels{ResultKey: MissVal})
+}
+
+func (m *PrometheusMetrics) RecordTierLookup(tier string, hit bool) {
+	result := MissVal
+	if hit {
+		result = HitVal
+	}
+	m.incCounter(m.collectors().GetsBackend.TierResults, prometheus.Labels{TierKey: tier, ResultKey: result})
 }
 
 func (m *PrometheusMetrics) RecordConnectionOpen() {
-	m.Connections.ConnectionsOpened.Inc()
+	m.collectors().Connections.ConnectionsOpened.Inc()
 }
 
 func (m *PrometheusMetrics) RecordConnectionClosed() {
-	m.Connections.ConnectionsClosed.Inc()
+	m.collectors().Connections.ConnectionsClosed.Inc()
 }
 
 func (m *PrometheusMetrics) RecordCloseConnectionErrors() {
-	m.Connections.ConnectionsErrors.With(prometheus.Labels{ConnErrorKey: CloseVal}).Inc()
+	m.incCounter(m.collectors().Connections.ConnectionsErrors, prometheus.Labels{ConnErrorKey: CloseVal})
 }
 
 func (m *PrometheusMetrics) RecordAcceptConnectionErrors() {
-	m.Connections.ConnectionsErrors.With(prometheus.Labels{ConnErrorKey: AcceptVal}).Inc()
+	m.incCounter(m.collectors().Connections.ConnectionsErrors, prometheus.Labels{ConnErrorKey: AcceptVal})
+}
+
+func (m *PrometheusMetrics) RecordTLSHandshake(duration time.Duration) {
+	collectors := m.collectors()
+	collectors.Connections.TLSHandshakes.Inc()
+	collectors.Connections.TLSHandshakeDuration.Observe(duration.Seconds())
+}
+
+// RecordTLSHandshakeFailure counts reasons outside of tlsFailureReasonVals as OtherVal
+func (m *PrometheusMetrics) RecordTLSHandshakeFailure(reason string) {
+	label := OtherVal
+	for _, known := range tlsFailureReasonVals {
+		if reason == known {
+			label = reason
+			break
+		}
+	}
+	m.incCounter(m.collectors().Connections.TLSHandshakeFailures, prometheus.Labels{ReasonKey: label})
 }
 
 func (m *PrometheusMetrics) RecordExtraTTLSeconds(value float64) {
-	m.ExtraTTL.ExtraTTLSeconds.Observe(value)
+	m.collectors().ExtraTTL.ExtraTTLSeconds.Observe(value)
+}
+
+func (m *PrometheusMetrics) RecordAppliedTTLSeconds(value float64) {
+	m.collectors().ExtraTTL.AppliedTTLSeconds.Observe(value)
+}
+
+func (m *PrometheusMetrics) RecordMaxAttemptsOverride(attempts int) {
+	m.collectors().MaxAttempts.Observe(float64(attempts))
+}
+
+func (m *PrometheusMetrics) RecordReplicationLag(duration time.Duration) {
+	m.collectors().Replication.Lag.Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) RecordBackendConcurrencyWait(duration time.Duration) {
+	m.collectors().BackendWait.Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) RecordReplicationQueueDepth(depth int) {
+	m.collectors().Replication.QueueDepth.Set(float64(depth))
+}
+
+func (m *PrometheusMetrics) RecordReplicationDropped() {
+	m.collectors().Replication.Dropped.Inc()
+}
+
+func (m *PrometheusMetrics) RecordReplicationError() {
+	m.collectors().Replication.Errors.Inc()
+}
+
+func (m *PrometheusMetrics) RecordCircuitBreakerTransition(state string) {
+	m.incCounter(m.collectors().Breaker, prometheus.Labels{StateKey: state})
+}
+
+func (m *PrometheusMetrics) RecordPutBackendBatchSize(size int) {
+	m.collectors().Batches.PutSize.Observe(float64(size))
+}
+
+func (m *PrometheusMetrics) RecordGetBackendBatchSize(size int) {
+	m.collectors().Batches.GetSize.Observe(float64(size))
+}
+
+func (m *PrometheusMetrics) RecordMemoryEviction(reason string, age time.Duration) {
+	collectors := m.collectors()
+	m.incCounter(collectors.Memory.Evictions, prometheus.Labels{ReasonKey: reason})
+	collectors.Memory.EvictedAge.Observe(age.Seconds())
+}
+
+func (m *PrometheusMetrics) RecordFileDescriptors(open int, limit int) {
+	collectors := m.collectors()
+	collectors.FDs.Open.Set(float64(open))
+	collectors.FDs.Limit.Set(float64(limit))
+}
+
+func (m *PrometheusMetrics) RecordFileDescriptorWarning() {
+	m.collectors().FDs.Warnings.Inc()
 }
@@ -1,12 +1,16 @@
 package metrics
 
 import (
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/prebid/prebid-cache/config"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +21,7 @@ func createPrometheusMetricsForTesting() *PrometheusMetrics {
 		Port:      8080,
 		Namespace: "prebid",
 		Subsystem: "cache",
-	})
+	}, config.BackendMemory)
 }
 
 func assertCounterVecValue(t *testing.T, description string, counterVec *prometheus.CounterVec, expected float64, labels prometheus.Labels) {
@@ -41,6 +45,12 @@ func assertGaugeValue(t *testing.T, description string, gauge prometheus.Gauge,
 	assert.Equal(t, expected, actual, description)
 }
 
+// backendHistogram returns the histogram the backend metrics for testing are observed in, labeled with
+// the memory backend
+func backendHistogram(histogramVec *prometheus.HistogramVec) prometheus.Histogram {
+	return histogramVec.With(prometheus.Labels{BackendKey: string(config.BackendMemory)}).(prometheus.Histogram)
+}
+
 func assertHistogram(t *testing.T, name string, histogram prometheus.Histogram, expectedCount uint64, expectedSum float64) {
 	m := dto.Metric{}
 	histogram.Write(&m)
@@ -136,30 +146,58 @@ func TestPrometheusRequestStatusMetric(t *testing.T) {
 				expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 1,
 			},
 		},
-		m.GetsBackend: {
+		m.Deletes: {
 			{
-				description: "Log get backend request duration",
+				description: "Log delete request duration",
 				testCase: func(pm *PrometheusMetrics) {
-					pm.RecordGetBackendDuration(TenSeconds)
+					pm.RecordDeleteDuration(TenSeconds)
 				},
 				expDuration:      10,
 				expRequestTotals: 0, expRequestErrors: 0, expBadRequests: 0,
 			},
 			{
-				description:      "Count get backend request total",
-				testCase:         func(pm *PrometheusMetrics) { pm.RecordGetBackendTotal() },
+				description:      "Count delete request total",
+				testCase:         func(pm *PrometheusMetrics) { pm.RecordDeleteTotal() },
 				expDuration:      10,
 				expRequestTotals: 1, expRequestErrors: 0, expBadRequests: 0,
 			},
 			{
-				description:      "Count get backend request error",
-				testCase:         func(pm *PrometheusMetrics) { pm.RecordGetBackendError() },
+				description:      "Count delete request error",
+				testCase:         func(pm *PrometheusMetrics) { pm.RecordDeleteError() },
 				expDuration:      10,
 				expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 0,
 			},
 			{
-				description:      "Count get backend request bad request",
-				testCase:         func(pm *PrometheusMetrics) { pm.RecordGetBackendBadRequest() },
+				description:      "Count delete request bad request",
+				testCase:         func(pm *PrometheusMetrics) { pm.RecordDeleteBadRequest() },
+				expDuration:      10,
+				expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 1,
+			},
+		},
+		m.Touches: {
+			{
+				description: "Log touch request duration",
+				testCase: func(pm *PrometheusMetrics) {
+					pm.RecordTouchDuration(TenSeconds)
+				},
+				expDuration:      10,
+				expRequestTotals: 0, expRequestErrors: 0, expBadRequests: 0,
+			},
+			{
+				description:      "Count touch request total",
+				testCase:         func(pm *PrometheusMetrics) { pm.RecordTouchTotal() },
+				expDuration:      10,
+				expRequestTotals: 1, expRequestErrors: 0, expBadRequests: 0,
+			},
+			{
+				description:      "Count touch request error",
+				testCase:         func(pm *PrometheusMetrics) { pm.RecordTouchError() },
+				expDuration:      10,
+				expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 0,
+			},
+			{
+				description:      "Count touch request bad request",
+				testCase:         func(pm *PrometheusMetrics) { pm.RecordTouchBadRequest() },
 				expDuration:      10,
 				expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 1,
 			},
@@ -178,6 +216,146 @@ func TestPrometheusRequestStatusMetric(t *testing.T) {
 	}
 }
 
+// TestGetBackendMetrics makes sure backend gets are recorded under the type of the configured backend
+func TestGetBackendMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	testCases := []struct {
+		description      string
+		expDuration      float64
+		expRequestTotals float64
+		expRequestErrors float64
+		expBadRequests   float64
+		testCase         func(pm *PrometheusMetrics)
+	}{
+		{
+			description: "Log get backend request duration",
+			testCase: func(pm *PrometheusMetrics) {
+				pm.RecordGetBackendDuration(TenSeconds)
+			},
+			expDuration:      10,
+			expRequestTotals: 0, expRequestErrors: 0, expBadRequests: 0,
+		},
+		{
+			description:      "Count get backend request total",
+			testCase:         func(pm *PrometheusMetrics) { pm.RecordGetBackendTotal() },
+			expDuration:      10,
+			expRequestTotals: 1, expRequestErrors: 0, expBadRequests: 0,
+		},
+		{
+			description:      "Count get backend request error",
+			testCase:         func(pm *PrometheusMetrics) { pm.RecordGetBackendError() },
+			expDuration:      10,
+			expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 0,
+		},
+		{
+			description:      "Count get backend request bad request",
+			testCase:         func(pm *PrometheusMetrics) { pm.RecordGetBackendBadRequest() },
+			expDuration:      10,
+			expRequestTotals: 1, expRequestErrors: 1, expBadRequests: 1,
+		},
+	}
+
+	backend := string(config.BackendMemory)
+	for _, test := range testCases {
+		test.testCase(m)
+
+		assertHistogram(t, test.description, backendHistogram(m.GetsBackend.Duration), 1, test.expDuration)
+		assertCounterVecValue(t, test.description, m.GetsBackend.RequestStatus, test.expRequestTotals, prometheus.Labels{StatusKey: TotalsVal, BackendKey: backend})
+		assertCounterVecValue(t, test.description, m.GetsBackend.RequestStatus, test.expRequestErrors, prometheus.Labels{StatusKey: ErrorVal, BackendKey: backend})
+		assertCounterVecValue(t, test.description, m.GetsBackend.RequestStatus, test.expBadRequests, prometheus.Labels{StatusKey: BadRequestVal, BackendKey: backend})
+	}
+}
+
+func TestBackendTimeouts(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+	backend := string(config.BackendMemory)
+
+	m.RecordGetBackendTimeout()
+	m.RecordPutBackendTimeout()
+
+	assertCounterVecValue(t, "Get timeouts", m.GetsBackend.RequestStatus, 1, prometheus.Labels{StatusKey: TimeoutVal, BackendKey: backend})
+	assertCounterVecValue(t, "Gets that timed out aren't errors", m.GetsBackend.RequestStatus, 0, prometheus.Labels{StatusKey: ErrorVal, BackendKey: backend})
+	assertCounterVecValue(t, "Put timeouts", m.PutsBackend.RequestStatus, 1, prometheus.Labels{StatusKey: TimeoutVal, BackendKey: backend})
+	assertCounterVecValue(t, "Puts that timed out aren't errors", m.PutsBackend.PutBackendRequests, 0, prometheus.Labels{FormatKey: ErrorVal, BackendKey: backend})
+	for _, format := range putBackendFormatVals {
+		assertCounterVecValue(t, "Put timeouts aren't counted under a format", m.PutsBackend.PutBackendRequests, 0, prometheus.Labels{FormatKey: format, BackendKey: backend})
+	}
+}
+
+func TestLabelAllowLists(t *testing.T) {
+	m := CreatePrometheusMetrics(config.PrometheusMetrics{
+		Port:      8080,
+		Namespace: "prebid",
+		Subsystem: "cache",
+		LabelAllowLists: map[string][]string{
+			FormatKey:    {JsonVal},
+			UserAgentKey: {PrebidServerVal, BrowserVal},
+		},
+	}, config.BackendMemory)
+
+	// Only the series of allowed values get preloaded
+	families, err := m.Registry.Gather()
+	if assert.NoError(t, err) {
+		for _, family := range families {
+			if family.GetName() != "prebid_cache_"+PutBackendMet {
+				continue
+			}
+			var formats []string
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == FormatKey {
+						formats = append(formats, label.GetValue())
+					}
+				}
+			}
+			assert.ElementsMatch(t, []string{JsonVal, OtherVal}, formats)
+		}
+	}
+
+	m.RecordPutBackendJson()
+	m.RecordPutBackendXml()
+	m.RecordPutBackendDefTTL()
+	m.RecordPutUserAgent(BrowserVal)
+	m.RecordPutTotal()
+
+	assertCounterVecValue(t, "Listed format", m.PutsBackend.PutBackendRequests, 1, prometheus.Labels{FormatKey: JsonVal, BackendKey: string(config.BackendMemory)})
+	assertCounterVecValue(t, "Unlisted formats collapse to other", m.PutsBackend.PutBackendRequests, 2, prometheus.Labels{FormatKey: OtherVal, BackendKey: string(config.BackendMemory)})
+	assertCounterVecValue(t, "Listed user agent", m.Puts.ByUserAgent, 1, prometheus.Labels{UserAgentKey: BrowserVal})
+	assertCounterVecValue(t, "Label without an allow-list", m.Puts.RequestStatus, 1, prometheus.Labels{StatusKey: TotalsVal})
+}
+
+func TestRequestDurationSampling(t *testing.T) {
+	m := CreatePrometheusMetrics(config.PrometheusMetrics{
+		Port:                  8080,
+		Namespace:             "prebid",
+		Subsystem:             "cache",
+		GetDurationSampleRate: 0.5,
+		PutDurationSampleRate: 1,
+	}, config.BackendMemory)
+
+	// Deterministic sequence of draws: only those under 0.5 get a GET duration observed
+	draws := []float64{0.1, 0.6, 0.3, 0.9}
+	i := 0
+	m.randFloat = func() float64 {
+		draw := draws[i%len(draws)]
+		i++
+		return draw
+	}
+
+	for j := 0; j < len(draws); j++ {
+		m.RecordGetTotal()
+		m.RecordGetDuration(TenSeconds)
+		m.RecordPutTotal()
+		m.RecordPutDuration(TenSeconds)
+	}
+
+	assertHistogram(t, "Sampled get request duration", m.Gets.Duration, 2, 20)
+	assertHistogram(t, "Unsampled put request duration", m.Puts.Duration, 4, 40)
+	assertCounterVecValue(t, "Get request counter is not sampled", m.Gets.RequestStatus, 4, prometheus.Labels{StatusKey: TotalsVal})
+	assertCounterVecValue(t, "Put request counter is not sampled", m.Puts.RequestStatus, 4, prometheus.Labels{StatusKey: TotalsVal})
+}
+
 func TestGetsBackendErrorsByType(t *testing.T) {
 
 	m := createPrometheusMetricsForTesting()
@@ -186,6 +364,7 @@ func TestGetsBackendErrorsByType(t *testing.T) {
 		description          string
 		expKeyNotFoundErrors float64
 		expMissingKeyErrors  float64
+		expCorruptValues     float64
 		recordMetric         func(pm *PrometheusMetrics)
 	}{
 		{
@@ -200,6 +379,13 @@ func TestGetsBackendErrorsByType(t *testing.T) {
 			expMissingKeyErrors:  1,
 			recordMetric:         func(pm *PrometheusMetrics) { pm.RecordMissingKeyError() },
 		},
+		{
+			description:          "Add to the corrupt stored values counter",
+			expKeyNotFoundErrors: 1,
+			expMissingKeyErrors:  1,
+			expCorruptValues:     1,
+			recordMetric:         func(pm *PrometheusMetrics) { pm.RecordCorruptValue() },
+		},
 	}
 
 	for _, test := range testCaseArray {
@@ -207,9 +393,35 @@ func TestGetsBackendErrorsByType(t *testing.T) {
 
 		assertCounterVecValue(t, test.description, m.GetsBackend.ErrorsByType, test.expKeyNotFoundErrors, prometheus.Labels{TypeKey: KeyNotFoundVal})
 		assertCounterVecValue(t, test.description, m.GetsBackend.ErrorsByType, test.expMissingKeyErrors, prometheus.Labels{TypeKey: MissingKeyVal})
+		assertCounterVecValue(t, test.description, m.GetsBackend.ErrorsByType, test.expCorruptValues, prometheus.Labels{TypeKey: CorruptVal})
 	}
 }
 
+func TestGetsBackendResults(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordGetBackendKeyHit()
+	m.RecordGetBackendKeyHit()
+	m.RecordGetBackendKeyMiss()
+
+	assertCounterVecValue(t, "Hits", m.GetsBackend.Results, 2, prometheus.Labels{ResultKey: HitVal})
+	assertCounterVecValue(t, "Misses", m.GetsBackend.Results, 1, prometheus.Labels{ResultKey: MissVal})
+}
+
+func TestTierLookupMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordTierLookup(NearVal, true)
+	m.RecordTierLookup(NearVal, false)
+	m.RecordTierLookup(NearVal, false)
+	m.RecordTierLookup(RemoteVal, true)
+
+	assertCounterVecValue(t, "Near hits", m.GetsBackend.TierResults, 1, prometheus.Labels{TierKey: NearVal, ResultKey: HitVal})
+	assertCounterVecValue(t, "Near misses", m.GetsBackend.TierResults, 2, prometheus.Labels{TierKey: NearVal, ResultKey: MissVal})
+	assertCounterVecValue(t, "Remote hits", m.GetsBackend.TierResults, 1, prometheus.Labels{TierKey: RemoteVal, ResultKey: HitVal})
+	assertCounterVecValue(t, "Remote misses", m.GetsBackend.TierResults, 0, prometheus.Labels{TierKey: RemoteVal, ResultKey: MissVal})
+}
+
 func TestPutBackendMetrics(t *testing.T) {
 	m := createPrometheusMetricsForTesting()
 
@@ -297,16 +509,210 @@ func TestPutBackendMetrics(t *testing.T) {
 	for _, test := range testCases {
 		test.testCase(m)
 
-		assertHistogram(t, test.description, m.PutsBackend.Duration, 1, test.expDuration)
-		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expXmlCount, prometheus.Labels{FormatKey: XmlVal})
-		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expJsonCount, prometheus.Labels{FormatKey: JsonVal})
-		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expInvalidCount, prometheus.Labels{FormatKey: InvFormatVal})
-		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expDefTTLCount, prometheus.Labels{FormatKey: DefinesTTLVal})
-		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expErrorCount, prometheus.Labels{FormatKey: ErrorVal})
+		assertHistogram(t, test.description, backendHistogram(m.PutsBackend.Duration), 1, test.expDuration)
+		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expXmlCount, prometheus.Labels{FormatKey: XmlVal, BackendKey: string(config.BackendMemory)})
+		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expJsonCount, prometheus.Labels{FormatKey: JsonVal, BackendKey: string(config.BackendMemory)})
+		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expInvalidCount, prometheus.Labels{FormatKey: InvFormatVal, BackendKey: string(config.BackendMemory)})
+		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expDefTTLCount, prometheus.Labels{FormatKey: DefinesTTLVal, BackendKey: string(config.BackendMemory)})
+		assertCounterVecValue(t, test.description, m.PutsBackend.PutBackendRequests, test.expErrorCount, prometheus.Labels{FormatKey: ErrorVal, BackendKey: string(config.BackendMemory)})
 		assertHistogram(t, test.description, m.PutsBackend.RequestLength, test.expSizeHistCount, test.expSizeHistSum)
 	}
 }
 
+func TestPutBackendUnknownFormat(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.recordPutBackendFormat("yaml")
+
+	assertCounterVecValue(t, "Unknown format is counted as invalid", m.PutsBackend.PutBackendRequests, 1, prometheus.Labels{FormatKey: InvFormatVal, BackendKey: string(config.BackendMemory)})
+
+	metricFamilies, err := m.Registry.Gather()
+	assert.NoError(t, err, "gather metrics")
+	found := false
+	for _, family := range metricFamilies {
+		if family.GetName() != "prebid_cache_"+PutBackendMet {
+			continue
+		}
+		found = true
+		assert.Len(t, family.GetMetric(), len(putBackendFormatVals), "Unknown format should not add a label value")
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				assert.NotEqual(t, "yaml", label.GetValue(), "Unknown format should not be used as a label value")
+			}
+		}
+	}
+	assert.True(t, found, "Put backend counter should be registered")
+}
+
+func TestBackendConcurrencyWait(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordBackendConcurrencyWait(TenSeconds)
+
+	assertHistogram(t, "Backend concurrency wait", m.BackendWait, 1, 10)
+}
+
+func TestEndToEndDuration(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordEndToEndDuration(TenSeconds)
+	m.RecordEndToEndDuration(TenSeconds)
+
+	assertHistogram(t, "End to end duration", m.EndToEnd, 2, 20)
+}
+
+func TestMaxAttemptsOverrideMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordMaxAttemptsOverride(3)
+	m.RecordMaxAttemptsOverride(5)
+
+	assertHistogram(t, "Max attempts override", m.MaxAttempts, 2, 8)
+}
+
+func TestBatchMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutBackendBatchSize(3)
+	m.RecordGetBackendBatchSize(2)
+	m.RecordGetBackendBatchSize(5)
+
+	assertHistogram(t, "Put batch size", m.Batches.PutSize, 1, 3)
+	assertHistogram(t, "Get batch size", m.Batches.GetSize, 2, 7)
+}
+
+func TestReplicationMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordReplicationLag(TenSeconds)
+	m.RecordReplicationQueueDepth(7)
+	m.RecordReplicationQueueDepth(3)
+	m.RecordReplicationDropped()
+	m.RecordReplicationError()
+	m.RecordReplicationError()
+
+	assertHistogram(t, "Replication lag", m.Replication.Lag, 1, 10)
+	assertGaugeValue(t, "Replication queue depth", m.Replication.QueueDepth, 3)
+	assertCounterValue(t, "Replication drops", m.Replication.Dropped, 1)
+	assertCounterValue(t, "Replication errors", m.Replication.Errors, 2)
+}
+
+func TestCircuitBreakerMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordCircuitBreakerTransition(BrkOpenVal)
+	m.RecordCircuitBreakerTransition(BrkHalfOpenVal)
+	m.RecordCircuitBreakerTransition(BrkOpenVal)
+	m.RecordCircuitBreakerTransition(BrkHalfOpenVal)
+	m.RecordCircuitBreakerTransition(BrkClosedVal)
+
+	assertCounterVecValue(t, "Breaker opened", m.Breaker, 2, prometheus.Labels{StateKey: BrkOpenVal})
+	assertCounterVecValue(t, "Breaker half opened", m.Breaker, 2, prometheus.Labels{StateKey: BrkHalfOpenVal})
+	assertCounterVecValue(t, "Breaker closed", m.Breaker, 1, prometheus.Labels{StateKey: BrkClosedVal})
+}
+
+func TestMemoryEvictionMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordMemoryEviction(TTLVal, TenSeconds)
+	m.RecordMemoryEviction(LRUVal, 2*TenSeconds)
+	m.RecordMemoryEviction(LRUVal, 3*TenSeconds)
+
+	assertCounterVecValue(t, "TTL evictions", m.Memory.Evictions, 1, prometheus.Labels{ReasonKey: TTLVal})
+	assertCounterVecValue(t, "LRU evictions", m.Memory.Evictions, 2, prometheus.Labels{ReasonKey: LRUVal})
+	assertCounterVecValue(t, "Manual evictions", m.Memory.Evictions, 0, prometheus.Labels{ReasonKey: ManualVal})
+	assertHistogram(t, "Evicted value age", m.Memory.EvictedAge, 3, 60)
+}
+
+func TestRejectedRequestMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordRejectedRequest(RateLimitVal)
+	m.RecordRejectedRequest(OverloadVal)
+	m.RecordRejectedRequest(OverloadVal)
+	m.RecordRejectedRequest(ForbiddenVal)
+	m.RecordRejectedRequest(ThrottledVal)
+	m.RecordRejectedRequest("unknown")
+
+	assertCounterVecValue(t, "Rate limit rejections", m.Rejections, 1, prometheus.Labels{ReasonKey: RateLimitVal})
+	assertCounterVecValue(t, "Quota rejections", m.Rejections, 0, prometheus.Labels{ReasonKey: QuotaVal})
+	assertCounterVecValue(t, "Overload rejections", m.Rejections, 2, prometheus.Labels{ReasonKey: OverloadVal})
+	assertCounterVecValue(t, "Forbidden rejections", m.Rejections, 1, prometheus.Labels{ReasonKey: ForbiddenVal})
+	assertCounterVecValue(t, "Throttled rejections", m.Rejections, 1, prometheus.Labels{ReasonKey: ThrottledVal})
+	assertCounterVecValue(t, "Unknown reasons are counted as other", m.Rejections, 1, prometheus.Labels{ReasonKey: OtherVal})
+}
+
+func TestFileDescriptorMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordFileDescriptors(900, 1024)
+	m.RecordFileDescriptors(950, 1024)
+	m.RecordFileDescriptorWarning()
+
+	assertGaugeValue(t, "Open file descriptors", m.FDs.Open, 950)
+	assertGaugeValue(t, "File descriptor limit", m.FDs.Limit, 1024)
+	assertCounterValue(t, "File descriptor warnings", m.FDs.Warnings, 1)
+}
+
+func TestPutBodyErrors(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutParseError()
+	m.RecordPutValidationError()
+	m.RecordPutValidationError()
+
+	assertCounterVecValue(t, "Put parse errors", m.Puts.RequestStatus, 1, prometheus.Labels{StatusKey: ParseErrorVal})
+	assertCounterVecValue(t, "Put validation errors", m.Puts.RequestStatus, 2, prometheus.Labels{StatusKey: InvalidVal})
+	assertCounterVecValue(t, "Put bad requests", m.Puts.RequestStatus, 0, prometheus.Labels{StatusKey: BadRequestVal})
+}
+
+func TestPutQuotaRejections(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutQuotaRejection()
+
+	assertCounterValue(t, "Put quota rejections", m.Puts.QuotaRejections, 1)
+}
+
+func TestPutVerificationFailures(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutVerificationFailure()
+
+	assertCounterValue(t, "Put verification failures", m.PutsBackend.VerificationFailures, 1)
+}
+
+func TestPutKeyCollisions(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutKeyCollision()
+	m.RecordPutKeyCollision()
+
+	assertCounterValue(t, "Put key collisions", m.PutsBackend.KeyCollisions, 2)
+}
+
+func TestPutOversized(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutOversized()
+
+	assertCounterValue(t, "Oversized puts", m.PutsBackend.Oversized, 1)
+}
+
+func TestRequestsByUserAgent(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordPutUserAgent(PrebidServerVal)
+	m.RecordGetUserAgent(BrowserVal)
+	m.RecordGetUserAgent(OtherVal)
+	m.RecordGetUserAgent("crawler")
+
+	assertCounterVecValue(t, "Put request from Prebid Server", m.Puts.ByUserAgent, 1, prometheus.Labels{UserAgentKey: PrebidServerVal})
+	assertCounterVecValue(t, "Get request from a browser", m.Gets.ByUserAgent, 1, prometheus.Labels{UserAgentKey: BrowserVal})
+	assertCounterVecValue(t, "Get requests of other or unknown user agent classes", m.Gets.ByUserAgent, 2, prometheus.Labels{UserAgentKey: OtherVal})
+	assertCounterVecValue(t, "No put request from a browser", m.Puts.ByUserAgent, 0, prometheus.Labels{UserAgentKey: BrowserVal})
+}
+
 func TestConnectionMetrics(t *testing.T) {
 	testCases := []struct {
 		description                    string
@@ -370,13 +776,51 @@ func TestConnectionMetrics(t *testing.T) {
 		assertCounterVecValue(t, test.description, m.Connections.ConnectionsErrors, test.expectedAcceptConnectionErrors, prometheus.Labels{ConnErrorKey: AcceptVal})
 		assertCounterVecValue(t, test.description, m.Connections.ConnectionsErrors, test.expectedCloseConnectionErrors, prometheus.Labels{ConnErrorKey: CloseVal})
 	}
+
+	// Opening and closing connections doesn't add label values of its own to the error counter
+	assert.Equal(t, 2, testutil.CollectAndCount(m.Connections.ConnectionsErrors), "Connection errors should only be counted as accept or close errors")
+}
+
+func TestTLSHandshakeMetrics(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	m.RecordTLSHandshake(50 * time.Millisecond)
+	m.RecordTLSHandshakeFailure(NotTLSVal)
+	m.RecordTLSHandshakeFailure(TimeoutVal)
+	m.RecordTLSHandshakeFailure("cosmic_rays")
+
+	assertCounterValue(t, "Successful handshakes", m.Connections.TLSHandshakes, 1)
+	assertHistogram(t, "Successful handshake durations", m.Connections.TLSHandshakeDuration, 1, 0.05)
+	assertCounterVecValue(t, "Handshakes with clients that don't speak TLS", m.Connections.TLSHandshakeFailures, 1, prometheus.Labels{ReasonKey: NotTLSVal})
+	assertCounterVecValue(t, "Handshakes that timed out", m.Connections.TLSHandshakeFailures, 1, prometheus.Labels{ReasonKey: TimeoutVal})
+	assertCounterVecValue(t, "Handshakes failed for unknown reasons", m.Connections.TLSHandshakeFailures, 1, prometheus.Labels{ReasonKey: OtherVal})
+	assert.Equal(t, len(tlsFailureReasonVals), testutil.CollectAndCount(m.Connections.TLSHandshakeFailures), "Failures should only be counted under the known reasons")
 }
 
 func TestExtraTTLMetrics(t *testing.T) {
 	m := createPrometheusMetricsForTesting()
+	assertHistogram(t, "Assert the extra time to live histogram starts empty", m.ExtraTTL.ExtraTTLSeconds, 0, 0)
 
 	m.RecordExtraTTLSeconds(5)
 	assertHistogram(t, "Assert the extra time to live in seconds was logged", m.ExtraTTL.ExtraTTLSeconds, 1, 5.00)
+
+	m.RecordAppliedTTLSeconds(3600)
+	assertHistogram(t, "Assert the applied time to live in seconds was logged", m.ExtraTTL.AppliedTTLSeconds, 1, 3600)
+}
+
+// TestHistogramsStartEmpty makes sure no histogram is seeded with a sample when it's created
+func TestHistogramsStartEmpty(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+	metricFamilies, err := m.Registry.Gather()
+	assert.NoError(t, err, "gather metrics")
+
+	for _, metricFamily := range metricFamilies {
+		for _, metric := range metricFamily.GetMetric() {
+			if metric.Histogram != nil {
+				assert.Zero(t, metric.GetHistogram().GetSampleCount(), metricFamily.GetName()+":count")
+			}
+		}
+	}
 }
 
 func TestMetricCountGatekeeping(t *testing.T) {
@@ -398,3 +842,241 @@ func TestMetricCountGatekeeping(t *testing.T) {
 	// needing to increase this number.
 	assert.True(t, actualCardinalityCount <= expectedCardinalityCount, "General Cardinality doesn't match")
 }
+
+// TestCounterVecLabelKeys makes sure the counter vectors get recorded under the dimension they were
+// registered with, rather than under StatusKey like most of them
+func TestCounterVecLabelKeys(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+	m.RecordPutBackendXml()
+	m.RecordAcceptConnectionErrors()
+
+	testCases := []struct {
+		description   string
+		metricName    string
+		expectedKey   string
+		recordedValue string
+	}{
+		{
+			description:   "Backend puts are labeled by format",
+			metricName:    "prebid_cache_" + PutBackendMet,
+			expectedKey:   FormatKey,
+			recordedValue: XmlVal,
+		},
+		{
+			description:   "Connection errors are labeled by the kind of error",
+			metricName:    "prebid_cache_" + ConnErrorKey,
+			expectedKey:   ConnErrorKey,
+			recordedValue: AcceptVal,
+		},
+	}
+
+	metricFamilies, err := m.Registry.Gather()
+	assert.NoError(t, err, "gather metrics")
+
+	for _, test := range testCases {
+		var recorded float64
+		found := false
+		for _, metricFamily := range metricFamilies {
+			if metricFamily.GetName() != test.metricName {
+				continue
+			}
+			found = true
+			for _, metric := range metricFamily.GetMetric() {
+				// Backend metrics are also labeled by backend type
+				labeled := false
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == test.expectedKey {
+						labeled = true
+						if label.GetValue() == test.recordedValue {
+							recorded = metric.GetCounter().GetValue()
+						}
+					}
+				}
+				assert.True(t, labeled, "%s: every series should be labeled by %s", test.description, test.expectedKey)
+			}
+		}
+		assert.True(t, found, "%s: %s should be registered", test.description, test.metricName)
+		assert.Equal(t, 1.0, recorded, "%s: recorded value should be counted under %s", test.description, test.expectedKey)
+	}
+}
+
+func TestBackendTypeLabel(t *testing.T) {
+	m := CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendRedis)
+
+	m.RecordPutBackendDuration(time.Second)
+	m.RecordPutBackendJson()
+	m.RecordGetBackendDuration(2 * time.Second)
+	m.RecordGetBackendTotal()
+
+	metricFamilies, err := m.Registry.Gather()
+	assert.NoError(t, err, "gather metrics")
+
+	labeled := map[string]bool{
+		"prebid_cache_" + PutBackDurMet: false,
+		"prebid_cache_" + PutBackendMet: false,
+		"prebid_cache_" + GetBackDurMet: false,
+		"prebid_cache_" + GetBackendMet: false,
+	}
+	for _, metricFamily := range metricFamilies {
+		if _, ok := labeled[metricFamily.GetName()]; !ok {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == BackendKey {
+					assert.Equal(t, "redis", label.GetValue(), metricFamily.GetName())
+					labeled[metricFamily.GetName()] = true
+				}
+			}
+		}
+	}
+	for name, found := range labeled {
+		assert.True(t, found, "%s should be labeled by backend type", name)
+	}
+
+	redis := prometheus.Labels{BackendKey: "redis"}
+	assertHistogram(t, "Put duration", m.PutsBackend.Duration.With(redis).(prometheus.Histogram), 1, 1)
+	assertHistogram(t, "Get duration", m.GetsBackend.Duration.With(redis).(prometheus.Histogram), 1, 2)
+	assertCounterVecValue(t, "Json puts", m.PutsBackend.PutBackendRequests, 1, prometheus.Labels{FormatKey: JsonVal, BackendKey: "redis"})
+	assertCounterVecValue(t, "Get totals", m.GetsBackend.RequestStatus, 1, prometheus.Labels{StatusKey: TotalsVal, BackendKey: "redis"})
+}
+
+func TestDuplicateMetricRegistration(t *testing.T) {
+	cfg := config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}
+	registry := prometheus.NewRegistry()
+
+	var firstCounterVec, secondCounterVec *prometheus.CounterVec
+	var firstCounter, secondCounter prometheus.Counter
+	var firstHistogram, secondHistogram prometheus.Histogram
+	assert.NotPanics(t, func() {
+		firstCounterVec = newCounterVecWithLabels(cfg, registry, "counter_vec", "help", []string{StatusKey})
+		secondCounterVec = newCounterVecWithLabels(cfg, registry, "counter_vec", "help", []string{StatusKey})
+		firstCounter = newSingleCounter(cfg, registry, "counter", "help")
+		secondCounter = newSingleCounter(cfg, registry, "counter", "help")
+		firstHistogram = newHistogram(cfg, registry, "histogram", "help", []float64{1})
+		secondHistogram = newHistogram(cfg, registry, "histogram", "help", []float64{1})
+	}, "Registering a metric twice should not panic")
+
+	assert.True(t, firstCounterVec == secondCounterVec, "The counter vector registered first should be reused")
+	assert.True(t, firstCounter == secondCounter, "The counter registered first should be reused")
+	assert.True(t, firstHistogram == secondHistogram, "The histogram registered first should be reused")
+
+	// Values recorded through either of them land in the same registered metric
+	secondCounter.Inc()
+	assertCounterValue(t, "Reused counter", firstCounter, 1)
+}
+
+func TestInvalidMetricRegistration(t *testing.T) {
+	cfg := config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}
+	registry := prometheus.NewRegistry()
+
+	hook := test.NewGlobal()
+	defer func() { logrus.StandardLogger().ExitFunc = nil }()
+	var fatal bool
+	logrus.StandardLogger().ExitFunc = func(int) { fatal = true }
+
+	// Same fully-qualified name but inconsistent help text makes Register fail with an error
+	// other than prometheus.AlreadyRegisteredError
+	newSingleCounter(cfg, registry, "counter", "help")
+	assert.NotPanics(t, func() { newSingleCounter(cfg, registry, "counter", "different help") })
+
+	assert.True(t, fatal, "Registration errors should be fatal")
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Contains(t, hook.LastEntry().Message, "Failed to register Prometheus metric: ")
+		assert.Equal(t, logrus.FatalLevel, hook.LastEntry().Level)
+	}
+}
+
+func TestReset(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+	m.RecordPutTotal()
+	m.RecordGetTotal()
+	m.RecordGetBackendDuration(time.Second)
+	m.RecordReplicationQueueDepth(5)
+	registryBeforeReset := m.GetEngineRegistry()
+
+	m.Reset()
+
+	assert.False(t, registryBeforeReset == m.GetEngineRegistry(), "Reset should replace the registry metrics are gathered from")
+	assertCounterVecValue(t, "Put totals after the reset", m.Puts.RequestStatus, 0, prometheus.Labels{StatusKey: TotalsVal})
+	assertCounterVecValue(t, "Get totals after the reset", m.Gets.RequestStatus, 0, prometheus.Labels{StatusKey: TotalsVal})
+	assertHistogram(t, "Backend get duration after the reset", backendHistogram(m.GetsBackend.Duration), 0, 0)
+	assertGaugeValue(t, "Replication queue depth after the reset", m.Replication.QueueDepth, 0)
+
+	m.RecordPutTotal()
+	assertCounterVecValue(t, "Put totals recorded after the reset", m.Puts.RequestStatus, 1, prometheus.Labels{StatusKey: TotalsVal})
+
+	metricFamilies, err := m.GetEngineRegistry().(*prometheus.Registry).Gather()
+	assert.NoError(t, err, "Gather metrics after the reset")
+	found := false
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "prebid_cache_"+PutRequestMet {
+			found = true
+			assert.NotEmpty(t, metricFamily.GetMetric(), "Preloaded labels should be registered again")
+		}
+	}
+	assert.True(t, found, "Metrics recorded after the reset should be gathered from the new registry")
+}
+
+// gatheredGauges returns the value of every gauge m gathers, by name
+func gatheredGauges(t *testing.T, m *PrometheusMetrics) map[string]float64 {
+	t.Helper()
+	metricFamilies, err := m.GetEngineRegistry().(*prometheus.Registry).Gather()
+	assert.NoError(t, err, "Gather metrics")
+	gauges := make(map[string]float64)
+	for _, metricFamily := range metricFamilies {
+		if gauge := metricFamily.GetMetric()[0].GetGauge(); gauge != nil {
+			gauges[metricFamily.GetName()] = gauge.GetValue()
+		}
+	}
+	return gauges
+}
+
+func TestRegisterConnectionPool(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+	assert.NotContains(t, gatheredGauges(t, m), "prebid_cache_"+PoolActiveMet, "Pool gauges shouldn't be registered until a pool is")
+
+	active := 3
+	m.RegisterConnectionPool(func() int { return active }, func() int { return 8 }, nil)
+
+	gauges := gatheredGauges(t, m)
+	assert.Equal(t, float64(3), gauges["prebid_cache_"+PoolActiveMet])
+	assert.Equal(t, float64(8), gauges["prebid_cache_"+PoolSizeMet])
+	assert.NotContains(t, gauges, "prebid_cache_"+PoolPendMet, "Stats without a func shouldn't be registered")
+
+	active = 5
+	m.Reset()
+	assert.Equal(t, float64(5), gatheredGauges(t, m)["prebid_cache_"+PoolActiveMet], "Pool gauges should be registered again on reset")
+}
+
+func TestResetWhileRecording(t *testing.T) {
+	m := createPrometheusMetricsForTesting()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					m.RecordPutTotal()
+					m.RecordPutDuration(time.Millisecond)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		m.Reset()
+	}
+	close(stop)
+	wg.Wait()
+
+	m.Reset()
+	assertCounterVecValue(t, "Put totals once nothing records anymore", m.Puts.RequestStatus, 0, prometheus.Labels{StatusKey: TotalsVal})
+	m.RecordPutTotal()
+	assertCounterVecValue(t, "Put totals recorded after resetting concurrently", m.Puts.RequestStatus, 1, prometheus.Labels{StatusKey: TotalsVal})
+}
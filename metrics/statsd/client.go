@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdClient sends metrics to a StatsD agent. Counters and timers get sampled at rate: the agent
+// scales sampled values back up.
+type statsdClient interface {
+	Count(name string, value int64, rate float64)
+	Timing(name string, value time.Duration, rate float64)
+	Histogram(name string, value float64, rate float64)
+	Gauge(name string, value float64)
+}
+
+// udpClient writes every metric as a line of the StatsD protocol in its own UDP datagram. Writes are
+// fire and forget: a metric the agent never gets is lost, but recording it never blocks or fails.
+type udpClient struct {
+	conn   net.Conn
+	prefix string
+	// sample returns whether a metric sampled at rate gets sent
+	sample func(rate float64) bool
+}
+
+func newUDPClient(address, prefix string) (*udpClient, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	return &udpClient{
+		conn:   conn,
+		prefix: prefix,
+		sample: func(rate float64) bool { return rate >= 1 || rand.Float64() < rate },
+	}, nil
+}
+
+func (c *udpClient) Count(name string, value int64, rate float64) {
+	c.send(name, strconv.FormatInt(value, 10), "c", rate)
+}
+
+func (c *udpClient) Timing(name string, value time.Duration, rate float64) {
+	c.send(name, strconv.FormatFloat(float64(value)/float64(time.Millisecond), 'f', -1, 64), "ms", rate)
+}
+
+func (c *udpClient) Histogram(name string, value float64, rate float64) {
+	c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "h", rate)
+}
+
+func (c *udpClient) Gauge(name string, value float64) {
+	c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", 1)
+}
+
+func (c *udpClient) send(name, value, kind string, rate float64) {
+	if !c.sample(rate) {
+		return
+	}
+	line := c.prefix + name + ":" + value + "|" + kind
+	if rate < 1 {
+		line += "|@" + strconv.FormatFloat(rate, 'f', -1, 64)
+	}
+	c.conn.Write([]byte(line))
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUDPClient(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer agent.Close()
+
+	client, err := newUDPClient(agent.LocalAddr().String(), "prebid_cache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// Sampled metrics are only sent when the sampler lets them through
+	sampled := true
+	client.sample = func(rate float64) bool { return sampled }
+
+	client.Count("puts.current_url.request_count", 1, 1)
+	client.Timing("puts.current_url.request_duration", 1500*time.Microsecond, 0.25)
+	sampled = false
+	client.Histogram("puts.backend.request_size_bytes", 512, 0.25)
+	sampled = true
+	client.Histogram("extra_ttl_seconds", 30, 1)
+	client.Gauge("connections.active_incoming", 3)
+
+	expected := []string{
+		"prebid_cache.puts.current_url.request_count:1|c",
+		"prebid_cache.puts.current_url.request_duration:1.5|ms|@0.25",
+		"prebid_cache.extra_ttl_seconds:30|h",
+		"prebid_cache.connections.active_incoming:3|g",
+	}
+	buf := make([]byte, 512)
+	for _, line := range expected {
+		agent.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := agent.ReadFrom(buf)
+		if assert.NoError(t, err) {
+			assert.Equal(t, line, string(buf[:n]))
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const MetricsStatsd = "StatsD"
+
+// StatsdMetrics sends every metric to a StatsD agent as soon as it's recorded. Names are the ones the
+// Influx engine registers, so both can share dashboards.
+type StatsdMetrics struct {
+	client      statsdClient
+	rate        float64
+	connections int64
+	MetricsName string
+}
+
+// CreateStatsdMetrics sends the metrics to the agent of cfg. It exits if the agent's address can't
+// be resolved.
+func CreateStatsdMetrics(cfg config.StatsdMetrics) *StatsdMetrics {
+	client, err := newUDPClient(net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), cfg.Prefix)
+	if err != nil {
+		log.Fatalf("Unable to reach the StatsD agent at %s:%d: %v", cfg.Host, cfg.Port, err)
+	}
+	return newStatsdMetrics(client, cfg.SampleRate)
+}
+
+func newStatsdMetrics(client statsdClient, rate float64) *StatsdMetrics {
+	return &StatsdMetrics{
+		client:      client,
+		rate:        rate,
+		MetricsName: MetricsStatsd,
+	}
+}
+
+// Export does nothing, because metrics are sent to the agent as they get recorded
+func (m *StatsdMetrics) Export(cfg config.Metrics) {
+}
+
+func (m *StatsdMetrics) GetMetricsEngineName() string {
+	return m.MetricsName
+}
+
+// GetEngineRegistry returns nil, since metrics aren't kept anywhere but in the agent
+func (m *StatsdMetrics) GetEngineRegistry() interface{} {
+	return nil
+}
+
+func (m *StatsdMetrics) count(name string) {
+	m.client.Count(name, 1, m.rate)
+}
+
+func (m *StatsdMetrics) timing(name string, duration time.Duration) {
+	m.client.Timing(name, duration, m.rate)
+}
+
+func (m *StatsdMetrics) RecordPutError() {
+	m.count("puts.current_url.error_count")
+}
+
+func (m *StatsdMetrics) RecordPutBadRequest() {
+	m.count("puts.current_url.bad_request_count")
+}
+
+func (m *StatsdMetrics) RecordPutTotal() {
+	m.count("puts.current_url.request_count")
+}
+
+func (m *StatsdMetrics) RecordPutDuration(duration time.Duration) {
+	m.timing("puts.current_url.request_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordGetError() {
+	m.count("gets.current_url.error_count")
+}
+
+func (m *StatsdMetrics) RecordGetBadRequest() {
+	m.count("gets.current_url.bad_request_count")
+}
+
+func (m *StatsdMetrics) RecordGetTotal() {
+	m.count("gets.current_url.request_count")
+}
+
+func (m *StatsdMetrics) RecordGetDuration(duration time.Duration) {
+	m.timing("gets.current_url.request_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordDeleteError() {
+	m.count("deletes.current_url.error_count")
+}
+
+func (m *StatsdMetrics) RecordDeleteBadRequest() {
+	m.count("deletes.current_url.bad_request_count")
+}
+
+func (m *StatsdMetrics) RecordDeleteTotal() {
+	m.count("deletes.current_url.request_count")
+}
+
+func (m *StatsdMetrics) RecordDeleteDuration(duration time.Duration) {
+	m.timing("deletes.current_url.request_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordTouchError() {
+	m.count("touches.current_url.error_count")
+}
+
+func (m *StatsdMetrics) RecordTouchBadRequest() {
+	m.count("touches.current_url.bad_request_count")
+}
+
+func (m *StatsdMetrics) RecordTouchTotal() {
+	m.count("touches.current_url.request_count")
+}
+
+func (m *StatsdMetrics) RecordTouchDuration(duration time.Duration) {
+	m.timing("touches.current_url.request_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordEndToEndDuration(duration time.Duration) {
+	m.timing("requests.end_to_end_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordPutParseError() {
+	m.count("puts.current_url.json_parse_error")
+}
+
+func (m *StatsdMetrics) RecordPutValidationError() {
+	m.count("puts.current_url.validation_error")
+}
+
+func (m *StatsdMetrics) RecordPutQuotaRejection() {
+	m.count("puts.current_url.quota_rejected")
+}
+
+func (m *StatsdMetrics) RecordRejectedRequest(reason string) {
+	switch reason {
+	case "rate_limit", "quota", "overload", "forbidden", "throttled":
+	default:
+		reason = "other"
+	}
+	m.count("requests.rejected." + reason)
+}
+
+func (m *StatsdMetrics) RecordPutUserAgent(class string) {
+	m.count("puts.current_url.user_agent." + userAgentName(class))
+}
+
+func (m *StatsdMetrics) RecordGetUserAgent(class string) {
+	m.count("gets.current_url.user_agent." + userAgentName(class))
+}
+
+// userAgentName counts unknown classes as other
+func userAgentName(class string) string {
+	switch class {
+	case "prebid-server":
+		return "prebid_server"
+	case "browser":
+		return "browser"
+	}
+	return "other"
+}
+
+func (m *StatsdMetrics) RecordPutBackendXml() {
+	m.count("puts.backend.xml_request_count")
+}
+
+func (m *StatsdMetrics) RecordPutBackendJson() {
+	m.count("puts.backend.json_request_count")
+}
+
+func (m *StatsdMetrics) RecordPutBackendInvalid() {
+	m.count("puts.backend.unknown_request_count")
+}
+
+func (m *StatsdMetrics) RecordPutBackendDefTTL() {
+	m.count("puts.backend.defines_ttl")
+}
+
+func (m *StatsdMetrics) RecordPutBackendDuration(duration time.Duration) {
+	m.timing("puts.backend.request_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordPutBackendError() {
+	m.count("puts.backend.error_count")
+}
+
+func (m *StatsdMetrics) RecordPutBackendTimeout() {
+	m.count("puts.backend.timeout_count")
+}
+
+func (m *StatsdMetrics) RecordPutBackendSize(sizeInBytes float64) {
+	m.client.Histogram("puts.backend.request_size_bytes", sizeInBytes, m.rate)
+}
+
+func (m *StatsdMetrics) RecordPutVerificationFailure() {
+	m.count("puts.backend.verification_failed")
+}
+
+func (m *StatsdMetrics) RecordPutKeyCollision() {
+	m.count("puts.backend.key_collisions")
+}
+
+func (m *StatsdMetrics) RecordPutOversized() {
+	m.count("puts.backend.oversized")
+}
+
+func (m *StatsdMetrics) RecordGetBackendTotal() {
+	m.count("gets.backend.request_count")
+}
+
+func (m *StatsdMetrics) RecordGetBackendDuration(duration time.Duration) {
+	m.timing("gets.backend.request_duration", duration)
+}
+
+func (m *StatsdMetrics) RecordGetBackendError() {
+	m.count("gets.backend.error_count")
+}
+
+func (m *StatsdMetrics) RecordGetBackendTimeout() {
+	m.count("gets.backend.timeout_count")
+}
+
+func (m *StatsdMetrics) RecordKeyNotFoundError() {
+	m.count("gets.backend_error.key_not_found")
+}
+
+func (m *StatsdMetrics) RecordMissingKeyError() {
+	m.count("gets.backend_error.missing_key")
+}
+
+func (m *StatsdMetrics) RecordCorruptValue() {
+	m.count("gets.backend_error.corrupt_value")
+}
+
+func (m *StatsdMetrics) RecordGetBackendKeyHit() {
+	m.count("gets.backend.hit")
+}
+
+func (m *StatsdMetrics) RecordGetBackendKeyMiss() {
+	m.count("gets.backend.miss")
+}
+
+func (m *StatsdMetrics) RecordTierLookup(tier string, hit bool) {
+	switch tier {
+	case "near", "remote":
+	default:
+		tier = "other"
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.count("gets.backend.tier." + tier + "." + result)
+}
+
+// RecordConnectionOpen and RecordConnectionClosed keep the count of active connections, and send it
+// as a gauge. A counter would only tell the agent how many connections opened or closed.
+func (m *StatsdMetrics) RecordConnectionOpen() {
+	m.client.Gauge("connections.active_incoming", float64(atomic.AddInt64(&m.connections, 1)))
+}
+
+func (m *StatsdMetrics) RecordConnectionClosed() {
+	m.client.Gauge("connections.active_incoming", float64(atomic.AddInt64(&m.connections, -1)))
+}
+
+func (m *StatsdMetrics) RecordCloseConnectionErrors() {
+	m.count("connections.close_errors")
+}
+
+func (m *StatsdMetrics) RecordAcceptConnectionErrors() {
+	m.count("connections.accept_errors")
+}
+
+func (m *StatsdMetrics) RecordTLSHandshake(duration time.Duration) {
+	m.timing("connections.tls_handshakes", duration)
+}
+
+// RecordTLSHandshakeFailure counts unknown reasons as other
+func (m *StatsdMetrics) RecordTLSHandshakeFailure(reason string) {
+	switch reason {
+	case "not_tls", "timeout", "unsupported", "bad_certificate", "closed":
+	default:
+		reason = "other"
+	}
+	m.count("connections.tls_handshake_failures." + reason)
+}
+
+func (m *StatsdMetrics) RecordExtraTTLSeconds(value float64) {
+	m.client.Histogram("extra_ttl_seconds", value, m.rate)
+}
+
+func (m *StatsdMetrics) RecordBackendConcurrencyWait(duration time.Duration) {
+	m.timing("backend.concurrency_wait", duration)
+}
+
+func (m *StatsdMetrics) RecordAppliedTTLSeconds(value float64) {
+	m.client.Histogram("applied_ttl_seconds", value, m.rate)
+}
+
+func (m *StatsdMetrics) RecordMaxAttemptsOverride(attempts int) {
+	m.client.Histogram("backend.max_attempts_override", float64(attempts), m.rate)
+}
+
+func (m *StatsdMetrics) RecordReplicationLag(duration time.Duration) {
+	m.timing("replication.lag", duration)
+}
+
+func (m *StatsdMetrics) RecordReplicationQueueDepth(depth int) {
+	m.client.Gauge("replication.queue_depth", float64(depth))
+}
+
+func (m *StatsdMetrics) RecordReplicationDropped() {
+	m.count("replication.dropped")
+}
+
+func (m *StatsdMetrics) RecordReplicationError() {
+	m.count("replication.errors")
+}
+
+func (m *StatsdMetrics) RecordCircuitBreakerTransition(state string) {
+	switch state {
+	case "closed", "open", "half_open":
+	default:
+		state = "other"
+	}
+	m.count("backend.circuit_breaker." + state)
+}
+
+func (m *StatsdMetrics) RecordPutBackendBatchSize(size int) {
+	m.client.Histogram("puts.backend.batch_size", float64(size), m.rate)
+}
+
+func (m *StatsdMetrics) RecordGetBackendBatchSize(size int) {
+	m.client.Histogram("gets.backend.batch_size", float64(size), m.rate)
+}
+
+func (m *StatsdMetrics) RecordMemoryEviction(reason string, age time.Duration) {
+	switch reason {
+	case "ttl", "lru":
+	default:
+		reason = "manual"
+	}
+	m.count("memory.evictions." + reason)
+	m.timing("memory.evicted_value_age", age)
+}
+
+// RegisterConnectionPool does nothing, because the agent only gets the metrics sent as they're recorded
+// and the pool isn't read on its own
+func (m *StatsdMetrics) RegisterConnectionPool(active, size, pending func() int) {
+}
+
+func (m *StatsdMetrics) RecordFileDescriptors(open int, limit int) {
+	m.client.Gauge("file_descriptors.open", float64(open))
+	m.client.Gauge("file_descriptors.limit", float64(limit))
+}
+
+func (m *StatsdMetrics) RecordFileDescriptorWarning() {
+	m.count("file_descriptors.warnings")
+}
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient remembers every metric it's asked to send as "name|type|value|rate"
+type fakeClient struct {
+	sent []string
+}
+
+func (c *fakeClient) Count(name string, value int64, rate float64) {
+	c.sent = append(c.sent, fmt.Sprintf("%s|c|%d|%v", name, value, rate))
+}
+
+func (c *fakeClient) Timing(name string, value time.Duration, rate float64) {
+	c.sent = append(c.sent, fmt.Sprintf("%s|ms|%v|%v", name, value, rate))
+}
+
+func (c *fakeClient) Histogram(name string, value float64, rate float64) {
+	c.sent = append(c.sent, fmt.Sprintf("%s|h|%v|%v", name, value, rate))
+}
+
+func (c *fakeClient) Gauge(name string, value float64) {
+	c.sent = append(c.sent, fmt.Sprintf("%s|g|%v|1", name, value))
+}
+
+func TestStatsdMetrics(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		record   func(m *StatsdMetrics)
+		expected []string
+	}{
+		{
+			desc: "Put requests",
+			record: func(m *StatsdMetrics) {
+				m.RecordPutTotal()
+				m.RecordPutError()
+				m.RecordPutBadRequest()
+				m.RecordPutParseError()
+				m.RecordPutValidationError()
+				m.RecordPutDuration(10 * time.Millisecond)
+			},
+			expected: []string{
+				"puts.current_url.request_count|c|1|0.5",
+				"puts.current_url.error_count|c|1|0.5",
+				"puts.current_url.bad_request_count|c|1|0.5",
+				"puts.current_url.json_parse_error|c|1|0.5",
+				"puts.current_url.validation_error|c|1|0.5",
+				"puts.current_url.request_duration|ms|10ms|0.5",
+			},
+		},
+		{
+			desc: "Get requests",
+			record: func(m *StatsdMetrics) {
+				m.RecordGetTotal()
+				m.RecordGetError()
+				m.RecordGetBadRequest()
+				m.RecordGetDuration(time.Second)
+			},
+			expected: []string{
+				"gets.current_url.request_count|c|1|0.5",
+				"gets.current_url.error_count|c|1|0.5",
+				"gets.current_url.bad_request_count|c|1|0.5",
+				"gets.current_url.request_duration|ms|1s|0.5",
+			},
+		},
+		{
+			desc: "Backend puts",
+			record: func(m *StatsdMetrics) {
+				m.RecordPutBackendJson()
+				m.RecordPutBackendXml()
+				m.RecordPutBackendError()
+				m.RecordPutBackendTimeout()
+				m.RecordPutBackendDuration(time.Millisecond)
+				m.RecordPutBackendSize(512)
+			},
+			expected: []string{
+				"puts.backend.json_request_count|c|1|0.5",
+				"puts.backend.xml_request_count|c|1|0.5",
+				"puts.backend.error_count|c|1|0.5",
+				"puts.backend.timeout_count|c|1|0.5",
+				"puts.backend.request_duration|ms|1ms|0.5",
+				"puts.backend.request_size_bytes|h|512|0.5",
+			},
+		},
+		{
+			desc: "Backend gets",
+			record: func(m *StatsdMetrics) {
+				m.RecordGetBackendTotal()
+				m.RecordGetBackendError()
+				m.RecordGetBackendDuration(time.Millisecond)
+				m.RecordGetBackendKeyHit()
+				m.RecordGetBackendKeyMiss()
+				m.RecordKeyNotFoundError()
+				m.RecordGetBackendTimeout()
+			},
+			expected: []string{
+				"gets.backend.request_count|c|1|0.5",
+				"gets.backend.error_count|c|1|0.5",
+				"gets.backend.request_duration|ms|1ms|0.5",
+				"gets.backend.hit|c|1|0.5",
+				"gets.backend.miss|c|1|0.5",
+				"gets.backend_error.key_not_found|c|1|0.5",
+				"gets.backend.timeout_count|c|1|0.5",
+			},
+		},
+		{
+			desc: "Backend concurrency wait",
+			record: func(m *StatsdMetrics) {
+				m.RecordBackendConcurrencyWait(time.Millisecond)
+			},
+			expected: []string{
+				"backend.concurrency_wait|ms|1ms|0.5",
+			},
+		},
+		{
+			desc: "Tier lookups",
+			record: func(m *StatsdMetrics) {
+				m.RecordTierLookup("near", true)
+				m.RecordTierLookup("near", false)
+				m.RecordTierLookup("remote", true)
+				m.RecordTierLookup("unknown", false)
+			},
+			expected: []string{
+				"gets.backend.tier.near.hit|c|1|0.5",
+				"gets.backend.tier.near.miss|c|1|0.5",
+				"gets.backend.tier.remote.hit|c|1|0.5",
+				"gets.backend.tier.other.miss|c|1|0.5",
+			},
+		},
+		{
+			desc: "Connections are sent as a gauge of the active ones",
+			record: func(m *StatsdMetrics) {
+				m.RecordConnectionOpen()
+				m.RecordConnectionOpen()
+				m.RecordConnectionClosed()
+				m.RecordAcceptConnectionErrors()
+			},
+			expected: []string{
+				"connections.active_incoming|g|1|1",
+				"connections.active_incoming|g|2|1",
+				"connections.active_incoming|g|1|1",
+				"connections.accept_errors|c|1|0.5",
+			},
+		},
+		{
+			desc: "Unknown labels count as other",
+			record: func(m *StatsdMetrics) {
+				m.RecordRejectedRequest("quota")
+				m.RecordRejectedRequest("forbidden")
+				m.RecordRejectedRequest("throttled")
+				m.RecordRejectedRequest("unknown")
+				m.RecordPutUserAgent("prebid-server")
+				m.RecordGetUserAgent("unknown")
+				m.RecordTLSHandshakeFailure("unknown")
+			},
+			expected: []string{
+				"requests.rejected.quota|c|1|0.5",
+				"requests.rejected.forbidden|c|1|0.5",
+				"requests.rejected.throttled|c|1|0.5",
+				"requests.rejected.other|c|1|0.5",
+				"puts.current_url.user_agent.prebid_server|c|1|0.5",
+				"gets.current_url.user_agent.other|c|1|0.5",
+				"connections.tls_handshake_failures.other|c|1|0.5",
+			},
+		},
+		{
+			desc: "Circuit breaker transitions",
+			record: func(m *StatsdMetrics) {
+				m.RecordCircuitBreakerTransition("open")
+				m.RecordCircuitBreakerTransition("half_open")
+				m.RecordCircuitBreakerTransition("closed")
+			},
+			expected: []string{
+				"backend.circuit_breaker.open|c|1|0.5",
+				"backend.circuit_breaker.half_open|c|1|0.5",
+				"backend.circuit_breaker.closed|c|1|0.5",
+			},
+		},
+		{
+			desc: "Extra TTL",
+			record: func(m *StatsdMetrics) {
+				m.RecordExtraTTLSeconds(30)
+			},
+			expected: []string{"extra_ttl_seconds|h|30|0.5"},
+		},
+		{
+			desc: "Applied TTL",
+			record: func(m *StatsdMetrics) {
+				m.RecordAppliedTTLSeconds(3600)
+			},
+			expected: []string{"applied_ttl_seconds|h|3600|0.5"},
+		},
+		{
+			desc: "Max attempts override",
+			record: func(m *StatsdMetrics) {
+				m.RecordMaxAttemptsOverride(3)
+			},
+			expected: []string{"backend.max_attempts_override|h|3|0.5"},
+		},
+	}
+
+	for _, tc := range testCases {
+		client := &fakeClient{}
+		m := newStatsdMetrics(client, 0.5)
+
+		tc.record(m)
+
+		assert.Equal(t, tc.expected, client.sent, tc.desc)
+	}
+}
@@ -0,0 +1,150 @@
+package priming
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/endpoints"
+	log "github.com/sirupsen/logrus"
+)
+
+// primer copies the values of a peer instance, listing its keys through GET /admin/keys and reading
+// their values through GET /cache batches
+type primer struct {
+	peerURL   string
+	batchSize int
+	client    *http.Client
+	backend   backends.Backend
+}
+
+// Prime copies every value of the peer of cfg into backend, along with what's left of its TTL, and
+// returns how many values were copied. It stops after cfg.Timeout(), or as soon as the peer can't list
+// its keys, returning how many values were copied until then along with the error. Batches the peer
+// fails to read are skipped.
+func Prime(cfg config.Priming, backend backends.Backend) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout())
+	defer cancel()
+
+	p := &primer{
+		peerURL:   strings.TrimSuffix(cfg.PeerURL, "/"),
+		batchSize: cfg.BatchSize,
+		client:    &http.Client{},
+		backend:   backend,
+	}
+
+	var copied int64
+	batches := make(chan []string)
+	var wg sync.WaitGroup
+	wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for keys := range batches {
+				atomic.AddInt64(&copied, int64(p.copyBatch(ctx, keys)))
+			}
+		}()
+	}
+
+	err := p.listKeys(ctx, batches)
+	close(batches)
+	wg.Wait()
+	return int(atomic.LoadInt64(&copied)), err
+}
+
+// listKeys sends the keys of the peer to batches, a page of up to batchSize keys at a time
+func (p *primer) listKeys(ctx context.Context, batches chan<- []string) error {
+	var cursor uint64
+	for {
+		var page endpoints.KeysResponse
+		query := url.Values{"cursor": {strconv.FormatUint(cursor, 10)}, "count": {strconv.Itoa(p.batchSize)}}
+		if err := p.getJSON(ctx, "/admin/keys?"+query.Encode(), &page); err != nil {
+			return err
+		}
+		if len(page.Keys) > 0 {
+			select {
+			case batches <- page.Keys:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if page.Cursor == 0 {
+			return nil
+		}
+		cursor = page.Cursor
+	}
+}
+
+// copyBatch reads the values of keys from the peer and stores them in the backend, returning how many
+// were stored. Keys the peer doesn't hold a value for anymore are skipped.
+func (p *primer) copyBatch(ctx context.Context, keys []string) int {
+	query := url.Values{"uuid": keys}
+	if len(keys) == 1 {
+		// GET /cache only responds with a batch to requests with more than one key
+		query.Add("uuid", keys[0])
+	}
+	var batch endpoints.GetBatchResponse
+	if err := p.getJSON(ctx, "/cache?"+query.Encode(), &batch); err != nil {
+		log.Warnf("Priming skipped %d keys: %v", len(keys), err)
+		return 0
+	}
+
+	copied := 0
+	for i, resp := range batch.Responses {
+		if i >= len(keys) || resp.NotFound || resp.Error != "" {
+			continue
+		}
+		value, err := storedValue(resp)
+		if err != nil {
+			log.Warnf("Priming skipped key %s: %v", resp.UUID, err)
+			continue
+		}
+		if err := p.backend.Put(ctx, resp.UUID, value, resp.TTLSeconds); err != nil {
+			log.Warnf("Priming failed to store key %s: %v", resp.UUID, err)
+			continue
+		}
+		copied++
+	}
+	return copied
+}
+
+// storedValue turns an element of a GET /cache batch back into the value the backend stores, prefixed
+// with its format
+func storedValue(resp endpoints.GetBatchResponseObject) (string, error) {
+	switch resp.Type {
+	case backends.JSON_PREFIX:
+		return backends.JSON_PREFIX + string(resp.Value), nil
+	case backends.XML_PREFIX:
+		var xml string
+		if err := json.Unmarshal(resp.Value, &xml); err != nil {
+			return "", err
+		}
+		return backends.XML_PREFIX + xml, nil
+	}
+	return "", fmt.Errorf("unknown value type: %s", resp.Type)
+}
+
+func (p *primer) getJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.peerURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s responded with a %d", req.URL.Path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
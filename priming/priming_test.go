@@ -0,0 +1,150 @@
+package priming
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prebid/prebid-cache/backends"
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/endpoints"
+	"github.com/prebid/prebid-cache/envelope"
+	"github.com/prebid/prebid-cache/metrics"
+	"github.com/prebid/prebid-cache/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPeer serves the admin routes priming reads from, out of a memory backend that stores values along
+// with their TTL
+func newPeer(base *backends.MemoryBackend) *httptest.Server {
+	routes := config.Routes{GetBatch: config.GetBatch{Enabled: true, MaxKeys: 10, Workers: 2}}
+	router := httprouter.New()
+	router.GET("/admin/keys", endpoints.NewKeysHandler(base))
+	router.GET("/cache", endpoints.NewGetHandler(envelope.Versioned(base, 1), &metrics.Metrics{}, true, routes))
+	return httptest.NewServer(router)
+}
+
+func TestPrime(t *testing.T) {
+	peerBase := backends.NewMemoryBackend()
+	peer := envelope.Versioned(peerBase, 1)
+	expectedValues := map[string]string{
+		"json-key":     `json{"field":"value"}`,
+		"xml-key":      "xml<tag>&amp;</tag>",
+		"no-ttl-key":   "jsontrue",
+		"another-key":  `json"another value"`,
+		"the-last-key": "xml<last/>",
+	}
+	for key, value := range expectedValues {
+		ttl := 60
+		if key == "no-ttl-key" {
+			ttl = 0
+		}
+		assert.NoError(t, peer.Put(context.Background(), key, value, ttl))
+	}
+	server := newPeer(peerBase)
+	defer server.Close()
+
+	local := envelope.Versioned(backends.NewMemoryBackend(), 1)
+	// Five keys in batches of two leave a batch with a single key
+	cfg := config.Priming{Enabled: true, PeerURL: server.URL + "/", BatchSize: 2, Workers: 2, TimeoutMillis: 5000}
+
+	copied, err := Prime(cfg, local)
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(expectedValues), copied)
+	for key, expected := range expectedValues {
+		ctx, md := envelope.WithMetadata(context.Background())
+		value, err := local.Get(ctx, key)
+		if assert.NoError(t, err, key) {
+			assert.Equal(t, expected, value, key)
+		}
+		remaining, ok := md.Remaining(time.Now())
+		if key == "no-ttl-key" {
+			assert.False(t, ok, key)
+		} else if assert.True(t, ok, key) {
+			assert.InDelta(t, 60, remaining.Seconds(), 2, key)
+		}
+	}
+}
+
+func TestPrimeEmptyPeer(t *testing.T) {
+	server := newPeer(backends.NewMemoryBackend())
+	defer server.Close()
+
+	local := backends.NewMemoryBackend()
+	copied, err := Prime(config.Priming{PeerURL: server.URL, BatchSize: 10, Workers: 1, TimeoutMillis: 5000}, local)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, copied)
+	keys, _, _ := local.ScanKeys(context.Background(), 0, 10)
+	assert.Empty(t, keys)
+}
+
+func TestPrimeFailingPeer(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		inHandler   http.HandlerFunc
+		expectedErr string
+	}{
+		{
+			desc: "Keys can't be listed",
+			inHandler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			expectedErr: "GET /admin/keys responded with a 404",
+		},
+		{
+			desc: "Peer slower than the priming timeout",
+			inHandler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			expectedErr: "context deadline exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		server := httptest.NewServer(tc.inHandler)
+		local := backends.NewMemoryBackend()
+
+		copied, err := Prime(config.Priming{PeerURL: server.URL, BatchSize: 10, Workers: 1, TimeoutMillis: 100}, local)
+
+		assert.Equal(t, 0, copied, tc.desc)
+		if assert.Error(t, err, tc.desc) {
+			assert.Contains(t, err.Error(), tc.expectedErr, tc.desc)
+		}
+		server.Close()
+	}
+}
+
+func TestPrimeSkipsUnreadableBatches(t *testing.T) {
+	peerBase := backends.NewMemoryBackend()
+	for i := 0; i < 4; i++ {
+		peerBase.Put(context.Background(), fmt.Sprintf("key-%d", i), "jsontrue", 0)
+	}
+	router := httprouter.New()
+	router.GET("/admin/keys", endpoints.NewKeysHandler(peerBase))
+	// Only the first batch of keys can be read
+	router.GET("/cache", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if r.URL.Query().Get("uuid") != "key-0" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		endpoints.NewGetHandler(peerBase, &metrics.Metrics{}, true, config.Routes{GetBatch: config.GetBatch{Enabled: true, MaxKeys: 10, Workers: 1}})(w, r, ps)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	local := backends.NewMemoryBackend()
+	copied, err := Prime(config.Priming{PeerURL: server.URL, BatchSize: 2, Workers: 1, TimeoutMillis: 5000}, local)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, copied)
+	for key, expectedErr := range map[string]error{"key-0": nil, "key-1": nil, "key-2": utils.KeyNotFoundError{}, "key-3": utils.KeyNotFoundError{}} {
+		_, err := local.Get(context.Background(), key)
+		assert.Equal(t, expectedErr, err, key)
+	}
+}
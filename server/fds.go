@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// fdGuard compares the file descriptors the process has open with their soft limit. Running out of them
+// makes accepting connections fail, so it warns once usage crosses a fraction of the limit, before then.
+type fdGuard struct {
+	warnFraction float64
+	metrics      *metrics.Metrics
+	// count and limit read the open file descriptors and their soft limit, where zero means unlimited
+	count func() (int, error)
+	limit func() (int, error)
+}
+
+func newFDGuard(cfg config.FileDescriptors, m *metrics.Metrics) *fdGuard {
+	return &fdGuard{
+		warnFraction: cfg.WarnFraction,
+		metrics:      m,
+		count:        countOpenFileDescriptors,
+		limit:        fileDescriptorSoftLimit,
+	}
+}
+
+// startFDGuard checks the file descriptors right away and, if they can be read on this system, keeps
+// checking them every interval in the background
+func startFDGuard(cfg config.FileDescriptors, m *metrics.Metrics) {
+	guard := newFDGuard(cfg, m)
+	if err := guard.check(); err != nil {
+		log.Warnf("Open file descriptors won't be checked: %v", err)
+		return
+	}
+	go guard.run(context.Background(), time.Duration(cfg.CheckIntervalSeconds)*time.Second)
+}
+
+func (g *fdGuard) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := g.check(); err != nil {
+				log.Errorf("Error checking the open file descriptors: %v", err)
+			}
+		}
+	}
+}
+
+// check records the open file descriptors and their limit, and warns if too many of them are open
+func (g *fdGuard) check() error {
+	open, err := g.count()
+	if err != nil {
+		return err
+	}
+	limit, err := g.limit()
+	if err != nil {
+		return err
+	}
+	g.metrics.RecordFileDescriptors(open, limit)
+
+	if limit > 0 && float64(open) >= g.warnFraction*float64(limit) {
+		log.Warnf("%d file descriptors are open out of a soft limit of %d. Accepting connections fails once they run out.", open, limit)
+		g.metrics.RecordFileDescriptorWarning()
+	}
+	return nil
+}
+
+// countOpenFileDescriptors counts the entries of /proc/self/fd, leaving out the one listing them
+func countOpenFileDescriptors() (int, error) {
+	dir, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	return len(names) - 1, nil
+}
+
+// fileDescriptorSoftLimit reads the soft limit off the "Max open files" line of /proc/self/limits
+func fileDescriptorSoftLimit() (int, error) {
+	limits, err := os.Open("/proc/self/limits")
+	if err != nil {
+		return 0, err
+	}
+	defer limits.Close()
+	return parseSoftLimit(limits, "Max open files")
+}
+
+func parseSoftLimit(limits io.Reader, name string) (int, error) {
+	scanner := bufio.NewScanner(limits)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, name) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, name))
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return 0, nil
+		}
+		return strconv.Atoi(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no %q limit found", name)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prebid/prebid-cache/metrics/metricstest"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFDGuardCheck(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	testCases := []struct {
+		desc            string
+		inOpen          int
+		inLimit         int
+		expectedWarning bool
+	}{
+		{
+			desc:    "Below the threshold",
+			inOpen:  700,
+			inLimit: 1024,
+		},
+		{
+			desc:            "Past the threshold",
+			inOpen:          900,
+			inLimit:         1024,
+			expectedWarning: true,
+		},
+		{
+			desc:   "No limit",
+			inOpen: 100000,
+		},
+	}
+
+	for _, tc := range testCases {
+		hook.Reset()
+		m := metricstest.CreateMockMetrics()
+		guard := &fdGuard{
+			warnFraction: 0.8,
+			metrics:      m,
+			count:        func() (int, error) { return tc.inOpen, nil },
+			limit:        func() (int, error) { return tc.inLimit, nil },
+		}
+
+		assert.NoError(t, guard.check(), tc.desc)
+
+		assert.Equal(t, int64(tc.inOpen), metricstest.MockCounters["file_descriptors.open"], tc.desc)
+		assert.Equal(t, int64(tc.inLimit), metricstest.MockCounters["file_descriptors.limit"], tc.desc)
+		if tc.expectedWarning {
+			assert.Equal(t, int64(1), metricstest.MockCounters["file_descriptors.warnings"], tc.desc)
+			if assert.NotNil(t, hook.LastEntry(), tc.desc) {
+				assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level, tc.desc)
+			}
+		} else {
+			assert.Equal(t, int64(0), metricstest.MockCounters["file_descriptors.warnings"], tc.desc)
+			assert.Nil(t, hook.LastEntry(), tc.desc)
+		}
+	}
+}
+
+func TestFDGuardCheckError(t *testing.T) {
+	m := metricstest.CreateMockMetrics()
+	guard := &fdGuard{
+		warnFraction: 0.8,
+		metrics:      m,
+		count:        func() (int, error) { return 0, errors.New("no /proc") },
+		limit:        func() (int, error) { return 1024, nil },
+	}
+
+	assert.EqualError(t, guard.check(), "no /proc")
+	assert.Equal(t, int64(0), metricstest.MockCounters["file_descriptors.limit"], "Nothing should be recorded when counting fails")
+}
+
+func TestParseSoftLimit(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		inLimits      string
+		expectedLimit int
+		expectError   bool
+	}{
+		{
+			desc:          "Soft limit",
+			inLimits:      "Limit                     Soft Limit           Hard Limit           Units\nMax open files            1024                 4096                 files\n",
+			expectedLimit: 1024,
+		},
+		{
+			desc:     "Unlimited",
+			inLimits: "Max open files            unlimited            unlimited            files\n",
+		},
+		{
+			desc:        "Limit missing",
+			inLimits:    "Max processes             63704                63704                processes\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		limit, err := parseSoftLimit(strings.NewReader(tc.inLimits), "Max open files")
+		if tc.expectError {
+			assert.Error(t, err, tc.desc)
+			continue
+		}
+		assert.NoError(t, err, tc.desc)
+		assert.Equal(t, tc.expectedLimit, limit, tc.desc)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"net"
 	"net/http"
 	"strconv"
 
@@ -11,13 +12,15 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func newPrometheusServer(cfg *config.Configuration, promRegistry *prometheus.Registry) *http.Server {
-	if promRegistry == nil {
+// newPrometheusServer serves the metrics on the configured host and port, which config validation keeps
+// apart from the main and admin servers
+func newPrometheusServer(cfg *config.Configuration, promGatherer prometheus.Gatherer) *http.Server {
+	if promGatherer == nil {
 		log.Errorf("Prometheus metrics configured, but a Prometheus metrics engine was not found. Cannot set up a Prometheus listener.")
 	}
 	return &http.Server{
-		Addr: ":" + strconv.Itoa(cfg.Metrics.Prometheus.Port),
-		Handler: promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{
+		Addr: net.JoinHostPort(cfg.Metrics.Prometheus.Host, strconv.Itoa(cfg.Metrics.Prometheus.Port)),
+		Handler: promhttp.HandlerFor(promGatherer, promhttp.HandlerOpts{
 			ErrorLog:            loggerForPrometheus{},
 			MaxRequestsInFlight: 5,
 			Timeout:             cfg.Metrics.Prometheus.Timeout(),
@@ -17,6 +17,7 @@ import (
 	"github.com/prebid/prebid-cache/metrics"
 	localprometheus "github.com/prebid/prebid-cache/metrics/prometheus"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 // Listen serves requests and blocks forever, until OS signals shut down the process.
@@ -44,6 +45,15 @@ func Listen(cfg config.Configuration, publicHandler http.Handler, adminHandler h
 		log.Errorf("Error listening for TCP connections on %s: %v", mainServer.Addr, err)
 		return
 	}
+	if cfg.TLS.Enabled {
+		if mainListener, err = newTLSListener(mainListener, cfg.TLS, metrics); err != nil {
+			log.Errorf("Error loading the TLS certificate of %s: %v", mainServer.Addr, err)
+			return
+		}
+	}
+	if cfg.FileDescriptors.Enabled {
+		startFDGuard(cfg.FileDescriptors, metrics)
+	}
 	adminListener, err := newListener(adminServer.Addr, nil)
 	if err != nil {
 		log.Errorf("Error listening for TCP connections on %s: %v", adminServer.Addr, err)
@@ -56,13 +66,16 @@ func Listen(cfg config.Configuration, publicHandler http.Handler, adminHandler h
 	// Once they're finished shutting down (the "done" channel gets pinged for each server),
 	// this funciton can return.
 	if cfg.Metrics.Prometheus.Enabled {
-		promRegistry := metrics.GetEngineRegistry(localprometheus.MetricsPrometheus).(*prometheus.Registry)
+		// The registry is looked up on every scrape because resetting the metrics replaces it
+		promGatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return metrics.GetEngineRegistry(localprometheus.MetricsPrometheus).(*prometheus.Registry).Gather()
+		})
 
-		prometheusServer := newPrometheusServer(&cfg, promRegistry)
+		prometheusServer := newPrometheusServer(&cfg, promGatherer)
 		go shutdownAfterSignals(prometheusServer, stopPrometheus, done)
 		prometheusListener, err := newListener(prometheusServer.Addr, nil)
 		if err != nil {
-			log.Errorf("Error listening for TCP connections on %s: %v for prometheus server", adminServer.Addr, err)
+			log.Errorf("Error listening for TCP connections on %s: %v for prometheus server", prometheusServer.Addr, err)
 			return
 		}
 		go runServer(prometheusServer, "Prometheus", prometheusListener)
@@ -85,8 +98,9 @@ func newMainServer(cfg config.Configuration, handler http.Handler) *http.Server
 	return &http.Server{
 		Addr:         ":" + strconv.Itoa(cfg.Port),
 		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout(),
+		WriteTimeout: cfg.Server.WriteTimeout(),
+		IdleTimeout:  cfg.Server.IdleTimeout(),
 	}
 }
 
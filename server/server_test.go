@@ -1,11 +1,16 @@
 package server
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/prebid/prebid-cache/config"
+	localprometheus "github.com/prebid/prebid-cache/metrics/prometheus"
 )
 
 func TestNewAdminServer(t *testing.T) {
@@ -23,11 +28,58 @@ func TestNewMainServer(t *testing.T) {
 	cfg := config.Configuration{
 		Port:      8000,
 		AdminPort: 6060,
+		Server: config.Server{
+			ReadTimeoutMillis:  5000,
+			WriteTimeoutMillis: 10000,
+			IdleTimeoutMillis:  120000,
+		},
 	}
 	server := newMainServer(cfg, http.HandlerFunc(handler))
 	if server.Addr != ":8000" {
 		t.Errorf("Admin server address should be %s. Got %s", ":8000", server.Addr)
 	}
+	if server.ReadTimeout != 5*time.Second {
+		t.Errorf("Main server read timeout should be %v. Got %v", 5*time.Second, server.ReadTimeout)
+	}
+	if server.WriteTimeout != 10*time.Second {
+		t.Errorf("Main server write timeout should be %v. Got %v", 10*time.Second, server.WriteTimeout)
+	}
+	if server.IdleTimeout != 2*time.Minute {
+		t.Errorf("Main server idle timeout should be %v. Got %v", 2*time.Minute, server.IdleTimeout)
+	}
+}
+
+func TestNewPrometheusServer(t *testing.T) {
+	cfg := config.Configuration{
+		Port:      8000,
+		AdminPort: 6060,
+		Metrics: config.Metrics{Prometheus: config.PrometheusMetrics{
+			Enabled:   true,
+			Host:      "127.0.0.1",
+			Port:      9090,
+			Namespace: "prebid",
+			Subsystem: "cache",
+		}},
+	}
+	engine := localprometheus.CreatePrometheusMetrics(cfg.Metrics.Prometheus, config.BackendMemory)
+	engine.RecordPutTotal()
+
+	server := newPrometheusServer(&cfg, engine.Registry)
+	if server.Addr != "127.0.0.1:9090" {
+		t.Errorf("Prometheus server address should be %s. Got %s", "127.0.0.1:9090", server.Addr)
+	}
+
+	scraper := httptest.NewServer(server.Handler)
+	defer scraper.Close()
+	resp, err := http.Get(scraper.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Error scraping the Prometheus server: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "prebid_cache_puts") {
+		t.Errorf("Prometheus server should serve the metrics. Got a %d with %s", resp.StatusCode, body)
+	}
 }
 
 func TestServerShutdown(t *testing.T) {
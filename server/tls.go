@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// tlsHandshakeTimeout bounds how long a client may take to complete its handshake
+const tlsHandshakeTimeout = 10 * time.Second
+
+// Classes TLS handshake failures are counted under
+const (
+	tlsFailureNotTLS      = "not_tls"
+	tlsFailureTimeout     = "timeout"
+	tlsFailureUnsupported = "unsupported"
+	tlsFailureBadCert     = "bad_certificate"
+	tlsFailureClosed      = "closed"
+	tlsFailureOther       = "other"
+)
+
+var errTLSListenerClosed = errors.New("TLS listener closed")
+
+// tlsListener terminates TLS on the connections of the listener it wraps. Every handshake runs in a
+// goroutine of its own, so that slow or failing clients don't hold up the others, and only connections
+// whose handshake succeeded are returned by Accept. Failed handshakes count as accept errors.
+type tlsListener struct {
+	net.Listener
+	config  *tls.Config
+	timeout time.Duration
+	metrics *metrics.Metrics
+
+	ready     chan net.Conn
+	errs      chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newTLSListener loads the certificate of cfg and starts terminating TLS on the connections of inner
+func newTLSListener(inner net.Listener, cfg config.TLS, m *metrics.Metrics) (net.Listener, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return startTLSListener(inner, &tls.Config{Certificates: []tls.Certificate{cert}}, tlsHandshakeTimeout, m), nil
+}
+
+func startTLSListener(inner net.Listener, tlsConfig *tls.Config, timeout time.Duration, m *metrics.Metrics) *tlsListener {
+	ln := &tlsListener{
+		Listener: inner,
+		config:   tlsConfig,
+		timeout:  timeout,
+		metrics:  m,
+		ready:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	go ln.acceptLoop()
+	return ln
+}
+
+func (ln *tlsListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-ln.ready:
+		return conn, nil
+	case err := <-ln.errs:
+		return nil, err
+	case <-ln.done:
+		return nil, errTLSListenerClosed
+	}
+}
+
+func (ln *tlsListener) Close() error {
+	ln.closeOnce.Do(func() { close(ln.done) })
+	return ln.Listener.Close()
+}
+
+// acceptLoop hands every accepted connection over to a handshake, until the wrapped listener fails
+// with an error that isn't temporary
+func (ln *tlsListener) acceptLoop() {
+	for {
+		conn, err := ln.Listener.Accept()
+		if err != nil {
+			select {
+			case ln.errs <- err:
+			case <-ln.done:
+				return
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				continue
+			}
+			return
+		}
+		go ln.handshake(conn)
+	}
+}
+
+func (ln *tlsListener) handshake(conn net.Conn) {
+	tlsConn := tls.Server(conn, ln.config)
+	start := time.Now()
+	tlsConn.SetDeadline(start.Add(ln.timeout))
+	err := tlsConn.Handshake()
+	if err != nil {
+		reason := classifyHandshakeError(err)
+		log.Debugf("TLS handshake with %s failed (%s): %v", conn.RemoteAddr(), reason, err)
+		ln.metrics.RecordAcceptConnectionErrors()
+		ln.metrics.RecordTLSHandshakeFailure(reason)
+		conn.Close()
+		return
+	}
+	ln.metrics.RecordTLSHandshake(time.Since(start))
+	tlsConn.SetDeadline(time.Time{})
+
+	select {
+	case ln.ready <- tlsConn:
+	case <-ln.done:
+		tlsConn.Close()
+	}
+}
+
+// classifyHandshakeError maps a failed handshake to the class it's counted under. Most errors of the
+// tls package aren't exported, so they're told apart by their message.
+func classifyHandshakeError(err error) string {
+	if _, ok := err.(tls.RecordHeaderError); ok {
+		return tlsFailureNotTLS
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return tlsFailureTimeout
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return tlsFailureClosed
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "protocol version"), strings.Contains(msg, "cipher suite"), strings.Contains(msg, "no application protocol"):
+		return tlsFailureUnsupported
+	case strings.Contains(msg, "certificate"):
+		return tlsFailureBadCert
+	}
+	return tlsFailureOther
+}
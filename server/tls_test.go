@@ -0,0 +1,167 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/prebid/prebid-cache/metrics"
+	prometheusMetrics "github.com/prebid/prebid-cache/metrics/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 that expires in an hour
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "prebid-cache"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTestTLSListener listens on a random local port, with the connection and TLS metrics recorded in
+// the returned Prometheus engine
+func newTestTLSListener(t *testing.T) (*tlsListener, *prometheusMetrics.PrometheusMetrics) {
+	t.Helper()
+
+	promMetrics := prometheusMetrics.CreatePrometheusMetrics(config.PrometheusMetrics{Namespace: "prebid", Subsystem: "cache"}, config.BackendMemory)
+	m := &metrics.Metrics{MetricEngines: []metrics.CacheMetrics{promMetrics}}
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}}
+	return startTLSListener(&monitorableListener{inner, m}, tlsConfig, time.Second, m), promMetrics
+}
+
+func TestTLSListenerHandshake(t *testing.T) {
+	ln, promMetrics := newTestTLSListener(t)
+	defer ln.Close()
+
+	go func() {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			conn.Write([]byte("ping"))
+			conn.Close()
+		}
+	}()
+
+	conn, err := ln.Accept()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, isTLS := conn.(*tls.Conn)
+	assert.True(t, isTLS, "Accepted connections should be TLS connections, so that HTTP servers tell them apart")
+	received, _ := ioutil.ReadAll(io.LimitReader(conn, 4))
+	assert.Equal(t, "ping", string(received))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(promMetrics.Connections.TLSHandshakes), "Successful handshakes")
+	assert.Equal(t, 0.0, testutil.ToFloat64(promMetrics.Connections.ConnectionsErrors.WithLabelValues("accept")), "Accept errors")
+}
+
+func TestTLSListenerHandshakeFailure(t *testing.T) {
+	ln, promMetrics := newTestTLSListener(t)
+	defer ln.Close()
+
+	// Speak plain HTTP to the TLS port
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /status HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+
+	// The failure is recorded before the server closes the connection
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	ioutil.ReadAll(conn)
+
+	failures := promMetrics.Connections.TLSHandshakeFailures
+	assert.Equal(t, 1.0, testutil.ToFloat64(failures.WithLabelValues(prometheusMetrics.NotTLSVal)), "Handshakes with clients that don't speak TLS")
+	assert.Equal(t, 1.0, testutil.ToFloat64(promMetrics.Connections.ConnectionsErrors.WithLabelValues("accept")), "Failed handshakes should count as accept errors")
+	assert.Equal(t, 0.0, testutil.ToFloat64(promMetrics.Connections.TLSHandshakes), "Successful handshakes")
+}
+
+func TestTLSListenerHandshakeTimeout(t *testing.T) {
+	ln, promMetrics := newTestTLSListener(t)
+	defer ln.Close()
+
+	// Connect without ever sending a ClientHello
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	ioutil.ReadAll(conn)
+
+	failures := promMetrics.Connections.TLSHandshakeFailures
+	assert.Equal(t, 1.0, testutil.ToFloat64(failures.WithLabelValues(prometheusMetrics.TimeoutVal)), "Handshakes that timed out")
+}
+
+func TestClassifyHandshakeError(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		inErr          error
+		expectedReason string
+	}{
+		{
+			desc:           "Client closed the connection",
+			inErr:          io.EOF,
+			expectedReason: tlsFailureClosed,
+		},
+		{
+			desc:           "No version in common",
+			inErr:          &net.OpError{Op: "remote error", Err: tlsAlertError("tls: protocol version not supported")},
+			expectedReason: tlsFailureUnsupported,
+		},
+		{
+			desc:           "Client rejected the certificate",
+			inErr:          &net.OpError{Op: "remote error", Err: tlsAlertError("tls: bad certificate")},
+			expectedReason: tlsFailureBadCert,
+		},
+		{
+			desc:           "Anything else",
+			inErr:          tlsAlertError("tls: unexpected message"),
+			expectedReason: tlsFailureOther,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedReason, classifyHandshakeError(tc.inErr), tc.desc)
+	}
+}
+
+type tlsAlertError string
+
+func (e tlsAlertError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prebid/prebid-cache/config"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of every span Prebid Cache creates
+const TracerName = "github.com/prebid/prebid-cache"
+
+// Span attributes of the cache operations
+const (
+	KeyAttribute     = attribute.Key("cache.key")
+	BackendAttribute = attribute.Key("cache.backend")
+	ResultAttribute  = attribute.Key("cache.result")
+)
+
+// Results of the backend operations
+const (
+	ResultOK      = "ok"
+	ResultHit     = "hit"
+	ResultMiss    = "miss"
+	ResultTimeout = "timeout"
+	ResultError   = "error"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Start sends the spans of every tracer to the OpenTelemetry collector of cfg, and reads the trace
+// context of incoming requests from their W3C traceparent header. Requests without a trace context are
+// sampled at cfg.SampleRate; the others follow the decision of their caller.
+//
+// The returned function flushes the spans not sent yet. Start does nothing if spans aren't exported.
+func Start(cfg config.Tracing) (stop func()) {
+	if !cfg.ExportsSpans() {
+		return func() {}
+	}
+	exporter, err := otlptracehttp.New(context.Background(), exporterOptions(cfg.OTLPEndpoint)...)
+	if err != nil {
+		log.Fatalf("Unable to export spans to %s: %v", cfg.OTLPEndpoint, err)
+	}
+	provider := newTracerProvider(cfg, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	log.Infof("Exporting spans to %s", cfg.OTLPEndpoint)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Errorf("Error flushing spans to %s: %v", cfg.OTLPEndpoint, err)
+		}
+	}
+}
+
+func newTracerProvider(cfg config.Tracing, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append(opts,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "prebid-cache"))),
+	)
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// exporterOptions points the exporter to endpoint, which config validation made sure is an http or
+// https URL
+func exporterOptions(endpoint string) []otlptracehttp.Option {
+	parsed, _ := url.Parse(endpoint)
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(parsed.Host)}
+	if parsed.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(parsed.Path))
+	}
+	return opts
+}
+
+// Tracer creates the spans of Prebid Cache. They go nowhere unless Start was called.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prebid/prebid-cache/config"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartWithoutEndpoint(t *testing.T) {
+	previous := otel.GetTracerProvider()
+
+	stop := Start(config.Tracing{Enabled: true, SampleRate: 1})
+	stop()
+
+	assert.Equal(t, previous, otel.GetTracerProvider(), "Spans should not be exported without an OTLP endpoint")
+}
+
+func TestSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := newTracerProvider(config.Tracing{SampleRate: 0}, sdktrace.WithSyncer(exporter))
+	tracer := provider.Tracer(TracerName)
+
+	_, root := tracer.Start(context.Background(), "GET /cache")
+	root.End()
+	assert.Empty(t, exporter.GetSpans(), "Requests without a trace context should be sampled at the sample rate")
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9},
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, child := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), parent), "GET /cache")
+	child.End()
+	assert.Len(t, exporter.GetSpans(), 1, "Requests should follow the sampling decision of their caller")
+}
@@ -1,5 +1,10 @@
 package utils
 
+import (
+	"context"
+	"errors"
+)
+
 /**************************/
 /* Get errors			  */
 /**************************/
@@ -13,6 +18,10 @@ func (e KeyNotFoundError) Error() string {
 	return "Key not found"
 }
 
+func (e KeyNotFoundError) ErrorCode() ErrorCode {
+	return NotFound
+}
+
 // Missing UUID error
 type MissingKeyError struct{}
 
@@ -26,3 +35,70 @@ type KeyLengthError struct{}
 func (e KeyLengthError) Error() string {
 	return "invalid uuid length"
 }
+
+/**************************/
+/* Put errors			  */
+/**************************/
+
+// Key already holding a value, returned by conditional puts
+type KeyExistsError struct{}
+
+func (e KeyExistsError) Error() string {
+	return "Key already exists"
+}
+
+func (e KeyExistsError) ErrorCode() ErrorCode {
+	return Conflict
+}
+
+/**************************/
+/* Backend error codes    */
+/**************************/
+
+// ErrorCode is a stable, machine-readable category of backend errors. Backends map the errors of their
+// drivers to them, so clients don't depend on what the backend is.
+type ErrorCode string
+
+const (
+	BackendUnavailable ErrorCode = "BackendUnavailable"
+	ValueTooLarge      ErrorCode = "ValueTooLarge"
+	Conflict           ErrorCode = "Conflict"
+	NotFound           ErrorCode = "NotFound"
+	Timeout            ErrorCode = "Timeout"
+	Corrupt            ErrorCode = "Corrupt"
+)
+
+// BackendError is an error of a backend driver, classified under Code
+type BackendError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func NewBackendError(code ErrorCode, err error) BackendError {
+	return BackendError{Code: code, Err: err}
+}
+
+func (e BackendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e BackendError) Unwrap() error {
+	return e.Err
+}
+
+func (e BackendError) ErrorCode() ErrorCode {
+	return e.Code
+}
+
+// ErrorCodeOf returns the code of an error returned by a backend. Timeouts are classified wherever they
+// come from, and any other error that isn't classified counts as the backend being unavailable.
+func ErrorCodeOf(err error) ErrorCode {
+	var coded interface{ ErrorCode() ErrorCode }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout
+	}
+	return BackendUnavailable
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ResponseCode is the stable, machine-readable class of an error response. Every status maps to a
+// single class, so clients can branch on either.
+type ResponseCode string
+
+const (
+	BadRequestCode      ResponseCode = "bad_request"
+	UnauthorizedCode    ResponseCode = "unauthorized"
+	NotFoundCode        ResponseCode = "not_found"
+	PayloadTooLargeCode ResponseCode = "payload_too_large"
+	TooManyRequestsCode ResponseCode = "too_many_requests"
+	BackendErrorCode    ResponseCode = "backend_error"
+)
+
+// ResponseCodeOf returns the class of the error responses with status. Client errors without a class
+// of their own are bad requests, and every server error is a backend error.
+func ResponseCodeOf(status int) ResponseCode {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return UnauthorizedCode
+	case status == http.StatusNotFound:
+		return NotFoundCode
+	case status == http.StatusRequestEntityTooLarge:
+		return PayloadTooLargeCode
+	case status == http.StatusTooManyRequests:
+		return TooManyRequestsCode
+	case status >= http.StatusInternalServerError:
+		return BackendErrorCode
+	}
+	return BadRequestCode
+}
+
+// ErrorResponse is the body of every error response. BackendCode is only set for the errors returned
+// by the backend, when the request asks for it.
+type ErrorResponse struct {
+	Error       string       `json:"error"`
+	Code        ResponseCode `json:"code"`
+	BackendCode ErrorCode    `json:"backend_code,omitempty"`
+}
+
+// WriteError responds with status and an ErrorResponse carrying msg and the class of status. It stands
+// for http.Error, so the same rules apply: nothing else should be written to w.
+func WriteError(w http.ResponseWriter, msg string, status int) {
+	WriteErrorResponse(w, ErrorResponse{Error: msg, Code: ResponseCodeOf(status)}, status)
+}
+
+// WriteErrorResponse responds with status and body
+func WriteErrorResponse(w http.ResponseWriter, body ErrorResponse, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCodeOf(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		inStatus     int
+		expectedCode ResponseCode
+	}{
+		{desc: "Bad request", inStatus: http.StatusBadRequest, expectedCode: BadRequestCode},
+		{desc: "Client error without a class", inStatus: http.StatusMethodNotAllowed, expectedCode: BadRequestCode},
+		{desc: "Unauthorized", inStatus: http.StatusUnauthorized, expectedCode: UnauthorizedCode},
+		{desc: "Forbidden", inStatus: http.StatusForbidden, expectedCode: UnauthorizedCode},
+		{desc: "Not found", inStatus: http.StatusNotFound, expectedCode: NotFoundCode},
+		{desc: "Payload too large", inStatus: http.StatusRequestEntityTooLarge, expectedCode: PayloadTooLargeCode},
+		{desc: "Too many requests", inStatus: http.StatusTooManyRequests, expectedCode: TooManyRequestsCode},
+		{desc: "Internal server error", inStatus: http.StatusInternalServerError, expectedCode: BackendErrorCode},
+		{desc: "Dependency timeout", inStatus: 597, expectedCode: BackendErrorCode},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedCode, ResponseCodeOf(tc.inStatus), tc.desc)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	WriteError(rr, "Missing value.", http.StatusBadRequest)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, `{"error":"Missing value.","code":"bad_request"}`+"\n", rr.Body.String())
+}
+
+func TestWriteErrorResponseWithBackendCode(t *testing.T) {
+	rr := httptest.NewRecorder()
+	WriteErrorResponse(rr, ErrorResponse{Error: "Timeout writing value to the backend", Code: BackendErrorCode, BackendCode: Timeout}, 597)
+
+	assert.Equal(t, 597, rr.Code)
+	assert.Equal(t, `{"error":"Timeout writing value to the backend","code":"backend_error","backend_code":"Timeout"}`+"\n", rr.Body.String())
+}
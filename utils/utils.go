@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"time"
+
 	"github.com/gofrs/uuid"
 )
 
@@ -8,3 +13,32 @@ func GenerateRandomId() (string, error) {
 	u2, err := uuid.NewV4()
 	return u2.String(), err
 }
+
+// GenerateTimeOrderedId returns a version 7 UUID, which starts with the time it was generated at. Ids
+// generated a millisecond or more apart sort in the order they were generated, and so do most of
+// those generated within the same millisecond.
+func GenerateTimeOrderedId() (string, error) {
+	u, err := newV7(time.Now(), rand.Reader)
+	return u.String(), err
+}
+
+// newV7 lays out the UUID as RFC 9562 describes: 48 bits of Unix milliseconds, the version, 12 bits
+// of sub-millisecond precision, the variant and 62 random bits.
+func newV7(now time.Time, random io.Reader) (uuid.UUID, error) {
+	var u uuid.UUID
+	if _, err := io.ReadFull(random, u[8:]); err != nil {
+		return u, err
+	}
+
+	nanos := now.UnixNano()
+	millis := uint64(nanos / int64(time.Millisecond))
+	fraction := uint16(nanos % int64(time.Millisecond) * 4096 / int64(time.Millisecond))
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], millis)
+	copy(u[:6], ts[2:])
+	binary.BigEndian.PutUint16(u[6:8], fraction)
+	u.SetVersion(7)
+	u.SetVariant(uuid.VariantRFC4122)
+	return u, nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateTimeOrderedId(t *testing.T) {
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := GenerateTimeOrderedId()
+		if !assert.NoError(t, err) {
+			return
+		}
+		ids = append(ids, id)
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, id := range ids {
+		parsed, err := uuid.FromString(id)
+		if assert.NoError(t, err, "%s should be a valid UUID", id) {
+			assert.Len(t, id, 36, "Version 7 UUIDs should be as long as version 4 ones")
+			assert.Equal(t, byte(7), parsed.Version())
+			assert.Equal(t, uuid.VariantRFC4122, parsed.Variant())
+		}
+	}
+	assert.True(t, sort.StringsAreSorted(ids), "Ids generated a millisecond apart should sort in order: %v", ids)
+}
+
+func TestGenerateTimeOrderedIdWithinAMillisecond(t *testing.T) {
+	previous, _ := GenerateTimeOrderedId()
+	for i := 0; i < 1000; i++ {
+		id, err := GenerateTimeOrderedId()
+		if !assert.NoError(t, err) {
+			return
+		}
+		// The timestamp and its sub-millisecond fraction take the first 18 characters, version included
+		assert.True(t, id[:18] >= previous[:18], "Timestamp of %s should not be earlier than that of %s", id, previous)
+		previous = id
+	}
+}
+
+func TestNewV7Layout(t *testing.T) {
+	now := time.Unix(0, 1700000000123*int64(time.Millisecond)+500*int64(time.Microsecond))
+	random := bytes.NewReader(bytes.Repeat([]byte{0xff}, 8))
+
+	id, err := newV7(now, random)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "018bcfe5-687b-7800-bfff-ffffffffffff", id.String())
+}